@@ -0,0 +1,110 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gojue/moling/pkg/server"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	auditTailCmd.Flags().IntVarP(&auditTailLines, "lines", "n", 50, "Number of entries to print, 0 prints all")
+	auditTailCmd.Flags().StringVar(&auditTailTool, "tool", "", "Only print entries of this tool, prompt or resource URI")
+	auditTailCmd.Flags().StringVar(&auditTailSession, "session", "", "Only print entries of this client session")
+	auditTailCmd.Flags().BoolVar(&auditTailJson, "json", false, "Print the entries as JSON lines")
+	auditCmd.AddCommand(auditTailCmd)
+	rootCmd.AddCommand(auditCmd)
+}
+
+// auditCmd 读取审计日志
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Read the audit log of tool calls, prompt gets and resource reads",
+	Long: `Read the audit log written to logs/audit.jsonl when audit_log is enabled in MoLingConfig.
+    moling audit tail -n 50 --tool command_execute
+`,
+}
+
+// auditTailCmd 打印审计日志的最后几条记录
+var auditTailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "Print the last entries of the audit log",
+	RunE:  AuditTailCommandFunc,
+}
+
+var (
+	auditTailLines   int
+	auditTailTool    string
+	auditTailSession string
+	auditTailJson    bool
+)
+
+// AuditTailCommandFunc executes the "audit tail" command.
+func AuditTailCommandFunc(command *cobra.Command, args []string) error {
+	auditFile := filepath.Join(mlConfig.BasePath, "logs", server.AuditFileName)
+	entries, err := server.ReadAuditLog(auditFile, server.AuditFilter{
+		Tool:    auditTailTool,
+		Session: auditTailSession,
+		Limit:   auditTailLines,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read audit log %s: %w", auditFile, err)
+	}
+	out := command.OutOrStdout()
+	if auditTailJson {
+		for _, entry := range entries {
+			data, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(out, string(data)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return printAuditEntries(out, entries)
+}
+
+// printAuditEntries 以表格形式打印审计记录
+func printAuditEntries(out io.Writer, entries []server.AuditEntry) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "TIME\tSESSION\tKIND\tNAME\tDURATION\tRESULT\tERROR")
+	for _, entry := range entries {
+		result := "-"
+		if entry.Result != nil {
+			result = fmt.Sprintf("%d items, %d bytes", entry.Result.Items, entry.Result.Bytes)
+		}
+		session, errCode := entry.Session, entry.ErrorCode
+		if session == "" {
+			session = "-"
+		}
+		if errCode == "" {
+			errCode = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%dms\t%s\t%s\n", entry.Time.Local().Format(time.RFC3339),
+			session, entry.Kind, entry.Name, entry.DurationMs, result, errCode)
+	}
+	return w.Flush()
+}
@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -11,10 +14,13 @@ import (
 
 	"github.com/gojue/moling/pkg/comm"
 	"github.com/gojue/moling/pkg/config"
+	"github.com/gojue/moling/pkg/plugins"
 	"github.com/gojue/moling/pkg/services"
 	"github.com/gojue/moling/pkg/services/abstract"
 	"github.com/gojue/moling/pkg/utils"
 	"github.com/rs/zerolog"
+	"github.com/spf13/pflag"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
@@ -37,6 +43,9 @@ Usage:
   moling -h
   moling client -i
   moling config 
+  moling config --preset web
+  moling tools
+  moling debug-bundle
 `
 	CliDescriptionLongZh = `MoLing（魔灵）是一个computer-use的MCP Server，基于操作系统API实现了系统交互，可以实现文件系统的读写、合并、统计、聚合等操作，也可以执行系统命令操作。是一个无需任何依赖的本地办公自动化助手。
 没有任何安装依赖，直接运行，兼容Windows、Linux、macOS等操作系统。再也不用苦恼NodeJS、Python等环境冲突等问题。
@@ -47,6 +56,9 @@ Usage:
   moling -h
   moling client -i
   moling config 
+  moling config --preset web
+  moling tools
+  moling debug-bundle
 `
 )
 
@@ -61,9 +73,16 @@ const (
 var (
 	GitVersion = "unknown_arm64_v0.0.0_2025-03-22 20:08"
 	mlConfig   = &config.MoLingConfig{
-		Version:    GitVersion,
-		ConfigFile: filepath.Join("config", MLConfigName),
-		BasePath:   filepath.Join(os.TempDir(), MLRootPath), // will set in mlsCommandPreFunc
+		Version:            GitVersion,
+		ConfigFile:         filepath.Join("config", MLConfigName),
+		BasePath:           filepath.Join(os.TempDir(), MLRootPath), // will set in mlsCommandPreFunc
+		RateLimit:          config.NewRateLimitConfig(),
+		ResultLimit:        config.NewResultLimitConfig(),
+		Plugins:            config.NewPluginConfig(),
+		Session:            config.NewSessionConfig(),
+		Audit:              config.NewAuditConfig(),
+		ShutdownTimeout:    5,
+		ServiceInitTimeout: 30,
 	}
 
 	// mlDirectories is a list of directories to be created in the base path
@@ -73,6 +92,7 @@ var (
 		"browser", // browser cache
 		"data",    // data
 		"cache",
+		"plugins", // external services
 	}
 )
 
@@ -85,7 +105,7 @@ func initLogger(mlDataPath string) zerolog.Logger {
 	}
 
 	// 初始化 RotateWriter
-	logFile := filepath.Join(mlDataPath, "logs", LogFileName)
+	logFile := logFilePath(mlDataPath)
 	rw, err := utils.NewRotateWriter(logFile, MaxLogSize) // 512MB 阈值
 	if err != nil {
 		panic(fmt.Sprintf("failed to open log file %s: %v", logFile, err))
@@ -97,6 +117,16 @@ func initLogger(mlDataPath string) zerolog.Logger {
 	return logger
 }
 
+// logFilePath 返回日志文件路径
+func logFilePath(basePath string) string {
+	return filepath.Join(basePath, "logs", LogFileName)
+}
+
+// mlConfigFilePath 返回 MoLing 配置文件路径
+func mlConfigFilePath() string {
+	return filepath.Join(mlConfig.BasePath, mlConfig.ConfigFile)
+}
+
 // setupLogger 初始化日志记录器，支持控制台和文件双重输出
 func setupLogger(basePath string) zerolog.Logger {
 	fileLogger := initLogger(basePath)
@@ -111,6 +141,85 @@ func createContext(logger zerolog.Logger) context.Context {
 	return context.WithValue(ctx, comm.MoLingLoggerKey, logger)
 }
 
+// loadGlobalConfig 从配置文件的 MoLingConfig 加载限流、结果大小限制、插件、会话和审计日志配置。
+// listen_addr、transport_mode、auth_token 和 module 只在命令行未指定时生效
+func loadGlobalConfig(configJson map[string]interface{}, flags *pflag.FlagSet) error {
+	return presetError("MoLingConfig", loadGlobalSettings(configJson, flags))
+}
+
+// loadGlobalSettings 加载 MoLingConfig 配置节
+func loadGlobalSettings(configJson map[string]interface{}, flags *pflag.FlagSet) error {
+	globalConfig, ok := configJson["MoLingConfig"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if addr, ok := globalConfig["listen_addr"].(string); ok && !flags.Changed("listen_addr") {
+		mlConfig.ListenAddr = addr
+	}
+	if mode, ok := globalConfig["transport_mode"].(string); ok && !flags.Changed("transport") {
+		mlConfig.TransportMode = mode
+	}
+	if token, ok := globalConfig["auth_token"].(string); ok && !flags.Changed("auth_token") {
+		mlConfig.AuthToken = token
+	}
+	if module, ok := globalConfig["module"].(string); ok && module != "" && !flags.Changed("module") {
+		mlConfig.Module = module
+	}
+	if auditLog, ok := globalConfig["audit_log"].(bool); ok {
+		mlConfig.AuditLog = auditLog
+	}
+	if timeout, ok := globalConfig["shutdown_timeout"].(float64); ok {
+		if timeout <= 0 {
+			return fmt.Errorf("invalid shutdown_timeout: must be greater than 0")
+		}
+		mlConfig.ShutdownTimeout = int(timeout)
+	}
+	if timeout, ok := globalConfig["service_init_timeout"].(float64); ok {
+		if timeout <= 0 {
+			return fmt.Errorf("invalid service_init_timeout: must be greater than 0")
+		}
+		mlConfig.ServiceInitTimeout = int(timeout)
+	}
+	if enabled, ok := globalConfig["metrics_enabled"].(bool); ok {
+		mlConfig.MetricsEnabled = enabled
+	}
+	if addr, ok := globalConfig["metrics_listen_addr"].(string); ok {
+		mlConfig.MetricsListenAddr = addr
+	}
+	if prefixing, ok := globalConfig["tool_name_prefixing"].(bool); ok {
+		mlConfig.ToolNamePrefixing = prefixing
+	}
+	if reload, ok := globalConfig["reload_on_change"].(bool); ok {
+		mlConfig.ReloadOnChange = reload
+	}
+	if disabled, ok := globalConfig["no_prompt_resources"].(bool); ok {
+		mlConfig.NoPromptResources = disabled
+	}
+	for key, target := range map[string]config.Config{
+		"rate_limit":   &mlConfig.RateLimit,
+		"result_limit": &mlConfig.ResultLimit,
+		"plugins":      &mlConfig.Plugins,
+		"session":      &mlConfig.Session,
+		"audit":        &mlConfig.Audit,
+	} {
+		raw, ok := globalConfig[key]
+		if !ok {
+			continue
+		}
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return fmt.Errorf("invalid %s config: %v", key, err)
+		}
+		if err := json.Unmarshal(data, target); err != nil {
+			return fmt.Errorf("invalid %s config: %v", key, err)
+		}
+		if err := target.Check(); err != nil {
+			return fmt.Errorf("invalid %s config: %v", key, err)
+		}
+	}
+	return nil
+}
+
 // initSingleService 初始化单个服务
 func initSingleService(ctx context.Context, serviceType comm.MoLingServerType, serviceFactory abstract.ServiceFactory, configJson map[string]interface{}) (abstract.Service, error) {
 	// 创建服务实例
@@ -126,52 +235,215 @@ func initSingleService(ctx context.Context, serviceType comm.MoLingServerType, s
 			if serviceSettings, ok := rawSettings.(map[string]interface{}); ok {
 				// 将提取的配置加载到服务
 				if err := service.LoadConfig(serviceSettings); err != nil {
-					return nil, fmt.Errorf("failed to load config for service %s: %v", service.Name(), err)
+					_ = service.Close()
+					return nil, presetError(string(serviceType), fmt.Errorf("failed to load config for service %s: %v", service.Name(), err))
 				}
 			}
 		}
 	}
 
-	// 初始化服务
-	if err := service.Init(); err != nil {
-		return nil, fmt.Errorf("failed to initialize service %s: %v", service.Name(), err)
+	// 注册工具，不会启动浏览器等重量级资源，首次调用工具时才启动
+	if err := service.RegisterTools(); err != nil {
+		_ = service.Close()
+		return nil, fmt.Errorf("failed to register tools of service %s: %v", service.Name(), err)
 	}
 	return service, nil
 }
 
-// initServices 批量初始化服务
+// parseModules 解析 --module 的模块列表，名称不区分大小写，返回按服务名规范化的模块和未知的名称。
+// 列表为空或包含 all 时加载全部服务，返回 nil
+func parseModules(module string, known []comm.MoLingServerType) (modules []string, unknown []string) {
+	names := make(map[string]string, len(known))
+	for _, name := range known {
+		names[strings.ToLower(string(name))] = string(name)
+	}
+	for _, item := range utils.SplitAndTrim(module, ",") {
+		if strings.EqualFold(item, "all") {
+			return nil, nil
+		}
+		if name, ok := names[strings.ToLower(item)]; ok {
+			if !utils.StringInSlice(name, modules) {
+				modules = append(modules, name)
+			}
+			continue
+		}
+		unknown = append(unknown, item)
+	}
+	return modules, unknown
+}
+
+// unknownModulesError 列出未知的模块名和可用的模块
+func unknownModulesError(unknown []string, known []comm.MoLingServerType) error {
+	valid := make([]string, 0, len(known))
+	for _, name := range known {
+		valid = append(valid, string(name))
+	}
+	sort.Strings(valid)
+	return fmt.Errorf("unknown module %s, valid modules: %s", strings.Join(unknown, ", "), strings.Join(valid, ", "))
+}
+
+// knownModules 返回内置服务和已发现插件的名称
+func knownModules(found []plugins.Plugin) []comm.MoLingServerType {
+	var known []comm.MoLingServerType
+	for serviceName := range services.ServiceList() {
+		known = append(known, serviceName)
+	}
+	for _, plugin := range found {
+		known = append(known, plugin.Name)
+	}
+	return known
+}
+
+// initServices 批量初始化服务，--module 中有未知的模块名时启动失败
 func initServices(ctx context.Context, configJson map[string]interface{}, logger zerolog.Logger) ([]abstract.Service, map[string]func() error, error) {
-	var moduleList []string
-	if mlConfig.Module != "all" {
-		moduleList = strings.Split(mlConfig.Module, ",")
+	// 插件名也是合法的模块名，先发现插件再校验模块列表
+	var found []plugins.Plugin
+	if mlConfig.Plugins.Enabled {
+		found = discoverPlugins(logger)
+	}
+	known := knownModules(found)
+	moduleList, unknown := parseModules(mlConfig.Module, known)
+	if len(unknown) > 0 {
+		return nil, nil, unknownModulesError(unknown, known)
 	}
 
-	var servicesList []abstract.Service
+	timeout := time.Duration(mlConfig.ServiceInitTimeout) * time.Second
+	servicesList, err := initBuiltinServices(ctx, services.ServiceList(), moduleList, configJson, timeout, logger)
+	if err != nil {
+		return nil, nil, err
+	}
 	closers := make(map[string]func() error)
+	for _, service := range servicesList {
+		closers[string(service.Name())] = service.Close
+	}
+
+	// 加载插件目录中的外部服务
+	for _, service := range initPlugins(ctx, configJson, found, moduleList, logger) {
+		servicesList = append(servicesList, service)
+		closers[string(service.Name())] = service.Close
+	}
+	return servicesList, closers, nil
+}
+
+// errInitTimeout 服务在初始化超时时间内未完成初始化
+var errInitTimeout = errors.New("initialization timed out")
 
-	for serviceName, serviceFactory := range services.ServiceList() {
+// initBuiltinServices 并行初始化 moduleList 中的内置服务，每个服务最多等待 timeout，结果按服务名称排序。
+// 任一服务失败时关闭已初始化的服务，返回的错误包含所有失败的服务
+func initBuiltinServices(ctx context.Context, factories map[comm.MoLingServerType]abstract.ServiceFactory, moduleList []string, configJson map[string]interface{}, timeout time.Duration, logger zerolog.Logger) ([]abstract.Service, error) {
+	var names []comm.MoLingServerType
+	for serviceName := range factories {
 		// 检查模块是否需要加载
-		if len(moduleList) > 0 {
-			// 如果模块列表不为空，则检查模块是否在列表中
-			if !utils.StringInSlice(string(serviceName), moduleList) {
-				logger.
-					Debug().
-					Str("moduleName", string(serviceName)).
-					Msgf("initServices debug, module %s not in %v, skip", string(serviceName), moduleList)
-				continue
-			}
-			logger.Debug().Str("moduleName", string(serviceName)).Msgf("initServices debug, starting %s service", serviceName)
+		if len(moduleList) > 0 && !utils.StringInSlice(string(serviceName), moduleList) {
+			logger.
+				Debug().
+				Str("moduleName", string(serviceName)).
+				Msgf("initServices debug, module %s not in %v, skip", string(serviceName), moduleList)
+			continue
 		}
+		names = append(names, serviceName)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
 
-		// 使用通用的初始化函数
-		service, err := initSingleService(ctx, serviceName, serviceFactory, configJson)
+	servicesList := make([]abstract.Service, len(names))
+	errs := make([]error, len(names))
+	var group errgroup.Group
+	for i, serviceName := range names {
+		group.Go(func() error {
+			logger.Debug().Str("moduleName", string(serviceName)).Msgf("initServices debug, starting %s service", serviceName)
+			start := time.Now()
+			servicesList[i], errs[i] = initServiceWithTimeout(ctx, serviceName, factories[serviceName], configJson, timeout)
+			if errs[i] != nil {
+				logger.Error().Err(errs[i]).Dur("duration", time.Since(start)).Msgf("failed to initialize service %s", serviceName)
+			} else {
+				logger.Debug().Dur("duration", time.Since(start)).Msgf("service %s initialized", serviceName)
+			}
+			// 收集所有服务的错误，不因第一个错误中止
+			return nil
+		})
+	}
+	_ = group.Wait()
+
+	var failed []string
+	for i, err := range errs {
 		if err != nil {
-			logger.Error().Err(err).Msgf("failed to initialize service %s", serviceName)
-			return nil, nil, err
+			failed = append(failed, string(names[i]))
 		}
+	}
+	if len(failed) == 0 {
+		return servicesList, nil
+	}
+	for _, service := range servicesList {
+		if service != nil {
+			_ = service.Close()
+		}
+	}
+	return nil, fmt.Errorf("failed to initialize services %s: %w", strings.Join(failed, ", "), errors.Join(errs...))
+}
 
+// initServiceWithTimeout 初始化单个服务，超过 timeout 时返回 errInitTimeout。服务的 context 在运行期间
+// 一直使用，不能用于超时，超时后才完成初始化的服务会被关闭
+func initServiceWithTimeout(ctx context.Context, serviceType comm.MoLingServerType, serviceFactory abstract.ServiceFactory, configJson map[string]interface{}, timeout time.Duration) (abstract.Service, error) {
+	type initResult struct {
+		service abstract.Service
+		err     error
+	}
+	done := make(chan initResult, 1)
+	go func() {
+		service, err := initSingleService(ctx, serviceType, serviceFactory, configJson)
+		done <- initResult{service, err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case result := <-done:
+		return result.service, result.err
+	case <-timer.C:
+		go func() {
+			if result := <-done; result.err == nil {
+				_ = result.service.Close()
+			}
+		}()
+		return nil, fmt.Errorf("service %s: %w after %s", serviceType, errInitTimeout, timeout)
+	}
+}
+
+// discoverPlugins 发现插件目录中的外部服务，无法加载的插件只记录警告
+func discoverPlugins(logger zerolog.Logger) []plugins.Plugin {
+	dir := mlConfig.Plugins.Dir
+	if dir == "" {
+		dir = filepath.Join(mlConfig.BasePath, "plugins")
+	}
+	var reserved []comm.MoLingServerType
+	for serviceName := range services.ServiceList() {
+		reserved = append(reserved, serviceName)
+	}
+	found, errs := plugins.Discover(dir, reserved, mlConfig.Plugins.MaxRestartBackoff)
+	for _, err := range errs {
+		logger.Warn().Err(err).Msg("failed to load plugin, skipping")
+	}
+	// 插件名同样可用于 module 配置
+	for _, plugin := range found {
+		config.RegisterModule(string(plugin.Name))
+	}
+	return found
+}
+
+// initPlugins 初始化已发现的外部服务，单个插件失败只记录警告，不影响其他服务
+func initPlugins(ctx context.Context, configJson map[string]interface{}, found []plugins.Plugin, moduleList []string, logger zerolog.Logger) []abstract.Service {
+	var servicesList []abstract.Service
+	for _, plugin := range found {
+		if len(moduleList) > 0 && !utils.StringInSlice(string(plugin.Name), moduleList) {
+			continue
+		}
+		service, err := initSingleService(ctx, plugin.Name, plugin.Factory, configJson)
+		if err != nil {
+			logger.Warn().Err(err).Str("plugin", plugin.Path).Msg("failed to initialize plugin, skipping")
+			continue
+		}
+		logger.Info().Str("plugin", plugin.Path).Int("tools", len(service.Tools())).Msgf("plugin %s loaded", plugin.Name)
 		servicesList = append(servicesList, service)
-		closers[string(service.Name())] = service.Close
 	}
-	return servicesList, closers, nil
+	return servicesList
 }
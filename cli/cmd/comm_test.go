@@ -0,0 +1,185 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/services/abstract"
+	"github.com/rs/zerolog"
+)
+
+func TestParseModules(t *testing.T) {
+	known := []comm.MoLingServerType{"Browser", "FileSystem", "Command", "MyPlugin"}
+	tests := []struct {
+		module      string
+		wantModules []string
+		wantUnknown []string
+	}{
+		{"all", nil, nil},
+		{"ALL", nil, nil},
+		{"", nil, nil},
+		{"Browser", []string{"Browser"}, nil},
+		{"browser,FILESYSTEM", []string{"Browser", "FileSystem"}, nil},
+		{" Browser , command ,", []string{"Browser", "Command"}, nil},
+		{"Browser,browser", []string{"Browser"}, nil},
+		{"myplugin", []string{"MyPlugin"}, nil},
+		{"Browser,Filesytem", []string{"Browser"}, []string{"Filesytem"}},
+		{"Foo,Bar", nil, []string{"Foo", "Bar"}},
+	}
+	for _, tt := range tests {
+		modules, unknown := parseModules(tt.module, known)
+		if !reflect.DeepEqual(modules, tt.wantModules) || !reflect.DeepEqual(unknown, tt.wantUnknown) {
+			t.Errorf("parseModules(%q) = %v, %v, want %v, %v", tt.module, modules, unknown, tt.wantModules, tt.wantUnknown)
+		}
+	}
+
+	err := unknownModulesError([]string{"Filesytem"}, known)
+	if want := "unknown module Filesytem, valid modules: Browser, Command, FileSystem, MyPlugin"; err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err)
+	}
+}
+
+func TestInitServicesUnknownModule(t *testing.T) {
+	oldModule := mlConfig.Module
+	defer func() { mlConfig.Module = oldModule }()
+	mlConfig.Module = "Browser,Filesytem"
+
+	_, _, err := initServices(context.Background(), nil, zerolog.Nop())
+	if err == nil || !strings.Contains(err.Error(), "unknown module Filesytem") || !strings.Contains(err.Error(), "FileSystem") {
+		t.Errorf("Expected an unknown module error listing the valid modules, got %v", err)
+	}
+}
+
+// initTestService 初始化测试使用的服务，记录是否已关闭
+type initTestService struct {
+	abstract.MLService
+	name   comm.MoLingServerType
+	closed atomic.Bool
+}
+
+func (s *initTestService) RegisterTools() error { return nil }
+
+func (s *initTestService) Name() comm.MoLingServerType { return s.name }
+
+func (s *initTestService) Close() error {
+	s.closed.Store(true)
+	return nil
+}
+
+func TestInitBuiltinServices(t *testing.T) {
+	var created sync.Map
+	// factory 等待 delay 后创建服务，delay 期间 release 关闭时提前返回
+	factory := func(name comm.MoLingServerType, delay time.Duration, release chan struct{}, err error) abstract.ServiceFactory {
+		return func(ctx context.Context) (abstract.Service, error) {
+			select {
+			case <-time.After(delay):
+			case <-release:
+			}
+			if err != nil {
+				return nil, err
+			}
+			base, berr := abstract.NewServiceBase(ctx, name)
+			if berr != nil {
+				return nil, berr
+			}
+			s := &initTestService{MLService: base, name: name}
+			created.Store(name, s)
+			return s, nil
+		}
+	}
+	names := func(list []abstract.Service) []string {
+		var got []string
+		for _, s := range list {
+			got = append(got, string(s.Name()))
+		}
+		return got
+	}
+	ctx := createContext(zerolog.Nop())
+
+	t.Run("SortedAndParallel", func(t *testing.T) {
+		factories := map[comm.MoLingServerType]abstract.ServiceFactory{
+			"Gamma": factory("Gamma", 200*time.Millisecond, nil, nil),
+			"Alpha": factory("Alpha", 200*time.Millisecond, nil, nil),
+			"Beta":  factory("Beta", 200*time.Millisecond, nil, nil),
+		}
+		start := time.Now()
+		list, err := initBuiltinServices(ctx, factories, nil, nil, 5*time.Second, zerolog.Nop())
+		if err != nil {
+			t.Fatalf("initBuiltinServices failed: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed >= 500*time.Millisecond {
+			t.Errorf("Expected the services to initialize in parallel, took %s", elapsed)
+		}
+		if got := names(list); !reflect.DeepEqual(got, []string{"Alpha", "Beta", "Gamma"}) {
+			t.Errorf("Expected the services sorted by name, got %v", got)
+		}
+
+		list, err = initBuiltinServices(ctx, factories, []string{"Gamma", "Alpha"}, nil, 5*time.Second, zerolog.Nop())
+		if err != nil {
+			t.Fatalf("initBuiltinServices failed: %v", err)
+		}
+		if got := names(list); !reflect.DeepEqual(got, []string{"Alpha", "Gamma"}) {
+			t.Errorf("Expected only the listed modules, got %v", got)
+		}
+	})
+
+	t.Run("AggregatedErrors", func(t *testing.T) {
+		release := make(chan struct{})
+		factories := map[comm.MoLingServerType]abstract.ServiceFactory{
+			"Healthy": factory("Healthy", 0, nil, nil),
+			"Broken":  factory("Broken", 0, nil, errors.New("broken on purpose")),
+			"Hanging": factory("Hanging", time.Hour, release, nil),
+		}
+		list, err := initBuiltinServices(ctx, factories, nil, nil, 100*time.Millisecond, zerolog.Nop())
+		if err == nil || list != nil {
+			t.Fatalf("Expected an error and no services, got %v, %v", list, err)
+		}
+		if !errors.Is(err, errInitTimeout) {
+			t.Errorf("Expected errInitTimeout, got %v", err)
+		}
+		for _, want := range []string{"failed to initialize services Broken, Hanging", "broken on purpose", "service Hanging: initialization timed out"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected %q in the error, got %v", want, err)
+			}
+		}
+		if s, ok := created.Load(comm.MoLingServerType("Healthy")); !ok || !s.(*initTestService).closed.Load() {
+			t.Error("Expected the initialized service to be closed after the failure")
+		}
+
+		// 超时后才完成初始化的服务同样被关闭
+		close(release)
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			if s, ok := created.Load(comm.MoLingServerType("Hanging")); ok && s.(*initTestService).closed.Load() {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("Expected the service finishing after the timeout to be closed")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
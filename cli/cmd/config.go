@@ -28,7 +28,11 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// printSchema 输出各服务配置的 JSON Schema
+var printSchema bool
+
 func init() {
+	configCmd.Flags().BoolVar(&printSchema, "schema", false, "Print the JSON Schema of the configuration of every service instead of the configuration.")
 	rootCmd.AddCommand(configCmd)
 }
 
@@ -37,12 +41,26 @@ var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Show the configuration of the current service list",
 	Long: `Show the configuration of the current service list. You can refer to the configuration file to modify the configuration.
+With --preset, print the effective configuration with the preset merged, without saving it.
 `,
 	RunE: ConfigCommandFunc,
 }
 
 // ConfigCommandFunc executes the "config" command.
 func ConfigCommandFunc(command *cobra.Command, args []string) error {
+	if printSchema {
+		// 标准输出只包含 Schema，日志仅写入文件
+		logger := initLogger(mlConfig.BasePath)
+		mlConfig.SetLogger(logger)
+		return printConfigSchemas(createContext(logger), command)
+	}
+	if preset := selectedPreset(); preset != "" {
+		// 标准输出只包含合并后的配置，日志仅写入文件
+		logger := initLogger(mlConfig.BasePath)
+		mlConfig.SetLogger(logger)
+		return printPresetConfig(createContext(logger), command, preset)
+	}
+
 	// 1. 设置日志
 	logger := setupLogger(mlConfig.BasePath)
 	mlConfig.SetLogger(logger)
@@ -57,6 +75,10 @@ func ConfigCommandFunc(command *cobra.Command, args []string) error {
 		logger.Error().Err(err).Msg("Failed to load config")
 		return err
 	}
+	if err := loadGlobalConfig(existingConfig, command.Flags()); err != nil {
+		logger.Error().Err(err).Msg("Failed to load global config")
+		return err
+	}
 
 	// 4. 构建完整配置(合并全局配置与各服务配置)
 	configData, err := buildConfigData(ctx, existingConfig)
@@ -85,6 +107,52 @@ func ConfigCommandFunc(command *cobra.Command, args []string) error {
 	return nil
 }
 
+// printConfigSchemas 以服务名称为键输出各服务配置的 JSON Schema，没有 Schema 的服务被跳过
+func printConfigSchemas(ctx context.Context, command *cobra.Command) error {
+	schemas := make(map[string]json.RawMessage)
+	for srvName, nsv := range services.ServiceList() {
+		srv, err := nsv(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to create service %s: %v", srvName, err)
+		}
+		if schema := srv.ConfigSchema(); schema != "" {
+			schemas[string(srvName)] = json.RawMessage(schema)
+		}
+		_ = srv.Close()
+	}
+	data, err := json.MarshalIndent(schemas, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling schemas: %v", err)
+	}
+	_, err = fmt.Fprintln(command.OutOrStdout(), string(data))
+	return err
+}
+
+// printPresetConfig 输出预设合并到配置文件后的生效配置，不写入配置文件
+func printPresetConfig(ctx context.Context, command *cobra.Command, preset string) error {
+	existingConfig, _, err := loadExistingConfig(mlConfigFilePath())
+	if err != nil {
+		return err
+	}
+	merged, err := applyPreset(existingConfig, preset)
+	if err != nil {
+		return err
+	}
+	if err := loadGlobalConfig(merged, command.Flags()); err != nil {
+		return err
+	}
+	configData, err := buildConfigData(ctx, merged)
+	if err != nil {
+		return err
+	}
+	formattedJson, err := formatConfigJson(configData)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(command.OutOrStdout(), string(formattedJson))
+	return err
+}
+
 // loadExistingConfig 加载现有配置文件(如果存在)
 func loadExistingConfig(configFilePath string) (map[string]interface{}, bool, error) {
 	// 尝试读取配置文件
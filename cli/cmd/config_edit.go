@@ -0,0 +1,447 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/services"
+	"github.com/gojue/moling/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configSetCmd)
+}
+
+// configValidateCmd 校验配置文件中的每个配置节
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the configuration file without starting the services",
+	Long: `Load the configuration file and check the MoLingConfig section and the section of every service, as
+MoLing does at startup, without starting the services or Chrome. Prints pass or fail with the error for every
+section, and exits with a non-zero code when a section is invalid.
+    moling config validate
+`,
+	Args: cobra.NoArgs,
+	RunE: ConfigValidateCommandFunc,
+}
+
+// configSetCmd 修改配置文件中的一个配置项
+var configSetCmd = &cobra.Command{
+	Use:   "set <Section>.<key> <value>",
+	Short: "Set one key of a section of the configuration file",
+	Long: `Set one key of the MoLingConfig section or of a service section of the configuration file. The value is
+converted to the type of the key: true/false for booleans, a number, a string, a JSON array or a comma separated
+list for arrays, and a JSON object for objects. The section is validated before the file is written, the rest of
+the file is kept as it is.
+    moling config set Browser.headless true
+    moling config set Command.allowed_command ls,cat,echo,git
+    moling config set FileSystem.allowed_dirs '["/tmp", "/data"]'
+`,
+	Args: cobra.ExactArgs(2),
+	RunE: ConfigSetCommandFunc,
+}
+
+const globalSection = "MoLingConfig"
+
+// sectionResult 一个配置节的校验结果
+type sectionResult struct {
+	Name    string
+	Err     error
+	Default bool // 配置文件中没有该配置节，校验的是默认配置
+	Skipped bool // 不是内置服务的配置节，如插件
+}
+
+// ConfigValidateCommandFunc executes the "config validate" command.
+func ConfigValidateCommandFunc(command *cobra.Command, args []string) error {
+	// 标准输出只包含校验结果，日志仅写入文件
+	logger := initLogger(mlConfig.BasePath)
+	mlConfig.SetLogger(logger)
+
+	configFilePath := mlConfigFilePath()
+	existingConfig, hasConfig, err := loadExistingConfig(configFilePath)
+	if err != nil {
+		return fmt.Errorf("%s: %w", configFilePath, err)
+	}
+	if !hasConfig {
+		return fmt.Errorf("config file %s does not exist, run moling config to create it", configFilePath)
+	}
+
+	results := validateConfig(createContext(logger), existingConfig)
+	if failed := printValidation(command.OutOrStdout(), configFilePath, results); failed > 0 {
+		return fmt.Errorf("%d invalid section(s) in %s", failed, configFilePath)
+	}
+	return nil
+}
+
+// validateConfig 校验 MoLingConfig 和每个服务的配置节，只创建服务并加载配置，不调用 Init
+func validateConfig(ctx context.Context, configJson map[string]interface{}) []sectionResult {
+	results := []sectionResult{validateGlobalSection(configJson)}
+	known := map[string]bool{globalSection: true}
+	for srvName := range services.ServiceList() {
+		name := string(srvName)
+		known[name] = true
+		section, exists := configJson[name]
+		result := sectionResult{Name: name, Default: !exists}
+		result.Err = validateServiceSection(ctx, srvName, section)
+		results = append(results, result)
+	}
+	for name := range configJson {
+		if !known[name] {
+			results = append(results, sectionResult{Name: name, Skipped: true})
+		}
+	}
+	sort.SliceStable(results[1:], func(i, j int) bool { return results[i+1].Name < results[j+1].Name })
+	return results
+}
+
+// validateGlobalSection 将 MoLingConfig 配置节合并到当前配置的副本并校验
+func validateGlobalSection(configJson map[string]interface{}) sectionResult {
+	result := sectionResult{Name: globalSection}
+	raw, exists := configJson[globalSection]
+	if !exists {
+		result.Default = true
+		raw = map[string]interface{}{}
+	}
+	section, ok := raw.(map[string]interface{})
+	if !ok {
+		result.Err = fmt.Errorf("must be an object, got %T", raw)
+		return result
+	}
+	cfg := *mlConfig
+	if err := utils.MergeJSONToStruct(&cfg, section); err != nil {
+		result.Err = err
+		return result
+	}
+	result.Err = cfg.Check()
+	return result
+}
+
+// validateServiceSection 创建服务并加载配置节，LoadConfig 会调用配置的 Check。section 为 nil 时校验默认配置
+func validateServiceSection(ctx context.Context, srvName comm.MoLingServerType, section interface{}) error {
+	nsv, ok := services.ServiceList()[srvName]
+	if !ok {
+		return fmt.Errorf("unknown service %s", srvName)
+	}
+	srv, err := nsv(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create service %s: %w", srvName, err)
+	}
+	defer func() { _ = srv.Close() }()
+	if section == nil {
+		return nil
+	}
+	settings, ok := section.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("must be an object, got %T", section)
+	}
+	return srv.LoadConfig(settings)
+}
+
+// printValidation 输出每个配置节的校验结果，返回无效配置节的数量
+func printValidation(out io.Writer, configFilePath string, results []sectionResult) int {
+	_, _ = fmt.Fprintf(out, "Validating %s\n", configFilePath)
+	failed := 0
+	for _, result := range results {
+		status := "ok"
+		switch {
+		case result.Skipped:
+			status = "skipped, not a built-in service"
+		case result.Err != nil:
+			failed++
+			status = "FAIL: " + result.Err.Error()
+		case result.Default:
+			status = "ok (not in the file, defaults)"
+		}
+		_, _ = fmt.Fprintf(out, "  %-14s %s\n", result.Name, status)
+	}
+	return failed
+}
+
+// ConfigSetCommandFunc executes the "config set" command.
+func ConfigSetCommandFunc(command *cobra.Command, args []string) error {
+	logger := initLogger(mlConfig.BasePath)
+	mlConfig.SetLogger(logger)
+	ctx := createContext(logger)
+
+	section, key, err := parseConfigPath(args[0])
+	if err != nil {
+		return err
+	}
+	configFilePath := mlConfigFilePath()
+	data, err := os.ReadFile(configFilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("config file %s does not exist, run moling config to create it", configFilePath)
+	}
+	if err != nil {
+		return err
+	}
+	var configJson map[string]interface{}
+	if err := json.Unmarshal(data, &configJson); err != nil {
+		return fmt.Errorf("invalid JSON in config file %s: %w", configFilePath, err)
+	}
+
+	defaults, err := sectionDefaults(ctx, section)
+	if err != nil {
+		return err
+	}
+	defaultValue, known := defaults[key]
+	if !known {
+		keys := make([]string, 0, len(defaults))
+		for k := range defaults {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return fmt.Errorf("unknown key %s of %s, known keys: %s", key, section, strings.Join(keys, ", "))
+	}
+	value, err := coerceConfigValue(args[1], defaultValue)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s.%s: %w", section, key, err)
+	}
+
+	// 写入前校验修改后的配置节
+	settings, _ := configJson[section].(map[string]interface{})
+	updated := make(map[string]interface{}, len(settings)+1)
+	for k, v := range settings {
+		updated[k] = v
+	}
+	updated[key] = value
+	configJson[section] = updated
+	if section == globalSection {
+		err = validateGlobalSection(configJson).Err
+	} else {
+		err = validateServiceSection(ctx, comm.MoLingServerType(section), updated)
+	}
+	if err != nil {
+		return fmt.Errorf("%s.%s=%s is invalid, the config file is unchanged: %w", section, key, args[1], err)
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	edited, err := setJSONMember(data, section, key, encoded)
+	if err != nil {
+		return fmt.Errorf("failed to edit %s: %w", configFilePath, err)
+	}
+	info, err := os.Stat(configFilePath)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(configFilePath, edited, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("error writing configuration file: %w", err)
+	}
+	_, _ = fmt.Fprintf(command.OutOrStdout(), "Set %s.%s to %s in %s\n", section, key, encoded, configFilePath)
+	return nil
+}
+
+// parseConfigPath 解析 <Section>.<key>，配置节名称不区分大小写，返回规范化的名称
+func parseConfigPath(path string) (string, string, error) {
+	section, key, ok := strings.Cut(path, ".")
+	if !ok || section == "" || key == "" {
+		return "", "", fmt.Errorf("invalid key %q, use <Section>.<key>, e.g. Browser.headless", path)
+	}
+	if strings.Contains(key, ".") {
+		return "", "", fmt.Errorf("invalid key %q, nested keys are not supported, set the whole object as JSON", path)
+	}
+	names := []string{globalSection}
+	for srvName := range services.ServiceList() {
+		names = append(names, string(srvName))
+	}
+	for _, name := range names {
+		if strings.EqualFold(name, section) {
+			return name, key, nil
+		}
+	}
+	sort.Strings(names[1:])
+	return "", "", fmt.Errorf("unknown section %s, known sections: %s", section, strings.Join(names, ", "))
+}
+
+// sectionDefaults 返回配置节的默认配置，用于确定配置项是否存在及其类型
+func sectionDefaults(ctx context.Context, section string) (map[string]interface{}, error) {
+	var raw []byte
+	if section == globalSection {
+		data, err := json.Marshal(mlConfig)
+		if err != nil {
+			return nil, err
+		}
+		raw = data
+	} else {
+		srv, err := services.ServiceList()[comm.MoLingServerType(section)](ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create service %s: %w", section, err)
+		}
+		raw = []byte(srv.Config())
+		_ = srv.Close()
+	}
+	var defaults map[string]interface{}
+	if err := json.Unmarshal(raw, &defaults); err != nil {
+		return nil, fmt.Errorf("invalid default config of %s: %w", section, err)
+	}
+	return defaults, nil
+}
+
+// coerceConfigValue 按默认值的类型转换命令行中的值
+func coerceConfigValue(raw string, defaultValue interface{}) (interface{}, error) {
+	switch defaultValue.(type) {
+	case bool:
+		return strconv.ParseBool(raw)
+	case float64:
+		return strconv.ParseFloat(raw, 64)
+	case string:
+		return raw, nil
+	case []interface{}:
+		if strings.HasPrefix(strings.TrimSpace(raw), "[") {
+			var list []interface{}
+			if err := json.Unmarshal([]byte(raw), &list); err != nil {
+				return nil, fmt.Errorf("invalid JSON array: %w", err)
+			}
+			return list, nil
+		}
+		list := make([]interface{}, 0)
+		for _, item := range utils.SplitAndTrim(raw, ",") {
+			list = append(list, item)
+		}
+		return list, nil
+	case map[string]interface{}:
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &object); err != nil {
+			return nil, fmt.Errorf("invalid JSON object: %w", err)
+		}
+		return object, nil
+	}
+	// 默认值为 null，可以是任意 JSON 值，不是 JSON 时作为字符串
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return raw, nil
+	}
+	return value, nil
+}
+
+// jsonObject 对象在 JSON 文本中的位置
+type jsonObject struct {
+	start   int               // { 的位置
+	end     int               // } 的位置
+	members map[string][2]int // 成员值的起止位置
+	last    int               // 最后一个成员值的结束位置，没有成员时为 -1
+}
+
+// parseJSONObject 解析 data[start:] 处的对象，记录每个成员值在 data 中的位置
+func parseJSONObject(data []byte, start int) (jsonObject, error) {
+	for start < len(data) && isJSONSpace(data[start]) {
+		start++
+	}
+	obj := jsonObject{start: start, members: make(map[string][2]int), last: -1}
+	dec := json.NewDecoder(bytes.NewReader(data[start:]))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return obj, errors.New("not a JSON object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return obj, err
+		}
+		key, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return obj, err
+		}
+		end := start + int(dec.InputOffset())
+		obj.members[key] = [2]int{end - len(value), end}
+		obj.last = end
+	}
+	if _, err := dec.Token(); err != nil {
+		return obj, err
+	}
+	obj.end = start + int(dec.InputOffset()) - 1
+	return obj, nil
+}
+
+// setJSONMember 将 data 中 section 配置节的 key 设为 value，其余内容保持不变。
+// 新的配置项或配置节按相邻成员的缩进插入
+func setJSONMember(data []byte, section, key string, value []byte) ([]byte, error) {
+	root, err := parseJSONObject(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	span, exists := root.members[section]
+	if !exists {
+		indent := memberIndent(data, root, "  ")
+		object := fmt.Sprintf("{\n%s%s%q: %s\n%s}", indent, indent, key, value, indent)
+		return insertJSONMember(data, root, section, []byte(object), indent), nil
+	}
+	obj, err := parseJSONObject(data, span[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", section, err)
+	}
+	if member, ok := obj.members[key]; ok {
+		return splice(data, member[0], member[1], value), nil
+	}
+	indent := memberIndent(data, obj, lineIndent(data, obj.start)+memberIndent(data, root, "  "))
+	return insertJSONMember(data, obj, key, value, indent), nil
+}
+
+// insertJSONMember 在对象的最后一个成员之后插入 "key": value
+func insertJSONMember(data []byte, obj jsonObject, key string, value []byte, indent string) []byte {
+	member := fmt.Sprintf("%s%q: %s", indent, key, value)
+	if obj.last < 0 {
+		return splice(data, obj.start+1, obj.end, []byte("\n"+member+"\n"+lineIndent(data, obj.start)))
+	}
+	return splice(data, obj.last, obj.last, []byte(",\n"+member))
+}
+
+// memberIndent 返回对象成员所在行的缩进，对象没有成员或成员与 { 在同一行时返回 fallback
+func memberIndent(data []byte, obj jsonObject, fallback string) string {
+	if obj.last < 0 || bytes.IndexByte(data[obj.start:obj.last], '\n') < 0 {
+		return fallback
+	}
+	return lineIndent(data, obj.last)
+}
+
+// lineIndent 返回 pos 所在行开头的空白
+func lineIndent(data []byte, pos int) string {
+	lineStart := bytes.LastIndexByte(data[:pos], '\n') + 1
+	end := lineStart
+	for end < len(data) && (data[end] == ' ' || data[end] == '\t') {
+		end++
+	}
+	return string(data[lineStart:end])
+}
+
+// splice 将 data[start:end] 替换为 replacement
+func splice(data []byte, start, end int, replacement []byte) []byte {
+	out := make([]byte, 0, len(data)-(end-start)+len(replacement))
+	out = append(out, data[:start]...)
+	out = append(out, replacement...)
+	return append(out, data[end:]...)
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
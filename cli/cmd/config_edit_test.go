@@ -0,0 +1,161 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// configEditTestConfig 配置文件，Browser 配置节使用单行格式以检查其余内容保持不变
+const configEditTestConfig = `{
+    "MoLingConfig": {
+        "base_path": %q,
+        "version": "test",
+        "module": "all"
+    },
+    "Browser": {"headless": true, "timeout": %d},
+    "Command": {
+        "allowed_command": "ls,cat"
+    }
+}
+`
+
+// setupConfigEdit 在临时目录中写入配置文件，返回配置文件路径
+func setupConfigEdit(t *testing.T, browserTimeout int) string {
+	t.Helper()
+	restoreGlobalConfig(t)
+	basePath := t.TempDir()
+	for _, dir := range []string{"logs", "config"} {
+		if err := os.MkdirAll(filepath.Join(basePath, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configFile := filepath.Join(basePath, "config", MLConfigName)
+	if err := os.WriteFile(configFile, []byte(fmt.Sprintf(configEditTestConfig, basePath, browserTimeout)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	oldBasePath := mlConfig.BasePath
+	t.Cleanup(func() { mlConfig.BasePath = oldBasePath })
+	mlConfig.BasePath = basePath
+	return configFile
+}
+
+// runConfigSubcommand 运行 config 的子命令，返回标准输出和错误
+func runConfigSubcommand(run func(*cobra.Command, []string) error, args ...string) (string, error) {
+	var stdout bytes.Buffer
+	command := &cobra.Command{}
+	command.SetOut(&stdout)
+	err := run(command, args)
+	return stdout.String(), err
+}
+
+func TestConfigValidate(t *testing.T) {
+	setupConfigEdit(t, 30)
+	out, err := runConfigSubcommand(ConfigValidateCommandFunc)
+	if err != nil {
+		t.Fatalf("Expected a valid config, got %v\n%s", err, out)
+	}
+	for _, line := range []string{"  MoLingConfig   ok\n", "  Browser        ok\n", "  Command        ok\n", "  FileSystem     ok (not in the file, defaults)\n"} {
+		if !strings.Contains(out, line) {
+			t.Errorf("Expected %q in the output, got:\n%s", line, out)
+		}
+	}
+
+	setupConfigEdit(t, 0)
+	out, err = runConfigSubcommand(ConfigValidateCommandFunc)
+	if err == nil || !strings.Contains(err.Error(), "1 invalid section(s)") {
+		t.Errorf("Expected one invalid section, got %v", err)
+	}
+	if !strings.Contains(out, "  Browser        FAIL: timeout must be greater than 0\n") || !strings.Contains(out, "  Command        ok\n") {
+		t.Errorf("Expected the Browser timeout to fail, got:\n%s", out)
+	}
+}
+
+func TestConfigSet(t *testing.T) {
+	configFile := setupConfigEdit(t, 30)
+	original, _ := os.ReadFile(configFile)
+
+	if _, err := runConfigSubcommand(ConfigSetCommandFunc, "command.allowed_command", "ls,cat,git"); err != nil {
+		t.Fatalf("config set failed: %v", err)
+	}
+	if _, err := runConfigSubcommand(ConfigSetCommandFunc, "Command.allow_rules", `["echo", {"cmd": "go", "args": ["test"]}]`); err != nil {
+		t.Fatalf("config set failed: %v", err)
+	}
+	if _, err := runConfigSubcommand(ConfigSetCommandFunc, "Browser.headless", "false"); err != nil {
+		t.Fatalf("config set failed: %v", err)
+	}
+	// 只有修改的值变化，新的配置项按相邻成员的缩进插入
+	want := strings.Replace(string(original), `"allowed_command": "ls,cat"`, `"allowed_command": "ls,cat,git",
+        "allow_rules": ["echo",{"args":["test"],"cmd":"go"}]`, 1)
+	want = strings.Replace(want, `{"headless": true,`, `{"headless": false,`, 1)
+	if data, _ := os.ReadFile(configFile); string(data) != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, data)
+	}
+	if out, err := runConfigSubcommand(ConfigValidateCommandFunc); err != nil {
+		t.Errorf("Expected the edited config to be valid, got %v\n%s", err, out)
+	}
+
+	// 无效的值不写入配置文件
+	before, _ := os.ReadFile(configFile)
+	for args, wantErr := range map[[2]string]string{
+		{"Browser.timeout", "0"}:         "timeout must be greater than 0",
+		{"Browser.headless", "maybe"}:    "invalid value for Browser.headless",
+		{"Browser.nope", "1"}:            "unknown key nope of Browser",
+		{"Nope.key", "1"}:                "unknown section Nope",
+		{"Command", "ls"}:                "use <Section>.<key>",
+		{"Browser.ocr.engine", "tesser"}: "nested keys are not supported",
+	} {
+		if _, err := runConfigSubcommand(ConfigSetCommandFunc, args[0], args[1]); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("%s=%s: expected %q, got %v", args[0], args[1], wantErr, err)
+		}
+	}
+	if data, _ := os.ReadFile(configFile); string(data) != string(before) {
+		t.Errorf("Expected the config file to be unchanged, got:\n%s", data)
+	}
+}
+
+func TestSetJSONMember(t *testing.T) {
+	for name, tc := range map[string]struct{ in, section, key, value, want string }{
+		"NewSection": {
+			in:      "{\n\t\"A\": {\n\t\t\"x\": 1\n\t}\n}\n",
+			section: "B", key: "y", value: "true",
+			want: "{\n\t\"A\": {\n\t\t\"x\": 1\n\t},\n\t\"B\": {\n\t\t\"y\": true\n\t}\n}\n",
+		},
+		"EmptySection": {
+			in:      "{\n  \"A\": {}\n}",
+			section: "A", key: "x", value: `"v"`,
+			want: "{\n  \"A\": {\n    \"x\": \"v\"\n  }\n}",
+		},
+		"Replace": {
+			in:      `{"A": {"x": [1, 2], "y": {"z": 1}}}`,
+			section: "A", key: "y", value: `{}`,
+			want: `{"A": {"x": [1, 2], "y": {}}}`,
+		},
+	} {
+		got, err := setJSONMember([]byte(tc.in), tc.section, tc.key, []byte(tc.value))
+		if err != nil || string(got) != tc.want {
+			t.Errorf("%s: expected %q, got %q (%v)", name, tc.want, got, err)
+		}
+	}
+}
@@ -0,0 +1,524 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package cmd
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gojue/moling/pkg/server"
+	"github.com/gojue/moling/pkg/services/browser"
+	"github.com/gojue/moling/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	debugBundleCmd.Flags().IntVar(&debugBundleLogKB, "log-kb", 256, "Include the last N KB of each log file")
+	debugBundleCmd.Flags().StringArrayVar(&debugBundleRedact, "redact", nil, "Additional regular expression to redact from every file, may be repeated. A named group (?P<value>...) redacts only that group")
+	debugBundleCmd.Flags().BoolVar(&debugBundleConfigRaw, "include-config-raw", false, "Include the config without redaction, for local debugging only")
+	rootCmd.AddCommand(debugBundleCmd)
+}
+
+// debugBundleCmd 生成用于问题反馈的诊断包
+var debugBundleCmd = &cobra.Command{
+	Use:   "debug-bundle",
+	Short: "Write a zip with the config, logs and environment for support requests",
+	Long: `Write moling-debug-<time>.zip to the current directory, containing the effective config, the end of
+the log files, the metrics, the environment (OS, Go and Chrome version), the services and tools, and
+the PID file state. Tokens, cookies, passwords and other secrets are redacted from every file.
+    moling debug-bundle
+    moling debug-bundle --log-kb 1024 --redact 'corp-[0-9a-f]{32}'
+`,
+	RunE: DebugBundleCommandFunc,
+}
+
+var (
+	debugBundleLogKB     int
+	debugBundleRedact    []string
+	debugBundleConfigRaw bool
+)
+
+const (
+	redactedValue       = "[REDACTED]"
+	debugProbeTimeout   = 5 * time.Second
+	debugBundleTimeFmt  = "20060102-150405"
+	debugBundleManifest = "manifest.json"
+)
+
+// sensitiveKeyPattern 匹配配置中的敏感字段名，其值(包括嵌套的值)被整体替换
+var sensitiveKeyPattern = regexp.MustCompile(`(?i)token|secret|passw(or)?d|cookie|api[_-]?key|authorization|credential|private[_-]?key`)
+
+// defaultRedactPatterns 对诊断包中每个文件生效的默认脱敏规则，命名分组 value 存在时只替换该分组
+var defaultRedactPatterns = []string{
+	// JSON 中敏感字段的字符串值，如 {"http_token":"..."}
+	`(?i)"[^"]*(?:token|secret|passw(?:or)?d|cookie|api[_-]?key|authorization|credential)[^"]*"\s*:\s*"(?P<value>(?:[^"\\]|\\.)*)"`,
+	// 日志和 URL 中的 key=value
+	`(?i)\b[\w.-]*(?:token|secret|passw(?:or)?d|api[_-]?key)[\w.-]*\s*[=:]\s*(?P<value>[^\s"',;&]+)`,
+	// HTTP 头
+	`(?i)\b(?:proxy-)?authorization\s*:\s*(?P<value>[^\r\n"]+)`,
+	`(?i)\b(?:set-)?cookie\s*:\s*(?P<value>[^\r\n"]+)`,
+	`(?i)\bbearer\s+(?P<value>[A-Za-z0-9._~+/=-]+)`,
+	// URL 中的用户名密码
+	`://[^/\s:@]+:(?P<value>[^/\s@]+)@`,
+	// JWT
+	`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`,
+}
+
+// redactor 按正则列表替换文件中的敏感内容
+type redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// newRedactor 编译默认规则和 extra 中的规则
+func newRedactor(extra []string) (*redactor, error) {
+	r := &redactor{}
+	for _, pattern := range append(append([]string{}, defaultRedactPatterns...), extra...) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// Redact 返回替换敏感内容后的数据
+func (r *redactor) Redact(data []byte) []byte {
+	for _, re := range r.patterns {
+		group := re.SubexpIndex("value")
+		data = re.ReplaceAllFunc(data, func(match []byte) []byte {
+			if group < 0 {
+				return []byte(redactedValue)
+			}
+			// 只替换 value 分组，保留字段名等上下文
+			loc := re.FindSubmatchIndex(match)
+			if loc == nil || loc[2*group] < 0 {
+				return match
+			}
+			out := append([]byte{}, match[:loc[2*group]]...)
+			out = append(out, redactedValue...)
+			return append(out, match[loc[2*group+1]:]...)
+		})
+	}
+	return data
+}
+
+// redactConfig 替换配置中敏感字段的值，布尔值不含敏感信息而保留，便于排查开关
+func redactConfig(value interface{}, sensitive bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			out[key] = redactConfig(item, sensitive || sensitiveKeyPattern.MatchString(key))
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = redactConfig(item, sensitive)
+		}
+		return out
+	case bool, nil:
+		return v
+	default:
+		if sensitive {
+			return redactedValue
+		}
+		return v
+	}
+}
+
+// bundleEntry 诊断包中的一个文件，或未包含的文件及原因
+type bundleEntry struct {
+	Name     string `json:"name"`
+	Size     int    `json:"size,omitempty"`
+	Redacted bool   `json:"redacted,omitempty"`
+	Note     string `json:"note,omitempty"`
+}
+
+// bundleManifest 诊断包的内容清单，写入 manifest.json
+type bundleManifest struct {
+	CreatedAt time.Time     `json:"created_at"`
+	Version   string        `json:"version"`
+	Included  []bundleEntry `json:"included"`
+	Excluded  []bundleEntry `json:"excluded"`
+}
+
+// debugBundle 写入诊断包，每个文件都经过脱敏
+type debugBundle struct {
+	zw       *zip.Writer
+	redactor *redactor
+	manifest bundleManifest
+}
+
+// add 写入文件，raw 为 true 时跳过脱敏
+func (b *debugBundle) add(name string, data []byte, raw bool, note string) error {
+	if !raw {
+		data = b.redactor.Redact(data)
+	}
+	w, err := b.zw.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	b.manifest.Included = append(b.manifest.Included, bundleEntry{Name: name, Size: len(data), Redacted: !raw, Note: note})
+	return nil
+}
+
+// addJSON 以缩进格式写入 JSON 文件
+func (b *debugBundle) addJSON(name string, value interface{}, raw bool, note string) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", name, err)
+	}
+	return b.add(name, data, raw, note)
+}
+
+// exclude 记录未包含的文件及原因
+func (b *debugBundle) exclude(name string, reason error) {
+	b.manifest.Excluded = append(b.manifest.Excluded, bundleEntry{Name: name, Note: reason.Error()})
+}
+
+// DebugBundleCommandFunc executes the "debug-bundle" command.
+func DebugBundleCommandFunc(command *cobra.Command, args []string) error {
+	if debugBundleLogKB < 0 {
+		return fmt.Errorf("invalid --log-kb: must not be negative")
+	}
+	redact, err := newRedactor(debugBundleRedact)
+	if err != nil {
+		return err
+	}
+
+	// 日志只写入文件，保持标准输出干净
+	logger := initLogger(mlConfig.BasePath)
+	mlConfig.SetLogger(logger)
+	ctx := createContext(logger)
+
+	now := time.Now()
+	bundlePath, err := filepath.Abs(fmt.Sprintf("moling-debug-%s.zip", now.Format(debugBundleTimeFmt)))
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(bundlePath)
+	if err != nil {
+		return fmt.Errorf("failed to create debug bundle: %w", err)
+	}
+	b := &debugBundle{
+		zw:       zip.NewWriter(f),
+		redactor: redact,
+		manifest: bundleManifest{CreatedAt: now, Version: GitVersion, Included: []bundleEntry{}, Excluded: []bundleEntry{}},
+	}
+	err = writeDebugBundle(ctx, command, b)
+	if err == nil {
+		err = b.addJSON(debugBundleManifest, b.manifest, false, "")
+	}
+	if closeErr := b.zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(bundlePath)
+		return fmt.Errorf("failed to write debug bundle: %w", err)
+	}
+	logger.Info().Str("bundle", bundlePath).Msg("debug bundle written")
+	return printBundleSummary(command.OutOrStdout(), bundlePath, b.manifest)
+}
+
+// writeDebugBundle 写入诊断包的各个文件，可选内容缺失时记录到 excluded 而不失败
+func writeDebugBundle(ctx context.Context, command *cobra.Command, b *debugBundle) error {
+	configJson, effective, err := effectiveConfig(ctx, command)
+	if err != nil {
+		b.exclude("config.json", err)
+	} else {
+		note := ""
+		if debugBundleConfigRaw {
+			note = "not redacted (--include-config-raw)"
+			_, _ = fmt.Fprintln(command.ErrOrStderr(), "warning: the config is included without redaction, do not share this bundle")
+		} else {
+			effective = redactConfig(effective, false).(map[string]interface{})
+		}
+		if err := b.addJSON("config.json", effective, debugBundleConfigRaw, note); err != nil {
+			return err
+		}
+	}
+
+	if err := addLogTails(b); err != nil {
+		return err
+	}
+
+	if metrics, err := fetchMetrics(ctx); err != nil {
+		b.exclude("metrics.txt", err)
+	} else if err := b.add("metrics.txt", metrics, false, ""); err != nil {
+		return err
+	}
+
+	if err := b.addJSON("status.json", processStatus(), false, ""); err != nil {
+		return err
+	}
+	if err := b.addJSON("environment.json", environmentInfo(ctx), false, ""); err != nil {
+		return err
+	}
+
+	// 配置无效时 configJson 为 nil，collectCatalog 使用默认配置
+	return b.addJSON("services.json", collectCatalog(ctx, configJson), false, "")
+}
+
+// effectiveConfig 返回合并预设后的配置文件内容，以及包含默认值的生效配置，与 moling config --preset 的输出一致
+func effectiveConfig(ctx context.Context, command *cobra.Command) (map[string]interface{}, map[string]interface{}, error) {
+	existingConfig, _, err := loadExistingConfig(mlConfigFilePath())
+	if err != nil {
+		return nil, nil, err
+	}
+	merged, err := applyPreset(existingConfig, selectedPreset())
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := loadGlobalConfig(merged, command.Flags()); err != nil {
+		return nil, nil, err
+	}
+	configData, err := buildConfigData(ctx, merged)
+	if err != nil {
+		return nil, nil, err
+	}
+	var effective map[string]interface{}
+	if err := json.Unmarshal([]byte(configData), &effective); err != nil {
+		return nil, nil, fmt.Errorf("invalid effective config: %w", err)
+	}
+	return merged, effective, nil
+}
+
+// addLogTails 写入 logs 目录下每个日志文件(包括轮转文件和审计日志)的最后 --log-kb KB
+func addLogTails(b *debugBundle) error {
+	logDir := filepath.Dir(logFilePath(mlConfig.BasePath))
+	entries, err := os.ReadDir(logDir)
+	if err != nil {
+		b.exclude("logs/", err)
+		return nil
+	}
+	limit := int64(debugBundleLogKB) * 1024
+	var found bool
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		found = true
+		name := "logs/" + entry.Name()
+		data, truncated, err := readTail(filepath.Join(logDir, entry.Name()), limit)
+		if err != nil {
+			b.exclude(name, err)
+			continue
+		}
+		note := ""
+		if truncated {
+			note = fmt.Sprintf("last %d KB", debugBundleLogKB)
+		}
+		if err := b.add(name, data, false, note); err != nil {
+			return err
+		}
+	}
+	if !found {
+		b.exclude("logs/", fmt.Errorf("no log files in %s", logDir))
+	}
+	return nil
+}
+
+// readTail 读取文件的最后 limit 字节，截断时丢弃第一行不完整的内容
+func readTail(path string, limit int64) ([]byte, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+	if info.Size() <= limit {
+		data, err := io.ReadAll(f)
+		return data, false, err
+	}
+	if _, err := f.Seek(info.Size()-limit, io.SeekStart); err != nil {
+		return nil, false, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, false, err
+	}
+	if i := strings.IndexByte(string(data), '\n'); i >= 0 {
+		data = data[i+1:]
+	}
+	return data, true, nil
+}
+
+// fetchMetrics 从运行中的实例获取 /metrics，只有 sse/http 传输且开启 metrics_enabled 时可用
+func fetchMetrics(ctx context.Context) ([]byte, error) {
+	if !mlConfig.MetricsEnabled {
+		return nil, errors.New("metrics_enabled is false")
+	}
+	addr, token := mlConfig.MetricsListenAddr, ""
+	if addr == "" {
+		// 与 MCP 端点共用地址时 /metrics 需要 auth_token
+		addr, token = mlConfig.ListenAddr, mlConfig.AuthToken
+	}
+	if addr == "" {
+		return nil, errors.New("metrics are only served by the sse and http transports, listen_addr is empty")
+	}
+	ctx, cancel := context.WithTimeout(ctx, debugProbeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr+server.MetricsPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch metrics: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch metrics: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// bundleStatus 实例的运行状态，来自 PID 文件和配置
+type bundleStatus struct {
+	BasePath          string `json:"base_path"`
+	PIDFile           string `json:"pid_file"`
+	PID               int    `json:"pid,omitempty"`
+	State             string `json:"state"` // running, stale (PID 文件未被锁定) 或 not running
+	Error             string `json:"error,omitempty"`
+	Mode              string `json:"mode"`
+	ListenAddr        string `json:"listen_addr,omitempty"`
+	Module            string `json:"module"`
+	MetricsEnabled    bool   `json:"metrics_enabled"`
+	MetricsListenAddr string `json:"metrics_listen_addr,omitempty"`
+}
+
+// processStatus 读取 PID 文件判断实例是否在运行
+func processStatus() bundleStatus {
+	status := bundleStatus{
+		BasePath:          mlConfig.BasePath,
+		PIDFile:           filepath.Join(mlConfig.BasePath, MLPidName),
+		Mode:              "stdio",
+		ListenAddr:        mlConfig.ListenAddr,
+		Module:            mlConfig.Module,
+		MetricsEnabled:    mlConfig.MetricsEnabled,
+		MetricsListenAddr: mlConfig.MetricsListenAddr,
+	}
+	if mode, err := server.ResolveTransport(mlConfig.TransportMode, mlConfig.ListenAddr); err == nil {
+		status.Mode = mode
+	} else {
+		status.Mode = mlConfig.TransportMode
+	}
+	pid, running, err := utils.ReadPIDFile(status.PIDFile)
+	status.PID = pid
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		status.State = "not running"
+	case err != nil:
+		status.State = "unknown"
+		status.Error = err.Error()
+	case running:
+		status.State = "running"
+	default:
+		status.State = "stale"
+	}
+	return status
+}
+
+// bundleEnvironment 运行环境，Chrome 路径与浏览器服务使用相同的查找逻辑
+type bundleEnvironment struct {
+	Version       string `json:"version"`
+	OS            string `json:"os"`
+	Arch          string `json:"arch"`
+	GoVersion     string `json:"go_version"`
+	NumCPU        int    `json:"num_cpu"`
+	ChromePath    string `json:"chrome_path,omitempty"`
+	ChromeVersion string `json:"chrome_version,omitempty"`
+	ChromeError   string `json:"chrome_error,omitempty"`
+}
+
+// environmentInfo 收集运行环境，Chrome 未安装时记录原因
+func environmentInfo(ctx context.Context) bundleEnvironment {
+	env := bundleEnvironment{
+		Version:   GitVersion,
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		GoVersion: runtime.Version(),
+		NumCPU:    runtime.NumCPU(),
+	}
+	path, err := browser.FindChrome()
+	if err != nil {
+		env.ChromeError = err.Error()
+		return env
+	}
+	env.ChromePath = path
+	ctx, cancel := context.WithTimeout(ctx, debugProbeTimeout)
+	defer cancel()
+	if env.ChromeVersion, err = browser.ChromeVersion(ctx, path); err != nil {
+		env.ChromeError = err.Error()
+	}
+	return env
+}
+
+// printBundleSummary 输出诊断包路径以及包含和未包含的文件
+func printBundleSummary(out io.Writer, bundlePath string, manifest bundleManifest) error {
+	_, _ = fmt.Fprintf(out, "Debug bundle written to %s\n", bundlePath)
+	_, _ = fmt.Fprintln(out, "Included:")
+	for _, entry := range manifest.Included {
+		var notes []string
+		if entry.Note != "" {
+			notes = append(notes, entry.Note)
+		}
+		if entry.Redacted {
+			notes = append(notes, "redacted")
+		}
+		line := fmt.Sprintf("  %s (%d bytes)", entry.Name, entry.Size)
+		if len(notes) > 0 {
+			line += ", " + strings.Join(notes, ", ")
+		}
+		_, _ = fmt.Fprintln(out, line)
+	}
+	if len(manifest.Excluded) > 0 {
+		_, _ = fmt.Fprintln(out, "Excluded:")
+		excluded := append([]bundleEntry{}, manifest.Excluded...)
+		sort.Slice(excluded, func(i, j int) bool { return excluded[i].Name < excluded[j].Name })
+		for _, entry := range excluded {
+			_, _ = fmt.Fprintf(out, "  %s: %s\n", entry.Name, entry.Note)
+		}
+	}
+	return nil
+}
@@ -17,8 +17,10 @@
 package cmd
 
 import (
+	"context"
 	"path/filepath"
 
+	"github.com/gojue/moling/pkg/services/system"
 	"github.com/gojue/moling/pkg/utils"
 	"github.com/spf13/cobra"
 )
@@ -37,5 +39,7 @@ func mlsCommandPreFunc(cmd *cobra.Command, args []string) error {
 			return err
 		}
 	}
+	// 3. 获取系统信息
+	mlConfig.SystemInfo = system.Describe(context.Background())
 	return nil
 }
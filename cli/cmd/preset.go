@@ -0,0 +1,108 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// PresetsKey is the key of the named presets in config.json.
+	PresetsKey = "presets"
+	// PresetEnv selects a preset when --preset is not given.
+	PresetEnv = "MOLING_PRESET"
+)
+
+// presetName 启动时选择的预设，为空时使用 MOLING_PRESET 环境变量
+var presetName string
+
+// activePreset 已应用的预设及其覆盖的配置节，用于在错误信息中指明预设
+var activePreset struct {
+	name     string
+	sections map[string]bool
+}
+
+// selectedPreset 返回 --preset 或 MOLING_PRESET 选择的预设名称
+func selectedPreset() string {
+	if presetName != "" {
+		return presetName
+	}
+	return strings.TrimSpace(os.Getenv(PresetEnv))
+}
+
+// applyPreset 将名为 name 的预设深度合并到配置上，返回合并后的新配置，原配置不变。
+// name 为空时原样返回配置，预设不存在时返回错误并列出可用的预设
+func applyPreset(configJson map[string]interface{}, name string) (map[string]interface{}, error) {
+	activePreset.name, activePreset.sections = "", nil
+	if name == "" {
+		return configJson, nil
+	}
+	presets, _ := configJson[PresetsKey].(map[string]interface{})
+	raw, ok := presets[name]
+	if !ok {
+		available := "none"
+		if len(presets) > 0 {
+			names := make([]string, 0, len(presets))
+			for n := range presets {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			available = strings.Join(names, ", ")
+		}
+		return nil, fmt.Errorf("unknown preset %q, available presets: %s", name, available)
+	}
+	preset, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid preset %q: must be an object of config sections", name)
+	}
+
+	sections := make(map[string]bool, len(preset))
+	for section := range preset {
+		sections[section] = true
+	}
+	activePreset.name, activePreset.sections = name, sections
+	return deepMerge(configJson, preset), nil
+}
+
+// deepMerge 返回 override 深度合并到 base 上的新 map：两边都是对象时递归合并，否则 override 的值优先，数组整体替换
+func deepMerge(base, override map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		baseMap, baseIsMap := merged[k].(map[string]interface{})
+		overrideMap, overrideIsMap := v.(map[string]interface{})
+		if baseIsMap && overrideIsMap {
+			merged[k] = deepMerge(baseMap, overrideMap)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// presetError 当出错的配置节被预设覆盖时，在错误信息前加上预设名称
+func presetError(section string, err error) error {
+	if err == nil || !activePreset.sections[section] {
+		return err
+	}
+	return fmt.Errorf("preset %q: %w", activePreset.name, err)
+}
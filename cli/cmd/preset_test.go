@@ -0,0 +1,220 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gojue/moling/pkg/services/browser"
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const presetTestConfig = `{
+  "MoLingConfig": {"module": "all", "listen_addr": "", "shutdown_timeout": 5},
+  "Browser": {"headless": false, "selector_query_timeout": 10, "user_agent": "base"},
+  "Command": {"allowed_command": "ls,cat"},
+  "presets": {
+    "scrape": {
+      "MoLingConfig": {"module": "Browser,FileSystem", "listen_addr": "127.0.0.1:6789"},
+      "Browser": {"headless": true}
+    },
+    "empty": {},
+    "bad": {"Browser": {"headless": "yes"}},
+    "badGlobal": {"MoLingConfig": {"shutdown_timeout": -1}}
+  }
+}`
+
+func decodePresetTestConfig(t *testing.T) map[string]interface{} {
+	t.Helper()
+	var configJson map[string]interface{}
+	if err := json.Unmarshal([]byte(presetTestConfig), &configJson); err != nil {
+		t.Fatalf("invalid test config: %v", err)
+	}
+	return configJson
+}
+
+// restoreGlobalConfig 在测试结束后恢复被预设修改的全局配置
+func restoreGlobalConfig(t *testing.T) {
+	oldModule, oldAddr, oldTimeout, oldPreset := mlConfig.Module, mlConfig.ListenAddr, mlConfig.ShutdownTimeout, presetName
+	t.Cleanup(func() {
+		mlConfig.Module, mlConfig.ListenAddr, mlConfig.ShutdownTimeout, presetName = oldModule, oldAddr, oldTimeout, oldPreset
+		activePreset.name, activePreset.sections = "", nil
+	})
+}
+
+func TestApplyPreset(t *testing.T) {
+	restoreGlobalConfig(t)
+
+	t.Run("DeepMerge", func(t *testing.T) {
+		base := decodePresetTestConfig(t)
+		merged, err := applyPreset(base, "scrape")
+		if err != nil {
+			t.Fatalf("applyPreset failed: %v", err)
+		}
+		// 预设覆盖基础配置，未覆盖的字段和配置节保留
+		wantBrowser := map[string]interface{}{"headless": true, "selector_query_timeout": float64(10), "user_agent": "base"}
+		if !reflect.DeepEqual(merged["Browser"], wantBrowser) {
+			t.Errorf("Expected %v, got %v", wantBrowser, merged["Browser"])
+		}
+		if !reflect.DeepEqual(merged["Command"], base["Command"]) {
+			t.Errorf("Expected the Command section to be kept, got %v", merged["Command"])
+		}
+		global := merged["MoLingConfig"].(map[string]interface{})
+		if global["module"] != "Browser,FileSystem" || global["shutdown_timeout"] != float64(5) {
+			t.Errorf("Unexpected MoLingConfig %v", global)
+		}
+		// 基础配置不被修改
+		if base["Browser"].(map[string]interface{})["headless"] != false {
+			t.Errorf("Expected the base config to be unchanged, got %v", base["Browser"])
+		}
+	})
+
+	t.Run("EmptyPresetIsNoOp", func(t *testing.T) {
+		base := decodePresetTestConfig(t)
+		merged, err := applyPreset(base, "empty")
+		if err != nil {
+			t.Fatalf("applyPreset failed: %v", err)
+		}
+		if !reflect.DeepEqual(merged, decodePresetTestConfig(t)) {
+			t.Errorf("Expected the empty preset to change nothing, got %v", merged)
+		}
+		if merged, _ := applyPreset(base, ""); !reflect.DeepEqual(merged, base) {
+			t.Errorf("Expected no preset to change nothing, got %v", merged)
+		}
+	})
+
+	t.Run("UnknownPreset", func(t *testing.T) {
+		_, err := applyPreset(decodePresetTestConfig(t), "nope")
+		if err == nil || err.Error() != `unknown preset "nope", available presets: bad, badGlobal, empty, scrape` {
+			t.Errorf("Expected the available presets to be listed, got %v", err)
+		}
+		_, err = applyPreset(nil, "scrape")
+		if err == nil || !strings.Contains(err.Error(), "available presets: none") {
+			t.Errorf("Expected no available presets without a config, got %v", err)
+		}
+	})
+
+	t.Run("FlagsOverPreset", func(t *testing.T) {
+		merged, err := applyPreset(decodePresetTestConfig(t), "scrape")
+		if err != nil {
+			t.Fatalf("applyPreset failed: %v", err)
+		}
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		flags.StringVarP(&mlConfig.ListenAddr, "listen_addr", "l", "", "")
+		flags.StringVarP(&mlConfig.Module, "module", "m", "all", "")
+		if err := loadGlobalConfig(merged, flags); err != nil {
+			t.Fatalf("loadGlobalConfig failed: %v", err)
+		}
+		if mlConfig.Module != "Browser,FileSystem" || mlConfig.ListenAddr != "127.0.0.1:6789" {
+			t.Errorf("Expected the preset to override the base config, got module %q and listen_addr %q", mlConfig.Module, mlConfig.ListenAddr)
+		}
+
+		if err := flags.Parse([]string{"-m", "Command", "-l", "127.0.0.1:1234"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := loadGlobalConfig(merged, flags); err != nil {
+			t.Fatalf("loadGlobalConfig failed: %v", err)
+		}
+		if mlConfig.Module != "Command" || mlConfig.ListenAddr != "127.0.0.1:1234" {
+			t.Errorf("Expected the command line to override the preset, got module %q and listen_addr %q", mlConfig.Module, mlConfig.ListenAddr)
+		}
+	})
+
+	t.Run("ErrorsNameThePreset", func(t *testing.T) {
+		merged, err := applyPreset(decodePresetTestConfig(t), "bad")
+		if err != nil {
+			t.Fatalf("applyPreset failed: %v", err)
+		}
+		_, err = initSingleService(createContext(zerolog.Nop()), browser.BrowserServerName, browser.NewBrowserServer, merged)
+		if err == nil || !strings.HasPrefix(err.Error(), `preset "bad": failed to load config for service Browser`) || !strings.Contains(err.Error(), "type mismatch for field headless") {
+			t.Errorf("Expected the type error to name the preset, got %v", err)
+		}
+
+		merged, _ = applyPreset(decodePresetTestConfig(t), "badGlobal")
+		if err := loadGlobalConfig(merged, pflag.NewFlagSet("test", pflag.ContinueOnError)); err == nil || !strings.HasPrefix(err.Error(), `preset "badGlobal": invalid shutdown_timeout`) {
+			t.Errorf("Expected the global config error to name the preset, got %v", err)
+		}
+
+		// 预设未覆盖的配置节的错误不提及预设
+		base := decodePresetTestConfig(t)
+		base["Browser"].(map[string]interface{})["headless"] = "no"
+		merged, _ = applyPreset(base, "badGlobal")
+		if _, err := initSingleService(createContext(zerolog.Nop()), browser.BrowserServerName, browser.NewBrowserServer, merged); err == nil || strings.Contains(err.Error(), "preset") {
+			t.Errorf("Expected a plain config error, got %v", err)
+		}
+	})
+}
+
+func TestConfigCommandPreset(t *testing.T) {
+	restoreGlobalConfig(t)
+	basePath := t.TempDir()
+	for _, dir := range []string{"logs", "config"} {
+		if err := os.MkdirAll(filepath.Join(basePath, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configFile := filepath.Join(basePath, "config", MLConfigName)
+	if err := os.WriteFile(configFile, []byte(presetTestConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	oldBasePath := mlConfig.BasePath
+	t.Cleanup(func() { mlConfig.BasePath = oldBasePath })
+	mlConfig.BasePath = basePath
+
+	run := func(preset string) (map[string]map[string]interface{}, error) {
+		var stdout bytes.Buffer
+		command := &cobra.Command{}
+		command.SetOut(&stdout)
+		presetName = preset
+		if err := ConfigCommandFunc(command, nil); err != nil {
+			return nil, err
+		}
+		var effective map[string]map[string]interface{}
+		if err := json.Unmarshal(stdout.Bytes(), &effective); err != nil {
+			t.Fatalf("invalid JSON output: %v\n%s", err, stdout.String())
+		}
+		return effective, nil
+	}
+
+	effective, err := run("scrape")
+	if err != nil {
+		t.Fatalf("config --preset failed: %v", err)
+	}
+	if effective["Browser"]["headless"] != true || effective["Browser"]["user_agent"] != "base" || effective["MoLingConfig"]["module"] != "Browser,FileSystem" {
+		t.Errorf("Expected the merged configuration, got Browser %v and MoLingConfig %v", effective["Browser"], effective["MoLingConfig"])
+	}
+	if _, ok := effective[PresetsKey]; ok {
+		t.Errorf("Expected the presets not to be printed")
+	}
+	// 配置文件不被修改
+	if data, _ := os.ReadFile(configFile); string(data) != presetTestConfig {
+		t.Errorf("Expected the config file to be unchanged, got %s", data)
+	}
+
+	t.Setenv(PresetEnv, "nope")
+	if _, err := run(""); err == nil || !strings.Contains(err.Error(), `unknown preset "nope"`) {
+		t.Errorf("Expected MOLING_PRESET to select the preset, got %v", err)
+	}
+}
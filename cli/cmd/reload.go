@@ -0,0 +1,126 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gojue/moling/pkg/server"
+	"github.com/rs/zerolog"
+)
+
+// reloadDebounce 配置文件变化后等待的时间，合并编辑器保存时的多次写入
+var reloadDebounce = 500 * time.Millisecond
+
+// configReload 将配置文件的修改应用到运行中的服务
+type configReload struct {
+	reloader *server.ConfigReloader
+	changes  chan struct{} // 配置文件的变化，未开启 reload_on_change 时没有写入
+	logger   zerolog.Logger
+}
+
+// newConfigReload 创建 configReload，开启 reload_on_change 时监听配置文件，ctx 结束时停止监听
+func newConfigReload(ctx context.Context, reloader *server.ConfigReloader, logger zerolog.Logger) *configReload {
+	cr := &configReload{reloader: reloader, changes: make(chan struct{}, 1), logger: logger}
+	if mlConfig.ReloadOnChange {
+		if err := cr.watch(ctx, mlConfigFilePath()); err != nil {
+			logger.Warn().Err(err).Msg("failed to watch the config file, reload with SIGHUP")
+		}
+	}
+	return cr
+}
+
+// watch 监听配置文件所在目录，编辑器常以重命名替换文件，直接监听文件会丢失后续变化
+func (cr *configReload) watch(ctx context.Context, path string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		_ = watcher.Close()
+		return err
+	}
+	cr.logger.Info().Str("config_file", path).Msg("watching the config file for changes")
+	delay := reloadDebounce
+	go func() {
+		defer watcher.Close()
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) == filepath.Clean(path) && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+					debounce = time.After(delay)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				cr.logger.Warn().Err(err).Msg("config file watcher error")
+			case <-debounce:
+				debounce = nil
+				select {
+				case cr.changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// reload 重新读取配置文件，应用预设后交给 ConfigReloader，读取失败时保持当前配置
+func (cr *configReload) reload(reason string) []server.ServiceReload {
+	cr.logger.Info().Str("reason", reason).Msg("reloading config")
+	configJson, err := readReloadConfig(mlConfigFilePath())
+	if err != nil {
+		cr.logger.Error().Err(err).Msg("failed to read the config file, the running config is kept")
+		return nil
+	}
+	results := cr.reloader.Reload(configJson)
+	if len(results) == 0 {
+		cr.logger.Info().Msg("config unchanged")
+	}
+	return results
+}
+
+// readReloadConfig 读取配置文件并应用预设，与启动时一致。文件不存在时视为空配置
+func readReloadConfig(path string) (map[string]interface{}, error) {
+	var configJson map[string]interface{}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, &configJson); err != nil {
+			return nil, fmt.Errorf("Error unmarshaling JSON: %v, config file:%s", err, path)
+		}
+	}
+	return applyPreset(configJson, selectedPreset())
+}
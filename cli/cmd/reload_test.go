@@ -0,0 +1,76 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gojue/moling/pkg/server"
+	"github.com/rs/zerolog"
+)
+
+func TestConfigReload(t *testing.T) {
+	oldBasePath, oldConfigFile, oldReload, oldDebounce := mlConfig.BasePath, mlConfig.ConfigFile, mlConfig.ReloadOnChange, reloadDebounce
+	defer func() {
+		mlConfig.BasePath, mlConfig.ConfigFile, mlConfig.ReloadOnChange, reloadDebounce = oldBasePath, oldConfigFile, oldReload, oldDebounce
+	}()
+	mlConfig.BasePath, mlConfig.ConfigFile, mlConfig.ReloadOnChange = t.TempDir(), "config.json", true
+	reloadDebounce = 20 * time.Millisecond
+	t.Setenv("MOLING_PRESET", "")
+
+	path := mlConfigFilePath()
+	if err := os.WriteFile(path, []byte(`{"MoLingConfig": {"module": "all"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	configJson, err := readReloadConfig(path)
+	if err != nil {
+		t.Fatalf("readReloadConfig failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reload := newConfigReload(ctx, server.NewConfigReloader(nil, configJson, zerolog.Nop()), zerolog.Nop())
+
+	// 编辑器常先写临时文件再重命名
+	tmp := filepath.Join(mlConfig.BasePath, "config.json.tmp")
+	if err := os.WriteFile(tmp, []byte(`{"MoLingConfig": {"module": "Browser"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-reload.changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a change of the config file")
+	}
+	results := reload.reload("test")
+	if len(results) != 1 || results[0].Service != "MoLingConfig" || len(results[0].RestartRequired) != 1 || results[0].RestartRequired[0] != "module" {
+		t.Errorf("Expected module to require a restart, got %+v", results)
+	}
+
+	// 无法解析的配置文件不影响运行中的配置
+	if err := os.WriteFile(path, []byte(`{invalid`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if results := reload.reload("test"); results != nil {
+		t.Errorf("Expected no reload of an invalid config file, got %+v", results)
+	}
+}
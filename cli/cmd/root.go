@@ -19,12 +19,13 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"os/user"
 	"path/filepath"
-	"sync"
+	"sort"
 	"syscall"
 	"time"
 
@@ -48,8 +49,11 @@ func init() {
 	// when this action is called directly.
 	rootCmd.PersistentFlags().StringVar(&mlConfig.BasePath, "base_path", mlConfig.BasePath, "MoLing Base Data Path, automatically set by the system, cannot be changed, display only.")
 	rootCmd.PersistentFlags().BoolVarP(&mlConfig.Debug, "debug", "d", false, "Debug mode, default is false.")
-	rootCmd.PersistentFlags().StringVarP(&mlConfig.ListenAddr, "listen_addr", "l", "", "listen address for SSE mode. default:'', not listen, used STDIO mode.")
-	rootCmd.PersistentFlags().StringVarP(&mlConfig.Module, "module", "m", "all", "module to load, default: all; others: Browser,FileSystem,Command, etc. Multiple modules are separated by commas")
+	rootCmd.PersistentFlags().StringVarP(&mlConfig.ListenAddr, "listen_addr", "l", "", "listen address for the sse and http transports. default:'', not listen, used STDIO mode.")
+	rootCmd.PersistentFlags().StringVar(&mlConfig.TransportMode, "transport", "", "transport: stdio, sse or http (streamable HTTP at /mcp). default:'', sse if listen_addr is set, stdio otherwise.")
+	rootCmd.PersistentFlags().StringVar(&mlConfig.AuthToken, "auth_token", "", "token required by the sse and http transports as 'Authorization: Bearer <token>' or ?token=, default:'', no authentication. ignored in STDIO mode.")
+	rootCmd.PersistentFlags().StringVarP(&mlConfig.Module, "module", "m", "all", "module to load, default: all; others: Browser,FileSystem,Command,HttpFetch,System,Clipboard,Screen, etc. Multiple modules are separated by commas, names are case-insensitive and unknown names fail the startup")
+	rootCmd.PersistentFlags().StringVar(&presetName, "preset", "", "name of a preset of config.json merged over the configuration, default: the MOLING_PRESET environment variable.")
 	rootCmd.SilenceUsage = true
 }
 
@@ -89,7 +93,7 @@ func Execute() {
 	}
 }
 
-// mlsCommandFunc 服务核心启动函数
+// mlsCommandFunc 服务核心启动函数，启动失败时在标准错误输出结构化的错误信息
 func mlsCommandFunc(command *cobra.Command, args []string) error {
 	// 初始化日志
 	logger := initLogger(mlConfig.BasePath)
@@ -98,14 +102,22 @@ func mlsCommandFunc(command *cobra.Command, args []string) error {
 	// 检查运行实例和配置文件
 	pidFilePath := filepath.Join(mlConfig.BasePath, MLPidName)
 	if err := checkRunningInstance(pidFilePath, logger); err != nil {
-		return err
+		return startupFailed(command, err, pidFilePath)
 	}
 
 	// 加载配置文件
-	configFilePath := filepath.Join(mlConfig.BasePath, mlConfig.ConfigFile)
-	configJson, err := loadConfigFile(configFilePath, logger)
+	configJson, err := loadConfigFile(mlConfigFilePath(), logger)
 	if err != nil {
-		return err
+		return startupFailed(command, err, pidFilePath)
+	}
+	if configJson, err = applyPreset(configJson, selectedPreset()); err != nil {
+		return startupFailed(command, err, pidFilePath)
+	}
+	if activePreset.name != "" {
+		logger.Info().Str("preset", activePreset.name).Msg("preset applied")
+	}
+	if err := loadGlobalConfig(configJson, command.Flags()); err != nil {
+		return startupFailed(command, err, pidFilePath)
 	}
 
 	// 创建并启动服务
@@ -117,29 +129,83 @@ func mlsCommandFunc(command *cobra.Command, args []string) error {
 	servicesList, closers, err := initServices(ctx, configJson, logger)
 	if err != nil {
 		cancel()
-		return err
+		return startupFailed(command, err, pidFilePath)
 	}
 
 	// 启动MCP服务器
-	_, err = startMoLingServer(ctx, servicesList, logger)
+	srv, serveErr, err := startMoLingServer(ctx, servicesList, logger)
 	if err != nil {
 		cancel()
-		return err
+		_ = shutdownServices(closers, cancel, logger)
+		return startupFailed(command, err, pidFilePath)
 	}
-
-	// 等待信号并执行优雅关闭
-	return waitForShutdownSignal(cancel, closers, pidFilePath, logger)
+	// 关闭时写入缓冲的审计日志
+	closers["AuditLog"] = srv.Close
+
+	// 等待信号并执行优雅关闭，服务器启动失败（如端口被占用）时同样关闭
+	reload := newConfigReload(ctx, server.NewConfigReloader(servicesList, configJson, logger), logger)
+	err = waitForShutdownSignal(cancel, srv.Shutdown, reload, closers, serveErr, pidFilePath, logger)
+	if errors.Is(err, errServeFailed) {
+		return startupFailed(command, err, pidFilePath)
+	}
+	return err
 }
 
-// checkRunningInstance 检查是否有已运行的实例
+// errPIDFileLocked 表示 PID 文件被不是 MoLing 的进程锁定
+var errPIDFileLocked = errors.New("the pid file is locked by a process that is not a running MoLing")
+
+// checkRunningInstance 检查是否有已运行的实例。异常退出留下的 PID 文件和内容无效的 PID 文件会被覆盖；PID 文件
+// 被锁定时不会覆盖，MoLing 实例在运行时返回 ErrInstanceRunning，被其他进程锁定时返回 errPIDFileLocked
 func checkRunningInstance(pidFilePath string, logger zerolog.Logger) error {
 	logger.Info().Str("pid", pidFilePath).Msg("Starting MoLing MCP Server...")
+	pid, locked, err := utils.ReadPIDFile(pidFilePath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		// 例如写入 PID 前断电留下的空文件
+		logger.Warn().Err(err).Str("pid_file", pidFilePath).Msg("the pid file is unreadable, overwriting it")
+	case !locked:
+		logger.Warn().Int("stale_pid", pid).Str("pid_file", pidFilePath).Msg("found a stale pid file of an instance that exited, overwriting it")
+	case !isMoLingProcess(pid, logger):
+		// 锁被其他进程持有，删除文件会使锁失效，也可能破坏其他程序的文件，拒绝启动
+		return fmt.Errorf("%w: pid %d, pid file: %s, stop the process holding it or use another base path", errPIDFileLocked, pid, pidFilePath)
+	default:
+		return runningInstanceError(pidFilePath, pid)
+	}
+
 	if err := utils.CreatePIDFile(pidFilePath); err != nil {
+		if errors.Is(err, utils.ErrInstanceRunning) {
+			// 另一个实例同时启动
+			pid, _, _ := utils.ReadPIDFile(pidFilePath)
+			return runningInstanceError(pidFilePath, pid)
+		}
 		return err
 	}
 	return nil
 }
 
+// isMoLingProcess 判断 PID 文件中的进程是否存在且运行的是 MoLing，无法获取其可执行文件时视为 MoLing
+func isMoLingProcess(pid int, logger zerolog.Logger) bool {
+	if !utils.ProcessAlive(pid) {
+		return false
+	}
+	same, err := utils.IsSameExecutable(pid)
+	if err != nil {
+		logger.Warn().Err(err).Int("pid", pid).Msg("failed to check the executable of the running instance")
+		return true
+	}
+	return same
+}
+
+// runningInstanceError 返回实例已在运行的错误，启动时间取自 PID 文件的修改时间
+func runningInstanceError(pidFilePath string, pid int) error {
+	startedAt := "unknown"
+	if info, err := os.Stat(pidFilePath); err == nil {
+		startedAt = info.ModTime().Format(time.RFC3339)
+	}
+	return fmt.Errorf("%w: pid %d, started at %s, pid file: %s", utils.ErrInstanceRunning, pid, startedAt, pidFilePath)
+}
+
 // loadConfigFile 加载配置文件
 func loadConfigFile(configFilePath string, logger zerolog.Logger) (map[string]interface{}, error) {
 	logger.Info().Str("ServerName", MCPServerName).Str("version", GitVersion).Msg("start")
@@ -156,37 +222,83 @@ func loadConfigFile(configFilePath string, logger zerolog.Logger) (map[string]in
 	return configJson, nil
 }
 
-// startMoLingServer 启动MoLing服务器
-func startMoLingServer(ctx context.Context, servicesList []abstract.Service, logger zerolog.Logger) (*server.MoLingServer, error) {
+// startMoLingServer 启动MoLing服务器，服务器运行失败时错误写入返回的通道
+func startMoLingServer(ctx context.Context, servicesList []abstract.Service, logger zerolog.Logger) (*server.MoLingServer, <-chan error, error) {
 	server, err := server.NewMoLingServer(ctx, servicesList, *mlConfig)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to create server")
-		return nil, err
+		return nil, nil, err
 	}
+	logStartupBanner(servicesList, logger)
 
+	serveErr := make(chan error, 1)
 	go func() {
 		if err := server.Serve(); err != nil {
 			logger.Error().Err(err).Msg("failed to start server")
+			serveErr <- err
 		}
 	}()
-	return server, nil
+	return server, serveErr, nil
+}
+
+// logStartupBanner 记录启动信息，moling status 从日志中读取已加载的模块和监听地址
+func logStartupBanner(servicesList []abstract.Service, logger zerolog.Logger) {
+	modules := make([]string, 0, len(servicesList))
+	for _, srv := range servicesList {
+		modules = append(modules, string(srv.Name()))
+	}
+	transport, err := server.ResolveTransport(mlConfig.TransportMode, mlConfig.ListenAddr)
+	if err != nil {
+		transport = mlConfig.TransportMode
+	}
+	logger.Info().Int("pid", os.Getpid()).Str("version", GitVersion).Str("transport", transport).
+		Str("listenAddr", mlConfig.ListenAddr).Strs("modules", modules).Msg(startupBannerMsg)
 }
 
-// waitForShutdownSignal 等待关闭信号并优雅关闭服务
-func waitForShutdownSignal(cancelFunc context.CancelFunc, closers map[string]func() error, pidFilePath string, logger zerolog.Logger) error {
+// waitForShutdownSignal 等待关闭信号或服务器运行失败，先停止 SSE/HTTP 监听再优雅关闭服务。服务器运行失败时返回其错误。
+// 等待期间 SIGHUP 和配置文件的变化会重新加载服务配置
+func waitForShutdownSignal(cancelFunc context.CancelFunc, shutdownServer func(context.Context) error, reload *configReload, closers map[string]func() error, serveErr <-chan error, pidFilePath string, logger zerolog.Logger) error {
 	// 创建信号通道
 	sigChan := make(chan os.Signal, 2)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	defer signal.Stop(hupChan)
 
 	// 监控父进程退出
 	go monitorParentProcess(sigChan, logger)
 
 	// 等待信号
-	_ = <-sigChan
-	logger.Info().Msg("Received signal, shutting down...")
+	var err error
+wait:
+	for {
+		select {
+		case <-hupChan:
+			reload.reload("SIGHUP")
+		case <-reload.changes:
+			reload.reload("config file changed")
+		case <-sigChan:
+			logger.Info().Msg("Received signal, shutting down...")
+			break wait
+		case err = <-serveErr:
+			logger.Info().Msg("Server stopped, shutting down...")
+			break wait
+		}
+	}
+
+	// 停止接受新的请求并等待进行中的请求完成，再关闭服务
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), time.Duration(mlConfig.ShutdownTimeout)*time.Second)
+	if err := shutdownServer(shutdownCtx); err != nil {
+		logger.Warn().Err(err).Msg("server did not shut down gracefully")
+	}
+	shutdownCancel()
 
 	// 优雅关闭所有服务
-	shutdownServices(closers, cancelFunc, logger)
+	closeErr := shutdownServices(closers, cancelFunc, logger)
+	if err != nil {
+		// PID 文件由调用方在报告错误后清理
+		return fmt.Errorf("%w: %w", errServeFailed, err)
+	}
 
 	// 清理PID文件
 	if err := utils.RemovePIDFile(pidFilePath); err != nil {
@@ -196,7 +308,7 @@ func waitForShutdownSignal(cancelFunc context.CancelFunc, closers map[string]fun
 
 	logger.Info().Msgf("removed pid file %s", pidFilePath)
 	logger.Info().Msg(" Bye!")
-	return nil
+	return closeErr
 }
 
 // monitorParentProcess 监控父进程是否退出
@@ -214,37 +326,76 @@ func monitorParentProcess(sigChan chan<- os.Signal, logger zerolog.Logger) {
 	}
 }
 
-// shutdownServices 优雅关闭所有服务
-func shutdownServices(closers map[string]func() error, cancelFunc context.CancelFunc, logger zerolog.Logger) {
-	var wg sync.WaitGroup
-	done := make(chan struct{})
+// errCloseTimeout 服务在关闭超时时间内未完成关闭
+var errCloseTimeout = errors.New("close timed out")
 
-	// 并行关闭所有服务
-	go func() {
-		for serviceName, closer := range closers {
-			wg.Add(1)
-			go func(name string, closeFn func() error) {
-				defer wg.Done()
-				if err := closeFn(); err != nil {
-					logger.Error().Err(err).Msgf("failed to close service %s", name)
-				} else {
-					logger.Info().Msgf("service %s closed", name)
+// serviceCloseResult 单个服务的关闭结果
+type serviceCloseResult struct {
+	Name     string        // 服务名称
+	Duration time.Duration // 关闭耗时，超时的服务为超时时间
+	Err      error         // 关闭错误，超时为 errCloseTimeout
+}
+
+// closeServices 并行关闭所有服务，最多等待 timeout，结果按服务名称排序
+func closeServices(closers map[string]func() error, timeout time.Duration) []serviceCloseResult {
+	results := make(chan serviceCloseResult, len(closers))
+	start := time.Now()
+	for serviceName, closer := range closers {
+		go func(name string, closeFn func() error) {
+			err := closeFn()
+			results <- serviceCloseResult{Name: name, Duration: time.Since(start), Err: err}
+		}(serviceName, closer)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	closed := make(map[string]serviceCloseResult, len(closers))
+	for len(closed) < len(closers) {
+		select {
+		case result := <-results:
+			closed[result.Name] = result
+		case <-timer.C:
+			// 未完成的服务记为超时，其 goroutine 随进程退出
+			for name := range closers {
+				if _, ok := closed[name]; !ok {
+					closed[name] = serviceCloseResult{Name: name, Duration: timeout, Err: errCloseTimeout}
 				}
-			}(serviceName, closer)
+			}
 		}
+	}
 
-		// 等待所有服务关闭
-		wg.Wait()
-		close(done)
-	}()
+	list := make([]serviceCloseResult, 0, len(closed))
+	for _, result := range closed {
+		list = append(list, result)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
 
-	// 等待服务关闭或超时
-	select {
-	case <-time.After(5 * time.Second):
-		cancelFunc()
-		logger.Info().Msg("timeout, all services closed forcefully")
-	case <-done:
-		cancelFunc()
-		logger.Info().Msg("all services closed gracefully")
+// shutdownServices 优雅关闭所有服务，记录每个服务的关闭耗时和错误，返回合并后的关闭错误
+func shutdownServices(closers map[string]func() error, cancelFunc context.CancelFunc, logger zerolog.Logger) error {
+	timeout := time.Duration(mlConfig.ShutdownTimeout) * time.Second
+	results := closeServices(closers, timeout)
+	cancelFunc()
+
+	var errs []error
+	for _, result := range results {
+		switch {
+		case errors.Is(result.Err, errCloseTimeout):
+			logger.Warn().Str("service", result.Name).Dur("duration", result.Duration).Msg("service did not close before the shutdown timeout")
+		case result.Err != nil:
+			logger.Error().Err(result.Err).Str("service", result.Name).Dur("duration", result.Duration).Msg("failed to close service")
+		default:
+			logger.Info().Str("service", result.Name).Dur("duration", result.Duration).Msg("service closed")
+		}
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Name, result.Err))
+		}
+	}
+	if len(errs) > 0 {
+		logger.Warn().Int("failed", len(errs)).Int("services", len(results)).Msg("services closed with errors")
+	} else {
+		logger.Info().Int("services", len(results)).Msg("all services closed gracefully")
 	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,189 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gojue/moling/pkg/utils"
+	"github.com/rs/zerolog"
+)
+
+func TestShutdownServices(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	closers := map[string]func() error{
+		"Fast":   func() error { return nil },
+		"Failed": func() error { return errors.New("disk full") },
+		"Slow": func() error {
+			<-release
+			return nil
+		},
+	}
+
+	t.Run("TimeoutAccounting", func(t *testing.T) {
+		timeout := 100 * time.Millisecond
+		start := time.Now()
+		results := closeServices(closers, timeout)
+		if elapsed := time.Since(start); elapsed < timeout || elapsed > timeout+time.Second {
+			t.Errorf("Expected closeServices to return after the %s timeout, took %s", timeout, elapsed)
+		}
+		if len(results) != 3 {
+			t.Fatalf("Expected 3 results, got %+v", results)
+		}
+		// 结果按服务名称排序
+		failed, fast, slow := results[0], results[1], results[2]
+		if failed.Name != "Failed" || failed.Err == nil || failed.Err.Error() != "disk full" {
+			t.Errorf("Expected the close error of Failed, got %+v", failed)
+		}
+		if fast.Name != "Fast" || fast.Err != nil || fast.Duration >= timeout {
+			t.Errorf("Expected Fast to close before the timeout, got %+v", fast)
+		}
+		if slow.Name != "Slow" || !errors.Is(slow.Err, errCloseTimeout) || slow.Duration != timeout {
+			t.Errorf("Expected Slow to be reported as timed out after %s, got %+v", timeout, slow)
+		}
+	})
+
+	t.Run("PropagatesErrors", func(t *testing.T) {
+		oldTimeout := mlConfig.ShutdownTimeout
+		t.Cleanup(func() { mlConfig.ShutdownTimeout = oldTimeout })
+		mlConfig.ShutdownTimeout = 1
+
+		var logs strings.Builder
+		ctx, cancel := context.WithCancel(context.Background())
+		err := shutdownServices(closers, cancel, zerolog.New(&logs))
+		if ctx.Err() == nil {
+			t.Errorf("Expected the service context to be cancelled")
+		}
+		if err == nil || !strings.Contains(err.Error(), "Failed: disk full") || !errors.Is(err, errCloseTimeout) {
+			t.Errorf("Expected the joined close errors, got %v", err)
+		}
+		for _, want := range []string{`"service":"Fast"`, `"duration"`, "failed to close service", "did not close before the shutdown timeout"} {
+			if !strings.Contains(logs.String(), want) {
+				t.Errorf("Expected %q in the shutdown log, got %s", want, logs.String())
+			}
+		}
+	})
+
+	t.Run("AllClosed", func(t *testing.T) {
+		err := shutdownServices(map[string]func() error{"Fast": closers["Fast"]}, func() {}, zerolog.Nop())
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+}
+
+func TestCheckRunningInstance(t *testing.T) {
+	// 读取 PID 文件中记录的 PID
+	readPID := func(t *testing.T, path string) int {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+		return pid
+	}
+	// 运行 checkRunningInstance，返回日志和错误
+	check := func(t *testing.T, path string) (string, error) {
+		t.Helper()
+		var logs bytes.Buffer
+		err := checkRunningInstance(path, zerolog.New(&logs))
+		if err == nil {
+			t.Cleanup(func() { _ = utils.RemovePIDFile(path) })
+		}
+		return logs.String(), err
+	}
+
+	for name, content := range map[string]string{
+		"StaleFile":      "999999\n",
+		"UnreadableFile": "",
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), MLPidName)
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			logs, err := check(t, path)
+			if err != nil {
+				t.Fatalf("Expected the pid file to be overwritten, got %v", err)
+			}
+			if pid := readPID(t, path); pid != os.Getpid() {
+				t.Errorf("Expected pid %d in the pid file, got %d", os.Getpid(), pid)
+			}
+			if !strings.Contains(logs, `"level":"warn"`) {
+				t.Errorf("Expected a warning, got %s", logs)
+			}
+		})
+	}
+
+	t.Run("LiveProcess", func(t *testing.T) {
+		// 当前测试进程持有 PID 文件，与另一个 MoLing 实例相同
+		path := filepath.Join(t.TempDir(), MLPidName)
+		if err := utils.CreatePIDFile(path); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = utils.RemovePIDFile(path) })
+		_, err := check(t, path)
+		if !errors.Is(err, utils.ErrInstanceRunning) {
+			t.Fatalf("Expected ErrInstanceRunning, got %v", err)
+		}
+		if !strings.Contains(err.Error(), fmt.Sprintf("pid %d, started at ", os.Getpid())) {
+			t.Errorf("Expected the pid and start time in the error, got %v", err)
+		}
+	})
+
+	t.Run("LockedByOtherProcess", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("the pid file can't be rewritten while it is locked on Windows")
+		}
+		sleep := exec.Command("sleep", "30")
+		if err := sleep.Start(); err != nil {
+			t.Skipf("failed to start sleep: %v", err)
+		}
+		t.Cleanup(func() {
+			_ = sleep.Process.Kill()
+			_ = sleep.Wait()
+		})
+		// PID 文件被锁定，但记录的是不是 MoLing 的进程
+		path := filepath.Join(t.TempDir(), MLPidName)
+		if err := utils.CreatePIDFile(path); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(fmt.Sprintf("%d\n", sleep.Process.Pid)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := check(t, path)
+		if !errors.Is(err, errPIDFileLocked) {
+			t.Fatalf("Expected errPIDFileLocked, got %v", err)
+		}
+		if pid := readPID(t, path); pid != sleep.Process.Pid {
+			t.Errorf("Expected the pid file to be kept with pid %d, got %d", sleep.Process.Pid, pid)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gojue/moling/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+)
+
+// StartupErrorCode is the JSON-RPC error code of a startup failure, in the range reserved for
+// implementation-defined server errors.
+const StartupErrorCode = -32000
+
+// errServeFailed wraps the error of a server that stopped on its own, such as an SSE server whose
+// port is in use.
+var errServeFailed = errors.New("server stopped")
+
+// StartupErrorData is the data of the JSON-RPC error written to stderr when MoLing fails to start.
+type StartupErrorData struct {
+	Error      string `json:"error"`
+	LogFile    string `json:"log_file"`
+	ConfigFile string `json:"config_file"`
+}
+
+// writeStartupError writes a startup failure as a single-line JSON-RPC error without an id. MCP
+// clients over STDIO show stderr, stdout must stay free of anything but the protocol.
+func writeStartupError(w io.Writer, err error, logFile, configFile string) {
+	rpcErr := mcp.NewJSONRPCError(mcp.NewRequestId(nil), StartupErrorCode,
+		fmt.Sprintf("MoLing failed to start: %v", err),
+		StartupErrorData{Error: err.Error(), LogFile: logFile, ConfigFile: configFile})
+	data, merr := json.Marshal(rpcErr)
+	if merr != nil {
+		_, _ = fmt.Fprintf(w, "MoLing failed to start: %v, log file: %s, config file: %s\n", err, logFile, configFile)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "%s\n", data)
+}
+
+// startupFailed reports a startup failure on stderr and in the log, releases the PID file and
+// returns err. Cobra's own error output is silenced, the report already contains the error.
+func startupFailed(command *cobra.Command, err error, pidFilePath string) error {
+	configFile := mlConfigFilePath()
+	logger := mlConfig.Logger()
+	logger.Error().Err(err).Str("config_file", configFile).Msg("MoLing failed to start")
+	writeStartupError(command.ErrOrStderr(), err, logFilePath(mlConfig.BasePath), configFile)
+	command.SilenceErrors = true
+	_ = utils.RemovePIDFile(pidFilePath)
+	return err
+}
@@ -0,0 +1,91 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestStartupError(t *testing.T) {
+	basePath := t.TempDir()
+	for _, dir := range []string{"logs", "config"} {
+		if err := os.MkdirAll(filepath.Join(basePath, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configFile := filepath.Join(basePath, "config", MLConfigName)
+	if err := os.WriteFile(configFile, []byte(`{"MoLingConfig": {"rate_limit": {"max_concurrent": -1}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	oldBasePath, oldRateLimit := mlConfig.BasePath, mlConfig.RateLimit
+	t.Cleanup(func() {
+		mlConfig.BasePath, mlConfig.RateLimit = oldBasePath, oldRateLimit
+	})
+	mlConfig.BasePath = basePath
+
+	var stdout, stderr bytes.Buffer
+	command := &cobra.Command{}
+	command.SetOut(&stdout)
+	command.SetErr(&stderr)
+	if err := mlsCommandFunc(command, nil); err == nil {
+		t.Fatal("Expected the invalid config to fail the startup")
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("Expected nothing on stdout, got %q", stdout.String())
+	}
+	if !command.SilenceErrors {
+		t.Errorf("Expected the cobra error output to be silenced")
+	}
+
+	// 标准错误输出为一行 JSON-RPC 错误，包含错误、日志文件和配置文件路径
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected a single line on stderr, got %q", stderr.String())
+	}
+	var rpcErr struct {
+		JSONRPC string `json:"jsonrpc"`
+		ID      any    `json:"id"`
+		Error   struct {
+			Code    int              `json:"code"`
+			Message string           `json:"message"`
+			Data    StartupErrorData `json:"data"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &rpcErr); err != nil {
+		t.Fatalf("Expected a JSON-RPC error on stderr: %v\n%s", err, lines[0])
+	}
+	if rpcErr.JSONRPC != "2.0" || rpcErr.ID != nil || rpcErr.Error.Code != StartupErrorCode {
+		t.Errorf("Unexpected JSON-RPC envelope %+v", rpcErr)
+	}
+	data := rpcErr.Error.Data
+	if !strings.Contains(data.Error, "rate limit must not be negative") {
+		t.Errorf("Expected the config error, got %q", data.Error)
+	}
+	if data.LogFile != filepath.Join(basePath, "logs", LogFileName) || data.ConfigFile != configFile {
+		t.Errorf("Unexpected paths in %+v", data)
+	}
+	if _, err := os.Stat(filepath.Join(basePath, MLPidName)); !os.IsNotExist(err) {
+		t.Errorf("Expected the PID file to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gojue/moling/pkg/server"
+	"github.com/gojue/moling/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "Print the status as JSON")
+	statusCmd.Flags().BoolVar(&statusProbe, "probe", true, "Probe the /healthz endpoint when the instance listens on an address")
+	rootCmd.AddCommand(statusCmd)
+}
+
+// statusCmd 报告运行中实例的状态
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Report whether MoLing is running, its PID, modules and listen address",
+	Long: `Report whether the MoLing instance of the base path is running, from its PID file. The modules and the
+listen address are read from the startup banner in the log, and /healthz is probed when the instance
+listens on an address. Exits with a non-zero code when MoLing is not running.
+    moling status
+    moling status --json
+`,
+	RunE: StatusCommandFunc,
+}
+
+var (
+	statusJSON  bool
+	statusProbe bool
+)
+
+const (
+	startupBannerMsg = "MoLing MCP Server started" // 启动信息的日志消息，moling status 据此查找
+	statusLogTail    = 256 * 1024                  // 查找启动信息时读取的日志末尾字节数
+
+	statusRunning    = "running"
+	statusNotRunning = "not running"
+	statusStale      = "not running (stale pid file)"
+	statusUnknown    = "unknown"
+)
+
+// errNotRunning 实例未运行，status 命令以非零状态码退出
+var errNotRunning = errors.New("MoLing is not running")
+
+// instanceStatus 运行中实例的状态
+type instanceStatus struct {
+	State      string   `json:"state"` // running, not running 或 not running (stale pid file)
+	Running    bool     `json:"running"`
+	PID        int      `json:"pid,omitempty"`
+	PIDFile    string   `json:"pid_file"`
+	LogFile    string   `json:"log_file"`
+	StartedAt  string   `json:"started_at,omitempty"`
+	Version    string   `json:"version,omitempty"`
+	Transport  string   `json:"transport,omitempty"`
+	ListenAddr string   `json:"listen_addr,omitempty"`
+	Modules    []string `json:"modules,omitempty"`
+	Health     string   `json:"health,omitempty"` // ok，或 /healthz 探测失败的原因
+	Error      string   `json:"error,omitempty"`
+}
+
+// startupBanner 日志中的启动信息，由 logStartupBanner 写入
+type startupBanner struct {
+	Time       string   `json:"time"`
+	Message    string   `json:"message"`
+	PID        int      `json:"pid"`
+	Version    string   `json:"version"`
+	Transport  string   `json:"transport"`
+	ListenAddr string   `json:"listenAddr"`
+	Modules    []string `json:"modules"`
+}
+
+// StatusCommandFunc executes the "status" command.
+func StatusCommandFunc(command *cobra.Command, args []string) error {
+	// 命令行未指定监听地址时取自配置文件，日志中没有启动信息时使用
+	if existingConfig, _, err := loadExistingConfig(mlConfigFilePath()); err == nil {
+		_ = loadGlobalSettings(existingConfig, command.Flags())
+	}
+
+	status := checkInstanceStatus()
+	if status.Running && status.ListenAddr != "" && statusProbe {
+		status.Health = probeHealth(context.Background(), status.ListenAddr)
+	}
+
+	out := command.OutOrStdout()
+	if statusJSON {
+		data, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(out, "%s\n", data)
+	} else {
+		printStatus(out, status)
+	}
+	if !status.Running {
+		// 状态已输出，不再重复输出错误
+		command.SilenceErrors = true
+		return errNotRunning
+	}
+	return nil
+}
+
+// checkInstanceStatus 根据 PID 文件的锁和进程是否存在判断实例状态，运行中时从日志读取启动信息
+func checkInstanceStatus() instanceStatus {
+	status := instanceStatus{
+		PIDFile: filepath.Join(mlConfig.BasePath, MLPidName),
+		LogFile: logFilePath(mlConfig.BasePath),
+	}
+	pid, locked, err := utils.ReadPIDFile(status.PIDFile)
+	status.PID = pid
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		status.State = statusNotRunning
+		return status
+	case err != nil:
+		status.State = statusUnknown
+		status.Error = err.Error()
+		return status
+	case !locked || !utils.ProcessAlive(pid):
+		// PID 文件未被锁定或进程已不存在，实例异常退出后留下了 PID 文件
+		status.State = statusStale
+		return status
+	}
+	status.State, status.Running = statusRunning, true
+	status.ListenAddr = mlConfig.ListenAddr
+
+	banner, err := readStartupBanner(status.LogFile, pid)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.StartedAt = banner.Time
+	status.Version = banner.Version
+	status.Transport = banner.Transport
+	if banner.ListenAddr != "" {
+		status.ListenAddr = banner.ListenAddr
+	}
+	status.Modules = banner.Modules
+	return status
+}
+
+// readStartupBanner 从日志末尾查找 pid 最近一次写入的启动信息
+func readStartupBanner(logFile string, pid int) (startupBanner, error) {
+	data, _, err := readTail(logFile, statusLogTail)
+	if err != nil {
+		return startupBanner{}, err
+	}
+	lines := strings.Split(string(data), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if !strings.Contains(lines[i], startupBannerMsg) {
+			continue
+		}
+		var banner startupBanner
+		if json.Unmarshal([]byte(lines[i]), &banner) != nil || banner.Message != startupBannerMsg {
+			continue
+		}
+		if banner.PID == pid {
+			return banner, nil
+		}
+	}
+	return startupBanner{}, fmt.Errorf("no startup banner of pid %d in the last %d KB of %s", pid, statusLogTail/1024, logFile)
+}
+
+// probeHealth 请求 /healthz，成功时返回 ok，否则返回失败原因
+func probeHealth(ctx context.Context, listenAddr string) string {
+	ctx, cancel := context.WithTimeout(ctx, debugProbeTimeout)
+	defer cancel()
+	addr := strings.TrimPrefix(listenAddr, "http://")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr+server.HealthzPath, nil)
+	if err != nil {
+		return err.Error()
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err.Error()
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.Status
+	}
+	return "ok"
+}
+
+// printStatus 以便于阅读的格式输出状态
+func printStatus(out io.Writer, status instanceStatus) {
+	_, _ = fmt.Fprintf(out, "MoLing is %s\n", status.State)
+	field := func(name, value string) {
+		if value != "" {
+			_, _ = fmt.Fprintf(out, "  %-12s %s\n", name+":", value)
+		}
+	}
+	if status.PID > 0 {
+		field("PID", fmt.Sprintf("%d", status.PID))
+	}
+	field("PID file", status.PIDFile)
+	field("Started at", status.StartedAt)
+	field("Version", status.Version)
+	field("Transport", status.Transport)
+	field("Listen addr", status.ListenAddr)
+	field("Modules", strings.Join(status.Modules, ", "))
+	field("Health", status.Health)
+	field("Log file", status.LogFile)
+	field("Error", status.Error)
+}
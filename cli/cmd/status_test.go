@@ -0,0 +1,146 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gojue/moling/pkg/server"
+	"github.com/gojue/moling/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// runStatus 在 basePath 下运行 status 命令，返回标准输出和错误
+func runStatus(t *testing.T, basePath string, asJSON bool) (string, error) {
+	t.Helper()
+	restoreGlobalConfig(t)
+	oldBasePath, oldJSON, oldProbe := mlConfig.BasePath, statusJSON, statusProbe
+	t.Cleanup(func() {
+		mlConfig.BasePath, statusJSON, statusProbe = oldBasePath, oldJSON, oldProbe
+	})
+	mlConfig.BasePath, mlConfig.ListenAddr = basePath, ""
+	statusJSON, statusProbe = asJSON, true
+
+	var stdout bytes.Buffer
+	command := &cobra.Command{}
+	command.Flags().AddFlagSet(rootCmd.PersistentFlags())
+	command.SetOut(&stdout)
+	err := StatusCommandFunc(command, nil)
+	if err != nil && !command.SilenceErrors {
+		t.Errorf("Expected the error output to be silenced")
+	}
+	return stdout.String(), err
+}
+
+func TestStatusCommand(t *testing.T) {
+	t.Run("NotRunning", func(t *testing.T) {
+		out, err := runStatus(t, t.TempDir(), false)
+		if !errors.Is(err, errNotRunning) {
+			t.Errorf("Expected errNotRunning, got %v", err)
+		}
+		if !strings.HasPrefix(out, "MoLing is not running\n") {
+			t.Errorf("Unexpected output: %s", out)
+		}
+	})
+
+	t.Run("StalePIDFile", func(t *testing.T) {
+		basePath := t.TempDir()
+		if err := os.WriteFile(filepath.Join(basePath, MLPidName), []byte("12345\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		out, err := runStatus(t, basePath, true)
+		if !errors.Is(err, errNotRunning) {
+			t.Errorf("Expected errNotRunning, got %v", err)
+		}
+		var status instanceStatus
+		if err := json.Unmarshal([]byte(out), &status); err != nil {
+			t.Fatalf("invalid JSON output: %v\n%s", err, out)
+		}
+		if status.State != statusStale || status.Running || status.PID != 12345 {
+			t.Errorf("Expected a stale PID file of 12345, got %+v", status)
+		}
+	})
+
+	t.Run("Running", func(t *testing.T) {
+		basePath := t.TempDir()
+		pidFile := filepath.Join(basePath, MLPidName)
+		if err := utils.CreatePIDFile(pidFile); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = utils.RemovePIDFile(pidFile) })
+
+		health := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != server.HealthzPath {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write([]byte("ok"))
+		}))
+		defer health.Close()
+		addr := strings.TrimPrefix(health.URL, "http://")
+
+		// 最近一次启动信息属于当前进程，之前的属于已退出的实例
+		log := strings.Join([]string{
+			fmt.Sprintf(`{"level":"info","pid":%d,"version":"old","transport":"stdio","listenAddr":"","modules":["Command"],"time":"2025-01-01T00:00:00Z","message":%q}`, os.Getpid()+1, startupBannerMsg),
+			fmt.Sprintf(`{"level":"info","pid":%d,"version":"v1.2.3","transport":"sse","listenAddr":%q,"modules":["Browser","FileSystem"],"time":"2025-02-01T00:00:00Z","message":%q}`, os.Getpid(), addr, startupBannerMsg),
+			`{"level":"info","message":"Starting SSE server"}`,
+		}, "\n") + "\n"
+		if err := os.MkdirAll(filepath.Join(basePath, "logs"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(logFilePath(basePath), []byte(log), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := runStatus(t, basePath, true)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		var status instanceStatus
+		if err := json.Unmarshal([]byte(out), &status); err != nil {
+			t.Fatalf("invalid JSON output: %v\n%s", err, out)
+		}
+		want := instanceStatus{
+			State: statusRunning, Running: true, PID: os.Getpid(), PIDFile: pidFile, LogFile: logFilePath(basePath),
+			StartedAt: "2025-02-01T00:00:00Z", Version: "v1.2.3", Transport: "sse", ListenAddr: addr,
+			Modules: []string{"Browser", "FileSystem"}, Health: "ok",
+		}
+		if !reflect.DeepEqual(status, want) {
+			t.Errorf("Expected %+v, got %+v", want, status)
+		}
+
+		out, err = runStatus(t, basePath, false)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		for _, line := range []string{"MoLing is running", "Modules:     Browser, FileSystem", "Listen addr: " + addr, "Health:      ok"} {
+			if !strings.Contains(out, line) {
+				t.Errorf("Expected %q in the output, got:\n%s", line, out)
+			}
+		}
+	})
+}
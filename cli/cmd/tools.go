@@ -0,0 +1,279 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gojue/moling/pkg/services"
+	"github.com/gojue/moling/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	toolsCmd.Flags().BoolVar(&toolsJson, "json", false, "Print the tools, prompts and resources as JSON, including the input schemas")
+	rootCmd.AddCommand(toolsCmd)
+}
+
+// toolsCmd 列出各服务提供的工具、提示词和资源
+var toolsCmd = &cobra.Command{
+	Use:   "tools",
+	Short: "List the tools, prompts and resources provided by the services",
+	Long: `List the tools, prompts and resources provided by the services selected with --module, without
+connecting an MCP client. No browser or other heavy resource is started. A service whose config is
+invalid is listed with its default config, and a warning is printed.
+    moling tools
+    moling tools -m Browser --json
+`,
+	RunE: ToolsCommandFunc,
+}
+
+var toolsJson bool
+
+// paramInfo 工具参数，来自工具的输入 schema
+type paramInfo struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Required    bool   `json:"required"`
+	Description string `json:"description,omitempty"`
+}
+
+// toolInfo 工具列表的输出格式
+type toolInfo struct {
+	Service     string      `json:"service"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Parameters  []paramInfo `json:"parameters"`
+	InputSchema interface{} `json:"input_schema"`
+}
+
+// promptInfo 提示词列表的输出格式
+type promptInfo struct {
+	Service     string `json:"service"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// resourceInfo 资源及资源模板列表的输出格式，资源模板的 URI 为模板
+type resourceInfo struct {
+	Service     string `json:"service"`
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	MIMEType    string `json:"mime_type,omitempty"`
+}
+
+// serviceCatalog 各服务的工具、提示词和资源，以及初始化过程中的警告
+type serviceCatalog struct {
+	Tools     []toolInfo     `json:"tools"`
+	Prompts   []promptInfo   `json:"prompts"`
+	Resources []resourceInfo `json:"resources"`
+	Warnings  []string       `json:"warnings,omitempty"`
+}
+
+// ToolsCommandFunc executes the "tools" command.
+func ToolsCommandFunc(command *cobra.Command, args []string) error {
+	// 日志只写入文件，保持标准输出干净
+	logger := initLogger(mlConfig.BasePath)
+	mlConfig.SetLogger(logger)
+	ctx := createContext(logger)
+
+	configJson, _, err := loadExistingConfig(filepath.Join(mlConfig.BasePath, mlConfig.ConfigFile))
+	if err != nil {
+		// 配置文件无法解析时使用默认配置
+		logger.Warn().Err(err).Msg("failed to load config, using the default config")
+		_, _ = fmt.Fprintf(command.ErrOrStderr(), "warning: %v, using the default config\n", err)
+		configJson = nil
+	}
+	if configJson, err = applyPreset(configJson, selectedPreset()); err != nil {
+		return err
+	}
+
+	catalog := collectCatalog(ctx, configJson)
+	for _, warning := range catalog.Warnings {
+		logger.Warn().Msg(warning)
+		_, _ = fmt.Fprintf(command.ErrOrStderr(), "warning: %s\n", warning)
+	}
+
+	out := command.OutOrStdout()
+	if toolsJson {
+		data, err := json.MarshalIndent(catalog, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	}
+	return printCatalog(out, catalog)
+}
+
+// collectCatalog 只注册各服务的工具而不启动服务，收集 --module 选中的服务的工具、提示词和资源。
+// 服务配置无效时记录警告并使用默认配置，服务无法创建时记录警告并跳过。
+func collectCatalog(ctx context.Context, configJson map[string]interface{}) serviceCatalog {
+	known := knownModules(nil)
+	moduleList, unknown := parseModules(mlConfig.Module, known)
+
+	catalog := serviceCatalog{Tools: []toolInfo{}, Prompts: []promptInfo{}, Resources: []resourceInfo{}}
+	if len(unknown) > 0 {
+		catalog.Warnings = append(catalog.Warnings, unknownModulesError(unknown, known).Error())
+	}
+	for serviceName, serviceFactory := range services.ServiceList() {
+		if len(moduleList) > 0 && !utils.StringInSlice(string(serviceName), moduleList) {
+			continue
+		}
+		srv, err := initSingleService(ctx, serviceName, serviceFactory, configJson)
+		if err != nil && configJson != nil {
+			catalog.Warnings = append(catalog.Warnings, fmt.Sprintf("%v, listing %s with the default config", err, serviceName))
+			srv, err = initSingleService(ctx, serviceName, serviceFactory, nil)
+		}
+		if err != nil {
+			catalog.Warnings = append(catalog.Warnings, fmt.Sprintf("%v, skipping %s", err, serviceName))
+			continue
+		}
+
+		service := string(srv.Name())
+		for _, tool := range srv.Tools() {
+			catalog.Tools = append(catalog.Tools, toolInfo{
+				Service:     service,
+				Name:        tool.Tool.Name,
+				Description: tool.Tool.Description,
+				Parameters:  toolParams(tool.Tool.InputSchema),
+				InputSchema: tool.Tool.InputSchema,
+			})
+		}
+		for _, prompt := range srv.Prompts() {
+			catalog.Prompts = append(catalog.Prompts, promptInfo{
+				Service:     service,
+				Name:        prompt.Prompt().Name,
+				Description: prompt.Prompt().Description,
+			})
+		}
+		for resource := range srv.Resources() {
+			catalog.Resources = append(catalog.Resources, resourceInfo{
+				Service:     service,
+				URI:         resource.URI,
+				Name:        resource.Name,
+				Description: resource.Description,
+				MIMEType:    resource.MIMEType,
+			})
+		}
+		for template := range srv.ResourceTemplates() {
+			uri := ""
+			if template.URITemplate != nil {
+				uri = template.URITemplate.Raw()
+			}
+			catalog.Resources = append(catalog.Resources, resourceInfo{
+				Service:     service,
+				URI:         uri,
+				Name:        template.Name,
+				Description: template.Description,
+				MIMEType:    template.MIMEType,
+			})
+		}
+	}
+
+	sort.Slice(catalog.Tools, func(i, j int) bool {
+		if catalog.Tools[i].Service != catalog.Tools[j].Service {
+			return catalog.Tools[i].Service < catalog.Tools[j].Service
+		}
+		return catalog.Tools[i].Name < catalog.Tools[j].Name
+	})
+	sort.Slice(catalog.Prompts, func(i, j int) bool {
+		if catalog.Prompts[i].Service != catalog.Prompts[j].Service {
+			return catalog.Prompts[i].Service < catalog.Prompts[j].Service
+		}
+		return catalog.Prompts[i].Name < catalog.Prompts[j].Name
+	})
+	sort.Slice(catalog.Resources, func(i, j int) bool {
+		if catalog.Resources[i].Service != catalog.Resources[j].Service {
+			return catalog.Resources[i].Service < catalog.Resources[j].Service
+		}
+		return catalog.Resources[i].URI < catalog.Resources[j].URI
+	})
+	sort.Strings(catalog.Warnings)
+	return catalog
+}
+
+// toolParams 从工具的输入 schema 中提取参数，必填参数在前，其余按名称排序
+func toolParams(schema mcp.ToolInputSchema) []paramInfo {
+	params := make([]paramInfo, 0, len(schema.Properties))
+	for name, raw := range schema.Properties {
+		param := paramInfo{Name: name, Required: utils.StringInSlice(name, schema.Required)}
+		if prop, ok := raw.(map[string]interface{}); ok {
+			param.Type, _ = prop["type"].(string)
+			param.Description, _ = prop["description"].(string)
+		}
+		params = append(params, param)
+	}
+	sort.Slice(params, func(i, j int) bool {
+		if params[i].Required != params[j].Required {
+			return params[i].Required
+		}
+		return params[i].Name < params[j].Name
+	})
+	return params
+}
+
+// printCatalog 以表格形式输出工具、提示词和资源，可选参数用方括号标出
+func printCatalog(out io.Writer, catalog serviceCatalog) error {
+	tw := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "SERVICE\tTOOL\tDESCRIPTION\tPARAMETERS")
+	for _, tool := range catalog.Tools {
+		params := make([]string, 0, len(tool.Parameters))
+		for _, param := range tool.Parameters {
+			if param.Required {
+				params = append(params, param.Name)
+			} else {
+				params = append(params, "["+param.Name+"]")
+			}
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", tool.Service, tool.Name, firstSentence(tool.Description), strings.Join(params, " "))
+	}
+
+	if len(catalog.Prompts) > 0 {
+		_, _ = fmt.Fprintln(tw, "\t\t\t")
+		_, _ = fmt.Fprintln(tw, "SERVICE\tPROMPT\tDESCRIPTION\t")
+		for _, prompt := range catalog.Prompts {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t\n", prompt.Service, prompt.Name, firstSentence(prompt.Description))
+		}
+	}
+
+	if len(catalog.Resources) > 0 {
+		_, _ = fmt.Fprintln(tw, "\t\t\t")
+		_, _ = fmt.Fprintln(tw, "SERVICE\tRESOURCE\tDESCRIPTION\t")
+		for _, resource := range catalog.Resources {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t\n", resource.Service, resource.URI, firstSentence(resource.Description))
+		}
+	}
+	return tw.Flush()
+}
+
+// firstSentence 返回描述的第一句，避免表格过宽
+func firstSentence(desc string) string {
+	if i := strings.IndexAny(desc, ".\n"); i > 0 {
+		return desc[:i]
+	}
+	return desc
+}
@@ -0,0 +1,125 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// runToolsCommand 以 --json 运行 tools 命令，返回解析后的输出和标准错误输出
+func runToolsCommand(t *testing.T, module string, configJson string) (serviceCatalog, string) {
+	t.Helper()
+	basePath := t.TempDir()
+	for _, dir := range []string{"logs", "config"} {
+		if err := os.MkdirAll(filepath.Join(basePath, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if configJson != "" {
+		if err := os.WriteFile(filepath.Join(basePath, "config", MLConfigName), []byte(configJson), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldBasePath, oldModule, oldJson := mlConfig.BasePath, mlConfig.Module, toolsJson
+	t.Cleanup(func() {
+		mlConfig.BasePath, mlConfig.Module, toolsJson = oldBasePath, oldModule, oldJson
+	})
+	mlConfig.BasePath, mlConfig.Module, toolsJson = basePath, module, true
+
+	var stdout, stderr bytes.Buffer
+	command := &cobra.Command{}
+	command.SetOut(&stdout)
+	command.SetErr(&stderr)
+	if err := ToolsCommandFunc(command, nil); err != nil {
+		t.Fatalf("tools command failed: %v", err)
+	}
+	var catalog serviceCatalog
+	if err := json.Unmarshal(stdout.Bytes(), &catalog); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, stdout.String())
+	}
+	return catalog, stderr.String()
+}
+
+func TestToolsCommand(t *testing.T) {
+	t.Run("BrowserNavigate", func(t *testing.T) {
+		catalog, _ := runToolsCommand(t, "all", "")
+		var found bool
+		for _, tool := range catalog.Tools {
+			if tool.Name != "browser_navigate" {
+				continue
+			}
+			found = true
+			if tool.Service != "Browser" {
+				t.Errorf("Expected the Browser service, got %s", tool.Service)
+			}
+			var urlRequired bool
+			for _, param := range tool.Parameters {
+				if param.Name == "url" {
+					urlRequired = param.Required
+				}
+			}
+			if !urlRequired {
+				t.Errorf("Expected the url parameter to be required, got %+v", tool.Parameters)
+			}
+		}
+		if !found {
+			t.Fatal("Expected browser_navigate in the tool list")
+		}
+		if len(catalog.Prompts) == 0 {
+			t.Error("Expected the prompts of the services to be listed")
+		}
+	})
+
+	t.Run("ModuleFilter", func(t *testing.T) {
+		catalog, _ := runToolsCommand(t, "Command", "")
+		if len(catalog.Tools) == 0 {
+			t.Fatal("Expected the tools of the Command service")
+		}
+		for _, tool := range catalog.Tools {
+			if tool.Service != "Command" {
+				t.Errorf("Expected only Command tools, got %s from %s", tool.Name, tool.Service)
+			}
+		}
+		for _, prompt := range catalog.Prompts {
+			if prompt.Service != "Command" {
+				t.Errorf("Expected only Command prompts, got %s from %s", prompt.Name, prompt.Service)
+			}
+		}
+	})
+
+	t.Run("InvalidServiceConfig", func(t *testing.T) {
+		catalog, stderr := runToolsCommand(t, "Browser,Command", `{"Browser": {"timeout": -1}}`)
+		if !strings.Contains(stderr, "timeout must be greater than 0") {
+			t.Errorf("Expected a warning for the invalid Browser config, got %q", stderr)
+		}
+		services := map[string]bool{}
+		for _, tool := range catalog.Tools {
+			services[tool.Service] = true
+		}
+		if !services["Browser"] || !services["Command"] {
+			t.Errorf("Expected both services to be listed, got %v", services)
+		}
+	})
+}
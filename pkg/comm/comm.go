@@ -19,6 +19,10 @@ type contextKey string
 const (
 	MoLingConfigKey contextKey = "moling_config"
 	MoLingLoggerKey contextKey = "moling_logger"
+	// MoLingSessionKey stores the ID of the MCP client session of a tool call.
+	MoLingSessionKey contextKey = "moling_session"
+	// MoLingSessionStateKey stores the session-scoped state of the called service.
+	MoLingSessionStateKey contextKey = "moling_session_state"
 )
 
 // InitTestEnv initializes the test environment by creating a temporary log file and setting up the logger.
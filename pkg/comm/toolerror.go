@@ -0,0 +1,117 @@
+package comm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ErrorCode classifies a tool error so that clients can handle it without parsing the message.
+type ErrorCode string
+
+const (
+	ErrCodeInvalidArgument  ErrorCode = "INVALID_ARGUMENT"  // 参数缺失、类型错误或取值无效
+	ErrCodeTimeout          ErrorCode = "TIMEOUT"           // 操作超时
+	ErrCodeNotFound         ErrorCode = "NOT_FOUND"         // 文件、元素、会话等不存在
+	ErrCodePermissionDenied ErrorCode = "PERMISSION_DENIED" // 路径不在允许范围内、命令不允许执行或无权限
+	ErrCodeInternal         ErrorCode = "INTERNAL"          // 其他错误
+)
+
+// ToolErrorPayload is the JSON payload of a tool error result.
+type ToolErrorPayload struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	Details string    `json:"details,omitempty"` // Details keeps the original error, e.g. of the OS or the browser.
+}
+
+// CodedError attaches an ErrorCode to an error, so that helpers returning errors can decide the
+// code of the tool error built from them.
+type CodedError struct {
+	Code ErrorCode
+	Err  error
+}
+
+func (e *CodedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CodedError) Unwrap() error {
+	return e.Err
+}
+
+// NewCodedError wraps err with code.
+func NewCodedError(code ErrorCode, err error) error {
+	return &CodedError{Code: code, Err: err}
+}
+
+// ErrorCodeOf returns the ErrorCode of err: the code of a wrapped CodedError, TIMEOUT for
+// deadlines, NOT_FOUND and PERMISSION_DENIED for the matching OS errors, INTERNAL otherwise.
+func ErrorCodeOf(err error) ErrorCode {
+	var coded *CodedError
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return ErrCodeInternal
+	case errors.As(err, &coded):
+		return coded.Code
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		return ErrCodeTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ErrCodeTimeout
+	case errors.Is(err, os.ErrNotExist):
+		return ErrCodeNotFound
+	case errors.Is(err, os.ErrPermission):
+		return ErrCodePermissionDenied
+	}
+	return ErrCodeInternal
+}
+
+// ToolError builds an error result whose text is the JSON encoded ToolErrorPayload.
+func ToolError(code ErrorCode, msg, details string) *mcp.CallToolResult {
+	// 不转义命令行、选择器中的 & < >
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(ToolErrorPayload{Code: code, Message: msg, Details: details}); err != nil {
+		return mcp.NewToolResultError(msg)
+	}
+	return mcp.NewToolResultError(strings.TrimSuffix(buf.String(), "\n"))
+}
+
+// ToolErrorFromErr builds an error result from err, the code is taken from ErrorCodeOf and the
+// error text is kept in details.
+func ToolErrorFromErr(msg string, err error) *mcp.CallToolResult {
+	if err == nil {
+		return ToolError(ErrCodeInternal, msg, "")
+	}
+	return ToolError(ErrorCodeOf(err), msg, err.Error())
+}
+
+// ArgumentError builds the error result of an argument that failed to parse or validate, the
+// error text is the message. Errors with a specific code, e.g. a missing file, keep it, the
+// others are INVALID_ARGUMENT.
+func ArgumentError(err error) *mcp.CallToolResult {
+	code := ErrorCodeOf(err)
+	if code == ErrCodeInternal {
+		code = ErrCodeInvalidArgument
+	}
+	return ToolError(code, err.Error(), "")
+}
+
+// ParseToolError decodes the ToolErrorPayload of an error result, ok is false when result is not
+// an error built by ToolError.
+func ParseToolError(result *mcp.CallToolResult) (payload ToolErrorPayload, ok bool) {
+	if result == nil || !result.IsError || len(result.Content) == 0 {
+		return payload, false
+	}
+	text, isText := result.Content[0].(mcp.TextContent)
+	if !isText || json.Unmarshal([]byte(text.Text), &payload) != nil || payload.Code == "" {
+		return ToolErrorPayload{}, false
+	}
+	return payload, true
+}
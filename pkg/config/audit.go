@@ -0,0 +1,49 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package config
+
+import "fmt"
+
+// AuditConfig configures the audit log of tool calls, prompt gets and resource reads, enabled by
+// MoLingConfig.AuditLog.
+type AuditConfig struct {
+	MaxSize       int      `json:"max_size"`       // MaxSize is the size of an audit log file before it is rotated, default 64MB.
+	MaxFiles      int      `json:"max_files"`      // MaxFiles is the number of rotated audit log files kept, default 4.
+	BufferSize    int      `json:"buffer_size"`    // BufferSize is the number of entries buffered for the background writer, entries beyond it are dropped and counted.
+	SensitiveKeys []string `json:"sensitive_keys"` // SensitiveKeys are argument names whose values are redacted, matched case-insensitively as substrings.
+}
+
+// NewAuditConfig creates an AuditConfig with default values.
+func NewAuditConfig() AuditConfig {
+	return AuditConfig{
+		MaxSize:    64 * 1024 * 1024, // 64MB
+		MaxFiles:   4,
+		BufferSize: 1024,
+		SensitiveKeys: []string{
+			"password", "passwd", "secret", "token", "api_key", "api-key", "apikey",
+			"authorization", "cookie", "credential", "private_key",
+		},
+	}
+}
+
+// Check validates the AuditConfig.
+func (ac *AuditConfig) Check() error {
+	if ac.MaxSize <= 0 || ac.MaxFiles <= 0 || ac.BufferSize <= 0 {
+		return fmt.Errorf("audit max size, max files and buffer size must be greater than 0")
+	}
+	return nil
+}
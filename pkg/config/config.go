@@ -17,8 +17,15 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 
+	"github.com/gojue/moling/pkg/utils"
 	"github.com/rs/zerolog"
 )
 
@@ -33,13 +40,28 @@ type MoLingConfig struct {
 	ConfigFile string `json:"config_file"` // The path to the configuration file.
 	BasePath   string `json:"base_path"`   // The base path for the server, used for storing files. automatically created if not exists. eg: /Users/user1/.moling
 	//AllowDir   []string `json:"allow_dir"`   // The directories that are allowed to be accessed by the server.
-	Version    string `json:"version"`     // The version of the MoLing server.
-	ListenAddr string `json:"listen_addr"` // The address to listen on for SSE mode.
-	Debug      bool   `json:"debug"`       // Debug mode, if true, the server will run in debug mode.
-	Module     string `json:"module"`      // The module to load, default: all
-	Username   string // The username of the user running the server.
-	HomeDir    string // The home directory of the user running the server. macOS: /Users/user1, Linux: /home/user1
-	SystemInfo string // The system information of the user running the server. macOS: Darwin 15.3.3, Linux: Ubuntu 20.04.1 LTS
+	Version            string            `json:"version"`              // The version of the MoLing server.
+	ListenAddr         string            `json:"listen_addr"`          // The address to listen on for the sse and http transports.
+	TransportMode      string            `json:"transport_mode"`       // TransportMode is stdio, sse or http (streamable HTTP), empty: sse if listen_addr is set, stdio otherwise.
+	AuthToken          string            `json:"auth_token"`           // AuthToken is required as "Authorization: Bearer <token>" or ?token= by the sse and http transports, empty disables authentication.
+	Debug              bool              `json:"debug"`                // Debug mode, if true, the server will run in debug mode.
+	Module             string            `json:"module"`               // The module to load, default: all
+	RateLimit          RateLimitConfig   `json:"rate_limit"`           // Rate limits of tool calls, 0 means unlimited.
+	ResultLimit        ResultLimitConfig `json:"result_limit"`         // Size limits of tool results, oversized results overflow to data/overflow.
+	Plugins            PluginConfig      `json:"plugins"`              // External services loaded from the plugin directory.
+	Session            SessionConfig     `json:"session"`              // Session-scoped service state of MCP client sessions.
+	AuditLog           bool              `json:"audit_log"`            // AuditLog appends every tool call, prompt get and resource read to logs/audit.jsonl.
+	Audit              AuditConfig       `json:"audit"`                // Rotation, buffering and redaction of the audit log.
+	ShutdownTimeout    int               `json:"shutdown_timeout"`     // ShutdownTimeout caps the time all services have to close on exit. time.Second
+	ServiceInitTimeout int               `json:"service_init_timeout"` // ServiceInitTimeout caps the time each service has to initialize, the services initialize in parallel. time.Second
+	MetricsEnabled     bool              `json:"metrics_enabled"`      // MetricsEnabled serves Prometheus metrics at /metrics in SSE mode.
+	MetricsListenAddr  string            `json:"metrics_listen_addr"`  // MetricsListenAddr serves /metrics on its own address instead of the SSE address, e.g. 127.0.0.1:9464.
+	ToolNamePrefixing  bool              `json:"tool_name_prefixing"`  // ToolNamePrefixing prefixes tool and prompt names with the service name, e.g. browser.browser_navigate, instead of failing on name collisions.
+	ReloadOnChange     bool              `json:"reload_on_change"`     // ReloadOnChange reloads the service configuration when config.json changes, like SIGHUP does.
+	NoPromptResources  bool              `json:"no_prompt_resources"`  // NoPromptResources stops mirroring the prompts of the services as moling://prompts/{name} resources.
+	Username           string            // The username of the user running the server.
+	HomeDir            string            // The home directory of the user running the server. macOS: /Users/user1, Linux: /home/user1
+	SystemInfo         string            // The system information of the user running the server. macOS: Darwin 15.3.3, Linux: Ubuntu 20.04.1 LTS
 
 	// for MCP Server Config
 	Description string // Description of the MCP Server, default: CliDescription
@@ -50,8 +72,56 @@ type MoLingConfig struct {
 	logger      zerolog.Logger
 }
 
+var (
+	modulesLock  sync.RWMutex
+	knownModules = make(map[string]string)
+)
+
+// RegisterModule records a module name accepted by the module key. The services registry and
+// plugin discovery register their names here, since this package can't import them.
+func RegisterModule(name string) {
+	modulesLock.Lock()
+	defer modulesLock.Unlock()
+	knownModules[strings.ToLower(name)] = name
+}
+
+// isKnownModule reports whether name is a registered module, compared case-insensitively like
+// --module. Nothing registered means nothing to validate against, every name is accepted.
+func isKnownModule(name string) bool {
+	modulesLock.RLock()
+	defer modulesLock.RUnlock()
+	if len(knownModules) == 0 {
+		return true
+	}
+	_, ok := knownModules[strings.ToLower(name)]
+	return ok
+}
+
+// Check validates the base path, listen addresses, module names and version of the MoLing
+// server, all invalid fields are reported together with their config keys.
 func (cfg *MoLingConfig) Check() error {
-	panic("not implemented yet") // TODO: Implement Check
+	var errs []error
+	if err := checkBasePath(cfg.BasePath); err != nil {
+		errs = append(errs, fmt.Errorf("base_path: %w", err))
+	}
+	if cfg.ListenAddr != "" {
+		// 与 Serve 保持一致，允许 http:// 前缀
+		if err := checkListenAddr(strings.TrimPrefix(cfg.ListenAddr, "http://")); err != nil {
+			errs = append(errs, fmt.Errorf("listen_addr: %w", err))
+		}
+	}
+	if cfg.MetricsListenAddr != "" {
+		if err := checkListenAddr(cfg.MetricsListenAddr); err != nil {
+			errs = append(errs, fmt.Errorf("metrics_listen_addr: %w", err))
+		}
+	}
+	if err := checkModules(cfg.Module); err != nil {
+		errs = append(errs, fmt.Errorf("module: %w", err))
+	}
+	if strings.TrimSpace(cfg.Version) == "" {
+		errs = append(errs, fmt.Errorf("version: must not be empty"))
+	}
+	return errors.Join(errs...)
 }
 
 func (cfg *MoLingConfig) Logger() zerolog.Logger {
@@ -65,3 +135,54 @@ func (cfg *MoLingConfig) SetLogger(logger zerolog.Logger) {
 func (cfg *MoLingConfig) String() string {
 	return fmt.Sprintf("ConfigFile: %s, BasePath: %s, Version: %s, ListenAddr: %s, Debug: %t, Module: %s, Username: %s, HomeDir: %s, SystemInfo: %s", cfg.ConfigFile, cfg.BasePath, cfg.Version, cfg.ListenAddr, cfg.Debug, cfg.Module, cfg.Username, cfg.HomeDir, cfg.SystemInfo)
 }
+
+// checkBasePath 检查 base_path 存在、是目录且可写
+func checkBasePath(path string) error {
+	if path == "" {
+		return fmt.Errorf("must not be empty")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("%s does not exist: %w", path, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", path)
+	}
+	// 通过创建临时文件判断是否可写，权限位无法覆盖 ACL、只读挂载等情况
+	f, err := os.CreateTemp(path, ".moling-check-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %w", path, err)
+	}
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+	return nil
+}
+
+// checkListenAddr 检查地址是否为 host:port 格式，host 可以为空
+func checkListenAddr(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("%q is not host:port: %w", addr, err)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf("%q has an invalid port %q", addr, port)
+	}
+	return nil
+}
+
+// checkModules 检查 module 中的每个名称是否已注册，与 --module 一致，包含 all 表示全部模块
+func checkModules(module string) error {
+	var unknown []string
+	for _, name := range utils.SplitAndTrim(module, ",") {
+		if strings.EqualFold(name, "all") {
+			return nil
+		}
+		if !isKnownModule(name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown module %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
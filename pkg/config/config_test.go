@@ -23,6 +23,8 @@ package config
 import (
 	"encoding/json"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/gojue/moling/pkg/utils"
@@ -61,3 +63,60 @@ func TestConfigLoad(t *testing.T) {
 		t.Fatalf("expected BasePath to be '/newpath/.moling', got '%s'", cfg.BasePath)
 	}
 }
+
+func TestMoLingConfigCheck(t *testing.T) {
+	RegisterModule("Browser")
+	RegisterModule("FileSystem")
+	basePath := t.TempDir()
+	notDir := filepath.Join(basePath, "file")
+	if err := os.WriteFile(notDir, nil, 0o600); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	valid := func() MoLingConfig {
+		return MoLingConfig{BasePath: basePath, Version: "v1.0.0", ListenAddr: "127.0.0.1:6789", Module: "Browser, filesystem"}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(cfg *MoLingConfig)
+		wantKey string
+	}{
+		{name: "valid", modify: func(cfg *MoLingConfig) {}},
+		{name: "stdio without listen_addr", modify: func(cfg *MoLingConfig) { cfg.ListenAddr = "" }},
+		{name: "listen_addr with scheme", modify: func(cfg *MoLingConfig) { cfg.ListenAddr = "http://:6789" }},
+		{name: "all modules", modify: func(cfg *MoLingConfig) { cfg.Module = "all" }},
+		{name: "empty base_path", modify: func(cfg *MoLingConfig) { cfg.BasePath = "" }, wantKey: "base_path"},
+		{name: "missing base_path", modify: func(cfg *MoLingConfig) { cfg.BasePath = filepath.Join(basePath, "missing") }, wantKey: "base_path"},
+		{name: "base_path is a file", modify: func(cfg *MoLingConfig) { cfg.BasePath = notDir }, wantKey: "base_path"},
+		{name: "listen_addr without port", modify: func(cfg *MoLingConfig) { cfg.ListenAddr = "127.0.0.1" }, wantKey: "listen_addr"},
+		{name: "listen_addr with invalid port", modify: func(cfg *MoLingConfig) { cfg.ListenAddr = "127.0.0.1:99999" }, wantKey: "listen_addr"},
+		{name: "invalid metrics_listen_addr", modify: func(cfg *MoLingConfig) { cfg.MetricsListenAddr = "localhost" }, wantKey: "metrics_listen_addr"},
+		{name: "unknown module", modify: func(cfg *MoLingConfig) { cfg.Module = "Browser,Unknown" }, wantKey: "module"},
+		{name: "empty version", modify: func(cfg *MoLingConfig) { cfg.Version = " " }, wantKey: "version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(&cfg)
+			err := cfg.Check()
+			if tt.wantKey == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantKey+":") {
+				t.Fatalf("expected a %s error, got %v", tt.wantKey, err)
+			}
+		})
+	}
+
+	// 所有无效字段一并报告
+	cfg := MoLingConfig{ListenAddr: "invalid", Module: "Unknown"}
+	err := cfg.Check()
+	for _, key := range []string{"base_path:", "listen_addr:", "module: unknown module Unknown", "version:"} {
+		if err == nil || !strings.Contains(err.Error(), key) {
+			t.Errorf("expected %q in error, got %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package config
+
+import "fmt"
+
+// PluginConfig controls loading external services from the plugin directory.
+type PluginConfig struct {
+	Enabled           bool   `json:"enabled"`             // Enabled loads the plugins on startup, default true.
+	Dir               string `json:"dir"`                 // Dir is the plugin directory, default: <BasePath>/plugins.
+	MaxRestartBackoff int    `json:"max_restart_backoff"` // MaxRestartBackoff caps the delay before a crashed plugin process is restarted, default 60. time.Second
+}
+
+// NewPluginConfig creates a PluginConfig with default values.
+func NewPluginConfig() PluginConfig {
+	return PluginConfig{
+		Enabled:           true,
+		MaxRestartBackoff: 60,
+	}
+}
+
+// Check validates the PluginConfig.
+func (pc *PluginConfig) Check() error {
+	if pc.MaxRestartBackoff <= 0 {
+		return fmt.Errorf("max restart backoff must be greater than 0")
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package config
+
+import "fmt"
+
+// RateLimit limits the tool calls of the whole server or of a single service. 0 means unlimited.
+type RateLimit struct {
+	MaxConcurrent int `json:"max_concurrent"`  // MaxConcurrent is the maximum number of tool calls executing at the same time.
+	RatePerMinute int `json:"rate_per_minute"` // RatePerMinute is the number of tool calls allowed per minute, with a burst of the same size.
+}
+
+// Unlimited reports whether the rate limit has no limit set.
+func (rl RateLimit) Unlimited() bool {
+	return rl.MaxConcurrent <= 0 && rl.RatePerMinute <= 0
+}
+
+// RateLimitConfig is the rate limit configuration of the MoLing server.
+type RateLimitConfig struct {
+	RateLimit                         // global limits, shared by all services
+	Services     map[string]RateLimit `json:"services"`       // Services holds per-service limits, keyed by service name, e.g. Command, Browser.
+	QueueOnLimit bool                 `json:"queue_on_limit"` // QueueOnLimit queues calls exceeding the limits instead of rejecting them.
+	QueueSize    int                  `json:"queue_size"`     // QueueSize is the maximum number of queued calls, further calls are rejected.
+	QueueTimeout int                  `json:"queue_timeout"`  // QueueTimeout is the maximum time a call waits in the queue. time.Second
+}
+
+// NewRateLimitConfig creates a RateLimitConfig without limits.
+func NewRateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		Services:     map[string]RateLimit{},
+		QueueSize:    64,
+		QueueTimeout: 30,
+	}
+}
+
+// Check validates the RateLimitConfig.
+func (rc *RateLimitConfig) Check() error {
+	if rc.MaxConcurrent < 0 || rc.RatePerMinute < 0 {
+		return fmt.Errorf("rate limit must not be negative")
+	}
+	for name, rl := range rc.Services {
+		if rl.MaxConcurrent < 0 || rl.RatePerMinute < 0 {
+			return fmt.Errorf("rate limit of service %s must not be negative", name)
+		}
+	}
+	if rc.QueueOnLimit && (rc.QueueSize <= 0 || rc.QueueTimeout <= 0) {
+		return fmt.Errorf("queue size and queue timeout must be greater than 0 when queue_on_limit is enabled")
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package config
+
+import "fmt"
+
+// ResultLimitConfig limits the size of tool results. Oversized results are written to the
+// overflow directory and replaced by a preview.
+type ResultLimitConfig struct {
+	MaxToolResultBytes  int            `json:"max_tool_result_bytes"`  // MaxToolResultBytes is the maximum size of a text result, 0 means unlimited.
+	MaxImageResultBytes int            `json:"max_image_result_bytes"` // MaxImageResultBytes is the maximum size of image and embedded binary content (base64), 0 means unlimited.
+	PreviewBytes        int            `json:"preview_bytes"`          // PreviewBytes is the size of the preview kept in a truncated text result, default 16KB.
+	ToolResultLimits    map[string]int `json:"tool_result_limits"`     // ToolResultLimits overrides MaxToolResultBytes per tool name, e.g. {"browser_evaluate": 2097152}
+	OverflowRetention   int            `json:"overflow_retention"`     // OverflowRetention is how long overflow files are kept, default 24. time.Hour
+	OverflowMaxFiles    int            `json:"overflow_max_files"`     // OverflowMaxFiles is the maximum number of overflow files kept, default 100.
+}
+
+// NewResultLimitConfig creates a ResultLimitConfig with default values.
+func NewResultLimitConfig() ResultLimitConfig {
+	return ResultLimitConfig{
+		MaxToolResultBytes:  512 * 1024,      // 512KB
+		MaxImageResultBytes: 5 * 1024 * 1024, // 5MB
+		PreviewBytes:        16 * 1024,       // 16KB
+		ToolResultLimits:    map[string]int{},
+		OverflowRetention:   24,
+		OverflowMaxFiles:    100,
+	}
+}
+
+// LimitFor returns the text result limit of a tool, 0 means unlimited. The limit of tool takes
+// precedence over the limits of its aliases.
+func (rc *ResultLimitConfig) LimitFor(tool string, aliases ...string) int {
+	for _, name := range append([]string{tool}, aliases...) {
+		if limit, ok := rc.ToolResultLimits[name]; ok {
+			return limit
+		}
+	}
+	return rc.MaxToolResultBytes
+}
+
+// Check validates the ResultLimitConfig.
+func (rc *ResultLimitConfig) Check() error {
+	if rc.MaxToolResultBytes < 0 || rc.MaxImageResultBytes < 0 {
+		return fmt.Errorf("result limits must not be negative")
+	}
+	for tool, limit := range rc.ToolResultLimits {
+		if limit < 0 {
+			return fmt.Errorf("result limit of tool %s must not be negative", tool)
+		}
+	}
+	if rc.PreviewBytes < 0 || rc.OverflowRetention < 0 || rc.OverflowMaxFiles < 0 {
+		return fmt.Errorf("preview bytes, overflow retention and overflow max files must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SchemaDraft is the JSON Schema dialect of the generated schemas.
+const SchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// GenerateSchema generates the JSON Schema of a config struct by reflection, so that it cannot drift
+// from the struct. Properties are the exported fields with a json tag, described by the desc tag and
+// restricted by the enum tag (comma separated, a leading comma allows the empty string). The values
+// of defaults, the config created by the NewXxxConfig function, become the default of each property.
+func GenerateSchema(title string, defaults interface{}) (string, error) {
+	v := reflect.ValueOf(defaults)
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return "", fmt.Errorf("config schema: %s is not a struct", v.Type())
+	}
+	schema := objectSchema(v)
+	schema["$schema"] = SchemaDraft
+	schema["title"] = title
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("config schema: %w", err)
+	}
+	return string(data), nil
+}
+
+// objectSchema describes the fields of the struct value v.
+func objectSchema(v reflect.Value) map[string]interface{} {
+	properties := make(map[string]interface{})
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		prop := valueSchema(v.Field(i))
+		if desc := field.Tag.Get("desc"); desc != "" {
+			prop["description"] = desc
+		}
+		if enum, ok := field.Tag.Lookup("enum"); ok {
+			prop["enum"] = strings.Split(enum, ",")
+		}
+		properties[name] = prop
+	}
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+// valueSchema describes the type of v with the value of v as default.
+func valueSchema(v reflect.Value) map[string]interface{} {
+	switch v.Kind() {
+	case reflect.Struct:
+		return objectSchema(v)
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean", "default": v.Bool()}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "default": v.Interface()}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number", "default": v.Float()}
+	case reflect.String:
+		return map[string]interface{}{"type": "string", "default": v.String()}
+	case reflect.Slice, reflect.Array:
+		prop := map[string]interface{}{"type": "array", "items": valueSchema(reflect.Zero(v.Type().Elem()))}
+		delete(prop["items"].(map[string]interface{}), "default")
+		if v.Len() > 0 {
+			prop["default"] = v.Interface()
+		}
+		return prop
+	case reflect.Map:
+		prop := map[string]interface{}{"type": "object", "additionalProperties": valueSchema(reflect.Zero(v.Type().Elem()))}
+		delete(prop["additionalProperties"].(map[string]interface{}), "default")
+		if v.Len() > 0 {
+			prop["default"] = v.Interface()
+		}
+		return prop
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return map[string]interface{}{}
+		}
+		return valueSchema(v.Elem())
+	}
+	return map[string]interface{}{}
+}
@@ -0,0 +1,136 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+type schemaTestNested struct {
+	Backend string `json:"backend" desc:"The backend." enum:",local,http"`
+	Retries int    `json:"retries"`
+}
+
+type schemaTestConfig struct {
+	Enabled   bool              `json:"enabled" desc:"Whether it is enabled."`
+	Timeout   int               `json:"timeout,omitempty"`
+	Ratio     float64           `json:"ratio"`
+	Dirs      []string          `json:"dirs"`
+	Headers   map[string]string `json:"headers"`
+	Nested    schemaTestNested  `json:"nested"`
+	Ignored   string            `json:"-"`
+	NoTag     string
+	unexposed string
+}
+
+// compileSchema compiles the schema with a JSON Schema validator, which also validates it against the meta schema.
+func compileSchema(t *testing.T, schema string) *jsonschema.Schema {
+	t.Helper()
+	doc, err := jsonschema.UnmarshalJSON(strings.NewReader(schema))
+	if err != nil {
+		t.Fatalf("Invalid schema JSON: %v", err)
+	}
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource("schema.json", doc); err != nil {
+		t.Fatalf("Failed to add schema: %v", err)
+	}
+	sch, err := c.Compile("schema.json")
+	if err != nil {
+		t.Fatalf("Failed to compile schema: %v", err)
+	}
+	return sch
+}
+
+func TestGenerateSchema(t *testing.T) {
+	defaults := &schemaTestConfig{Enabled: true, Timeout: 30, Dirs: []string{"/tmp"}, Nested: schemaTestNested{Retries: 2}}
+	schema, err := GenerateSchema("Test", defaults)
+	if err != nil {
+		t.Fatalf("GenerateSchema failed: %v", err)
+	}
+	sch := compileSchema(t, schema)
+
+	var doc struct {
+		Schema     string                            `json:"$schema"`
+		Title      string                            `json:"title"`
+		Properties map[string]map[string]interface{} `json:"properties"`
+	}
+	if err := json.Unmarshal([]byte(schema), &doc); err != nil {
+		t.Fatalf("Invalid schema JSON: %v", err)
+	}
+	if doc.Schema != SchemaDraft || doc.Title != "Test" {
+		t.Errorf("Unexpected $schema %q or title %q", doc.Schema, doc.Title)
+	}
+	var names []string
+	for name := range doc.Properties {
+		names = append(names, name)
+	}
+	if len(names) != 6 {
+		t.Errorf("Expected 6 properties without ignored, untagged and unexported fields, got %v", names)
+	}
+	for name, want := range map[string]string{"enabled": "boolean", "timeout": "integer", "ratio": "number", "dirs": "array", "headers": "object", "nested": "object"} {
+		if got := doc.Properties[name]["type"]; got != want {
+			t.Errorf("Expected %s to be %s, got %v", name, want, got)
+		}
+	}
+	if doc.Properties["enabled"]["description"] != "Whether it is enabled." || doc.Properties["enabled"]["default"] != true {
+		t.Errorf("Unexpected enabled property %v", doc.Properties["enabled"])
+	}
+	if doc.Properties["timeout"]["default"] != float64(30) {
+		t.Errorf("Expected timeout default 30, got %v", doc.Properties["timeout"]["default"])
+	}
+	if !reflect.DeepEqual(doc.Properties["dirs"]["default"], []interface{}{"/tmp"}) {
+		t.Errorf("Expected dirs default [/tmp], got %v", doc.Properties["dirs"]["default"])
+	}
+	backend := doc.Properties["nested"]["properties"].(map[string]interface{})["backend"].(map[string]interface{})
+	if !reflect.DeepEqual(backend["enum"], []interface{}{"", "local", "http"}) {
+		t.Errorf("Unexpected backend enum %v", backend["enum"])
+	}
+
+	for _, tc := range []struct {
+		instance string
+		valid    bool
+	}{
+		{`{"enabled":false,"timeout":10,"dirs":["/a"],"headers":{"X":"1"},"nested":{"backend":"http"}}`, true},
+		{`{"nested":{"backend":""}}`, true},
+		{`{"enabled":"yes"}`, false},
+		{`{"timeout":1.5}`, false},
+		{`{"dirs":[1]}`, false},
+		{`{"headers":{"X":1}}`, false},
+		{`{"nested":{"backend":"ftp"}}`, false},
+	} {
+		inst, err := jsonschema.UnmarshalJSON(strings.NewReader(tc.instance))
+		if err != nil {
+			t.Fatalf("Invalid instance %s: %v", tc.instance, err)
+		}
+		if err := sch.Validate(inst); (err == nil) != tc.valid {
+			t.Errorf("Validate(%s) = %v, expected valid=%v", tc.instance, err, tc.valid)
+		}
+	}
+
+	if _, err := GenerateSchema("Test", "not a struct"); err == nil {
+		t.Error("Expected an error for a non-struct value")
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package config
+
+import "fmt"
+
+// SessionConfig configures the session-scoped state that services keep per MCP client session.
+type SessionConfig struct {
+	IdleTimeout int `json:"idle_timeout"` // IdleTimeout closes the scoped state of a session without tool calls for this long, 0 means never. time.Second
+}
+
+// NewSessionConfig creates a SessionConfig with default values.
+func NewSessionConfig() SessionConfig {
+	return SessionConfig{
+		IdleTimeout: 1800,
+	}
+}
+
+// Check validates the SessionConfig.
+func (sc *SessionConfig) Check() error {
+	if sc.IdleTimeout < 0 {
+		return fmt.Errorf("session idle timeout must not be negative")
+	}
+	return nil
+}
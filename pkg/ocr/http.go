@@ -0,0 +1,97 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// httpRequest is the JSON body posted to the HTTP OCR endpoint.
+type httpRequest struct {
+	Image     string   `json:"image"` // base64 encoded image
+	Languages []string `json:"languages,omitempty"`
+	WithBoxes bool     `json:"with_boxes"`
+}
+
+// httpEngine posts images to a user configured OCR endpoint, which answers with a Result as JSON.
+type httpEngine struct {
+	endpoint string
+	token    string
+	langs    string
+	client   *http.Client
+}
+
+func newHTTPEngine(cfg Config) *httpEngine {
+	return &httpEngine{
+		endpoint: cfg.HTTPEndpoint,
+		token:    os.ExpandEnv(cfg.HTTPToken),
+		langs:    cfg.Languages,
+		client:   &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+	}
+}
+
+func (h *httpEngine) Name() string {
+	return BackendHTTP
+}
+
+// Recognize posts the image to the endpoint.
+func (h *httpEngine) Recognize(ctx context.Context, image []byte, opts Options) (*Result, error) {
+	body, err := json.Marshal(httpRequest{
+		Image:     base64.StdEncoding.EncodeToString(image),
+		Languages: languages(opts, h.langs),
+		WithBoxes: opts.WithBoxes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.token)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ocr request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10*1024*1024))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ocr endpoint returned %s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	var result Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("invalid ocr response: %w", err)
+	}
+	if !opts.WithBoxes {
+		result.Boxes = nil
+	}
+	return &result, nil
+}
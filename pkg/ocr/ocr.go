@@ -0,0 +1,132 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+// Package ocr recognizes text in images through a local tesseract binary or an HTTP endpoint.
+package ocr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	BackendTesseract = "tesseract" // 本地 tesseract 命令
+	BackendHTTP      = "http"      // 远程 HTTP OCR 服务
+)
+
+// DefaultTimeout is the default timeout of a recognition. time.Second
+const DefaultTimeout = 60
+
+// ErrNotConfigured is returned when no OCR backend is configured or the configured one is unavailable.
+var ErrNotConfigured = errors.New("OCR not configured")
+
+// Options are the per-request options of a recognition.
+type Options struct {
+	Languages []string // 语言提示，例如 eng, chi_sim，为空时使用配置中的默认语言
+	WithBoxes bool     // 是否返回每个单词的边界框
+}
+
+// Box is a recognized word with its bounding box in pixels.
+type Box struct {
+	Text       string  `json:"text"`
+	X          int     `json:"x"`
+	Y          int     `json:"y"`
+	Width      int     `json:"width"`
+	Height     int     `json:"height"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Result is the recognized text of an image.
+type Result struct {
+	Text  string `json:"text"`
+	Boxes []Box  `json:"boxes,omitempty"`
+}
+
+// Engine recognizes text in an encoded image (PNG, JPEG, ...).
+type Engine interface {
+	Name() string
+	Recognize(ctx context.Context, image []byte, opts Options) (*Result, error)
+}
+
+// Config selects and configures the OCR backend.
+type Config struct {
+	Backend       string `json:"backend" desc:"OCR backend, empty disables OCR" enum:",tesseract,http"`               // Backend is tesseract or http, empty disables OCR.
+	Languages     string `json:"languages" desc:"Comma separated default languages, e.g. eng,chi_sim"`                // Languages is the default language hint. split by comma. e.g. eng,chi_sim
+	TesseractPath string `json:"tesseract_path" desc:"Path of the tesseract binary, default: looked up in PATH"`      // TesseractPath is the path of the tesseract binary, default: looked up in PATH.
+	HTTPEndpoint  string `json:"http_endpoint" desc:"URL of the HTTP OCR service"`                                    // HTTPEndpoint is the URL of the HTTP OCR service.
+	HTTPToken     string `json:"http_token" desc:"Bearer token sent to the HTTP OCR service, may reference env vars"` // HTTPToken is sent as a Bearer token to the HTTP OCR service, may reference env vars.
+	Timeout       int    `json:"timeout" desc:"Timeout of a recognition, in seconds"`                                 // Timeout of a recognition. time.Second
+}
+
+// NewConfig creates a Config with OCR disabled.
+func NewConfig() Config {
+	return Config{
+		Languages: "eng",
+		Timeout:   DefaultTimeout,
+	}
+}
+
+// Check validates the Config.
+func (c *Config) Check() error {
+	switch c.Backend {
+	case "", BackendTesseract:
+	case BackendHTTP:
+		if c.HTTPEndpoint == "" {
+			return fmt.Errorf("ocr http_endpoint is required for the http backend")
+		}
+	default:
+		return fmt.Errorf("unknown ocr backend %q, supported: %s, %s", c.Backend, BackendTesseract, BackendHTTP)
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("ocr timeout must not be negative")
+	}
+	if c.Timeout == 0 {
+		c.Timeout = DefaultTimeout
+	}
+	return nil
+}
+
+// New creates the Engine selected by cfg.
+func New(cfg Config) (Engine, error) {
+	switch cfg.Backend {
+	case BackendTesseract:
+		return newTesseract(cfg)
+	case BackendHTTP:
+		if cfg.HTTPEndpoint == "" {
+			return nil, fmt.Errorf("%w: http_endpoint is empty", ErrNotConfigured)
+		}
+		return newHTTPEngine(cfg), nil
+	default:
+		return nil, fmt.Errorf("%w: set ocr.backend to %q (requires the tesseract binary, optionally ocr.tesseract_path) or %q (requires ocr.http_endpoint)",
+			ErrNotConfigured, BackendTesseract, BackendHTTP)
+	}
+}
+
+// languages returns the language hints of a request, falling back to the configured default.
+func languages(opts Options, defaults string) []string {
+	if len(opts.Languages) > 0 {
+		return opts.Languages
+	}
+	var langs []string
+	for _, l := range strings.Split(defaults, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			langs = append(langs, l)
+		}
+	}
+	return langs
+}
@@ -0,0 +1,129 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package ocr
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNewNotConfigured(t *testing.T) {
+	_, err := New(NewConfig())
+	if !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("Expected ErrNotConfigured, got %v", err)
+	}
+	for _, option := range []string{BackendTesseract, BackendHTTP} {
+		if !strings.Contains(err.Error(), option) {
+			t.Errorf("Expected error to list backend %s, got %v", option, err)
+		}
+	}
+
+	cfg := NewConfig()
+	cfg.Backend = BackendTesseract
+	cfg.TesseractPath = "/nonexistent/tesseract"
+	if _, err := New(cfg); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("Expected ErrNotConfigured for a missing binary, got %v", err)
+	}
+}
+
+func TestConfigCheck(t *testing.T) {
+	cfg := Config{Backend: BackendHTTP}
+	if err := cfg.Check(); err == nil {
+		t.Error("Expected error for http backend without endpoint")
+	}
+	cfg = Config{Backend: "paddle"}
+	if err := cfg.Check(); err == nil {
+		t.Error("Expected error for unknown backend")
+	}
+	cfg = Config{}
+	if err := cfg.Check(); err != nil || cfg.Timeout != DefaultTimeout {
+		t.Errorf("Expected default timeout, got %d, %v", cfg.Timeout, err)
+	}
+}
+
+func TestParseTSV(t *testing.T) {
+	data, err := os.ReadFile("testdata/words.tsv")
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	result, err := parseTSV(data)
+	if err != nil {
+		t.Fatalf("parseTSV failed: %v", err)
+	}
+	if result.Text != "Hello World\nMoLing" {
+		t.Errorf("Unexpected text %q", result.Text)
+	}
+	if len(result.Boxes) != 3 {
+		t.Fatalf("Expected 3 boxes, got %d", len(result.Boxes))
+	}
+	want := Box{Text: "World", X: 70, Y: 10, Width: 60, Height: 20, Confidence: 95.1}
+	if result.Boxes[1] != want {
+		t.Errorf("Expected %+v, got %+v", want, result.Boxes[1])
+	}
+}
+
+func TestHTTPEngine(t *testing.T) {
+	image := []byte("\x89PNG fake image")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req httpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		decoded, _ := base64.StdEncoding.DecodeString(req.Image)
+		if string(decoded) != string(image) || strings.Join(req.Languages, ",") != "eng,chi_sim" || !req.WithBoxes {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(Result{Text: "hello", Boxes: []Box{{Text: "hello", Width: 10, Height: 5}}})
+	}))
+	defer srv.Close()
+
+	cfg := NewConfig()
+	cfg.Backend = BackendHTTP
+	cfg.HTTPEndpoint = srv.URL
+	cfg.HTTPToken = "secret"
+	cfg.Languages = "eng, chi_sim"
+	engine, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	result, err := engine.Recognize(context.Background(), image, Options{WithBoxes: true})
+	if err != nil {
+		t.Fatalf("Recognize failed: %v", err)
+	}
+	if result.Text != "hello" || len(result.Boxes) != 1 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+
+	cfg.HTTPToken = "wrong"
+	engine, _ = New(cfg)
+	if _, err := engine.Recognize(context.Background(), image, Options{}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected HTTP status error, got %v", err)
+	}
+}
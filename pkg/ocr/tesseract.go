@@ -0,0 +1,142 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package ocr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tesseract runs the local tesseract binary.
+type tesseract struct {
+	path    string
+	langs   string
+	timeout time.Duration
+}
+
+func newTesseract(cfg Config) (*tesseract, error) {
+	path := cfg.TesseractPath
+	if path == "" {
+		path = "tesseract"
+	}
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: tesseract binary %q not found, install tesseract or set ocr.tesseract_path: %v", ErrNotConfigured, path, err)
+	}
+	return &tesseract{path: resolved, langs: cfg.Languages, timeout: time.Duration(cfg.Timeout) * time.Second}, nil
+}
+
+func (t *tesseract) Name() string {
+	return BackendTesseract
+}
+
+// Recognize writes the image to a temp file and runs tesseract on it, in TSV mode when boxes are requested.
+func (t *tesseract) Recognize(ctx context.Context, image []byte, opts Options) (*Result, error) {
+	f, err := os.CreateTemp("", "moling_ocr_*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(image); err != nil {
+		f.Close()
+		return nil, err
+	}
+	f.Close()
+
+	if t.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+	args := []string{f.Name(), "stdout"}
+	if langs := languages(opts, t.langs); len(langs) > 0 {
+		args = append(args, "-l", strings.Join(langs, "+"))
+	}
+	if opts.WithBoxes {
+		args = append(args, "tsv")
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, t.path, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("tesseract failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if opts.WithBoxes {
+		return parseTSV(stdout.Bytes())
+	}
+	return &Result{Text: strings.TrimSpace(stdout.String())}, nil
+}
+
+// parseTSV parses the TSV output of tesseract. Word rows (level 5) become boxes, the text is
+// rebuilt line by line.
+func parseTSV(data []byte) (*Result, error) {
+	result := &Result{}
+	var lines []string
+	var line []string
+	lastLine := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	header := true
+	for scanner.Scan() {
+		if header {
+			header = false
+			continue
+		}
+		cols := strings.Split(scanner.Text(), "\t")
+		if len(cols) < 12 || cols[0] != "5" {
+			continue
+		}
+		text := strings.TrimSpace(cols[11])
+		if text == "" {
+			continue
+		}
+		nums := make([]int, 4)
+		for i := range nums {
+			n, err := strconv.Atoi(cols[6+i])
+			if err != nil {
+				return nil, fmt.Errorf("invalid tesseract tsv output: %q", scanner.Text())
+			}
+			nums[i] = n
+		}
+		conf, _ := strconv.ParseFloat(cols[10], 64)
+		result.Boxes = append(result.Boxes, Box{Text: text, X: nums[0], Y: nums[1], Width: nums[2], Height: nums[3], Confidence: conf})
+
+		// page, block, paragraph and line number identify a line
+		key := strings.Join(cols[1:5], ".")
+		if key != lastLine && len(line) > 0 {
+			lines = append(lines, strings.Join(line, " "))
+			line = nil
+		}
+		lastLine = key
+		line = append(line, text)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(line) > 0 {
+		lines = append(lines, strings.Join(line, " "))
+	}
+	result.Text = strings.Join(lines, "\n")
+	return result, nil
+}
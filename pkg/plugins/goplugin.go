@@ -0,0 +1,48 @@
+//go:build (linux || darwin) && cgo
+
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"plugin"
+
+	"github.com/gojue/moling/pkg/services/abstract"
+)
+
+// openGoPlugin opens a Go plugin and looks up its NewService symbol. The plugin must be built
+// with the same Go version and dependency versions as MoLing.
+func openGoPlugin(path string) (abstract.ServiceFactory, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := p.Lookup(NewServiceSymbol)
+	if err != nil {
+		return nil, err
+	}
+	switch factory := sym.(type) {
+	case func(context.Context) (abstract.Service, error):
+		return factory, nil
+	case *abstract.ServiceFactory:
+		return *factory, nil
+	default:
+		return nil, fmt.Errorf("symbol %s is %T, expected func(context.Context) (abstract.Service, error)", NewServiceSymbol, sym)
+	}
+}
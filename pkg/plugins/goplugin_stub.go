@@ -0,0 +1,31 @@
+//go:build !((linux || darwin) && cgo)
+
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package plugins
+
+import (
+	"errors"
+
+	"github.com/gojue/moling/pkg/services/abstract"
+)
+
+// openGoPlugin reports that Go plugins are not supported, they need cgo on Linux or macOS. Use a
+// manifest to load an MCP server binary instead.
+func openGoPlugin(path string) (abstract.ServiceFactory, error) {
+	return nil, errors.New("Go plugins need a MoLing binary built with cgo on Linux or macOS, use a manifest instead")
+}
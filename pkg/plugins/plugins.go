@@ -0,0 +1,167 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+// Package plugins loads external services from the plugin directory. A plugin is either a
+// manifest JSON describing an MCP server binary that MoLing launches and proxies over stdio, or a
+// Go plugin (.so) exposing a NewService symbol.
+package plugins
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/services/abstract"
+)
+
+const (
+	// ManifestExt is the extension of plugin manifests.
+	ManifestExt = ".json"
+	// GoPluginExt is the extension of Go plugins.
+	GoPluginExt = ".so"
+	// NewServiceSymbol is the symbol a Go plugin exports, a func(context.Context) (abstract.Service, error).
+	NewServiceSymbol = "NewService"
+)
+
+var (
+	// ErrInvalidManifest is returned for a manifest that cannot be used.
+	ErrInvalidManifest = errors.New("invalid plugin manifest")
+	// ErrDuplicatePlugin is returned when a plugin name is taken by a service or another plugin.
+	ErrDuplicatePlugin = errors.New("duplicate plugin name")
+)
+
+// nameRegexp keeps plugin names usable as service names and tool name prefixes.
+var nameRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,31}$`)
+
+// Manifest describes an external MCP server that speaks MCP over stdio.
+type Manifest struct {
+	Name       string            `json:"name"`        // Service name, also the default tool name prefix
+	Command    string            `json:"command"`     // Executable, a relative path with a separator is relative to the manifest
+	Args       []string          `json:"args"`        // Arguments of the command
+	Env        map[string]string `json:"env"`         // Extra environment variables of the process
+	ToolPrefix string            `json:"tool_prefix"` // Prefix of the re-exposed tool names, default: <name>_
+	Disabled   bool              `json:"disabled"`    // Skip the plugin
+}
+
+// Check validates the manifest and fills the defaults.
+func (m *Manifest) Check() error {
+	if !nameRegexp.MatchString(m.Name) {
+		return fmt.Errorf("%w: name %q must start with a letter and contain at most 32 letters, digits, - and _", ErrInvalidManifest, m.Name)
+	}
+	if m.Command == "" {
+		return fmt.Errorf("%w: command of %s is empty", ErrInvalidManifest, m.Name)
+	}
+	if m.ToolPrefix == "" {
+		m.ToolPrefix = m.Name + "_"
+	}
+	return nil
+}
+
+// LoadManifest reads a manifest. A relative command containing a path separator is resolved
+// against the directory of the manifest, a bare command name is looked up in PATH on start.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidManifest, path, err)
+	}
+	if err := m.Check(); err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(m.Command) && strings.ContainsAny(m.Command, `/\`) {
+		m.Command = filepath.Join(filepath.Dir(path), m.Command)
+	}
+	return m, nil
+}
+
+// Plugin is a service found in the plugin directory.
+type Plugin struct {
+	Name    comm.MoLingServerType   // Service name
+	Path    string                  // Manifest or Go plugin file
+	Factory abstract.ServiceFactory // Creates the service
+}
+
+// Discover scans dir for manifests and Go plugins, sorted by file name. Plugins whose name is in
+// reserved, like the built-in services, or taken by an earlier plugin are skipped. A missing
+// directory has no plugins. The errors of skipped plugins are returned along with the plugins.
+func Discover(dir string, reserved []comm.MoLingServerType, maxBackoff int) ([]Plugin, []error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, []error{err}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	taken := make(map[string]bool, len(reserved))
+	for _, name := range reserved {
+		taken[strings.ToLower(string(name))] = true
+	}
+	var plugins []Plugin
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		var plugin Plugin
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ManifestExt:
+			m, err := LoadManifest(path)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if m.Disabled {
+				continue
+			}
+			plugin = Plugin{Name: comm.MoLingServerType(m.Name), Path: path, Factory: proxyFactory(m, maxBackoff)}
+		case GoPluginExt:
+			name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+			if !nameRegexp.MatchString(name) {
+				errs = append(errs, fmt.Errorf("invalid Go plugin name %q: %s", name, path))
+				continue
+			}
+			factory, err := openGoPlugin(path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to open Go plugin %s: %w", path, err))
+				continue
+			}
+			plugin = Plugin{Name: comm.MoLingServerType(name), Path: path, Factory: factory}
+		default:
+			continue
+		}
+		// 服务名不区分大小写，避免与内置服务或其他插件混淆
+		key := strings.ToLower(string(plugin.Name))
+		if taken[key] {
+			errs = append(errs, fmt.Errorf("%w: %s in %s", ErrDuplicatePlugin, plugin.Name, path))
+			continue
+		}
+		taken[key] = true
+		plugins = append(plugins, plugin)
+	}
+	return plugins, errs
+}
@@ -0,0 +1,209 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package plugins
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// buildFakeServer builds testdata/fakeserver, a stdio MCP server with echo, pid and crash tools.
+func buildFakeServer(t *testing.T) string {
+	t.Helper()
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not in PATH")
+	}
+	bin := filepath.Join(t.TempDir(), "fakeserver")
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+	out, err := exec.Command(goBin, "build", "-o", bin, "./testdata/fakeserver").CombinedOutput()
+	if err != nil {
+		t.Fatalf("failed to build the fake server: %v\n%s", err, out)
+	}
+	return bin
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func callTool(t *testing.T, tools []server.ServerTool, name string, args map[string]interface{}) *mcp.CallToolResult {
+	t.Helper()
+	for _, tool := range tools {
+		if tool.Tool.Name != name {
+			continue
+		}
+		request := mcp.CallToolRequest{}
+		request.Params.Name = name
+		request.Params.Arguments = args
+		result, err := tool.Handler(context.Background(), request)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", name, err)
+		}
+		return result
+	}
+	t.Fatalf("tool %s is not registered", name)
+	return nil
+}
+
+func resultText(result *mcp.CallToolResult) string {
+	if len(result.Content) == 0 {
+		return ""
+	}
+	text, _ := result.Content[0].(mcp.TextContent)
+	return text.Text
+}
+
+func TestDiscover(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.json"), `{"name": "fake", "command": "./bin/fakeserver", "args": ["-v"]}`)
+	writeFile(t, filepath.Join(dir, "b.json"), `{"name": "Fake", "command": "fakeserver"}`)
+	writeFile(t, filepath.Join(dir, "c.json"), `{"name": "browser", "command": "fakeserver"}`)
+	writeFile(t, filepath.Join(dir, "d.json"), `{"name": "off", "command": "fakeserver", "disabled": true}`)
+	writeFile(t, filepath.Join(dir, "e.json"), `{"name": "1bad", "command": "fakeserver"}`)
+	writeFile(t, filepath.Join(dir, "f.json"), `{"name": "nocmd"}`)
+	writeFile(t, filepath.Join(dir, "g.json"), `not json`)
+	writeFile(t, filepath.Join(dir, "broken.so"), `not a plugin`)
+	writeFile(t, filepath.Join(dir, "README.md"), `ignored`)
+
+	found, errs := Discover(dir, []comm.MoLingServerType{"Browser"}, 60)
+	if len(found) != 1 || found[0].Name != "fake" {
+		t.Fatalf("Expected only the fake plugin, got %+v", found)
+	}
+	m, err := LoadManifest(filepath.Join(dir, "a.json"))
+	if err != nil {
+		t.Fatalf("LoadManifest failed: %v", err)
+	}
+	if m.Command != filepath.Join(dir, "bin", "fakeserver") || m.ToolPrefix != "fake_" {
+		t.Errorf("Unexpected manifest %+v", m)
+	}
+
+	var duplicates, invalid int
+	for _, err := range errs {
+		switch {
+		case errors.Is(err, ErrDuplicatePlugin):
+			duplicates++
+		case errors.Is(err, ErrInvalidManifest):
+			invalid++
+		}
+	}
+	// b 与 a 重名，c 与内置服务重名；e、f、g 无效；broken.so 无法打开
+	if duplicates != 2 || invalid != 3 || len(errs) != 6 {
+		t.Errorf("Expected 2 duplicates, 3 invalid manifests and 6 errors, got %d, %d and %v", duplicates, invalid, errs)
+	}
+
+	if found, errs := Discover(filepath.Join(dir, "missing"), nil, 60); len(found) != 0 || len(errs) != 0 {
+		t.Errorf("Expected a missing directory to have no plugins, got %v, %v", found, errs)
+	}
+}
+
+func TestProxyService(t *testing.T) {
+	bin := buildFakeServer(t)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "fake.json"), `{"name": "fake", "command": "`+filepath.ToSlash(bin)+`"}`)
+	found, errs := Discover(dir, nil, 2)
+	if len(errs) != 0 || len(found) != 1 {
+		t.Fatalf("Discover failed: %v", errs)
+	}
+	_, ctx, err := comm.InitTestEnv()
+	if err != nil {
+		t.Fatalf("Failed to initialize test environment: %v", err)
+	}
+	srv, err := found[0].Factory(ctx)
+	if err != nil {
+		t.Fatalf("Failed to start the plugin: %v", err)
+	}
+	ps := srv.(*ProxyService)
+	defer ps.Close()
+	if err := ps.RegisterTools(); err != nil {
+		t.Fatalf("RegisterTools failed: %v", err)
+	}
+
+	var names []string
+	for _, tool := range ps.Tools() {
+		names = append(names, tool.Tool.Name)
+	}
+	if strings.Join(names, ",") != "fake_crash,fake_echo,fake_pid" {
+		t.Fatalf("Expected namespaced tools, got %v", names)
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		result := callTool(t, ps.Tools(), "fake_echo", map[string]interface{}{"text": "hello"})
+		if result.IsError || resultText(result) != "echo: hello" {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+
+	t.Run("RestartAfterCrash", func(t *testing.T) {
+		pid := resultText(callTool(t, ps.Tools(), "fake_pid", nil))
+		result := callTool(t, ps.Tools(), "fake_crash", nil)
+		if !result.IsError || !strings.Contains(resultText(result), "exited") {
+			t.Fatalf("Expected the crash to be reported, got %+v", result)
+		}
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			result = callTool(t, ps.Tools(), "fake_pid", nil)
+			if !result.IsError {
+				break
+			}
+			if !strings.Contains(resultText(result), ErrNotRunning.Error()) {
+				t.Fatalf("Unexpected error while restarting: %s", resultText(result))
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("Expected the plugin to be restarted")
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		if resultText(result) == pid || ps.Restarts() != 1 {
+			t.Errorf("Expected a new process after 1 restart, got pid %s (was %s) after %d restarts", resultText(result), pid, ps.Restarts())
+		}
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		ps.lock.Lock()
+		exited := ps.exited
+		ps.lock.Unlock()
+		if err := ps.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		select {
+		case <-exited:
+		default:
+			t.Error("Expected the process to exit on Close")
+		}
+		result := callTool(t, ps.Tools(), "fake_echo", map[string]interface{}{"text": "hello"})
+		if payload, ok := comm.ParseToolError(result); !ok || !strings.Contains(payload.Details, ErrNotRunning.Error()) {
+			t.Errorf("Expected calls to fail after Close, got %+v", result)
+		}
+	})
+}
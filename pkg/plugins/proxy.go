@@ -0,0 +1,351 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/services/abstract"
+	"github.com/gojue/moling/pkg/utils"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// InitTimeout is how long a plugin process may take to answer initialize and tools/list.
+	InitTimeout = 10 * time.Second
+	// MinRestartBackoff is the delay before the first restart of a crashed plugin process.
+	MinRestartBackoff = time.Second
+	// CloseTimeout is how long a plugin process may take to exit after its stdin is closed.
+	CloseTimeout = 3 * time.Second
+)
+
+// ErrNotRunning is returned for calls while the plugin process is down.
+var ErrNotRunning = errors.New("plugin process is not running")
+
+// ProxyService re-exposes the tools of an external MCP server under a name prefix. The process is
+// started when the service is created, killed on Close, and restarted with exponential backoff
+// when it crashes.
+type ProxyService struct {
+	abstract.MLService
+	manifest   *Manifest
+	maxBackoff time.Duration
+
+	lock      sync.Mutex
+	client    *client.Client // 当前进程的 MCP 客户端
+	cmd       *exec.Cmd      // 当前进程
+	exited    chan struct{}  // 当前进程退出时关闭
+	startedAt time.Time      // 当前进程启动时间
+	backoff   time.Duration  // 下一次重启前的等待时间
+	restarts  int            // 崩溃后的累计重启次数
+	closed    bool           // Close 已调用，不再重启
+	tools     []mcp.Tool     // 首次启动时列出的远端工具
+}
+
+// proxyFactory returns the factory of the proxy service of a manifest.
+func proxyFactory(m *Manifest, maxBackoff int) abstract.ServiceFactory {
+	return func(ctx context.Context) (abstract.Service, error) {
+		return NewProxyService(ctx, m, time.Duration(maxBackoff)*time.Second)
+	}
+}
+
+// NewProxyService starts the process of the manifest and lists its tools.
+func NewProxyService(ctx context.Context, m *Manifest, maxBackoff time.Duration) (*ProxyService, error) {
+	base, err := abstract.NewServiceBase(ctx, comm.MoLingServerType(m.Name))
+	if err != nil {
+		return nil, err
+	}
+	ps := &ProxyService{
+		MLService:  base,
+		manifest:   m,
+		maxBackoff: maxBackoff,
+	}
+	if err := ps.InitResources(); err != nil {
+		return nil, err
+	}
+
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	if err := ps.spawn(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), InitTimeout)
+	defer cancel()
+	result, err := ps.client.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		ps.kill()
+		return nil, fmt.Errorf("failed to list the tools of plugin %s: %w", m.Name, err)
+	}
+	ps.tools = result.Tools
+	return ps, nil
+}
+
+// spawn starts the process and initializes the MCP session. The caller holds lock.
+func (ps *ProxyService) spawn() error {
+	cmd := exec.Command(ps.manifest.Command, ps.manifest.Args...)
+	cmd.Env = os.Environ()
+	for key, value := range ps.manifest.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	cmd.Stderr = stderrLogger{logger: ps.Logger}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start plugin %s: %w", ps.manifest.Name, err)
+	}
+
+	// 标准错误已转发到日志，传输层只需要一个空的 stderr
+	tr := transport.NewIO(eofReader{stdout}, stdin, io.NopCloser(bytes.NewReader(nil)))
+	c := client.NewClient(tr)
+	exited := make(chan struct{})
+	ps.client, ps.cmd, ps.exited, ps.startedAt = c, cmd, exited, time.Now()
+	go ps.wait(cmd, exited)
+
+	ctx, cancel := context.WithTimeout(context.Background(), InitTimeout)
+	defer cancel()
+	if err := c.Start(ctx); err != nil {
+		ps.kill()
+		return fmt.Errorf("failed to connect to plugin %s: %w", ps.manifest.Name, err)
+	}
+	request := mcp.InitializeRequest{}
+	request.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	request.Params.ClientInfo = mcp.Implementation{Name: "MoLing", Version: ps.MlConfig().Version}
+	if _, err := c.Initialize(ctx, request); err != nil {
+		ps.kill()
+		return fmt.Errorf("failed to initialize plugin %s: %w", ps.manifest.Name, err)
+	}
+	ps.Logger.Info().Str("command", ps.manifest.Command).Int("pid", cmd.Process.Pid).Msg("plugin started")
+	return nil
+}
+
+// kill stops the current process without restarting it. The caller holds lock.
+func (ps *ProxyService) kill() {
+	c, cmd := ps.client, ps.cmd
+	// 清空后 wait 不会把这次退出当作崩溃
+	ps.client, ps.cmd = nil, nil
+	if c != nil {
+		_ = c.Close()
+	}
+	if cmd != nil && cmd.Process != nil {
+		_ = cmd.Process.Kill()
+	}
+}
+
+// wait reaps the process and schedules a restart if it exited on its own.
+func (ps *ProxyService) wait(cmd *exec.Cmd, exited chan struct{}) {
+	err := cmd.Wait()
+	close(exited)
+
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	if ps.closed || ps.cmd != cmd {
+		return
+	}
+	// 稳定运行超过最大退避时间后重新从最小退避开始
+	if ps.backoff == 0 || time.Since(ps.startedAt) > ps.maxBackoff {
+		ps.backoff = MinRestartBackoff
+	} else {
+		ps.backoff = min(ps.backoff*2, ps.maxBackoff)
+	}
+	ps.Logger.Warn().Err(err).Dur("backoff", ps.backoff).Msg("plugin process exited, restarting")
+	time.AfterFunc(ps.backoff, ps.restart)
+}
+
+// restart starts a new process after a crash, and retries with a longer backoff if it fails.
+func (ps *ProxyService) restart() {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	if ps.closed {
+		return
+	}
+	if ps.client != nil {
+		_ = ps.client.Close()
+	}
+	ps.restarts++
+	if err := ps.spawn(); err != nil {
+		ps.backoff = min(ps.backoff*2, ps.maxBackoff)
+		ps.Logger.Error().Err(err).Dur("backoff", ps.backoff).Msg("failed to restart plugin")
+		time.AfterFunc(ps.backoff, ps.restart)
+	}
+}
+
+// current returns the client of the running process.
+func (ps *ProxyService) current() (*client.Client, chan struct{}, error) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	if ps.closed || ps.client == nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrNotRunning, ps.manifest.Name)
+	}
+	select {
+	case <-ps.exited:
+		return nil, nil, fmt.Errorf("%w: %s, restarting in %s", ErrNotRunning, ps.manifest.Name, ps.backoff)
+	default:
+	}
+	return ps.client, ps.exited, nil
+}
+
+// RegisterTools registers the tools listed when the process was started, with the tool prefix.
+// Tools are not listed again after a restart.
+func (ps *ProxyService) RegisterTools() error {
+	for _, tool := range ps.tools {
+		remote := tool.Name
+		tool.Name = ps.manifest.ToolPrefix + remote
+		ps.AddTool(tool, ps.forward(remote))
+	}
+	return nil
+}
+
+// forward calls the tool of the plugin process. The call fails as soon as the process exits.
+func (ps *ProxyService) forward(name string) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		c, exited, err := ps.current()
+		if err != nil {
+			return comm.ToolErrorFromErr(fmt.Sprintf("plugin %s is not available", ps.manifest.Name), err), nil
+		}
+		callCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-exited:
+				cancel()
+			case <-callCtx.Done():
+			}
+		}()
+
+		remote := mcp.CallToolRequest{}
+		remote.Params.Name = name
+		remote.Params.Arguments = request.Params.Arguments
+		result, err := c.CallTool(callCtx, remote)
+		if err != nil {
+			select {
+			case <-exited:
+				return comm.ToolError(comm.ErrCodeInternal, fmt.Sprintf("plugin %s exited during the call of %s, it is restarted in the background", ps.manifest.Name, name), err.Error()), nil
+			default:
+			}
+			return comm.ToolErrorFromErr(fmt.Sprintf("plugin %s failed to call %s", ps.manifest.Name, name), err), nil
+		}
+		return result, nil
+	}
+}
+
+// Close closes the stdin of the process and kills it if it does not exit in time.
+func (ps *ProxyService) Close() error {
+	ps.lock.Lock()
+	ps.closed = true
+	c, cmd, exited := ps.client, ps.cmd, ps.exited
+	ps.lock.Unlock()
+	if c == nil {
+		return nil
+	}
+	_ = c.Close()
+	select {
+	case <-exited:
+	case <-time.After(CloseTimeout):
+		_ = cmd.Process.Kill()
+		<-exited
+	}
+	ps.Logger.Info().Msg("plugin stopped")
+	return nil
+}
+
+// Restarts returns how many times the process was restarted after a crash.
+func (ps *ProxyService) Restarts() int {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	return ps.restarts
+}
+
+// Config returns the manifest of the plugin.
+func (ps *ProxyService) Config() string {
+	cfg, err := json.Marshal(ps.manifest)
+	if err != nil {
+		ps.Logger.Err(err).Msg("failed to marshal config")
+		return "{}"
+	}
+	return string(cfg)
+}
+
+// LoadConfig merges the config section of the plugin into the manifest. Changes to command, args
+// and env take effect on the next restart of the process.
+func (ps *ProxyService) LoadConfig(jsonData map[string]interface{}) error {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	// 在副本上合并和校验，校验失败时保持原配置
+	next := *ps.manifest
+	if err := utils.MergeJSONToStruct(&next, jsonData); err != nil {
+		return err
+	}
+	// 名称和前缀在加载时已确定，工具注册后不再修改
+	next.Name, next.ToolPrefix = ps.manifest.Name, ps.manifest.ToolPrefix
+	if err := next.Check(); err != nil {
+		return err
+	}
+	*ps.manifest = next
+	return nil
+}
+
+// Name returns the name of the plugin.
+func (ps *ProxyService) Name() comm.MoLingServerType {
+	return comm.MoLingServerType(ps.manifest.Name)
+}
+
+// eofReader reports every read error as io.EOF. The stdio transport prints other read errors,
+// like reading a closed pipe after the process is killed, to stdout, which is the MCP stream of
+// MoLing in STDIO mode.
+type eofReader struct {
+	r io.Reader
+}
+
+func (er eofReader) Read(p []byte) (int, error) {
+	n, err := er.r.Read(p)
+	if err != nil {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// stderrLogger writes the stderr output of the plugin process to the log.
+type stderrLogger struct {
+	logger zerolog.Logger
+}
+
+func (sl stderrLogger) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		sl.logger.Debug().Str("stderr", line).Msg("plugin output")
+	}
+	return len(p), nil
+}
@@ -0,0 +1,402 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gojue/moling/pkg/config"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog"
+)
+
+// AuditFileName is the name of the audit log in the logs directory, rotated to audit.jsonl.1 .. N.
+const AuditFileName = "audit.jsonl"
+
+// Kinds of audited requests.
+const (
+	AuditKindTool     = "tool"
+	AuditKindPrompt   = "prompt"
+	AuditKindResource = "resource"
+)
+
+// Error codes of audit entries, structured tool errors keep their own code (e.g. RATE_LIMITED).
+const (
+	ErrCodeToolError     = "TOOL_ERROR"
+	ErrCodeInternalError = "INTERNAL_ERROR"
+)
+
+// auditRedacted replaces the values of sensitive arguments.
+const auditRedacted = "[REDACTED]"
+
+// AuditEntry is one line of the audit log. Results are only summarized by their size and hash.
+type AuditEntry struct {
+	Time       time.Time              `json:"time"`
+	Session    string                 `json:"session,omitempty"`
+	Kind       string                 `json:"kind"`
+	Name       string                 `json:"name"`
+	Arguments  map[string]interface{} `json:"arguments,omitempty"`
+	Result     *AuditResult           `json:"result,omitempty"`
+	DurationMs int64                  `json:"duration_ms"`
+	ErrorCode  string                 `json:"error_code,omitempty"`
+}
+
+// AuditResult summarizes the result of an audited request.
+type AuditResult struct {
+	Items  int    `json:"items"`
+	Bytes  int    `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+// AuditStats counts the entries of the audit log.
+type AuditStats struct {
+	Written uint64 `json:"written"`
+	Dropped uint64 `json:"dropped"`
+	Failed  uint64 `json:"failed"`
+}
+
+// auditRecord is an audited request waiting for the background writer, which summarizes the
+// result and redacts the arguments so the request itself does not pay for it.
+type auditRecord struct {
+	entry  AuditEntry
+	items  int
+	result interface{}
+}
+
+// AuditLogger appends audit entries as JSON lines. Requests never block on disk: entries are
+// buffered in a bounded channel and written in the background, entries that do not fit are
+// dropped and counted. A nil AuditLogger audits nothing.
+type AuditLogger struct {
+	w         io.WriteCloser
+	records   chan auditRecord
+	sensitive []string
+	logger    zerolog.Logger
+	mu        sync.RWMutex // guards closed against Record racing with Close
+	closed    bool
+	done      chan struct{}
+	written   atomic.Uint64
+	dropped   atomic.Uint64
+	failed    atomic.Uint64
+}
+
+// NewAuditLogger creates an AuditLogger writing to w and starts its background writer.
+func NewAuditLogger(cfg config.AuditConfig, w io.WriteCloser, logger zerolog.Logger) *AuditLogger {
+	al := &AuditLogger{
+		w:       w,
+		records: make(chan auditRecord, cfg.BufferSize),
+		logger:  logger,
+		done:    make(chan struct{}),
+	}
+	for _, key := range cfg.SensitiveKeys {
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			al.sensitive = append(al.sensitive, key)
+		}
+	}
+	go al.run()
+	return al
+}
+
+// WrapTool audits the calls of a tool.
+func (al *AuditLogger) WrapTool(name string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if al == nil {
+		return handler
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := handler(ctx, request)
+		rec := al.newRecord(ctx, AuditKindTool, name, request.GetArguments(), start)
+		switch {
+		case err != nil:
+			rec.entry.ErrorCode = ErrCodeInternalError
+		case result != nil:
+			rec.items, rec.result = len(result.Content), result
+			if result.IsError {
+				rec.entry.ErrorCode = toolErrorCode(result)
+			}
+		}
+		al.record(rec)
+		return result, err
+	}
+}
+
+// WrapPrompt audits the gets of a prompt.
+func (al *AuditLogger) WrapPrompt(name string, handler server.PromptHandlerFunc) server.PromptHandlerFunc {
+	if al == nil {
+		return handler
+	}
+	return func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		start := time.Now()
+		result, err := handler(ctx, request)
+		args := make(map[string]interface{}, len(request.Params.Arguments))
+		for k, v := range request.Params.Arguments {
+			args[k] = v
+		}
+		rec := al.newRecord(ctx, AuditKindPrompt, name, args, start)
+		if err != nil {
+			rec.entry.ErrorCode = ErrCodeInternalError
+		} else if result != nil {
+			rec.items, rec.result = len(result.Messages), result
+		}
+		al.record(rec)
+		return result, err
+	}
+}
+
+// WrapResource audits the reads of a resource or resource template.
+func (al *AuditLogger) WrapResource(handler server.ResourceHandlerFunc) server.ResourceHandlerFunc {
+	if al == nil {
+		return handler
+	}
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		start := time.Now()
+		contents, err := handler(ctx, request)
+		rec := al.newRecord(ctx, AuditKindResource, request.Params.URI, request.Params.Arguments, start)
+		if err != nil {
+			rec.entry.ErrorCode = ErrCodeInternalError
+		} else {
+			rec.items, rec.result = len(contents), contents
+		}
+		al.record(rec)
+		return contents, err
+	}
+}
+
+// Stats returns the counters of the audit log.
+func (al *AuditLogger) Stats() AuditStats {
+	return AuditStats{Written: al.written.Load(), Dropped: al.dropped.Load(), Failed: al.failed.Load()}
+}
+
+// Close writes the buffered entries and closes the writer.
+func (al *AuditLogger) Close() error {
+	al.mu.Lock()
+	if al.closed {
+		al.mu.Unlock()
+		return nil
+	}
+	al.closed = true
+	close(al.records)
+	al.mu.Unlock()
+	<-al.done
+	return al.w.Close()
+}
+
+func (al *AuditLogger) newRecord(ctx context.Context, kind, name string, args map[string]interface{}, start time.Time) auditRecord {
+	entry := AuditEntry{
+		Time:       start.UTC(),
+		Kind:       kind,
+		Name:       name,
+		Arguments:  args,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		entry.Session = session.SessionID()
+	}
+	return auditRecord{entry: entry}
+}
+
+// record queues an entry without blocking, it is dropped when the buffer is full.
+func (al *AuditLogger) record(rec auditRecord) {
+	al.mu.RLock()
+	defer al.mu.RUnlock()
+	if al.closed {
+		al.dropped.Add(1)
+		return
+	}
+	select {
+	case al.records <- rec:
+	default:
+		al.dropped.Add(1)
+	}
+}
+
+// run is the background writer.
+func (al *AuditLogger) run() {
+	defer close(al.done)
+	for rec := range al.records {
+		entry := rec.entry
+		entry.Arguments = al.redact(entry.Arguments)
+		if rec.result != nil {
+			entry.Result = summarize(rec.items, rec.result)
+		}
+		data, err := json.Marshal(entry)
+		if err == nil {
+			_, err = al.w.Write(append(data, '\n'))
+		}
+		if err != nil {
+			al.failed.Add(1)
+			al.logger.Warn().Err(err).Str("name", entry.Name).Msg("failed to write audit entry")
+			continue
+		}
+		al.written.Add(1)
+	}
+}
+
+// redact copies the arguments with the values of sensitive keys replaced. The value of a field
+// whose selector matches a sensitive key, e.g. browser_fill on input[type=password], is redacted
+// as well.
+func (al *AuditLogger) redact(args map[string]interface{}) map[string]interface{} {
+	if args == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		if al.isSensitive(k) {
+			out[k] = auditRedacted
+			continue
+		}
+		out[k] = al.redactValue(v)
+	}
+	if selector, ok := args["selector"].(string); ok && al.isSensitive(selector) {
+		if _, ok := out["value"]; ok {
+			out["value"] = auditRedacted
+		}
+	}
+	return out
+}
+
+func (al *AuditLogger) redactValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		return al.redact(value)
+	case []interface{}:
+		items := make([]interface{}, len(value))
+		for i, item := range value {
+			items[i] = al.redactValue(item)
+		}
+		return items
+	default:
+		return v
+	}
+}
+
+func (al *AuditLogger) isSensitive(s string) bool {
+	s = strings.ToLower(s)
+	for _, key := range al.sensitive {
+		if strings.Contains(s, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// summarize hashes the JSON encoding of a result.
+func summarize(items int, result interface{}) *AuditResult {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return &AuditResult{Items: items}
+	}
+	sum := sha256.Sum256(data)
+	return &AuditResult{Items: items, Bytes: len(data), SHA256: hex.EncodeToString(sum[:])}
+}
+
+// toolErrorCode returns the code of a structured tool error, or TOOL_ERROR.
+func toolErrorCode(result *mcp.CallToolResult) string {
+	for _, content := range result.Content {
+		text, ok := content.(mcp.TextContent)
+		if !ok {
+			continue
+		}
+		var structured struct {
+			Code string `json:"code"`
+		}
+		if json.Unmarshal([]byte(text.Text), &structured) == nil && structured.Code != "" {
+			return structured.Code
+		}
+	}
+	return ErrCodeToolError
+}
+
+// AuditFilter selects entries of the audit log.
+type AuditFilter struct {
+	Tool    string // Tool matches the name of the tool, prompt or resource.
+	Session string // Session matches the client session id.
+	Limit   int    // Limit keeps the last Limit matching entries, 0 means all.
+}
+
+// ReadAuditLog reads the entries of the rotated audit log at path that match the filter, oldest
+// first.
+func ReadAuditLog(path string, filter AuditFilter) ([]AuditEntry, error) {
+	files, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	// 按修改时间排序，旧文件中的记录在前
+	modTimes := make(map[string]time.Time, len(files))
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			modTimes[file] = info.ModTime()
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool { return modTimes[files[i]].Before(modTimes[files[j]]) })
+	var entries []AuditEntry
+	for _, file := range files {
+		fileEntries, err := readAuditFile(file, filter)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	return entries, nil
+}
+
+func readAuditFile(file string, filter AuditFilter) ([]AuditEntry, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// 轮转时截断的行等无法解析的行直接跳过
+			continue
+		}
+		if (filter.Tool != "" && entry.Name != filter.Tool) || (filter.Session != "" && entry.Session != filter.Session) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,241 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gojue/moling/pkg/config"
+	"github.com/gojue/moling/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog"
+)
+
+// blockingWriter blocks every write until released, to fill the audit buffer.
+type blockingWriter struct {
+	entered chan struct{}
+	release chan struct{}
+	lines   int
+}
+
+func (bw *blockingWriter) Write(p []byte) (int, error) {
+	bw.entered <- struct{}{}
+	<-bw.release
+	bw.lines++
+	return len(p), nil
+}
+
+func (bw *blockingWriter) Close() error { return nil }
+
+// auditCall calls a tool through the audit logger in the given client session.
+func auditCall(t *testing.T, al *AuditLogger, session, tool string, args map[string]interface{}, result *mcp.CallToolResult) {
+	t.Helper()
+	ctx := context.Background()
+	if session != "" {
+		ctx = server.NewMCPServer("test", "1.0").WithContext(ctx, &fakeSession{id: session})
+	}
+	handler := al.WrapTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return result, nil
+	})
+	request := mcp.CallToolRequest{}
+	request.Params.Name = tool
+	request.Params.Arguments = args
+	if _, err := handler(ctx, request); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+// openAuditLog opens an audit log in dir rotated over files files of maxSize bytes.
+func openAuditLog(t *testing.T, dir string, maxSize, files int) *AuditLogger {
+	t.Helper()
+	cfg := config.NewAuditConfig()
+	cfg.MaxSize, cfg.MaxFiles = maxSize, files
+	rw, err := utils.NewRotateWriterN(filepath.Join(dir, AuditFileName), int64(cfg.MaxSize), cfg.MaxFiles)
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	return NewAuditLogger(cfg, rw, zerolog.Nop())
+}
+
+func TestAuditLog(t *testing.T) {
+	t.Run("Redaction", func(t *testing.T) {
+		al := NewAuditLogger(config.NewAuditConfig(), &blockingWriter{}, zerolog.Nop())
+		defer al.Close()
+		args := al.redact(map[string]interface{}{
+			"url":      "https://example.com",
+			"Password": "hunter2",
+			"headers":  map[string]interface{}{"Authorization": "Bearer abc", "Accept": "text/html"},
+			"items":    []interface{}{map[string]interface{}{"api_key": "k"}},
+		})
+		if args["url"] != "https://example.com" || args["Password"] != auditRedacted {
+			t.Errorf("Unexpected top-level redaction %v", args)
+		}
+		headers := args["headers"].(map[string]interface{})
+		if headers["Authorization"] != auditRedacted || headers["Accept"] != "text/html" {
+			t.Errorf("Unexpected nested redaction %v", headers)
+		}
+		if args["items"].([]interface{})[0].(map[string]interface{})["api_key"] != auditRedacted {
+			t.Errorf("Expected api_key in an array to be redacted, got %v", args["items"])
+		}
+
+		// browser_fill 的值在选择器指向密码框时脱敏
+		fill := al.redact(map[string]interface{}{"selector": "input[type=password]", "value": "hunter2"})
+		if fill["value"] != auditRedacted || fill["selector"] != "input[type=password]" {
+			t.Errorf("Expected the value of a password input to be redacted, got %v", fill)
+		}
+		fill = al.redact(map[string]interface{}{"selector": "#username", "value": "alice"})
+		if fill["value"] != "alice" {
+			t.Errorf("Expected the value of a plain input to be kept, got %v", fill)
+		}
+	})
+
+	t.Run("WrittenEntries", func(t *testing.T) {
+		dir := t.TempDir()
+		al := openAuditLog(t, dir, 1024*1024, 2)
+		auditCall(t, al, "s1", "browser_fill", map[string]interface{}{"selector": "#password", "value": "hunter2"}, mcp.NewToolResultText("filled"))
+		auditCall(t, al, "s1", "command_execute", map[string]interface{}{"command": "ls"}, rateLimitedError(fmt.Errorf("busy"), time.Second))
+		if err := al.Close(); err != nil {
+			t.Fatalf("Failed to close audit log: %v", err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, AuditFileName+".1"))
+		if err != nil || strings.Contains(string(data), "hunter2") {
+			t.Fatalf("Expected the password to be redacted on disk, got %s %v", data, err)
+		}
+		entries, err := ReadAuditLog(filepath.Join(dir, AuditFileName), AuditFilter{})
+		if err != nil || len(entries) != 2 {
+			t.Fatalf("Expected 2 entries, got %v %v", entries, err)
+		}
+		fill := entries[0]
+		if fill.Kind != AuditKindTool || fill.Session != "s1" || fill.Result == nil || fill.Result.Items != 1 || len(fill.Result.SHA256) != 64 || fill.ErrorCode != "" {
+			t.Errorf("Unexpected fill entry %+v", fill)
+		}
+		if entries[1].ErrorCode != ErrCodeRateLimited {
+			t.Errorf("Expected the structured error code, got %q", entries[1].ErrorCode)
+		}
+		if stats := al.Stats(); stats.Written != 2 || stats.Dropped != 0 {
+			t.Errorf("Unexpected stats %+v", stats)
+		}
+	})
+
+	t.Run("Rotation", func(t *testing.T) {
+		dir := t.TempDir()
+		al := openAuditLog(t, dir, 512, 3)
+		for i := 0; i < 100; i++ {
+			auditCall(t, al, "", "file_read", map[string]interface{}{"n": i}, mcp.NewToolResultText("ok"))
+		}
+		if err := al.Close(); err != nil {
+			t.Fatalf("Failed to close audit log: %v", err)
+		}
+		for i := 1; i <= 3; i++ {
+			if info, err := os.Stat(filepath.Join(dir, fmt.Sprintf("%s.%d", AuditFileName, i))); err != nil || info.Size() == 0 {
+				t.Errorf("Expected rotated file %d to have entries: %v", i, err)
+			}
+		}
+
+		// 重新打开后从最近写入的文件继续写入
+		al = openAuditLog(t, dir, 512, 3)
+		auditCall(t, al, "", "file_read", map[string]interface{}{"n": 100}, mcp.NewToolResultText("ok"))
+		if err := al.Close(); err != nil {
+			t.Fatalf("Failed to close audit log: %v", err)
+		}
+		entries, err := ReadAuditLog(filepath.Join(dir, AuditFileName), AuditFilter{Limit: 5})
+		if err != nil || len(entries) != 5 {
+			t.Fatalf("Expected 5 entries, got %v %v", entries, err)
+		}
+		for i, entry := range entries {
+			if entry.Arguments["n"] != float64(96+i) {
+				t.Errorf("Expected entry %d to be call %d, got %v", i, 96+i, entry.Arguments["n"])
+			}
+		}
+	})
+
+	t.Run("FilterQuery", func(t *testing.T) {
+		dir := t.TempDir()
+		al := openAuditLog(t, dir, 1024*1024, 2)
+		for i := 0; i < 6; i++ {
+			tool, session := "command_execute", "s1"
+			if i%2 == 1 {
+				tool, session = "file_read", "s2"
+			}
+			auditCall(t, al, session, tool, map[string]interface{}{"n": i}, mcp.NewToolResultText("ok"))
+		}
+		if err := al.Close(); err != nil {
+			t.Fatalf("Failed to close audit log: %v", err)
+		}
+		path := filepath.Join(dir, AuditFileName)
+		entries, err := ReadAuditLog(path, AuditFilter{Tool: "command_execute", Limit: 2})
+		if err != nil || len(entries) != 2 || entries[0].Arguments["n"] != float64(2) || entries[1].Arguments["n"] != float64(4) {
+			t.Errorf("Expected the last 2 command_execute calls, got %v %v", entries, err)
+		}
+		entries, _ = ReadAuditLog(path, AuditFilter{Session: "s2"})
+		if len(entries) != 3 || entries[0].Name != "file_read" {
+			t.Errorf("Expected the 3 calls of session s2, got %v", entries)
+		}
+		if entries, _ = ReadAuditLog(filepath.Join(dir, "missing.jsonl"), AuditFilter{}); len(entries) != 0 {
+			t.Errorf("Expected no entries of a missing log, got %v", entries)
+		}
+	})
+
+	t.Run("Overflow", func(t *testing.T) {
+		cfg := config.NewAuditConfig()
+		cfg.BufferSize = 2
+		bw := &blockingWriter{entered: make(chan struct{}), release: make(chan struct{})}
+		al := NewAuditLogger(cfg, bw, zerolog.Nop())
+
+		// 第一条记录阻塞在写入中，之后两条填满缓冲，其余丢弃且不阻塞调用
+		auditCall(t, al, "", "file_read", nil, mcp.NewToolResultText("ok"))
+		<-bw.entered
+		done := make(chan struct{})
+		go func() {
+			for i := 0; i < 5; i++ {
+				auditCall(t, al, "", "file_read", nil, mcp.NewToolResultText("ok"))
+			}
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Tool calls blocked on the audit writer")
+		}
+		if stats := al.Stats(); stats.Dropped != 3 {
+			t.Errorf("Expected 3 dropped entries, got %+v", stats)
+		}
+
+		go func() {
+			for range bw.entered {
+			}
+		}()
+		close(bw.release)
+		if err := al.Close(); err != nil {
+			t.Fatalf("Failed to close audit log: %v", err)
+		}
+		if stats := al.Stats(); stats.Written != 3 || stats.Dropped != 3 || bw.lines != 3 {
+			t.Errorf("Expected 3 written and 3 dropped entries, got %+v, %d lines", stats, bw.lines)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// AuthTokenParam is the query parameter carrying the auth token for clients that can't set headers,
+// such as browser EventSource clients.
+const AuthTokenParam = "token"
+
+// requireAuth wraps next so that requests without the auth token get a 401. An empty auth_token
+// disables authentication.
+func (s *MoLingServer) requireAuth(next http.Handler) http.Handler {
+	token := s.mlConfig.AuthToken
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !checkAuthToken(r, token) {
+			// 不记录请求中携带的令牌
+			s.logger.Warn().Str("remoteAddr", r.RemoteAddr).Str("path", r.URL.Path).Msg("unauthorized request")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="MoLing"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkAuthToken reports whether r carries token as a bearer token or as the token query parameter.
+// Tokens are compared in constant time.
+func checkAuthToken(r *http.Request, token string) bool {
+	got := r.URL.Query().Get(AuthTokenParam)
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		scheme, value, ok := strings.Cut(auth, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return false
+		}
+		got = strings.TrimSpace(value)
+	}
+	return got != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
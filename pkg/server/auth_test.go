@@ -0,0 +1,129 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gojue/moling/pkg/config"
+)
+
+func authGet(t *testing.T, url, header string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if header != "" {
+		req.Header.Set("Authorization", header)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to get %s: %v", url, err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestAuthToken(t *testing.T) {
+	srv, _ := newMetricsServer(t, config.MoLingConfig{MetricsEnabled: true, AuthToken: "s3cret"})
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	})
+	ts := httptest.NewServer(srv.httpMux(ok))
+	defer ts.Close()
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		want   int
+	}{
+		{"NoToken", "/sse", "", http.StatusUnauthorized},
+		{"WrongToken", "/sse", "Bearer wrong", http.StatusUnauthorized},
+		{"WrongScheme", "/sse", "Basic s3cret", http.StatusUnauthorized},
+		{"EmptyQuery", "/sse?token=", "", http.StatusUnauthorized},
+		{"BearerToken", "/sse", "Bearer s3cret", http.StatusOK},
+		{"BearerCaseInsensitive", "/message", "bearer s3cret", http.StatusOK},
+		{"QueryToken", "/sse?token=s3cret", "", http.StatusOK},
+		// 错误的 Authorization 头不会被查询参数覆盖
+		{"HeaderWinsOverQuery", "/sse?token=s3cret", "Bearer wrong", http.StatusUnauthorized},
+		{"MetricsNoToken", MetricsPath, "", http.StatusUnauthorized},
+		{"MetricsToken", MetricsPath, "Bearer s3cret", http.StatusOK},
+		{"HealthzOpen", HealthzPath, "", http.StatusOK},
+		{"ReadyzOpen", ReadyzPath, "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := authGet(t, ts.URL+tt.path, tt.header); got != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("NoAuthToken", func(t *testing.T) {
+		open, _ := newMetricsServer(t, config.MoLingConfig{})
+		ts := httptest.NewServer(open.httpMux(ok))
+		defer ts.Close()
+		if got := authGet(t, ts.URL+"/sse", ""); got != http.StatusOK {
+			t.Errorf("Expected status 200 without auth_token, got %d", got)
+		}
+	})
+
+	t.Run("MessageEndpointKeepsQueryToken", func(t *testing.T) {
+		ts := httptest.NewServer(srv.httpMux(srv.mcpHandler("", nil)))
+		defer ts.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/sse?token=s3cret", nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+			if !strings.Contains(line, "token=s3cret") {
+				t.Errorf("Expected the message endpoint to carry the token, got %q", line)
+			}
+			return
+		}
+		t.Fatal("No endpoint event received")
+	})
+}
@@ -0,0 +1,74 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Banner describes a started server. It is logged on startup and sent to every client as a
+// logging notification once its session is initialized.
+type Banner struct {
+	Version    string   `json:"version"`
+	Services   []string `json:"services"`
+	Transport  string   `json:"transport"` // stdio, sse or http
+	BasePath   string   `json:"base_path"`
+	ListenAddr string   `json:"listen_addr,omitempty"`
+}
+
+// Banner returns the banner of the server.
+func (m *MoLingServer) Banner() Banner {
+	banner := Banner{
+		Version:   m.mlConfig.Version,
+		Services:  make([]string, 0, len(m.services)),
+		Transport: m.transport,
+		BasePath:  m.mlConfig.BasePath,
+	}
+	if m.transport != TransportStdio {
+		banner.ListenAddr = m.listenAddr
+	}
+	for _, srv := range m.services {
+		banner.Services = append(banner.Services, string(srv.Name()))
+	}
+	return banner
+}
+
+// logBanner logs the banner of the server.
+func (m *MoLingServer) logBanner() {
+	banner := m.Banner()
+	m.logger.Info().Str("version", banner.Version).Strs("services", banner.Services).Str("transport", banner.Transport).
+		Str("basePath", banner.BasePath).Str("listenAddr", banner.ListenAddr).Msg("MoLing MCP Server started")
+}
+
+// handleInitialized sends the banner to the client as a logging notification, once the client
+// has confirmed the initialization of its session.
+func (m *MoLingServer) handleInitialized(ctx context.Context, notification mcp.JSONRPCNotification) {
+	params := map[string]any{
+		"level":  mcp.LoggingLevelInfo,
+		"logger": m.mlConfig.ServerName,
+		"data":   m.Banner(),
+	}
+	if err := m.server.SendNotificationToClient(ctx, "notifications/message", params); err != nil {
+		m.logger.Debug().Err(err).Msg("failed to send the banner to the client")
+	}
+}
@@ -0,0 +1,149 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog"
+)
+
+// LogLevelToolName is the name of the tool that changes the log level at runtime.
+const LogLevelToolName = "moling_set_log_level"
+
+// builtinOwner is the owner of the tools of the server itself in the tool name registry.
+const builtinOwner = "MoLing"
+
+// 日志级别的修改来源
+const (
+	LogLevelByTool     = "tool"
+	LogLevelBySetLevel = "logging/setLevel"
+)
+
+// logLevelNames are the levels accepted by moling_set_log_level, from the most verbose.
+var logLevelNames = []string{"trace", "debug", "info", "warn", "error"}
+
+// mcpLogLevels maps the levels of logging/setLevel to zerolog levels. MoLing doesn't log above
+// error, the more severe levels of MCP keep only errors.
+var mcpLogLevels = map[mcp.LoggingLevel]zerolog.Level{
+	mcp.LoggingLevelDebug:     zerolog.DebugLevel,
+	mcp.LoggingLevelInfo:      zerolog.InfoLevel,
+	mcp.LoggingLevelNotice:    zerolog.InfoLevel,
+	mcp.LoggingLevelWarning:   zerolog.WarnLevel,
+	mcp.LoggingLevelError:     zerolog.ErrorLevel,
+	mcp.LoggingLevelCritical:  zerolog.ErrorLevel,
+	mcp.LoggingLevelAlert:     zerolog.ErrorLevel,
+	mcp.LoggingLevelEmergency: zerolog.ErrorLevel,
+}
+
+// LogLevelStatus is the log level in the status resource, with the last runtime change.
+type LogLevelStatus struct {
+	Level     string     `json:"level"`
+	ChangedAt *time.Time `json:"changed_at,omitempty"`
+	ChangedBy string     `json:"changed_by,omitempty"` // tool 或 logging/setLevel
+	Session   string     `json:"session,omitempty"`    // 修改级别的客户端会话
+}
+
+// logLevelState records the last change of the log level.
+type logLevelState struct {
+	mu   sync.Mutex
+	last LogLevelStatus
+}
+
+// parseLogLevel parses a level accepted by moling_set_log_level.
+func parseLogLevel(name string) (zerolog.Level, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, valid := range logLevelNames {
+		if name == valid {
+			return zerolog.ParseLevel(name)
+		}
+	}
+	return zerolog.NoLevel, fmt.Errorf("invalid log level %q, valid levels: %s", name, strings.Join(logLevelNames, ", "))
+}
+
+// SetLogLevel changes the global log level of all services. The change is logged at the old level
+// before switching, so it shows up in the log file either way. zerolog checks the global level
+// when an event is created, so the console writer follows the new level as well as the file.
+func (m *MoLingServer) SetLogLevel(ctx context.Context, level zerolog.Level, source string) {
+	m.logLevel.mu.Lock()
+	defer m.logLevel.mu.Unlock()
+	var session string
+	if cs := server.ClientSessionFromContext(ctx); cs != nil {
+		session = cs.SessionID()
+	}
+	old := zerolog.GlobalLevel()
+	m.logger.WithLevel(old).Str("from", old.String()).Str("to", level.String()).Str("source", source).
+		Str("session", session).Msg("log level changed")
+	zerolog.SetGlobalLevel(level)
+	now := time.Now()
+	m.logLevel.last = LogLevelStatus{ChangedAt: &now, ChangedBy: source, Session: session}
+}
+
+// LogLevel returns the current log level and its last runtime change.
+func (m *MoLingServer) LogLevel() LogLevelStatus {
+	m.logLevel.mu.Lock()
+	defer m.logLevel.mu.Unlock()
+	status := m.logLevel.last
+	status.Level = zerolog.GlobalLevel().String()
+	return status
+}
+
+// handleSetLevel applies the level of a logging/setLevel request that mcp-go has validated and
+// stored on the session.
+func (m *MoLingServer) handleSetLevel(ctx context.Context, id any, message *mcp.SetLevelRequest, result *mcp.EmptyResult) {
+	level, ok := mcpLogLevels[message.Params.Level]
+	if !ok {
+		return
+	}
+	m.SetLogLevel(ctx, level, LogLevelBySetLevel)
+}
+
+// addLogLevelTool registers moling_set_log_level.
+func (m *MoLingServer) addLogLevelTool() {
+	// 内置工具最先登记，不会冲突
+	_ = m.tools.claim(LogLevelToolName, LogLevelToolName, builtinOwner)
+	m.server.AddTool(mcp.NewTool(
+		LogLevelToolName,
+		mcp.WithDescription("Change the log level of MoLing at runtime, e.g. to debug while investigating a problem, without restarting and losing state such as the browser session. Applies to all services and to the log file and console."),
+		mcp.WithString("level",
+			mcp.Description("New log level"),
+			mcp.Required(),
+			mcp.Enum(logLevelNames...),
+		),
+	), m.audit.WrapTool(LogLevelToolName, m.handleSetLogLevelTool))
+}
+
+func (m *MoLingServer) handleSetLogLevelTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, _ := request.GetArguments()["level"].(string)
+	level, err := parseLogLevel(name)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	old := zerolog.GlobalLevel()
+	m.SetLogLevel(ctx, level, LogLevelByTool)
+	return mcp.NewToolResultText(fmt.Sprintf("Log level changed from %s to %s", old, level)), nil
+}
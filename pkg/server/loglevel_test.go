@@ -0,0 +1,200 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/config"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog"
+)
+
+// logSession is an MCP client session that supports logging/setLevel.
+type logSession struct {
+	notifySession
+	level mcp.LoggingLevel
+}
+
+func (ls *logSession) SessionID() string                  { return "log-session" }
+func (ls *logSession) SetLogLevel(level mcp.LoggingLevel) { ls.level = level }
+func (ls *logSession) GetLogLevel() mcp.LoggingLevel      { return ls.level }
+
+// newLogLevelServer creates a server that logs to buf, the global level is restored after the test.
+func newLogLevelServer(t *testing.T, buf *bytes.Buffer) *MoLingServer {
+	t.Helper()
+	_, ctx, err := comm.InitTestEnv()
+	if err != nil {
+		t.Fatalf("Failed to initialize test environment: %v", err)
+	}
+	t.Cleanup(func() { zerolog.SetGlobalLevel(zerolog.DebugLevel) })
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	ctx = context.WithValue(ctx, comm.MoLingLoggerKey, zerolog.New(buf))
+	srv, err := NewMoLingServer(ctx, nil, config.MoLingConfig{BasePath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	return srv
+}
+
+// setLogLevelTool calls moling_set_log_level with level.
+func setLogLevelTool(t *testing.T, srv *MoLingServer, level string) *mcp.CallToolResult {
+	t.Helper()
+	msg := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":%q,"arguments":{"level":%q}}}`, LogLevelToolName, level)
+	resp := srv.server.HandleMessage(context.Background(), json.RawMessage(msg))
+	rpc, ok := resp.(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("Unexpected response %#v", resp)
+	}
+	result, ok := rpc.Result.(mcp.CallToolResult)
+	if !ok {
+		t.Fatalf("Unexpected result %#v", rpc.Result)
+	}
+	return &result
+}
+
+// readStatus reads the status resource.
+func readStatus(t *testing.T, srv *MoLingServer) Status {
+	t.Helper()
+	contents, err := srv.handleStatus(context.Background(), mcp.ReadResourceRequest{})
+	if err != nil {
+		t.Fatalf("Failed to read status: %v", err)
+	}
+	var status Status
+	if err := json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &status); err != nil {
+		t.Fatalf("Failed to unmarshal status: %v", err)
+	}
+	return status
+}
+
+func TestLogLevel(t *testing.T) {
+	t.Run("Tool", func(t *testing.T) {
+		var buf bytes.Buffer
+		srv := newLogLevelServer(t, &buf)
+		srv.logger.Debug().Msg("probe before")
+		if strings.Contains(buf.String(), "probe before") {
+			t.Fatalf("Expected debug messages to be dropped at info")
+		}
+
+		result := setLogLevelTool(t, srv, "debug")
+		if result.IsError {
+			t.Fatalf("Unexpected error: %v", result.Content)
+		}
+		// 切换前以旧级别记录修改
+		if !strings.Contains(buf.String(), `"level":"info","from":"info","to":"debug","source":"tool"`) {
+			t.Errorf("Expected the change to be logged at info, got %s", buf.String())
+		}
+		srv.logger.Debug().Msg("probe debug")
+		if !strings.Contains(buf.String(), "probe debug") {
+			t.Errorf("Expected debug messages to be written after switching to debug")
+		}
+
+		status := readStatus(t, srv)
+		if status.LogLevel.Level != "debug" || status.LogLevel.ChangedBy != LogLevelByTool || status.LogLevel.ChangedAt == nil {
+			t.Errorf("Unexpected log level status %+v", status.LogLevel)
+		}
+
+		setLogLevelTool(t, srv, "error")
+		buf.Reset()
+		srv.logger.Warn().Msg("probe warn")
+		if buf.Len() != 0 {
+			t.Errorf("Expected warnings to be dropped at error, got %s", buf.String())
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		var buf bytes.Buffer
+		srv := newLogLevelServer(t, &buf)
+		result := setLogLevelTool(t, srv, "verbose")
+		if !result.IsError {
+			t.Fatalf("Expected an error for an invalid level")
+		}
+		text := result.Content[0].(mcp.TextContent).Text
+		if !strings.Contains(text, `"verbose"`) || !strings.Contains(text, "trace, debug, info, warn, error") {
+			t.Errorf("Expected the valid levels to be listed, got %s", text)
+		}
+		if zerolog.GlobalLevel() != zerolog.InfoLevel {
+			t.Errorf("Expected the level to be unchanged, got %s", zerolog.GlobalLevel())
+		}
+		for _, name := range []string{"fatal", "panic", "disabled", ""} {
+			if _, err := parseLogLevel(name); err == nil {
+				t.Errorf("Expected %q to be rejected", name)
+			}
+		}
+	})
+
+	t.Run("SetLevel", func(t *testing.T) {
+		var buf bytes.Buffer
+		srv := newLogLevelServer(t, &buf)
+		session := &logSession{}
+		ctx := srv.server.WithContext(context.Background(), session)
+		send := func(level string) mcp.JSONRPCMessage {
+			msg := fmt.Sprintf(`{"jsonrpc":"2.0","id":2,"method":"logging/setLevel","params":{"level":%q}}`, level)
+			return srv.server.HandleMessage(ctx, json.RawMessage(msg))
+		}
+
+		if _, ok := send("debug").(mcp.JSONRPCResponse); !ok {
+			t.Fatalf("Expected logging/setLevel to succeed")
+		}
+		srv.logger.Debug().Msg("probe debug")
+		if !strings.Contains(buf.String(), "probe debug") {
+			t.Errorf("Expected debug messages to be written after logging/setLevel debug")
+		}
+
+		send("warning")
+		buf.Reset()
+		srv.logger.Info().Msg("probe info")
+		if buf.Len() != 0 {
+			t.Errorf("Expected info messages to be dropped at warning, got %s", buf.String())
+		}
+		status := readStatus(t, srv)
+		if status.LogLevel.Level != "warn" || status.LogLevel.ChangedBy != LogLevelBySetLevel || status.LogLevel.Session != "log-session" {
+			t.Errorf("Unexpected log level status %+v", status.LogLevel)
+		}
+
+		// mcp-go 拒绝未知级别，日志级别保持不变
+		if _, ok := send("verbose").(mcp.JSONRPCError); !ok {
+			t.Errorf("Expected an error for an invalid level")
+		}
+		if zerolog.GlobalLevel() != zerolog.WarnLevel {
+			t.Errorf("Expected the level to stay warn, got %s", zerolog.GlobalLevel())
+		}
+	})
+
+	t.Run("Console", func(t *testing.T) {
+		var buf bytes.Buffer
+		srv := newLogLevelServer(t, &bytes.Buffer{})
+		console := zerolog.ConsoleWriter{Out: &buf, NoColor: true}
+		logger := zerolog.New(zerolog.MultiLevelWriter(console, srv.logger))
+		logger.Debug().Msg("probe before")
+		setLogLevelTool(t, srv, "trace")
+		logger.Trace().Msg("probe trace")
+		if strings.Contains(buf.String(), "probe before") || !strings.Contains(buf.String(), "probe trace") {
+			t.Errorf("Expected the console to follow the log level, got %s", buf.String())
+		}
+	})
+}
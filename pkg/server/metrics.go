@@ -0,0 +1,277 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gojue/moling/pkg/services/abstract"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// MetricsPath is the HTTP path of the Prometheus metrics.
+const MetricsPath = "/metrics"
+
+// HealthzPath is the HTTP path of the liveness check.
+const HealthzPath = "/healthz"
+
+// metricsContentType is the content type of the Prometheus text exposition format.
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// latencyBuckets are the upper bounds of the tool latency histogram in seconds. Browser and
+// command tools run for seconds, so the buckets go further than the Prometheus defaults.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// toolKey identifies the series of a tool.
+type toolKey struct {
+	service string
+	tool    string
+}
+
+// toolStats are the counters of a tool. buckets[i] counts the calls up to latencyBuckets[i],
+// the calls above the last bucket are only in calls.
+type toolStats struct {
+	calls   uint64
+	errors  uint64
+	seconds float64
+	buckets []uint64
+}
+
+// Metrics counts the tool calls for the /metrics endpoint. Only the counters of MoLing are
+// exposed, in the Prometheus text format, without the client library. A nil Metrics counts nothing.
+type Metrics struct {
+	mu       sync.Mutex
+	tools    map[toolKey]*toolStats
+	inFlight map[string]int64 // 按服务统计正在执行的调用
+	now      func() time.Time
+	started  time.Time
+}
+
+// NewMetrics creates an empty Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		tools:    make(map[toolKey]*toolStats),
+		inFlight: make(map[string]int64),
+		now:      time.Now,
+		started:  time.Now(),
+	}
+}
+
+// WrapTool counts the calls, errors and latency of a tool of a service.
+func (mt *Metrics) WrapTool(service, tool string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if mt == nil {
+		return handler
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		mt.mu.Lock()
+		mt.inFlight[service]++
+		mt.mu.Unlock()
+		start := mt.now()
+		result, err := handler(ctx, request)
+		mt.observe(toolKey{service, tool}, mt.now().Sub(start), err != nil || (result != nil && result.IsError))
+		return result, err
+	}
+}
+
+// observe records a finished call.
+func (mt *Metrics) observe(key toolKey, d time.Duration, failed bool) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	mt.inFlight[key.service]--
+	ts, ok := mt.tools[key]
+	if !ok {
+		ts = &toolStats{buckets: make([]uint64, len(latencyBuckets))}
+		mt.tools[key] = ts
+	}
+	ts.calls++
+	if failed {
+		ts.errors++
+	}
+	seconds := d.Seconds()
+	ts.seconds += seconds
+	for i, le := range latencyBuckets {
+		if seconds <= le {
+			ts.buckets[i]++
+		}
+	}
+}
+
+// writeTo writes the tool families.
+func (mt *Metrics) writeTo(ew *expositionWriter) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	keys := make([]toolKey, 0, len(mt.tools))
+	for key := range mt.tools {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].service != keys[j].service {
+			return keys[i].service < keys[j].service
+		}
+		return keys[i].tool < keys[j].tool
+	})
+
+	ew.family("moling_tool_calls_total", "Tool calls by service and tool.", "counter")
+	for _, key := range keys {
+		ew.sample("moling_tool_calls_total", float64(mt.tools[key].calls), "service", key.service, "tool", key.tool)
+	}
+	ew.family("moling_tool_errors_total", "Tool calls that returned an error, by service and tool.", "counter")
+	for _, key := range keys {
+		ew.sample("moling_tool_errors_total", float64(mt.tools[key].errors), "service", key.service, "tool", key.tool)
+	}
+	ew.family("moling_tool_duration_seconds", "Latency of tool calls in seconds.", "histogram")
+	for _, key := range keys {
+		ts := mt.tools[key]
+		for i, le := range latencyBuckets {
+			ew.sample("moling_tool_duration_seconds_bucket", float64(ts.buckets[i]), "service", key.service, "tool", key.tool, "le", formatFloat(le))
+		}
+		ew.sample("moling_tool_duration_seconds_bucket", float64(ts.calls), "service", key.service, "tool", key.tool, "le", "+Inf")
+		ew.sample("moling_tool_duration_seconds_sum", ts.seconds, "service", key.service, "tool", key.tool)
+		ew.sample("moling_tool_duration_seconds_count", float64(ts.calls), "service", key.service, "tool", key.tool)
+	}
+
+	services := make([]string, 0, len(mt.inFlight))
+	for service := range mt.inFlight {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	ew.family("moling_tool_calls_in_flight", "Tool calls being executed, by service.", "gauge")
+	for _, service := range services {
+		ew.sample("moling_tool_calls_in_flight", float64(mt.inFlight[service]), "service", service)
+	}
+}
+
+// writeRuntime writes the Go runtime and process families, named like those of the standard
+// Prometheus collectors.
+func (mt *Metrics) writeRuntime(ew *expositionWriter) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	ew.family("go_goroutines", "Number of goroutines that currently exist.", "gauge")
+	ew.sample("go_goroutines", float64(runtime.NumGoroutine()))
+	ew.family("go_info", "Information about the Go environment.", "gauge")
+	ew.sample("go_info", 1, "version", runtime.Version())
+	ew.family("go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", "gauge")
+	ew.sample("go_memstats_alloc_bytes", float64(ms.Alloc))
+	ew.family("go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", "gauge")
+	ew.sample("go_memstats_heap_inuse_bytes", float64(ms.HeapInuse))
+	ew.family("go_memstats_sys_bytes", "Number of bytes obtained from system.", "gauge")
+	ew.sample("go_memstats_sys_bytes", float64(ms.Sys))
+	ew.family("go_gc_cycles_total", "Number of completed GC cycles.", "counter")
+	ew.sample("go_gc_cycles_total", float64(ms.NumGC))
+	ew.family("process_start_time_seconds", "Start time of the process since unix epoch in seconds.", "gauge")
+	ew.sample("process_start_time_seconds", float64(mt.started.UnixNano())/1e9)
+}
+
+// expositionWriter writes metric families in the Prometheus text format.
+type expositionWriter struct {
+	buf bytes.Buffer
+}
+
+// family writes the HELP and TYPE lines of a metric family.
+func (ew *expositionWriter) family(name, help, typ string) {
+	fmt.Fprintf(&ew.buf, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, typ)
+}
+
+// sample writes a sample with label name and value pairs.
+func (ew *expositionWriter) sample(name string, value float64, labels ...string) {
+	ew.buf.WriteString(name)
+	if len(labels) > 0 {
+		ew.buf.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				ew.buf.WriteByte(',')
+			}
+			ew.buf.WriteString(labels[i] + "=\"" + escapeLabelValue(labels[i+1]) + "\"")
+		}
+		ew.buf.WriteByte('}')
+	}
+	ew.buf.WriteString(" " + formatFloat(value) + "\n")
+}
+
+// WriteTo writes the exposition to w.
+func (ew *expositionWriter) WriteTo(w io.Writer) (int64, error) {
+	return ew.buf.WriteTo(w)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer("\\", "\\\\", "\n", "\\n")
+	labelEscaper = strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\"", "\\\"")
+)
+
+// escapeHelp escapes the backslashes and line feeds of a HELP text.
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+// escapeLabelValue escapes the backslashes, double quotes and line feeds of a label value.
+func escapeLabelValue(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// formatFloat formats a sample value, with +Inf, -Inf and NaN spelled as Prometheus expects.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// handleMetrics serves the tool, session, restart and runtime metrics.
+func (m *MoLingServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	ew := &expositionWriter{}
+	m.metrics.writeTo(ew)
+	ew.family("moling_active_sessions", "MCP client sessions with session-scoped state.", "gauge")
+	ew.sample("moling_active_sessions", float64(len(m.sessions.Sessions())))
+	ew.family("moling_service_restarts_total", "Restarts of the process behind a service after a crash, such as Chrome.", "counter")
+	for _, srv := range m.services {
+		if restarter, ok := srv.(abstract.Restarter); ok {
+			ew.sample("moling_service_restarts_total", float64(restarter.Restarts()), "service", string(srv.Name()))
+		}
+	}
+	m.metrics.writeRuntime(ew)
+	w.Header().Set("Content-Type", metricsContentType)
+	if _, err := ew.WriteTo(w); err != nil {
+		m.logger.Debug().Err(err).Msg("failed to write metrics")
+	}
+}
+
+// handleHealthz reports that the server is up.
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = io.WriteString(w, "ok\n")
+}
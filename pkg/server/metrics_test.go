@@ -0,0 +1,243 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/config"
+	"github.com/gojue/moling/pkg/services/abstract"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// metricsService has a tool that succeeds, one that fails and one that blocks until released.
+type metricsService struct {
+	abstract.MLService
+	name    comm.MoLingServerType
+	release chan struct{}
+}
+
+func (ms *metricsService) RegisterTools() error {
+	ms.AddTool(mcp.NewTool("ok_tool"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+	ms.AddTool(mcp.NewTool("fail_tool"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultError("failed"), nil
+	})
+	ms.AddTool(mcp.NewTool("slow_tool"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		<-ms.release
+		return mcp.NewToolResultText("done"), nil
+	})
+	return nil
+}
+
+func (ms *metricsService) Name() comm.MoLingServerType {
+	return ms.name
+}
+
+func (ms *metricsService) Close() error {
+	return nil
+}
+
+func (ms *metricsService) Restarts() int {
+	return 2
+}
+
+func newMetricsServer(t *testing.T, mlConfig config.MoLingConfig) (*MoLingServer, *metricsService) {
+	t.Helper()
+	_, ctx, err := comm.InitTestEnv()
+	if err != nil {
+		t.Fatalf("Failed to initialize test environment: %v", err)
+	}
+	base, err := abstract.NewServiceBase(ctx, "Metrics")
+	if err != nil {
+		t.Fatalf("Failed to create service base: %v", err)
+	}
+	// 服务名包含需要转义的字符
+	ms := &metricsService{MLService: base, name: "Odd\"Name\\\n", release: make(chan struct{})}
+	if err := ms.RegisterTools(); err != nil {
+		t.Fatalf("RegisterTools failed: %v", err)
+	}
+	mlConfig.BasePath = t.TempDir()
+	srv, err := NewMoLingServer(ctx, []abstract.Service{ms}, mlConfig)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	return srv, ms
+}
+
+func scrape(t *testing.T, url string) string {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Failed to scrape %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Unexpected status %d of %s: %s", resp.StatusCode, url, body)
+	}
+	if ct := resp.Header.Get("Content-Type"); strings.HasSuffix(url, MetricsPath) && ct != metricsContentType {
+		t.Errorf("Unexpected content type %q", ct)
+	}
+	return string(body)
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	srv, ms := newMetricsServer(t, config.MoLingConfig{MetricsEnabled: true})
+	ts := httptest.NewServer(srv.httpMux(http.NotFoundHandler()))
+	defer ts.Close()
+
+	for i := 0; i < 3; i++ {
+		callTool(t, srv, "ok_tool")
+	}
+	callTool(t, srv, "fail_tool")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		callTool(t, srv, "slow_tool")
+	}()
+	// 等待 slow_tool 开始执行
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(scrape(t, ts.URL+MetricsPath), `moling_tool_calls_in_flight{service="Odd\"Name\\\n"} 1`) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected slow_tool to be in flight")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(ms.release)
+	<-done
+
+	body := scrape(t, ts.URL+MetricsPath)
+	service := `service="Odd\"Name\\\n"`
+	for _, want := range []string{
+		"# TYPE moling_tool_calls_total counter",
+		fmt.Sprintf("moling_tool_calls_total{%s,tool=\"ok_tool\"} 3", service),
+		fmt.Sprintf("moling_tool_calls_total{%s,tool=\"fail_tool\"} 1", service),
+		fmt.Sprintf("moling_tool_calls_total{%s,tool=\"slow_tool\"} 1", service),
+		fmt.Sprintf("moling_tool_errors_total{%s,tool=\"ok_tool\"} 0", service),
+		fmt.Sprintf("moling_tool_errors_total{%s,tool=\"fail_tool\"} 1", service),
+		"# TYPE moling_tool_duration_seconds histogram",
+		fmt.Sprintf("moling_tool_duration_seconds_bucket{%s,tool=\"ok_tool\",le=\"0.005\"} 3", service),
+		fmt.Sprintf("moling_tool_duration_seconds_bucket{%s,tool=\"ok_tool\",le=\"+Inf\"} 3", service),
+		fmt.Sprintf("moling_tool_duration_seconds_count{%s,tool=\"ok_tool\"} 3", service),
+		fmt.Sprintf("moling_tool_calls_in_flight{%s} 0", service),
+		"moling_active_sessions 0",
+		fmt.Sprintf("moling_service_restarts_total{%s} 2", service),
+		"# TYPE go_goroutines gauge",
+		"process_start_time_seconds ",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in the metrics:\n%s", want, body)
+		}
+	}
+	// 每个样本行都是 名称{标签} 值 的格式
+	for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if i := strings.LastIndex(line, " "); i <= 0 || strings.ContainsAny(line[i+1:], "{}\"") {
+			t.Errorf("Malformed sample line %q", line)
+		}
+	}
+
+	if got := scrape(t, ts.URL+HealthzPath); got != "ok\n" {
+		t.Errorf("Unexpected healthz %q", got)
+	}
+}
+
+func TestMetricsListenAddr(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	srv, _ := newMetricsServer(t, config.MoLingConfig{MetricsEnabled: true, MetricsListenAddr: addr})
+	if err := srv.serveMetrics(); err != nil {
+		t.Fatalf("serveMetrics failed: %v", err)
+	}
+	callTool(t, srv, "ok_tool")
+	if body := scrape(t, "http://"+addr+MetricsPath); !strings.Contains(body, `tool="ok_tool"} 1`) {
+		t.Errorf("Expected the call on the metrics address, got:\n%s", body)
+	}
+
+	// MCP 地址上不再提供 /metrics
+	ts := httptest.NewServer(srv.httpMux(http.NotFoundHandler()))
+	defer ts.Close()
+	resp, err := http.Get(ts.URL + MetricsPath)
+	if err != nil {
+		t.Fatalf("Failed to get metrics: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected no /metrics on the MCP address, got %d", resp.StatusCode)
+	}
+}
+
+func TestMetricsDisabled(t *testing.T) {
+	srv, _ := newMetricsServer(t, config.MoLingConfig{})
+	if srv.metrics != nil {
+		t.Fatal("Expected no metrics when metrics_enabled is off")
+	}
+	callTool(t, srv, "ok_tool")
+	ts := httptest.NewServer(srv.httpMux(http.NotFoundHandler()))
+	defer ts.Close()
+	resp, err := http.Get(ts.URL + MetricsPath)
+	if err != nil {
+		t.Fatalf("Failed to get metrics: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected no /metrics when disabled, got %d", resp.StatusCode)
+	}
+}
+
+func TestMetricsEscaping(t *testing.T) {
+	for in, want := range map[string]string{
+		`plain`:       `plain`,
+		`back\slash`:  `back\\slash`,
+		`"quoted"`:    `\"quoted\"`,
+		"line\nbreak": `line\nbreak`,
+		"\\\"\n":      `\\\"\n`,
+		"中文 tool_名称":  "中文 tool_名称",
+	} {
+		if got := escapeLabelValue(in); got != want {
+			t.Errorf("escapeLabelValue(%q) = %q, expected %q", in, got, want)
+		}
+	}
+	if got := escapeHelp("a \"b\" \\ c\nd"); got != `a "b" \\ c\nd` {
+		t.Errorf("Unexpected escaped help %q", got)
+	}
+}
@@ -22,99 +22,431 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gojue/moling/pkg/comm"
 	"github.com/gojue/moling/pkg/config"
 	"github.com/gojue/moling/pkg/services/abstract"
+	"github.com/gojue/moling/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/rs/zerolog"
 )
 
+// StatusURI is the URI of the status resource of the server.
+const StatusURI = "moling://status"
+
+// ConfigSchemaURITemplate is the URI template of the configuration schema of a service.
+const ConfigSchemaURITemplate = "moling://config-schema/{service}"
+
 // MoLingServer 服务器实例
 type MoLingServer struct {
-	ctx        context.Context     // 上下文
-	server     *server.MCPServer   // MCP服务器实例
-	services   []abstract.Service  // 服务列表
-	logger     zerolog.Logger      // 日志记录器
-	mlConfig   config.MoLingConfig // 配置
-	listenAddr string              // SSE模式监听地址，如果为空，则使用STDIO模式
+	ctx        context.Context                 // 上下文
+	server     *server.MCPServer               // MCP服务器实例
+	services   []abstract.Service              // 服务列表
+	logger     zerolog.Logger                  // 日志记录器
+	mlConfig   config.MoLingConfig             // 配置
+	listenAddr string                          // SSE/HTTP 模式监听地址
+	transport  string                          // 传输方式：stdio、sse 或 http
+	limiter    *RateLimiter                    // 工具调用限流器
+	resLimiter *ResultLimiter                  // 工具结果大小限制
+	tools      *namespace                      // 已注册的工具名及其所属服务
+	prompts    *namespace                      // 已注册的提示名及其所属服务
+	resources  *namespace                      // 已注册的资源 URI 及其所属服务
+	notifies   *namespace                      // 已注册的通知名及其所属服务
+	notifyFns  map[string]notificationHandlers // 各通知的处理程序，按加载顺序调用
+	sessions   *SessionManager                 // 客户端会话及会话级服务状态
+	audit      *AuditLogger                    // 审计日志，未启用时为 nil
+	metrics    *Metrics                        // Prometheus 指标，未启用时为 nil
+	loads      *serviceStates                  // 各服务的加载状态，用于 /readyz
+	logLevel   logLevelState                   // 运行时修改的日志级别
+	httpLock   sync.Mutex                      // 保护以下 HTTP 服务器字段
+	httpSrv    *http.Server                    // sse/http 传输的 HTTP 服务器，未启动时为 nil
+	httpAddr   net.Addr                        // httpSrv 实际监听的地址
+	sseSrv     *server.SSEServer               // sse 传输的 SSE 服务器，关闭时先结束所有 SSE 会话
+	metricsSrv *http.Server                    // metrics_listen_addr 上的 HTTP 服务器
+	stopping   bool                            // 已调用 Shutdown，之后不再启动 HTTP 服务器
 }
 
 // NewMoLingServer 创建MoLingServer实例
 func NewMoLingServer(ctx context.Context, srvs []abstract.Service, mlConfig config.MoLingConfig) (*MoLingServer, error) {
+	transport, err := ResolveTransport(mlConfig.TransportMode, mlConfig.ListenAddr)
+	if err != nil {
+		return nil, err
+	}
+	if err := mlConfig.RateLimit.Check(); err != nil {
+		return nil, fmt.Errorf("invalid rate limit config: %w", err)
+	}
+	if err := mlConfig.ResultLimit.Check(); err != nil {
+		return nil, fmt.Errorf("invalid result limit config: %w", err)
+	}
+	if err := mlConfig.Session.Check(); err != nil {
+		return nil, fmt.Errorf("invalid session config: %w", err)
+	}
+	logger, ok := ctx.Value(comm.MoLingLoggerKey).(zerolog.Logger)
+	if !ok {
+		// 上下文中没有日志记录器时输出到标准错误，避免 panic
+		logger = zerolog.New(os.Stderr).With().Timestamp().Logger()
+	}
+	var audit *AuditLogger
+	if mlConfig.AuditLog {
+		if err := mlConfig.Audit.Check(); err != nil {
+			return nil, fmt.Errorf("invalid audit config: %w", err)
+		}
+		auditFile := filepath.Join(mlConfig.BasePath, "logs", AuditFileName)
+		rw, err := utils.NewRotateWriterN(auditFile, int64(mlConfig.Audit.MaxSize), mlConfig.Audit.MaxFiles)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log %s: %w", auditFile, err)
+		}
+		audit = NewAuditLogger(mlConfig.Audit, rw, logger)
+	}
+	var metrics *Metrics
+	if mlConfig.MetricsEnabled {
+		metrics = NewMetrics()
+	}
+	sessions := NewSessionManager(mlConfig.Session, logger)
+	var ms *MoLingServer
+	// 客户端断开时释放其会话级状态
+	hooks := &server.Hooks{}
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		sessions.CloseSession(session.SessionID())
+	})
+	// 客户端通过 logging/setLevel 修改日志级别
+	hooks.AddAfterSetLevel(func(ctx context.Context, id any, message *mcp.SetLevelRequest, result *mcp.EmptyResult) {
+		ms.handleSetLevel(ctx, id, message, result)
+	})
 	mcpServer := server.NewMCPServer(
 		mlConfig.ServerName,
 		mlConfig.Version,
 		server.WithResourceCapabilities(true, true),
 		server.WithLogging(),
 		server.WithPromptCapabilities(true),
+		server.WithHooks(hooks),
 	)
 	// Set the context for the server
-	ms := &MoLingServer{
+	ms = &MoLingServer{
 		ctx:        ctx,
 		server:     mcpServer,
 		services:   srvs,
 		listenAddr: mlConfig.ListenAddr,
-		logger:     ctx.Value(comm.MoLingLoggerKey).(zerolog.Logger),
+		transport:  transport,
+		logger:     logger,
 		mlConfig:   mlConfig,
+		limiter:    NewRateLimiter(mlConfig.RateLimit, logger),
+		resLimiter: NewResultLimiter(mlConfig.ResultLimit, filepath.Join(mlConfig.BasePath, "data", OverflowDir), logger),
+		tools:      newNamespace(kindTool),
+		prompts:    newNamespace(kindPrompt),
+		resources:  newNamespace(kindResource),
+		notifies:   newNamespace(kindNotification),
+		notifyFns:  make(map[string]notificationHandlers),
+		sessions:   sessions,
+		audit:      audit,
+		metrics:    metrics,
+		loads:      newServiceStates(srvs),
 	}
-	err := ms.init()
+	err = ms.init()
 	return ms, err
 }
 
-// init 初始化MoLingServer实例
+// init 初始化MoLingServer实例，服务之间的命名冲突使启动失败
 func (m *MoLingServer) init() error {
-	var err error
+	// 内置资源最先登记，不会冲突
+	_ = m.resources.claim(StatusURI, StatusURI, builtinOwner)
+	_ = m.resources.claim(ConfigSchemaURITemplate, ConfigSchemaURITemplate, builtinOwner)
+	m.server.AddResource(mcp.NewResource(StatusURI, "MoLing Status",
+		mcp.WithResourceDescription("Active client sessions with their session-scoped service state, rate limit counters, audit log counters and the log level"),
+		mcp.WithMIMEType("application/json"),
+	), m.audit.WrapResource(m.handleStatus))
+	m.server.AddResourceTemplate(mcp.NewResourceTemplate(ConfigSchemaURITemplate, "Service Config Schema",
+		mcp.WithTemplateDescription("JSON Schema of the configuration of a service, with field types, defaults, enums and descriptions"),
+		mcp.WithTemplateMIMEType("application/schema+json"),
+	), server.ResourceTemplateHandlerFunc(m.audit.WrapResource(m.handleConfigSchema)))
+	m.addLogLevelTool()
 	for _, srv := range m.services {
 		m.logger.Debug().Str("serviceName", string(srv.Name())).Msg("Loading service")
-		err = m.loadService(srv)
-		if err != nil {
-			m.logger.Info().Err(err).Str("serviceName", string(srv.Name())).Msg("Failed to load service")
+		if err := m.loadService(srv); err != nil {
+			m.loads.set(string(srv.Name()), ServiceFailed, err)
+			m.logger.Error().Err(err).Str("serviceName", string(srv.Name())).Msg("Failed to load service")
+			return fmt.Errorf("failed to load service %s: %w", srv.Name(), err)
 		}
+		m.loads.set(string(srv.Name()), ServiceLoaded, nil)
 	}
-	return err
+	// 客户端完成初始化后发送启动信息
+	m.notifyFns["notifications/initialized"] = append(m.notifyFns["notifications/initialized"], m.handleInitialized)
+	for n, handlers := range m.notifyFns {
+		m.server.AddNotificationHandler(n, handlers.handle)
+	}
+	return nil
 }
 
-// loadService 加载服务
+// registeredName 返回服务的工具或提示注册到 MCP 服务器的名字
+func (m *MoLingServer) registeredName(service, name string) string {
+	if m.mlConfig.ToolNamePrefixing {
+		return prefixedName(service, name)
+	}
+	return name
+}
+
+// claimNames 登记服务的所有名字，与已加载的服务冲突时返回 ErrNameCollision
+func (m *MoLingServer) claimNames(srv abstract.Service) error {
+	service := string(srv.Name())
+	hint := ""
+	if !m.mlConfig.ToolNamePrefixing {
+		hint = ", set tool_name_prefixing to prefix tool and prompt names with the service name"
+	}
+	for _, tool := range srv.Tools() {
+		if err := m.tools.claim(m.registeredName(service, tool.Tool.Name), tool.Tool.Name, service); err != nil {
+			return fmt.Errorf("%w%s", err, hint)
+		}
+	}
+	for _, pe := range srv.Prompts() {
+		name := pe.Prompt().Name
+		if err := m.prompts.claim(m.registeredName(service, name), name, service); err != nil {
+			return fmt.Errorf("%w%s", err, hint)
+		}
+		if !m.mlConfig.NoPromptResources {
+			uri := promptResourceURI(m.registeredName(service, name))
+			if err := m.resources.claim(uri, uri, service); err != nil {
+				return err
+			}
+		}
+	}
+	// 资源由 URI 标识，URI 被其他资源引用，无法加前缀，冲突时总是报错
+	for r := range srv.Resources() {
+		if err := m.resources.claim(r.URI, r.URI, service); err != nil {
+			return err
+		}
+	}
+	for rt := range srv.ResourceTemplates() {
+		uri := rt.URITemplate.Raw()
+		if err := m.resources.claim(uri, uri, service); err != nil {
+			return err
+		}
+	}
+	// 通知名由协议定义，无法加前缀。启用前缀时同名通知的处理程序按加载顺序依次调用
+	if !m.mlConfig.ToolNamePrefixing {
+		for n := range srv.NotificationHandlers() {
+			if err := m.notifies.claim(n, n, service); err != nil {
+				return fmt.Errorf("%w%s", err, ", set tool_name_prefixing to call all handlers of the notification")
+			}
+		}
+	}
+	return nil
+}
+
+// loadService 加载服务，名字与已加载的服务冲突时不注册该服务的任何内容
 func (m *MoLingServer) loadService(srv abstract.Service) error {
+	if err := m.claimNames(srv); err != nil {
+		return err
+	}
+	service := string(srv.Name())
 
 	// 添加资源
 	for r, rhf := range srv.Resources() {
-		m.server.AddResource(r, rhf)
+		m.server.AddResource(r, m.audit.WrapResource(rhf))
 	}
 
 	// 添加资源模板
 	for rt, rthf := range srv.ResourceTemplates() {
-		m.server.AddResourceTemplate(rt, rthf)
+		m.server.AddResourceTemplate(rt, server.ResourceTemplateHandlerFunc(m.audit.WrapResource(server.ResourceHandlerFunc(rthf))))
 	}
 
-	// 添加工具
-	m.server.AddTools(srv.Tools()...)
+	// 添加工具，统一经过限流和结果大小限制中间件
+	starter, lazy := srv.(abstract.Starter)
+	scoped, _ := srv.(abstract.SessionScoped)
+	tools := append([]server.ServerTool(nil), srv.Tools()...)
+	for i := range tools {
+		original := tools[i].Tool.Name
+		tools[i].Tool.Name = m.registeredName(service, original)
+		handler := tools[i].Handler
+		if lazy {
+			// 浏览器等重量级服务在首次调用工具时才启动
+			handler = withStart(srv.Name(), starter, handler)
+		}
+		handler = m.sessions.Wrap(srv.Name(), scoped, handler)
+		handler = m.resLimiter.Wrap(tools[i].Tool.Name, handler, toolAliases(service, original, tools[i].Tool.Name)...)
+		handler = m.limiter.Wrap(srv.Name(), handler)
+		handler = m.metrics.WrapTool(service, tools[i].Tool.Name, handler)
+		tools[i].Handler = m.audit.WrapTool(tools[i].Tool.Name, handler)
+	}
+	m.server.AddTools(tools...)
 
 	// 添加通知处理程序
 	for n, nhf := range srv.NotificationHandlers() {
-		m.server.AddNotificationHandler(n, nhf)
+		m.notifyFns[n] = append(m.notifyFns[n], nhf)
 	}
 
 	// 添加提示
 	for _, pe := range srv.Prompts() {
-		// 添加提示
-		m.server.AddPrompt(pe.Prompt(), pe.Handler())
+		prompt := pe.Prompt()
+		original := prompt.Name
+		prompt.Name = m.registeredName(service, prompt.Name)
+		m.server.AddPrompt(prompt, m.audit.WrapPrompt(prompt.Name, pe.Handler()))
+		// 不支持提示的客户端可以通过资源读取提示
+		if !m.mlConfig.NoPromptResources {
+			resource := newPromptResource(prompt)
+			m.server.AddResource(resource, m.audit.WrapResource(promptResourceHandler(resource.URI, original, pe.Handler())))
+		}
 	}
 	return nil
 }
 
+// withStart starts the service before the tool call, a startup error becomes the result of the call.
+func withStart(name comm.MoLingServerType, starter abstract.Starter, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := starter.Start(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to start %s service: %v", name, err)), nil
+		}
+		return handler(ctx, request)
+	}
+}
+
+// RateLimitStats 返回工具调用的限流统计
+func (m *MoLingServer) RateLimitStats() RateLimitStats {
+	return m.limiter.Stats()
+}
+
+// Sessions 返回活跃的客户端会话
+func (m *MoLingServer) Sessions() []SessionInfo {
+	return m.sessions.Sessions()
+}
+
+// Names returns the registered tool and prompt names with their services.
+func (m *MoLingServer) Names() NameMapping {
+	return NameMapping{Prefixing: m.mlConfig.ToolNamePrefixing, Tools: m.tools.list(), Prompts: m.prompts.list()}
+}
+
+// Status is the content of the status resource.
+type Status struct {
+	Sessions  []SessionInfo  `json:"sessions"`
+	RateLimit RateLimitStats `json:"rate_limit"`
+	Audit     *AuditStats    `json:"audit,omitempty"`
+	LogLevel  LogLevelStatus `json:"log_level"`
+	Names     NameMapping    `json:"names"`
+}
+
+// handleStatus returns the status resource as JSON.
+func (m *MoLingServer) handleStatus(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	status := Status{Sessions: m.Sessions(), RateLimit: m.RateLimitStats(), LogLevel: m.LogLevel(), Names: m.Names()}
+	if m.audit != nil {
+		stats := m.audit.Stats()
+		status.Audit = &stats
+	}
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{URI: StatusURI, MIMEType: "application/json", Text: string(data)},
+	}, nil
+}
+
+// handleConfigSchema returns the configuration schema of the service named in the URI.
+func (m *MoLingServer) handleConfigSchema(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	name := strings.TrimPrefix(request.Params.URI, strings.TrimSuffix(ConfigSchemaURITemplate, "{service}"))
+	for _, srv := range m.services {
+		if !strings.EqualFold(string(srv.Name()), name) {
+			continue
+		}
+		schema := srv.ConfigSchema()
+		if schema == "" {
+			return nil, fmt.Errorf("service %s has no config schema", srv.Name())
+		}
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{URI: request.Params.URI, MIMEType: "application/schema+json", Text: schema},
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown service %q", name)
+}
+
+// Close 写入缓冲的审计日志并关闭
+func (m *MoLingServer) Close() error {
+	if m.audit == nil {
+		return nil
+	}
+	return m.audit.Close()
+}
+
+// httpMux returns the mux of the SSE mode: the MCP endpoints, /healthz, /readyz and, unless metrics
+// have their own listen address, /metrics. With an auth_token, all of them but /healthz and /readyz
+// require the token.
+func (s *MoLingServer) httpMux(sse http.Handler) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/", s.requireAuth(sse))
+	mux.HandleFunc(HealthzPath, handleHealthz)
+	mux.HandleFunc(ReadyzPath, s.handleReadyz)
+	if s.metrics != nil && s.mlConfig.MetricsListenAddr == "" {
+		mux.Handle(MetricsPath, s.requireAuth(http.HandlerFunc(s.handleMetrics)))
+	}
+	return mux
+}
+
+// serveMetrics serves /metrics, /healthz and /readyz on metrics_listen_addr, so that metrics aren't exposed
+// on the interface of the MCP endpoint. It returns after the address is bound.
+func (s *MoLingServer) serveMetrics() error {
+	if s.metrics == nil || s.mlConfig.MetricsListenAddr == "" {
+		return nil
+	}
+	ln, err := net.Listen("tcp", s.mlConfig.MetricsListenAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on metrics_listen_addr %s: %w", s.mlConfig.MetricsListenAddr, err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(MetricsPath, s.handleMetrics)
+	mux.HandleFunc(HealthzPath, handleHealthz)
+	mux.HandleFunc(ReadyzPath, s.handleReadyz)
+	ms := &http.Server{Handler: mux}
+	s.httpLock.Lock()
+	if s.stopping {
+		s.httpLock.Unlock()
+		return ln.Close()
+	}
+	s.metricsSrv = ms
+	s.httpLock.Unlock()
+	s.logger.Info().Str("metricsAddr", ln.Addr().String()).Msg("Serving metrics")
+	go func() {
+		if err := ms.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error().Err(err).Msg("metrics server stopped")
+		}
+	}()
+	return nil
+}
+
+// mcpHandler returns the handler of the MCP endpoints of the sse or http transport. hs is the
+// HTTP server of the handler, the SSE server shuts it down after ending its sessions.
+func (s *MoLingServer) mcpHandler(baseURL string, hs *http.Server) http.Handler {
+	if s.transport == TransportHTTP {
+		mux := http.NewServeMux()
+		mux.Handle(StreamableHTTPPath, server.NewStreamableHTTPServer(s.server, server.WithEndpointPath(StreamableHTTPPath)))
+		return mux
+	}
+	opts := []server.SSEOption{server.WithBaseURL(baseURL)}
+	if hs != nil {
+		opts = append(opts, server.WithHTTPServer(hs))
+	}
+	if s.mlConfig.AuthToken != "" {
+		// 通过 ?token= 连接的客户端，消息端点沿用同样的查询参数
+		opts = append(opts, server.WithAppendQueryToMessageEndpoint())
+	}
+	return server.NewSSEServer(s.server, opts...)
+}
+
 // Serve 启动服务
 func (s *MoLingServer) Serve() error {
 	mLogger := log.New(s.logger, s.mlConfig.ServerName, 0)
+	go s.sessions.Run(s.ctx)
 
-	// 监听地址不为空，启动sse服务
-	if s.listenAddr != "" {
+	if s.transport == TransportSSE || s.transport == TransportHTTP {
 		// 设置监听地址
 		ltnAddr := fmt.Sprintf("http://%s", strings.TrimPrefix(s.listenAddr, "http://"))
 		// 设置控制台输出
@@ -124,13 +456,89 @@ func (s *MoLingServer) Serve() error {
 		// 设置日志记录器
 		s.logger = zerolog.New(multi).With().Timestamp().Logger()
 		// 设置日志记录器
-		s.logger.Info().Str("listenAddr", s.listenAddr).Str("BaseURL", ltnAddr).Msg("Starting SSE server")
-		// 设置日志记录器
-		s.logger.Warn().Msgf("The SSE server URL must be: %s. Please do not make mistakes, even if it is another IP or domain name on the same computer, it cannot be mixed.", ltnAddr)
-		return server.NewSSEServer(s.server, server.WithBaseURL(ltnAddr)).Start(s.listenAddr)
+		s.logBanner()
+		if s.transport == TransportHTTP {
+			s.logger.Info().Str("listenAddr", s.listenAddr).Str("endpoint", ltnAddr+StreamableHTTPPath).Msg("Starting streamable HTTP server")
+		} else {
+			s.logger.Info().Str("listenAddr", s.listenAddr).Str("BaseURL", ltnAddr).Msg("Starting SSE server")
+			// 设置日志记录器
+			s.logger.Warn().Msgf("The SSE server URL must be: %s. Please do not make mistakes, even if it is another IP or domain name on the same computer, it cannot be mixed.", ltnAddr)
+		}
+		if s.mlConfig.AuthToken != "" {
+			s.logger.Info().Msg("MCP endpoints require the auth token")
+		}
+		hs := &http.Server{Addr: s.listenAddr}
+		handler := s.mcpHandler(ltnAddr, hs)
+		hs.Handler = s.httpMux(handler)
+		if err := s.serveMetrics(); err != nil {
+			return err
+		}
+		ln, err := net.Listen("tcp", s.listenAddr)
+		if err != nil {
+			return err
+		}
+		sse, _ := handler.(*server.SSEServer)
+		s.httpLock.Lock()
+		if s.stopping {
+			// Shutdown 先于监听完成
+			s.httpLock.Unlock()
+			_ = ln.Close()
+			return nil
+		}
+		s.httpSrv, s.httpAddr, s.sseSrv = hs, ln.Addr(), sse
+		s.httpLock.Unlock()
+		if err := hs.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
 	}
 
-	// 监听地址为空，启动stdio服务
+	// stdio 传输
+	if s.listenAddr != "" {
+		s.logger.Warn().Str("listenAddr", s.listenAddr).Msg("listen_addr is ignored by the stdio transport")
+	}
+	if s.metrics != nil {
+		s.logger.Warn().Msg("metrics_enabled only takes effect in the sse and http transports, /metrics is not served over STDIO")
+	}
+	s.logBanner()
 	s.logger.Info().Msg("Starting STDIO server")
 	return server.ServeStdio(s.server, server.WithErrorLogger(mLogger))
 }
+
+// Addr returns the address the sse or http transport listens on, nil before it is bound and in
+// the stdio transport.
+func (s *MoLingServer) Addr() net.Addr {
+	s.httpLock.Lock()
+	defer s.httpLock.Unlock()
+	return s.httpAddr
+}
+
+// Shutdown stops the HTTP servers of the sse and http transports: they stop accepting
+// connections, SSE sessions are ended and in-flight requests are waited for until ctx is done,
+// then the remaining connections are closed. It is a no-op in the stdio transport.
+func (s *MoLingServer) Shutdown(ctx context.Context) error {
+	s.httpLock.Lock()
+	s.stopping = true
+	hs, sse, ms := s.httpSrv, s.sseSrv, s.metricsSrv
+	s.httpLock.Unlock()
+
+	var errs []error
+	if hs != nil {
+		var err error
+		if sse != nil {
+			// 先结束 SSE 会话，否则长连接会一直阻塞 Shutdown
+			err = sse.Shutdown(ctx)
+		} else {
+			err = hs.Shutdown(ctx)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to shut down %s server: %w", s.transport, err), hs.Close())
+		}
+	}
+	if ms != nil {
+		if err := ms.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to shut down metrics server: %w", err), ms.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -21,15 +21,27 @@
 package server
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gojue/moling/pkg/comm"
 	"github.com/gojue/moling/pkg/config"
 	"github.com/gojue/moling/pkg/services/abstract"
 	"github.com/gojue/moling/pkg/services/filesystem"
 	"github.com/gojue/moling/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
 )
 
 func TestNewMLServer(t *testing.T) {
@@ -66,7 +78,7 @@ func TestNewMLServer(t *testing.T) {
 	if err != nil {
 		t.Errorf("Failed to create filesystem server: %v", err)
 	}
-	err = fs.Init()
+	err = fs.RegisterTools()
 	if err != nil {
 		t.Errorf("Failed to initialize filesystem server: %v", err)
 	}
@@ -83,3 +95,453 @@ func TestNewMLServer(t *testing.T) {
 	}
 	t.Logf("Server started successfully: %v", srv)
 }
+
+// lazyService is a service whose Start counts how often it is called.
+type lazyService struct {
+	abstract.MLService
+	starts   atomic.Int32
+	startErr error
+}
+
+func (ls *lazyService) RegisterTools() error {
+	ls.AddTool(mcp.NewTool("navigate"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("navigated"), nil
+	})
+	return nil
+}
+
+func (ls *lazyService) Start() error {
+	ls.starts.Add(1)
+	// 模拟启动浏览器的耗时，放大并发竞争
+	time.Sleep(10 * time.Millisecond)
+	return ls.startErr
+}
+
+func (ls *lazyService) Name() comm.MoLingServerType {
+	return "Lazy"
+}
+
+func (ls *lazyService) Close() error {
+	return nil
+}
+
+func callTool(t *testing.T, srv *MoLingServer, name string) *mcp.CallToolResult {
+	t.Helper()
+	msg := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":%q}}`, name)
+	resp := srv.server.HandleMessage(context.Background(), json.RawMessage(msg))
+	rpc, ok := resp.(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("Unexpected response %#v", resp)
+	}
+	result, ok := rpc.Result.(mcp.CallToolResult)
+	if !ok {
+		t.Fatalf("Unexpected result %#v", rpc.Result)
+	}
+	return &result
+}
+
+func TestLazyStart(t *testing.T) {
+	_, ctx, err := comm.InitTestEnv()
+	if err != nil {
+		t.Fatalf("Failed to initialize test environment: %v", err)
+	}
+	newLazyServer := func(startErr error) (*MoLingServer, *lazyService) {
+		base, err := abstract.NewServiceBase(ctx, "Lazy")
+		if err != nil {
+			t.Fatalf("Failed to create service base: %v", err)
+		}
+		ls := &lazyService{MLService: base, startErr: startErr}
+		if err := ls.RegisterTools(); err != nil {
+			t.Fatalf("RegisterTools failed: %v", err)
+		}
+		srv, err := NewMoLingServer(ctx, []abstract.Service{ls}, config.MoLingConfig{BasePath: t.TempDir()})
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		return srv, ls
+	}
+
+	t.Run("NotStartedByLoading", func(t *testing.T) {
+		_, ls := newLazyServer(nil)
+		if n := ls.starts.Load(); n != 0 {
+			t.Errorf("Expected no start before the first tool call, got %d", n)
+		}
+	})
+
+	t.Run("StartBeforeEveryCall", func(t *testing.T) {
+		srv, ls := newLazyServer(nil)
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if result := callTool(t, srv, "navigate"); result.IsError {
+					t.Errorf("Unexpected error: %v", result.Content)
+				}
+			}()
+		}
+		wg.Wait()
+		// Start 的幂等由服务自己保证，服务器每次调用都会先调用 Start
+		if n := ls.starts.Load(); n != 8 {
+			t.Errorf("Expected Start before each of the 8 calls, got %d", n)
+		}
+	})
+
+	t.Run("StartError", func(t *testing.T) {
+		srv, _ := newLazyServer(fmt.Errorf("chrome not found"))
+		result := callTool(t, srv, "navigate")
+		text := result.Content[0].(mcp.TextContent).Text
+		if !result.IsError || !strings.Contains(text, "failed to start Lazy service: chrome not found") {
+			t.Errorf("Expected startup error in the result, got %s", text)
+		}
+	})
+}
+
+// staticService is a service with a single tool that returns a fixed text.
+type staticService struct {
+	abstract.MLService
+	name comm.MoLingServerType
+	text string
+}
+
+func (ss *staticService) RegisterTools() error {
+	ss.AddTool(mcp.NewTool("navigate"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText(ss.text), nil
+	})
+	return nil
+}
+
+func (ss *staticService) Name() comm.MoLingServerType {
+	return ss.name
+}
+
+func (ss *staticService) Close() error {
+	return nil
+}
+
+// overlapService is a service whose tool, prompt, resource and notification handler have the same
+// names as those of every other overlapService.
+type overlapService struct {
+	abstract.MLService
+	name     comm.MoLingServerType
+	resource bool
+	notified atomic.Int32
+}
+
+func (ov *overlapService) RegisterTools() error {
+	ov.AddTool(mcp.NewTool("navigate"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText(strings.Repeat(string(ov.name), 10)), nil
+	})
+	ov.AddPrompt(abstract.PromptEntry{
+		PromptVar: mcp.NewPrompt("usage"),
+		HandlerFunc: func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			return mcp.NewGetPromptResult(string(ov.name), nil), nil
+		},
+	})
+	ov.AddNotificationHandler("notifications/custom", func(ctx context.Context, notification mcp.JSONRPCNotification) {
+		ov.notified.Add(1)
+	})
+	if ov.resource {
+		ov.AddResource(mcp.NewResource("file:///shared", "shared"), func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return nil, nil
+		})
+	}
+	return nil
+}
+
+func (ov *overlapService) Name() comm.MoLingServerType {
+	return ov.name
+}
+
+func (ov *overlapService) Close() error {
+	return nil
+}
+
+func TestToolNameCollision(t *testing.T) {
+	_, ctx, err := comm.InitTestEnv()
+	if err != nil {
+		t.Fatalf("Failed to initialize test environment: %v", err)
+	}
+	newServices := func(resource bool) []*overlapService {
+		var srvs []*overlapService
+		for _, name := range []comm.MoLingServerType{"Builtin", "Plugin"} {
+			base, err := abstract.NewServiceBase(ctx, name)
+			if err != nil {
+				t.Fatalf("Failed to create service base: %v", err)
+			}
+			ov := &overlapService{MLService: base, name: name, resource: resource}
+			if err := ov.RegisterTools(); err != nil {
+				t.Fatalf("RegisterTools failed: %v", err)
+			}
+			srvs = append(srvs, ov)
+		}
+		return srvs
+	}
+	newServer := func(mlConfig config.MoLingConfig, srvs []*overlapService) (*MoLingServer, error) {
+		mlConfig.BasePath = t.TempDir()
+		var services []abstract.Service
+		for _, ov := range srvs {
+			services = append(services, ov)
+		}
+		return NewMoLingServer(ctx, services, mlConfig)
+	}
+
+	t.Run("FailByDefault", func(t *testing.T) {
+		_, err := newServer(config.MoLingConfig{}, newServices(false))
+		if !errors.Is(err, ErrNameCollision) {
+			t.Fatalf("Expected ErrNameCollision, got %v", err)
+		}
+		for _, want := range []string{`tool "navigate"`, "service Plugin", "service Builtin", "tool_name_prefixing"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected %q in the error, got %v", want, err)
+			}
+		}
+	})
+
+	t.Run("Prefixing", func(t *testing.T) {
+		srvs := newServices(false)
+		mlConfig := config.MoLingConfig{ToolNamePrefixing: true, ResultLimit: config.NewResultLimitConfig()}
+		// 两种形式的名字都能匹配工具的结果大小限制，带前缀的名字优先
+		mlConfig.ResultLimit.ToolResultLimits = map[string]int{"navigate": 5, "plugin.navigate": 100}
+		srv, err := newServer(mlConfig, srvs)
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		builtin := callTool(t, srv, "builtin.navigate").Content[0].(mcp.TextContent).Text
+		if !strings.HasPrefix(builtin, "Built") || !strings.Contains(builtin, "overflow") {
+			t.Errorf("Expected the truncated result of Builtin, got %s", builtin)
+		}
+		if text := callTool(t, srv, "plugin.navigate").Content[0].(mcp.TextContent).Text; text != strings.Repeat("Plugin", 10) {
+			t.Errorf("Expected the result of Plugin, got %s", text)
+		}
+		resp := srv.server.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"navigate"}}`))
+		if _, ok := resp.(mcp.JSONRPCError); !ok {
+			t.Errorf("Expected the unprefixed name to be unknown, got %#v", resp)
+		}
+
+		for _, name := range []string{"Builtin", "Plugin"} {
+			msg := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"prompts/get","params":{"name":%q}}`, strings.ToLower(name)+".usage")
+			resp := srv.server.HandleMessage(context.Background(), json.RawMessage(msg))
+			rpc, ok := resp.(mcp.JSONRPCResponse)
+			if !ok {
+				t.Fatalf("Unexpected response %#v", resp)
+			}
+			if desc := rpc.Result.(mcp.GetPromptResult).Description; desc != name {
+				t.Errorf("Expected the prompt of %s, got %s", name, desc)
+			}
+		}
+
+		// 通知名无法加前缀，两个服务的处理程序都被调用
+		srv.server.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","method":"notifications/custom"}`))
+		for _, ov := range srvs {
+			if n := ov.notified.Load(); n != 1 {
+				t.Errorf("Expected %s to handle the notification once, got %d", ov.name, n)
+			}
+		}
+
+		names := readStatus(t, srv).Names
+		if !names.Prefixing || len(names.Prompts) != 2 {
+			t.Errorf("Unexpected names %+v", names)
+		}
+		want := RegisteredName{Name: "plugin.navigate", Service: "Plugin", Original: "navigate"}
+		found := false
+		for _, rn := range names.Tools {
+			found = found || rn == want
+		}
+		if !found || len(names.Tools) != 3 {
+			t.Errorf("Expected %+v and the builtin tool in %+v", want, names.Tools)
+		}
+	})
+
+	t.Run("ResourceAlwaysFails", func(t *testing.T) {
+		_, err := newServer(config.MoLingConfig{ToolNamePrefixing: true}, newServices(true))
+		if !errors.Is(err, ErrNameCollision) || !strings.Contains(err.Error(), `resource "file:///shared"`) {
+			t.Errorf("Expected a resource collision, got %v", err)
+		}
+	})
+}
+
+func TestConfigSchemaResource(t *testing.T) {
+	_, ctx, err := comm.InitTestEnv()
+	if err != nil {
+		t.Fatalf("Failed to initialize test environment: %v", err)
+	}
+	fs, err := filesystem.NewFilesystemServer(ctx)
+	if err != nil {
+		t.Fatalf("Failed to create filesystem server: %v", err)
+	}
+	base, err := abstract.NewServiceBase(ctx, "Static")
+	if err != nil {
+		t.Fatalf("Failed to create service base: %v", err)
+	}
+	srv, err := NewMoLingServer(ctx, []abstract.Service{fs, &staticService{MLService: base, name: "Static"}}, config.MoLingConfig{BasePath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	read := func(service string) (mcp.JSONRPCMessage, string) {
+		uri := strings.Replace(ConfigSchemaURITemplate, "{service}", service, 1)
+		msg := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":%q}}`, uri)
+		resp := srv.server.HandleMessage(context.Background(), json.RawMessage(msg))
+		rpc, ok := resp.(mcp.JSONRPCResponse)
+		if !ok {
+			return resp, ""
+		}
+		return resp, rpc.Result.(mcp.ReadResourceResult).Contents[0].(mcp.TextResourceContents).Text
+	}
+
+	// 服务名不区分大小写
+	_, schema := read("filesystem")
+	if schema != fs.ConfigSchema() {
+		t.Errorf("Expected the schema of the filesystem service, got %q", schema)
+	}
+	for _, service := range []string{"Static", "Unknown"} {
+		if resp, _ := read(service); resp == nil {
+			t.Errorf("Expected a response for %s", service)
+		} else if _, ok := resp.(mcp.JSONRPCError); !ok {
+			t.Errorf("Expected an error for %s, got %#v", service, resp)
+		}
+	}
+}
+
+// notifySession is an MCP client session that keeps the notifications sent to it.
+type notifySession struct {
+	notifications chan mcp.JSONRPCNotification
+}
+
+func (ns *notifySession) Initialize()       {}
+func (ns *notifySession) Initialized() bool { return true }
+func (ns *notifySession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return ns.notifications
+}
+func (ns *notifySession) SessionID() string { return "notify" }
+
+func TestNewMoLingServerWithoutLogger(t *testing.T) {
+	_, ctx, err := comm.InitTestEnv()
+	if err != nil {
+		t.Fatalf("Failed to initialize test environment: %v", err)
+	}
+	ctx = context.WithValue(ctx, comm.MoLingLoggerKey, nil)
+	if _, err := NewMoLingServer(ctx, nil, config.MoLingConfig{BasePath: t.TempDir()}); err != nil {
+		t.Fatalf("Failed to create server without a logger: %v", err)
+	}
+}
+
+func TestStartupBanner(t *testing.T) {
+	_, ctx, err := comm.InitTestEnv()
+	if err != nil {
+		t.Fatalf("Failed to initialize test environment: %v", err)
+	}
+	base, err := abstract.NewServiceBase(ctx, "Builtin")
+	if err != nil {
+		t.Fatalf("Failed to create service base: %v", err)
+	}
+	ss := &staticService{MLService: base, name: "Builtin", text: "ok"}
+	basePath := t.TempDir()
+	srv, err := NewMoLingServer(ctx, []abstract.Service{ss}, config.MoLingConfig{BasePath: basePath, Version: "v1.2.3", ServerName: "MoLing"})
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	banner := srv.Banner()
+	if banner.Version != "v1.2.3" || banner.Transport != "stdio" || banner.BasePath != basePath ||
+		len(banner.Services) != 1 || banner.Services[0] != "Builtin" {
+		t.Errorf("Unexpected banner %+v", banner)
+	}
+
+	// 客户端确认初始化后收到启动信息的日志通知
+	session := &notifySession{notifications: make(chan mcp.JSONRPCNotification, 1)}
+	msg := json.RawMessage(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	srv.server.HandleMessage(srv.server.WithContext(context.Background(), session), msg)
+	select {
+	case n := <-session.notifications:
+		if n.Method != "notifications/message" {
+			t.Fatalf("Expected a logging notification, got %s", n.Method)
+		}
+		fields := n.Params.AdditionalFields
+		if fields["level"] != mcp.LoggingLevelInfo || fields["logger"] != "MoLing" {
+			t.Errorf("Unexpected notification params %v", fields)
+		}
+		if data, ok := fields["data"].(Banner); !ok || data.Version != "v1.2.3" {
+			t.Errorf("Expected the banner as data, got %#v", fields["data"])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the banner notification")
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	for _, mode := range []string{TransportSSE, TransportHTTP} {
+		t.Run(mode, func(t *testing.T) {
+			srv, _ := newMetricsServer(t, config.MoLingConfig{TransportMode: mode, ListenAddr: "127.0.0.1:0"})
+			serveErr := make(chan error, 1)
+			go func() { serveErr <- srv.Serve() }()
+			var addr net.Addr
+			for deadline := time.Now().Add(5 * time.Second); addr == nil; addr = srv.Addr() {
+				if time.Now().After(deadline) {
+					t.Fatal("Server did not start listening")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			if mode == TransportSSE {
+				// 保持一个 SSE 长连接，Shutdown 不应等到超时
+				resp, err := http.Get("http://" + addr.String() + "/sse")
+				if err != nil {
+					t.Fatalf("Failed to connect: %v", err)
+				}
+				defer resp.Body.Close()
+				if _, err := bufio.NewReader(resp.Body).ReadString('\n'); err != nil {
+					t.Fatalf("Failed to read the SSE stream: %v", err)
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			start := time.Now()
+			if err := srv.Shutdown(ctx); err != nil {
+				t.Fatalf("Shutdown failed: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("Shutdown took %s", elapsed)
+			}
+			select {
+			case err := <-serveErr:
+				if err != nil {
+					t.Errorf("Expected Serve to return nil after Shutdown, got %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Serve did not return after Shutdown")
+			}
+
+			// 端口已释放
+			ln, err := net.Listen("tcp", addr.String())
+			if err != nil {
+				t.Fatalf("Expected %s to be released: %v", addr, err)
+			}
+			ln.Close()
+		})
+	}
+
+	t.Run("Stdio", func(t *testing.T) {
+		srv, _ := newMetricsServer(t, config.MoLingConfig{})
+		if err := srv.Shutdown(context.Background()); err != nil {
+			t.Errorf("Expected Shutdown to be a no-op, got %v", err)
+		}
+		if srv.Addr() != nil {
+			t.Errorf("Expected no listen address, got %s", srv.Addr())
+		}
+	})
+
+	t.Run("BeforeServe", func(t *testing.T) {
+		srv, _ := newMetricsServer(t, config.MoLingConfig{TransportMode: TransportSSE, ListenAddr: "127.0.0.1:0"})
+		if err := srv.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Shutdown failed: %v", err)
+		}
+		if err := srv.Serve(); err != nil {
+			t.Errorf("Expected Serve to return nil after Shutdown, got %v", err)
+		}
+		if srv.Addr() != nil {
+			t.Errorf("Expected Serve not to listen after Shutdown, got %s", srv.Addr())
+		}
+	})
+}
@@ -0,0 +1,116 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ErrNameCollision is returned when two services register a tool, prompt, resource or notification
+// handler with the same name.
+var ErrNameCollision = errors.New("name collision")
+
+// 名字空间的种类
+const (
+	kindTool         = "tool"
+	kindPrompt       = "prompt"
+	kindResource     = "resource"
+	kindNotification = "notification handler"
+)
+
+// RegisteredName is a tool or prompt name registered by a service, with its name before prefixing.
+type RegisteredName struct {
+	Name     string `json:"name"`     // 注册到 MCP 服务器的名字，客户端调用时使用
+	Service  string `json:"service"`  // 所属服务
+	Original string `json:"original"` // 服务定义的原始名字
+}
+
+// NameMapping is the tool and prompt names of the status resource.
+type NameMapping struct {
+	Prefixing bool             `json:"prefixing"` // 是否以服务名作为前缀
+	Tools     []RegisteredName `json:"tools"`
+	Prompts   []RegisteredName `json:"prompts"`
+}
+
+// namespace 记录一类名字的所属服务，用于检测服务之间的命名冲突
+type namespace struct {
+	kind  string
+	names map[string]RegisteredName
+}
+
+func newNamespace(kind string) *namespace {
+	return &namespace{kind: kind, names: make(map[string]RegisteredName)}
+}
+
+// claim 登记 service 的名字 name，已被其他服务登记时返回 ErrNameCollision
+func (ns *namespace) claim(name, original, service string) error {
+	if owner, ok := ns.names[name]; ok {
+		return fmt.Errorf("%w: %s %q of service %s is already registered by service %s", ErrNameCollision, ns.kind, name, service, owner.Service)
+	}
+	ns.names[name] = RegisteredName{Name: name, Service: service, Original: original}
+	return nil
+}
+
+// owner 返回名字的所属服务
+func (ns *namespace) owner(name string) (string, bool) {
+	rn, ok := ns.names[name]
+	return rn.Service, ok
+}
+
+// list 返回按名字排序的已登记名字
+func (ns *namespace) list() []RegisteredName {
+	list := make([]RegisteredName, 0, len(ns.names))
+	for _, rn := range ns.names {
+		list = append(list, rn)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// prefixedName returns name prefixed with the lower-case service name, e.g. browser.browser_navigate.
+func prefixedName(service, name string) string {
+	return strings.ToLower(service) + "." + name
+}
+
+// toolAliases returns the other form of a tool name, so that per-tool settings can be keyed by the
+// name with or without the service prefix.
+func toolAliases(service, original, registered string) []string {
+	if registered == original {
+		return []string{prefixedName(service, original)}
+	}
+	return []string{original}
+}
+
+// notificationHandlers 同一通知的所有处理程序，按注册顺序依次调用
+type notificationHandlers []server.NotificationHandlerFunc
+
+func (nh notificationHandlers) handle(ctx context.Context, notification mcp.JSONRPCNotification) {
+	for _, handler := range nh {
+		handler(ctx, notification)
+	}
+}
@@ -0,0 +1,73 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// PromptResourceURIPrefix is the URI prefix of the resources that mirror the prompts of the services,
+// for clients that show resources but not prompts, e.g. moling://prompts/browser_prompt.
+const PromptResourceURIPrefix = "moling://prompts/"
+
+// promptResourceMIMEType 提示文本为 Markdown
+const promptResourceMIMEType = "text/markdown"
+
+// promptResourceURI 返回提示镜像资源的 URI，name 为注册到 MCP 服务器的提示名
+func promptResourceURI(name string) string {
+	return PromptResourceURIPrefix + name
+}
+
+// newPromptResource 返回提示的镜像资源
+func newPromptResource(prompt mcp.Prompt) mcp.Resource {
+	return mcp.NewResource(promptResourceURI(prompt.Name), prompt.Name,
+		mcp.WithResourceDescription(prompt.Description),
+		mcp.WithMIMEType(promptResourceMIMEType),
+	)
+}
+
+// promptResourceHandler 每次读取时调用提示的处理函数，资源内容与 prompt_file 等配置及其重新加载保持一致。
+// name 为服务中的原始提示名
+func promptResourceHandler(uri, name string, handler server.PromptHandlerFunc) server.ResourceHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		promptRequest := mcp.GetPromptRequest{}
+		promptRequest.Params.Name = name
+		result, err := handler(ctx, promptRequest)
+		if err != nil {
+			return nil, err
+		}
+		texts := make([]string, 0, len(result.Messages))
+		for _, message := range result.Messages {
+			if text, ok := message.Content.(mcp.TextContent); ok {
+				texts = append(texts, text.Text)
+			}
+		}
+		return []mcp.ResourceContents{mcp.TextResourceContents{
+			URI:      uri,
+			MIMEType: promptResourceMIMEType,
+			Text:     strings.Join(texts, "\n\n"),
+		}}, nil
+	}
+}
@@ -0,0 +1,151 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/config"
+	"github.com/gojue/moling/pkg/services/abstract"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// promptService is a service with one prompt, its text can be changed like with a prompt_file.
+type promptService struct {
+	abstract.MLService
+	prompt string
+}
+
+func (ps *promptService) RegisterTools() error {
+	ps.AddPrompt(abstract.PromptEntry{
+		PromptVar: mcp.NewPrompt("guide_prompt", mcp.WithPromptDescription("How to use the guide service")),
+		HandlerFunc: func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			if request.Params.Name != "guide_prompt" {
+				return nil, fmt.Errorf("unexpected prompt %s", request.Params.Name)
+			}
+			return mcp.NewGetPromptResult("", []mcp.PromptMessage{mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(ps.prompt))}), nil
+		},
+	})
+	return nil
+}
+
+func (ps *promptService) Name() comm.MoLingServerType {
+	return "Guide"
+}
+
+func (ps *promptService) Close() error {
+	return nil
+}
+
+func TestPromptResources(t *testing.T) {
+	_, ctx, err := comm.InitTestEnv()
+	if err != nil {
+		t.Fatalf("Failed to initialize test environment: %v", err)
+	}
+	newServer := func(t *testing.T, cfg config.MoLingConfig) (*MoLingServer, *promptService) {
+		t.Helper()
+		base, err := abstract.NewServiceBase(ctx, "Guide")
+		if err != nil {
+			t.Fatalf("Failed to create service base: %v", err)
+		}
+		ps := &promptService{MLService: base, prompt: "# Guide\nUse the tools."}
+		if err := ps.RegisterTools(); err != nil {
+			t.Fatal(err)
+		}
+		cfg.BasePath = t.TempDir()
+		srv, err := NewMoLingServer(ctx, []abstract.Service{ps}, cfg)
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		return srv, ps
+	}
+	// listResources 返回 resources/list 中 URI 以 moling://prompts/ 开头的资源
+	listResources := func(t *testing.T, srv *MoLingServer) map[string]mcp.Resource {
+		t.Helper()
+		resp := srv.server.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"resources/list"}`))
+		rpc, ok := resp.(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatalf("Unexpected response %#v", resp)
+		}
+		resources := make(map[string]mcp.Resource)
+		for _, r := range rpc.Result.(mcp.ListResourcesResult).Resources {
+			if strings.HasPrefix(r.URI, PromptResourceURIPrefix) {
+				resources[r.URI] = r
+			}
+		}
+		return resources
+	}
+	readResource := func(t *testing.T, srv *MoLingServer, uri string) mcp.TextResourceContents {
+		t.Helper()
+		msg := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":%q}}`, uri)
+		resp := srv.server.HandleMessage(context.Background(), json.RawMessage(msg))
+		rpc, ok := resp.(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatalf("Unexpected response %#v", resp)
+		}
+		return rpc.Result.(mcp.ReadResourceResult).Contents[0].(mcp.TextResourceContents)
+	}
+
+	t.Run("Mirrored", func(t *testing.T) {
+		srv, ps := newServer(t, config.MoLingConfig{})
+		uri := "moling://prompts/guide_prompt"
+		resource, ok := listResources(t, srv)[uri]
+		if !ok {
+			t.Fatalf("Expected the %s resource, got %v", uri, listResources(t, srv))
+		}
+		if resource.Name != "guide_prompt" || resource.MIMEType != "text/markdown" || resource.Description != "How to use the guide service" {
+			t.Errorf("Unexpected resource %+v", resource)
+		}
+		contents := readResource(t, srv, uri)
+		if contents.URI != uri || contents.MIMEType != "text/markdown" || contents.Text != "# Guide\nUse the tools." {
+			t.Errorf("Unexpected contents %+v", contents)
+		}
+
+		// 提示文本变化(如 prompt_file)后资源内容同步变化
+		ps.prompt = "# Custom guide"
+		if text := readResource(t, srv, uri).Text; text != "# Custom guide" {
+			t.Errorf("Expected the changed prompt, got %q", text)
+		}
+	})
+
+	t.Run("Prefixed", func(t *testing.T) {
+		srv, _ := newServer(t, config.MoLingConfig{ToolNamePrefixing: true})
+		uri := "moling://prompts/guide.guide_prompt"
+		if _, ok := listResources(t, srv)[uri]; !ok {
+			t.Fatalf("Expected the %s resource, got %v", uri, listResources(t, srv))
+		}
+		if text := readResource(t, srv, uri).Text; text != "# Guide\nUse the tools." {
+			t.Errorf("Expected the prompt, got %q", text)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		srv, _ := newServer(t, config.MoLingConfig{NoPromptResources: true})
+		if resources := listResources(t, srv); len(resources) != 0 {
+			t.Errorf("Expected no prompt resources, got %v", resources)
+		}
+	})
+}
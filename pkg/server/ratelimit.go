@@ -0,0 +1,306 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/config"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog"
+)
+
+// ErrCodeRateLimited is the code of the structured tool error returned when a call is throttled.
+const ErrCodeRateLimited = "RATE_LIMITED"
+
+// RateLimitStats is a snapshot of the rate limiter counters.
+type RateLimitStats struct {
+	InFlight  map[string]int64 `json:"in_flight"` // 正在执行的工具调用数，按服务统计
+	Queued    int64            `json:"queued"`    // 正在排队的工具调用数
+	Throttled map[string]int64 `json:"throttled"` // 被限流（拒绝或排队）的次数，按服务统计
+}
+
+// tokenBucket is a token bucket refilled at RatePerMinute tokens per minute with a burst of
+// the same size.
+type tokenBucket struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	rate     float64 // tokens per second
+	last     time.Time
+	now      func() time.Time
+}
+
+func newTokenBucket(perMinute int, now func() time.Time) *tokenBucket {
+	return &tokenBucket{
+		capacity: float64(perMinute),
+		tokens:   float64(perMinute),
+		rate:     float64(perMinute) / 60,
+		last:     now(),
+		now:      now,
+	}
+}
+
+// take takes a token, or returns how long to wait until the next token is available.
+func (b *tokenBucket) take() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// refund gives back a token taken by a call that was rejected afterwards.
+func (b *tokenBucket) refund() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = math.Min(b.capacity, b.tokens+1)
+}
+
+// limiter enforces one RateLimit, a nil field means no limit.
+type limiter struct {
+	sem    chan struct{}
+	bucket *tokenBucket
+}
+
+func newLimiter(rl config.RateLimit, now func() time.Time) *limiter {
+	if rl.Unlimited() {
+		return nil
+	}
+	l := &limiter{}
+	if rl.MaxConcurrent > 0 {
+		l.sem = make(chan struct{}, rl.MaxConcurrent)
+	}
+	if rl.RatePerMinute > 0 {
+		l.bucket = newTokenBucket(rl.RatePerMinute, now)
+	}
+	return l
+}
+
+// RateLimiter limits the concurrency and rate of tool calls globally and per service.
+type RateLimiter struct {
+	cfg       config.RateLimitConfig
+	global    *limiter
+	services  map[comm.MoLingServerType]*limiter
+	logger    zerolog.Logger
+	now       func() time.Time
+	mu        sync.Mutex
+	inFlight  map[string]*int64
+	throttled map[string]*int64
+	queued    int64
+}
+
+// NewRateLimiter creates a RateLimiter from the configuration.
+func NewRateLimiter(cfg config.RateLimitConfig, logger zerolog.Logger) *RateLimiter {
+	rl := &RateLimiter{
+		cfg:       cfg,
+		services:  make(map[comm.MoLingServerType]*limiter),
+		logger:    logger,
+		now:       time.Now,
+		inFlight:  make(map[string]*int64),
+		throttled: make(map[string]*int64),
+	}
+	rl.global = newLimiter(cfg.RateLimit, rl.now)
+	for name, l := range cfg.Services {
+		rl.services[comm.MoLingServerType(name)] = newLimiter(l, rl.now)
+	}
+	return rl
+}
+
+// Wrap returns handler wrapped with the global limits and the limits of the service.
+func (rl *RateLimiter) Wrap(srvName comm.MoLingServerType, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	limiters := make([]*limiter, 0, 2)
+	for _, l := range []*limiter{rl.global, rl.services[srvName]} {
+		if l != nil {
+			limiters = append(limiters, l)
+		}
+	}
+	inFlight := rl.counter(rl.inFlight, string(srvName))
+	throttled := rl.counter(rl.throttled, string(srvName))
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		release, retryAfter, err := rl.acquire(ctx, limiters, throttled)
+		if err != nil {
+			rl.logger.Warn().Str("service", string(srvName)).Str("tool", request.Params.Name).Err(err).Msg("tool call rate limited")
+			return rateLimitedError(err, retryAfter), nil
+		}
+		defer release()
+		atomic.AddInt64(inFlight, 1)
+		defer atomic.AddInt64(inFlight, -1)
+		return handler(ctx, request)
+	}
+}
+
+// acquire takes a token and a concurrency slot from every limiter. When queue_on_limit is set
+// it waits up to QueueTimeout, otherwise it fails at the first exceeded limit. A rejected call
+// gives back the tokens and slots it already took.
+func (rl *RateLimiter) acquire(ctx context.Context, limiters []*limiter, throttled *int64) (func(), time.Duration, error) {
+	var acquired, taken []*limiter
+	release := func() {
+		for _, l := range acquired {
+			<-l.sem
+		}
+	}
+	// 调用被拒绝时归还已取走的令牌，避免被拒绝的调用消耗配额
+	reject := func() {
+		release()
+		for _, l := range taken {
+			l.bucket.refund()
+		}
+	}
+	counted := false
+	throttle := func() {
+		if !counted {
+			counted = true
+			atomic.AddInt64(throttled, 1)
+		}
+	}
+
+	var cancel context.CancelFunc = func() {}
+	if rl.cfg.QueueOnLimit {
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(rl.cfg.QueueTimeout)*time.Second)
+	}
+	defer cancel()
+	queued := false
+	defer func() {
+		if queued {
+			atomic.AddInt64(&rl.queued, -1)
+		}
+	}()
+	enqueue := func() error {
+		if queued {
+			return nil
+		}
+		if atomic.AddInt64(&rl.queued, 1) > int64(rl.cfg.QueueSize) {
+			atomic.AddInt64(&rl.queued, -1)
+			return fmt.Errorf("rate limited, queue is full")
+		}
+		queued = true
+		return nil
+	}
+
+	for _, l := range limiters {
+		if l.bucket != nil {
+			for {
+				ok, wait := l.bucket.take()
+				if ok {
+					taken = append(taken, l)
+					break
+				}
+				throttle()
+				if !rl.cfg.QueueOnLimit {
+					reject()
+					return nil, wait, fmt.Errorf("rate limited, retry in %ds", retrySeconds(wait))
+				}
+				if err := enqueue(); err != nil {
+					reject()
+					return nil, wait, err
+				}
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					reject()
+					return nil, wait, fmt.Errorf("rate limited, timed out in queue, retry in %ds", retrySeconds(wait))
+				}
+			}
+		}
+		if l.sem != nil {
+			select {
+			case l.sem <- struct{}{}:
+				acquired = append(acquired, l)
+				continue
+			default:
+			}
+			throttle()
+			if !rl.cfg.QueueOnLimit {
+				reject()
+				return nil, time.Second, fmt.Errorf("rate limited, too many concurrent tool calls, retry in 1s")
+			}
+			if err := enqueue(); err != nil {
+				reject()
+				return nil, time.Second, err
+			}
+			select {
+			case l.sem <- struct{}{}:
+				acquired = append(acquired, l)
+			case <-ctx.Done():
+				reject()
+				return nil, time.Second, fmt.Errorf("rate limited, timed out waiting for a free slot, retry in 1s")
+			}
+		}
+	}
+	return release, 0, nil
+}
+
+// Stats returns a snapshot of the in-flight and throttle counters.
+func (rl *RateLimiter) Stats() RateLimitStats {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	stats := RateLimitStats{
+		InFlight:  make(map[string]int64, len(rl.inFlight)),
+		Queued:    atomic.LoadInt64(&rl.queued),
+		Throttled: make(map[string]int64, len(rl.throttled)),
+	}
+	for name, c := range rl.inFlight {
+		stats.InFlight[name] = atomic.LoadInt64(c)
+	}
+	for name, c := range rl.throttled {
+		stats.Throttled[name] = atomic.LoadInt64(c)
+	}
+	return stats
+}
+
+func (rl *RateLimiter) counter(counters map[string]*int64, name string) *int64 {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if c, ok := counters[name]; ok {
+		return c
+	}
+	c := new(int64)
+	counters[name] = c
+	return c
+}
+
+// rateLimitedError builds the structured tool error of a throttled call.
+func rateLimitedError(err error, retryAfter time.Duration) *mcp.CallToolResult {
+	data, _ := json.Marshal(map[string]interface{}{
+		"code":        ErrCodeRateLimited,
+		"message":     err.Error(),
+		"retry_after": retrySeconds(retryAfter),
+	})
+	return mcp.NewToolResultError(string(data))
+}
+
+func retrySeconds(d time.Duration) int {
+	return int(math.Max(1, math.Ceil(d.Seconds())))
+}
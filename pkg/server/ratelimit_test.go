@@ -0,0 +1,246 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/config"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog"
+)
+
+// concurrencyHandler returns a fake handler that records the maximum number of concurrent calls.
+func concurrencyHandler(current, peak *int64, hold time.Duration) func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		n := atomic.AddInt64(current, 1)
+		for {
+			p := atomic.LoadInt64(peak)
+			if n <= p || atomic.CompareAndSwapInt64(peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(hold)
+		atomic.AddInt64(current, -1)
+		return mcp.NewToolResultText("ok"), nil
+	}
+}
+
+func TestRateLimiterConcurrency(t *testing.T) {
+	t.Run("QueueRespectsSemaphoreCap", func(t *testing.T) {
+		cfg := config.NewRateLimitConfig()
+		cfg.QueueOnLimit = true
+		cfg.Services = map[string]config.RateLimit{"Command": {MaxConcurrent: 3}}
+		rl := NewRateLimiter(cfg, zerolog.Nop())
+
+		var current, peak int64
+		handler := rl.Wrap(comm.MoLingServerType("Command"), concurrencyHandler(&current, &peak, 10*time.Millisecond))
+
+		var wg sync.WaitGroup
+		var failed int64
+		for i := 0; i < 30; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				result, _ := handler(context.Background(), mcp.CallToolRequest{})
+				if result.IsError {
+					atomic.AddInt64(&failed, 1)
+				}
+			}()
+		}
+		wg.Wait()
+		if peak > 3 {
+			t.Errorf("Expected at most 3 concurrent calls, got %d", peak)
+		}
+		if failed != 0 {
+			t.Errorf("Expected all queued calls to succeed, %d failed", failed)
+		}
+		stats := rl.Stats()
+		if stats.InFlight["Command"] != 0 || stats.Queued != 0 {
+			t.Errorf("Expected no in-flight or queued calls, got %+v", stats)
+		}
+		if stats.Throttled["Command"] == 0 {
+			t.Errorf("Expected throttle events, got %+v", stats)
+		}
+	})
+
+	t.Run("RejectWhenNotQueued", func(t *testing.T) {
+		cfg := config.NewRateLimitConfig()
+		cfg.MaxConcurrent = 1
+		rl := NewRateLimiter(cfg, zerolog.Nop())
+
+		block := make(chan struct{})
+		started := make(chan struct{})
+		handler := rl.Wrap(comm.MoLingServerType("Browser"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			close(started)
+			<-block
+			return mcp.NewToolResultText("ok"), nil
+		})
+		done := make(chan struct{})
+		go func() {
+			_, _ = handler(context.Background(), mcp.CallToolRequest{})
+			close(done)
+		}()
+		<-started
+		if n := rl.Stats().InFlight["Browser"]; n != 1 {
+			t.Errorf("Expected 1 in-flight call, got %d", n)
+		}
+
+		result, _ := handler(context.Background(), mcp.CallToolRequest{})
+		assertRateLimited(t, result)
+		close(block)
+		<-done
+	})
+
+	t.Run("RejectedCallKeepsToken", func(t *testing.T) {
+		cfg := config.NewRateLimitConfig()
+		cfg.RatePerMinute = 2
+		cfg.Services = map[string]config.RateLimit{"Browser": {MaxConcurrent: 1}}
+		rl := NewRateLimiter(cfg, zerolog.Nop())
+
+		block := make(chan struct{})
+		started := make(chan struct{}, 1)
+		handler := rl.Wrap(comm.MoLingServerType("Browser"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			started <- struct{}{}
+			<-block
+			return mcp.NewToolResultText("ok"), nil
+		})
+		done := make(chan struct{})
+		go func() {
+			_, _ = handler(context.Background(), mcp.CallToolRequest{})
+			close(done)
+		}()
+		<-started
+
+		// 被并发限制拒绝的调用不消耗全局令牌
+		for i := 0; i < 3; i++ {
+			result, _ := handler(context.Background(), mcp.CallToolRequest{})
+			assertRateLimited(t, result)
+		}
+		close(block)
+		<-done
+		if result, _ := handler(context.Background(), mcp.CallToolRequest{}); result.IsError {
+			t.Errorf("Expected the second token to be left, got %v", result.Content)
+		}
+	})
+
+	t.Run("QueueTimeout", func(t *testing.T) {
+		cfg := config.NewRateLimitConfig()
+		cfg.MaxConcurrent = 1
+		cfg.QueueOnLimit = true
+		cfg.QueueTimeout = 1
+		rl := NewRateLimiter(cfg, zerolog.Nop())
+
+		block := make(chan struct{})
+		defer close(block)
+		started := make(chan struct{}, 1)
+		handler := rl.Wrap(comm.MoLingServerType("Browser"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			started <- struct{}{}
+			<-block
+			return mcp.NewToolResultText("ok"), nil
+		})
+		go func() { _, _ = handler(context.Background(), mcp.CallToolRequest{}) }()
+		<-started
+
+		result, _ := handler(context.Background(), mcp.CallToolRequest{})
+		assertRateLimited(t, result)
+	})
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	clock := func() time.Time { return now }
+	b := newTokenBucket(60, clock)
+
+	// 初始可突发 60 次
+	for i := 0; i < 60; i++ {
+		if ok, _ := b.take(); !ok {
+			t.Fatalf("Expected token %d to be available", i)
+		}
+	}
+	ok, wait := b.take()
+	if ok || wait != time.Second {
+		t.Fatalf("Expected empty bucket with 1s wait, got %v %s", ok, wait)
+	}
+
+	// 每秒补充 1 个令牌
+	now = now.Add(1500 * time.Millisecond)
+	if ok, _ := b.take(); !ok {
+		t.Fatalf("Expected a token after refill")
+	}
+	if ok, wait := b.take(); ok || wait != 500*time.Millisecond {
+		t.Fatalf("Expected 500ms wait, got %v %s", ok, wait)
+	}
+
+	// 补充不超过容量
+	now = now.Add(10 * time.Minute)
+	for i := 0; i < 60; i++ {
+		if ok, _ := b.take(); !ok {
+			t.Fatalf("Expected token %d to be available after full refill", i)
+		}
+	}
+	if ok, _ := b.take(); ok {
+		t.Fatalf("Expected bucket capacity to be capped at 60")
+	}
+}
+
+func TestRateLimiterRatePerMinute(t *testing.T) {
+	cfg := config.NewRateLimitConfig()
+	cfg.RatePerMinute = 2
+	rl := NewRateLimiter(cfg, zerolog.Nop())
+	handler := rl.Wrap(comm.MoLingServerType("Command"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+	for i := 0; i < 2; i++ {
+		if result, _ := handler(context.Background(), mcp.CallToolRequest{}); result.IsError {
+			t.Fatalf("Expected call %d to succeed", i)
+		}
+	}
+	result, _ := handler(context.Background(), mcp.CallToolRequest{})
+	payload := assertRateLimited(t, result)
+	if retry, _ := payload["retry_after"].(float64); retry < 29 || retry > 30 {
+		t.Errorf("Expected retry after about 30s, got %v", payload["retry_after"])
+	}
+	if n := rl.Stats().Throttled["Command"]; n != 1 {
+		t.Errorf("Expected 1 throttle event, got %d", n)
+	}
+}
+
+func assertRateLimited(t *testing.T, result *mcp.CallToolResult) map[string]interface{} {
+	t.Helper()
+	if !result.IsError {
+		t.Fatalf("Expected rate limited error, got %v", result.Content)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &payload); err != nil {
+		t.Fatalf("Expected structured error, got %v", result.Content)
+	}
+	if payload["code"] != ErrCodeRateLimited {
+		t.Errorf("Expected code %s, got %v", ErrCodeRateLimited, payload["code"])
+	}
+	return payload
+}
@@ -0,0 +1,114 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/gojue/moling/pkg/services/abstract"
+)
+
+// ReadyzPath is the HTTP path of the readiness check.
+const ReadyzPath = "/readyz"
+
+// Load states of a service.
+const (
+	ServicePending = "pending" // 尚未加载
+	ServiceLoaded  = "loaded"  // 已加载到 MCP 服务器
+	ServiceFailed  = "failed"  // 加载失败
+)
+
+// ServiceState is the load state of a service.
+type ServiceState struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // pending, loaded or failed
+	Error  string `json:"error,omitempty"`
+}
+
+// Readiness is the body of /readyz.
+type Readiness struct {
+	Ready    bool           `json:"ready"`
+	Version  string         `json:"version"`
+	Services []ServiceState `json:"services"`
+}
+
+// serviceStates tracks the load state of the services in load order.
+type serviceStates struct {
+	lock   sync.Mutex
+	states []ServiceState
+}
+
+// newServiceStates returns the states of srvs, all pending.
+func newServiceStates(srvs []abstract.Service) *serviceStates {
+	ss := &serviceStates{states: make([]ServiceState, 0, len(srvs))}
+	for _, srv := range srvs {
+		ss.states = append(ss.states, ServiceState{Name: string(srv.Name()), Status: ServicePending})
+	}
+	return ss
+}
+
+// set records the load state of the named service.
+func (ss *serviceStates) set(name, status string, err error) {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	for i := range ss.states {
+		if ss.states[i].Name != name {
+			continue
+		}
+		ss.states[i].Status = status
+		ss.states[i].Error = ""
+		if err != nil {
+			ss.states[i].Error = err.Error()
+		}
+		return
+	}
+}
+
+// list returns a copy of the states and whether all services are loaded.
+func (ss *serviceStates) list() ([]ServiceState, bool) {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	ready := true
+	for _, state := range ss.states {
+		if state.Status != ServiceLoaded {
+			ready = false
+		}
+	}
+	return append([]ServiceState(nil), ss.states...), ready
+}
+
+// Readiness reports whether all services are loaded, with the load state of each service.
+func (m *MoLingServer) Readiness() Readiness {
+	states, ready := m.loads.list()
+	return Readiness{Ready: ready, Version: m.mlConfig.Version, Services: states}
+}
+
+// handleReadyz answers 200 once all services are loaded, 503 otherwise, with the Readiness as JSON.
+func (m *MoLingServer) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	readiness := m.Readiness()
+	w.Header().Set("Content-Type", "application/json")
+	if !readiness.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(readiness)
+}
@@ -0,0 +1,88 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gojue/moling/pkg/config"
+)
+
+func getReadyz(t *testing.T, url string) (int, Readiness) {
+	t.Helper()
+	resp, err := http.Get(url + ReadyzPath)
+	if err != nil {
+		t.Fatalf("Failed to get readyz: %v", err)
+	}
+	defer resp.Body.Close()
+	var readiness Readiness
+	if err := json.NewDecoder(resp.Body).Decode(&readiness); err != nil {
+		t.Fatalf("Failed to decode readyz: %v", err)
+	}
+	return resp.StatusCode, readiness
+}
+
+func TestReadyz(t *testing.T) {
+	srv, ms := newMetricsServer(t, config.MoLingConfig{Version: "v1.2.3"})
+	name := string(ms.Name())
+	ts := httptest.NewServer(srv.httpMux(http.NotFoundHandler()))
+	defer ts.Close()
+
+	// 模拟服务尚未加载
+	srv.loads = newServiceStates(srv.services)
+	code, readiness := getReadyz(t, ts.URL)
+	if code != http.StatusServiceUnavailable || readiness.Ready {
+		t.Errorf("Expected 503 before the services are loaded, got %d %+v", code, readiness)
+	}
+	if len(readiness.Services) != 1 || readiness.Services[0].Name != name || readiness.Services[0].Status != ServicePending {
+		t.Errorf("Unexpected services %+v", readiness.Services)
+	}
+	if readiness.Version != "v1.2.3" {
+		t.Errorf("Expected version v1.2.3, got %q", readiness.Version)
+	}
+	// 存活检查不依赖服务加载
+	if got := scrape(t, ts.URL+HealthzPath); got != "ok\n" {
+		t.Errorf("Unexpected healthz %q", got)
+	}
+
+	srv.loads.set(name, ServiceFailed, errors.New("boom"))
+	code, readiness = getReadyz(t, ts.URL)
+	if code != http.StatusServiceUnavailable || readiness.Services[0].Status != ServiceFailed || readiness.Services[0].Error != "boom" {
+		t.Errorf("Expected 503 with the failed service, got %d %+v", code, readiness)
+	}
+
+	srv.loads.set(name, ServiceLoaded, nil)
+	code, readiness = getReadyz(t, ts.URL)
+	if code != http.StatusOK || !readiness.Ready || readiness.Services[0].Status != ServiceLoaded || readiness.Services[0].Error != "" {
+		t.Errorf("Expected 200 after the services are loaded, got %d %+v", code, readiness)
+	}
+
+	t.Run("LoadedByNewMoLingServer", func(t *testing.T) {
+		srv, _ := newMetricsServer(t, config.MoLingConfig{})
+		if readiness := srv.Readiness(); !readiness.Ready || len(readiness.Services) != 1 {
+			t.Errorf("Expected all services loaded, got %+v", readiness)
+		}
+	})
+}
@@ -0,0 +1,189 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/gojue/moling/pkg/services/abstract"
+	"github.com/rs/zerolog"
+)
+
+// globalSection is the section of config.json with the MoLingConfig, it is read at startup only.
+const globalSection = "MoLingConfig"
+
+// ServiceReload is the result of a config reload for one section of config.json.
+type ServiceReload struct {
+	Service         string   // 配置节名称，即服务名或 MoLingConfig
+	Applied         []string // 已应用到运行中服务的变更字段
+	RestartRequired []string // 重启后才能生效的变更字段，包括被删除的字段
+	Err             error    // LoadConfig 失败时的错误，此时服务保持原配置
+}
+
+// ConfigReloader applies changes of config.json to the running services.
+type ConfigReloader struct {
+	lock     sync.Mutex
+	services []abstract.Service
+	running  map[string]map[string]interface{} // 各配置节正在生效的配置
+	logger   zerolog.Logger
+}
+
+// NewConfigReloader returns a ConfigReloader for srvs, configJson is the configuration they were
+// loaded with.
+func NewConfigReloader(srvs []abstract.Service, configJson map[string]interface{}, logger zerolog.Logger) *ConfigReloader {
+	cr := &ConfigReloader{services: srvs, running: make(map[string]map[string]interface{}), logger: logger}
+	cr.running[globalSection] = section(configJson, globalSection)
+	for _, srv := range srvs {
+		cr.running[string(srv.Name())] = section(configJson, string(srv.Name()))
+	}
+	return cr
+}
+
+// Reload diffs configJson against the running configuration and calls LoadConfig of every
+// service whose section changed, with the new section without its restart-only fields. Changes
+// of restart-only fields, removed fields, whose defaults only apply at startup, and changes of the
+// MoLingConfig are reported as requiring a restart. LoadConfig validates the new section before
+// applying it, when it fails the service keeps its running config. Only the names of the changed
+// fields are logged, never their values.
+func (cr *ConfigReloader) Reload(configJson map[string]interface{}) []ServiceReload {
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+
+	var results []ServiceReload
+	if changed, removed := diffSection(cr.running[globalSection], section(configJson, globalSection)); len(changed)+len(removed) > 0 {
+		results = append(results, ServiceReload{Service: globalSection, RestartRequired: sortedUnion(changed, removed)})
+	}
+	for _, srv := range cr.services {
+		name := string(srv.Name())
+		if result, ok := cr.reloadService(srv, section(configJson, name)); ok {
+			results = append(results, result)
+		}
+	}
+	for _, result := range results {
+		cr.logResult(result)
+	}
+	return results
+}
+
+// reloadService applies next to srv, it returns false when the section didn't change.
+func (cr *ConfigReloader) reloadService(srv abstract.Service, next map[string]interface{}) (ServiceReload, bool) {
+	name := string(srv.Name())
+	current := cr.running[name]
+	changed, removed := diffSection(current, next)
+	if len(changed)+len(removed) == 0 {
+		return ServiceReload{}, false
+	}
+
+	restartOnly := make(map[string]bool)
+	if rc, ok := srv.(abstract.RestartOnlyConfig); ok {
+		for _, field := range rc.RestartOnlyFields() {
+			restartOnly[field] = true
+		}
+	}
+	result := ServiceReload{Service: name}
+	var live []string
+	for _, field := range changed {
+		if restartOnly[field] {
+			result.RestartRequired = append(result.RestartRequired, field)
+		} else {
+			live = append(live, field)
+		}
+	}
+	result.RestartRequired = sortedUnion(result.RestartRequired, removed)
+	if len(live) == 0 {
+		return result, true
+	}
+
+	if err := srv.LoadConfig(withoutFields(next, restartOnly)); err != nil {
+		result.Err = err
+		return result, true
+	}
+	result.Applied = live
+	updated := make(map[string]interface{}, len(current)+len(live))
+	for k, v := range current {
+		updated[k] = v
+	}
+	for _, field := range live {
+		updated[field] = next[field]
+	}
+	cr.running[name] = updated
+	return result, true
+}
+
+// logResult logs the changed fields of a reload result.
+func (cr *ConfigReloader) logResult(result ServiceReload) {
+	if result.Err != nil {
+		cr.logger.Error().Err(result.Err).Str("service", result.Service).Msg("failed to reload config, the running config is kept")
+	}
+	if len(result.Applied) > 0 {
+		cr.logger.Info().Str("service", result.Service).Strs("fields", result.Applied).Msg("config reloaded")
+	}
+	if len(result.RestartRequired) > 0 {
+		cr.logger.Warn().Str("service", result.Service).Strs("fields", result.RestartRequired).Msg("config changes require a restart")
+	}
+}
+
+// section returns the named section of configJson, nil when it is missing.
+func section(configJson map[string]interface{}, name string) map[string]interface{} {
+	sec, _ := configJson[name].(map[string]interface{})
+	return sec
+}
+
+// diffSection returns the sorted fields of next that differ from current, and the fields of
+// current missing in next.
+func diffSection(current, next map[string]interface{}) (changed, removed []string) {
+	for k, v := range next {
+		if old, ok := current[k]; !ok || !reflect.DeepEqual(old, v) {
+			changed = append(changed, k)
+		}
+	}
+	for k := range current {
+		if _, ok := next[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	sort.Strings(changed)
+	sort.Strings(removed)
+	return changed, removed
+}
+
+// withoutFields returns a copy of sec without the fields.
+func withoutFields(sec map[string]interface{}, fields map[string]bool) map[string]interface{} {
+	out := make(map[string]interface{}, len(sec))
+	for k, v := range sec {
+		if !fields[k] {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// sortedUnion returns the sorted union of a and b.
+func sortedUnion(a, b []string) []string {
+	if len(a)+len(b) == 0 {
+		return nil
+	}
+	out := append(append([]string(nil), a...), b...)
+	sort.Strings(out)
+	return out
+}
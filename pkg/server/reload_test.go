@@ -0,0 +1,162 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/services/abstract"
+	"github.com/gojue/moling/pkg/utils"
+	"github.com/rs/zerolog"
+)
+
+type reloadConfig struct {
+	Timeout  int    `json:"timeout"`
+	Prefix   string `json:"prefix"`
+	DataPath string `json:"data_path"`
+}
+
+// reloadService has a live timeout and prefix and a restart-only data_path.
+type reloadService struct {
+	abstract.MLService
+	name   comm.MoLingServerType
+	config reloadConfig
+	loads  int
+}
+
+func (rs *reloadService) Name() comm.MoLingServerType { return rs.name }
+
+func (rs *reloadService) Close() error { return nil }
+
+func (rs *reloadService) RegisterTools() error { return nil }
+
+func (rs *reloadService) LoadConfig(jsonData map[string]interface{}) error {
+	rs.loads++
+	next := rs.config
+	if err := utils.MergeJSONToStruct(&next, jsonData); err != nil {
+		return err
+	}
+	if next.Timeout <= 0 {
+		return errors.New("timeout must be greater than 0")
+	}
+	rs.config = next
+	return nil
+}
+
+func (rs *reloadService) RestartOnlyFields() []string { return []string{"data_path"} }
+
+func TestConfigReloader(t *testing.T) {
+	svc := &reloadService{name: "Fake", config: reloadConfig{Timeout: 10, Prefix: "a", DataPath: "/data"}}
+	other := &reloadService{name: "Other", config: reloadConfig{Timeout: 5}}
+	initial := map[string]interface{}{
+		"MoLingConfig": map[string]interface{}{"listen_addr": "127.0.0.1:6789"},
+		"Fake":         map[string]interface{}{"timeout": float64(10), "prefix": "a", "data_path": "/data"},
+		"Other":        map[string]interface{}{"timeout": float64(5)},
+		"Unknown":      map[string]interface{}{"x": true},
+	}
+	cr := NewConfigReloader([]abstract.Service{svc, other}, initial, zerolog.Nop())
+
+	t.Run("Unchanged", func(t *testing.T) {
+		next := map[string]interface{}{
+			"MoLingConfig": map[string]interface{}{"listen_addr": "127.0.0.1:6789"},
+			"Fake":         map[string]interface{}{"timeout": float64(10), "prefix": "a", "data_path": "/data"},
+			"Other":        map[string]interface{}{"timeout": float64(5)},
+			// 未加载的服务被忽略
+			"Unknown": map[string]interface{}{"x": false},
+		}
+		if results := cr.Reload(next); len(results) != 0 {
+			t.Errorf("Expected no changes, got %+v", results)
+		}
+		if svc.loads != 0 || other.loads != 0 {
+			t.Errorf("Expected no LoadConfig calls, got %d and %d", svc.loads, other.loads)
+		}
+	})
+
+	t.Run("LiveAndRestartOnly", func(t *testing.T) {
+		next := map[string]interface{}{
+			"MoLingConfig": map[string]interface{}{"listen_addr": "127.0.0.1:9999"},
+			"Fake":         map[string]interface{}{"timeout": float64(20), "prefix": "a", "data_path": "/new"},
+			"Other":        map[string]interface{}{"timeout": float64(5)},
+		}
+		results := cr.Reload(next)
+		want := []ServiceReload{
+			{Service: "MoLingConfig", RestartRequired: []string{"listen_addr"}},
+			{Service: "Fake", Applied: []string{"timeout"}, RestartRequired: []string{"data_path"}},
+		}
+		if !reflect.DeepEqual(results, want) {
+			t.Fatalf("Expected %+v, got %+v", want, results)
+		}
+		if svc.config.Timeout != 20 || svc.config.DataPath != "/data" {
+			t.Errorf("Expected the timeout applied and the data path kept, got %+v", svc.config)
+		}
+		if other.loads != 0 {
+			t.Errorf("Expected the unchanged service not to be reloaded, got %d calls", other.loads)
+		}
+
+		// 需要重启的变更在重启前一直被报告，已应用的变更不再报告
+		results = cr.Reload(next)
+		want = []ServiceReload{
+			{Service: "MoLingConfig", RestartRequired: []string{"listen_addr"}},
+			{Service: "Fake", RestartRequired: []string{"data_path"}},
+		}
+		if !reflect.DeepEqual(results, want) {
+			t.Errorf("Expected %+v, got %+v", want, results)
+		}
+	})
+
+	t.Run("InvalidConfigKeepsRunning", func(t *testing.T) {
+		loads := other.loads
+		next := map[string]interface{}{
+			"MoLingConfig": map[string]interface{}{"listen_addr": "127.0.0.1:6789"},
+			"Fake":         map[string]interface{}{"timeout": float64(20), "prefix": "a", "data_path": "/data"},
+			"Other":        map[string]interface{}{"timeout": float64(-1)},
+		}
+		results := cr.Reload(next)
+		if len(results) != 1 || results[0].Service != "Other" || results[0].Err == nil || results[0].Applied != nil {
+			t.Fatalf("Expected a failed reload of Other, got %+v", results)
+		}
+		if other.config.Timeout != 5 {
+			t.Errorf("Expected the running timeout kept, got %d", other.config.Timeout)
+		}
+		if other.loads != loads+1 {
+			t.Errorf("Expected the new config to be loaded once, got %d calls", other.loads-loads)
+		}
+	})
+
+	t.Run("RemovedField", func(t *testing.T) {
+		next := map[string]interface{}{
+			"MoLingConfig": map[string]interface{}{"listen_addr": "127.0.0.1:6789"},
+			"Fake":         map[string]interface{}{"timeout": float64(20), "data_path": "/data"},
+			"Other":        map[string]interface{}{"timeout": float64(5)},
+		}
+		results := cr.Reload(next)
+		want := []ServiceReload{{Service: "Fake", RestartRequired: []string{"prefix"}}}
+		if !reflect.DeepEqual(results, want) {
+			t.Errorf("Expected %+v, got %+v", want, results)
+		}
+		if svc.config.Prefix != "a" {
+			t.Errorf("Expected the prefix kept until a restart, got %q", svc.config.Prefix)
+		}
+	})
+}
@@ -0,0 +1,233 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gojue/moling/pkg/config"
+	"github.com/gojue/moling/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog"
+)
+
+const (
+	OverflowDir = "overflow" // 超限结果存储目录，位于 data 目录下
+
+	defaultPreviewBytes      = 16 * 1024
+	defaultOverflowRetention = 24 * time.Hour
+	defaultOverflowMaxFiles  = 100
+)
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// ResultLimiter caps the size of tool results. Oversized content is written to the overflow
+// directory and replaced with a preview and the path of the full content.
+type ResultLimiter struct {
+	cfg    config.ResultLimitConfig
+	dir    string
+	logger zerolog.Logger
+	mu     sync.Mutex
+	seq    int
+}
+
+// NewResultLimiter creates a ResultLimiter writing overflow files to dir.
+func NewResultLimiter(cfg config.ResultLimitConfig, dir string, logger zerolog.Logger) *ResultLimiter {
+	if cfg.PreviewBytes <= 0 {
+		cfg.PreviewBytes = defaultPreviewBytes
+	}
+	if cfg.OverflowMaxFiles <= 0 {
+		cfg.OverflowMaxFiles = defaultOverflowMaxFiles
+	}
+	rl := &ResultLimiter{cfg: cfg, dir: dir, logger: logger}
+	rl.cleanup()
+	return rl
+}
+
+// Wrap returns handler with the result limits of the tool applied. aliases are other names of the
+// tool that tool_result_limits may use, e.g. the name without the service prefix.
+func (rl *ResultLimiter) Wrap(tool string, handler server.ToolHandlerFunc, aliases ...string) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := handler(ctx, request)
+		if err != nil || result == nil {
+			return result, err
+		}
+		return rl.limit(tool, result, aliases...), nil
+	}
+}
+
+// limit replaces the oversized contents of result.
+func (rl *ResultLimiter) limit(tool string, result *mcp.CallToolResult, aliases ...string) *mcp.CallToolResult {
+	textLimit := rl.cfg.LimitFor(tool, aliases...)
+	binLimit := rl.cfg.MaxImageResultBytes
+	for i, content := range result.Content {
+		switch c := content.(type) {
+		case mcp.TextContent:
+			if textLimit > 0 && len(c.Text) > textLimit {
+				result.Content[i] = rl.overflowText(tool, c.Text, ".txt")
+			}
+		case mcp.ImageContent:
+			if binLimit > 0 && len(c.Data) > binLimit {
+				result.Content[i] = rl.overflowBinary(tool, c.Data, c.MIMEType, "image")
+			}
+		case mcp.EmbeddedResource:
+			switch res := c.Resource.(type) {
+			case mcp.TextResourceContents:
+				if textLimit > 0 && len(res.Text) > textLimit {
+					result.Content[i] = rl.overflowText(tool, res.Text, ".txt")
+				}
+			case mcp.BlobResourceContents:
+				if binLimit > 0 && len(res.Blob) > binLimit {
+					result.Content[i] = rl.overflowBinary(tool, res.Blob, res.MIMEType, "resource")
+				}
+			}
+		}
+	}
+	return result
+}
+
+// overflowText saves text and returns a preview of its first PreviewBytes bytes.
+func (rl *ResultLimiter) overflowText(tool, text, ext string) mcp.Content {
+	preview := truncateUTF8(text, rl.cfg.PreviewBytes)
+	path, err := rl.write(tool, ext, []byte(text))
+	if err != nil {
+		rl.logger.Error().Err(err).Str("tool", tool).Msg("failed to save overflow result")
+		return mcp.NewTextContent(fmt.Sprintf("%s\n\n[result truncated: %d bytes total, showing the first %d bytes, failed to save the full content: %v]",
+			preview, len(text), len(preview), err))
+	}
+	return mcp.NewTextContent(fmt.Sprintf("%s\n\n[result truncated: %d bytes total, showing the first %d bytes. Full content saved to: %s]",
+		preview, len(text), len(preview), path))
+}
+
+// overflowBinary saves base64 encoded data and returns a notice instead of the content.
+func (rl *ResultLimiter) overflowBinary(tool, data, mimeType, kind string) mcp.Content {
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return mcp.NewTextContent(fmt.Sprintf("[%s content removed: %d bytes exceeds the limit of %d bytes]", kind, len(data), rl.cfg.MaxImageResultBytes))
+	}
+	path, err := rl.write(tool, extForMIME(mimeType), raw)
+	if err != nil {
+		rl.logger.Error().Err(err).Str("tool", tool).Msg("failed to save overflow result")
+		return mcp.NewTextContent(fmt.Sprintf("[%s content removed: %d bytes exceeds the limit of %d bytes, failed to save it: %v]", kind, len(data), rl.cfg.MaxImageResultBytes, err))
+	}
+	return mcp.NewTextContent(fmt.Sprintf("[%s content removed: %d bytes exceeds the limit of %d bytes. Saved to: %s]",
+		kind, len(data), rl.cfg.MaxImageResultBytes, path))
+}
+
+// write saves data to a new file in the overflow directory and prunes old files.
+func (rl *ResultLimiter) write(tool, ext string, data []byte) (string, error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if err := utils.CreateDirectory(rl.dir); err != nil {
+		return "", err
+	}
+	rl.seq++
+	name := fmt.Sprintf("%s_%s_%d%s", unsafeFileChars.ReplaceAllString(tool, "_"), time.Now().Format("20060102_150405"), rl.seq, ext)
+	path := filepath.Join(rl.dir, name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", err
+	}
+	rl.pruneLocked()
+	return path, nil
+}
+
+// cleanup removes expired overflow files.
+func (rl *ResultLimiter) cleanup() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.pruneLocked()
+}
+
+// pruneLocked removes files older than OverflowRetention and the oldest files beyond OverflowMaxFiles.
+func (rl *ResultLimiter) pruneLocked() {
+	entries, err := os.ReadDir(rl.dir)
+	if err != nil {
+		return
+	}
+	retention := defaultOverflowRetention
+	if rl.cfg.OverflowRetention > 0 {
+		retention = time.Duration(rl.cfg.OverflowRetention) * time.Hour
+	}
+	type file struct {
+		path    string
+		modTime time.Time
+	}
+	var files []file
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(rl.dir, entry.Name())
+		if time.Since(info.ModTime()) > retention {
+			_ = os.Remove(path)
+			continue
+		}
+		files = append(files, file{path: path, modTime: info.ModTime()})
+	}
+	if len(files) <= rl.cfg.OverflowMaxFiles {
+		return
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	for _, f := range files[:len(files)-rl.cfg.OverflowMaxFiles] {
+		_ = os.Remove(f.path)
+	}
+}
+
+// truncateUTF8 returns the first n bytes of s without splitting a multi-byte character.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// extForMIME returns the file extension for an image or binary MIME type.
+func extForMIME(mimeType string) string {
+	switch strings.ToLower(mimeType) {
+	case "image/png":
+		return ".png"
+	case "image/jpeg", "image/jpg":
+		return ".jpg"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	case "application/pdf":
+		return ".pdf"
+	default:
+		return ".bin"
+	}
+}
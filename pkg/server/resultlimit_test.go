@@ -0,0 +1,141 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gojue/moling/pkg/config"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog"
+)
+
+var overflowPathRegexp = regexp.MustCompile(`(?i)saved to: (\S+?)\]`)
+
+func callLimited(t *testing.T, rl *ResultLimiter, tool string, result *mcp.CallToolResult) *mcp.CallToolResult {
+	t.Helper()
+	handler := rl.Wrap(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return result, nil
+	})
+	got, err := handler(context.Background(), mcp.CallToolRequest{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return got
+}
+
+func TestResultLimiter(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.NewResultLimitConfig()
+	cfg.MaxToolResultBytes = 1024 * 1024
+	cfg.MaxImageResultBytes = 1024
+	cfg.PreviewBytes = 4096
+	cfg.ToolResultLimits = map[string]int{"big_tool": 0}
+	rl := NewResultLimiter(cfg, dir, zerolog.Nop())
+
+	t.Run("TruncateLargeText", func(t *testing.T) {
+		full := strings.Repeat("0123456789", 1024*1024) // 10MB
+		got := callLimited(t, rl, "browser_evaluate", mcp.NewToolResultText(full))
+		text := got.Content[0].(mcp.TextContent).Text
+		if len(text) > 5000 || !strings.HasPrefix(text, full[:4096]) {
+			t.Fatalf("Expected a 4KB preview, got %d bytes", len(text))
+		}
+		if !strings.Contains(text, "result truncated: 10485760 bytes total") {
+			t.Errorf("Expected truncation notice, got %s", text[4096:])
+		}
+		m := overflowPathRegexp.FindStringSubmatch(text)
+		if m == nil {
+			t.Fatalf("Expected overflow file path, got %s", text[4096:])
+		}
+		data, err := os.ReadFile(m[1])
+		if err != nil {
+			t.Fatalf("Failed to read overflow file: %v", err)
+		}
+		if string(data) != full {
+			t.Errorf("Expected overflow file to contain the full result, got %d bytes", len(data))
+		}
+	})
+
+	t.Run("SmallResultUntouched", func(t *testing.T) {
+		got := callLimited(t, rl, "read_file", mcp.NewToolResultText("hello"))
+		if text := got.Content[0].(mcp.TextContent).Text; text != "hello" {
+			t.Errorf("Expected result untouched, got %s", text)
+		}
+	})
+
+	t.Run("PerToolOverride", func(t *testing.T) {
+		full := strings.Repeat("a", 2*1024*1024)
+		got := callLimited(t, rl, "big_tool", mcp.NewToolResultText(full))
+		if text := got.Content[0].(mcp.TextContent).Text; text != full {
+			t.Errorf("Expected unlimited result for big_tool, got %d bytes", len(text))
+		}
+	})
+
+	t.Run("ImageCappedSeparately", func(t *testing.T) {
+		raw := make([]byte, 2048)
+		result := mcp.NewToolResultImage("screenshot", base64.StdEncoding.EncodeToString(raw), "image/png")
+		got := callLimited(t, rl, "browser_screenshot", result)
+		if text := got.Content[0].(mcp.TextContent).Text; text != "screenshot" {
+			t.Errorf("Expected small text untouched, got %s", text)
+		}
+		notice, ok := got.Content[1].(mcp.TextContent)
+		if !ok || !strings.Contains(notice.Text, "image content removed") {
+			t.Fatalf("Expected image to be replaced by a notice, got %#v", got.Content[1])
+		}
+		m := overflowPathRegexp.FindStringSubmatch(notice.Text)
+		if m == nil || filepath.Ext(m[1]) != ".png" {
+			t.Fatalf("Expected png overflow file, got %s", notice.Text)
+		}
+		if data, _ := os.ReadFile(m[1]); len(data) != len(raw) {
+			t.Errorf("Expected decoded image in overflow file, got %d bytes", len(data))
+		}
+	})
+
+	t.Run("Retention", func(t *testing.T) {
+		old := filepath.Join(dir, "old.txt")
+		if err := os.WriteFile(old, []byte("old"), 0600); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		past := time.Now().Add(-48 * time.Hour)
+		_ = os.Chtimes(old, past, past)
+		NewResultLimiter(cfg, dir, zerolog.Nop())
+		if _, err := os.Stat(old); !os.IsNotExist(err) {
+			t.Errorf("Expected expired overflow file to be removed")
+		}
+
+		cfg := cfg
+		cfg.OverflowMaxFiles = 2
+		limited := NewResultLimiter(cfg, dir, zerolog.Nop())
+		for i := 0; i < 3; i++ {
+			callLimited(t, limited, "browser_evaluate", mcp.NewToolResultText(strings.Repeat("x", 2*1024*1024)))
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+			t.Errorf("Expected 2 overflow files, got %d", len(entries))
+		}
+	})
+}
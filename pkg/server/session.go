@@ -0,0 +1,226 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/config"
+	"github.com/gojue/moling/pkg/services/abstract"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog"
+)
+
+// maxSweepInterval is the longest interval between two sweeps of idle sessions.
+const maxSweepInterval = time.Minute
+
+// SessionInfo describes an active MCP client session.
+type SessionInfo struct {
+	ID       string            `json:"id"`
+	Started  time.Time         `json:"started"`
+	LastSeen time.Time         `json:"last_seen"`
+	Calls    int64             `json:"calls"`  // 工具调用次数
+	Scoped   map[string]string `json:"scoped"` // 持有会话状态的服务，值为状态描述（实现 fmt.Stringer 时）
+}
+
+// clientSession is the session-scoped state of one MCP client session.
+type clientSession struct {
+	id       string
+	started  time.Time
+	lastSeen time.Time
+	calls    int64
+	active   int // 正在执行的工具调用数，执行中的会话不会因空闲而关闭
+	states   map[comm.MoLingServerType]abstract.SessionState
+}
+
+// SessionManager threads the MCP client session through tool calls and keeps the state of
+// SessionScoped services per session. States are created on the first tool call of a session and
+// closed when the session ends or idles out.
+type SessionManager struct {
+	idleTimeout time.Duration
+	logger      zerolog.Logger
+	now         func() time.Time
+	mu          sync.Mutex
+	sessions    map[string]*clientSession
+}
+
+// NewSessionManager creates a SessionManager from the configuration.
+func NewSessionManager(cfg config.SessionConfig, logger zerolog.Logger) *SessionManager {
+	return &SessionManager{
+		idleTimeout: time.Duration(cfg.IdleTimeout) * time.Second,
+		logger:      logger,
+		now:         time.Now,
+		sessions:    make(map[string]*clientSession),
+	}
+}
+
+// Wrap stores the session ID of the call in the context, see abstract.SessionIDFromContext. For
+// SessionScoped services the state of the session is stored as well, a nil scoped means the
+// service keeps no session state. Calls without a session are passed through unchanged.
+func (sm *SessionManager) Wrap(name comm.MoLingServerType, scoped abstract.SessionScoped, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		session := server.ClientSessionFromContext(ctx)
+		if session == nil || session.SessionID() == "" {
+			return handler(ctx, request)
+		}
+		id := session.SessionID()
+		state, err := sm.acquire(id, name, scoped)
+		defer sm.release(id)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to create %s session state: %v", name, err)), nil
+		}
+		ctx = context.WithValue(ctx, comm.MoLingSessionKey, id)
+		if state != nil {
+			ctx = context.WithValue(ctx, comm.MoLingSessionStateKey, state)
+		}
+		return handler(ctx, request)
+	}
+}
+
+// acquire marks a call of the session as running and returns the state of the service, creating
+// the session and the state when needed.
+func (sm *SessionManager) acquire(id string, name comm.MoLingServerType, scoped abstract.SessionScoped) (abstract.SessionState, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	now := sm.now()
+	cs, ok := sm.sessions[id]
+	if !ok {
+		cs = &clientSession{id: id, started: now, states: make(map[comm.MoLingServerType]abstract.SessionState)}
+		sm.sessions[id] = cs
+		sm.logger.Debug().Str("session", id).Msg("session started")
+	}
+	cs.lastSeen = now
+	cs.calls++
+	cs.active++
+	if scoped == nil {
+		return nil, nil
+	}
+	if state, ok := cs.states[name]; ok {
+		return state, nil
+	}
+	state, err := scoped.NewSessionState(id)
+	if err != nil {
+		return nil, err
+	}
+	cs.states[name] = state
+	return state, nil
+}
+
+// release marks a call of the session as finished.
+func (sm *SessionManager) release(id string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if cs, ok := sm.sessions[id]; ok {
+		cs.active--
+		cs.lastSeen = sm.now()
+	}
+}
+
+// CloseSession closes the states of a session and forgets it. It is called when the client
+// disconnects, a later call of the same session starts over with new states.
+func (sm *SessionManager) CloseSession(id string) {
+	sm.mu.Lock()
+	cs, ok := sm.sessions[id]
+	delete(sm.sessions, id)
+	sm.mu.Unlock()
+	if ok {
+		sm.closeStates(cs, "session ended")
+	}
+}
+
+// Sweep closes the sessions without tool calls for longer than the idle timeout.
+func (sm *SessionManager) Sweep() {
+	if sm.idleTimeout <= 0 {
+		return
+	}
+	sm.mu.Lock()
+	now := sm.now()
+	var idle []*clientSession
+	for id, cs := range sm.sessions {
+		if cs.active == 0 && now.Sub(cs.lastSeen) >= sm.idleTimeout {
+			idle = append(idle, cs)
+			delete(sm.sessions, id)
+		}
+	}
+	sm.mu.Unlock()
+	for _, cs := range idle {
+		sm.closeStates(cs, "session idled out")
+	}
+}
+
+// Run sweeps idle sessions until ctx is done.
+func (sm *SessionManager) Run(ctx context.Context) {
+	if sm.idleTimeout <= 0 {
+		return
+	}
+	interval := min(sm.idleTimeout, maxSweepInterval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sm.Sweep()
+		}
+	}
+}
+
+// closeStates closes the states of a forgotten session, a failed Close is only logged.
+func (sm *SessionManager) closeStates(cs *clientSession, reason string) {
+	for name, state := range cs.states {
+		if err := state.Close(); err != nil {
+			sm.logger.Warn().Err(err).Str("session", cs.id).Str("serviceName", string(name)).Msg("failed to close session state")
+		}
+	}
+	sm.logger.Debug().Str("session", cs.id).Int("states", len(cs.states)).Msg(reason)
+}
+
+// Sessions returns the active sessions sorted by start time.
+func (sm *SessionManager) Sessions() []SessionInfo {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sessions := make([]SessionInfo, 0, len(sm.sessions))
+	for _, cs := range sm.sessions {
+		info := SessionInfo{ID: cs.id, Started: cs.started, LastSeen: cs.lastSeen, Calls: cs.calls, Scoped: map[string]string{}}
+		for name, state := range cs.states {
+			var desc string
+			if s, ok := state.(fmt.Stringer); ok {
+				desc = s.String()
+			}
+			info.Scoped[string(name)] = desc
+		}
+		sessions = append(sessions, info)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].Started.Equal(sessions[j].Started) {
+			return sessions[i].ID < sessions[j].ID
+		}
+		return sessions[i].Started.Before(sessions[j].Started)
+	})
+	return sessions
+}
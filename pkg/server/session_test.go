@@ -0,0 +1,279 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/config"
+	"github.com/gojue/moling/pkg/services/abstract"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// fakeSession is an MCP client session without a transport.
+type fakeSession struct {
+	id string
+}
+
+func (fs *fakeSession) Initialize()       {}
+func (fs *fakeSession) Initialized() bool { return true }
+func (fs *fakeSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return make(chan mcp.JSONRPCNotification, 1)
+}
+func (fs *fakeSession) SessionID() string { return fs.id }
+
+// pageState is the session state of scopedService, the page the session visited last.
+type pageState struct {
+	mu     sync.Mutex
+	page   string
+	closed bool
+}
+
+func (ps *pageState) Close() error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.closed = true
+	return nil
+}
+
+func (ps *pageState) String() string {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.page
+}
+
+// scopedService is a SessionScoped service with a visit tool that sets the page of the session
+// and a where tool that returns it.
+type scopedService struct {
+	abstract.MLService
+	mu     sync.Mutex
+	states map[string]*pageState
+}
+
+func (ss *scopedService) NewSessionState(sessionID string) (abstract.SessionState, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	state := &pageState{}
+	ss.states[sessionID] = state
+	return state, nil
+}
+
+func (ss *scopedService) RegisterTools() error {
+	ss.AddTool(mcp.NewTool("visit", mcp.WithString("page")), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		state := abstract.SessionStateFromContext(ctx).(*pageState)
+		page, _ := request.GetArguments()["page"].(string)
+		state.mu.Lock()
+		state.page = page
+		state.mu.Unlock()
+		return mcp.NewToolResultText("visited " + page), nil
+	})
+	ss.AddTool(mcp.NewTool("where"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		state, ok := abstract.SessionStateFromContext(ctx).(*pageState)
+		if !ok {
+			return mcp.NewToolResultText("no session"), nil
+		}
+		return mcp.NewToolResultText(abstract.SessionIDFromContext(ctx) + ":" + state.String()), nil
+	})
+	return nil
+}
+
+func (ss *scopedService) Name() comm.MoLingServerType {
+	return "Scoped"
+}
+
+func (ss *scopedService) Close() error {
+	return nil
+}
+
+// state returns the state created for a session.
+func (ss *scopedService) state(id string) *pageState {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return ss.states[id]
+}
+
+func newScopedServer(t *testing.T, cfg config.SessionConfig) (*MoLingServer, *scopedService) {
+	t.Helper()
+	_, ctx, err := comm.InitTestEnv()
+	if err != nil {
+		t.Fatalf("Failed to initialize test environment: %v", err)
+	}
+	base, err := abstract.NewServiceBase(ctx, "Scoped")
+	if err != nil {
+		t.Fatalf("Failed to create service base: %v", err)
+	}
+	ss := &scopedService{MLService: base, states: make(map[string]*pageState)}
+	if err := ss.RegisterTools(); err != nil {
+		t.Fatalf("RegisterTools failed: %v", err)
+	}
+	srv, err := NewMoLingServer(ctx, []abstract.Service{ss}, config.MoLingConfig{BasePath: t.TempDir(), Session: cfg})
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	return srv, ss
+}
+
+// callSessionTool calls a tool as the given session.
+func callSessionTool(t *testing.T, srv *MoLingServer, session server.ClientSession, name string, args map[string]interface{}) string {
+	t.Helper()
+	params, _ := json.Marshal(map[string]interface{}{"name": name, "arguments": args})
+	msg := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":%s}`, params)
+	ctx := context.Background()
+	if session != nil {
+		ctx = srv.server.WithContext(ctx, session)
+	}
+	resp := srv.server.HandleMessage(ctx, json.RawMessage(msg))
+	rpc, ok := resp.(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("Unexpected response %#v", resp)
+	}
+	result, ok := rpc.Result.(mcp.CallToolResult)
+	if !ok {
+		t.Fatalf("Unexpected result %#v", rpc.Result)
+	}
+	return result.Content[0].(mcp.TextContent).Text
+}
+
+func TestSessionScopedState(t *testing.T) {
+	t.Run("Isolation", func(t *testing.T) {
+		srv, _ := newScopedServer(t, config.NewSessionConfig())
+		a, b := &fakeSession{id: "a"}, &fakeSession{id: "b"}
+
+		// 两个会话交替调用，各自的页面互不影响
+		callSessionTool(t, srv, a, "visit", map[string]interface{}{"page": "https://a.example"})
+		callSessionTool(t, srv, b, "visit", map[string]interface{}{"page": "https://b.example"})
+		if got := callSessionTool(t, srv, a, "where", nil); got != "a:https://a.example" {
+			t.Errorf("Expected session a to stay on its page, got %s", got)
+		}
+		callSessionTool(t, srv, a, "visit", map[string]interface{}{"page": "https://a.example/next"})
+		if got := callSessionTool(t, srv, b, "where", nil); got != "b:https://b.example" {
+			t.Errorf("Expected session b to stay on its page, got %s", got)
+		}
+		if got := callSessionTool(t, srv, nil, "where", nil); got != "no session" {
+			t.Errorf("Expected no state without a session, got %s", got)
+		}
+
+		sessions := srv.Sessions()
+		if len(sessions) != 2 {
+			t.Fatalf("Expected 2 sessions, got %d", len(sessions))
+		}
+		for _, s := range sessions {
+			if _, ok := s.Scoped["Scoped"]; !ok {
+				t.Errorf("Expected session %s to hold Scoped state, got %v", s.ID, s.Scoped)
+			}
+		}
+	})
+
+	t.Run("ConcurrentSessions", func(t *testing.T) {
+		srv, ss := newScopedServer(t, config.NewSessionConfig())
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			session := &fakeSession{id: fmt.Sprintf("s%d", i)}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 20; j++ {
+					page := fmt.Sprintf("%s/%d", session.id, j)
+					callSessionTool(t, srv, session, "visit", map[string]interface{}{"page": page})
+					if got := callSessionTool(t, srv, session, "where", nil); got != session.id+":"+page {
+						t.Errorf("Expected %s:%s, got %s", session.id, page, got)
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		if n := len(ss.states); n != 4 {
+			t.Errorf("Expected one state per session, got %d", n)
+		}
+	})
+
+	t.Run("CloseOnUnregister", func(t *testing.T) {
+		srv, ss := newScopedServer(t, config.NewSessionConfig())
+		a, b := &fakeSession{id: "a"}, &fakeSession{id: "b"}
+		for _, s := range []*fakeSession{a, b} {
+			if err := srv.server.RegisterSession(context.Background(), s); err != nil {
+				t.Fatalf("RegisterSession failed: %v", err)
+			}
+			callSessionTool(t, srv, s, "visit", map[string]interface{}{"page": s.id})
+		}
+
+		srv.server.UnregisterSession(context.Background(), "a")
+		if !ss.state("a").closed {
+			t.Errorf("Expected the state of session a to be closed")
+		}
+		if ss.state("b").closed {
+			t.Errorf("Expected the state of session b to stay open")
+		}
+		if sessions := srv.Sessions(); len(sessions) != 1 || sessions[0].ID != "b" {
+			t.Errorf("Expected only session b, got %v", sessions)
+		}
+	})
+
+	t.Run("IdleTimeout", func(t *testing.T) {
+		srv, ss := newScopedServer(t, config.SessionConfig{IdleTimeout: 60})
+		now := time.Now()
+		srv.sessions.now = func() time.Time { return now }
+		callSessionTool(t, srv, &fakeSession{id: "idle"}, "visit", map[string]interface{}{"page": "x"})
+		now = now.Add(30 * time.Second)
+		callSessionTool(t, srv, &fakeSession{id: "busy"}, "visit", map[string]interface{}{"page": "y"})
+
+		now = now.Add(40 * time.Second)
+		srv.sessions.Sweep()
+		if !ss.state("idle").closed {
+			t.Errorf("Expected the idle session to be closed")
+		}
+		if ss.state("busy").closed {
+			t.Errorf("Expected the recently used session to stay open")
+		}
+
+		// 空闲关闭后再次调用，会话以新的状态重新开始
+		if got := callSessionTool(t, srv, &fakeSession{id: "idle"}, "where", nil); got != "idle:" {
+			t.Errorf("Expected a fresh state after idling out, got %s", got)
+		}
+	})
+
+	t.Run("StatusResource", func(t *testing.T) {
+		srv, _ := newScopedServer(t, config.NewSessionConfig())
+		callSessionTool(t, srv, &fakeSession{id: "a"}, "visit", map[string]interface{}{"page": "https://a.example"})
+		msg := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":%q}}`, StatusURI)
+		resp := srv.server.HandleMessage(context.Background(), json.RawMessage(msg))
+		rpc, ok := resp.(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatalf("Unexpected response %#v", resp)
+		}
+		result := rpc.Result.(mcp.ReadResourceResult)
+		var status Status
+		if err := json.Unmarshal([]byte(result.Contents[0].(mcp.TextResourceContents).Text), &status); err != nil {
+			t.Fatalf("Invalid status: %v", err)
+		}
+		if len(status.Sessions) != 1 || status.Sessions[0].Scoped["Scoped"] != "https://a.example" {
+			t.Errorf("Expected session a with its page in the status, got %+v", status.Sessions)
+		}
+	})
+}
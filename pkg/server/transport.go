@@ -0,0 +1,56 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	TransportStdio = "stdio" // 标准输入输出
+	TransportSSE   = "sse"   // HTTP + Server-Sent Events
+	TransportHTTP  = "http"  // Streamable HTTP
+
+	// StreamableHTTPPath is the MCP endpoint of the streamable HTTP transport.
+	StreamableHTTPPath = "/mcp"
+)
+
+// ResolveTransport returns the transport selected by transport_mode. An empty mode keeps the
+// auto-detection: sse when listen_addr is set, stdio otherwise. sse and http need listen_addr.
+func ResolveTransport(mode, listenAddr string) (string, error) {
+	switch mode = strings.ToLower(strings.TrimSpace(mode)); mode {
+	case "":
+		if listenAddr != "" {
+			return TransportSSE, nil
+		}
+		return TransportStdio, nil
+	case TransportStdio:
+		return mode, nil
+	case TransportSSE, TransportHTTP:
+		if listenAddr == "" {
+			return "", fmt.Errorf("transport %s requires listen_addr", mode)
+		}
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown transport %q, must be one of %s, %s or %s", mode, TransportStdio, TransportSSE, TransportHTTP)
+	}
+}
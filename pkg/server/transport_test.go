@@ -0,0 +1,108 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/config"
+	"github.com/gojue/moling/pkg/services/abstract"
+)
+
+func TestResolveTransport(t *testing.T) {
+	tests := []struct {
+		mode, addr string
+		want       string
+		wantErr    bool
+	}{
+		{"", "", TransportStdio, false},
+		{"", "127.0.0.1:6789", TransportSSE, false},
+		{"stdio", "127.0.0.1:6789", TransportStdio, false},
+		{"sse", "127.0.0.1:6789", TransportSSE, false},
+		{"HTTP", "127.0.0.1:6789", TransportHTTP, false},
+		{" http ", "127.0.0.1:6789", TransportHTTP, false},
+		{"http", "", "", true},
+		{"sse", "", "", true},
+		{"websocket", "127.0.0.1:6789", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ResolveTransport(tt.mode, tt.addr)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ResolveTransport(%q, %q) = %q, %v, want %q, error %t", tt.mode, tt.addr, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestTransportMode(t *testing.T) {
+	t.Run("UnknownMode", func(t *testing.T) {
+		_, ctx, err := comm.InitTestEnv()
+		if err != nil {
+			t.Fatalf("Failed to initialize test environment: %v", err)
+		}
+		_, err = NewMoLingServer(ctx, []abstract.Service{}, config.MoLingConfig{
+			TransportMode: "grpc", ListenAddr: "127.0.0.1:6789", BasePath: t.TempDir(),
+		})
+		if err == nil || !strings.Contains(err.Error(), `unknown transport "grpc"`) {
+			t.Errorf("Expected an unknown transport error, got %v", err)
+		}
+	})
+
+	t.Run("Banner", func(t *testing.T) {
+		srv, _ := newMetricsServer(t, config.MoLingConfig{TransportMode: "http", ListenAddr: "127.0.0.1:6789"})
+		if banner := srv.Banner(); banner.Transport != TransportHTTP || banner.ListenAddr != "127.0.0.1:6789" {
+			t.Errorf("Unexpected banner transport %q, listen_addr %q", banner.Transport, banner.ListenAddr)
+		}
+		// stdio 忽略 listen_addr
+		srv, _ = newMetricsServer(t, config.MoLingConfig{TransportMode: "stdio", ListenAddr: "127.0.0.1:6789"})
+		if banner := srv.Banner(); banner.Transport != TransportStdio || banner.ListenAddr != "" {
+			t.Errorf("Unexpected banner transport %q, listen_addr %q", banner.Transport, banner.ListenAddr)
+		}
+	})
+
+	t.Run("StreamableHTTP", func(t *testing.T) {
+		srv, _ := newMetricsServer(t, config.MoLingConfig{TransportMode: "http", ListenAddr: "127.0.0.1:6789"})
+		mux := srv.httpMux(srv.mcpHandler("http://127.0.0.1:6789", nil))
+
+		body := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`
+		req := httptest.NewRequest(http.MethodPost, StreamableHTTPPath, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"serverInfo"`) {
+			t.Fatalf("Expected an initialize result, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if rec.Header().Get("Mcp-Session-Id") == "" {
+			t.Error("Expected a session id header")
+		}
+
+		// http 传输不提供 SSE 端点
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sse", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Expected no /sse endpoint, got %d", rec.Code)
+		}
+	})
+}
@@ -27,11 +27,16 @@ type Service interface {
 
 	// Config returns the configuration of the service as a string.
 	Config() string
-	// LoadConfig loads the configuration for the service from a map.
+	// LoadConfig loads the configuration for the service from a map. An invalid configuration must
+	// leave the running configuration unchanged.
 	LoadConfig(jsonData map[string]interface{}) error
+	// ConfigSchema returns the JSON Schema of the configuration, so that clients can render a
+	// settings form. It is empty for services without a schema.
+	ConfigSchema() string
 
-	// Init initializes the service with the given context and configuration.
-	Init() error
+	// RegisterTools registers the prompts, resources and tools of the service. It must not have
+	// side effects such as launching processes, so that tools can be listed cheaply.
+	RegisterTools() error
 
 	MlConfig() *config.MoLingConfig
 
@@ -41,3 +46,50 @@ type Service interface {
 	// Close closes the service and releases any resources it holds.
 	Close() error
 }
+
+// Starter is implemented by services with heavy startup work, such as launching Chrome. The
+// server calls Start before every tool call of the service instead of at startup, so Start must
+// be idempotent and safe for concurrent use. Its error is returned as the result of the call.
+type Starter interface {
+	Start() error
+}
+
+// Restarter is implemented by services that restart the process behind them after a crash, such
+// as Chrome or a plugin binary. The restarts are exposed as a metric.
+type Restarter interface {
+	Restarts() int
+}
+
+// RestartOnlyConfig is implemented by services with configuration fields that only take effect
+// when the service or the process behind it starts, such as the Chrome profile directory. A
+// config reload doesn't apply changes to these fields and reports that they need a restart.
+type RestartOnlyConfig interface {
+	// RestartOnlyFields returns the JSON names of the fields.
+	RestartOnlyFields() []string
+}
+
+// SessionState is the state a SessionScoped service keeps for one MCP client session. Close is
+// called when the session ends or idles out.
+type SessionState interface {
+	Close() error
+}
+
+// SessionScoped is implemented by services that keep state per MCP client session, such as the
+// browser tab. The server calls NewSessionState on the first tool call of a new session and passes
+// the state to the handlers of the session, see SessionStateFromContext.
+type SessionScoped interface {
+	NewSessionState(sessionID string) (SessionState, error)
+}
+
+// SessionIDFromContext returns the MCP client session ID of a tool call, empty outside a session.
+func SessionIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(comm.MoLingSessionKey).(string)
+	return id
+}
+
+// SessionStateFromContext returns the session-scoped state of the called service, nil when the
+// service is not SessionScoped or the call has no session.
+func SessionStateFromContext(ctx context.Context) SessionState {
+	state, _ := ctx.Value(comm.MoLingSessionStateKey).(SessionState)
+	return state
+}
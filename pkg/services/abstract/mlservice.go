@@ -2,6 +2,10 @@ package abstract
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/gojue/moling/pkg/comm"
@@ -54,6 +58,56 @@ func NewMLService(ctx context.Context, logger zerolog.Logger, cfg *config.MoLing
 	return service
 }
 
+var (
+	// ErrConfigNotFound is returned when the MoLing config is missing from the context.
+	ErrConfigNotFound = errors.New("MoLing config not found in context")
+	// ErrLoggerNotFound is returned when the logger is missing from the context.
+	ErrLoggerNotFound = errors.New("logger not found in context")
+	// ErrNoClient is returned when a notification is sent outside the tool call of an MCP client.
+	ErrNoClient = errors.New("no MCP client in context")
+)
+
+// FromContext extracts the global MoLing config and the logger that the server stores in ctx.
+func FromContext(ctx context.Context) (*config.MoLingConfig, zerolog.Logger, error) {
+	if ctx == nil {
+		return nil, zerolog.Nop(), ErrConfigNotFound
+	}
+	rawConf := ctx.Value(comm.MoLingConfigKey)
+	if rawConf == nil {
+		return nil, zerolog.Nop(), ErrConfigNotFound
+	}
+	cfg, ok := rawConf.(*config.MoLingConfig)
+	if !ok {
+		return nil, zerolog.Nop(), fmt.Errorf("invalid config type in context: %T, expected *config.MoLingConfig", rawConf)
+	}
+	if cfg == nil {
+		return nil, zerolog.Nop(), ErrConfigNotFound
+	}
+
+	rawLogger := ctx.Value(comm.MoLingLoggerKey)
+	if rawLogger == nil {
+		return nil, zerolog.Nop(), ErrLoggerNotFound
+	}
+	logger, ok := rawLogger.(zerolog.Logger)
+	if !ok {
+		return nil, zerolog.Nop(), fmt.Errorf("invalid logger type in context: %T, expected zerolog.Logger", rawLogger)
+	}
+	return cfg, logger, nil
+}
+
+// NewServiceBase creates an MLService from the config and logger stored in ctx. Every log
+// event of the returned service carries a "Service" field with the given service name.
+func NewServiceBase(ctx context.Context, serviceName comm.MoLingServerType) (MLService, error) {
+	cfg, logger, err := FromContext(ctx)
+	if err != nil {
+		return MLService{}, fmt.Errorf("%s: %w", serviceName, err)
+	}
+	loggerNameHook := zerolog.HookFunc(func(e *zerolog.Event, level zerolog.Level, msg string) {
+		e.Str("Service", string(serviceName))
+	})
+	return NewMLService(ctx, logger.Hook(loggerNameHook), cfg), nil
+}
+
 // init initializes the MLService with empty maps and a mutex.
 func (mls *MLService) InitResources() error {
 	if mls.lock == nil {
@@ -117,6 +171,16 @@ func (mls *MLService) AddNotificationHandler(name string, handler server.Notific
 	mls.notificationHandlers[name] = handler
 }
 
+// SendNotification sends a notification to the MCP client of the tool call in ctx. It returns
+// ErrNoClient outside a tool call, e.g. in tests.
+func (mls *MLService) SendNotification(ctx context.Context, method string, params map[string]any) error {
+	srv := server.ServerFromContext(ctx)
+	if srv == nil {
+		return ErrNoClient
+	}
+	return srv.SendNotificationToClient(ctx, method, params)
+}
+
 // Resources returns the map of resources and their handler functions.
 func (mls *MLService) Resources() map[mcp.Resource]server.ResourceHandlerFunc {
 	mls.lock.Lock()
@@ -162,17 +226,32 @@ func (mls *MLService) Config() string {
 	return mls.mlConfig.String()
 }
 
+// ConfigSchema returns an empty schema, services with a configuration override it.
+func (mls *MLService) ConfigSchema() string {
+	return ""
+}
+
 // Name returns the name of the service.
 func (mls *MLService) Name() comm.MoLingServerType {
 	return "MLService"
 }
 
-// LoadConfig loads the configuration for the service from a map.
+// LoadConfig loads the configuration for the service from a map. An invalid configuration is
+// rolled back, the error names the keys that were loaded.
 func (mls *MLService) LoadConfig(jsonData map[string]interface{}) error {
-	//panic("not implemented yet") // TODO: Implement
+	backup := *mls.MlConfig()
 	err := utils.MergeJSONToStruct(mls.MlConfig(), jsonData)
+	if err == nil {
+		err = mls.MlConfig().Check()
+	}
 	if err != nil {
-		return err
+		*mls.MlConfig() = backup
+		keys := make([]string, 0, len(jsonData))
+		for key := range jsonData {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return fmt.Errorf("invalid MoLingConfig (keys: %s): %w", strings.Join(keys, ", "), err)
 	}
-	return mls.MlConfig().Check()
+	return nil
 }
@@ -17,10 +17,16 @@
 package abstract
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/config"
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog"
 )
 
 func TestMLService_AddResource(t *testing.T) {
@@ -164,3 +170,99 @@ func TestMLService_AddNotificationHandler(t *testing.T) {
 		t.Errorf("Handler for notification not found")
 	}
 }
+
+func TestFromContext(t *testing.T) {
+	logger := zerolog.Nop()
+	cfg := &config.MoLingConfig{BasePath: "/tmp/moling"}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+	}{
+		{
+			name:    "missing config",
+			ctx:     context.WithValue(context.Background(), comm.MoLingLoggerKey, logger),
+			wantErr: ErrConfigNotFound,
+		},
+		{
+			name:    "missing logger",
+			ctx:     context.WithValue(context.Background(), comm.MoLingConfigKey, cfg),
+			wantErr: ErrLoggerNotFound,
+		},
+		{
+			name: "wrong config type",
+			ctx: context.WithValue(context.WithValue(context.Background(),
+				comm.MoLingConfigKey, *cfg), comm.MoLingLoggerKey, logger),
+		},
+		{
+			name: "wrong logger type",
+			ctx: context.WithValue(context.WithValue(context.Background(),
+				comm.MoLingConfigKey, cfg), comm.MoLingLoggerKey, &logger),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := FromContext(tt.ctx)
+			if err == nil {
+				t.Fatalf("Expected error, got nil")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr == nil && !strings.Contains(err.Error(), "invalid") {
+				t.Errorf("Expected type error, got %v", err)
+			}
+		})
+	}
+
+	ctx := context.WithValue(context.WithValue(context.Background(), comm.MoLingConfigKey, cfg), comm.MoLingLoggerKey, logger)
+	gotCfg, _, err := FromContext(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if gotCfg != cfg {
+		t.Errorf("Expected config %p, got %p", cfg, gotCfg)
+	}
+}
+
+func TestNewServiceBase(t *testing.T) {
+	if _, err := NewServiceBase(context.Background(), "Test"); !errors.Is(err, ErrConfigNotFound) {
+		t.Fatalf("Expected ErrConfigNotFound, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	ctx := context.WithValue(context.Background(), comm.MoLingConfigKey, &config.MoLingConfig{})
+	ctx = context.WithValue(ctx, comm.MoLingLoggerKey, zerolog.New(&buf))
+	base, err := NewServiceBase(ctx, "Test")
+	if err != nil {
+		t.Fatalf("Failed to create service base: %v", err)
+	}
+	base.Logger.Info().Msg("hello")
+	if !strings.Contains(buf.String(), `"Service":"Test"`) {
+		t.Errorf("Expected Service field in log output, got %s", buf.String())
+	}
+}
+
+func TestMLService_LoadConfig(t *testing.T) {
+	cfg := &config.MoLingConfig{BasePath: t.TempDir(), Version: "v1.0.0"}
+	service := NewMLService(context.Background(), zerolog.Nop(), cfg)
+
+	if err := service.LoadConfig(map[string]interface{}{"listen_addr": "127.0.0.1:6789"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.ListenAddr != "127.0.0.1:6789" {
+		t.Errorf("Expected listen_addr to be loaded, got %s", cfg.ListenAddr)
+	}
+
+	err := service.LoadConfig(map[string]interface{}{"version": "", "listen_addr": "invalid", "debug": true})
+	if err == nil {
+		t.Fatal("Expected an error for an invalid configuration")
+	}
+	if !strings.Contains(err.Error(), "keys: debug, listen_addr, version") {
+		t.Errorf("Expected the offending keys in error, got %v", err)
+	}
+	if cfg.ListenAddr != "127.0.0.1:6789" || cfg.Version != "v1.0.0" || cfg.Debug {
+		t.Errorf("Expected the configuration rolled back, got %s", cfg)
+	}
+}
@@ -20,21 +20,27 @@ package browser
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"math/rand"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
+	"github.com/chromedp/cdproto/page"
 	"github.com/chromedp/chromedp"
 	"github.com/gojue/moling/pkg/comm"
 	"github.com/gojue/moling/pkg/config"
+	"github.com/gojue/moling/pkg/ocr"
 	"github.com/gojue/moling/pkg/services/abstract"
 	"github.com/gojue/moling/pkg/utils"
 	"github.com/mark3labs/mcp-go/mcp"
-	"github.com/rs/zerolog"
+	"github.com/mark3labs/mcp-go/server"
 )
 
 const (
@@ -45,90 +51,79 @@ const (
 
 // BrowserServer represents the configuration for the browser service.
 type BrowserServer struct {
-	abstract.MLService                    // 继承MLService
-	config             *BrowserConfig     // 浏览器配置
-	name               string             // 服务名称
-	cancelAlloc        context.CancelFunc // 资源清理方法
-	cancelChrome       context.CancelFunc // 浏览器清理方法
+	abstract.MLService                                                                    // 继承MLService
+	config             *BrowserConfig                                                     // 浏览器配置，重新加载或切换无头模式时整体替换，通过 cfg 读取
+	configLock         sync.RWMutex                                                       // 保护 config
+	name               string                                                             // 服务名称
+	cancelAlloc        context.CancelFunc                                                 // 资源清理方法
+	cancelChrome       context.CancelFunc                                                 // 浏览器清理方法
+	starter            func() error                                                       // 浏览器启动方法，崩溃后用于重启
+	browserLock        sync.RWMutex                                                       // 保护 Context、cancelAlloc 和 cancelChrome，重启时替换
+	restartGen         uint64                                                             // 浏览器每次停止后加一，避免并发调用重复重启，由 browserLock 保护
+	restartLock        sync.Mutex                                                         // 浏览器重启锁
+	restartTimes       []time.Time                                                        // 重启时间窗口内的重启记录
+	restartCount       int                                                                // 累计重启次数
+	toolHandlers       map[string]server.ToolHandlerFunc                                  // 工具处理函数，用于宏回放
+	macroLock          sync.Mutex                                                         // 宏录制锁
+	recording          *Macro                                                             // 正在录制的宏
+	startOnce          sync.Once                                                          // 首次调用工具时启动浏览器
+	startErr           error                                                              // 浏览器启动错误
+	opLock             sync.RWMutex                                                       // 工具调用持有读锁，切换无头模式持有写锁
+	ocr                ocr.Engine                                                         // OCR 引擎，为空时按配置创建
+	emulate            func(context.Context, ...chromedp.Action) error                    // 在调用所在标签页执行模拟覆盖，测试时可替换
+	emulationLock      sync.Mutex                                                         // 模拟覆盖状态锁
+	emulation          EmulationState                                                     // 地理位置、时区和语言覆盖
+	openTab            func(parent context.Context) (context.Context, context.CancelFunc) // 为会话打开标签页，测试时可替换
+	auth               authStore                                                          // HTTP 认证凭据和额外请求头
+	permissions        permissionStore                                                    // 本次浏览器运行中设置的权限覆盖
+	downloads          downloadTracker                                                    // 下载到 DataPath/downloads 的文件
+	netlog             networkLog                                                         // browser_network_enable 记录的请求
+	console            consoleLog                                                         // browser_console_start 记录的控制台日志
+	hars               harStore                                                           // 各标签页正在进行的 HAR 录制
+	blocking           blockStore                                                         // 请求拦截规则和统计
+	stopAutosave       context.CancelFunc                                                 // 停止定时保存会话快照
+	listenTarget       func(ctx context.Context, fn func(ev interface{}))                 // 监听标签页事件，测试时可替换
+	runner             Runner                                                             // 执行浏览器操作和脚本，测试时可替换
+	tabs               tabStore                                                           // browser_tab_new 打开的标签页
 }
 
 // NewBrowserServer creates a new BrowserServer instance with the given context and configuration.
 func NewBrowserServer(ctx context.Context) (abstract.Service, error) {
+	base, err := abstract.NewServiceBase(ctx, BrowserServerName)
+	if err != nil {
+		return nil, err
+	}
+
 	// 获取浏览器配置
 	bc := NewBrowserConfig()
-	globalConf := ctx.Value(comm.MoLingConfigKey).(*config.MoLingConfig)
-	bc.BrowserDataPath = filepath.Join(globalConf.BasePath, BrowserDataPath)
-	bc.DataPath = filepath.Join(globalConf.BasePath, "data")
-
-	// 获取日志记录器
-	logger, ok := ctx.Value(comm.MoLingLoggerKey).(zerolog.Logger)
-	if !ok {
-		return nil, fmt.Errorf("BrowserServer: invalid logger type: %T", ctx.Value(comm.MoLingLoggerKey))
+	bc.BrowserDataPath = filepath.Join(base.MlConfig().BasePath, BrowserDataPath)
+	bc.DataPath = filepath.Join(base.MlConfig().BasePath, "data")
+	bc.AllowedUploadDirs = bc.DataPath
+	if headless, reason := defaultHeadless(runtime.GOOS, os.Getenv); headless {
+		bc.Headless = true
+		base.Logger.Info().Str("reason", reason).Msg("no display detected, the browser defaults to headless mode")
 	}
-	// 添加服务名称
-	loggerNameHook := zerolog.HookFunc(func(e *zerolog.Event, level zerolog.Level, msg string) {
-		e.Str("Service", string(BrowserServerName))
-	})
 
 	// 创建浏览器服务实例
 	bs := &BrowserServer{
-		MLService: abstract.NewMLService(ctx, logger.Hook(loggerNameHook), globalConf),
-		config:    bc,
+		MLService:    base,
+		config:       bc,
+		toolHandlers: make(map[string]server.ToolHandlerFunc),
 	}
+	bs.starter = bs.startBrowser
+	bs.emulate = bs.runEmulation
+	bs.openTab = bs.newTab
+	bs.listenTarget = chromedp.ListenTarget
+	bs.runner = chromedpRunner{}
 	if err := bs.InitResources(); err != nil {
 		return nil, err
 	}
 	return bs, nil
 }
 
-// Init initializes the browser server by creating a new context.
-func (bs *BrowserServer) Init() error {
-	// 初始化浏览器
-	if err := bs.initBrowser(bs.config.BrowserDataPath); err != nil {
-		return fmt.Errorf("failed to initialize browser: %v", err)
-	}
-
-	// 创建数据目录
-	if err := utils.CreateDirectory(bs.config.DataPath); err != nil {
-		return fmt.Errorf("failed to create data directory: %v", err)
-	}
-
-	// 创建浏览器上下文
-	opts := append(
-		chromedp.DefaultExecAllocatorOptions[:],                         // 默认浏览器配置
-		chromedp.UserAgent(bs.config.UserAgent),                         // 用户代理
-		chromedp.Flag("lang", bs.config.DefaultLanguage),                // 语言
-		chromedp.Flag("disable-blink-features", "AutomationControlled"), // 禁用自动化控制
-		chromedp.Flag("enable-automation", false),                       // 禁用自动化
-		chromedp.Flag("disable-features", "Translate"),                  // 禁用翻译
-		chromedp.Flag("hide-scrollbars", false),                         // 是否隐藏滚动条
-		chromedp.Flag("mute-audio", true),                               // 是否静音
-		chromedp.Flag("disable-infobars", true),                         // 禁用信息栏
-		chromedp.Flag("disable-extensions", true),                       // 禁用扩展
-		chromedp.Flag("CommandLineFlagSecurityWarningsEnabled", false),  // 禁用安全警告
-		chromedp.Flag("disable-notifications", true),                    // 禁用通知
-		chromedp.Flag("disable-dev-shm-usage", true),                    // 禁用dev-shm-usage
-		chromedp.Flag("autoplay-policy", "user-gesture-required"),       // 自动播放策略
-		chromedp.CombinedOutput(bs.Logger),                              // 输出日志
-		chromedp.WindowSize(1280, 800),                                  // 窗口大小 (1920, 1080), (1366, 768), (1440, 900), (1280, 800)
-		chromedp.UserDataDir(bs.config.BrowserDataPath),                 // 用户数据目录
-		chromedp.IgnoreCertErrors,                                       // 忽略证书错误
-	)
-
-	// 无头浏览器设置
-	if bs.config.Headless {
-		opts = append(opts, chromedp.Flag("headless", true))      // 无头模式
-		opts = append(opts, chromedp.Flag("disable-gpu", true))   // 禁用GPU
-		opts = append(opts, chromedp.Flag("disable-webgl", true)) // 禁用WebGL
-	}
-
-	bs.Context, bs.cancelAlloc = chromedp.NewExecAllocator(context.Background(), opts...)
-
-	bs.Context, bs.cancelChrome = chromedp.NewContext(bs.Context,
-		chromedp.WithErrorf(bs.Logger.Error().Msgf),
-		chromedp.WithDebugf(bs.Logger.Debug().Msgf),
-	)
-
+// RegisterTools registers the prompt and the tools of the browser. Chrome is not launched here,
+// see Start.
+func (bs *BrowserServer) RegisterTools() error {
 	// 添加浏览器prompt
 	pe := abstract.PromptEntry{
 		PromptVar: mcp.Prompt{
@@ -144,17 +139,33 @@ func (bs *BrowserServer) Init() error {
 	bs.AddPrompt(pe)
 
 	// 导航
-	bs.AddTool(mcp.NewTool(
+	bs.addTool(mcp.NewTool(
 		"browser_navigate",
-		mcp.WithDescription("Navigate to a URL"),
+		mcp.WithDescription("Navigate to a URL and wait for it to load, optionally until an element is visible. Returns the final URL and HTTP status, and reports cookie consent dialogs, CAPTCHAs and full-page overlays that cover the loaded page"),
 		mcp.WithString("url",
 			mcp.Description("URL to navigate to"),
 			mcp.Required(),
 		),
+		mcp.WithString("wait_for",
+			mcp.Description("CSS selector of an element to wait visible after the navigation"),
+		),
+		mcp.WithString("wait_until",
+			mcp.Description("Page state to wait for: load (default), domcontentloaded, or networkidle when no resource loaded for 500ms after load"),
+			mcp.Enum(WaitUntilLoad, WaitUntilDOMContentLoaded, WaitUntilNetworkIdle),
+		),
+		mcp.WithNumber("timeout_seconds",
+			mcp.Description("Timeout of the navigation and the wait conditions (default: url_timeout of the config)"),
+		),
 	), bs.handleNavigate)
 
+	// 页面信息
+	bs.addTool(mcp.NewTool(
+		"browser_page_info",
+		mcp.WithDescription("Get the current page info as JSON: url, title, ready_state, content_type, canonical_url, meta_description, og_title, language and frame_count. Read only and cheap, use it to confirm where you are before acting"),
+	), bs.handlePageInfo)
+
 	// 截图
-	bs.AddTool(mcp.NewTool(
+	bs.addTool(mcp.NewTool(
 		"browser_screenshot",
 		mcp.WithDescription("Take a screenshot of the current page or a specific element"),
 		mcp.WithString("name",
@@ -165,39 +176,92 @@ func (bs *BrowserServer) Init() error {
 			mcp.Description("CSS selector for element to screenshot"),
 		),
 		mcp.WithNumber("width",
-			mcp.Description("Width in pixels (default: 1700)"),
+			mcp.Description("Width in pixels (default: 1280)"),
 		),
 		mcp.WithNumber("height",
-			mcp.Description("Height in pixels (default: 1100)"),
+			mcp.Description("Height in pixels (default: 800)"),
+		),
+		mcp.WithString("format",
+			mcp.Description("Image format, the file is named after it (default: screenshot_format of the config)"),
+			mcp.Enum(ScreenshotFormatPNG, ScreenshotFormatJPEG, ScreenshotFormatWebP),
+		),
+		mcp.WithNumber("quality",
+			mcp.Description("JPEG and WebP quality, 1 to 100 (default: screenshot_quality of the config)"),
+		),
+		mcp.WithBoolean("ocr",
+			mcp.Description("Recognize the text of the screenshot with the configured OCR backend (default: false)"),
+		),
+		mcp.WithBoolean("with_boxes",
+			mcp.Description("Together with ocr, return the bounding box and confidence of every recognized word as JSON (default: false)"),
+		),
+		mcp.WithString("return_mode",
+			mcp.Description("How to return the screenshot: file saves it on the server and returns the path, inline returns the image itself, both does both (default: file). Remote clients that can't read the server's files should use inline"),
+			mcp.Enum(ScreenshotReturnFile, ScreenshotReturnInline, ScreenshotReturnBoth),
 		),
 	), bs.handleScreenshot)
 
 	// 点击
-	bs.AddTool(mcp.NewTool(
+	bs.addTool(mcp.NewTool(
 		"browser_click",
-		mcp.WithDescription("Click an element on the page"),
-		mcp.WithString("selector",
-			mcp.Description("CSS selector for element to click"),
-			mcp.Required(),
-		),
-	), bs.handleClick)
+		append([]mcp.ToolOption{
+			mcp.WithDescription("Click an element on the page, targeted by a CSS selector, its visible text, several candidate selectors or its accessible role and name. One of selector, selectors or aria is required"),
+			mcp.WithObject("aria",
+				mcp.Description("Target the element by accessibility role and name instead of a selector, e.g. {\"role\": \"button\", \"name\": \"Submit\"}"),
+				mcp.Properties(ariaTargetProperties),
+			),
+		}, selectorOptions("element to click", "its visible text")...)...,
+	), bs.withSelectorFallback(targetClickable, bs.handleClick))
 
 	// 填写
-	bs.AddTool(mcp.NewTool(
+	bs.addTool(mcp.NewTool(
 		"browser_fill",
-		mcp.WithDescription("Fill out an input field"),
+		append([]mcp.ToolOption{
+			mcp.WithDescription("Fill out an input field, targeted by a CSS selector, its label, several candidate selectors or its accessible role and name. One of selector, selectors or aria is required"),
+			mcp.WithObject("aria",
+				mcp.Description("Target the field by accessibility role and name instead of a selector, e.g. {\"role\": \"textbox\", \"name\": \"Email\"}"),
+				mcp.Properties(ariaTargetProperties),
+			),
+			mcp.WithString("value",
+				mcp.Description("Value to fill"),
+				mcp.Required(),
+			),
+			mcp.WithString("mask",
+				mcp.Description("When recording a macro, record the value as this {placeholder} parameter instead of the literal (e.g. password)"),
+			),
+		}, selectorOptions("input field", "its label, aria-label or placeholder")...)...,
+	), bs.withSelectorFallback(targetField, bs.handleFill))
+
+	// 无障碍树快照
+	bs.addTool(mcp.NewTool(
+		"browser_accessibility_snapshot",
+		mcp.WithDescription("Get a pruned accessibility tree of the current page as JSON (role, name, value, focusable, backend_node_id). Use the role and name with the aria argument of browser_click and browser_fill for robust targeting."),
+		mcp.WithNumber("max_depth",
+			mcp.Description("Maximum depth of the returned tree (default: 30)"),
+		),
+		mcp.WithNumber("max_nodes",
+			mcp.Description("Maximum number of returned nodes (default: 300)"),
+		),
+	), bs.handleAccessibilitySnapshot)
+
+	// 上传文件
+	bs.addTool(mcp.NewTool(
+		"browser_upload_file",
+		mcp.WithDescription("Upload local files into an <input type=file> element. The input does not need to be visible, so inputs hidden behind styled upload buttons work too. Files must be inside the allowed upload directories. Returns the file names the page sees."),
 		mcp.WithString("selector",
-			mcp.Description("CSS selector for input field"),
+			mcp.Description("CSS selector for the file input element"),
 			mcp.Required(),
 		),
-		mcp.WithString("value",
-			mcp.Description("Value to fill"),
-			mcp.Required(),
+		mcp.WithString("path",
+			mcp.Description("Absolute path of the file to upload"),
+		),
+		mcp.WithArray("paths",
+			mcp.Description("Absolute paths of several files to upload, the input must have the multiple attribute. Use either path or paths"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
 		),
-	), bs.handleFill)
+	), bs.handleUploadFile)
 
 	// 选择
-	bs.AddTool(mcp.NewTool(
+	bs.addTool(mcp.NewTool(
 		"browser_select",
 		mcp.WithDescription("Select an element on the page with Select tag"),
 		mcp.WithString("selector",
@@ -211,17 +275,73 @@ func (bs *BrowserServer) Init() error {
 	), bs.handleSelect)
 
 	// 悬停
-	bs.AddTool(mcp.NewTool(
+	bs.addTool(mcp.NewTool(
 		"browser_hover",
-		mcp.WithDescription("Hover an element on the page"),
-		mcp.WithString("selector",
-			mcp.Description("CSS selector for element to hover"),
+		append([]mcp.ToolOption{
+			mcp.WithDescription("Hover an element on the page, targeted by a CSS selector, its visible text or several candidate selectors. One of selector or selectors is required"),
+		}, selectorOptions("element to hover", "its visible text")...)...,
+	), bs.withSelectorFallback(targetClickable, bs.handleHover))
+
+	// 双击、右击和拖放
+	bs.addTool(mcp.NewTool(
+		"browser_dblclick",
+		append([]mcp.ToolOption{
+			mcp.WithDescription("Double-click an element with real mouse input, e.g. to open an item. Falls back to simulated events; the JSON result names the method used (cdp or js). One of selector or selectors is required"),
+		}, selectorOptions("element to double-click", "its visible text")...)...,
+	), bs.withSelectorFallback(targetClickable, bs.handleDblClick))
+	bs.addTool(mcp.NewTool(
+		"browser_right_click",
+		append([]mcp.ToolOption{
+			mcp.WithDescription("Right-click an element to open its context menu. The JSON result names the method used (cdp or js) and context_menu_handled, whether the page handled the contextmenu event with its own menu. One of selector or selectors is required"),
+		}, selectorOptions("element to right-click", "its visible text")...)...,
+	), bs.withSelectorFallback(targetClickable, bs.handleRightClick))
+	bs.addTool(mcp.NewTool(
+		"browser_drag_drop",
+		mcp.WithDescription("Drag an element and drop it onto another element or at an offset, e.g. to reorder a list or move a file. Uses real mouse input, and simulates HTML5 drag and drop when the page doesn't receive a drop. The JSON result names the method used (cdp, html5 or js)"),
+		mcp.WithString("source",
+			mcp.Description("CSS selector for the element to drag"),
 			mcp.Required(),
 		),
-	), bs.handleHover)
+		mcp.WithString("target",
+			mcp.Description("CSS selector for the element to drop onto. Use either target or offset_x and offset_y"),
+		),
+		mcp.WithNumber("offset_x",
+			mcp.Description("Horizontal distance in pixels from the center of the source to drop at"),
+		),
+		mcp.WithNumber("offset_y",
+			mcp.Description("Vertical distance in pixels from the center of the source to drop at"),
+		),
+		mcp.WithNumber("steps",
+			mcp.Description(fmt.Sprintf("Number of mouse moves between pressing and releasing the button (default: %d, max: %d)", defaultDragSteps, maxDragSteps)),
+		),
+	), bs.handleDragDrop)
+
+	// 获取文本
+	bs.addTool(mcp.NewTool(
+		"browser_get_text",
+		mcp.WithDescription("Get the visible text (innerText) of an element, or of the whole page when no selector is given. Use it to read page content instead of writing scripts for browser_evaluate"),
+		mcp.WithString("selector",
+			mcp.Description("CSS selector for the element (default: body)"),
+		),
+		mcp.WithNumber("max_length",
+			mcp.Description("Maximum number of characters to return, longer text is truncated (default: unlimited)"),
+		),
+	), bs.handleGetText)
+
+	// 获取 HTML
+	bs.addTool(mcp.NewTool(
+		"browser_get_html",
+		mcp.WithDescription("Get the outerHTML of the first element matching a selector, or of the whole document when no selector is given. Notes how many elements matched"),
+		mcp.WithString("selector",
+			mcp.Description("CSS selector for the element (default: html)"),
+		),
+		mcp.WithBoolean("strip_scripts",
+			mcp.Description("Remove <script> and <style> blocks from the HTML (default: false)"),
+		),
+	), bs.handleGetHTML)
 
 	// 执行
-	bs.AddTool(mcp.NewTool(
+	bs.addTool(mcp.NewTool(
 		"browser_evaluate",
 		mcp.WithDescription("Execute JavaScript in the browser console"),
 		mcp.WithString("script",
@@ -231,7 +351,7 @@ func (bs *BrowserServer) Init() error {
 	), bs.handleEvaluate)
 
 	// 调试
-	bs.AddTool(mcp.NewTool(
+	bs.addTool(mcp.NewTool(
 		"browser_debug_enable",
 		mcp.WithDescription("Enable JavaScript debugging"),
 		mcp.WithBoolean("enabled",
@@ -241,7 +361,7 @@ func (bs *BrowserServer) Init() error {
 	), bs.handleDebugEnable)
 
 	// 设置断点
-	bs.AddTool(mcp.NewTool(
+	bs.addTool(mcp.NewTool(
 		"browser_set_breakpoint",
 		mcp.WithDescription("Set a JavaScript breakpoint"),
 		mcp.WithString("url",
@@ -261,7 +381,7 @@ func (bs *BrowserServer) Init() error {
 	), bs.handleSetBreakpoint)
 
 	// 移除断点
-	bs.AddTool(mcp.NewTool(
+	bs.addTool(mcp.NewTool(
 		"browser_remove_breakpoint",
 		mcp.WithDescription("Remove a JavaScript breakpoint"),
 		mcp.WithString("breakpointId",
@@ -271,25 +391,163 @@ func (bs *BrowserServer) Init() error {
 	), bs.handleRemoveBreakpoint)
 
 	// 暂停
-	bs.AddTool(mcp.NewTool(
+	bs.addTool(mcp.NewTool(
 		"browser_pause",
 		mcp.WithDescription("Pause JavaScript execution"),
 	), bs.handlePause)
 
 	// 恢复
-	bs.AddTool(mcp.NewTool(
+	bs.addTool(mcp.NewTool(
 		"browser_resume",
 		mcp.WithDescription("Resume JavaScript execution"),
 	), bs.handleResume)
 
 	// 获取调用栈
-	bs.AddTool(mcp.NewTool(
+	bs.addTool(mcp.NewTool(
 		"browser_get_callstack",
 		mcp.WithDescription("Get current call stack when paused"),
 	), bs.handleGetCallstack)
+
+	// 地理位置、时区和语言覆盖
+	bs.addEmulationTools()
+
+	// 宏录制与回放
+	bs.addMacroTools()
+	bs.addHeadlessTool()
+	bs.addClearDataTool()
+	bs.addAnnotateTool()
+	bs.addAuthTools()
+	bs.addObstructionTool()
+	bs.addPermissionTools()
+	bs.addHARTools()
+	bs.addExtractListTool()
+	bs.addExtractMetadataTool()
+	bs.addBlockingTools()
+	bs.addSnapshotTools()
+	bs.addTabTools()
+	bs.addCookieTools()
+	bs.addWaitForTool()
+	bs.addHistoryTools()
+	bs.addNetworkLogTools()
+	bs.addDownloadTool()
+	bs.addScrollTool()
+	bs.addQueryAllTool()
+	bs.addPressKeyTool()
+	bs.addConsoleTools()
+	bs.addDeviceTools()
 	return nil
 }
 
+// Start creates the data directory and launches Chrome. The server calls it before every tool
+// call, Chrome is launched only once, on the first call.
+func (bs *BrowserServer) Start() error {
+	bs.startOnce.Do(func() {
+		if err := utils.CreateDirectory(bs.cfg().DataPath); err != nil {
+			bs.startErr = fmt.Errorf("failed to create data directory: %v", err)
+			return
+		}
+		bs.startErr = bs.starter()
+		if bs.startErr == nil {
+			bs.resetPermissions()
+			bs.applyDefaultBlocking()
+			bs.applyProxyAuth()
+			bs.enableDownloads()
+			bs.restoreOnStart()
+		}
+	})
+	return bs.startErr
+}
+
+// startBrowser creates the allocator and browser contexts. It is used by Start and when the
+// browser is restarted after a crash, the stale SingletonLock is cleaned up on every call.
+func (bs *BrowserServer) startBrowser() error {
+	if bs.cfg().remote() {
+		// 连接已运行的浏览器，启动参数和用户数据目录由该浏览器自己决定
+		if ignored := bs.cfg().remoteIgnoredSettings(); len(ignored) > 0 {
+			bs.Logger.Warn().Strs("settings", ignored).Msg("connected to a remote browser, launch-only settings are ignored")
+		}
+	} else if err := bs.initBrowser(bs.cfg().BrowserDataPath); err != nil {
+		// 初始化浏览器
+		return fmt.Errorf("failed to initialize browser: %v", err)
+	}
+
+	// 创建浏览器上下文
+	allocCtx, cancelAlloc := bs.newAllocator(context.Background())
+	browserCtx, cancelChrome := chromedp.NewContext(allocCtx,
+		chromedp.WithErrorf(bs.Logger.Error().Msgf),
+		chromedp.WithDebugf(bs.Logger.Debug().Msgf),
+	)
+	bs.listenAuth(browserCtx)
+	chromedp.ListenTarget(browserCtx, bs.downloads.handleEvent)
+
+	bs.browserLock.Lock()
+	bs.Context, bs.cancelAlloc, bs.cancelChrome = browserCtx, cancelAlloc, cancelChrome
+	bs.browserLock.Unlock()
+	return nil
+}
+
+// allocatorOptions returns the Chrome command line options of the config.
+func (bs *BrowserServer) allocatorOptions() []chromedp.ExecAllocatorOption {
+	opts := append(
+		chromedp.DefaultExecAllocatorOptions[:],                         // 默认浏览器配置
+		chromedp.UserAgent(bs.cfg().UserAgent),                          // 用户代理
+		chromedp.Flag("lang", bs.cfg().DefaultLanguage),                 // 语言
+		chromedp.Flag("disable-blink-features", "AutomationControlled"), // 禁用自动化控制
+		chromedp.Flag("enable-automation", false),                       // 禁用自动化
+		chromedp.Flag("disable-features", "Translate"),                  // 禁用翻译
+		chromedp.Flag("hide-scrollbars", false),                         // 是否隐藏滚动条
+		chromedp.Flag("mute-audio", true),                               // 是否静音
+		chromedp.Flag("disable-infobars", true),                         // 禁用信息栏
+		chromedp.Flag("disable-extensions", true),                       // 禁用扩展
+		chromedp.Flag("CommandLineFlagSecurityWarningsEnabled", false),  // 禁用安全警告
+		chromedp.Flag("disable-notifications", true),                    // 禁用通知
+		chromedp.Flag("disable-dev-shm-usage", true),                    // 禁用dev-shm-usage
+		chromedp.Flag("autoplay-policy", "user-gesture-required"),       // 自动播放策略
+		chromedp.CombinedOutput(bs.Logger),                              // 输出日志
+		chromedp.WindowSize(1280, 800),                                  // 窗口大小 (1920, 1080), (1366, 768), (1440, 900), (1280, 800)
+		chromedp.UserDataDir(bs.cfg().BrowserDataPath),                  // 用户数据目录
+		chromedp.IgnoreCertErrors,                                       // 忽略证书错误
+		chromedp.ModifyCmdFunc(chromeCmdOptions),                        // 独立进程组，关闭超时后整组结束
+	)
+
+	// 与 debug-bundle 使用相同的 Chrome 查找逻辑，未找到时由 chromedp 回退到默认路径
+	if execPath, err := FindChrome(); err == nil {
+		opts = append(opts, chromedp.ExecPath(execPath))
+	}
+
+	// 无头浏览器设置，DefaultExecAllocatorOptions 默认开启 headless，需显式覆盖
+	opts = append(opts, chromedp.Flag("headless", bs.cfg().Headless)) // 无头模式
+	if bs.cfg().Headless {
+		opts = append(opts, chromedp.Flag("disable-gpu", true))   // 禁用GPU
+		opts = append(opts, chromedp.Flag("disable-webgl", true)) // 禁用WebGL
+	}
+
+	// 代理，凭据不能放在命令行中，由 listenAuth 响应代理的认证请求
+	if bs.cfg().proxy != nil {
+		opts = append(opts, chromedp.ProxyServer(bs.cfg().proxy.server))
+		if bs.cfg().noProxyList != "" {
+			opts = append(opts, chromedp.Flag("proxy-bypass-list", bs.cfg().noProxyList))
+		}
+	}
+	return opts
+}
+
+// stopBrowser cancels the browser and allocator contexts, which terminates the Chrome process or
+// detaches from a remote browser.
+func (bs *BrowserServer) stopBrowser() {
+	bs.browserLock.Lock()
+	cancelChrome, cancelAlloc := bs.cancelChrome, bs.cancelAlloc
+	// 停止后的浏览器属于上一代，等待重启的调用据此判断是否已被其他调用重启
+	bs.restartGen++
+	bs.browserLock.Unlock()
+	if cancelChrome != nil {
+		cancelChrome()
+	}
+	if cancelAlloc != nil {
+		cancelAlloc()
+	}
+}
+
 // initBrowser 初始化浏览器
 func (bs *BrowserServer) initBrowser(userDataDir string) error {
 	// 检查用户数据目录是否存在
@@ -330,7 +588,7 @@ func (bs *BrowserServer) handlePrompt(ctx context.Context, request mcp.GetPrompt
 				Role: mcp.RoleUser,
 				Content: mcp.TextContent{
 					Type: "text",
-					Text: bs.config.prompt,
+					Text: bs.cfg().prompt,
 				},
 			},
 		},
@@ -345,11 +603,21 @@ func (bs *BrowserServer) handleNavigate(ctx context.Context, request mcp.CallToo
 		return nil, fmt.Errorf("url must be a string")
 	}
 
-	err := chromedp.Run(bs.Context, chromedp.Navigate(url))
+	opts, err := parseNavigateOptions(args, url, bs.cfg().URLTimeout)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to navigate: %v", err)), nil
+		return bs.toolError(ctx, request, comm.ArgumentError(err)), nil
 	}
-	return mcp.NewToolResultText(fmt.Sprintf("Navigated to %s", url)), nil
+
+	info, err := bs.navigate(bs.pageContext(ctx), opts)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrorCodeOf(err), err.Error(), "")), nil
+	}
+	message := navigateMessage(url, info)
+	// 同意 Cookie 的弹窗和人机验证会让后续操作找不到元素，导航后立即报告
+	if obstruction := bs.navigationObstruction(ctx); obstruction != "" {
+		return mcp.NewToolResultText(fmt.Sprintf("%s\nThe page is obstructed: %s", message, obstruction)), nil
+	}
+	return mcp.NewToolResultText(message), nil
 }
 
 // handleScreenshot handles the screenshot action.
@@ -357,16 +625,29 @@ func (bs *BrowserServer) handleScreenshot(ctx context.Context, request mcp.CallT
 	args := request.GetArguments()
 	name, ok := args["name"].(string)
 	if !ok {
-		return mcp.NewToolResultError("name must be a string"), nil
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInvalidArgument, "name must be a string", "")), nil
+	}
+	if _, err := parseReturnMode(args); err != nil {
+		return bs.toolError(ctx, request, comm.ArgumentError(err)), nil
+	}
+	opts, err := parseScreenshotOptions(args, bs.cfg())
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ArgumentError(err)), nil
 	}
 	selector, _ := args["selector"].(string)
-	width, _ := args["width"].(int)
-	height, _ := args["height"].(int)
-	if width == 0 {
-		width = 1280
+	width, err := intArg(args, "width", 1280)
+	if err == nil && width < 1 {
+		err = errors.New("width must be a positive integer")
 	}
-	if height == 0 {
-		height = 800
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ArgumentError(err)), nil
+	}
+	height, err := intArg(args, "height", 800)
+	if err == nil && height < 1 {
+		err = errors.New("height must be a positive integer")
+	}
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ArgumentError(err)), nil
 	}
 
 	// 记录尝试截图操作
@@ -375,64 +656,73 @@ func (bs *BrowserServer) handleScreenshot(ctx context.Context, request mcp.CallT
 		Str("selector", selector).
 		Int("width", width).
 		Int("height", height).
+		Str("format", opts.Format).
+		Int("quality", opts.Quality).
 		Msg("尝试截取屏幕截图")
 
 	// 设置更长的超时时间
-	timeoutDuration := time.Duration(bs.config.SelectorQueryTimeout*3) * time.Second
-	runCtx, cancelFunc := context.WithTimeout(bs.Context, timeoutDuration)
+	timeoutDuration := time.Duration(bs.cfg().SelectorQueryTimeout*3) * time.Second
+	runCtx, cancelFunc := context.WithTimeout(bs.pageContext(ctx), timeoutDuration)
 	defer cancelFunc()
 
 	var buf []byte
-	var err error
 
 	// 根据是否提供选择器决定截取全屏还是特定元素
 	if selector == "" {
 		// 全屏截图
-		err = chromedp.Run(runCtx,
+		actions := []chromedp.Action{
 			chromedp.EmulateViewport(int64(width), int64(height)), // 设置视口大小
-			chromedp.FullScreenshot(&buf, 90),                     // 90% 质量
-		)
+			captureAction(captureParams(opts, nil), &buf),
+		}
+		// 模拟设备时未指定尺寸则保留设备的视口
+		if args["width"] == nil && args["height"] == nil && bs.emulatedDevice() != nil {
+			actions = actions[1:]
+		}
+		err = bs.runner.Run(runCtx, actions...)
 	} else {
-		// 元素截图，确保使用相同的上下文
-		err = chromedp.Run(runCtx,
-			chromedp.WaitVisible(selector), // 等待元素可见
-			chromedp.Screenshot(selector, &buf, chromedp.NodeVisible),
-		)
-	}
-
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("截图失败: %v", err)), nil
+		// 元素截图，按元素在页面中的位置裁剪，确保使用相同的上下文
+		err = bs.runner.Run(runCtx, chromedp.WaitVisible(selector)) // 等待元素可见
+		var clip *page.Viewport
+		if err == nil {
+			clip, err = bs.elementClip(runCtx, selector)
+		}
+		if err == nil {
+			err = bs.runner.Run(runCtx, captureAction(captureParams(opts, clip), &buf))
+		}
 	}
 
-	// 使用随机数确保文件名唯一
-	newName := filepath.Join(bs.config.DataPath, fmt.Sprintf("%s_%d.png", strings.TrimRight(name, ".png"), rand.Int()))
-	err = os.WriteFile(newName, buf, 0644)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("保存截图失败: %v", err)), nil
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to take the screenshot", err)), nil
 	}
 
-	bs.Logger.Debug().Str("path", newName).Msg("成功保存截图")
-	return mcp.NewToolResultText(fmt.Sprintf("截图已保存至: %s", newName)), nil
+	return bs.screenshotResult(ctx, request, name, buf), nil
 }
 
 // handleClick handles the click action on a specified element.
 func (bs *BrowserServer) handleClick(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args := request.GetArguments()
+	// 优先通过无障碍角色和名称定位元素
+	if target, ok, err := parseAriaTarget(args["aria"]); ok {
+		if err != nil {
+			return bs.toolError(ctx, request, comm.ArgumentError(err)), nil
+		}
+		return bs.clickAria(ctx, request, target), nil
+	}
 	selector, ok := args["selector"].(string)
 	if !ok {
-		return mcp.NewToolResultError(fmt.Sprintf("selector must be a string:%v", selector)), nil
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("selector must be a string: %v", selector), "")), nil
 	}
 
 	// 记录尝试点击的元素选择器
 	bs.Logger.Debug().Str("selector", selector).Msg("尝试点击元素")
 
 	// 设置更长的超时时间，以确保有足够时间执行操作
-	timeoutDuration := time.Duration(bs.config.SelectorQueryTimeout*3) * time.Second
-	runCtx, cancelFunc := context.WithTimeout(bs.Context, timeoutDuration)
+	timeoutDuration := time.Duration(bs.cfg().SelectorQueryTimeout*3) * time.Second
+	runCtx, cancelFunc := context.WithTimeout(bs.pageContext(ctx), timeoutDuration)
 	defer cancelFunc()
 
 	// 先尝试合并所有操作，避免分割操作可能引起的上下文问题
-	err := chromedp.Run(runCtx,
+	err := bs.runner.Run(runCtx,
 		chromedp.WaitReady("body"),     // 等待页面主体加载完成
 		chromedp.WaitVisible(selector), // 等待目标元素可见
 		chromedp.Click(selector),       // 点击目标元素
@@ -447,7 +737,7 @@ func (bs *BrowserServer) handleClick(ctx context.Context, request mcp.CallToolRe
 			(function() {
 				try {
 					const el = document.querySelector(%s);
-					if (!el) return { success: false, error: "元素不存在" };
+					if (!el) return { success: false, error: "element not found" };
 					
 					// 尝试点击元素
 					el.click();
@@ -461,19 +751,19 @@ func (bs *BrowserServer) handleClick(ctx context.Context, request mcp.CallToolRe
 
 		// 使用结构化结果
 		var clickResult map[string]interface{}
-		err = chromedp.Run(runCtx, chromedp.Evaluate(jsClick, &clickResult))
+		err = bs.runner.Evaluate(runCtx, jsClick, &clickResult)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Errorf("无法执行点击脚本: %v", err).Error()), nil
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to run the click script", err)), nil
 		}
 
 		// 检查脚本执行结果
 		success, ok := clickResult["success"].(bool)
 		if !ok || !success {
-			errorMsg := "未知错误"
+			errorMsg := "unknown error"
 			if errMsg, hasErr := clickResult["error"].(string); hasErr {
 				errorMsg = errMsg
 			}
-			return mcp.NewToolResultError(fmt.Sprintf("点击失败: %s", errorMsg)), nil
+			return bs.toolError(ctx, request, elementScriptError("click", errorMsg)), nil
 		}
 
 		bs.Logger.Debug().Str("selector", selector).Msg("通过JavaScript成功点击元素")
@@ -487,26 +777,38 @@ func (bs *BrowserServer) handleClick(ctx context.Context, request mcp.CallToolRe
 // handleFill handles the fill action on a specified input field.
 func (bs *BrowserServer) handleFill(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args := request.GetArguments()
+	// 优先通过无障碍角色和名称定位元素
+	if target, ok, err := parseAriaTarget(args["aria"]); ok {
+		if err != nil {
+			return bs.toolError(ctx, request, comm.ArgumentError(err)), nil
+		}
+		value, ok := args["value"].(string)
+		if !ok {
+			return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("value must be a string: %v", args["value"]), "")), nil
+		}
+		return bs.fillAria(ctx, request, target, value), nil
+	}
+
 	selector, ok := args["selector"].(string)
 	if !ok {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to fill selector:%v", args["selector"])), nil
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("selector must be a string: %v", args["selector"]), "")), nil
 	}
 
 	value, ok := args["value"].(string)
 	if !ok {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to fill input field: %v, selector:%v", args["value"], selector)), nil
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("value must be a string: %v, selector: %s", args["value"], selector), "")), nil
 	}
 
 	// 记录尝试填写的输入字段
 	bs.Logger.Debug().Str("selector", selector).Str("value", value).Msg("尝试填写输入字段")
 
 	// 设置更长的超时时间
-	timeoutDuration := time.Duration(bs.config.SelectorQueryTimeout*3) * time.Second
-	runCtx, cancelFunc := context.WithTimeout(bs.Context, timeoutDuration)
+	timeoutDuration := time.Duration(bs.cfg().SelectorQueryTimeout*3) * time.Second
+	runCtx, cancelFunc := context.WithTimeout(bs.pageContext(ctx), timeoutDuration)
 	defer cancelFunc()
 
 	// 合并操作：等待元素可见并填写内容
-	err := chromedp.Run(runCtx,
+	err := bs.runner.Run(runCtx,
 		chromedp.WaitVisible(selector),     // 等待输入字段可见
 		chromedp.Clear(selector),           // 清除现有内容
 		chromedp.SendKeys(selector, value), // 输入新内容
@@ -521,7 +823,7 @@ func (bs *BrowserServer) handleFill(ctx context.Context, request mcp.CallToolReq
 			(function() {
 				try {
 					const el = document.querySelector(%s);
-					if (!el) return { success: false, error: "元素不存在" };
+					if (!el) return { success: false, error: "element not found" };
 					
 					// 设置值，使用安全处理过的字符串
 					el.value = %s;
@@ -539,19 +841,19 @@ func (bs *BrowserServer) handleFill(ctx context.Context, request mcp.CallToolReq
 
 		// 使用更复杂的结果对象来接收信息
 		var fillResult map[string]interface{}
-		err = chromedp.Run(runCtx, chromedp.Evaluate(jsFill, &fillResult))
+		err = bs.runner.Evaluate(runCtx, jsFill, &fillResult)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Errorf("无法执行填写脚本: %v", err).Error()), nil
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to run the fill script", err)), nil
 		}
 
 		// 检查脚本执行结果
 		success, ok := fillResult["success"].(bool)
 		if !ok || !success {
-			errorMsg := "未知错误"
+			errorMsg := "unknown error"
 			if errMsg, hasErr := fillResult["error"].(string); hasErr {
 				errorMsg = errMsg
 			}
-			return mcp.NewToolResultError(fmt.Sprintf("填写失败: %s", errorMsg)), nil
+			return bs.toolError(ctx, request, elementScriptError("fill", errorMsg)), nil
 		}
 
 		bs.Logger.Debug().Str("selector", selector).Msg("通过JavaScript成功填写输入字段")
@@ -562,6 +864,32 @@ func (bs *BrowserServer) handleFill(ctx context.Context, request mcp.CallToolReq
 	return mcp.NewToolResultText(fmt.Sprintf("填写了输入字段 %s，值为 %s", selector, value)), nil
 }
 
+// intArg returns the integer argument key, def when it is missing. JSON numbers of the MCP
+// arguments arrive as float64, int and json.Number are accepted too; fractions are an error.
+func intArg(args map[string]interface{}, key string, def int) (int, error) {
+	switch v := args[key].(type) {
+	case nil:
+		return def, nil
+	case float64:
+		if v != math.Trunc(v) || math.IsInf(v, 0) {
+			return 0, fmt.Errorf("%s must be an integer", key)
+		}
+		return int(v), nil
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return 0, fmt.Errorf("%s must be an integer", key)
+		}
+		return int(n), nil
+	default:
+		return 0, fmt.Errorf("%s must be an integer", key)
+	}
+}
+
 // 安全处理JSON编码的辅助函数
 func safeJSONString(s string) string {
 	bytes, err := json.Marshal(s)
@@ -575,23 +903,23 @@ func (bs *BrowserServer) handleSelect(ctx context.Context, request mcp.CallToolR
 	args := request.GetArguments()
 	selector, ok := args["selector"].(string)
 	if !ok {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to select selector:%v", args["selector"])), nil
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("selector must be a string: %v", args["selector"]), "")), nil
 	}
 	value, ok := args["value"].(string)
 	if !ok {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to select value:%v", args["value"])), nil
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("value must be a string: %v", args["value"]), "")), nil
 	}
 
 	// 记录尝试选择的下拉菜单和值
 	bs.Logger.Debug().Str("selector", selector).Str("value", value).Msg("尝试设置下拉菜单选项")
 
 	// 设置更长的超时时间
-	timeoutDuration := time.Duration(bs.config.SelectorQueryTimeout*3) * time.Second
-	runCtx, cancelFunc := context.WithTimeout(bs.Context, timeoutDuration)
+	timeoutDuration := time.Duration(bs.cfg().SelectorQueryTimeout*3) * time.Second
+	runCtx, cancelFunc := context.WithTimeout(bs.pageContext(ctx), timeoutDuration)
 	defer cancelFunc()
 
 	// 合并操作：等待元素可见并设置值
-	err := chromedp.Run(runCtx,
+	err := bs.runner.Run(runCtx,
 		chromedp.WaitVisible(selector),     // 等待选择器可见
 		chromedp.SetValue(selector, value), // 设置选择器的值
 	)
@@ -605,7 +933,7 @@ func (bs *BrowserServer) handleSelect(ctx context.Context, request mcp.CallToolR
 			(function() {
 				try {
 					const selectEl = document.querySelector(%s);
-					if (!selectEl) return { success: false, error: "选择器元素不存在" };
+					if (!selectEl) return { success: false, error: "select element not found" };
 					
 					// 直接设置值
 					selectEl.value = %s;
@@ -616,7 +944,7 @@ func (bs *BrowserServer) handleSelect(ctx context.Context, request mcp.CallToolR
 					
 					// 检查是否设置成功
 					if (selectEl.value !== %s) {
-						return { success: false, error: "无法设置选择器值，可能没有匹配的选项" };
+						return { success: false, error: "failed to set the value, no option matches it" };
 					}
 					
 					return { success: true };
@@ -628,19 +956,19 @@ func (bs *BrowserServer) handleSelect(ctx context.Context, request mcp.CallToolR
 
 		// 使用结构化结果
 		var selectResult map[string]interface{}
-		err = chromedp.Run(runCtx, chromedp.Evaluate(jsSelect, &selectResult))
+		err = bs.runner.Evaluate(runCtx, jsSelect, &selectResult)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Errorf("无法执行选择脚本: %v", err).Error()), nil
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to run the select script", err)), nil
 		}
 
 		// 检查脚本执行结果
 		success, ok := selectResult["success"].(bool)
 		if !ok || !success {
-			errorMsg := "未知错误"
+			errorMsg := "unknown error"
 			if errMsg, hasErr := selectResult["error"].(string); hasErr {
 				errorMsg = errMsg
 			}
-			return mcp.NewToolResultError(fmt.Sprintf("选择失败: %s", errorMsg)), nil
+			return bs.toolError(ctx, request, elementScriptError("select", errorMsg)), nil
 		}
 
 		bs.Logger.Debug().Str("selector", selector).Msg("通过JavaScript成功设置选择器")
@@ -656,20 +984,20 @@ func (bs *BrowserServer) handleHover(ctx context.Context, request mcp.CallToolRe
 	args := request.GetArguments()
 	selector, ok := args["selector"].(string)
 	if !ok {
-		return mcp.NewToolResultError(fmt.Sprintf("selector must be a string:%v", selector)), nil
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("selector must be a string: %v", selector), "")), nil
 	}
 
 	// 记录尝试悬停的元素
 	bs.Logger.Debug().Str("selector", selector).Msg("尝试悬停在元素上")
 
 	// 设置更长的超时时间
-	timeoutDuration := time.Duration(bs.config.SelectorQueryTimeout*3) * time.Second
-	runCtx, cancelFunc := context.WithTimeout(bs.Context, timeoutDuration)
+	timeoutDuration := time.Duration(bs.cfg().SelectorQueryTimeout*3) * time.Second
+	runCtx, cancelFunc := context.WithTimeout(bs.pageContext(ctx), timeoutDuration)
 	defer cancelFunc()
 
 	// 合并操作：等待元素可见并悬停
 	var res bool
-	err := chromedp.Run(runCtx,
+	err := bs.runner.Run(runCtx,
 		chromedp.WaitVisible(selector), // 等待元素可见
 		chromedp.Evaluate(`
 			(function() {
@@ -691,7 +1019,7 @@ func (bs *BrowserServer) handleHover(ctx context.Context, request mcp.CallToolRe
 			(function() {
 				try {
 					const el = document.querySelector(%s);
-					if (!el) return { success: false, error: "元素不存在" };
+					if (!el) return { success: false, error: "element not found" };
 					
 					// 尝试模拟完整的鼠标悬停事件序列
 					['mouseenter', 'mouseover', 'mousemove'].forEach(type => {
@@ -711,19 +1039,19 @@ func (bs *BrowserServer) handleHover(ctx context.Context, request mcp.CallToolRe
 
 		// 使用结构化结果
 		var hoverResult map[string]interface{}
-		err = chromedp.Run(runCtx, chromedp.Evaluate(jsHover, &hoverResult))
+		err = bs.runner.Evaluate(runCtx, jsHover, &hoverResult)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Errorf("无法执行悬停脚本: %v", err).Error()), nil
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to run the hover script", err)), nil
 		}
 
 		// 检查脚本执行结果
 		success, ok := hoverResult["success"].(bool)
 		if !ok || !success {
-			errorMsg := "未知错误"
+			errorMsg := "unknown error"
 			if errMsg, hasErr := hoverResult["error"].(string); hasErr {
 				errorMsg = errMsg
 			}
-			return mcp.NewToolResultError(fmt.Sprintf("悬停失败: %s", errorMsg)), nil
+			return bs.toolError(ctx, request, elementScriptError("hover over", errorMsg)), nil
 		}
 
 		bs.Logger.Debug().Str("selector", selector).Msg("通过JavaScript成功悬停在元素上")
@@ -734,19 +1062,97 @@ func (bs *BrowserServer) handleHover(ctx context.Context, request mcp.CallToolRe
 	return mcp.NewToolResultText(fmt.Sprintf("悬停在了元素 %s 上，结果:%t", selector, res)), nil
 }
 
+// handleGetText returns the trimmed visible text of an element or of the page body.
+func (bs *BrowserServer) handleGetText(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	selector, _ := args["selector"].(string)
+	if selector = strings.TrimSpace(selector); selector == "" {
+		selector = "body"
+	}
+	maxLength := 0
+	if v, ok := args["max_length"]; ok {
+		n, ok := v.(float64)
+		if !ok || n < 1 {
+			return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInvalidArgument, "max_length must be a positive number", "")), nil
+		}
+		maxLength = int(n)
+	}
+
+	runCtx, cancelFunc := context.WithTimeout(bs.pageContext(ctx), time.Duration(bs.cfg().SelectorQueryTimeout)*time.Second)
+	defer cancelFunc()
+	var text string
+	if err := bs.runner.Run(runCtx, chromedp.Text(selector, &text, chromedp.ByQuery)); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeNotFound, fmt.Sprintf("no element matches selector %s within %d seconds", selector, bs.cfg().SelectorQueryTimeout), "")), nil
+		}
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr(fmt.Sprintf("failed to get text of %s", selector), err)), nil
+	}
+	return mcp.NewToolResultText(truncateText(strings.TrimSpace(text), maxLength)), nil
+}
+
+// scriptStylePattern matches <script> and <style> blocks with their content.
+var scriptStylePattern = regexp.MustCompile(`(?is)<script\b[^>]*>.*?</script\s*>|<style\b[^>]*>.*?</style\s*>`)
+
+// stripScripts removes the <script> and <style> blocks of html.
+func stripScripts(html string) string {
+	return scriptStylePattern.ReplaceAllString(html, "")
+}
+
+// handleGetHTML returns the outerHTML of the first element matching the selector.
+func (bs *BrowserServer) handleGetHTML(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	selector, _ := args["selector"].(string)
+	if selector = strings.TrimSpace(selector); selector == "" {
+		selector = "html"
+	}
+	strip, _ := args["strip_scripts"].(bool)
+
+	runCtx, cancelFunc := context.WithTimeout(bs.pageContext(ctx), time.Duration(bs.cfg().SelectorQueryTimeout)*time.Second)
+	defer cancelFunc()
+	var html string
+	if err := bs.runner.Run(runCtx, chromedp.OuterHTML(selector, &html, chromedp.ByQuery)); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeNotFound, fmt.Sprintf("no element matches selector %s within %d seconds", selector, bs.cfg().SelectorQueryTimeout), "")), nil
+		}
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr(fmt.Sprintf("failed to get html of %s", selector), err)), nil
+	}
+	if strip {
+		html = stripScripts(html)
+	}
+	// 只返回第一个匹配的元素，匹配数只是提示，获取失败不影响结果
+	var count int
+	if err := bs.runner.Evaluate(runCtx, fmt.Sprintf(`document.querySelectorAll(%s).length`, safeJSONString(selector)), &count); err != nil {
+		bs.Logger.Debug().Err(err).Str("selector", selector).Msg("failed to count matching elements")
+	}
+	if count > 1 {
+		html = fmt.Sprintf("<!-- first of %d elements matching %s -->\n%s", count, selector, html)
+	}
+	return mcp.NewToolResultText(html), nil
+}
+
+// truncateText keeps the first maxLength characters of text and notes how much was cut, 0 means
+// unlimited.
+func truncateText(text string, maxLength int) string {
+	if maxLength <= 0 || utf8.RuneCountInString(text) <= maxLength {
+		return text
+	}
+	runes := []rune(text)
+	return fmt.Sprintf("%s\n... (truncated, %d of %d characters)", string(runes[:maxLength]), maxLength, len(runes))
+}
+
 func (bs *BrowserServer) handleEvaluate(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args := request.GetArguments()
 	script, ok := args["script"].(string)
 	if !ok {
-		return mcp.NewToolResultError("script must be a string"), nil
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInvalidArgument, "script must be a string", "")), nil
 	}
 
 	// 记录尝试执行的脚本
 	bs.Logger.Debug().Str("script", script).Msg("尝试执行JavaScript脚本")
 
 	// 设置更长的超时时间
-	timeoutDuration := time.Duration(bs.config.SelectorQueryTimeout*2) * time.Second
-	runCtx, cancelFunc := context.WithTimeout(bs.Context, timeoutDuration)
+	timeoutDuration := time.Duration(bs.cfg().SelectorQueryTimeout*2) * time.Second
+	runCtx, cancelFunc := context.WithTimeout(bs.pageContext(ctx), timeoutDuration)
 	defer cancelFunc()
 
 	// 检测脚本是否为简单的DOM属性访问(如querySelector().href)
@@ -768,9 +1174,9 @@ func (bs *BrowserServer) handleEvaluate(ctx context.Context, request mcp.CallToo
 		`, script)
 
 		var result interface{}
-		err := chromedp.Run(runCtx, chromedp.Evaluate(safeScript, &result))
+		err := bs.runner.Evaluate(runCtx, safeScript, &result)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Errorf("执行安全包装脚本失败: %v", err).Error()), nil
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to run the wrapped script", err)), nil
 		}
 
 		// 处理结果
@@ -797,9 +1203,9 @@ func (bs *BrowserServer) handleEvaluate(ctx context.Context, request mcp.CallToo
 						})()
 					`, safeAccessScript)
 
-					err := chromedp.Run(runCtx, chromedp.Evaluate(finalScript, &result))
+					err := bs.runner.Evaluate(runCtx, finalScript, &result)
 					if err != nil {
-						return mcp.NewToolResultError(fmt.Errorf("执行可选链脚本失败: %v", err).Error()), nil
+						return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to run the optional chaining script", err)), nil
 					}
 
 					// 再次检查结果
@@ -813,7 +1219,7 @@ func (bs *BrowserServer) handleEvaluate(ctx context.Context, request mcp.CallToo
 									return mcp.NewToolResultText(fmt.Sprintf("脚本执行成功，结果: %v", actualResult)), nil
 								}
 							} else if errorMsg, hasError := resultMap["error"].(string); hasError {
-								return mcp.NewToolResultError(fmt.Sprintf("脚本执行遇到错误(可选链): %s", errorMsg)), nil
+								return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInternal, "the script threw an error (optional chaining)", errorMsg)), nil
 							}
 						}
 					}
@@ -852,7 +1258,7 @@ func (bs *BrowserServer) handleEvaluate(ctx context.Context, request mcp.CallToo
 				// 先检查元素是否存在
 				var exists bool
 				checkScript := fmt.Sprintf(`document.querySelector(%s) !== null`, safeJSONString(selector))
-				err := chromedp.Run(runCtx, chromedp.Evaluate(checkScript, &exists))
+				err := bs.runner.Evaluate(runCtx, checkScript, &exists)
 
 				if err != nil {
 					bs.Logger.Warn().Err(err).Str("selector", selector).Msg("检查元素存在性时出错，继续执行")
@@ -879,7 +1285,7 @@ func (bs *BrowserServer) handleEvaluate(ctx context.Context, request mcp.CallToo
 
 					// 获取页面上的相似元素
 					if suggestionsScript != "" {
-						err = chromedp.Run(runCtx, chromedp.Evaluate(suggestionsScript, &suggestions))
+						err = bs.runner.Evaluate(runCtx, suggestionsScript, &suggestions)
 						if err == nil && len(suggestions) > 0 {
 							suggestionStr, _ := json.Marshal(suggestions)
 							bs.Logger.Warn().
@@ -939,7 +1345,7 @@ func (bs *BrowserServer) handleEvaluate(ctx context.Context, request mcp.CallToo
 
 	// 执行脚本
 	var result interface{}
-	err := chromedp.Run(runCtx, chromedp.Evaluate(script, &result))
+	err := bs.runner.Evaluate(runCtx, script, &result)
 
 	// 如果执行失败，尝试修复
 	if err != nil {
@@ -961,7 +1367,7 @@ func (bs *BrowserServer) handleEvaluate(ctx context.Context, request mcp.CallToo
 				})()
 			`, strings.ReplaceAll(script, "return ", "__result = "))
 
-			err = chromedp.Run(runCtx, chromedp.Evaluate(alternativeScript, &result))
+			err = bs.runner.Evaluate(runCtx, alternativeScript, &result)
 			if err != nil {
 				// 最后一个尝试
 				lastResortScript := fmt.Sprintf(`
@@ -976,9 +1382,9 @@ func (bs *BrowserServer) handleEvaluate(ctx context.Context, request mcp.CallToo
 					})()
 				`, strings.ReplaceAll(script, "return ", "return "))
 
-				err = chromedp.Run(runCtx, chromedp.Evaluate(lastResortScript, &result))
+				err = bs.runner.Evaluate(runCtx, lastResortScript, &result)
 				if err != nil {
-					return mcp.NewToolResultError(fmt.Errorf("尝试所有方法后仍无法执行脚本: %v", err).Error()), nil
+					return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to run the script with every wrapping", err)), nil
 				}
 			}
 		} else if strings.Contains(err.Error(), "Cannot read properties of null") ||
@@ -1007,17 +1413,17 @@ func (bs *BrowserServer) handleEvaluate(ctx context.Context, request mcp.CallToo
 						
 						return { success: true, result: result };
 					} catch(e) {
-						return { success: false, error: e.message, details: '空引用处理失败' };
+						return { success: false, error: e.message, details: 'null reference handling failed' };
 					}
 				})()
 			`, scriptWithSimpleSafeCheck(script))
 
-			err = chromedp.Run(runCtx, chromedp.Evaluate(saferScript, &result))
+			err = bs.runner.Evaluate(runCtx, saferScript, &result)
 			if err != nil {
-				return mcp.NewToolResultError(fmt.Errorf("安全脚本执行失败: %v", err).Error()), nil
+				return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to run the null-safe script", err)), nil
 			}
 		} else {
-			return mcp.NewToolResultError(fmt.Errorf("执行脚本失败: %v", err).Error()), nil
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to run the script", err)), nil
 		}
 	}
 
@@ -1028,11 +1434,11 @@ func (bs *BrowserServer) handleEvaluate(ctx context.Context, request mcp.CallToo
 			if errorMsg, hasError := resultMap["error"].(string); hasError {
 				// 对于特定类型的错误添加更详细的解释
 				if strings.Contains(errorMsg, "Cannot read properties of null") {
-					errorDetails := "发生空引用错误，可能是尝试访问不存在的DOM元素或其属性。" +
-						"请确认元素选择器是否正确，或在访问属性前先检查元素是否存在。"
-					return mcp.NewToolResultError(fmt.Sprintf("脚本执行遇到错误: %s\n%s", errorMsg, errorDetails)), nil
+					errorDetails := "A null reference usually means that a DOM element or one of its properties does not exist. " +
+						"Check the selector, or check that the element exists before reading its properties."
+					return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeNotFound, "the script threw an error", errorMsg+"\n"+errorDetails)), nil
 				}
-				return mcp.NewToolResultError(fmt.Sprintf("脚本执行遇到错误: %s", errorMsg)), nil
+				return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInternal, "the script threw an error", errorMsg)), nil
 			}
 		}
 
@@ -1072,17 +1478,60 @@ func scriptWithSimpleSafeCheck(script string) string {
 
 func (bs *BrowserServer) Close() error {
 	bs.Logger.Debug().Msg("Closing browser server")
-	bs.cancelAlloc()
-	bs.cancelChrome()
-	// Cancel the context to stop the browser
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-	return chromedp.Cancel(ctx)
+	bs.hars.discardAll()
+	bs.netlog.stopAll()
+	bs.console.stopAll()
+	// 浏览器从未启动，无需关闭
+	bs.browserLock.RLock()
+	started := bs.cancelChrome != nil
+	bs.browserLock.RUnlock()
+	if !started {
+		return nil
+	}
+	// 关闭前保存会话快照，下次启动时恢复
+	if bs.stopAutosave != nil {
+		bs.stopAutosave()
+		bs.saveAutosave()
+	}
+	bs.disableInterception()
+	bs.tabs.closeAll()
+	if bs.cfg().remote() {
+		// 远程浏览器不由 MoLing 启动，只断开连接，不关闭浏览器
+		bs.stopBrowser()
+		return nil
+	}
+	err := bs.closeBrowser()
+	bs.stopBrowser()
+	return err
+}
+
+// ConfigSchema returns the JSON Schema of the Browser configuration with its default values.
+func (bs *BrowserServer) ConfigSchema() string {
+	schema, err := config.GenerateSchema("Browser", NewBrowserConfig())
+	if err != nil {
+		bs.Logger.Err(err).Msg("failed to generate config schema")
+		return ""
+	}
+	return schema
+}
+
+// Restarts returns how many times the browser was restarted after a crash.
+func (bs *BrowserServer) Restarts() int {
+	bs.restartLock.Lock()
+	defer bs.restartLock.Unlock()
+	return bs.restartCount
 }
 
 // Config returns the configuration of the service as a string.
 func (bs *BrowserServer) Config() string {
-	cfg, err := json.Marshal(bs.config)
+	bs.restartLock.Lock()
+	restarts := bs.restartCount
+	bs.restartLock.Unlock()
+	cfg, err := json.Marshal(struct {
+		*BrowserConfig
+		Restarts  int             `json:"browser_restarts,omitempty"` // 浏览器崩溃后的累计重启次数
+		Emulation *EmulationState `json:"emulation,omitempty"`        // 当前生效的模拟覆盖
+	}{bs.cfg(), restarts, bs.emulationState()})
 	if err != nil {
 		bs.Logger.Err(err).Msg("failed to marshal config")
 		return "{}"
@@ -1096,9 +1545,34 @@ func (bs *BrowserServer) Name() comm.MoLingServerType {
 
 // LoadConfig loads the configuration from a JSON object.
 func (bs *BrowserServer) LoadConfig(jsonData map[string]interface{}) error {
-	err := utils.MergeJSONToStruct(bs.config, jsonData)
+	// 在副本上合并和校验，校验通过后才替换正在使用的配置
+	next := *bs.cfg()
+	err := utils.MergeJSONToStruct(&next, jsonData)
 	if err != nil {
 		return err
 	}
-	return bs.config.Check()
+	if err := next.Check(); err != nil {
+		return err
+	}
+	bs.setConfig(&next)
+	return nil
+}
+
+// cfg returns the running config, LoadConfig and browser_set_headless replace it as a whole
+// instead of changing it.
+func (bs *BrowserServer) cfg() *BrowserConfig {
+	bs.configLock.RLock()
+	defer bs.configLock.RUnlock()
+	return bs.config
+}
+
+func (bs *BrowserServer) setConfig(config *BrowserConfig) {
+	bs.configLock.Lock()
+	bs.config = config
+	bs.configLock.Unlock()
+}
+
+// RestartOnlyFields returns the fields read when Chrome is launched or the tools are registered.
+func (bs *BrowserServer) RestartOnlyFields() []string {
+	return []string{"headless", "proxy", "no_proxy_list", "user_agent", "default_language", "data_path", "browser_data_path", "disable_session_tools", "remote_debugging_url"}
 }
@@ -0,0 +1,350 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/accessibility"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	axDefaultMaxDepth = 30  // 无障碍树默认最大深度
+	axDefaultMaxNodes = 300 // 无障碍树默认最大节点数
+	axMaxCandidates   = 10  // 未匹配时返回的候选节点数
+)
+
+// axInteractiveRoles are the roles that are always kept in the snapshot, even without a name.
+var axInteractiveRoles = map[string]bool{
+	"button": true, "checkbox": true, "combobox": true, "link": true, "listbox": true,
+	"menuitem": true, "menuitemcheckbox": true, "menuitemradio": true, "option": true,
+	"radio": true, "searchbox": true, "slider": true, "spinbutton": true, "switch": true,
+	"tab": true, "textbox": true, "treeitem": true,
+}
+
+// axSkippedRoles are structural roles that are flattened into their parent unless they are focusable.
+var axSkippedRoles = map[string]bool{
+	"generic": true, "none": true, "presentation": true, "InlineTextBox": true, "LineBreak": true,
+}
+
+// axRawNode is a flattened accessibility node as returned by Accessibility.getFullAXTree.
+type axRawNode struct {
+	ID        string
+	ChildIDs  []string
+	Ignored   bool
+	Role      string
+	Name      string
+	Value     string
+	Focusable bool
+	BackendID int64
+}
+
+// AXNode is a node of the pruned accessibility snapshot.
+type AXNode struct {
+	Role          string    `json:"role"`
+	Name          string    `json:"name,omitempty"`
+	Value         string    `json:"value,omitempty"`
+	Focusable     bool      `json:"focusable,omitempty"`
+	BackendNodeID int64     `json:"backend_node_id,omitempty"`
+	Children      []*AXNode `json:"children,omitempty"`
+}
+
+// AXSnapshot is the result of browser_accessibility_snapshot.
+type AXSnapshot struct {
+	Root      *AXNode `json:"root"`
+	NodeCount int     `json:"node_count"`
+	Truncated bool    `json:"truncated"` // 是否因深度或数量限制被截断
+}
+
+// AriaTarget identifies an element by its accessible role and name.
+type AriaTarget struct {
+	Role      string `json:"role"`
+	Name      string `json:"name"`
+	Substring bool   `json:"substring"` // 名称按子串匹配，默认完全匹配，均不区分大小写
+}
+
+// ariaTargetProperties is the schema of the aria argument of browser_click and browser_fill.
+var ariaTargetProperties = map[string]any{
+	"role":      map[string]any{"type": "string", "description": "Accessible role, e.g. button, link, textbox"},
+	"name":      map[string]any{"type": "string", "description": "Accessible name, e.g. the button label"},
+	"substring": map[string]any{"type": "boolean", "description": "Match the name as a substring (default: exact match), always case-insensitive"},
+}
+
+func (bs *BrowserServer) handleAccessibilitySnapshot(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	maxDepth, maxNodes := axDefaultMaxDepth, axDefaultMaxNodes
+	if v, ok := args["max_depth"].(float64); ok && v > 0 {
+		maxDepth = int(v)
+	}
+	if v, ok := args["max_nodes"].(float64); ok && v > 0 {
+		maxNodes = int(v)
+	}
+
+	nodes, err := bs.fetchAXTree(ctx)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to get accessibility tree", err)), nil
+	}
+	snapshot := buildAXSnapshot(nodes, maxDepth, maxNodes)
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to encode accessibility tree", err)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// fetchAXTree returns the full accessibility tree of the current page.
+func (bs *BrowserServer) fetchAXTree(ctx context.Context) ([]axRawNode, error) {
+	runCtx, cancel := context.WithTimeout(bs.pageContext(ctx), time.Duration(bs.cfg().SelectorQueryTimeout)*time.Second)
+	defer cancel()
+	var nodes []*accessibility.Node
+	err := chromedp.Run(runCtx,
+		chromedp.WaitReady("body"),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			nodes, err = accessibility.GetFullAXTree().Do(ctx)
+			return err
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return fromCDPNodes(nodes), nil
+}
+
+// fromCDPNodes converts CDP accessibility nodes to raw nodes.
+func fromCDPNodes(nodes []*accessibility.Node) []axRawNode {
+	raw := make([]axRawNode, 0, len(nodes))
+	for _, n := range nodes {
+		node := axRawNode{
+			ID:        string(n.NodeID),
+			Ignored:   n.Ignored,
+			Role:      axValueString(n.Role),
+			Name:      axValueString(n.Name),
+			Value:     axValueString(n.Value),
+			BackendID: int64(n.BackendDOMNodeID),
+		}
+		for _, id := range n.ChildIDs {
+			node.ChildIDs = append(node.ChildIDs, string(id))
+		}
+		for _, p := range n.Properties {
+			if p.Name == accessibility.PropertyNameFocusable && p.Value != nil && string(p.Value.Value) == "true" {
+				node.Focusable = true
+			}
+		}
+		raw = append(raw, node)
+	}
+	return raw
+}
+
+// axValueString returns the string form of an accessibility value.
+func axValueString(v *accessibility.Value) string {
+	if v == nil || len(v.Value) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal([]byte(v.Value), &s); err == nil {
+		return s
+	}
+	return string(v.Value)
+}
+
+// axInteresting reports whether a node is kept in the snapshot. Ignored nodes, inline text
+// boxes and unnamed structural nodes are flattened into their parent.
+func axInteresting(n axRawNode) bool {
+	if n.Ignored || n.Role == "InlineTextBox" {
+		return false
+	}
+	if n.Focusable || axInteractiveRoles[n.Role] {
+		return true
+	}
+	if axSkippedRoles[n.Role] {
+		return false
+	}
+	return strings.TrimSpace(n.Name) != "" || n.Role == "RootWebArea"
+}
+
+// buildAXSnapshot prunes the raw tree to the interesting nodes, limited to maxDepth levels and
+// maxNodes nodes. The first node is the root of the tree.
+func buildAXSnapshot(raw []axRawNode, maxDepth, maxNodes int) *AXSnapshot {
+	snapshot := &AXSnapshot{}
+	if len(raw) == 0 {
+		return snapshot
+	}
+	byID := make(map[string]axRawNode, len(raw))
+	for _, n := range raw {
+		byID[n.ID] = n
+	}
+
+	var visit func(id string, parent *AXNode, depth int)
+	visit = func(id string, parent *AXNode, depth int) {
+		n, ok := byID[id]
+		if !ok {
+			return
+		}
+		target := parent
+		if axInteresting(n) {
+			if depth >= maxDepth || snapshot.NodeCount >= maxNodes {
+				snapshot.Truncated = true
+				return
+			}
+			node := &AXNode{Role: n.Role, Name: n.Name, Value: n.Value, Focusable: n.Focusable, BackendNodeID: n.BackendID}
+			snapshot.NodeCount++
+			if parent == nil {
+				snapshot.Root = node
+			} else {
+				parent.Children = append(parent.Children, node)
+			}
+			target = node
+			depth++
+		}
+		for _, child := range n.ChildIDs {
+			visit(child, target, depth)
+		}
+	}
+	visit(raw[0].ID, nil, 0)
+	return snapshot
+}
+
+// parseAriaTarget reads the aria argument, ok is false when it is absent.
+func parseAriaTarget(v interface{}) (*AriaTarget, bool, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, false, nil
+	}
+	target := &AriaTarget{}
+	target.Role, _ = m["role"].(string)
+	target.Name, _ = m["name"].(string)
+	target.Substring, _ = m["substring"].(bool)
+	if target.Role == "" && target.Name == "" {
+		return nil, true, fmt.Errorf("aria target requires a role or a name")
+	}
+	return target, true, nil
+}
+
+// matchAXNodes returns the non-ignored nodes matching the target. When nothing matches, the
+// candidates are nodes with the same role or a similar name, to help correct the target.
+func matchAXNodes(raw []axRawNode, target *AriaTarget) (matches []axRawNode, candidates []axRawNode) {
+	role := strings.ToLower(target.Role)
+	name := strings.ToLower(strings.TrimSpace(target.Name))
+	for _, n := range raw {
+		if n.Ignored || n.BackendID == 0 {
+			continue
+		}
+		nodeName := strings.ToLower(strings.TrimSpace(n.Name))
+		roleOK := role == "" || strings.ToLower(n.Role) == role
+		nameOK := name == "" || nodeName == name || (target.Substring && strings.Contains(nodeName, name))
+		if roleOK && nameOK {
+			matches = append(matches, n)
+			continue
+		}
+		nearName := name != "" && nodeName != "" && (strings.Contains(nodeName, name) || strings.Contains(name, nodeName))
+		if len(candidates) < axMaxCandidates && ((roleOK && nodeName != "") || nearName) {
+			candidates = append(candidates, n)
+		}
+	}
+	return matches, candidates
+}
+
+// describeAXCandidates renders near-miss candidates for an error message.
+func describeAXCandidates(candidates []axRawNode) string {
+	if len(candidates) == 0 {
+		return "no similar elements found, use browser_accessibility_snapshot to inspect the page"
+	}
+	items := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		items = append(items, fmt.Sprintf("{role: %q, name: %q}", c.Role, c.Name))
+	}
+	return "did you mean: " + strings.Join(items, ", ")
+}
+
+// resolveAriaTarget finds the element matching the target and returns its backend node id.
+func (bs *BrowserServer) resolveAriaTarget(ctx context.Context, target *AriaTarget) (int64, string, error) {
+	nodes, err := bs.fetchAXTree(ctx)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to get accessibility tree: %w", err)
+	}
+	matches, candidates := matchAXNodes(nodes, target)
+	if len(matches) == 0 {
+		return 0, "", comm.NewCodedError(comm.ErrCodeNotFound, fmt.Errorf("no element with role %q and name %q, %s", target.Role, target.Name, describeAXCandidates(candidates)))
+	}
+	desc := fmt.Sprintf("%s %q", matches[0].Role, matches[0].Name)
+	if len(matches) > 1 {
+		desc = fmt.Sprintf("%s (first of %d matches)", desc, len(matches))
+	}
+	return matches[0].BackendID, desc, nil
+}
+
+// callOnBackendNode resolves a backend DOM node and calls fn on it with this bound to the element.
+func (bs *BrowserServer) callOnBackendNode(ctx context.Context, backendID int64, fn string) error {
+	runCtx, cancel := context.WithTimeout(bs.pageContext(ctx), time.Duration(bs.cfg().SelectorQueryTimeout)*time.Second)
+	defer cancel()
+	return chromedp.Run(runCtx, chromedp.ActionFunc(func(ctx context.Context) error {
+		obj, err := dom.ResolveNode().WithBackendNodeID(cdp.BackendNodeID(backendID)).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to resolve node: %w", err)
+		}
+		_, exception, err := runtime.CallFunctionOn(fn).WithObjectID(obj.ObjectID).WithUserGesture(true).Do(ctx)
+		if err != nil {
+			return err
+		}
+		if exception != nil {
+			return fmt.Errorf("%s", exception.Text)
+		}
+		return nil
+	}))
+}
+
+// clickAria clicks the element matching the aria target.
+func (bs *BrowserServer) clickAria(ctx context.Context, request mcp.CallToolRequest, target *AriaTarget) *mcp.CallToolResult {
+	backendID, desc, err := bs.resolveAriaTarget(ctx, target)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrorCodeOf(err), err.Error(), ""))
+	}
+	err = bs.callOnBackendNode(ctx, backendID, `function() { this.scrollIntoView({block: "center"}); this.click(); }`)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr(fmt.Sprintf("failed to click %s", desc), err))
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Clicked %s", desc))
+}
+
+// fillAria fills the element matching the aria target and fires input and change events.
+func (bs *BrowserServer) fillAria(ctx context.Context, request mcp.CallToolRequest, target *AriaTarget, value string) *mcp.CallToolResult {
+	backendID, desc, err := bs.resolveAriaTarget(ctx, target)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrorCodeOf(err), err.Error(), ""))
+	}
+	fn := fmt.Sprintf(`function() {
+		this.focus();
+		this.value = %s;
+		this.dispatchEvent(new Event("input", {bubbles: true}));
+		this.dispatchEvent(new Event("change", {bubbles: true}));
+	}`, safeJSONString(value))
+	if err := bs.callOnBackendNode(ctx, backendID, fn); err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr(fmt.Sprintf("failed to fill %s", desc), err))
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Filled %s", desc))
+}
@@ -0,0 +1,292 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	annotationAttr         = "data-moling-annotation" // 标注覆盖层元素的属性，用于清理
+	annotationDefaultColor = "red"                    // 默认标注颜色
+	annotationMaxCount     = 50                       // 单次调用的最大标注数量
+)
+
+// annotationColorPattern accepts CSS color names, hex colors and rgb()/rgba()/hsl()/hsla().
+var annotationColorPattern = regexp.MustCompile(`^(#[0-9a-fA-F]{3,8}|[a-zA-Z]{3,20}|(rgb|rgba|hsl|hsla)\([0-9.,%\s]+\))$`)
+
+// Annotation is a marker drawn around an element before the screenshot of browser_annotate.
+type Annotation struct {
+	Selector string `json:"selector"`
+	Label    string `json:"label,omitempty"`
+	Color    string `json:"color"`
+}
+
+// AnnotationBox is the bounding box of an annotated element in page coordinates.
+type AnnotationBox struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// AnnotationResult is the outcome of one annotation, elements that are not found are reported
+// here instead of failing the call.
+type AnnotationResult struct {
+	Index    int            `json:"index"`
+	Selector string         `json:"selector"`
+	Label    string         `json:"label,omitempty"`
+	Found    bool           `json:"found"`
+	Box      *AnnotationBox `json:"box,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// annotationPage is the page browser_annotate draws on: the tab of the call, or a fake in tests.
+type annotationPage interface {
+	Evaluate(script string, res interface{}) error
+	Screenshot() ([]byte, error)
+}
+
+// tabPage runs the annotation steps on a browser tab, each step with its own timeout.
+type tabPage struct {
+	ctx     context.Context
+	timeout time.Duration
+}
+
+func (tp tabPage) Evaluate(script string, res interface{}) error {
+	runCtx, cancel := context.WithTimeout(tp.ctx, tp.timeout)
+	defer cancel()
+	return chromedp.Run(runCtx, chromedp.Evaluate(script, res))
+}
+
+func (tp tabPage) Screenshot() ([]byte, error) {
+	runCtx, cancel := context.WithTimeout(tp.ctx, tp.timeout)
+	defer cancel()
+	var buf []byte
+	err := chromedp.Run(runCtx, chromedp.FullScreenshot(&buf, 90))
+	return buf, err
+}
+
+// annotationCleanupJS removes every annotation overlay and returns how many were removed.
+const annotationCleanupJS = `(function() {
+	var nodes = document.querySelectorAll("[` + annotationAttr + `]");
+	for (var i = 0; i < nodes.length; i++) {
+		if (nodes[i].parentNode) {
+			nodes[i].parentNode.removeChild(nodes[i]);
+		}
+	}
+	return nodes.length;
+})()`
+
+// annotationDrawJS draws a box, and a label when set, around the element of every item. It is
+// plain ES5 and returns the results as a JSON string. %s is replaced by the items as JSON.
+const annotationDrawJS = `(function(items) {
+	var attr = "` + annotationAttr + `";
+	var root = document.body || document.documentElement;
+	var sx = window.scrollX || window.pageXOffset || 0;
+	var sy = window.scrollY || window.pageYOffset || 0;
+	var results = [];
+	for (var i = 0; i < items.length; i++) {
+		var item = items[i];
+		var res = {index: i + 1, selector: item.selector, label: item.label, found: false};
+		results.push(res);
+		var el = null;
+		try {
+			el = document.querySelector(item.selector);
+		} catch (e) {
+			res.error = "invalid selector: " + e.message;
+			continue;
+		}
+		if (!el) {
+			res.error = "element not found";
+			continue;
+		}
+		var r = el.getBoundingClientRect();
+		res.found = true;
+		res.box = {x: r.left + sx, y: r.top + sy, width: r.width, height: r.height};
+		var box = document.createElement("div");
+		box.setAttribute(attr, "box");
+		box.style.cssText = "position:absolute;pointer-events:none;z-index:2147483647;box-sizing:border-box;margin:0;";
+		box.style.left = res.box.x + "px";
+		box.style.top = res.box.y + "px";
+		box.style.width = res.box.width + "px";
+		box.style.height = res.box.height + "px";
+		box.style.border = "3px solid " + item.color;
+		root.appendChild(box);
+		if (item.label) {
+			var tag = document.createElement("div");
+			tag.setAttribute(attr, "label");
+			tag.textContent = item.label;
+			tag.style.cssText = "position:absolute;pointer-events:none;z-index:2147483647;margin:0;padding:1px 5px;" +
+				"font:bold 13px/16px sans-serif;color:#fff;white-space:nowrap;";
+			tag.style.left = res.box.x + "px";
+			tag.style.top = Math.max(0, res.box.y - 18) + "px";
+			tag.style.background = item.color;
+			root.appendChild(tag);
+		}
+	}
+	return JSON.stringify(results);
+})(%s)`
+
+// addAnnotateTool registers browser_annotate.
+func (bs *BrowserServer) addAnnotateTool() {
+	bs.addTool(mcp.NewTool(
+		"browser_annotate",
+		mcp.WithDescription("Draw labeled boxes around elements, take a full-page screenshot and remove the boxes again. Use it to ask the user which element they mean. Returns the screenshot path and the bounding box of every annotation; elements that are not found are reported per annotation."),
+		mcp.WithArray("annotations",
+			mcp.Description("Elements to mark"),
+			mcp.Required(),
+			mcp.Items(map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"selector": map[string]interface{}{"type": "string", "description": "CSS selector of the element"},
+					"label":    map[string]interface{}{"type": "string", "description": "Text shown above the box"},
+					"color":    map[string]interface{}{"type": "string", "description": "CSS color of the box and label, e.g. red or #00aa00 (default: red)"},
+				},
+				"required": []string{"selector"},
+			}),
+		),
+		mcp.WithBoolean("numbered",
+			mcp.Description("Prefix the labels with 1..N so the user can answer with a number (default: false)"),
+		),
+		mcp.WithString("name",
+			mcp.Description("Name for the screenshot (default: annotated)"),
+		),
+	), bs.handleAnnotate)
+}
+
+func (bs *BrowserServer) handleAnnotate(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	annotations, err := parseAnnotations(args["annotations"])
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	numbered, _ := args["numbered"].(bool)
+	name, _ := args["name"].(string)
+	if name = strings.TrimSpace(name); name == "" {
+		name = "annotated"
+	}
+
+	page := tabPage{ctx: bs.pageContext(ctx), timeout: time.Duration(bs.cfg().SelectorQueryTimeout) * time.Second}
+	results, buf, err := annotateAndCapture(page, annotations, numbered)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to annotate", err)), nil
+	}
+
+	path := filepath.Join(bs.cfg().DataPath, fmt.Sprintf("%s_%d.png", strings.TrimSuffix(name, ".png"), rand.Int()))
+	if err := os.WriteFile(path, buf, 0644); err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to save screenshot", err)), nil
+	}
+	data, err := json.Marshal(map[string]interface{}{"path": path, "annotations": results})
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to marshal annotations", err), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// parseAnnotations validates the annotations argument and fills in the default color.
+func parseAnnotations(raw interface{}) ([]Annotation, error) {
+	items, ok := raw.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("annotations must be a non-empty array of objects")
+	}
+	if len(items) > annotationMaxCount {
+		return nil, fmt.Errorf("at most %d annotations are allowed, got %d", annotationMaxCount, len(items))
+	}
+	annotations := make([]Annotation, 0, len(items))
+	for i, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("annotation %d must be an object", i+1)
+		}
+		a := Annotation{Color: annotationDefaultColor}
+		a.Selector, _ = m["selector"].(string)
+		if a.Selector = strings.TrimSpace(a.Selector); a.Selector == "" {
+			return nil, fmt.Errorf("annotation %d requires a selector", i+1)
+		}
+		a.Label, _ = m["label"].(string)
+		if color, _ := m["color"].(string); strings.TrimSpace(color) != "" {
+			a.Color = strings.TrimSpace(color)
+		}
+		if !annotationColorPattern.MatchString(a.Color) {
+			return nil, fmt.Errorf("annotation %d has an invalid color %q", i+1, a.Color)
+		}
+		annotations = append(annotations, a)
+	}
+	return annotations, nil
+}
+
+// annotationScript returns the script drawing the annotations. In numbered mode the labels are
+// prefixed with their 1-based index.
+func annotationScript(annotations []Annotation, numbered bool) (string, error) {
+	items := make([]Annotation, len(annotations))
+	for i, a := range annotations {
+		if numbered {
+			a.Label = strings.TrimSpace(fmt.Sprintf("%d %s", i+1, a.Label))
+		}
+		items[i] = a
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(annotationDrawJS, data), nil
+}
+
+// annotateAndCapture draws the annotations, takes a screenshot and removes the overlays. The
+// overlays are removed even when drawing or the screenshot fails, so they never stay on the page.
+func annotateAndCapture(page annotationPage, annotations []Annotation, numbered bool) (results []AnnotationResult, buf []byte, err error) {
+	script, err := annotationScript(annotations, numbered)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		var removed int
+		if cerr := page.Evaluate(annotationCleanupJS, &removed); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to remove annotations: %w", cerr)
+		}
+	}()
+
+	var raw string
+	if err := page.Evaluate(script, &raw); err != nil {
+		return nil, nil, fmt.Errorf("failed to draw annotations: %w", err)
+	}
+	if err := json.Unmarshal([]byte(raw), &results); err != nil {
+		return nil, nil, fmt.Errorf("unexpected annotation results: %w", err)
+	}
+	buf, err = page.Screenshot()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to take screenshot: %w", err)
+	}
+	if len(buf) == 0 {
+		return nil, nil, errors.New("empty screenshot")
+	}
+	return results, buf, nil
+}
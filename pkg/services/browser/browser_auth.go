@@ -0,0 +1,551 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+var (
+	// ErrInvalidOriginPattern is returned for an origin pattern that is not scheme://host:port.
+	ErrInvalidOriginPattern = errors.New("invalid origin pattern")
+	// ErrInvalidHeader is returned for a header name or value that can't be sent.
+	ErrInvalidHeader = errors.New("invalid header")
+)
+
+// headerNameRegexp matches an HTTP header field name (RFC 9110 token).
+var headerNameRegexp = regexp.MustCompile("^[!#$%&'*+\\-.^_`|~0-9A-Za-z]+$")
+
+// maxAuthRetries is the number of times the stored credentials are offered for a request before
+// the challenge is cancelled, so that wrong credentials don't loop.
+const maxAuthRetries = 1
+
+// originPattern matches the origin of a URL. Empty fields match anything, a host starting with
+// "*." matches the subdomains of the rest.
+type originPattern struct {
+	scheme string
+	host   string
+	port   string
+}
+
+// parseOriginPattern parses patterns like *, example.com, https://*.example.com or
+// http://localhost:8080. Paths are not allowed.
+func parseOriginPattern(raw string) (originPattern, error) {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	if raw == "" || raw == "*" {
+		return originPattern{}, nil
+	}
+	var p originPattern
+	hostPort := raw
+	if scheme, rest, ok := strings.Cut(raw, "://"); ok {
+		if scheme != "http" && scheme != "https" {
+			return p, fmt.Errorf("%w: %q, the scheme must be http or https", ErrInvalidOriginPattern, raw)
+		}
+		p.scheme, hostPort = scheme, rest
+	}
+	hostPort = strings.TrimSuffix(hostPort, "/")
+	if hostPort == "" || strings.ContainsAny(hostPort, "/?#@ ") {
+		return p, fmt.Errorf("%w: %q, use scheme://host:port without a path", ErrInvalidOriginPattern, raw)
+	}
+	p.host = hostPort
+	if host, port, err := net.SplitHostPort(hostPort); err == nil {
+		p.host, p.port = host, port
+	}
+	p.host = strings.Trim(p.host, "[]")
+	if p.host == "*" {
+		p.host = ""
+	}
+	if strings.Contains(strings.TrimPrefix(p.host, "*."), "*") {
+		return p, fmt.Errorf("%w: %q, only a leading *. wildcard is supported", ErrInvalidOriginPattern, raw)
+	}
+	return p, nil
+}
+
+// matches reports whether the origin of rawURL matches the pattern.
+func (p originPattern) matches(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if p.scheme != "" && p.scheme != scheme {
+		return false
+	}
+	if p.port != "" {
+		port := u.Port()
+		if port == "" {
+			port = map[string]string{"http": "80", "https": "443"}[scheme]
+		}
+		if p.port != port {
+			return false
+		}
+	}
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case p.host == "":
+		return true
+	case strings.HasPrefix(p.host, "*."):
+		return strings.HasSuffix(host, p.host[1:])
+	default:
+		return host == p.host
+	}
+}
+
+// specificity ranks patterns, the most specific matching pattern wins.
+func (p originPattern) specificity() int {
+	score := 0
+	switch {
+	case p.host == "":
+	case strings.HasPrefix(p.host, "*."):
+		score = 100 + len(p.host)
+	default:
+		score = 1000
+	}
+	if p.scheme != "" {
+		score += 2
+	}
+	if p.port != "" {
+		score++
+	}
+	return score
+}
+
+func (p originPattern) String() string {
+	host := p.host
+	if host == "" {
+		host = "*"
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if p.port != "" {
+		host += ":" + p.port
+	}
+	if p.scheme != "" {
+		return p.scheme + "://" + host
+	}
+	return host
+}
+
+// credential is a username and password for the origins matching pattern.
+type credential struct {
+	pattern  originPattern
+	username string
+	password string
+}
+
+// headerScope is a set of extra headers for the origins matching pattern.
+type headerScope struct {
+	pattern originPattern
+	headers map[string]string
+}
+
+// authState holds the credentials and extra headers. They persist across navigations until
+// cleared, and are applied to new tabs and to the restarted browser.
+type authState struct {
+	credentials map[string]credential   // 按规范化后的来源模式
+	global      map[string]string       // 发往所有来源的请求头，使用 Network.setExtraHTTPHeaders
+	scoped      map[string]headerScope  // 只发往匹配来源的请求头，通过 Fetch 拦截注入
+	tried       map[fetch.RequestID]int // 已提供凭据的请求，凭据错误时不再重试
+	proxy       *credential             // 代理服务器的凭据，来自配置的 proxy
+}
+
+// intercepts reports whether requests have to be paused with Fetch.
+func (as *authState) intercepts() bool {
+	return len(as.credentials) > 0 || len(as.scoped) > 0 || as.proxy != nil
+}
+
+// credentialFor returns the credential of the most specific pattern matching the origin.
+func (as *authState) credentialFor(origin string) (credential, bool) {
+	var best credential
+	found := false
+	for _, cred := range as.credentials {
+		if cred.pattern.matches(origin) && (!found || cred.pattern.specificity() > best.pattern.specificity()) {
+			best, found = cred, true
+		}
+	}
+	return best, found
+}
+
+// headersFor returns the scoped headers for a request URL. When scopes set the same header, the
+// most specific scope wins.
+func (as *authState) headersFor(rawURL string) map[string]string {
+	var scopes []headerScope
+	for _, scope := range as.scoped {
+		if scope.pattern.matches(rawURL) {
+			scopes = append(scopes, scope)
+		}
+	}
+	sort.Slice(scopes, func(i, j int) bool { return scopes[i].pattern.specificity() < scopes[j].pattern.specificity() })
+	headers := make(map[string]string)
+	for _, scope := range scopes {
+		for name, value := range scope.headers {
+			headers[name] = value
+		}
+	}
+	return headers
+}
+
+// authChallengeResponse answers a Fetch.authRequired event: the stored credentials for a
+// matching server challenge or the proxy credentials for a proxy challenge, the default behavior
+// otherwise, and a cancel when the credentials were already rejected for the request.
+func (as *authState) authChallengeResponse(ev *fetch.EventAuthRequired) *fetch.AuthChallengeResponse {
+	deflt := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseDefault}
+	if ev.AuthChallenge == nil {
+		return deflt
+	}
+	var cred credential
+	if ev.AuthChallenge.Source == fetch.AuthChallengeSourceProxy {
+		if as.proxy == nil {
+			return deflt
+		}
+		cred = *as.proxy
+	} else {
+		origin := ev.AuthChallenge.Origin
+		if origin == "" && ev.Request != nil {
+			origin = ev.Request.URL
+		}
+		var ok bool
+		if cred, ok = as.credentialFor(origin); !ok {
+			return deflt
+		}
+	}
+	if as.tried == nil || len(as.tried) > 1024 {
+		as.tried = make(map[fetch.RequestID]int)
+	}
+	if as.tried[ev.RequestID] >= maxAuthRetries {
+		return &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseCancelAuth}
+	}
+	as.tried[ev.RequestID]++
+	return &fetch.AuthChallengeResponse{
+		Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+		Username: cred.username,
+		Password: cred.password,
+	}
+}
+
+// continueRequest continues a paused request with the scoped headers of its origin added.
+func (as *authState) continueRequest(ev *fetch.EventRequestPaused) *fetch.ContinueRequestParams {
+	params := fetch.ContinueRequest(ev.RequestID)
+	if ev.Request == nil {
+		return params
+	}
+	extra := as.headersFor(ev.Request.URL)
+	if len(extra) == 0 {
+		return params
+	}
+	merged := make(map[string]string, len(ev.Request.Headers)+len(extra))
+	names := make(map[string]string) // 小写名称到原名称，请求头名称不区分大小写
+	for name, value := range ev.Request.Headers {
+		merged[name] = fmt.Sprint(value)
+		names[strings.ToLower(name)] = name
+	}
+	for name, value := range extra {
+		if original, ok := names[strings.ToLower(name)]; ok {
+			delete(merged, original)
+		}
+		merged[name] = value
+	}
+	entries := make([]*fetch.HeaderEntry, 0, len(merged))
+	for name, value := range merged {
+		entries = append(entries, &fetch.HeaderEntry{Name: name, Value: value})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return params.WithHeaders(entries)
+}
+
+// actions returns the actions applying the headers to a tab, Fetch is enabled by fetchAction.
+func (as *authState) actions() []chromedp.Action {
+	global := make(network.Headers, len(as.global))
+	for name, value := range as.global {
+		global[name] = value
+	}
+	return []chromedp.Action{network.Enable(), network.SetExtraHTTPHeaders(global)}
+}
+
+// fetchAction returns the action pausing the requests that credentials, scoped headers and
+// request blocking have to see, or disabling Fetch when none do.
+func (bs *BrowserServer) fetchAction() chromedp.Action {
+	var intercepts, handleAuth bool
+	bs.auth.with(func(as *authState) {
+		intercepts, handleAuth = as.intercepts(), len(as.credentials) > 0 || as.proxy != nil
+	})
+	patterns := bs.blocking.load().requestPatterns()
+	if intercepts {
+		patterns = []*fetch.RequestPattern{{URLPattern: "*", RequestStage: fetch.RequestStageRequest}}
+	}
+	if len(patterns) == 0 {
+		return fetch.Disable()
+	}
+	return fetch.Enable().WithPatterns(patterns).WithHandleAuthRequests(handleAuth)
+}
+
+// empty reports whether no credential or header is set.
+func (as *authState) empty() bool {
+	return len(as.credentials) == 0 && len(as.global) == 0 && len(as.scoped) == 0 && as.proxy == nil
+}
+
+// authStore guards the authState of the browser.
+type authStore struct {
+	mu    sync.Mutex
+	state authState
+}
+
+// update changes the state and returns the actions applying it.
+func (s *authStore) update(fn func(as *authState)) []chromedp.Action {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.state)
+	return s.state.actions()
+}
+
+// with runs fn with the state locked.
+func (s *authStore) with(fn func(as *authState)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.state)
+}
+
+// parseHeaders validates the headers argument of browser_set_extra_headers.
+func parseHeaders(raw interface{}) (map[string]string, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: headers must be an object of header names to values", ErrInvalidHeader)
+	}
+	headers := make(map[string]string, len(m))
+	for name, value := range m {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: the value of %s must be a string", ErrInvalidHeader, name)
+		}
+		if !headerNameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("%w: %q is not a valid header name", ErrInvalidHeader, name)
+		}
+		if strings.ContainsAny(s, "\r\n\x00") {
+			return nil, fmt.Errorf("%w: the value of %s contains a line break", ErrInvalidHeader, name)
+		}
+		headers[name] = s
+	}
+	return headers, nil
+}
+
+// sortedKeys returns the header names, values are not shown because they may be secrets.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// addAuthTools registers browser_set_credentials and browser_set_extra_headers.
+func (bs *BrowserServer) addAuthTools() {
+	bs.addTool(mcp.NewTool(
+		"browser_set_credentials",
+		mcp.WithDescription("Answer HTTP basic/digest authentication challenges of matching origins with a username and password, instead of the login dialog. The credentials persist across navigations until cleared and are never returned. Call with an origin only to remove its credentials, or without arguments to remove all."),
+		mcp.WithString("origin",
+			mcp.Description("Origin pattern, e.g. https://intranet.example.com, *.example.com or http://localhost:8080, * matches all origins"),
+		),
+		mcp.WithString("username",
+			mcp.Description("Username"),
+		),
+		mcp.WithString("password",
+			mcp.Description("Password"),
+		),
+	), bs.handleSetCredentials)
+
+	bs.addTool(mcp.NewTool(
+		"browser_set_extra_headers",
+		mcp.WithDescription("Send extra HTTP headers, e.g. X-Api-Key or a staging bypass cookie, with every request, or only with the requests to the origins matching origin. The headers of an origin replace its previous ones and persist across navigations. Call without headers to remove the headers of the origin, or of all origins when no origin is given."),
+		mcp.WithObject("headers",
+			mcp.Description("Header names to values, e.g. {\"X-Api-Key\": \"...\"}"),
+		),
+		mcp.WithString("origin",
+			mcp.Description("Origin pattern the headers are limited to, e.g. https://*.staging.example.com (default: all origins)"),
+		),
+	), bs.handleSetExtraHeaders)
+}
+
+func (bs *BrowserServer) handleSetCredentials(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	rawOrigin, _ := args["origin"].(string)
+	username, _ := args["username"].(string)
+	password, _ := args["password"].(string)
+
+	pattern, err := parseOriginPattern(rawOrigin)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	var message string
+	var actions []chromedp.Action
+	switch {
+	case strings.TrimSpace(rawOrigin) == "" && username == "" && password == "":
+		actions = bs.auth.update(func(as *authState) { as.credentials = nil })
+		message = "All credentials cleared"
+	case username == "" && password == "":
+		actions = bs.auth.update(func(as *authState) { delete(as.credentials, pattern.String()) })
+		message = fmt.Sprintf("Credentials for %s cleared", pattern)
+	case strings.TrimSpace(rawOrigin) == "":
+		return comm.ToolError(comm.ErrCodeInvalidArgument, "origin is required, use * to send the credentials to all origins", ""), nil
+	default:
+		actions = bs.auth.update(func(as *authState) {
+			if as.credentials == nil {
+				as.credentials = make(map[string]credential)
+			}
+			as.credentials[pattern.String()] = credential{pattern: pattern, username: username, password: password}
+		})
+		message = fmt.Sprintf("Credentials set for %s", pattern)
+	}
+	if err := bs.emulate(ctx, append(actions, bs.fetchAction())...); err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to apply credentials", err)), nil
+	}
+	bs.Logger.Debug().Str("origin", pattern.String()).Msg(message)
+	return mcp.NewToolResultText(message), nil
+}
+
+func (bs *BrowserServer) handleSetExtraHeaders(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	rawOrigin, _ := args["origin"].(string)
+	headers, err := parseHeaders(args["headers"])
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	pattern, err := parseOriginPattern(rawOrigin)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	scoped := pattern != (originPattern{})
+
+	var message string
+	var actions []chromedp.Action
+	switch {
+	case len(headers) == 0 && !scoped:
+		actions = bs.auth.update(func(as *authState) { as.global, as.scoped = nil, nil })
+		message = "All extra headers cleared"
+	case len(headers) == 0:
+		actions = bs.auth.update(func(as *authState) { delete(as.scoped, pattern.String()) })
+		message = fmt.Sprintf("Extra headers for %s cleared", pattern)
+	case !scoped:
+		actions = bs.auth.update(func(as *authState) { as.global = headers })
+		message = fmt.Sprintf("Extra headers set for all origins: %s", strings.Join(sortedKeys(headers), ", "))
+	default:
+		actions = bs.auth.update(func(as *authState) {
+			if as.scoped == nil {
+				as.scoped = make(map[string]headerScope)
+			}
+			as.scoped[pattern.String()] = headerScope{pattern: pattern, headers: headers}
+		})
+		message = fmt.Sprintf("Extra headers set for %s: %s", pattern, strings.Join(sortedKeys(headers), ", "))
+	}
+	if err := bs.emulate(ctx, append(actions, bs.fetchAction())...); err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to apply extra headers", err)), nil
+	}
+	return mcp.NewToolResultText(message), nil
+}
+
+// authActions returns the actions applying the credentials, headers and request blocking to a
+// new tab, nil when none are set.
+func (bs *BrowserServer) authActions() []chromedp.Action {
+	var actions []chromedp.Action
+	bs.auth.with(func(as *authState) {
+		if !as.empty() {
+			actions = as.actions()
+		}
+	})
+	if len(actions) == 0 && !bs.blocking.load().active() {
+		return nil
+	}
+	return append(actions, bs.fetchAction())
+}
+
+// reapplyAuth applies the credentials, headers and request blocking again after the browser was
+// restarted.
+func (bs *BrowserServer) reapplyAuth() {
+	actions := bs.authActions()
+	if len(actions) == 0 {
+		return
+	}
+	if err := bs.emulate(context.Background(), actions...); err != nil {
+		bs.Logger.Warn().Err(err).Msg("failed to apply the credentials, extra headers and request blocking to the restarted browser")
+	}
+}
+
+// listenAuth answers the paused requests and authentication challenges of a tab, see
+// pausedRequestAction for the paused requests. It is
+// registered once per tab, and the events only arrive while Fetch is enabled on the tab.
+func (bs *BrowserServer) listenAuth(tab context.Context) {
+	chromedp.ListenTarget(tab, func(ev interface{}) {
+		var action chromedp.Action
+		switch ev := ev.(type) {
+		case *fetch.EventRequestPaused:
+			action = bs.pausedRequestAction(ev)
+		case *fetch.EventAuthRequired:
+			bs.auth.with(func(as *authState) {
+				action = fetch.ContinueWithAuth(ev.RequestID, as.authChallengeResponse(ev))
+			})
+			bs.Logger.Debug().Str("requestID", string(ev.RequestID)).Msg("answering authentication challenge")
+		default:
+			return
+		}
+		// 事件回调中不能阻塞，在新的协程中响应
+		go func() {
+			c := chromedp.FromContext(tab)
+			if c == nil || c.Target == nil {
+				return
+			}
+			if err := action.Do(cdp.WithExecutor(tab, c.Target)); err != nil && tab.Err() == nil {
+				bs.Logger.Debug().Err(err).Msg("failed to continue intercepted request")
+			}
+		}()
+	})
+}
+
+// disableInterception stops pausing the requests of the default tab, so that pages don't hang
+// on requests nobody answers while the browser shuts down.
+func (bs *BrowserServer) disableInterception() {
+	intercepts := false
+	bs.auth.with(func(as *authState) { intercepts = as.intercepts() })
+	browserCtx := bs.browserContext()
+	if (!intercepts && !bs.blocking.load().active()) || browserCtx == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(browserCtx, time.Second)
+	defer cancel()
+	if err := chromedp.Run(ctx, fetch.Disable()); err != nil {
+		bs.Logger.Debug().Err(err).Msg("failed to disable request interception")
+	}
+}
@@ -0,0 +1,364 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ErrInvalidBlockRule is returned for an unknown resource type or a URL pattern that doesn't compile.
+var ErrInvalidBlockRule = errors.New("invalid blocking rule")
+
+// blockableResourceTypes are the resource types that can be blocked. Documents, XHR and fetch are
+// not among them, so that blocking by type never breaks the page itself.
+var blockableResourceTypes = map[string]network.ResourceType{
+	"image":      network.ResourceTypeImage,
+	"font":       network.ResourceTypeFont,
+	"media":      network.ResourceTypeMedia,
+	"stylesheet": network.ResourceTypeStylesheet,
+	"script":     network.ResourceTypeScript,
+	"texttrack":  network.ResourceTypeTextTrack,
+	"manifest":   network.ResourceTypeManifest,
+	"ping":       network.ResourceTypePing,
+	"prefetch":   network.ResourceTypePrefetch,
+	"other":      network.ResourceTypeOther,
+}
+
+// blockableTypeNames returns the names of blockableResourceTypes for error messages and descriptions.
+func blockableTypeNames() string {
+	names := make([]string, 0, len(blockableResourceTypes))
+	for name := range blockableResourceTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// blockRule is a blocked resource type, a blocked URL pattern or an allowed URL pattern, with the
+// number of requests it matched.
+type blockRule struct {
+	value        string
+	re           *regexp.Regexp       // URL 规则
+	resourceType network.ResourceType // 资源类型规则
+	hits         atomic.Int64
+}
+
+// blockRules is a set of rules. It is never changed after creation, browser_set_blocking
+// replaces it as a whole, so the request handler reads it without a lock.
+type blockRules struct {
+	types    []*blockRule
+	patterns []*blockRule
+	allow    []*blockRule
+	blockXHR bool      // URL 规则也拦截 XHR 和 fetch 请求
+	since    time.Time // 规则生效的时间
+}
+
+// newBlockRules validates the resource types and compiles the URL patterns.
+func newBlockRules(types, patterns, allow []string, blockXHR bool) (*blockRules, error) {
+	r := &blockRules{blockXHR: blockXHR}
+	seen := make(map[string]bool)
+	for _, name := range types {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		rt, ok := blockableResourceTypes[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown resource type %q, supported: %s", ErrInvalidBlockRule, name, blockableTypeNames())
+		}
+		seen[name] = true
+		r.types = append(r.types, &blockRule{value: name, resourceType: rt})
+	}
+	var err error
+	if r.patterns, err = compileBlockPatterns(patterns); err != nil {
+		return nil, err
+	}
+	if r.allow, err = compileBlockPatterns(allow); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// compileBlockPatterns compiles URL patterns, empty patterns are skipped.
+func compileBlockPatterns(patterns []string) ([]*blockRule, error) {
+	var rules []*blockRule
+	for _, pattern := range patterns {
+		if strings.TrimSpace(pattern) == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: URL pattern %q: %v", ErrInvalidBlockRule, pattern, err)
+		}
+		rules = append(rules, &blockRule{value: pattern, re: re})
+	}
+	return rules, nil
+}
+
+// active reports whether any request is blocked.
+func (r *blockRules) active() bool {
+	return r != nil && (len(r.types) > 0 || len(r.patterns) > 0)
+}
+
+// requestPatterns returns the Fetch patterns of the requests the rules have to see. Without URL
+// patterns only the blocked resource types are paused, the other requests don't make the round trip.
+func (r *blockRules) requestPatterns() []*fetch.RequestPattern {
+	if !r.active() {
+		return nil
+	}
+	if len(r.patterns) > 0 {
+		return []*fetch.RequestPattern{{URLPattern: "*", RequestStage: fetch.RequestStageRequest}}
+	}
+	patterns := make([]*fetch.RequestPattern, 0, len(r.types))
+	for _, rule := range r.types {
+		patterns = append(patterns, &fetch.RequestPattern{URLPattern: "*", ResourceType: rule.resourceType, RequestStage: fetch.RequestStageRequest})
+	}
+	return patterns
+}
+
+// match returns the rule blocking a request and counts the hit, nil when the request goes
+// through. Documents are never blocked, XHR and fetch only by URL patterns with blockXHR, and the
+// allow patterns win over all blocking rules.
+func (r *blockRules) match(rawURL string, rt network.ResourceType) *blockRule {
+	if !r.active() || rt == network.ResourceTypeDocument {
+		return nil
+	}
+	var hit *blockRule
+	for _, rule := range r.types {
+		if rule.resourceType == rt {
+			hit = rule
+			break
+		}
+	}
+	if hit == nil && (r.blockXHR || (rt != network.ResourceTypeXHR && rt != network.ResourceTypeFetch)) {
+		for _, rule := range r.patterns {
+			if rule.re.MatchString(rawURL) {
+				hit = rule
+				break
+			}
+		}
+	}
+	if hit == nil {
+		return nil
+	}
+	for _, rule := range r.allow {
+		if rule.re.MatchString(rawURL) {
+			rule.hits.Add(1)
+			return nil
+		}
+	}
+	hit.hits.Add(1)
+	return hit
+}
+
+// BlockingStats is the result of browser_get_blocking_stats.
+type BlockingStats struct {
+	Enabled   bool             `json:"enabled"`
+	Since     *time.Time       `json:"since,omitempty"` // 当前规则生效的时间
+	Blocked   int64            `json:"blocked"`
+	ByType    map[string]int64 `json:"by_type"`
+	ByPattern map[string]int64 `json:"by_pattern"`
+	Allowed   map[string]int64 `json:"allowed_by_pattern"` // 被 allow_patterns 放行的请求
+	BlockXHR  bool             `json:"block_xhr"`
+}
+
+// stats returns the hits of the rules.
+func (r *blockRules) stats() BlockingStats {
+	stats := BlockingStats{ByType: map[string]int64{}, ByPattern: map[string]int64{}, Allowed: map[string]int64{}}
+	if r == nil {
+		return stats
+	}
+	stats.Enabled, stats.BlockXHR = r.active(), r.blockXHR
+	if !r.since.IsZero() {
+		since := r.since
+		stats.Since = &since
+	}
+	for _, rule := range r.types {
+		stats.ByType[rule.value] = rule.hits.Load()
+		stats.Blocked += stats.ByType[rule.value]
+	}
+	for _, rule := range r.patterns {
+		stats.ByPattern[rule.value] = rule.hits.Load()
+		stats.Blocked += stats.ByPattern[rule.value]
+	}
+	for _, rule := range r.allow {
+		stats.Allowed[rule.value] = rule.hits.Load()
+	}
+	return stats
+}
+
+// describe summarizes the rules for the tool result.
+func (r *blockRules) describe() string {
+	if !r.active() {
+		return "Request blocking is off"
+	}
+	var parts []string
+	if len(r.types) > 0 {
+		names := make([]string, 0, len(r.types))
+		for _, rule := range r.types {
+			names = append(names, rule.value)
+		}
+		parts = append(parts, "resource types "+strings.Join(names, ", "))
+	}
+	if len(r.patterns) > 0 {
+		parts = append(parts, fmt.Sprintf("%d URL patterns", len(r.patterns)))
+	}
+	msg := "Blocking " + strings.Join(parts, " and ")
+	if len(r.allow) > 0 {
+		msg += fmt.Sprintf(", except %d allow patterns", len(r.allow))
+	}
+	if r.blockXHR {
+		msg += ", URL patterns also block XHR and fetch"
+	}
+	return msg
+}
+
+// blockStore holds the current rules of the browser. They persist across browser restarts.
+type blockStore struct {
+	rules atomic.Pointer[blockRules]
+}
+
+// load returns the current rules, nil when blocking was never set.
+func (s *blockStore) load() *blockRules {
+	return s.rules.Load()
+}
+
+// set replaces the rules, the statistics start over.
+func (s *blockStore) set(r *blockRules) {
+	if r != nil {
+		r.since = time.Now()
+	}
+	s.rules.Store(r)
+}
+
+// pausedRequestAction answers a paused request: blocked requests fail as blocked by the client,
+// the others continue with the scoped headers of their origin.
+func (bs *BrowserServer) pausedRequestAction(ev *fetch.EventRequestPaused) chromedp.Action {
+	if ev.Request != nil {
+		if rule := bs.blocking.load().match(ev.Request.URL, ev.ResourceType); rule != nil {
+			return fetch.FailRequest(ev.RequestID, network.ErrorReasonBlockedByClient)
+		}
+	}
+	var action chromedp.Action
+	bs.auth.with(func(as *authState) { action = as.continueRequest(ev) })
+	return action
+}
+
+// applyDefaultBlocking installs the rules of the config when the browser is first started.
+func (bs *BrowserServer) applyDefaultBlocking() {
+	rules := bs.cfg().blockRules
+	if !rules.active() {
+		return
+	}
+	bs.blocking.set(rules)
+	if err := bs.emulate(context.Background(), bs.fetchAction()); err != nil {
+		bs.Logger.Warn().Err(err).Msg("failed to apply the request blocking of the config")
+	}
+}
+
+// stringArray parses an optional array of strings argument.
+func stringArray(raw interface{}, name string) ([]string, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array of strings", name)
+	}
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be an array of strings", name)
+		}
+		values = append(values, s)
+	}
+	return values, nil
+}
+
+// addBlockingTools registers browser_set_blocking and browser_get_blocking_stats.
+func (bs *BrowserServer) addBlockingTools() {
+	bs.addTool(mcp.NewTool(
+		"browser_set_blocking",
+		mcp.WithDescription("Block requests the task doesn't need, e.g. images, fonts and trackers, to make navigation faster and more reliable. The rules replace the previous ones, including those of the config, and persist across navigations. The page document is never blocked, XHR and fetch only with block_xhr. Call without arguments to turn blocking off."),
+		mcp.WithArray("resource_types",
+			mcp.Description("Resource types to block: "+blockableTypeNames()),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithArray("url_patterns",
+			mcp.Description("Regular expressions of request URLs to block, e.g. [\"doubleclick\\\\.net\", \"google-analytics\\\\.com\"]"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithArray("allow_patterns",
+			mcp.Description("Regular expressions of request URLs that are never blocked, they win over resource_types and url_patterns"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithBoolean("block_xhr",
+			mcp.Description("Also block XHR and fetch requests matching url_patterns (default: false)"),
+		),
+	), bs.handleSetBlocking)
+
+	bs.addTool(mcp.NewTool(
+		"browser_get_blocking_stats",
+		mcp.WithDescription("Report how many requests were blocked since the current blocking rules took effect, by resource type and URL pattern, and how many were let through by allow patterns."),
+	), bs.handleGetBlockingStats)
+}
+
+func (bs *BrowserServer) handleSetBlocking(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	var lists [3][]string
+	for i, name := range []string{"resource_types", "url_patterns", "allow_patterns"} {
+		values, err := stringArray(args[name], name)
+		if err != nil {
+			return comm.ArgumentError(err), nil
+		}
+		lists[i] = values
+	}
+	blockXHR, _ := args["block_xhr"].(bool)
+	rules, err := newBlockRules(lists[0], lists[1], lists[2], blockXHR)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+
+	bs.blocking.set(rules)
+	if err := bs.emulate(ctx, bs.fetchAction()); err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to apply request blocking", err)), nil
+	}
+	return mcp.NewToolResultText(rules.describe()), nil
+}
+
+func (bs *BrowserServer) handleGetBlockingStats(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	data, err := json.Marshal(bs.blocking.load().stats())
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to encode the result", err), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
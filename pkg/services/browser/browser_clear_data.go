@@ -0,0 +1,286 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/domstorage"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Browsing data types of browser_clear_data.
+const (
+	clearCookies        = "cookies"
+	clearCache          = "cache"
+	clearLocalStorage   = "local_storage"
+	clearSessionStorage = "session_storage"
+	clearServiceWorkers = "service_workers"
+	clearAll            = "all"
+)
+
+// clearDataTypes are the data types in the order they are cleared and reported.
+var clearDataTypes = []string{clearCookies, clearCache, clearLocalStorage, clearSessionStorage, clearServiceWorkers}
+
+var (
+	// ErrInvalidOrigin is returned when the origin of browser_clear_data is not an http(s) origin.
+	ErrInvalidOrigin = errors.New("invalid origin")
+	// ErrProfileWipeDisabled is returned when restart_profile is used without allow_profile_wipe.
+	ErrProfileWipeDisabled = errors.New("profile wipe is disabled, set allow_profile_wipe in the Browser config to enable it")
+)
+
+// addClearDataTool registers browser_clear_data. Like browser_set_headless it bypasses addTool,
+// restart_profile takes the write side of opLock itself, and clearing data is not a step worth
+// recording into a macro.
+func (bs *BrowserServer) addClearDataTool() {
+	clearData := bs.withDrain(bs.withRecovery(bs.handleClearData))
+	bs.AddTool(mcp.NewTool(
+		"browser_clear_data",
+		mcp.WithDescription("Clear browsing data of the persistent browser profile, globally or for one site. Use it when stale cookies or cache cause unexpected logins or outdated pages. Reports what was cleared."),
+		mcp.WithArray("types",
+			mcp.Description("Data to clear: cookies, cache, local_storage, session_storage, service_workers or all (default: all). local_storage, session_storage and service_workers require an origin; session_storage is the one of the current tab"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithString("origin",
+			mcp.Description("Only clear the data of this site, e.g. https://example.com. With an origin, cache clears the Cache Storage of the site"),
+		),
+		mcp.WithBoolean("restart_profile",
+			mcp.Description("Shut down the browser, delete the whole profile (all cookies, logins, cache and storage of every site) and start a fresh browser. Ignores types and origin, requires allow_profile_wipe in the config"),
+		),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if restart, _ := request.GetArguments()["restart_profile"].(bool); restart {
+			return bs.handleRestartProfile(ctx, request)
+		}
+		return clearData(ctx, request)
+	})
+}
+
+func (bs *BrowserServer) handleClearData(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	types, all, err := parseClearTypes(args["types"])
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	origin := ""
+	if raw, _ := args["origin"].(string); strings.TrimSpace(raw) != "" {
+		if origin, err = normalizeOrigin(raw); err != nil {
+			return comm.ArgumentError(err), nil
+		}
+	}
+	actions, cleared, skipped, err := clearDataActions(types, all, origin)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+
+	runCtx, cancel := context.WithTimeout(bs.pageContext(ctx), time.Duration(bs.cfg().SelectorQueryTimeout)*time.Second)
+	defer cancel()
+	if err := chromedp.Run(runCtx, actions...); err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to clear browsing data", err)), nil
+	}
+	scope := "all sites"
+	if origin != "" {
+		scope = origin
+	}
+	msg := fmt.Sprintf("Cleared %s for %s", strings.Join(cleared, ", "), scope)
+	if len(skipped) > 0 {
+		msg += fmt.Sprintf(", skipped %s which can only be cleared for an origin", strings.Join(skipped, ", "))
+	}
+	return mcp.NewToolResultText(msg), nil
+}
+
+// parseClearTypes parses the types argument and reports whether all types were requested, a
+// missing argument means all. Duplicates are removed.
+func parseClearTypes(raw interface{}) ([]string, bool, error) {
+	if raw == nil {
+		return clearDataTypes, true, nil
+	}
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil, false, fmt.Errorf("types must be an array of strings")
+	}
+	if len(items) == 0 {
+		return clearDataTypes, true, nil
+	}
+	selected := make(map[string]bool)
+	for _, item := range items {
+		name, ok := item.(string)
+		if !ok {
+			return nil, false, fmt.Errorf("types must be an array of strings")
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == clearAll {
+			return clearDataTypes, true, nil
+		}
+		known := false
+		for _, t := range clearDataTypes {
+			known = known || t == name
+		}
+		if !known {
+			return nil, false, fmt.Errorf("unknown data type %q, valid types are %s and %s", name, strings.Join(clearDataTypes, ", "), clearAll)
+		}
+		selected[name] = true
+	}
+	types := make([]string, 0, len(selected))
+	for _, t := range clearDataTypes {
+		if selected[t] {
+			types = append(types, t)
+		}
+	}
+	return types, false, nil
+}
+
+// normalizeOrigin validates an http(s) origin and returns it as scheme://host[:port].
+func normalizeOrigin(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("%w %q: %v", ErrInvalidOrigin, raw, err)
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return "", fmt.Errorf("%w %q: the scheme must be http or https", ErrInvalidOrigin, raw)
+	}
+	if u.Host == "" || u.Hostname() == "" {
+		return "", fmt.Errorf("%w %q: missing host", ErrInvalidOrigin, raw)
+	}
+	if u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("%w %q: an origin has no credentials, path, query or fragment", ErrInvalidOrigin, raw)
+	}
+	return scheme + "://" + strings.ToLower(u.Host), nil
+}
+
+// clearDataActions maps the data types to CDP commands and returns the cleared and the skipped
+// types. Without an origin, cookies and cache are cleared for all sites and the storage types are
+// skipped when all types were requested, or rejected when they were named. With an origin, every
+// type except session storage is cleared by a single Storage.clearDataForOrigin.
+func clearDataActions(types []string, all bool, origin string) (actions []chromedp.Action, cleared, skipped []string, err error) {
+	if origin == "" {
+		for _, t := range types {
+			switch t {
+			case clearCookies:
+				actions = append(actions, network.ClearBrowserCookies())
+			case clearCache:
+				actions = append(actions, network.ClearBrowserCache())
+			default:
+				skipped = append(skipped, t)
+				continue
+			}
+			cleared = append(cleared, t)
+		}
+		// 显式指定的存储类型缺少 origin 时报错，all 展开的存储类型则跳过
+		if len(skipped) > 0 && !all {
+			return nil, nil, nil, fmt.Errorf("%s can only be cleared for an origin", strings.Join(skipped, ", "))
+		}
+		return actions, cleared, skipped, nil
+	}
+
+	var storageTypes []string
+	for _, t := range types {
+		switch t {
+		case clearCookies:
+			storageTypes = append(storageTypes, string(storage.TypeCookies))
+		case clearCache:
+			storageTypes = append(storageTypes, string(storage.TypeCacheStorage))
+		case clearLocalStorage:
+			storageTypes = append(storageTypes, string(storage.TypeLocalStorage))
+		case clearServiceWorkers:
+			storageTypes = append(storageTypes, string(storage.TypeServiceWorkers))
+		case clearSessionStorage:
+			actions = append(actions, domstorage.Enable(), domstorage.Clear(&domstorage.StorageID{SecurityOrigin: origin, IsLocalStorage: false}))
+		}
+		cleared = append(cleared, t)
+	}
+	if len(storageTypes) > 0 {
+		actions = append([]chromedp.Action{storage.ClearDataForOrigin(origin, strings.Join(storageTypes, ","))}, actions...)
+	}
+	return actions, cleared, nil, nil
+}
+
+// handleRestartProfile shuts the browser down, deletes the contents of BrowserDataPath and starts
+// a fresh browser. Like switching the headless mode, it waits for the calls in flight.
+func (bs *BrowserServer) handleRestartProfile(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if !bs.cfg().AllowProfileWipe {
+		return comm.ToolError(comm.ErrCodePermissionDenied, ErrProfileWipeDisabled.Error(), ""), nil
+	}
+	if bs.cfg().remote() {
+		return comm.ToolError(comm.ErrCodePermissionDenied, ErrRemoteBrowser.Error(), "the profile of a remote browser is not managed by MoLing"), nil
+	}
+
+	bs.opLock.Lock()
+	defer bs.opLock.Unlock()
+	bs.restartLock.Lock()
+	defer bs.restartLock.Unlock()
+
+	bs.stopBrowser()
+	removed, err := wipeDirectory(bs.cfg().BrowserDataPath)
+	if err != nil {
+		bs.Logger.Error().Err(err).Str("path", bs.cfg().BrowserDataPath).Msg("failed to wipe the browser profile")
+	}
+	if serr := bs.starter(); serr != nil {
+		return comm.ToolErrorFromErr("failed to restart browser after wiping the profile", serr), nil
+	}
+	bs.resetPermissions()
+	bs.reapplyEmulation()
+	bs.reapplyAuth()
+	if err != nil {
+		return comm.ToolErrorFromErr("the browser was restarted, but the profile was only partly wiped", err), nil
+	}
+	bs.Logger.Info().Int("entries", removed).Msg("browser profile wiped")
+	return mcp.NewToolResultText(fmt.Sprintf("The browser profile was wiped (%d entries removed from %s) and the browser was restarted with a fresh profile", removed, bs.cfg().BrowserDataPath)), nil
+}
+
+// wipeDirectory removes the contents of dir but keeps dir itself, and returns the number of
+// removed entries. It refuses to wipe an empty path or a filesystem root.
+func wipeDirectory(dir string) (int, error) {
+	if strings.TrimSpace(dir) == "" {
+		return 0, fmt.Errorf("refusing to wipe an empty path")
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return 0, err
+	}
+	if filepath.Dir(abs) == abs {
+		return 0, fmt.Errorf("refusing to wipe the filesystem root %s", abs)
+	}
+	entries, err := os.ReadDir(abs)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	removed := 0
+	var errs []error
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(abs, entry.Name())); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
+	}
+	return removed, errors.Join(errs...)
+}
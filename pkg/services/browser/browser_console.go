@@ -0,0 +1,332 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	cdplog "github.com/chromedp/cdproto/log"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// 控制台日志的级别
+const (
+	ConsoleLevelDebug   = "debug"
+	ConsoleLevelLog     = "log"
+	ConsoleLevelInfo    = "info"
+	ConsoleLevelWarning = "warning"
+	ConsoleLevelError   = "error"
+
+	consoleSourceAPI       = "console-api" // console.log 等调用
+	consoleSourceException = "exception"   // 未捕获的异常
+	consoleMaxTextLength   = 2000          // 单条日志文本的最大长度，更长的以 "..." 结尾
+)
+
+// ConsoleEntry is a console message or an uncaught exception of the console log.
+type ConsoleEntry struct {
+	Time   string `json:"time"`
+	Level  string `json:"level"`  // debug, log, info, warning 或 error
+	Source string `json:"source"` // console-api、exception 或 Log 域的来源，如 network
+	Text   string `json:"text"`
+	URL    string `json:"url,omitempty"`
+	Line   int64  `json:"line,omitempty"` // 从 1 开始的行号
+}
+
+// consoleLog buffers the last console messages of the tabs it listens to. The event callbacks of
+// several tabs call it concurrently.
+type consoleLog struct {
+	mu        sync.Mutex
+	entries   []ConsoleEntry
+	size      int // 保留的条目数上限
+	dropped   int // 超出上限被丢弃的旧条目数
+	listening map[context.Context]context.CancelFunc
+}
+
+// listen registers a tab, it returns false when the tab is already logged. size is the capacity
+// of the buffer, set on the first call.
+func (cl *consoleLog) listen(tab context.Context, size int, cancel context.CancelFunc) bool {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if _, ok := cl.listening[tab]; ok {
+		return false
+	}
+	if cl.listening == nil {
+		cl.listening = make(map[context.Context]context.CancelFunc)
+	}
+	if cl.size == 0 {
+		cl.size = size
+	}
+	cl.listening[tab] = cancel
+	return true
+}
+
+// unlisten stops logging a tab.
+func (cl *consoleLog) unlisten(tab context.Context) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if cancel, ok := cl.listening[tab]; ok {
+		cancel()
+		delete(cl.listening, tab)
+	}
+}
+
+// stopAll detaches the listeners of all tabs.
+func (cl *consoleLog) stopAll() {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	for _, cancel := range cl.listening {
+		cancel()
+	}
+	cl.listening = nil
+}
+
+// enabled reports whether a tab is logged.
+func (cl *consoleLog) enabled() bool {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return len(cl.listening) > 0
+}
+
+// handleEvent records console API calls, uncaught exceptions and Log domain entries. It is
+// called by the event listeners of the tabs and must not block.
+func (cl *consoleLog) handleEvent(ev interface{}) {
+	var entry ConsoleEntry
+	switch ev := ev.(type) {
+	case *runtime.EventConsoleAPICalled:
+		entry = ConsoleEntry{Time: consoleTime(ev.Timestamp), Level: consoleAPILevel(ev.Type), Source: consoleSourceAPI, Text: remoteObjectsText(ev.Args)}
+		entry.URL, entry.Line = stackLocation(ev.StackTrace)
+	case *runtime.EventExceptionThrown:
+		details := ev.ExceptionDetails
+		if details == nil {
+			return
+		}
+		entry = ConsoleEntry{Time: consoleTime(ev.Timestamp), Level: ConsoleLevelError, Source: consoleSourceException, Text: details.Text, URL: details.URL, Line: details.LineNumber + 1}
+		if details.Exception != nil && details.Exception.Description != "" {
+			entry.Text = details.Exception.Description
+		}
+		if entry.URL == "" {
+			entry.URL, entry.Line = stackLocation(details.StackTrace)
+		}
+	case *cdplog.EventEntryAdded:
+		if ev.Entry == nil {
+			return
+		}
+		entry = ConsoleEntry{Time: consoleTime(ev.Entry.Timestamp), Level: logEntryLevel(ev.Entry.Level), Source: string(ev.Entry.Source), Text: ev.Entry.Text, URL: ev.Entry.URL}
+		if ev.Entry.LineNumber > 0 {
+			entry.Line = ev.Entry.LineNumber + 1
+		}
+	default:
+		return
+	}
+	if len(entry.Text) > consoleMaxTextLength {
+		entry.Text = entry.Text[:consoleMaxTextLength] + "..."
+	}
+	cl.add(entry)
+}
+
+// add appends an entry, dropping the oldest one when the buffer is full.
+func (cl *consoleLog) add(entry ConsoleEntry) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if cl.size <= 0 {
+		return
+	}
+	if len(cl.entries) >= cl.size {
+		n := copy(cl.entries, cl.entries[len(cl.entries)-cl.size+1:])
+		cl.dropped += len(cl.entries) - n
+		cl.entries = cl.entries[:n]
+	}
+	cl.entries = append(cl.entries, entry)
+}
+
+// filter returns the buffered entries of level, all entries when level is empty, oldest first,
+// and the number of dropped entries.
+func (cl *consoleLog) filter(level string) ([]ConsoleEntry, int) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	entries := make([]ConsoleEntry, 0, len(cl.entries))
+	for _, e := range cl.entries {
+		if level == "" || e.Level == level {
+			entries = append(entries, e)
+		}
+	}
+	return entries, cl.dropped
+}
+
+// clear empties the buffer and returns the number of removed entries.
+func (cl *consoleLog) clear() int {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	removed := len(cl.entries)
+	cl.entries, cl.dropped = nil, 0
+	return removed
+}
+
+// consoleTime formats the time of an event, the current time when the event has none.
+func consoleTime(ts *runtime.Timestamp) string {
+	if ts == nil {
+		return time.Now().Format(time.RFC3339Nano)
+	}
+	return ts.Time().Format(time.RFC3339Nano)
+}
+
+// consoleAPILevel maps the console method to a level, e.g. console.assert is an error and
+// console.table a log.
+func consoleAPILevel(t runtime.APIType) string {
+	switch t {
+	case runtime.APITypeDebug:
+		return ConsoleLevelDebug
+	case runtime.APITypeInfo:
+		return ConsoleLevelInfo
+	case runtime.APITypeWarning:
+		return ConsoleLevelWarning
+	case runtime.APITypeError, runtime.APITypeAssert:
+		return ConsoleLevelError
+	}
+	return ConsoleLevelLog
+}
+
+// logEntryLevel maps the level of a Log domain entry, verbose is debug.
+func logEntryLevel(level cdplog.Level) string {
+	switch level {
+	case cdplog.LevelVerbose:
+		return ConsoleLevelDebug
+	case cdplog.LevelWarning:
+		return ConsoleLevelWarning
+	case cdplog.LevelError:
+		return ConsoleLevelError
+	}
+	return ConsoleLevelInfo
+}
+
+// remoteObjectsText joins the arguments of a console call the way DevTools prints them: strings
+// without quotes, other values by their JSON value or description.
+func remoteObjectsText(args []*runtime.RemoteObject) string {
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == nil {
+			continue
+		}
+		var s string
+		switch {
+		case len(arg.Value) > 0:
+			if err := json.Unmarshal(arg.Value, &s); err != nil {
+				s = string(arg.Value)
+			}
+		case arg.UnserializableValue != "":
+			s = string(arg.UnserializableValue)
+		case arg.Description != "":
+			s = arg.Description
+		default:
+			s = string(arg.Type)
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " ")
+}
+
+// stackLocation returns the URL and the 1-based line of the top frame of a stack trace.
+func stackLocation(st *runtime.StackTrace) (string, int64) {
+	if st == nil || len(st.CallFrames) == 0 || st.CallFrames[0] == nil {
+		return "", 0
+	}
+	return st.CallFrames[0].URL, st.CallFrames[0].LineNumber + 1
+}
+
+// parseConsoleLevel validates the level argument of browser_console_read.
+func parseConsoleLevel(args map[string]interface{}) (string, error) {
+	v, ok := args["level"]
+	if !ok {
+		return "", nil
+	}
+	level, _ := v.(string)
+	switch level = strings.ToLower(strings.TrimSpace(level)); level {
+	case "":
+		return "", nil
+	case "warn":
+		return ConsoleLevelWarning, nil
+	case ConsoleLevelDebug, ConsoleLevelLog, ConsoleLevelInfo, ConsoleLevelWarning, ConsoleLevelError:
+		return level, nil
+	}
+	return "", fmt.Errorf("level must be one of %s, %s, %s, %s or %s", ConsoleLevelDebug, ConsoleLevelLog, ConsoleLevelInfo, ConsoleLevelWarning, ConsoleLevelError)
+}
+
+// addConsoleTools registers browser_console_start, browser_console_read and
+// browser_console_clear.
+func (bs *BrowserServer) addConsoleTools() {
+	bs.addTool(mcp.NewTool(
+		"browser_console_start",
+		mcp.WithDescription("Start capturing the console of the current tab: console.log, warn and error calls, uncaught exceptions and browser messages such as failed resources, with their level, text, source URL and line. The last console_log_size messages of the config are kept, read them with browser_console_read"),
+	), bs.handleConsoleStart)
+	bs.addTool(mcp.NewTool(
+		"browser_console_read",
+		mcp.WithDescription("Return the captured console messages as JSON, oldest first. Start capturing with browser_console_start"),
+		mcp.WithString("level",
+			mcp.Description("Only return messages of this level"),
+			mcp.Enum(ConsoleLevelDebug, ConsoleLevelLog, ConsoleLevelInfo, ConsoleLevelWarning, ConsoleLevelError),
+		),
+	), bs.handleConsoleRead)
+	bs.addTool(mcp.NewTool(
+		"browser_console_clear",
+		mcp.WithDescription("Remove the captured console messages, capturing continues"),
+	), bs.handleConsoleClear)
+}
+
+func (bs *BrowserServer) handleConsoleStart(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tab := bs.pageContext(ctx)
+	listenCtx, cancel := context.WithCancel(tab)
+	if !bs.console.listen(tab, bs.cfg().ConsoleLogSize, cancel) {
+		cancel()
+		return mcp.NewToolResultText("Console capture is already enabled in this tab"), nil
+	}
+	bs.listenTarget(listenCtx, bs.console.handleEvent)
+	if err := bs.emulate(ctx, runtime.Enable(), cdplog.Enable()); err != nil {
+		bs.console.unlisten(tab)
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to enable console events", err)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Console capture enabled, the last %d messages are kept", bs.cfg().ConsoleLogSize)), nil
+}
+
+func (bs *BrowserServer) handleConsoleRead(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	level, err := parseConsoleLevel(request.GetArguments())
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ArgumentError(err)), nil
+	}
+	if !bs.console.enabled() {
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInvalidArgument, "console capture is not enabled, call browser_console_start first", "")), nil
+	}
+	entries, dropped := bs.console.filter(level)
+	data, err := json.Marshal(struct {
+		Entries []ConsoleEntry `json:"entries"`
+		Dropped int            `json:"dropped"` // 缓冲区满后丢弃的旧条目
+	}{entries, dropped})
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to marshal the console log", err)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+func (bs *BrowserServer) handleConsoleClear(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return mcp.NewToolResultText(fmt.Sprintf("Cleared %d console messages", bs.console.clear())), nil
+}
@@ -0,0 +1,252 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// CookieInfo is a cookie as returned by browser_get_cookies.
+type CookieInfo struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Domain   string  `json:"domain"`
+	Path     string  `json:"path"`
+	Expires  float64 `json:"expires"` // 过期时间（Unix 秒），会话 Cookie 为 -1
+	Secure   bool    `json:"secure"`
+	HTTPOnly bool    `json:"http_only"`
+	SameSite string  `json:"same_site,omitempty"`
+}
+
+// cookieDomainMatches reports whether a cookie of cookieDomain belongs to domain: the domain
+// itself, with or without the leading dot, or one of its subdomains.
+func cookieDomainMatches(cookieDomain, domain string) bool {
+	cd := strings.TrimPrefix(strings.ToLower(cookieDomain), ".")
+	d := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	return cd == d || strings.HasSuffix(cd, "."+d)
+}
+
+// filterCookies returns the cookies of domain, all cookies when domain is empty.
+func filterCookies(cookies []*network.Cookie, domain string) []*network.Cookie {
+	if strings.TrimSpace(domain) == "" {
+		return cookies
+	}
+	var matched []*network.Cookie
+	for _, c := range cookies {
+		if cookieDomainMatches(c.Domain, domain) {
+			matched = append(matched, c)
+		}
+	}
+	return matched
+}
+
+// cookieInfos converts the CDP cookies for the result.
+func cookieInfos(cookies []*network.Cookie) []CookieInfo {
+	infos := make([]CookieInfo, 0, len(cookies))
+	for _, c := range cookies {
+		expires := c.Expires
+		if c.Session {
+			expires = -1
+		}
+		infos = append(infos, CookieInfo{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  expires,
+			Secure:   c.Secure,
+			HTTPOnly: c.HTTPOnly,
+			SameSite: string(c.SameSite),
+		})
+	}
+	return infos
+}
+
+// parseSetCookie builds the parameters of browser_set_cookie. Without a domain the cookie is set
+// for pageURL, the URL of the current page.
+func parseSetCookie(args map[string]interface{}, pageURL string, now time.Time) (*network.SetCookieParams, error) {
+	name, _ := args["name"].(string)
+	if strings.TrimSpace(name) == "" {
+		return nil, errors.New("name must be a non-empty string")
+	}
+	value, ok := args["value"].(string)
+	if !ok {
+		return nil, errors.New("value must be a string")
+	}
+	params := network.SetCookie(name, value)
+	domain, _ := args["domain"].(string)
+	if params.Domain = strings.TrimSpace(domain); params.Domain == "" {
+		if !strings.HasPrefix(pageURL, "http://") && !strings.HasPrefix(pageURL, "https://") {
+			return nil, fmt.Errorf("domain is required when the current page is not a web page (%s)", pageURL)
+		}
+		params.URL = pageURL
+	}
+	path, _ := args["path"].(string)
+	if params.Path = strings.TrimSpace(path); params.Path == "" {
+		params.Path = "/"
+	}
+	params.Secure, _ = args["secure"].(bool)
+	params.HTTPOnly, _ = args["http_only"].(bool)
+	if v, ok := args["expiry"]; ok {
+		seconds, ok := v.(float64)
+		if !ok || seconds <= 0 {
+			return nil, errors.New("expiry must be a positive number of seconds")
+		}
+		expires := cdp.TimeSinceEpoch(now.Add(time.Duration(seconds * float64(time.Second))))
+		params.Expires = &expires
+	}
+	return params, nil
+}
+
+// addCookieTools registers the cookie tools.
+func (bs *BrowserServer) addCookieTools() {
+	bs.addTool(mcp.NewTool(
+		"browser_get_cookies",
+		mcp.WithDescription("Get the cookies of the browser as a JSON array of name, value, domain, path, expires (Unix seconds, -1 for session cookies), secure, http_only and same_site"),
+		mcp.WithString("domain",
+			mcp.Description("Only return the cookies of this domain and its subdomains, e.g. example.com"),
+		),
+	), bs.handleGetCookies)
+	bs.addTool(mcp.NewTool(
+		"browser_set_cookie",
+		mcp.WithDescription("Set a cookie in the browser, e.g. a session token to reach a site that requires a login"),
+		mcp.WithString("name",
+			mcp.Description("Cookie name"),
+			mcp.Required(),
+		),
+		mcp.WithString("value",
+			mcp.Description("Cookie value"),
+			mcp.Required(),
+		),
+		mcp.WithString("domain",
+			mcp.Description("Cookie domain, e.g. example.com or .example.com for all subdomains (default: the host of the current page)"),
+		),
+		mcp.WithString("path",
+			mcp.Description("Cookie path (default: /)"),
+		),
+		mcp.WithBoolean("secure",
+			mcp.Description("Only send the cookie over HTTPS (default: false)"),
+		),
+		mcp.WithBoolean("http_only",
+			mcp.Description("Hide the cookie from JavaScript (default: false)"),
+		),
+		mcp.WithNumber("expiry",
+			mcp.Description("Lifetime of the cookie in seconds from now (default: a session cookie)"),
+		),
+	), bs.handleSetCookie)
+	bs.addTool(mcp.NewTool(
+		"browser_clear_cookies",
+		mcp.WithDescription("Delete all cookies of the browser, or only those of one domain and its subdomains"),
+		mcp.WithString("domain",
+			mcp.Description("Only delete the cookies of this domain, e.g. example.com"),
+		),
+	), bs.handleClearCookies)
+}
+
+// cookieContext returns the tab of the call with the selector query timeout.
+func (bs *BrowserServer) cookieContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(bs.pageContext(ctx), time.Duration(bs.cfg().SelectorQueryTimeout)*time.Second)
+}
+
+// allCookies returns the cookies of all sites.
+func (bs *BrowserServer) allCookies(ctx context.Context) ([]*network.Cookie, error) {
+	var cookies []*network.Cookie
+	err := bs.runner.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		cookies, err = storage.GetCookies().Do(ctx)
+		return err
+	}))
+	return cookies, err
+}
+
+func (bs *BrowserServer) handleGetCookies(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	domain, _ := request.GetArguments()["domain"].(string)
+	runCtx, cancel := bs.cookieContext(ctx)
+	defer cancel()
+	cookies, err := bs.allCookies(runCtx)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to get cookies", err)), nil
+	}
+	data, err := json.Marshal(cookieInfos(filterCookies(cookies, domain)))
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to marshal cookies", err)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+func (bs *BrowserServer) handleSetCookie(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	runCtx, cancel := bs.cookieContext(ctx)
+	defer cancel()
+	var pageURL string
+	if domain, _ := args["domain"].(string); strings.TrimSpace(domain) == "" {
+		if err := bs.runner.Evaluate(runCtx, `location.href`, &pageURL); err != nil {
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to get the URL of the current page", err)), nil
+		}
+	}
+	params, err := parseSetCookie(args, pageURL, time.Now())
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ArgumentError(err)), nil
+	}
+	if err := bs.runner.Run(runCtx, params); err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr(fmt.Sprintf("failed to set cookie %s", params.Name), err)), nil
+	}
+	target := params.Domain
+	if target == "" {
+		target = params.URL
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Set cookie %s for %s%s", params.Name, target, params.Path)), nil
+}
+
+func (bs *BrowserServer) handleClearCookies(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	domain, _ := request.GetArguments()["domain"].(string)
+	runCtx, cancel := bs.cookieContext(ctx)
+	defer cancel()
+	if domain = strings.TrimSpace(domain); domain == "" {
+		if err := bs.runner.Run(runCtx, network.ClearBrowserCookies()); err != nil {
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to clear cookies", err)), nil
+		}
+		return mcp.NewToolResultText("Cleared all cookies"), nil
+	}
+	cookies, err := bs.allCookies(runCtx)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to get cookies", err)), nil
+	}
+	matched := filterCookies(cookies, domain)
+	actions := make([]chromedp.Action, 0, len(matched))
+	for _, c := range matched {
+		actions = append(actions, network.DeleteCookies(c.Name).WithDomain(c.Domain).WithPath(c.Path))
+	}
+	if len(actions) > 0 {
+		if err := bs.runner.Run(runCtx, actions...); err != nil {
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr(fmt.Sprintf("failed to clear cookies of %s", domain), err)), nil
+		}
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Cleared %d cookies of %s", len(matched), domain)), nil
+}
@@ -20,10 +20,12 @@ package browser
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/chromedp/cdproto/target"
 	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
@@ -32,11 +34,11 @@ func (bs *BrowserServer) handleDebugEnable(ctx context.Context, request mcp.Call
 	args := request.GetArguments()
 	enabled, ok := args["enabled"].(bool)
 	if !ok {
-		return mcp.NewToolResultError("enabled must be a boolean"), nil
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInvalidArgument, "enabled must be a boolean", "")), nil
 	}
 
 	var err error
-	rctx, cancel := context.WithCancel(bs.Ctx())
+	rctx, cancel := context.WithCancel(bs.pageContext(ctx))
 	defer cancel()
 
 	if enabled {
@@ -56,40 +58,55 @@ func (bs *BrowserServer) handleDebugEnable(ctx context.Context, request mcp.Call
 	}
 
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to %s debugging: %v",
-			map[bool]string{true: "enable", false: "disable"}[enabled], err)), nil
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr(fmt.Sprintf("failed to %s debugging",
+			map[bool]string{true: "enable", false: "disable"}[enabled]), err)), nil
 	}
 	return mcp.NewToolResultText(fmt.Sprintf("Debugging %s",
 		map[bool]string{true: "enabled", false: "disabled"}[enabled])), nil
 }
 
-// handleSetBreakpoint handles setting a breakpoint in the browser.
-func (bs *BrowserServer) handleSetBreakpoint(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	args := request.GetArguments()
+// breakpointParams builds the parameters of Debugger.setBreakpoint from the arguments of
+// browser_set_breakpoint.
+func breakpointParams(args map[string]interface{}) (map[string]interface{}, error) {
 	url, ok := args["url"].(string)
 	if !ok {
-		return mcp.NewToolResultError("url must be a string"), nil
+		return nil, errors.New("url must be a string")
 	}
-
-	line, ok := args["line"].(float64)
-	if !ok {
-		return mcp.NewToolResultError("line must be a number"), nil
+	if args["line"] == nil {
+		return nil, errors.New("line must be a number")
+	}
+	line, err := intArg(args, "line", 0)
+	if err != nil {
+		return nil, err
+	}
+	column, err := intArg(args, "column", 0)
+	if err != nil {
+		return nil, err
+	}
+	if line < 0 || column < 0 {
+		return nil, errors.New("line and column must not be negative")
 	}
-
-	column, _ := args["column"].(float64)
 	condition, _ := args["condition"].(string)
+	return map[string]interface{}{
+		"url":       url,
+		"line":      line,
+		"column":    column,
+		"condition": condition,
+	}, nil
+}
+
+// handleSetBreakpoint handles setting a breakpoint in the browser.
+func (bs *BrowserServer) handleSetBreakpoint(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	params, err := breakpointParams(request.GetArguments())
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ArgumentError(err)), nil
+	}
 
 	var breakpointID string
-	rctx, cancel := context.WithCancel(bs.Ctx())
+	rctx, cancel := context.WithCancel(bs.pageContext(ctx))
 	defer cancel()
-	err := chromedp.Run(rctx, chromedp.ActionFunc(func(ctx context.Context) error {
+	err = chromedp.Run(rctx, chromedp.ActionFunc(func(ctx context.Context) error {
 		t := chromedp.FromContext(ctx).Target
-		params := map[string]interface{}{
-			"url":       url,
-			"line":      int(line),
-			"column":    int(column),
-			"condition": condition,
-		}
 
 		var result map[string]interface{}
 		// 使用Execute方法执行Debugger.setBreakpoint命令
@@ -97,6 +114,7 @@ func (bs *BrowserServer) handleSetBreakpoint(ctx context.Context, request mcp.Ca
 			return err
 		}
 
+		var ok bool
 		breakpointID, ok = result["breakpointId"].(string)
 		if !ok {
 			breakpointID = ""
@@ -106,7 +124,7 @@ func (bs *BrowserServer) handleSetBreakpoint(ctx context.Context, request mcp.Ca
 	}))
 
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to set breakpoint: %v", err)), nil
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to set breakpoint", err)), nil
 	}
 	return mcp.NewToolResultText(fmt.Sprintf("Breakpoint set with ID: %s", breakpointID)), nil
 }
@@ -116,9 +134,9 @@ func (bs *BrowserServer) handleRemoveBreakpoint(ctx context.Context, request mcp
 	args := request.GetArguments()
 	breakpointID, ok := args["breakpointId"].(string)
 	if !ok {
-		return mcp.NewToolResultError("breakpointId must be a string"), nil
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInvalidArgument, "breakpointId must be a string", "")), nil
 	}
-	rctx, cancel := context.WithCancel(bs.Ctx())
+	rctx, cancel := context.WithCancel(bs.pageContext(ctx))
 	defer cancel()
 	err := chromedp.Run(rctx, chromedp.ActionFunc(func(ctx context.Context) error {
 		t := chromedp.FromContext(ctx).Target
@@ -129,14 +147,14 @@ func (bs *BrowserServer) handleRemoveBreakpoint(ctx context.Context, request mcp
 	}))
 
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to remove breakpoint: %v", err)), nil
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to remove breakpoint", err)), nil
 	}
 	return mcp.NewToolResultText(fmt.Sprintf("Breakpoint %s removed", breakpointID)), nil
 }
 
 // handlePause handles pausing the JavaScript execution in the browser.
 func (bs *BrowserServer) handlePause(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	rctx, cancel := context.WithCancel(bs.Ctx())
+	rctx, cancel := context.WithCancel(bs.pageContext(ctx))
 	defer cancel()
 	err := chromedp.Run(rctx, chromedp.ActionFunc(func(ctx context.Context) error {
 		t := chromedp.FromContext(ctx).Target
@@ -145,14 +163,14 @@ func (bs *BrowserServer) handlePause(ctx context.Context, request mcp.CallToolRe
 	}))
 
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to pause execution: %v", err)), nil
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to pause execution", err)), nil
 	}
 	return mcp.NewToolResultText("JavaScript execution paused"), nil
 }
 
 // handleResume handles resuming the JavaScript execution in the browser.
 func (bs *BrowserServer) handleResume(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	rctx, cancel := context.WithCancel(bs.Ctx())
+	rctx, cancel := context.WithCancel(bs.pageContext(ctx))
 	defer cancel()
 	err := chromedp.Run(rctx, chromedp.ActionFunc(func(ctx context.Context) error {
 		t := chromedp.FromContext(ctx).Target
@@ -161,7 +179,7 @@ func (bs *BrowserServer) handleResume(ctx context.Context, request mcp.CallToolR
 	}))
 
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to resume execution: %v", err)), nil
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to resume execution", err)), nil
 	}
 	return mcp.NewToolResultText("JavaScript execution resumed"), nil
 }
@@ -169,7 +187,7 @@ func (bs *BrowserServer) handleResume(ctx context.Context, request mcp.CallToolR
 // handleStepOver handles stepping over the next line of JavaScript code in the browser.
 func (bs *BrowserServer) handleGetCallstack(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	var callstack interface{}
-	rctx, cancel := context.WithCancel(bs.Ctx())
+	rctx, cancel := context.WithCancel(bs.pageContext(ctx))
 	defer cancel()
 	err := chromedp.Run(rctx, chromedp.ActionFunc(func(ctx context.Context) error {
 		t := chromedp.FromContext(ctx).Target
@@ -178,12 +196,12 @@ func (bs *BrowserServer) handleGetCallstack(ctx context.Context, request mcp.Cal
 	}))
 
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to get call stack: %v", err)), nil
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to get call stack", err)), nil
 	}
 
 	callstackJSON, err := json.Marshal(callstack)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal call stack: %v", err)), nil
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to marshal call stack", err)), nil
 	}
 
 	return mcp.NewToolResultText(fmt.Sprintf("Current call stack: %s", string(callstackJSON))), nil
@@ -0,0 +1,240 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	deviceNone      = "none" // browser_emulate_device 取消设备模拟
+	maxDeviceSize   = 10000  // 视口宽高上限，像素
+	maxDeviceScale  = 10     // 设备像素比上限
+	defaultDPRScale = 1      // 自定义尺寸的默认设备像素比
+)
+
+// ErrUnknownDevice is returned for a device name that is not a preset.
+var ErrUnknownDevice = errors.New("unknown device")
+
+// DeviceProfile is an emulated device: the viewport, the device pixel ratio, mobile and touch
+// emulation and the user agent.
+type DeviceProfile struct {
+	Name      string  `json:"name,omitempty"` // 预设名称，自定义尺寸时为空
+	Width     int64   `json:"width"`
+	Height    int64   `json:"height"`
+	Scale     float64 `json:"scale"`
+	Mobile    bool    `json:"mobile"`
+	Touch     bool    `json:"touch"`
+	UserAgent string  `json:"user_agent,omitempty"` // 为空时使用配置的 user_agent
+}
+
+// deviceKey normalizes a device name for the lookup.
+func deviceKey(name string) string {
+	return strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(name))
+}
+
+// deviceNames lists the presets in a stable order.
+func deviceNames() []string {
+	names := make([]string, 0, len(devicePresets))
+	for name := range devicePresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupDevice returns the preset of a device name.
+func lookupDevice(name string) (DeviceProfile, error) {
+	key := deviceKey(name)
+	for presetName, preset := range devicePresets {
+		if deviceKey(presetName) == key {
+			preset.Name = presetName
+			return preset, nil
+		}
+	}
+	return DeviceProfile{}, fmt.Errorf("%w %q, available: %s", ErrUnknownDevice, name, strings.Join(deviceNames(), ", "))
+}
+
+// parseDeviceProfile builds the device of browser_emulate_device from a preset and the explicit
+// overrides, or from the overrides alone, which then need width and height. It returns nil for
+// device none.
+func parseDeviceProfile(args map[string]interface{}) (*DeviceProfile, error) {
+	name, _ := args["device"].(string)
+	name = strings.TrimSpace(name)
+	if strings.EqualFold(name, deviceNone) {
+		return nil, nil
+	}
+	profile := DeviceProfile{Scale: defaultDPRScale}
+	if name != "" {
+		preset, err := lookupDevice(name)
+		if err != nil {
+			return nil, err
+		}
+		profile = preset
+	} else if args["width"] == nil || args["height"] == nil {
+		return nil, fmt.Errorf("device or both width and height are required, devices: %s", strings.Join(deviceNames(), ", "))
+	}
+
+	for key, dst := range map[string]*int64{"width": &profile.Width, "height": &profile.Height} {
+		v, err := intArg(args, key, int(*dst))
+		if err != nil {
+			return nil, err
+		}
+		if v < 1 || v > maxDeviceSize {
+			return nil, fmt.Errorf("%s must be between 1 and %d", key, maxDeviceSize)
+		}
+		*dst = int64(v)
+	}
+	if v, ok := args["scale"]; ok {
+		scale, ok := v.(float64)
+		if !ok || scale <= 0 || scale > maxDeviceScale {
+			return nil, fmt.Errorf("scale must be a number greater than 0 and at most %d", maxDeviceScale)
+		}
+		profile.Scale = scale
+	}
+	if v, ok := args["mobile"]; ok {
+		mobile, ok := v.(bool)
+		if !ok {
+			return nil, errors.New("mobile must be a boolean")
+		}
+		// 移动设备同时模拟触摸
+		profile.Mobile, profile.Touch = mobile, mobile
+	}
+	if v, ok := args["user_agent"]; ok {
+		ua, ok := v.(string)
+		if !ok {
+			return nil, errors.New("user_agent must be a string")
+		}
+		profile.UserAgent = strings.TrimSpace(ua)
+	}
+	if profile.Name != "" && (args["width"] != nil || args["height"] != nil || args["scale"] != nil || args["mobile"] != nil || args["user_agent"] != nil) {
+		profile.Name += " (customized)"
+	}
+	return &profile, nil
+}
+
+// deviceActions emulates the device in a tab. Without a user agent of its own the device uses
+// userAgent, the one of the config.
+func deviceActions(d *DeviceProfile, userAgent string) []chromedp.Action {
+	opts := []chromedp.EmulateViewportOption{chromedp.EmulateScale(d.Scale)}
+	if d.Mobile {
+		opts = append(opts, chromedp.EmulateMobile)
+	}
+	if d.Touch {
+		opts = append(opts, chromedp.EmulateTouch)
+	}
+	actions := []chromedp.Action{chromedp.EmulateViewport(d.Width, d.Height, opts...)}
+	if d.UserAgent != "" {
+		userAgent = d.UserAgent
+	}
+	if userAgent != "" {
+		actions = append(actions, emulation.SetUserAgentOverride(userAgent))
+	}
+	return actions
+}
+
+// resetDeviceActions restores the viewport, touch and the user agent of the config.
+func resetDeviceActions(userAgent string) []chromedp.Action {
+	actions := []chromedp.Action{emulation.ClearDeviceMetricsOverride(), emulation.SetTouchEmulationEnabled(false)}
+	if userAgent != "" {
+		actions = append(actions, emulation.SetUserAgentOverride(userAgent))
+	}
+	return actions
+}
+
+// emulatedDevice returns the emulated device, nil when there is none.
+func (bs *BrowserServer) emulatedDevice() *DeviceProfile {
+	if state := bs.emulationState(); state != nil {
+		return state.Device
+	}
+	return nil
+}
+
+// addDeviceTools registers browser_emulate_device and browser_emulation_status.
+func (bs *BrowserServer) addDeviceTools() {
+	bs.addTool(mcp.NewTool(
+		"browser_emulate_device",
+		mcp.WithDescription("Emulate a phone, tablet or desktop: viewport, device pixel ratio, mobile and touch emulation and user agent, from a preset and/or explicit values. The emulation persists across navigations and applies to new tabs; device none restores the defaults. Full-page screenshots without width and height keep the emulated viewport"),
+		mcp.WithString("device",
+			mcp.Description("Preset name: "+strings.Join(deviceNames(), ", ")+", or none to stop emulating"),
+		),
+		mcp.WithNumber("width",
+			mcp.Description(fmt.Sprintf("Viewport width in CSS pixels, 1 to %d, overrides the preset", maxDeviceSize)),
+		),
+		mcp.WithNumber("height",
+			mcp.Description(fmt.Sprintf("Viewport height in CSS pixels, 1 to %d, overrides the preset", maxDeviceSize)),
+		),
+		mcp.WithNumber("scale",
+			mcp.Description("Device pixel ratio, e.g. 3 for most phones (default: 1 without a preset)"),
+		),
+		mcp.WithBoolean("mobile",
+			mcp.Description("Emulate a mobile device with touch input, overrides the preset"),
+		),
+		mcp.WithString("user_agent",
+			mcp.Description("User agent, overrides the preset"),
+		),
+	), bs.handleEmulateDevice)
+	bs.addTool(mcp.NewTool(
+		"browser_emulation_status",
+		mcp.WithDescription("Return the emulation in effect as JSON: the device of browser_emulate_device and the geolocation, timezone and locale overrides. An empty object means nothing is emulated"),
+	), bs.handleEmulationStatus)
+}
+
+func (bs *BrowserServer) handleEmulateDevice(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	profile, err := parseDeviceProfile(request.GetArguments())
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ArgumentError(err)), nil
+	}
+	if profile == nil {
+		if err := bs.emulate(ctx, resetDeviceActions(bs.cfg().UserAgent)...); err != nil {
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to reset the device emulation", err)), nil
+		}
+		bs.updateEmulation(func(es *EmulationState) { es.Device = nil })
+		return mcp.NewToolResultText("Device emulation cleared"), nil
+	}
+	if err := bs.emulate(ctx, deviceActions(profile, bs.cfg().UserAgent)...); err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to emulate the device", err)), nil
+	}
+	bs.updateEmulation(func(es *EmulationState) { es.Device = profile })
+	name := profile.Name
+	if name == "" {
+		name = "custom device"
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Emulating %s: %dx%d at %gx, mobile %t", name, profile.Width, profile.Height, profile.Scale, profile.Mobile)), nil
+}
+
+func (bs *BrowserServer) handleEmulationStatus(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	state := bs.emulationState()
+	if state == nil {
+		state = &EmulationState{}
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to marshal the emulation state", err)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+// devicePresets are the devices of browser_emulate_device. Names are matched ignoring case,
+// spaces, - and _; an empty UserAgent keeps the user_agent of the config.
+var devicePresets = map[string]DeviceProfile{
+	"iPhone SE": {
+		Width: 375, Height: 667, Scale: 2, Mobile: true, Touch: true,
+		UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 15_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.0 Mobile/15E148 Safari/604.1",
+	},
+	"iPhone 14": {
+		Width: 390, Height: 844, Scale: 3, Mobile: true, Touch: true,
+		UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.0 Mobile/15E148 Safari/604.1",
+	},
+	"iPhone 14 Pro Max": {
+		Width: 430, Height: 932, Scale: 3, Mobile: true, Touch: true,
+		UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.0 Mobile/15E148 Safari/604.1",
+	},
+	"iPhone 15": {
+		Width: 393, Height: 852, Scale: 3, Mobile: true, Touch: true,
+		UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+	},
+	"Pixel 7": {
+		Width: 412, Height: 915, Scale: 2.625, Mobile: true, Touch: true,
+		UserAgent: "Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/134.0.0.0 Mobile Safari/537.36",
+	},
+	"Galaxy S23": {
+		Width: 360, Height: 780, Scale: 3, Mobile: true, Touch: true,
+		UserAgent: "Mozilla/5.0 (Linux; Android 13; SM-S911B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/134.0.0.0 Mobile Safari/537.36",
+	},
+	"iPad": {
+		Width: 810, Height: 1080, Scale: 2, Mobile: true, Touch: true,
+		UserAgent: "Mozilla/5.0 (iPad; CPU OS 16_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.0 Mobile/15E148 Safari/604.1",
+	},
+	"iPad Pro": {
+		Width: 1024, Height: 1366, Scale: 2, Mobile: true, Touch: true,
+		UserAgent: "Mozilla/5.0 (iPad; CPU OS 16_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.0 Mobile/15E148 Safari/604.1",
+	},
+	"Desktop-720p":  {Width: 1280, Height: 720, Scale: 1},
+	"Desktop-1080p": {Width: 1920, Height: 1080, Scale: 1},
+	"Desktop-1440p": {Width: 2560, Height: 1440, Scale: 1},
+}
@@ -0,0 +1,285 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// downloadDir is the directory under DataPath downloads are saved to.
+	downloadDir = "downloads"
+	// maxTrackedDownloads is the number of downloads remembered, the oldest are forgotten.
+	maxTrackedDownloads = 100
+)
+
+// download is a download of the browser. Chrome saves it under its GUID, it is renamed to the
+// suggested file name when completed.
+type download struct {
+	guid     string
+	url      string
+	name     string // 建议的文件名
+	state    browser.DownloadProgressState
+	received float64
+	total    float64
+	path     string // 完成后的文件路径
+	err      error  // 重命名失败的错误
+	reported bool   // 已由 browser_wait_download 返回
+}
+
+// downloadTracker follows the downloads by their events. The event listeners of several tabs call
+// it concurrently, a download is reported to each tab that enabled the events.
+type downloadTracker struct {
+	mu        sync.Mutex
+	dir       string
+	downloads []*download
+	byGUID    map[string]*download
+	changed   chan struct{} // 每次状态变化时关闭并替换
+}
+
+// setDir sets the directory the downloads are saved to.
+func (dt *downloadTracker) setDir(dir string) {
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+	dt.dir = dir
+}
+
+// notify wakes up the waiters, the caller holds mu.
+func (dt *downloadTracker) notify() {
+	if dt.changed != nil {
+		close(dt.changed)
+	}
+	dt.changed = make(chan struct{})
+}
+
+// handleEvent records a download event. It is called by the event listeners and must not block.
+func (dt *downloadTracker) handleEvent(ev interface{}) {
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+	switch ev := ev.(type) {
+	case *browser.EventDownloadWillBegin:
+		if _, ok := dt.byGUID[ev.GUID]; ok {
+			return
+		}
+		if dt.byGUID == nil {
+			dt.byGUID = make(map[string]*download)
+		}
+		d := &download{guid: ev.GUID, url: ev.URL, name: ev.SuggestedFilename, state: browser.DownloadProgressStateInProgress}
+		dt.downloads = append(dt.downloads, d)
+		dt.byGUID[ev.GUID] = d
+		if len(dt.downloads) > maxTrackedDownloads {
+			delete(dt.byGUID, dt.downloads[0].guid)
+			dt.downloads = dt.downloads[1:]
+		}
+	case *browser.EventDownloadProgress:
+		d, ok := dt.byGUID[ev.GUID]
+		if !ok || d.state != browser.DownloadProgressStateInProgress {
+			return
+		}
+		d.received, d.total, d.state = ev.ReceivedBytes, ev.TotalBytes, ev.State
+		switch ev.State {
+		case browser.DownloadProgressStateCompleted:
+			d.path, d.err = finishDownload(dt.dir, d.guid, d.name)
+		case browser.DownloadProgressStateCanceled:
+			removePartialDownload(dt.dir, d.guid)
+		}
+	default:
+		return
+	}
+	dt.notify()
+}
+
+// directory returns the directory the downloads are saved to, empty before the browser started.
+func (dt *downloadTracker) directory() string {
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+	return dt.dir
+}
+
+// discard removes the partial file of a download that is given up on.
+func (dt *downloadTracker) discard(guid string) {
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+	removePartialDownload(dt.dir, guid)
+}
+
+// latest returns the most recent download that was not reported yet, nil when there is none.
+func (dt *downloadTracker) latest() *download {
+	for i := len(dt.downloads) - 1; i >= 0; i-- {
+		if !dt.downloads[i].reported {
+			return dt.downloads[i]
+		}
+	}
+	return nil
+}
+
+// wait waits until the most recent unreported download, or the next one to begin, is completed or
+// canceled, and marks it reported. When ctx ends first, it returns the download in progress, nil
+// when none began, with the error of ctx.
+func (dt *downloadTracker) wait(ctx context.Context) (download, bool, error) {
+	for {
+		dt.mu.Lock()
+		d := dt.latest()
+		if d != nil && d.state != browser.DownloadProgressStateInProgress {
+			d.reported = true
+			dt.mu.Unlock()
+			return *d, true, nil
+		}
+		if dt.changed == nil {
+			dt.changed = make(chan struct{})
+		}
+		changed := dt.changed
+		dt.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			dt.mu.Lock()
+			defer dt.mu.Unlock()
+			if d == nil {
+				return download{}, false, ctx.Err()
+			}
+			d.reported = true
+			return *d, true, ctx.Err()
+		}
+	}
+}
+
+// finishDownload renames the file of a completed download to its suggested name.
+func finishDownload(dir, guid, name string) (string, error) {
+	path := uniqueDownloadPath(dir, name)
+	if err := os.Rename(filepath.Join(dir, guid), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// uniqueDownloadPath returns the path of name in dir, suffixed with a counter when the file
+// exists, e.g. report (1).pdf.
+func uniqueDownloadPath(dir, name string) string {
+	name = filepath.Base(strings.ReplaceAll(strings.TrimSpace(name), "\\", "/"))
+	if name == "" || name == "." || name == ".." || name == "/" {
+		name = "download"
+	}
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	path := filepath.Join(dir, name)
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", base, i, ext))
+	}
+}
+
+// removePartialDownload removes the unfinished file of a download.
+func removePartialDownload(dir, guid string) {
+	for _, name := range []string{guid, guid + ".crdownload"} {
+		os.Remove(filepath.Join(dir, name))
+	}
+}
+
+// downloadActions returns the action saving the downloads of a tab into the download directory
+// and reporting their events, nil before the browser started.
+func (bs *BrowserServer) downloadActions() []chromedp.Action {
+	dir := bs.downloads.directory()
+	if dir == "" {
+		return nil
+	}
+	return []chromedp.Action{
+		browser.SetDownloadBehavior(browser.SetDownloadBehaviorBehaviorAllowAndName).WithDownloadPath(dir).WithEventsEnabled(true),
+	}
+}
+
+// enableDownloads creates the download directory and saves the downloads of the main tab into it.
+// It is called when the browser starts and after a restart, startBrowser listens to the events.
+func (bs *BrowserServer) enableDownloads() {
+	dir, err := filepath.Abs(filepath.Join(bs.cfg().DataPath, downloadDir))
+	if err == nil {
+		err = os.MkdirAll(dir, 0o755)
+	}
+	if err != nil {
+		bs.Logger.Warn().Err(err).Msg("failed to create the download directory")
+		return
+	}
+	bs.downloads.setDir(dir)
+	browserCtx := bs.browserContext()
+	if browserCtx == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(browserCtx, time.Duration(bs.cfg().SelectorQueryTimeout)*time.Second)
+	defer cancel()
+	if err := chromedp.Run(ctx, bs.downloadActions()...); err != nil {
+		bs.Logger.Warn().Err(err).Msg("failed to set the download directory")
+	}
+}
+
+// addDownloadTool registers browser_wait_download.
+func (bs *BrowserServer) addDownloadTool() {
+	bs.addTool(mcp.NewTool(
+		"browser_wait_download",
+		mcp.WithDescription("Wait for the most recent download, e.g. after clicking a download link, to complete and return the path and size of the file. Downloads are saved in the downloads directory of the data path, a number is appended when the file name is taken. Each download is returned once, when none is in progress it waits for the next one to begin"),
+		mcp.WithNumber("timeout_seconds",
+			mcp.Description("Maximum time to wait in seconds (default: timeout of the config). The download is cancelled and its partial file removed when it doesn't complete in time"),
+		),
+	), bs.handleWaitDownload)
+}
+
+func (bs *BrowserServer) handleWaitDownload(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	timeout := time.Duration(bs.cfg().Timeout) * time.Second
+	if v, ok := request.GetArguments()["timeout_seconds"]; ok {
+		seconds, ok := v.(float64)
+		if !ok || seconds <= 0 {
+			return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInvalidArgument, "timeout_seconds must be a positive number", "")), nil
+		}
+		timeout = time.Duration(seconds * float64(time.Second))
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	d, ok, err := bs.downloads.wait(waitCtx)
+	switch {
+	case err != nil && !ok:
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeTimeout, fmt.Sprintf("no download started within %s", timeout), "")), nil
+	case err != nil:
+		// 取消未完成的下载并删除不完整的文件
+		if err := bs.emulate(ctx, browser.CancelDownload(d.guid)); err != nil {
+			bs.Logger.Debug().Err(err).Str("guid", d.guid).Msg("failed to cancel the download")
+		}
+		bs.downloads.discard(d.guid)
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeTimeout, fmt.Sprintf("download of %s did not complete within %s, %.0f of %.0f bytes received; it was cancelled and the partial file removed", d.url, timeout, d.received, d.total), "")), nil
+	case d.state == browser.DownloadProgressStateCanceled:
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInternal, fmt.Sprintf("download of %s was cancelled", d.url), "")), nil
+	case d.err != nil:
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr(fmt.Sprintf("download of %s completed, but failed to save it", d.url), d.err)), nil
+	}
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr(fmt.Sprintf("download of %s completed, but the file is missing", d.url), err)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Downloaded %s to %s (%d bytes)", d.url, d.path, info.Size())), nil
+}
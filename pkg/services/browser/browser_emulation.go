@@ -0,0 +1,349 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	_ "time/tzdata" // 内置时区数据库，保证在没有系统时区数据的平台上也能校验时区
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// DefaultGeolocationAccuracy is the accuracy in meters used when browser_set_geolocation has none.
+const DefaultGeolocationAccuracy = 100
+
+var (
+	// ErrInvalidGeolocation is returned for coordinates out of range.
+	ErrInvalidGeolocation = errors.New("invalid geolocation")
+	// ErrInvalidTimezone is returned for a timezone that is not an IANA zone name.
+	ErrInvalidTimezone = errors.New("invalid timezone")
+	// ErrInvalidLocale is returned for a malformed locale.
+	ErrInvalidLocale = errors.New("invalid locale")
+)
+
+// localeRegexp matches BCP 47 style locales like en, en-US, zh-Hans-CN, and the ICU style en_US.
+var localeRegexp = regexp.MustCompile(`^[A-Za-z]{2,3}([-_][A-Za-z0-9]{2,8})*$`)
+
+// Geolocation is an emulated position.
+type Geolocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Accuracy  float64 `json:"accuracy"` // meters
+}
+
+// EmulationState holds the overrides set by the emulation tools. They persist across navigations
+// until cleared, and are applied again when the browser is restarted.
+type EmulationState struct {
+	Geolocation *Geolocation   `json:"geolocation,omitempty"`
+	Timezone    string         `json:"timezone,omitempty"` // IANA zone name
+	Locale      string         `json:"locale,omitempty"`   // ICU style, e.g. en_US
+	Device      *DeviceProfile `json:"device,omitempty"`   // browser_emulate_device 模拟的设备
+}
+
+// empty reports whether no override is set.
+func (es EmulationState) empty() bool {
+	return es.Geolocation == nil && es.Timezone == "" && es.Locale == "" && es.Device == nil
+}
+
+// geolocationOverride is Emulation.setGeolocationOverride. The cdproto params omit zero
+// coordinates, which Chrome treats as position unavailable, so the equator and the prime
+// meridian could not be emulated.
+type geolocationOverride struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Accuracy  float64 `json:"accuracy"`
+}
+
+// Do executes Emulation.setGeolocationOverride against the provided context.
+func (p *geolocationOverride) Do(ctx context.Context) error {
+	return cdp.Execute(ctx, emulation.CommandSetGeolocationOverride, p, nil)
+}
+
+// parseGeolocation validates the arguments of browser_set_geolocation.
+func parseGeolocation(args map[string]interface{}) (*Geolocation, error) {
+	latitude, ok := args["latitude"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("%w: latitude is required", ErrInvalidGeolocation)
+	}
+	longitude, ok := args["longitude"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("%w: longitude is required", ErrInvalidGeolocation)
+	}
+	if latitude < -90 || latitude > 90 {
+		return nil, fmt.Errorf("%w: latitude %v is out of range [-90, 90]", ErrInvalidGeolocation, latitude)
+	}
+	if longitude < -180 || longitude > 180 {
+		return nil, fmt.Errorf("%w: longitude %v is out of range [-180, 180]", ErrInvalidGeolocation, longitude)
+	}
+	accuracy := float64(DefaultGeolocationAccuracy)
+	if value, ok := args["accuracy"].(float64); ok {
+		if value <= 0 {
+			return nil, fmt.Errorf("%w: accuracy must be greater than 0", ErrInvalidGeolocation)
+		}
+		accuracy = value
+	}
+	return &Geolocation{Latitude: latitude, Longitude: longitude, Accuracy: accuracy}, nil
+}
+
+// validateTimezone checks that name is an IANA zone name known to Go's tzdata.
+func validateTimezone(name string) error {
+	if name == "" || name == "Local" {
+		return fmt.Errorf("%w: %q, use an IANA zone name like Europe/Berlin", ErrInvalidTimezone, name)
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("%w: %q, use an IANA zone name like Europe/Berlin", ErrInvalidTimezone, name)
+	}
+	return nil
+}
+
+// normalizeLocale converts a BCP 47 style locale to the ICU style expected by Chrome.
+func normalizeLocale(locale string) (string, error) {
+	if !localeRegexp.MatchString(locale) {
+		return "", fmt.Errorf("%w: %q, use a locale like en-US", ErrInvalidLocale, locale)
+	}
+	return strings.ReplaceAll(locale, "-", "_"), nil
+}
+
+// addEmulationTools registers the geolocation, timezone and locale override tools.
+func (bs *BrowserServer) addEmulationTools() {
+	bs.addTool(mcp.NewTool(
+		"browser_set_geolocation",
+		mcp.WithDescription("Override the geolocation reported to pages and grant the geolocation permission to the current origin. The override persists across navigations until cleared."),
+		mcp.WithNumber("latitude",
+			mcp.Description("Latitude in degrees, from -90 to 90"),
+		),
+		mcp.WithNumber("longitude",
+			mcp.Description("Longitude in degrees, from -180 to 180"),
+		),
+		mcp.WithNumber("accuracy",
+			mcp.Description(fmt.Sprintf("Accuracy in meters, default: %d", DefaultGeolocationAccuracy)),
+		),
+		mcp.WithBoolean("clear",
+			mcp.Description("Clear the override instead of setting it"),
+		),
+	), bs.handleSetGeolocation)
+
+	bs.addTool(mcp.NewTool(
+		"browser_set_timezone",
+		mcp.WithDescription("Override the timezone of pages with an IANA zone name. The override persists across navigations until cleared."),
+		mcp.WithString("timezone",
+			mcp.Description("IANA zone name, e.g. America/New_York"),
+		),
+		mcp.WithBoolean("clear",
+			mcp.Description("Clear the override instead of setting it"),
+		),
+	), bs.handleSetTimezone)
+
+	bs.addTool(mcp.NewTool(
+		"browser_set_locale",
+		mcp.WithDescription("Override the locale used by Intl and number/date formatting of pages. Clearing falls back to the default_language config. The override persists across navigations until cleared."),
+		mcp.WithString("locale",
+			mcp.Description("Locale, e.g. de-DE"),
+		),
+		mcp.WithBoolean("clear",
+			mcp.Description("Clear the override instead of setting it"),
+		),
+	), bs.handleSetLocale)
+}
+
+func (bs *BrowserServer) handleSetGeolocation(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	if clear, _ := args["clear"].(bool); clear {
+		if err := bs.emulate(ctx, emulation.ClearGeolocationOverride()); err != nil {
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to clear geolocation", err)), nil
+		}
+		bs.updateEmulation(func(es *EmulationState) { es.Geolocation = nil })
+		return mcp.NewToolResultText("Geolocation override cleared"), nil
+	}
+
+	geo, err := parseGeolocation(args)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	if err := bs.emulate(ctx, grantGeolocation(), geolocationAction(geo)); err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to set geolocation", err)), nil
+	}
+	bs.updateEmulation(func(es *EmulationState) { es.Geolocation = geo })
+	return mcp.NewToolResultText(fmt.Sprintf("Geolocation set to %v, %v (accuracy %vm)", geo.Latitude, geo.Longitude, geo.Accuracy)), nil
+}
+
+func (bs *BrowserServer) handleSetTimezone(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	if clear, _ := args["clear"].(bool); clear {
+		if err := bs.emulate(ctx, emulation.SetTimezoneOverride("")); err != nil {
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to clear timezone", err)), nil
+		}
+		bs.updateEmulation(func(es *EmulationState) { es.Timezone = "" })
+		return mcp.NewToolResultText("Timezone override cleared"), nil
+	}
+
+	timezone, _ := args["timezone"].(string)
+	if err := validateTimezone(timezone); err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	if err := bs.emulate(ctx, timezoneActions(timezone)...); err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to set timezone", err)), nil
+	}
+	bs.updateEmulation(func(es *EmulationState) { es.Timezone = timezone })
+	return mcp.NewToolResultText(fmt.Sprintf("Timezone set to %s", timezone)), nil
+}
+
+func (bs *BrowserServer) handleSetLocale(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	if clear, _ := args["clear"].(bool); clear {
+		// 回退到 default_language 配置，配置无效时恢复系统默认
+		fallback, err := normalizeLocale(bs.cfg().DefaultLanguage)
+		if err != nil {
+			fallback = ""
+		}
+		if err := bs.emulate(ctx, localeActions(fallback)...); err != nil {
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to clear locale", err)), nil
+		}
+		bs.updateEmulation(func(es *EmulationState) { es.Locale = "" })
+		return mcp.NewToolResultText(fmt.Sprintf("Locale override cleared, using the default language %s", bs.cfg().DefaultLanguage)), nil
+	}
+
+	raw, _ := args["locale"].(string)
+	locale, err := normalizeLocale(raw)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	if err := bs.emulate(ctx, localeActions(locale)...); err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to set locale", err)), nil
+	}
+	bs.updateEmulation(func(es *EmulationState) { es.Locale = locale })
+	return mcp.NewToolResultText(fmt.Sprintf("Locale set to %s", locale)), nil
+}
+
+// geolocationAction sets the geolocation override.
+func geolocationAction(geo *Geolocation) chromedp.Action {
+	return &geolocationOverride{Latitude: geo.Latitude, Longitude: geo.Longitude, Accuracy: geo.Accuracy}
+}
+
+// grantGeolocation grants the geolocation permission to the origin of the current page, so that
+// pages don't wait for the permission prompt. Pages without an origin, like about:blank, grant it
+// to all origins.
+func grantGeolocation() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var origin string
+		if err := chromedp.Evaluate(`location.origin`, &origin).Do(ctx); err != nil {
+			return err
+		}
+		grant := browser.GrantPermissions([]browser.PermissionType{browser.PermissionTypeGeolocation})
+		if strings.HasPrefix(origin, "http://") || strings.HasPrefix(origin, "https://") {
+			grant = grant.WithOrigin(origin)
+		}
+		c := chromedp.FromContext(ctx)
+		if c == nil || c.Browser == nil {
+			return grant.Do(ctx)
+		}
+		return grant.Do(cdp.WithExecutor(ctx, c.Browser))
+	})
+}
+
+// timezoneActions sets the timezone override. Chrome rejects a new override while another one is
+// in effect, so the old one is cleared first.
+func timezoneActions(timezone string) []chromedp.Action {
+	return []chromedp.Action{emulation.SetTimezoneOverride(""), emulation.SetTimezoneOverride(timezone)}
+}
+
+// localeActions sets the locale override, an empty locale restores the system locale. Like the
+// timezone, the old override is cleared first.
+func localeActions(locale string) []chromedp.Action {
+	actions := []chromedp.Action{emulation.SetLocaleOverride()}
+	if locale != "" {
+		actions = append(actions, emulation.SetLocaleOverride().WithLocale(locale))
+	}
+	return actions
+}
+
+// runEmulation runs the emulation actions on the tab of the call.
+func (bs *BrowserServer) runEmulation(ctx context.Context, actions ...chromedp.Action) error {
+	runCtx, cancel := context.WithTimeout(bs.pageContext(ctx), time.Duration(bs.cfg().SelectorQueryTimeout)*time.Second)
+	defer cancel()
+	return chromedp.Run(runCtx, actions...)
+}
+
+// updateEmulation changes the emulation state under emulationLock.
+func (bs *BrowserServer) updateEmulation(update func(es *EmulationState)) {
+	bs.emulationLock.Lock()
+	defer bs.emulationLock.Unlock()
+	update(&bs.emulation)
+}
+
+// emulationState returns a copy of the emulation state, nil when no override is set.
+func (bs *BrowserServer) emulationState() *EmulationState {
+	bs.emulationLock.Lock()
+	defer bs.emulationLock.Unlock()
+	if bs.emulation.empty() {
+		return nil
+	}
+	state := bs.emulation
+	if state.Geolocation != nil {
+		geo := *state.Geolocation
+		state.Geolocation = &geo
+	}
+	if state.Device != nil {
+		device := *state.Device
+		state.Device = &device
+	}
+	return &state
+}
+
+// reapplyEmulation applies the overrides again after the browser was restarted. Session tabs
+// are opened again on their next call and get the overrides then, see openTab.
+func (bs *BrowserServer) reapplyEmulation() {
+	actions := bs.emulationActions()
+	if len(actions) == 0 {
+		return
+	}
+	if err := bs.emulate(context.Background(), actions...); err != nil {
+		bs.Logger.Warn().Err(err).Msg("failed to apply the emulation overrides to the restarted browser")
+	}
+}
+
+// emulationActions returns the actions applying the current overrides, nil when no override is set.
+func (bs *BrowserServer) emulationActions() []chromedp.Action {
+	state := bs.emulationState()
+	if state == nil {
+		return nil
+	}
+	var actions []chromedp.Action
+	if state.Geolocation != nil {
+		actions = append(actions, grantGeolocation(), geolocationAction(state.Geolocation))
+	}
+	if state.Timezone != "" {
+		actions = append(actions, timezoneActions(state.Timezone)...)
+	}
+	if state.Locale != "" {
+		actions = append(actions, localeActions(state.Locale)...)
+	}
+	if state.Device != nil {
+		actions = append(actions, deviceActions(state.Device, bs.cfg().UserAgent)...)
+	}
+	return actions
+}
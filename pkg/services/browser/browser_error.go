@@ -0,0 +1,155 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	errorScreenshotDir     = "errors"        // 错误截图目录，位于 DataPath 下
+	errorScreenshotExt     = ".png"          // 错误截图扩展名
+	errorScreenshotTimeout = 5 * time.Second // 错误截图超时时间
+)
+
+// toolError decorates the error result of a browser tool call, built by comm.ToolError. When
+// ScreenshotOnError is enabled, a full-page screenshot is captured and its path is appended to the
+// details. A failed capture is only logged, the original result is always returned.
+func (bs *BrowserServer) toolError(ctx context.Context, request mcp.CallToolRequest, result *mcp.CallToolResult) *mcp.CallToolResult {
+	if !bs.cfg().ScreenshotOnError {
+		return result
+	}
+	payload, ok := comm.ParseToolError(result)
+	if !ok {
+		return result
+	}
+	path, err := bs.captureErrorScreenshot(ctx, request.Params.Name)
+	if err != nil {
+		bs.Logger.Debug().Err(err).Str("tool", request.Params.Name).Msg("failed to capture error screenshot")
+		return result
+	}
+	details := fmt.Sprintf("screenshot saved to: %s", path)
+	if payload.Details != "" {
+		details = payload.Details + "\n" + details
+	}
+	return comm.ToolError(payload.Code, payload.Message, details)
+}
+
+// elementScriptError builds the error result of an element action that the page script reported
+// as failed, a missing element is NOT_FOUND.
+func elementScriptError(action, errorMsg string) *mcp.CallToolResult {
+	code := comm.ErrCodeInternal
+	if strings.HasSuffix(errorMsg, "not found") {
+		code = comm.ErrCodeNotFound
+	}
+	return comm.ToolError(code, fmt.Sprintf("failed to %s the element", action), errorMsg)
+}
+
+// captureErrorScreenshot takes a full-page screenshot of the tab of the call and saves it under
+// DataPath/errors, then prunes the oldest error screenshots beyond MaxErrorScreenshots.
+func (bs *BrowserServer) captureErrorScreenshot(ctx context.Context, toolName string) (string, error) {
+	if bs.browserContext() == nil {
+		return "", fmt.Errorf("browser context is not initialized")
+	}
+	runCtx, cancel := context.WithTimeout(bs.pageContext(ctx), errorScreenshotTimeout)
+	defer cancel()
+
+	var buf []byte
+	if err := chromedp.Run(runCtx, chromedp.FullScreenshot(&buf, 100)); err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(bs.cfg().DataPath, errorScreenshotDir)
+	if err := utils.CreateDirectory(dir); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s_%s%s", errorScreenshotPrefix(toolName), time.Now().Format("20060102_150405.000"), errorScreenshotExt)
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, buf, 0644); err != nil {
+		return "", err
+	}
+
+	if err := pruneErrorScreenshots(dir, bs.cfg().MaxErrorScreenshots); err != nil {
+		bs.Logger.Warn().Err(err).Str("dir", dir).Msg("failed to prune error screenshots")
+	}
+	return path, nil
+}
+
+// errorScreenshotPrefix turns a tool name into a safe file name prefix.
+func errorScreenshotPrefix(toolName string) string {
+	prefix := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, toolName)
+	if prefix == "" {
+		return "browser"
+	}
+	return prefix
+}
+
+// pruneErrorScreenshots keeps at most maxKeep screenshots in dir, removing the oldest first.
+func pruneErrorScreenshots(dir string, maxKeep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	type shot struct {
+		path    string
+		modTime time.Time
+	}
+	var shots []shot
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != errorScreenshotExt {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		shots = append(shots, shot{path: filepath.Join(dir, entry.Name()), modTime: info.ModTime()})
+	}
+	if len(shots) <= maxKeep {
+		return nil
+	}
+
+	sort.Slice(shots, func(i, j int) bool {
+		if shots[i].modTime.Equal(shots[j].modTime) {
+			return shots[i].path < shots[j].path
+		}
+		return shots[i].modTime.Before(shots[j].modTime)
+	})
+	for _, s := range shots[:len(shots)-maxKeep] {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// chromeVersionPattern matches the version in the output of chrome --version and the version
+// directories next to chrome.exe.
+var chromeVersionPattern = regexp.MustCompile(`\d+\.\d+\.\d+\.\d+`)
+
+// chromeLocations returns the Chrome executables to look for, in the order used by chromedp.
+func chromeLocations() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{
+			"/Applications/Chromium.app/Contents/MacOS/Chromium",
+			"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+		}
+	case "windows":
+		return []string{
+			"chrome",
+			"chrome.exe",
+			`C:\Program Files (x86)\Google\Chrome\Application\chrome.exe`,
+			`C:\Program Files\Google\Chrome\Application\chrome.exe`,
+			filepath.Join(os.Getenv("USERPROFILE"), `AppData\Local\Google\Chrome\Application\chrome.exe`),
+			filepath.Join(os.Getenv("USERPROFILE"), `AppData\Local\Chromium\Application\chrome.exe`),
+		}
+	default:
+		return []string{
+			"headless_shell",
+			"headless-shell",
+			"chromium",
+			"chromium-browser",
+			"google-chrome",
+			"google-chrome-stable",
+			"google-chrome-beta",
+			"google-chrome-unstable",
+			"/usr/bin/google-chrome",
+			"/usr/local/bin/chrome",
+			"/snap/bin/chromium",
+			"chrome",
+		}
+	}
+}
+
+// FindChrome returns the path of the Chrome executable the browser service launches. It returns an
+// error when no Chrome is installed, chromedp then falls back to google-chrome on PATH.
+func FindChrome() (string, error) {
+	for _, location := range chromeLocations() {
+		if found, err := exec.LookPath(location); err == nil {
+			return found, nil
+		}
+	}
+	return "", errors.New("chrome not found, install Chrome or Chromium")
+}
+
+// ChromeVersion returns the version of the Chrome executable at path, e.g. 126.0.6478.126.
+func ChromeVersion(ctx context.Context, path string) (string, error) {
+	// Windows 上 chrome.exe --version 会打开浏览器，改为读取 chrome.exe 同级的版本目录
+	if runtime.GOOS == "windows" {
+		entries, err := os.ReadDir(filepath.Dir(path))
+		if err != nil {
+			return "", fmt.Errorf("failed to read the chrome directory: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() && chromeVersionPattern.MatchString(entry.Name()) {
+				return entry.Name(), nil
+			}
+		}
+		return "", fmt.Errorf("no version directory next to %s", path)
+	}
+
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run %s --version: %w", path, err)
+	}
+	if version := chromeVersionPattern.FindString(string(out)); version != "" {
+		return version, nil
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,411 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	listDefaultMaxPages = 10                     // 默认最多翻页数
+	listDefaultMaxItems = 1000                   // 默认最多提取条目数
+	listMaxPagesLimit   = 100                    // max_pages 的上限
+	listMaxItemsLimit   = 10000                  // max_items 的上限
+	listPollInterval    = 200 * time.Millisecond // 翻页后检查列表是否刷新的间隔
+)
+
+// 提取停止的原因
+const (
+	ListStopNoNext   = "no_next"   // 没有 next_selector，或下一页按钮不存在或已禁用
+	ListStopNoChange = "no_change" // 点击下一页后列表在超时内没有变化
+	ListStopMaxPages = "max_pages"
+	ListStopMaxItems = "max_items"
+	ListStopError    = "error" // 翻页过程中出错，已提取的条目仍会返回
+)
+
+// listAttrPattern matches the attribute name after the last @ of a field spec.
+var listAttrPattern = regexp.MustCompile(`^[a-zA-Z_:][-a-zA-Z0-9_:.]*$`)
+
+// listField is a field of browser_extract_list: the text, or the attribute when Attr is set, of
+// the element matching Selector within the item, or of the item itself when Selector is empty.
+type listField struct {
+	Name     string `json:"name"`
+	Selector string `json:"selector"`
+	Attr     string `json:"attr"`
+}
+
+// listOptions are the arguments of browser_extract_list.
+type listOptions struct {
+	ItemSelector string
+	Fields       []listField
+	NextSelector string
+	MaxPages     int
+	MaxItems     int
+	DedupeField  string
+}
+
+// listSnapshot is what listExtractJS returns for the current page.
+type listSnapshot struct {
+	Count   int                      `json:"count"`
+	Hash    string                   `json:"hash"`
+	HasNext bool                     `json:"has_next"`
+	Items   []map[string]interface{} `json:"items"`
+}
+
+// ListResult is the outcome of browser_extract_list.
+type ListResult struct {
+	Items          []map[string]interface{} `json:"items"`
+	Pages          int                      `json:"pages"`
+	StoppedAtLimit bool                     `json:"stopped_at_limit"`
+	StopReason     string                   `json:"stop_reason"`
+	Duplicates     int                      `json:"duplicates,omitempty"`
+	Error          string                   `json:"error,omitempty"`
+}
+
+// listNextFn returns the element of the next selector when it can be clicked, null otherwise.
+const listNextFn = `function nextOf(selector) {
+	if (!selector) {
+		return null;
+	}
+	var el = document.querySelector(selector);
+	if (!el || el.disabled || (el.getAttribute && el.getAttribute("aria-disabled") === "true")) {
+		return null;
+	}
+	return el;
+}`
+
+// listExtractJS extracts the fields of every item and hashes the text of the items, so a refresh
+// of the list can be detected. It is plain ES5 and returns a listSnapshot as a JSON string; with
+// null fields only count, hash and has_next are filled. The %s are the item selector, the fields
+// and the next selector as JSON.
+const listExtractJS = `(function(itemSelector, fields, nextSelector) {
+	` + listNextFn + `
+	function clean(s) {
+		return String(s || "").replace(/\s+/g, " ").replace(/^ | $/g, "");
+	}
+	function textOf(el) {
+		return clean(typeof el.innerText === "string" ? el.innerText : el.textContent);
+	}
+	function valueOf(item, field) {
+		var el = item;
+		if (field.selector) {
+			el = item.querySelector(field.selector);
+			if (!el) {
+				return null;
+			}
+		}
+		if (!field.attr) {
+			return textOf(el);
+		}
+		// href 和 src 使用解析后的绝对地址
+		if ((field.attr === "href" || field.attr === "src") && typeof el[field.attr] === "string" && el[field.attr]) {
+			return el[field.attr];
+		}
+		var value = el.getAttribute(field.attr);
+		return value === null || value === undefined ? null : String(value);
+	}
+	var nodes = document.querySelectorAll(itemSelector);
+	var hash = 5381;
+	var items = [];
+	for (var i = 0; i < nodes.length; i++) {
+		var text = textOf(nodes[i]);
+		for (var j = 0; j < text.length; j++) {
+			hash = (hash * 33 + text.charCodeAt(j)) >>> 0;
+		}
+		hash = (hash * 33 + 1) >>> 0;
+		if (fields) {
+			var item = {};
+			for (var k = 0; k < fields.length; k++) {
+				item[fields[k].name] = valueOf(nodes[i], fields[k]);
+			}
+			items.push(item);
+		}
+	}
+	return JSON.stringify({count: nodes.length, hash: nodes.length + ":" + hash.toString(16), has_next: nextOf(nextSelector) !== null, items: items});
+})(%s, %s, %s)`
+
+// listClickNextJS clicks the element of the next selector %s and reports whether it was clickable.
+const listClickNextJS = `(function(selector) {
+	` + listNextFn + `
+	var el = nextOf(selector);
+	if (!el) {
+		return false;
+	}
+	if (el.scrollIntoView) {
+		el.scrollIntoView();
+	}
+	el.click();
+	return true;
+})(%s)`
+
+// listPage is the page browser_extract_list walks through: scripts on the tab of the call, or a
+// fake in tests.
+type listPage interface {
+	Extract() (*listSnapshot, error)
+	ClickNext() (bool, error)
+	// WaitForChange reports whether the items were refreshed, i.e. their hash differs from hash.
+	WaitForChange(hash string) (bool, error)
+}
+
+// scriptListPage implements listPage with listExtractJS and listClickNextJS.
+type scriptListPage struct {
+	page    scriptPage
+	opts    listOptions
+	timeout time.Duration // WaitForChange 的超时时间
+}
+
+func (lp scriptListPage) snapshot(withFields bool) (*listSnapshot, error) {
+	var fields interface{}
+	if withFields {
+		fields = lp.opts.Fields
+	}
+	args := make([]string, 0, 3)
+	for _, v := range []interface{}{lp.opts.ItemSelector, fields, lp.opts.NextSelector} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, string(data))
+	}
+	var raw string
+	if err := lp.page.Evaluate(fmt.Sprintf(listExtractJS, args[0], args[1], args[2]), &raw); err != nil {
+		return nil, err
+	}
+	var snap listSnapshot
+	if err := json.Unmarshal([]byte(raw), &snap); err != nil {
+		return nil, fmt.Errorf("unexpected list result %q: %w", raw, err)
+	}
+	return &snap, nil
+}
+
+func (lp scriptListPage) Extract() (*listSnapshot, error) {
+	return lp.snapshot(true)
+}
+
+func (lp scriptListPage) ClickNext() (bool, error) {
+	selector, err := json.Marshal(lp.opts.NextSelector)
+	if err != nil {
+		return false, err
+	}
+	var clicked bool
+	err = lp.page.Evaluate(fmt.Sprintf(listClickNextJS, selector), &clicked)
+	return clicked, err
+}
+
+func (lp scriptListPage) WaitForChange(hash string) (bool, error) {
+	// 列表加载中可能短暂为空，等到有条目且内容变化才算刷新完成
+	deadline := time.Now().Add(lp.timeout)
+	for {
+		snap, err := lp.snapshot(false)
+		if err != nil {
+			return false, err
+		}
+		if snap.Count > 0 && snap.Hash != hash {
+			return true, nil
+		}
+		if time.Now().After(deadline) {
+			return false, nil
+		}
+		time.Sleep(listPollInterval)
+	}
+}
+
+// extractList extracts the items of the current page, clicks next and waits for the list to be
+// refreshed until there is no next page, the list doesn't change or a limit is reached. With a
+// dedupe field, items whose value of the field was seen before are dropped, so a "load more"
+// button or an infinite scroll that keeps the earlier items can be walked too. An error after the
+// first page stops the walk and is reported in the result with the items so far.
+func extractList(page listPage, opts listOptions) (*ListResult, error) {
+	result := &ListResult{Items: []map[string]interface{}{}}
+	seen := make(map[string]bool)
+	stop := func(reason string, atLimit bool, err error) (*ListResult, error) {
+		result.StopReason = reason
+		result.StoppedAtLimit = atLimit
+		if err != nil {
+			result.Error = err.Error()
+		}
+		return result, nil
+	}
+	for {
+		snap, err := page.Extract()
+		if err != nil {
+			if result.Pages == 0 {
+				return nil, err
+			}
+			return stop(ListStopError, false, err)
+		}
+		result.Pages++
+		for _, item := range snap.Items {
+			if opts.DedupeField != "" {
+				if key, _ := item[opts.DedupeField].(string); key != "" {
+					if seen[key] {
+						result.Duplicates++
+						continue
+					}
+					seen[key] = true
+				}
+			}
+			if len(result.Items) >= opts.MaxItems {
+				return stop(ListStopMaxItems, true, nil)
+			}
+			result.Items = append(result.Items, item)
+		}
+
+		hasNext := opts.NextSelector != "" && snap.HasNext
+		switch {
+		case !hasNext:
+			return stop(ListStopNoNext, false, nil)
+		case len(result.Items) >= opts.MaxItems:
+			return stop(ListStopMaxItems, true, nil)
+		case result.Pages >= opts.MaxPages:
+			return stop(ListStopMaxPages, true, nil)
+		}
+		clicked, err := page.ClickNext()
+		if err != nil {
+			return stop(ListStopError, false, err)
+		}
+		if !clicked {
+			return stop(ListStopNoNext, false, nil)
+		}
+		changed, err := page.WaitForChange(snap.Hash)
+		if err != nil {
+			return stop(ListStopError, false, err)
+		}
+		if !changed {
+			return stop(ListStopNoChange, false, nil)
+		}
+	}
+}
+
+// parseListField parses a field spec: "sel" for the text of sel within the item, "sel@attr" for
+// an attribute of it, "@attr" for an attribute of the item itself and "" for the text of the item.
+func parseListField(name, spec string) listField {
+	field := listField{Name: name, Selector: strings.TrimSpace(spec)}
+	// 选择器中也可能出现 @，只有 @ 之后是合法的属性名才当作属性
+	if idx := strings.LastIndex(field.Selector, "@"); idx >= 0 && listAttrPattern.MatchString(field.Selector[idx+1:]) {
+		field.Attr = field.Selector[idx+1:]
+		field.Selector = strings.TrimSpace(field.Selector[:idx])
+	}
+	return field
+}
+
+// parseListOptions validates the arguments of browser_extract_list.
+func parseListOptions(args map[string]interface{}) (listOptions, error) {
+	opts := listOptions{MaxPages: listDefaultMaxPages, MaxItems: listDefaultMaxItems}
+	itemSelector, _ := args["item_selector"].(string)
+	if opts.ItemSelector = strings.TrimSpace(itemSelector); opts.ItemSelector == "" {
+		return opts, errors.New("item_selector is required")
+	}
+	rawFields, _ := args["fields"].(map[string]interface{})
+	if len(rawFields) == 0 {
+		return opts, errors.New("fields is required, e.g. {\"title\": \"h2\", \"url\": \"a@href\"}")
+	}
+	for name, value := range rawFields {
+		spec, ok := value.(string)
+		if !ok {
+			return opts, fmt.Errorf("field %q must be a selector string", name)
+		}
+		if strings.TrimSpace(name) == "" {
+			return opts, errors.New("field names must not be empty")
+		}
+		opts.Fields = append(opts.Fields, parseListField(name, spec))
+	}
+	sort.Slice(opts.Fields, func(i, j int) bool { return opts.Fields[i].Name < opts.Fields[j].Name })
+
+	nextSelector, _ := args["next_selector"].(string)
+	opts.NextSelector = strings.TrimSpace(nextSelector)
+	if v, ok := args["max_pages"].(float64); ok {
+		if v < 1 || v > listMaxPagesLimit {
+			return opts, fmt.Errorf("max_pages must be between 1 and %d", listMaxPagesLimit)
+		}
+		opts.MaxPages = int(v)
+	}
+	if v, ok := args["max_items"].(float64); ok {
+		if v < 1 || v > listMaxItemsLimit {
+			return opts, fmt.Errorf("max_items must be between 1 and %d", listMaxItemsLimit)
+		}
+		opts.MaxItems = int(v)
+	}
+	dedupeField, _ := args["dedupe_field"].(string)
+	if opts.DedupeField = strings.TrimSpace(dedupeField); opts.DedupeField != "" {
+		if _, ok := rawFields[opts.DedupeField]; !ok {
+			return opts, fmt.Errorf("dedupe_field %q is not one of the fields", opts.DedupeField)
+		}
+	}
+	return opts, nil
+}
+
+// addExtractListTool registers browser_extract_list.
+func (bs *BrowserServer) addExtractListTool() {
+	bs.addTool(mcp.NewTool(
+		"browser_extract_list",
+		mcp.WithDescription("Extract the items of a list, e.g. search results or products, into a JSON array, following the pagination button across pages. Returns items, pages visited, stop_reason (no_next, no_change, max_pages, max_items or error) and stopped_at_limit when more items were left."),
+		mcp.WithString("item_selector",
+			mcp.Description("CSS selector matching every list item"),
+			mcp.Required(),
+		),
+		mcp.WithObject("fields",
+			mcp.Description("Field names to selectors within the item; the text by default, \"a@href\" for an attribute, \"@data-id\" for an attribute of the item itself, \"\" for the text of the item. E.g. {\"title\": \"h2\", \"url\": \"a@href\"}"),
+			mcp.Required(),
+		),
+		mcp.WithString("next_selector",
+			mcp.Description("CSS selector of the next page or load more button; only the current page is extracted without it"),
+		),
+		mcp.WithNumber("max_pages",
+			mcp.Description(fmt.Sprintf("Maximum pages to visit (default: %d, max: %d)", listDefaultMaxPages, listMaxPagesLimit)),
+		),
+		mcp.WithNumber("max_items",
+			mcp.Description(fmt.Sprintf("Maximum items to return (default: %d, max: %d)", listDefaultMaxItems, listMaxItemsLimit)),
+		),
+		mcp.WithString("dedupe_field",
+			mcp.Description("Drop items whose value of this field was seen before, for load more buttons and infinite scroll that keep the earlier items"),
+		),
+	), bs.handleExtractList)
+}
+
+func (bs *BrowserServer) handleExtractList(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	opts, err := parseListOptions(request.GetArguments())
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	timeout := time.Duration(bs.cfg().SelectorQueryTimeout) * time.Second
+	page := scriptListPage{
+		page:    tabPage{ctx: bs.pageContext(ctx), timeout: timeout},
+		opts:    opts,
+		timeout: timeout,
+	}
+	result, err := extractList(page, opts)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to extract list", err)), nil
+	}
+	bs.Logger.Debug().Int("items", len(result.Items)).Int("pages", result.Pages).Str("stop_reason", result.StopReason).Msg("list extracted")
+	data, err := json.Marshal(result)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to marshal list", err), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,422 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// browser_extract_metadata 的提取类别
+const (
+	MetadataOpenGraph = "opengraph" // og:*、article:*、product:* 等 OpenGraph meta 标签
+	MetadataTwitter   = "twitter"   // twitter:* 卡片 meta 标签
+	MetadataCanonical = "canonical" // link rel=canonical
+	MetadataFeeds     = "feeds"     // RSS、Atom 和 JSON Feed 的 link rel=alternate
+	MetadataJSONLD    = "json_ld"   // application/ld+json 脚本块
+	MetadataMicrodata = "microdata" // itemscope/itemprop 条目
+)
+
+const (
+	metadataDefaultMaxBytes = 512 * 1024      // JSON-LD 默认总大小上限
+	metadataMaxBytesLimit   = 8 * 1024 * 1024 // max_bytes 的上限
+	metadataMaxMicrodata    = 100             // 最多返回的顶层 microdata 条目数
+	metadataMaxDepth        = 5               // microdata 嵌套条目的最大深度
+)
+
+// metadataTypes lists the categories in the order of the result.
+var metadataTypes = []string{MetadataOpenGraph, MetadataTwitter, MetadataCanonical, MetadataFeeds, MetadataJSONLD, MetadataMicrodata}
+
+// metadataOptions are the arguments of browser_extract_metadata.
+type metadataOptions struct {
+	Types    map[string]bool
+	MaxBytes int
+}
+
+// jsonLDBlock is the raw text of a JSON-LD script block, nil when it is larger than max_bytes.
+type jsonLDBlock struct {
+	Size int     `json:"size"`
+	Text *string `json:"text"`
+}
+
+// MetadataFeed is an RSS, Atom or JSON Feed alternate link.
+type MetadataFeed struct {
+	Href  string `json:"href"`
+	Type  string `json:"type"`
+	Title string `json:"title,omitempty"`
+}
+
+// jsMetadata is what metadataExtractJS returns, categories that weren't selected are missing.
+type jsMetadata struct {
+	URL            string                   `json:"url"`
+	OpenGraph      map[string]interface{}   `json:"opengraph"`
+	Twitter        map[string]interface{}   `json:"twitter"`
+	Canonical      *string                  `json:"canonical"`
+	Feeds          []MetadataFeed           `json:"feeds"`
+	JSONLD         []jsonLDBlock            `json:"json_ld"`
+	Microdata      []map[string]interface{} `json:"microdata"`
+	MicrodataTotal int                      `json:"microdata_total"`
+}
+
+// metadataExtractJS collects the selected categories in a single pass without touching the page.
+// JSON-LD blocks are returned as text and parsed on the Go side, so a malformed block only costs
+// a warning. Repeated meta tags and item properties become arrays, and href/src are resolved. It
+// is plain ES5 and returns a jsMetadata as a JSON string. The %s are the selected categories as a
+// JSON object and the %d are max_bytes, the maximum microdata items and their maximum depth.
+const metadataExtractJS = `(function(types, maxBytes, maxItems, maxDepth) {
+	var feedTypes = {"application/rss+xml": true, "application/atom+xml": true, "application/feed+json": true};
+	function attr(el, name) {
+		var value = el.getAttribute(name);
+		return value === null || value === undefined ? "" : String(value);
+	}
+	function has(el, name) {
+		var value = el.getAttribute(name);
+		return value !== null && value !== undefined;
+	}
+	function abs(el, name) {
+		if (typeof el[name] === "string" && el[name]) {
+			return el[name];
+		}
+		return attr(el, name);
+	}
+	function clean(s) {
+		return String(s || "").replace(/\s+/g, " ").replace(/^ | $/g, "");
+	}
+	function add(obj, key, value) {
+		if (!Object.prototype.hasOwnProperty.call(obj, key)) {
+			obj[key] = value;
+		} else if (obj[key] instanceof Array) {
+			obj[key].push(value);
+		} else {
+			obj[key] = [obj[key], value];
+		}
+	}
+	function propValue(el, depth) {
+		if (has(el, "itemscope")) {
+			return depth < maxDepth ? item(el, depth + 1) : null;
+		}
+		var tag = String(el.tagName || "").toLowerCase();
+		if (tag === "meta") {
+			return attr(el, "content");
+		}
+		if (tag === "a" || tag === "link" || tag === "area") {
+			return abs(el, "href");
+		}
+		if (tag === "img" || tag === "audio" || tag === "video" || tag === "source" || tag === "iframe" || tag === "embed") {
+			return abs(el, "src");
+		}
+		if (tag === "time" && has(el, "datetime")) {
+			return attr(el, "datetime");
+		}
+		if ((tag === "data" || tag === "meter") && has(el, "value")) {
+			return attr(el, "value");
+		}
+		if (has(el, "content")) {
+			return attr(el, "content");
+		}
+		return clean(el.textContent);
+	}
+	function collect(el, obj, depth) {
+		var children = el.children || [];
+		for (var i = 0; i < children.length; i++) {
+			var child = children[i];
+			if (has(child, "itemprop")) {
+				var value = propValue(child, depth);
+				var names = clean(attr(child, "itemprop")).split(" ");
+				for (var j = 0; j < names.length; j++) {
+					if (names[j]) {
+						add(obj, names[j], value);
+					}
+				}
+			}
+			// 嵌套条目的属性属于嵌套条目
+			if (!has(child, "itemscope")) {
+				collect(child, obj, depth);
+			}
+		}
+	}
+	function item(el, depth) {
+		var obj = {};
+		if (attr(el, "itemtype")) {
+			obj["@type"] = attr(el, "itemtype");
+		}
+		if (attr(el, "itemid")) {
+			obj["@id"] = attr(el, "itemid");
+		}
+		collect(el, obj, depth);
+		return obj;
+	}
+	var result = {url: location.href};
+	var i, el;
+	if (types.opengraph || types.twitter) {
+		if (types.opengraph) {
+			result.opengraph = {};
+		}
+		if (types.twitter) {
+			result.twitter = {};
+		}
+		var metas = document.getElementsByTagName("meta");
+		for (i = 0; i < metas.length; i++) {
+			el = metas[i];
+			var key = attr(el, "property") || attr(el, "name");
+			if (types.opengraph && /^(og|article|product|book|profile|music|video):/.test(key)) {
+				add(result.opengraph, key, attr(el, "content"));
+			} else if (types.twitter && /^twitter:/.test(key)) {
+				add(result.twitter, key, attr(el, "content"));
+			}
+		}
+	}
+	if (types.canonical || types.feeds) {
+		if (types.canonical) {
+			result.canonical = null;
+		}
+		if (types.feeds) {
+			result.feeds = [];
+		}
+		var links = document.getElementsByTagName("link");
+		for (i = 0; i < links.length; i++) {
+			el = links[i];
+			var rel = " " + clean(attr(el, "rel")).toLowerCase() + " ";
+			if (types.canonical && result.canonical === null && rel.indexOf(" canonical ") >= 0) {
+				result.canonical = abs(el, "href") || null;
+			}
+			var type = clean(attr(el, "type")).toLowerCase();
+			if (types.feeds && rel.indexOf(" alternate ") >= 0 && feedTypes[type] === true) {
+				result.feeds.push({href: abs(el, "href"), type: type, title: attr(el, "title")});
+			}
+		}
+	}
+	if (types.json_ld) {
+		result.json_ld = [];
+		var scripts = document.getElementsByTagName("script");
+		for (i = 0; i < scripts.length; i++) {
+			el = scripts[i];
+			if (clean(attr(el, "type")).toLowerCase() !== "application/ld+json") {
+				continue;
+			}
+			var text = String(el.textContent || "");
+			result.json_ld.push({size: text.length, text: text.length > maxBytes ? null : text});
+		}
+	}
+	if (types.microdata) {
+		result.microdata = [];
+		result.microdata_total = 0;
+		var scopes = document.querySelectorAll("[itemscope]");
+		for (i = 0; i < scopes.length; i++) {
+			// 只有顶层条目，作为属性的条目包含在其所属条目中
+			if (has(scopes[i], "itemprop")) {
+				continue;
+			}
+			result.microdata_total++;
+			if (result.microdata.length < maxItems) {
+				result.microdata.push(item(scopes[i], 0));
+			}
+		}
+	}
+	return JSON.stringify(result);
+})(%s, %d, %d, %d)`
+
+// metadataScript returns metadataExtractJS for the options.
+func metadataScript(opts metadataOptions) (string, error) {
+	types, err := json.Marshal(opts.Types)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(metadataExtractJS, types, opts.MaxBytes, metadataMaxMicrodata, metadataMaxDepth), nil
+}
+
+// parseMetadataOptions validates the arguments of browser_extract_metadata.
+func parseMetadataOptions(args map[string]interface{}) (metadataOptions, error) {
+	opts := metadataOptions{Types: make(map[string]bool), MaxBytes: metadataDefaultMaxBytes}
+	switch raw := args["types"].(type) {
+	case nil:
+		for _, t := range metadataTypes {
+			opts.Types[t] = true
+		}
+	case []interface{}:
+		for _, v := range raw {
+			t, _ := v.(string)
+			if !isMetadataType(t) {
+				return opts, fmt.Errorf("unknown metadata type %v, must be one of %s", v, strings.Join(metadataTypes, ", "))
+			}
+			opts.Types[t] = true
+		}
+		if len(opts.Types) == 0 {
+			return opts, errors.New("types must not be empty")
+		}
+	default:
+		return opts, errors.New("types must be an array of strings")
+	}
+	if v, ok := args["max_bytes"]; ok {
+		n, ok := v.(float64)
+		if !ok || n < 1 || n > metadataMaxBytesLimit {
+			return opts, fmt.Errorf("max_bytes must be between 1 and %d", metadataMaxBytesLimit)
+		}
+		opts.MaxBytes = int(n)
+	}
+	return opts, nil
+}
+
+func isMetadataType(t string) bool {
+	for _, mt := range metadataTypes {
+		if t == mt {
+			return true
+		}
+	}
+	return false
+}
+
+// trimJSONLD removes the HTML comment and CDATA wrappers some sites put around JSON-LD.
+func trimJSONLD(text string) string {
+	text = strings.TrimSpace(text)
+	for _, wrapper := range [][2]string{{"<!--", "-->"}, {"//<![CDATA[", "//]]>"}, {"<![CDATA[", "]]>"}} {
+		if strings.HasPrefix(text, wrapper[0]) && strings.HasSuffix(text, wrapper[1]) {
+			text = strings.TrimSpace(text[len(wrapper[0]) : len(text)-len(wrapper[1])])
+		}
+	}
+	return text
+}
+
+// parseJSONLD parses the JSON-LD blocks until their total size reaches maxBytes. Malformed,
+// empty and oversized blocks are skipped with a warning, the others are still returned.
+func parseJSONLD(blocks []jsonLDBlock, maxBytes int) ([]interface{}, []string) {
+	items := []interface{}{}
+	var warnings []string
+	total := 0
+	for i, block := range blocks {
+		n := i + 1
+		if block.Text == nil {
+			warnings = append(warnings, fmt.Sprintf("json_ld block %d is larger than max_bytes (%d), skipped", n, maxBytes))
+			continue
+		}
+		text := trimJSONLD(*block.Text)
+		if text == "" {
+			warnings = append(warnings, fmt.Sprintf("json_ld block %d is empty", n))
+			continue
+		}
+		if total+len(text) > maxBytes {
+			warnings = append(warnings, fmt.Sprintf("json_ld block %d (%d bytes) exceeds the remaining max_bytes (%d of %d), skipped", n, len(text), maxBytes-total, maxBytes))
+			continue
+		}
+		var item interface{}
+		if err := json.Unmarshal([]byte(text), &item); err != nil {
+			warnings = append(warnings, fmt.Sprintf("json_ld block %d is not valid JSON: %v", n, err))
+			continue
+		}
+		total += len(text)
+		items = append(items, item)
+	}
+	return items, warnings
+}
+
+// buildMetadata turns the result of metadataExtractJS into the result of the tool: the url, one
+// key per selected category and the warnings.
+func buildMetadata(raw string, opts metadataOptions) (map[string]interface{}, error) {
+	var md jsMetadata
+	if err := json.Unmarshal([]byte(raw), &md); err != nil {
+		return nil, fmt.Errorf("unexpected metadata result %q: %w", raw, err)
+	}
+	warnings := []string{}
+	result := map[string]interface{}{"url": md.URL}
+	for _, t := range metadataTypes {
+		if !opts.Types[t] {
+			continue
+		}
+		switch t {
+		case MetadataOpenGraph:
+			result[t] = nonNilMap(md.OpenGraph)
+		case MetadataTwitter:
+			result[t] = nonNilMap(md.Twitter)
+		case MetadataCanonical:
+			result[t] = md.Canonical
+		case MetadataFeeds:
+			if md.Feeds == nil {
+				md.Feeds = []MetadataFeed{}
+			}
+			result[t] = md.Feeds
+		case MetadataJSONLD:
+			items, ws := parseJSONLD(md.JSONLD, opts.MaxBytes)
+			result[t] = items
+			warnings = append(warnings, ws...)
+		case MetadataMicrodata:
+			if md.Microdata == nil {
+				md.Microdata = []map[string]interface{}{}
+			}
+			result[t] = md.Microdata
+			if md.MicrodataTotal > len(md.Microdata) {
+				warnings = append(warnings, fmt.Sprintf("only the first %d of %d microdata items are returned", len(md.Microdata), md.MicrodataTotal))
+			}
+		}
+	}
+	result["warnings"] = warnings
+	return result, nil
+}
+
+func nonNilMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return map[string]interface{}{}
+	}
+	return m
+}
+
+// addExtractMetadataTool registers browser_extract_metadata.
+func (bs *BrowserServer) addExtractMetadataTool() {
+	bs.addTool(mcp.NewTool(
+		"browser_extract_metadata",
+		mcp.WithDescription("Extract the structured metadata of the current page in one pass: OpenGraph and Twitter card meta tags, the canonical URL, RSS/Atom feeds, JSON-LD blocks parsed as JSON and microdata items as objects. Useful for bookmarking, summaries and prices. Returns one key per category and warnings for malformed or oversized JSON-LD blocks."),
+		mcp.WithArray("types",
+			mcp.Description("Categories to extract (default: all)"),
+			mcp.Items(map[string]interface{}{"type": "string", "enum": metadataTypes}),
+		),
+		mcp.WithNumber("max_bytes",
+			mcp.Description(fmt.Sprintf("Maximum total size of the JSON-LD blocks, larger blocks are skipped with a warning (default: %d, max: %d)", metadataDefaultMaxBytes, metadataMaxBytesLimit)),
+		),
+	), bs.handleExtractMetadata)
+}
+
+func (bs *BrowserServer) handleExtractMetadata(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	opts, err := parseMetadataOptions(request.GetArguments())
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	script, err := metadataScript(opts)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	runCtx, cancel := context.WithTimeout(bs.pageContext(ctx), time.Duration(bs.cfg().SelectorQueryTimeout)*time.Second)
+	defer cancel()
+	var raw string
+	if err := bs.runner.Evaluate(runCtx, script, &raw); err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to extract metadata", err)), nil
+	}
+	result, err := buildMetadata(raw, opts)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ArgumentError(err)), nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to marshal metadata", err)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,761 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+var (
+	// ErrHARRecording is returned by browser_har_start when the tab is already recorded.
+	ErrHARRecording = errors.New("a HAR recording is already running in this tab")
+	// ErrHARNotRecording is returned by browser_har_stop when the tab is not recorded.
+	ErrHARNotRecording = errors.New("no HAR recording is running in this tab, start one with browser_har_start")
+)
+
+const (
+	// harVersion is the version of the HAR format written.
+	harVersion = "1.2"
+	// harMaxBodySize is the default maximum size of a response body kept in an entry.
+	harMaxBodySize = 1024 * 1024
+	// harBodyTimeout is the timeout of fetching one response body.
+	harBodyTimeout = 5 * time.Second
+	// harTimeFormat is the ISO 8601 format of the HAR timestamps.
+	harTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+)
+
+// The HAR 1.2 objects, see http://www.softwareishard.com/blog/har-12-spec/.
+type (
+	harCreator struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	harPage struct {
+		StartedDateTime string         `json:"startedDateTime"`
+		ID              string         `json:"id"`
+		Title           string         `json:"title"`
+		PageTimings     harPageTimings `json:"pageTimings"`
+	}
+	harPageTimings struct {
+		OnContentLoad float64 `json:"onContentLoad"`
+		OnLoad        float64 `json:"onLoad"`
+	}
+	harEntry struct {
+		Pageref         string      `json:"pageref,omitempty"`
+		StartedDateTime string      `json:"startedDateTime"`
+		Time            float64     `json:"time"`
+		Request         harRequest  `json:"request"`
+		Response        harResponse `json:"response"`
+		Cache           struct{}    `json:"cache"`
+		Timings         harTimings  `json:"timings"`
+		ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+		Connection      string      `json:"connection,omitempty"`
+		Comment         string      `json:"comment,omitempty"`
+	}
+	harRequest struct {
+		Method      string         `json:"method"`
+		URL         string         `json:"url"`
+		HTTPVersion string         `json:"httpVersion"`
+		Cookies     []harNameValue `json:"cookies"`
+		Headers     []harNameValue `json:"headers"`
+		QueryString []harNameValue `json:"queryString"`
+		PostData    *harPostData   `json:"postData,omitempty"`
+		HeadersSize int64          `json:"headersSize"`
+		BodySize    int64          `json:"bodySize"`
+	}
+	harResponse struct {
+		Status      int64          `json:"status"`
+		StatusText  string         `json:"statusText"`
+		HTTPVersion string         `json:"httpVersion"`
+		Cookies     []harNameValue `json:"cookies"`
+		Headers     []harNameValue `json:"headers"`
+		Content     harContent     `json:"content"`
+		RedirectURL string         `json:"redirectURL"`
+		HeadersSize int64          `json:"headersSize"`
+		BodySize    int64          `json:"bodySize"`
+	}
+	harNameValue struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	harPostData struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+	}
+	harContent struct {
+		Size     int64  `json:"size"`
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text,omitempty"`
+		Encoding string `json:"encoding,omitempty"`
+		Comment  string `json:"comment,omitempty"`
+	}
+	harTimings struct {
+		Blocked float64 `json:"blocked"`
+		DNS     float64 `json:"dns"`
+		Connect float64 `json:"connect"`
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+		Receive float64 `json:"receive"`
+		SSL     float64 `json:"ssl"`
+	}
+)
+
+// harPending is a request whose entry is not complete yet.
+type harPending struct {
+	entry       harEntry
+	start       float64 // requestWillBeSent 的单调时间，秒
+	responseAt  float64 // responseReceived 的单调时间，秒
+	timing      *network.ResourceTiming
+	headerBytes float64 // 收到响应头时已接收的字节数
+	dataLength  int64   // 解码后的响应体大小
+}
+
+// harRecorder records the network events of a tab. Completed entries are appended to a spool
+// file as JSON lines, so that only the requests in flight are kept in memory.
+type harRecorder struct {
+	mu            sync.Mutex
+	includeBodies bool
+	maxBodySize   int
+	fetchBody     func(network.RequestID) ([]byte, error) // 获取响应体，为空时不获取
+	cancel        context.CancelFunc                      // 停止监听事件
+	stopped       bool                                    // 停止后不再记录事件
+	spool         *os.File
+	spoolWriter   *bufio.Writer
+	spoolErr      error // 第一个写入错误
+	entries       int
+	pages         []harPage
+	pageStarts    []float64 // 每个页面开始导航的单调时间，秒
+	pageByLoader  map[cdp.LoaderID]string
+	mainFrame     cdp.FrameID
+	pending       map[network.RequestID]*harPending
+	bodies        sync.WaitGroup
+}
+
+// newHARRecorder creates a recorder whose spool file is in dir.
+func newHARRecorder(dir string, includeBodies bool, maxBodySize int) (*harRecorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	spool, err := os.CreateTemp(dir, "har_*.spool")
+	if err != nil {
+		return nil, err
+	}
+	return &harRecorder{
+		includeBodies: includeBodies,
+		maxBodySize:   maxBodySize,
+		cancel:        func() {},
+		spool:         spool,
+		spoolWriter:   bufio.NewWriter(spool),
+		pages:         []harPage{},
+		pageByLoader:  make(map[cdp.LoaderID]string),
+		pending:       make(map[network.RequestID]*harPending),
+	}, nil
+}
+
+// monotonicSeconds converts a CDP monotonic timestamp to seconds.
+func monotonicSeconds(t *cdp.MonotonicTime) float64 {
+	if t == nil {
+		return 0
+	}
+	return t.Time().Sub(*cdp.MonotonicTimeEpoch).Seconds()
+}
+
+// millis converts seconds to milliseconds rounded to microseconds.
+func millis(seconds float64) float64 {
+	return math.Round(seconds*1e6) / 1e3
+}
+
+// handleEvent records a network or page event. It is called by the event listener of the tab and
+// must not block.
+func (hr *harRecorder) handleEvent(ev interface{}) {
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+	if hr.stopped {
+		return
+	}
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		hr.requestWillBeSent(ev)
+	case *network.EventResponseReceived:
+		if p, ok := hr.pending[ev.RequestID]; ok && ev.Response != nil {
+			p.setResponse(ev.Response, monotonicSeconds(ev.Timestamp))
+		}
+	case *network.EventDataReceived:
+		if p, ok := hr.pending[ev.RequestID]; ok {
+			p.dataLength += ev.DataLength
+		}
+	case *network.EventLoadingFinished:
+		p, ok := hr.pending[ev.RequestID]
+		if !ok {
+			return
+		}
+		delete(hr.pending, ev.RequestID)
+		p.finish(monotonicSeconds(ev.Timestamp), ev.EncodedDataLength)
+		status := p.entry.Response.Status
+		if !hr.includeBodies || hr.fetchBody == nil || status == 204 || status == 304 {
+			hr.write(&p.entry)
+			return
+		}
+		// 事件回调中不能阻塞，在新的协程中获取响应体
+		hr.bodies.Add(1)
+		go func(id network.RequestID) {
+			defer hr.bodies.Done()
+			body, err := hr.fetchBody(id)
+			hr.mu.Lock()
+			defer hr.mu.Unlock()
+			if err != nil {
+				p.entry.Response.Content.Comment = fmt.Sprintf("body not available: %v", err)
+			} else {
+				p.entry.Response.Content.setBody(body, hr.maxBodySize)
+			}
+			hr.write(&p.entry)
+		}(ev.RequestID)
+	case *network.EventLoadingFailed:
+		p, ok := hr.pending[ev.RequestID]
+		if !ok {
+			return
+		}
+		delete(hr.pending, ev.RequestID)
+		p.finish(monotonicSeconds(ev.Timestamp), 0)
+		p.entry.Comment = ev.ErrorText
+		if ev.BlockedReason != "" {
+			p.entry.Comment = fmt.Sprintf("%s (blocked: %s)", ev.ErrorText, ev.BlockedReason)
+		}
+		hr.write(&p.entry)
+	case *page.EventFrameNavigated:
+		if ev.Frame != nil && ev.Frame.ParentID == "" {
+			hr.mainFrame = ev.Frame.ID
+		}
+	case *page.EventDomContentEventFired:
+		if n := len(hr.pages); n > 0 {
+			hr.pages[n-1].PageTimings.OnContentLoad = millis(monotonicSeconds(ev.Timestamp) - hr.pageStarts[n-1])
+		}
+	case *page.EventLoadEventFired:
+		if n := len(hr.pages); n > 0 {
+			hr.pages[n-1].PageTimings.OnLoad = millis(monotonicSeconds(ev.Timestamp) - hr.pageStarts[n-1])
+		}
+	}
+}
+
+// requestWillBeSent starts the entry of a request. A redirect completes the entry of the previous
+// hop, whose request id is reused. A navigation of the main frame starts a new page.
+func (hr *harRecorder) requestWillBeSent(ev *network.EventRequestWillBeSent) {
+	if ev.Request == nil {
+		return
+	}
+	start := monotonicSeconds(ev.Timestamp)
+	if p, ok := hr.pending[ev.RequestID]; ok && ev.RedirectResponse != nil {
+		delete(hr.pending, ev.RequestID)
+		p.setResponse(ev.RedirectResponse, start)
+		p.entry.Response.RedirectURL = ev.Request.URL
+		p.finish(start, ev.RedirectResponse.EncodedDataLength)
+		hr.write(&p.entry)
+	}
+
+	wallTime := time.Now()
+	if ev.WallTime != nil {
+		wallTime = ev.WallTime.Time()
+	}
+	navigation := ev.Type == network.ResourceTypeDocument && string(ev.RequestID) == string(ev.LoaderID)
+	if navigation && (hr.mainFrame == "" || ev.FrameID == hr.mainFrame) {
+		if _, ok := hr.pageByLoader[ev.LoaderID]; !ok {
+			id := fmt.Sprintf("page_%d", len(hr.pages)+1)
+			hr.pages = append(hr.pages, harPage{
+				StartedDateTime: wallTime.Format(harTimeFormat),
+				ID:              id,
+				Title:           ev.Request.URL,
+				PageTimings:     harPageTimings{OnContentLoad: -1, OnLoad: -1},
+			})
+			hr.pageStarts = append(hr.pageStarts, start)
+			hr.pageByLoader[ev.LoaderID] = id
+		}
+		if hr.mainFrame == "" {
+			hr.mainFrame = ev.FrameID
+		}
+	}
+	// 并发导航时按 loader 归属页面，未知的 loader 归属最新的页面
+	pageref, ok := hr.pageByLoader[ev.LoaderID]
+	if !ok && len(hr.pages) > 0 {
+		pageref = hr.pages[len(hr.pages)-1].ID
+	}
+
+	hr.pending[ev.RequestID] = &harPending{
+		start: start,
+		entry: harEntry{
+			Pageref:         pageref,
+			StartedDateTime: wallTime.Format(harTimeFormat),
+			Request:         newHARRequest(ev.Request),
+			Response: harResponse{
+				Cookies:     []harNameValue{},
+				Headers:     []harNameValue{},
+				HeadersSize: -1,
+				BodySize:    -1,
+				Content:     harContent{MimeType: "x-unknown"},
+			},
+		},
+	}
+}
+
+// newHARRequest converts a CDP request.
+func newHARRequest(r *network.Request) harRequest {
+	hr := harRequest{
+		Method:      r.Method,
+		URL:         r.URL + r.URLFragment,
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(r.Headers),
+		QueryString: []harNameValue{},
+		HeadersSize: -1,
+	}
+	if u, err := url.Parse(r.URL); err == nil {
+		keys := make([]string, 0)
+		query := u.Query()
+		for k := range query {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			for _, v := range query[k] {
+				hr.QueryString = append(hr.QueryString, harNameValue{Name: k, Value: v})
+			}
+		}
+	}
+	var body strings.Builder
+	for _, e := range r.PostDataEntries {
+		if data, err := base64.StdEncoding.DecodeString(e.Bytes); err == nil {
+			body.Write(data)
+		}
+	}
+	hr.BodySize = int64(body.Len())
+	if r.HasPostData {
+		mimeType, _ := headerValue(r.Headers, "Content-Type")
+		hr.PostData = &harPostData{MimeType: mimeType, Text: body.String()}
+	}
+	return hr
+}
+
+// harHeaders converts CDP headers, sorted by name. Chrome joins repeated headers with newlines.
+func harHeaders(headers network.Headers) []harNameValue {
+	list := []harNameValue{}
+	for name, value := range headers {
+		for _, v := range strings.Split(fmt.Sprint(value), "\n") {
+			list = append(list, harNameValue{Name: name, Value: v})
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// headerValue returns the value of a header, the name is case insensitive.
+func headerValue(headers network.Headers, name string) (string, bool) {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return fmt.Sprint(v), true
+		}
+	}
+	return "", false
+}
+
+// harHTTPVersion converts the protocol reported by Chrome, e.g. h2 or http/1.1.
+func harHTTPVersion(protocol string) string {
+	switch p := strings.ToLower(protocol); {
+	case p == "h2":
+		return "HTTP/2"
+	case p == "h3" || strings.HasPrefix(p, "h3-"):
+		return "HTTP/3"
+	case strings.HasPrefix(p, "http/"):
+		return strings.ToUpper(p)
+	default:
+		return protocol
+	}
+}
+
+// setResponse records the response headers.
+func (p *harPending) setResponse(r *network.Response, at float64) {
+	p.responseAt = at
+	p.timing = r.Timing
+	p.headerBytes = r.EncodedDataLength
+	version := harHTTPVersion(r.Protocol)
+	p.entry.Request.HTTPVersion = version
+	if len(r.RequestHeaders) > 0 {
+		// 实际发送的请求头更完整
+		p.entry.Request.Headers = harHeaders(r.RequestHeaders)
+	}
+	p.entry.Response.Status = r.Status
+	p.entry.Response.StatusText = r.StatusText
+	p.entry.Response.HTTPVersion = version
+	p.entry.Response.Headers = harHeaders(r.Headers)
+	if location, ok := headerValue(r.Headers, "Location"); ok {
+		p.entry.Response.RedirectURL = location
+	}
+	if r.MimeType != "" {
+		p.entry.Response.Content.MimeType = r.MimeType
+	}
+	if r.EncodedDataLength > 0 {
+		p.entry.Response.HeadersSize = int64(r.EncodedDataLength)
+	}
+	p.entry.ServerIPAddress = strings.Trim(r.RemoteIPAddress, "[]")
+	if r.ConnectionID > 0 {
+		p.entry.Connection = fmt.Sprint(int64(r.ConnectionID))
+	}
+	if r.FromDiskCache {
+		p.entry.Comment = "served from the disk cache"
+	}
+}
+
+// finish computes the timings and sizes when the request ends at end (monotonic seconds).
+// encoded is the number of bytes received including the headers, 0 when unknown.
+func (p *harPending) finish(end float64, encoded float64) {
+	if p.entry.Request.HTTPVersion == "" {
+		p.entry.Request.HTTPVersion = "unknown"
+	}
+	if p.entry.Response.HTTPVersion == "" {
+		p.entry.Response.HTTPVersion = "unknown"
+	}
+	p.entry.Response.Content.Size = p.dataLength
+	if p.entry.Response.Status > 0 && encoded > 0 {
+		p.entry.Response.BodySize = int64(math.Max(0, encoded-p.headerBytes))
+	}
+
+	span := func(from, to float64) float64 {
+		if from < 0 || to < from {
+			return -1
+		}
+		return millis((to - from) / 1e3)
+	}
+	nonNegative := func(v float64) float64 { return math.Max(0, v) }
+	t := harTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1}
+	if tm := p.timing; tm != nil {
+		// ResourceTiming 的各项是相对 requestTime 的毫秒数
+		blocked := (tm.RequestTime - p.start) * 1e3
+		for _, first := range []float64{tm.DNSStart, tm.ConnectStart, tm.SendStart} {
+			if first >= 0 {
+				blocked += first
+				break
+			}
+		}
+		t.Blocked = millis(nonNegative(blocked) / 1e3)
+		t.DNS = span(tm.DNSStart, tm.DNSEnd)
+		t.Connect = span(tm.ConnectStart, tm.ConnectEnd)
+		t.SSL = span(tm.SslStart, tm.SslEnd)
+		t.Send = millis(nonNegative(tm.SendEnd-tm.SendStart) / 1e3)
+		t.Wait = millis(nonNegative(tm.ReceiveHeadersEnd-tm.SendEnd) / 1e3)
+		t.Receive = millis(nonNegative((end-tm.RequestTime)*1e3-tm.ReceiveHeadersEnd) / 1e3)
+	} else {
+		// 没有网络时序，例如缓存或 data URL：等待到响应头，其余算作接收
+		headersAt := end
+		if p.responseAt > 0 {
+			headersAt = p.responseAt
+		}
+		t.Wait = millis(nonNegative(headersAt - p.start))
+		t.Receive = millis(nonNegative(end - headersAt))
+	}
+	p.entry.Timings = t
+	total := 0.0
+	// ssl 已包含在 connect 中
+	for _, v := range []float64{t.Blocked, t.DNS, t.Connect, t.Send, t.Wait, t.Receive} {
+		if v > 0 {
+			total += v
+		}
+	}
+	p.entry.Time = millis(total / 1e3)
+}
+
+// setBody stores the body in the content, truncated to maxSize bytes. Bodies that are not UTF-8
+// are base64 encoded.
+func (c *harContent) setBody(body []byte, maxSize int) {
+	c.Size = int64(len(body))
+	truncated := body
+	if maxSize > 0 && len(body) > maxSize {
+		truncated = body[:maxSize]
+		c.Comment = fmt.Sprintf("body truncated to %d of %d bytes", maxSize, len(body))
+	}
+	if utf8.Valid(body) {
+		// 截断时不能拆开多字节字符
+		for len(truncated) > 0 && !utf8.Valid(truncated) {
+			truncated = truncated[:len(truncated)-1]
+		}
+		c.Text = string(truncated)
+		return
+	}
+	c.Text = base64.StdEncoding.EncodeToString(truncated)
+	c.Encoding = "base64"
+}
+
+// write appends an entry to the spool file. It is called with mu held.
+func (hr *harRecorder) write(entry *harEntry) {
+	if hr.spoolErr != nil {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err == nil {
+		data = append(data, '\n')
+		_, err = hr.spoolWriter.Write(data)
+	}
+	if err != nil {
+		hr.spoolErr = err
+		return
+	}
+	hr.entries++
+}
+
+// stop stops the recording and writes the HAR file to path. The requests still in flight are
+// written without a response. It returns the number of entries and pages.
+func (hr *harRecorder) stop(path string, creator harCreator) (int, int, error) {
+	hr.halt()
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+	defer hr.discard()
+
+	ids := make([]string, 0, len(hr.pending))
+	for id := range hr.pending {
+		ids = append(ids, string(id))
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		p := hr.pending[network.RequestID(id)]
+		p.finish(p.start, 0)
+		p.entry.Comment = "the request was still in flight when the recording stopped"
+		hr.write(&p.entry)
+	}
+	hr.pending = make(map[network.RequestID]*harPending)
+	if hr.spoolErr == nil {
+		hr.spoolErr = hr.spoolWriter.Flush()
+	}
+	if hr.spoolErr != nil {
+		return 0, 0, fmt.Errorf("failed to write the HAR spool file: %w", hr.spoolErr)
+	}
+	if _, err := hr.spool.Seek(0, io.SeekStart); err != nil {
+		return 0, 0, err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := hr.writeHAR(f, creator); err != nil {
+		f.Close()
+		os.Remove(path)
+		return 0, 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, 0, err
+	}
+	return hr.entries, len(hr.pages), nil
+}
+
+// writeHAR writes the HAR log, copying the entries from the spool file one by one.
+func (hr *harRecorder) writeHAR(w io.Writer, creator harCreator) error {
+	bw := bufio.NewWriter(w)
+	header, err := json.Marshal(struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Pages   []harPage  `json:"pages"`
+	}{harVersion, creator, hr.pages})
+	if err != nil {
+		return err
+	}
+	// 去掉结尾的 }，接着写 entries
+	bw.WriteString(`{"log":`)
+	bw.Write(header[:len(header)-1])
+	bw.WriteString(`,"entries":[`)
+	r := bufio.NewReader(hr.spool)
+	for i := 0; ; i++ {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 1 {
+			if i > 0 {
+				bw.WriteByte(',')
+			}
+			bw.Write(line[:len(line)-1])
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+	bw.WriteString("]}}\n")
+	return bw.Flush()
+}
+
+// halt stops listening and waits for the bodies being fetched.
+func (hr *harRecorder) halt() {
+	hr.mu.Lock()
+	hr.stopped = true
+	hr.mu.Unlock()
+	hr.cancel()
+	hr.bodies.Wait()
+}
+
+// discard removes the spool file.
+func (hr *harRecorder) discard() {
+	hr.spool.Close()
+	os.Remove(hr.spool.Name())
+}
+
+// harStore tracks the recordings by tab.
+type harStore struct {
+	mu         sync.Mutex
+	recordings map[context.Context]*harRecorder
+}
+
+// add registers the recording of a tab, it fails when the tab is already recorded.
+func (s *harStore) add(tab context.Context, hr *harRecorder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.recordings == nil {
+		s.recordings = make(map[context.Context]*harRecorder)
+	}
+	if _, ok := s.recordings[tab]; ok {
+		return ErrHARRecording
+	}
+	s.recordings[tab] = hr
+	return nil
+}
+
+// take removes and returns the recording of a tab.
+func (s *harStore) take(tab context.Context) (*harRecorder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hr, ok := s.recordings[tab]
+	if !ok {
+		return nil, ErrHARNotRecording
+	}
+	delete(s.recordings, tab)
+	return hr, nil
+}
+
+// discardAll stops all recordings without writing them.
+func (s *harStore) discardAll() {
+	s.mu.Lock()
+	recordings := s.recordings
+	s.recordings = nil
+	s.mu.Unlock()
+	for _, hr := range recordings {
+		hr.halt()
+		hr.discard()
+	}
+}
+
+func (bs *BrowserServer) addHARTools() {
+	bs.addTool(mcp.NewTool(
+		"browser_har_start",
+		mcp.WithDescription("Start recording the network traffic of the current tab as a HAR archive: request and response headers, timings, sizes and bodies. Stop with browser_har_stop to write the file."),
+		mcp.WithBoolean("include_bodies",
+			mcp.Description("Record the response bodies (default: true). Turn off to keep the file small"),
+		),
+		mcp.WithNumber("max_body_size",
+			mcp.Description(fmt.Sprintf("Maximum size in bytes of a recorded response body, longer bodies are truncated (default: %d)", harMaxBodySize)),
+		),
+	), bs.handleHARStart)
+
+	bs.addTool(mcp.NewTool(
+		"browser_har_stop",
+		mcp.WithDescription("Stop the HAR recording of the current tab and write it as a HAR 1.2 file, which Chrome DevTools and other tools can import. Returns the path and the number of entries."),
+		mcp.WithString("name",
+			mcp.Description("Name of the HAR file, without extension (default: session)"),
+		),
+	), bs.handleHARStop)
+}
+
+func (bs *BrowserServer) handleHARStart(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	includeBodies := true
+	if v, ok := args["include_bodies"].(bool); ok {
+		includeBodies = v
+	}
+	maxBodySize := harMaxBodySize
+	if v, ok := args["max_body_size"].(float64); ok {
+		if v <= 0 {
+			return comm.ToolError(comm.ErrCodeInvalidArgument, "max_body_size must be greater than 0", ""), nil
+		}
+		maxBodySize = int(v)
+	}
+
+	tab := bs.pageContext(ctx)
+	hr, err := newHARRecorder(bs.cfg().DataPath, includeBodies, maxBodySize)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to create the HAR spool file", err), nil
+	}
+	if err := bs.hars.add(tab, hr); err != nil {
+		hr.discard()
+		return comm.ArgumentError(err), nil
+	}
+	hr.fetchBody = func(id network.RequestID) ([]byte, error) {
+		runCtx, cancel := context.WithTimeout(tab, harBodyTimeout)
+		defer cancel()
+		var body []byte
+		err := chromedp.Run(runCtx, chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			body, err = network.GetResponseBody(id).Do(ctx)
+			return err
+		}))
+		return body, err
+	}
+	listenCtx, cancel := context.WithCancel(tab)
+	hr.cancel = cancel
+	bs.listenTarget(listenCtx, hr.handleEvent)
+	if err := bs.emulate(ctx, network.Enable()); err != nil {
+		if hr, err := bs.hars.take(tab); err == nil {
+			hr.halt()
+			hr.discard()
+		}
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to enable network capture", err)), nil
+	}
+	return mcp.NewToolResultText("HAR recording started, stop it with browser_har_stop to write the file"), nil
+}
+
+func (bs *BrowserServer) handleHARStop(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	name, _ := args["name"].(string)
+	name = strings.TrimSuffix(filepath.Base(strings.TrimSpace(name)), ".har")
+	if name == "" || name == "." || name == string(filepath.Separator) {
+		name = "session"
+	}
+
+	hr, err := bs.hars.take(bs.pageContext(ctx))
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	path := filepath.Join(bs.cfg().DataPath, fmt.Sprintf("%s_%s.har", name, time.Now().Format("20060102_150405")))
+	entries, pages, err := hr.stop(path, harCreator{Name: "MoLing", Version: bs.MlConfig().Version})
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to write the HAR file", err), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("HAR with %d entries and %d pages written to %s", entries, pages, path)), nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// defaultHeadless reports whether the browser should default to headless mode, and why. On Linux
+// and the BSDs Chrome needs an X11 or Wayland display to open a window; macOS and Windows always
+// have one, so they keep the headed default.
+func defaultHeadless(goos string, getenv func(string) string) (bool, string) {
+	switch goos {
+	case "darwin", "windows", "android", "ios":
+		return false, ""
+	}
+	if getenv("DISPLAY") != "" || getenv("WAYLAND_DISPLAY") != "" {
+		return false, ""
+	}
+	return true, "DISPLAY and WAYLAND_DISPLAY are not set"
+}
+
+// headless reports the effective browser mode.
+func (bs *BrowserServer) headless() bool {
+	bs.restartLock.Lock()
+	defer bs.restartLock.Unlock()
+	return bs.cfg().Headless
+}
+
+// addHeadlessTool registers browser_set_headless. It bypasses addTool: it takes the write side of
+// opLock itself, and switching the mode is not a step worth recording into a macro.
+func (bs *BrowserServer) addHeadlessTool() {
+	bs.AddTool(mcp.NewTool(
+		"browser_set_headless",
+		mcp.WithDescription("Switch the browser between headless and headed mode. The browser is restarted with the same profile, so cookies and logins survive, but open tabs and page state are lost. Calls in flight finish first, new calls wait for the restart."),
+		mcp.WithBoolean("headless",
+			mcp.Description("true for headless mode, false to show the browser window"),
+			mcp.Required(),
+		),
+	), bs.handleSetHeadless)
+}
+
+func (bs *BrowserServer) handleSetHeadless(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	headless, ok := request.GetArguments()["headless"].(bool)
+	if !ok {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, "headless must be a boolean", ""), nil
+	}
+	if bs.cfg().remote() {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, ErrRemoteBrowser.Error(), "the mode of a remote browser is set where it is started"), nil
+	}
+
+	// 等待进行中的工具调用结束，新的调用在浏览器重启完成前阻塞
+	bs.opLock.Lock()
+	defer bs.opLock.Unlock()
+	bs.restartLock.Lock()
+	defer bs.restartLock.Unlock()
+
+	previous := bs.cfg()
+	if previous.Headless == headless {
+		return mcp.NewToolResultText(fmt.Sprintf("The browser is already in %s mode", modeName(headless))), nil
+	}
+	next := *previous
+	next.Headless = headless
+	bs.setConfig(&next)
+	if err := bs.restartBrowser(); err != nil {
+		// 切换失败时恢复原模式，避免浏览器不可用
+		bs.setConfig(previous)
+		if rerr := bs.restartBrowser(); rerr != nil {
+			bs.Logger.Error().Err(rerr).Msg("failed to restore the previous browser mode")
+		}
+		return comm.ToolErrorFromErr(fmt.Sprintf("failed to switch to %s mode", modeName(headless)), err), nil
+	}
+	bs.Logger.Info().Bool("headless", headless).Msg("browser mode switched")
+	return mcp.NewToolResultText(fmt.Sprintf("The browser was restarted in %s mode, the profile is kept", modeName(headless))), nil
+}
+
+// modeName returns the human-readable name of a browser mode.
+func modeName(headless bool) string {
+	if headless {
+		return "headless"
+	}
+	return "headed"
+}
@@ -0,0 +1,134 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// errNoHistoryEntry is the error text of chromedp.NavigateBack and NavigateForward when the
+// history has no entry in that direction.
+const errNoHistoryEntry = "invalid navigation entry"
+
+// historyInfoJS returns the URL and the title of the page after a history navigation.
+const historyInfoJS = `({url: location.href, title: document.title})`
+
+// historyStep is a history navigation of browser_back, browser_forward or browser_reload.
+type historyStep struct {
+	tool        string
+	description string
+	action      func() chromedp.NavigateAction
+	done        string // 成功结果的开头
+	noEntry     string // 历史记录中没有对应条目时的错误
+}
+
+var historySteps = []historyStep{
+	{
+		tool:        "browser_back",
+		description: "Go back to the previous page in the history of the tab, like the back button",
+		action:      chromedp.NavigateBack,
+		done:        "Went back to",
+		noEntry:     "there is no previous page in the history to go back to",
+	},
+	{
+		tool:        "browser_forward",
+		description: "Go forward to the next page in the history of the tab, like the forward button",
+		action:      chromedp.NavigateForward,
+		done:        "Went forward to",
+		noEntry:     "there is no next page in the history to go forward to",
+	},
+	{
+		tool:        "browser_reload",
+		description: "Reload the current page",
+		action:      chromedp.Reload,
+		done:        "Reloaded",
+	},
+}
+
+// historyPage is the result of historyInfoJS.
+type historyPage struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+}
+
+// addHistoryTools registers browser_back, browser_forward and browser_reload.
+func (bs *BrowserServer) addHistoryTools() {
+	for _, step := range historySteps {
+		bs.addTool(mcp.NewTool(
+			step.tool,
+			mcp.WithDescription(step.description+" and wait for it to load, optionally until an element is visible. Returns the URL and title of the page"),
+			mcp.WithString("wait_for",
+				mcp.Description("CSS selector of an element to wait visible after the navigation"),
+			),
+			mcp.WithNumber("timeout_seconds",
+				mcp.Description("Timeout of the navigation and the wait condition (default: url_timeout of the config)"),
+			),
+		), bs.historyHandler(step))
+	}
+}
+
+// historyHandler returns the handler running the history navigation of step.
+func (bs *BrowserServer) historyHandler(step historyStep) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// wait_until 只对 browser_navigate 有意义，这里只取 wait_for 和超时
+		args := make(map[string]interface{})
+		for _, name := range []string{"wait_for", "timeout_seconds"} {
+			if v, ok := request.GetArguments()[name]; ok {
+				args[name] = v
+			}
+		}
+		opts, err := parseNavigateOptions(args, "", bs.cfg().URLTimeout)
+		if err != nil {
+			return bs.toolError(ctx, request, comm.ArgumentError(err)), nil
+		}
+
+		runCtx, cancel := context.WithTimeout(bs.pageContext(ctx), opts.Timeout)
+		defer cancel()
+		timedOut := func(err error) bool {
+			return errors.Is(err, context.DeadlineExceeded) || runCtx.Err() != nil
+		}
+		if err := bs.runner.Run(runCtx, step.action()); err != nil {
+			switch {
+			case step.noEntry != "" && strings.Contains(err.Error(), errNoHistoryEntry):
+				return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeNotFound, step.noEntry, "")), nil
+			case timedOut(err):
+				return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeTimeout, fmt.Sprintf("timed out after %s waiting for the page to load", opts.Timeout), "")), nil
+			}
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr(fmt.Sprintf("%s failed", step.tool), err)), nil
+		}
+		if opts.WaitFor != "" {
+			if err := bs.runner.Run(runCtx, chromedp.WaitVisible(opts.WaitFor, chromedp.ByQuery)); err != nil {
+				if timedOut(err) {
+					return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeTimeout, fmt.Sprintf("timed out after %s waiting for selector %s", opts.Timeout, opts.WaitFor), "")), nil
+				}
+				return bs.toolError(ctx, request, comm.ToolErrorFromErr(fmt.Sprintf("failed to wait for selector %s", opts.WaitFor), err)), nil
+			}
+		}
+		var info historyPage
+		if err := bs.runner.Evaluate(runCtx, historyInfoJS, &info); err != nil {
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr(fmt.Sprintf("%s succeeded, but failed to read the page URL and title", step.tool), err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("%s %s\nTitle: %s", step.done, info.URL, info.Title)), nil
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const maxKeyRepeat = 100 // browser_press_key 单次调用最多按键次数
+
+// namedKeys maps the key names of browser_press_key to the keys of the kb package. Single
+// printable characters such as "a", "A" or "/" are accepted as well.
+var namedKeys = map[string]string{
+	"Enter":      kb.Enter,
+	"Tab":        kb.Tab,
+	"Escape":     kb.Escape,
+	"Backspace":  kb.Backspace,
+	"Delete":     kb.Delete,
+	"Insert":     kb.Insert,
+	"Space":      " ",
+	"ArrowUp":    kb.ArrowUp,
+	"ArrowDown":  kb.ArrowDown,
+	"ArrowLeft":  kb.ArrowLeft,
+	"ArrowRight": kb.ArrowRight,
+	"Home":       kb.Home,
+	"End":        kb.End,
+	"PageUp":     kb.PageUp,
+	"PageDown":   kb.PageDown,
+	"F1":         kb.F1,
+	"F2":         kb.F2,
+	"F3":         kb.F3,
+	"F4":         kb.F4,
+	"F5":         kb.F5,
+	"F6":         kb.F6,
+	"F7":         kb.F7,
+	"F8":         kb.F8,
+	"F9":         kb.F9,
+	"F10":        kb.F10,
+	"F11":        kb.F11,
+	"F12":        kb.F12,
+}
+
+// keyModifiers maps the modifier names of browser_press_key to the modifier bits of
+// Input.dispatchKeyEvent.
+var keyModifiers = map[string]input.Modifier{
+	"alt":   input.ModifierAlt,
+	"ctrl":  input.ModifierCtrl,
+	"meta":  input.ModifierMeta,
+	"shift": input.ModifierShift,
+}
+
+// supportedKeys lists the named keys in a stable order for the error messages.
+func supportedKeys() string {
+	names := make([]string, 0, len(namedKeys))
+	for name := range namedKeys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// lookupKey returns the kb key of a key name. Named keys match case-insensitively, a single
+// character matches when the kb package knows it.
+func lookupKey(name string) (rune, error) {
+	for n, key := range namedKeys {
+		if strings.EqualFold(n, name) {
+			r, _ := utf8.DecodeRuneInString(key)
+			return r, nil
+		}
+	}
+	if r, size := utf8.DecodeRuneInString(name); size == len(name) && r != utf8.RuneError {
+		if k, ok := kb.Keys[r]; ok && k.Print {
+			return r, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown key %q, supported keys: %s, or a single character such as a, 1 or /", name, supportedKeys())
+}
+
+// pressKeyOptions are the arguments of browser_press_key.
+type pressKeyOptions struct {
+	Key       string
+	Selector  string
+	Modifiers []string
+	Repeat    int
+	key       rune
+	modifiers input.Modifier
+}
+
+// parsePressKeyOptions validates the arguments of browser_press_key.
+func parsePressKeyOptions(args map[string]interface{}) (pressKeyOptions, error) {
+	var opts pressKeyOptions
+	opts.Key, _ = args["key"].(string)
+	if opts.Key == "" {
+		return opts, fmt.Errorf("key must be a non-empty string, supported keys: %s", supportedKeys())
+	}
+	r, err := lookupKey(opts.Key)
+	if err != nil {
+		return opts, err
+	}
+	opts.key = r
+	if v, ok := args["selector"]; ok {
+		if opts.Selector, ok = v.(string); !ok {
+			return opts, fmt.Errorf("selector must be a string")
+		}
+	}
+	modifiers, err := stringArray(args["modifiers"], "modifiers")
+	if err != nil {
+		return opts, err
+	}
+	for _, name := range modifiers {
+		m, ok := keyModifiers[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return opts, fmt.Errorf("unknown modifier %q, supported modifiers: alt, ctrl, meta, shift", name)
+		}
+		if opts.modifiers&m == 0 {
+			opts.Modifiers = append(opts.Modifiers, strings.ToLower(strings.TrimSpace(name)))
+		}
+		opts.modifiers |= m
+	}
+	opts.Repeat = 1
+	if v, ok := args["repeat"]; ok {
+		f, ok := v.(float64)
+		if !ok || f < 1 || f > maxKeyRepeat || f != float64(int(f)) {
+			return opts, fmt.Errorf("repeat must be an integer between 1 and %d", maxKeyRepeat)
+		}
+		opts.Repeat = int(f)
+	}
+	return opts, nil
+}
+
+// keyEvents returns the keyDown, char and keyUp events of one key press. With ctrl, alt or meta
+// held no char event is sent, so that a shortcut such as ctrl+a doesn't type the character.
+func keyEvents(key rune, modifiers input.Modifier) []*input.DispatchKeyEventParams {
+	shortcut := modifiers&(input.ModifierAlt|input.ModifierCtrl|input.ModifierMeta) != 0
+	var events []*input.DispatchKeyEventParams
+	for _, ev := range kb.Encode(key) {
+		if ev.Type == input.KeyChar && shortcut {
+			continue
+		}
+		ev.Modifiers |= modifiers
+		events = append(events, ev)
+	}
+	return events
+}
+
+// keyLabel describes the key press for the result, e.g. ctrl+shift+Tab.
+func (opts pressKeyOptions) keyLabel() string {
+	return strings.Join(append(append([]string{}, opts.Modifiers...), opts.Key), "+")
+}
+
+// addPressKeyTool registers browser_press_key.
+func (bs *BrowserServer) addPressKeyTool() {
+	bs.addTool(mcp.NewTool(
+		"browser_press_key",
+		mcp.WithDescription("Press a key with real keyboard input, firing keydown, keypress and keyup, e.g. Enter to submit a form, Escape to close a dialog or ArrowDown to move in a list. Focuses the element of selector first when given, otherwise the key goes to the focused element"),
+		mcp.WithString("key",
+			mcp.Description("Key to press: "+supportedKeys()+", or a single character such as a, 1 or /"),
+			mcp.Required(),
+		),
+		mcp.WithString("selector",
+			mcp.Description("CSS selector of the element to focus before pressing the key"),
+		),
+		mcp.WithArray("modifiers",
+			mcp.Description("Modifier keys held during the press: ctrl, shift, alt, meta"),
+			mcp.Items(map[string]interface{}{"type": "string", "enum": []string{"ctrl", "shift", "alt", "meta"}}),
+		),
+		mcp.WithNumber("repeat",
+			mcp.Description(fmt.Sprintf("Number of times to press the key, 1 to %d (default: 1)", maxKeyRepeat)),
+		),
+	), bs.handlePressKey)
+}
+
+func (bs *BrowserServer) handlePressKey(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	opts, err := parsePressKeyOptions(request.GetArguments())
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ArgumentError(err)), nil
+	}
+
+	runCtx, cancel := context.WithTimeout(bs.pageContext(ctx), time.Duration(bs.cfg().SelectorQueryTimeout)*time.Second)
+	defer cancel()
+	if opts.Selector != "" {
+		if err := bs.runner.Run(runCtx, chromedp.Focus(opts.Selector, chromedp.ByQuery)); err != nil {
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr(fmt.Sprintf("failed to focus %s", opts.Selector), err)), nil
+		}
+	}
+	var actions []chromedp.Action
+	for i := 0; i < opts.Repeat; i++ {
+		for _, ev := range keyEvents(opts.key, opts.modifiers) {
+			actions = append(actions, ev)
+		}
+	}
+	if err := bs.runner.Run(runCtx, actions...); err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr(fmt.Sprintf("failed to press %s", opts.keyLabel()), err)), nil
+	}
+
+	msg := fmt.Sprintf("Pressed %s", opts.keyLabel())
+	if opts.Repeat > 1 {
+		msg += fmt.Sprintf(" %d times", opts.Repeat)
+	}
+	if opts.Selector != "" {
+		msg += " on " + opts.Selector
+	}
+	return mcp.NewToolResultText(msg), nil
+}
@@ -0,0 +1,390 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	macroDir     = "macros" // 宏存储目录，位于 DataPath 下
+	macroExt     = ".json"  // 宏文件扩展名
+	macroMaskArg = "mask"   // 录制时将 value 参数替换为占位符的参数名
+)
+
+var (
+	macroNameRegexp   = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	macroParamRegexp  = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+	errNotRecording   = errors.New("no macro is being recorded")
+	errAlreadyRecords = errors.New("a macro is already being recorded")
+)
+
+// MacroStep is a single recorded browser tool call.
+type MacroStep struct {
+	Tool      string                 `json:"tool"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+// Macro is a named sequence of browser tool calls that can be replayed.
+type Macro struct {
+	Name       string      `json:"name"`
+	CreatedAt  time.Time   `json:"created_at"`
+	Parameters []string    `json:"parameters,omitempty"` // 回放时必须提供的占位符参数
+	Steps      []MacroStep `json:"steps"`
+}
+
+// MacroStepResult is the replay result of a single step.
+type MacroStepResult struct {
+	Step    int    `json:"step"`
+	Tool    string `json:"tool"`
+	Status  string `json:"status"` // ok, error or skipped
+	Message string `json:"message,omitempty"`
+}
+
+// MacroReplayReport is the result of browser_macro_replay.
+type MacroReplayReport struct {
+	Macro     string            `json:"macro"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Skipped   int               `json:"skipped"`
+	Steps     []MacroStepResult `json:"steps"`
+}
+
+// addMacroTools registers the macro tools. They bypass addTool so that they are never recorded.
+func (bs *BrowserServer) addMacroTools() {
+	bs.AddTool(mcp.NewTool(
+		"browser_macro_record_start",
+		mcp.WithDescription("Start recording the following browser tool calls into a named macro. Pass mask to browser_fill to record its value as a {placeholder} instead of the literal."),
+		mcp.WithString("name",
+			mcp.Description("Macro name, letters, digits, - and _ only. An existing macro with the same name is overwritten"),
+			mcp.Required(),
+		),
+	), bs.handleMacroRecordStart)
+
+	bs.AddTool(mcp.NewTool(
+		"browser_macro_record_stop",
+		mcp.WithDescription("Stop recording and save the macro"),
+	), bs.handleMacroRecordStop)
+
+	bs.AddTool(mcp.NewTool(
+		"browser_macro_list",
+		mcp.WithDescription("List the saved macros with their steps and parameters"),
+	), bs.handleMacroList)
+
+	bs.AddTool(mcp.NewTool(
+		"browser_macro_replay",
+		mcp.WithDescription("Replay a saved macro step by step and return a per-step report"),
+		mcp.WithString("name",
+			mcp.Description("Macro name to replay"),
+			mcp.Required(),
+		),
+		mcp.WithObject("params",
+			mcp.Description("Values substituted for {placeholder} parameters in the recorded arguments"),
+		),
+		mcp.WithBoolean("continue_on_error",
+			mcp.Description("Continue with the next step when a step fails (default: false)"),
+		),
+	), bs.handleMacroReplay)
+}
+
+// withMacroRecording records successful calls of the tool while a macro is being recorded.
+func (bs *BrowserServer) withMacroRecording(handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := handler(ctx, request)
+		if err != nil || result == nil || result.IsError {
+			return result, err
+		}
+		bs.macroLock.Lock()
+		defer bs.macroLock.Unlock()
+		if bs.recording != nil {
+			step, params := recordStep(request.Params.Name, request.GetArguments())
+			bs.recording.Steps = append(bs.recording.Steps, step)
+			bs.recording.Parameters = appendUnique(bs.recording.Parameters, params...)
+		}
+		return result, err
+	}
+}
+
+// recordStep copies the arguments of a call. A value argument with a mask is replaced by the
+// {mask} placeholder, the mask itself is not recorded.
+func recordStep(tool string, args map[string]interface{}) (MacroStep, []string) {
+	step := MacroStep{Tool: tool, Arguments: make(map[string]interface{}, len(args))}
+	for k, v := range args {
+		step.Arguments[k] = v
+	}
+	mask, _ := step.Arguments[macroMaskArg].(string)
+	delete(step.Arguments, macroMaskArg)
+	if mask == "" {
+		return step, nil
+	}
+	if _, ok := step.Arguments["value"]; ok {
+		step.Arguments["value"] = "{" + mask + "}"
+	}
+	return step, []string{mask}
+}
+
+func (bs *BrowserServer) handleMacroRecordStart(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, _ := request.GetArguments()["name"].(string)
+	if !macroNameRegexp.MatchString(name) {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("invalid macro name %q, only letters, digits, - and _ are allowed", name), ""), nil
+	}
+	bs.macroLock.Lock()
+	defer bs.macroLock.Unlock()
+	if bs.recording != nil {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("%v: %s", errAlreadyRecords, bs.recording.Name), ""), nil
+	}
+	bs.recording = &Macro{Name: name, CreatedAt: time.Now()}
+	return mcp.NewToolResultText(fmt.Sprintf("Recording macro %s, call browser_macro_record_stop to save it", name)), nil
+}
+
+func (bs *BrowserServer) handleMacroRecordStop(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	bs.macroLock.Lock()
+	macro := bs.recording
+	bs.recording = nil
+	bs.macroLock.Unlock()
+	if macro == nil {
+		return comm.ArgumentError(errNotRecording), nil
+	}
+	path, err := bs.saveMacro(macro)
+	if err != nil {
+		return comm.ToolErrorFromErr(fmt.Sprintf("failed to save macro %s", macro.Name), err), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Saved macro %s with %d steps to %s, parameters: [%s]",
+		macro.Name, len(macro.Steps), path, strings.Join(macro.Parameters, ", "))), nil
+}
+
+func (bs *BrowserServer) handleMacroList(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	macros, err := bs.listMacros()
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to list macros", err), nil
+	}
+	if len(macros) == 0 {
+		return mcp.NewToolResultText("No macros saved"), nil
+	}
+	var sb strings.Builder
+	for _, m := range macros {
+		tools := make([]string, 0, len(m.Steps))
+		for _, s := range m.Steps {
+			tools = append(tools, s.Tool)
+		}
+		sb.WriteString(fmt.Sprintf("%s (%d steps, created %s)\n", m.Name, len(m.Steps), m.CreatedAt.Format(time.RFC3339)))
+		if len(m.Parameters) > 0 {
+			sb.WriteString(fmt.Sprintf("  parameters: %s\n", strings.Join(m.Parameters, ", ")))
+		}
+		sb.WriteString(fmt.Sprintf("  steps: %s\n", strings.Join(tools, " -> ")))
+	}
+	return mcp.NewToolResultText(sb.String()), nil
+}
+
+func (bs *BrowserServer) handleMacroReplay(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	name, _ := args["name"].(string)
+	continueOnError, _ := args["continue_on_error"].(bool)
+	params := make(map[string]string)
+	if raw, ok := args["params"].(map[string]interface{}); ok {
+		for k, v := range raw {
+			params[k] = fmt.Sprint(v)
+		}
+	}
+
+	macro, err := bs.loadMacro(name)
+	if err != nil {
+		return comm.ToolErrorFromErr(fmt.Sprintf("failed to load macro %s", name), err), nil
+	}
+	var missing []string
+	for _, p := range macro.Parameters {
+		if _, ok := params[p]; !ok {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) > 0 {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("missing macro parameters: %s", strings.Join(missing, ", ")), ""), nil
+	}
+
+	report := bs.replayMacro(ctx, macro, params, continueOnError)
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to encode replay report", err), nil
+	}
+	if report.Failed > 0 {
+		return mcp.NewToolResultError(string(data)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// replayMacro runs the steps through the registered tool handlers. After a failed step the
+// remaining steps are skipped unless continueOnError is set.
+func (bs *BrowserServer) replayMacro(ctx context.Context, macro *Macro, params map[string]string, continueOnError bool) *MacroReplayReport {
+	report := &MacroReplayReport{Macro: macro.Name}
+	stopped := false
+	for i, step := range macro.Steps {
+		res := MacroStepResult{Step: i + 1, Tool: step.Tool}
+		if stopped {
+			res.Status = "skipped"
+			report.Skipped++
+			report.Steps = append(report.Steps, res)
+			continue
+		}
+		msg, err := bs.runMacroStep(ctx, step, params)
+		if err != nil {
+			res.Status, res.Message = "error", err.Error()
+			report.Failed++
+			stopped = !continueOnError
+		} else {
+			res.Status, res.Message = "ok", msg
+			report.Succeeded++
+		}
+		report.Steps = append(report.Steps, res)
+	}
+	return report
+}
+
+// runMacroStep calls the handler of a single step and returns its text output.
+func (bs *BrowserServer) runMacroStep(ctx context.Context, step MacroStep, params map[string]string) (string, error) {
+	handler, ok := bs.toolHandlers[step.Tool]
+	if !ok {
+		return "", fmt.Errorf("unknown tool %s", step.Tool)
+	}
+	request := mcp.CallToolRequest{}
+	request.Params.Name = step.Tool
+	request.Params.Arguments = substituteParams(step.Arguments, params)
+	result, err := handler(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	var texts []string
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			texts = append(texts, text.Text)
+		}
+	}
+	msg := strings.Join(texts, "\n")
+	if result.IsError {
+		return "", fmt.Errorf("%s", msg)
+	}
+	return msg, nil
+}
+
+// substituteParams replaces {placeholder} in string values with the supplied parameters.
+// Placeholders without a supplied value are kept, e.g. braces in a script.
+func substituteParams(value interface{}, params map[string]string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return macroParamRegexp.ReplaceAllStringFunc(v, func(m string) string {
+			if p, ok := params[m[1:len(m)-1]]; ok {
+				return p
+			}
+			return m
+		})
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = substituteParams(item, params)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = substituteParams(item, params)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+func (bs *BrowserServer) macroPath(name string) string {
+	return filepath.Join(bs.cfg().DataPath, macroDir, name+macroExt)
+}
+
+// saveMacro persists the macro as JSON under DataPath/macros.
+func (bs *BrowserServer) saveMacro(macro *Macro) (string, error) {
+	if err := utils.CreateDirectory(filepath.Join(bs.cfg().DataPath, macroDir)); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(macro, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := bs.macroPath(macro.Name)
+	return path, os.WriteFile(path, data, 0600)
+}
+
+// loadMacro reads a saved macro by name.
+func (bs *BrowserServer) loadMacro(name string) (*Macro, error) {
+	if !macroNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid macro name %q", name)
+	}
+	data, err := os.ReadFile(bs.macroPath(name))
+	if err != nil {
+		return nil, err
+	}
+	var macro Macro
+	if err := json.Unmarshal(data, &macro); err != nil {
+		return nil, fmt.Errorf("invalid macro file: %w", err)
+	}
+	return &macro, nil
+}
+
+// listMacros returns the saved macros sorted by name.
+func (bs *BrowserServer) listMacros() ([]*Macro, error) {
+	entries, err := os.ReadDir(filepath.Join(bs.cfg().DataPath, macroDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var macros []*Macro
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != macroExt {
+			continue
+		}
+		macro, err := bs.loadMacro(strings.TrimSuffix(entry.Name(), macroExt))
+		if err != nil {
+			bs.Logger.Debug().Err(err).Str("file", entry.Name()).Msg("skip invalid macro")
+			continue
+		}
+		macros = append(macros, macro)
+	}
+	sort.Slice(macros, func(i, j int) bool { return macros[i].Name < macros[j].Name })
+	return macros, nil
+}
+
+func appendUnique(items []string, values ...string) []string {
+	for _, v := range values {
+		if !slices.Contains(items, v) {
+			items = append(items, v)
+		}
+	}
+	return items
+}
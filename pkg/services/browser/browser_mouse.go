@@ -0,0 +1,530 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	MouseMethodCDP   = "cdp"   // 通过 Input.dispatchMouseEvent 发送的真实鼠标输入
+	MouseMethodJS    = "js"    // 在页面中模拟的鼠标事件
+	MouseMethodHTML5 = "html5" // 在页面中模拟的 HTML5 拖放事件
+
+	defaultDragSteps = 10  // 拖动时按下和松开之间的移动次数
+	maxDragSteps     = 100 // 拖动移动次数上限
+)
+
+// point is a position in CSS pixels relative to the viewport.
+type point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// MouseActionResult is the result of browser_dblclick, browser_right_click and browser_drag_drop.
+type MouseActionResult struct {
+	Action   string `json:"action"`
+	Selector string `json:"selector,omitempty"`
+	Source   string `json:"source,omitempty"`
+	Target   string `json:"target,omitempty"`
+	From     *point `json:"from,omitempty"`
+	To       *point `json:"to,omitempty"`
+	Method   string `json:"method"` // cdp, js or html5
+	// ContextMenuHandled reports whether a contextmenu listener of the page called preventDefault,
+	// i.e. the page shows its own menu instead of the one of the browser.
+	ContextMenuHandled *bool `json:"context_menu_handled,omitempty"`
+}
+
+// mouseActions converts mouse events into actions for the Runner.
+func mouseActions(events []*input.DispatchMouseEventParams) []chromedp.Action {
+	actions := make([]chromedp.Action, 0, len(events))
+	for _, ev := range events {
+		actions = append(actions, ev)
+	}
+	return actions
+}
+
+// clickEvents moves the mouse to p and presses and releases button count times, with the
+// clickCount of each click, the way a user double-clicks.
+func clickEvents(p point, button input.MouseButton, count int) []*input.DispatchMouseEventParams {
+	events := []*input.DispatchMouseEventParams{input.DispatchMouseEvent(input.MouseMoved, p.X, p.Y)}
+	for i := 1; i <= count; i++ {
+		events = append(events,
+			input.DispatchMouseEvent(input.MousePressed, p.X, p.Y).WithButton(button).WithButtons(mouseButtons(button)).WithClickCount(int64(i)),
+			input.DispatchMouseEvent(input.MouseReleased, p.X, p.Y).WithButton(button).WithClickCount(int64(i)),
+		)
+	}
+	return events
+}
+
+// dragEvents presses the left button at from, moves to to in steps and releases it there. The
+// moves carry the pressed button so that the page sees a drag.
+func dragEvents(from, to point, steps int) []*input.DispatchMouseEventParams {
+	events := []*input.DispatchMouseEventParams{
+		input.DispatchMouseEvent(input.MouseMoved, from.X, from.Y),
+		input.DispatchMouseEvent(input.MousePressed, from.X, from.Y).WithButton(input.Left).WithButtons(1).WithClickCount(1),
+	}
+	for i := 1; i <= steps; i++ {
+		x := from.X + (to.X-from.X)*float64(i)/float64(steps)
+		y := from.Y + (to.Y-from.Y)*float64(i)/float64(steps)
+		events = append(events, input.DispatchMouseEvent(input.MouseMoved, x, y).WithButton(input.Left).WithButtons(1))
+	}
+	return append(events, input.DispatchMouseEvent(input.MouseReleased, to.X, to.Y).WithButton(input.Left).WithClickCount(1))
+}
+
+// mouseButtons is the buttons bit mask of the pressed button.
+func mouseButtons(button input.MouseButton) int64 {
+	switch button {
+	case input.Right:
+		return 2
+	case input.Middle:
+		return 4
+	default:
+		return 1
+	}
+}
+
+// elementCenterJS scrolls the element into view and returns the center of its box.
+func elementCenterJS(selector string) string {
+	return fmt.Sprintf(`(function() {
+	var el = document.querySelector(%s);
+	if (!el) return { found: false };
+	if (el.scrollIntoView) el.scrollIntoView({ block: "center", inline: "center" });
+	var r = el.getBoundingClientRect();
+	return { found: true, x: r.left + r.width / 2, y: r.top + r.height / 2 };
+})()`, safeJSONString(selector))
+}
+
+// elementCenter returns the center of the element in the viewport.
+func (bs *BrowserServer) elementCenter(ctx context.Context, selector string) (point, error) {
+	var res struct {
+		Found bool    `json:"found"`
+		X     float64 `json:"x"`
+		Y     float64 `json:"y"`
+	}
+	if err := bs.runner.Evaluate(ctx, elementCenterJS(selector), &res); err != nil {
+		return point{}, err
+	}
+	if !res.Found {
+		return point{}, fmt.Errorf("element %s not found", selector)
+	}
+	return point{X: res.X, Y: res.Y}, nil
+}
+
+// contextMenuProbeJS records the next contextmenu event on window. The listener runs in the
+// bubbling phase after the listeners of the page, so defaultPrevented tells if one handled it.
+const contextMenuProbeJS = `(function() {
+	window.__molingContextMenu = { fired: false, handled: false };
+	window.addEventListener("contextmenu", function probe(e) {
+		window.removeEventListener("contextmenu", probe);
+		window.__molingContextMenu = { fired: true, handled: e.defaultPrevented };
+	});
+	return true;
+})()`
+
+// contextMenuResultJS returns what the probe of contextMenuProbeJS recorded.
+const contextMenuResultJS = `(window.__molingContextMenu || { fired: false, handled: false })`
+
+// dblclickFallbackJS dispatches the mouse events of a double click on the element.
+func dblclickFallbackJS(selector string) string {
+	return fmt.Sprintf(`(function() {
+	try {
+		var el = document.querySelector(%s);
+		if (!el) return { success: false, error: "element not found" };
+		var r = el.getBoundingClientRect();
+		var init = function(detail) {
+			return { bubbles: true, cancelable: true, view: window, button: 0, detail: detail,
+				clientX: r.left + r.width / 2, clientY: r.top + r.height / 2 };
+		};
+		var types = ["mousedown", "mouseup", "click"];
+		for (var n = 1; n <= 2; n++) {
+			for (var i = 0; i < types.length; i++) {
+				el.dispatchEvent(new MouseEvent(types[i], init(n)));
+			}
+		}
+		el.dispatchEvent(new MouseEvent("dblclick", init(2)));
+		return { success: true };
+	} catch (e) {
+		return { success: false, error: e.message };
+	}
+})()`, safeJSONString(selector))
+}
+
+// rightClickFallbackJS dispatches a right click and a contextmenu event on the element.
+// handled is true when a listener of the page called preventDefault.
+func rightClickFallbackJS(selector string) string {
+	return fmt.Sprintf(`(function() {
+	try {
+		var el = document.querySelector(%s);
+		if (!el) return { success: false, error: "element not found" };
+		var r = el.getBoundingClientRect();
+		var init = { bubbles: true, cancelable: true, view: window, button: 2, buttons: 2,
+			clientX: r.left + r.width / 2, clientY: r.top + r.height / 2 };
+		el.dispatchEvent(new MouseEvent("mousedown", init));
+		el.dispatchEvent(new MouseEvent("mouseup", init));
+		var notPrevented = el.dispatchEvent(new MouseEvent("contextmenu", init));
+		return { success: true, handled: !notPrevented };
+	} catch (e) {
+		return { success: false, error: e.message };
+	}
+})()`, safeJSONString(selector))
+}
+
+// dragProbeJS records whether the source is draggable with HTML5 drag and drop, and the
+// dragstart and drop events that follow.
+func dragProbeJS(source string) string {
+	return fmt.Sprintf(`(function() {
+	var el = document.querySelector(%s);
+	var draggable = !!(el && el.closest && el.closest("[draggable=true]"));
+	var state = { draggable: draggable, dragstart: false, drop: false };
+	window.__molingDrag = state;
+	var onStart = function() { state.dragstart = true; };
+	var onDrop = function() {
+		state.drop = true;
+		document.removeEventListener("dragstart", onStart, true);
+		document.removeEventListener("drop", onDrop, true);
+	};
+	document.addEventListener("dragstart", onStart, true);
+	document.addEventListener("drop", onDrop, true);
+	return draggable;
+})()`, safeJSONString(source))
+}
+
+// dragResultJS returns what the probe of dragProbeJS recorded.
+const dragResultJS = `(window.__molingDrag || { draggable: false, dragstart: false, drop: false })`
+
+// dragFallbackJS drags the source onto the target selector, or onto the element at the offset
+// from the center of the source. Draggable sources get the HTML5 dragstart, dragenter,
+// dragover, drop and dragend events with a shared DataTransfer, or a shim when the browser
+// doesn't allow constructing one; other sources get mousedown, mousemove and mouseup.
+func dragFallbackJS(source, target string, offsetX, offsetY float64) string {
+	return fmt.Sprintf(`(function() {
+	try {
+		var src = document.querySelector(%s);
+		if (!src) return { success: false, error: "source element not found" };
+		var center = function(el) {
+			var r = el.getBoundingClientRect();
+			return { x: r.left + r.width / 2, y: r.top + r.height / 2 };
+		};
+		var from = center(src);
+		var targetSelector = %s;
+		var dst, to;
+		if (targetSelector) {
+			dst = document.querySelector(targetSelector);
+			if (!dst) return { success: false, error: "target element not found" };
+			to = center(dst);
+		} else {
+			to = { x: from.x + %g, y: from.y + %g };
+			dst = document.elementFromPoint(to.x, to.y) || document.body;
+		}
+
+		var dragSource = src.closest ? src.closest("[draggable=true]") : null;
+		if (dragSource) {
+			var dt;
+			try {
+				dt = new DataTransfer();
+			} catch (e) {
+				var store = {};
+				dt = {
+					dropEffect: "move", effectAllowed: "all", files: [], items: [], types: [],
+					setData: function(type, value) {
+						store[type] = String(value);
+						if (this.types.indexOf(type) < 0) this.types.push(type);
+					},
+					getData: function(type) { return store.hasOwnProperty(type) ? store[type] : ""; },
+					clearData: function(type) {
+						if (type === undefined) { store = {}; this.types = []; return; }
+						delete store[type];
+						var i = this.types.indexOf(type);
+						if (i >= 0) this.types.splice(i, 1);
+					},
+					setDragImage: function() {}
+				};
+			}
+			var fire = function(el, type, p) {
+				var ev = document.createEvent("Event");
+				ev.initEvent(type, true, true);
+				ev.clientX = p.x;
+				ev.clientY = p.y;
+				Object.defineProperty(ev, "dataTransfer", { value: dt });
+				return el.dispatchEvent(ev);
+			};
+			fire(dragSource, "dragstart", from);
+			fire(dst, "dragenter", to);
+			fire(dst, "dragover", to);
+			fire(dst, "drop", to);
+			fire(dragSource, "dragend", to);
+			return { success: true, method: "html5", from: from, to: to };
+		}
+
+		var mouse = function(el, type, p, buttons) {
+			el.dispatchEvent(new MouseEvent(type, { bubbles: true, cancelable: true, view: window,
+				button: 0, buttons: buttons, clientX: p.x, clientY: p.y }));
+		};
+		mouse(src, "mousedown", from, 1);
+		mouse(dst, "mousemove", to, 1);
+		mouse(dst, "mouseup", to, 0);
+		return { success: true, method: "js", from: from, to: to };
+	} catch (e) {
+		return { success: false, error: e.message };
+	}
+})()`, safeJSONString(source), safeJSONString(target), offsetX, offsetY)
+}
+
+// scriptResult is the result of the fallback scripts.
+type scriptResult struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error"`
+	Method  string `json:"method"`
+	Handled bool   `json:"handled"`
+	From    *point `json:"from"`
+	To      *point `json:"to"`
+}
+
+// runFallback runs a fallback script and turns a failure into an error.
+func (bs *BrowserServer) runFallback(ctx context.Context, script string) (scriptResult, error) {
+	var res scriptResult
+	if err := bs.runner.Evaluate(ctx, script, &res); err != nil {
+		return res, fmt.Errorf("failed to run the script: %w", err)
+	}
+	if !res.Success {
+		if res.Error == "" {
+			res.Error = "unknown error"
+		}
+		// 页面中找不到元素时脚本返回 "... not found"
+		if strings.HasSuffix(res.Error, "not found") {
+			return res, comm.NewCodedError(comm.ErrCodeNotFound, errors.New(res.Error))
+		}
+		return res, errors.New(res.Error)
+	}
+	return res, nil
+}
+
+// mouseResult returns the result as JSON.
+func mouseResult(result MouseActionResult) *mcp.CallToolResult {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to encode the result", err)
+	}
+	return mcp.NewToolResultText(string(data))
+}
+
+// mouseContext returns the context of a mouse tool, with the same timeout as browser_click.
+func (bs *BrowserServer) mouseContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(bs.pageContext(ctx), time.Duration(bs.cfg().SelectorQueryTimeout*3)*time.Second)
+}
+
+// handleDblClick double-clicks an element with real mouse input, or with simulated events when
+// that fails.
+func (bs *BrowserServer) handleDblClick(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	selector, ok := request.GetArguments()["selector"].(string)
+	if !ok || selector == "" {
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInvalidArgument, "selector must be a non-empty string", "")), nil
+	}
+	runCtx, cancel := bs.mouseContext(ctx)
+	defer cancel()
+
+	result := MouseActionResult{Action: "dblclick", Selector: selector, Method: MouseMethodCDP}
+	err := bs.runner.Run(runCtx, chromedp.WaitVisible(selector))
+	var p point
+	if err == nil {
+		p, err = bs.elementCenter(runCtx, selector)
+	}
+	if err == nil {
+		err = bs.runner.Run(runCtx, mouseActions(clickEvents(p, input.Left, 2))...)
+	}
+	if err == nil {
+		result.From = &p
+		return mouseResult(result), nil
+	}
+
+	bs.Logger.Debug().Str("selector", selector).Err(err).Msg("鼠标输入双击失败，尝试通过JavaScript双击")
+	if _, err := bs.runFallback(runCtx, dblclickFallbackJS(selector)); err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to double-click the element", err)), nil
+	}
+	result.Method = MouseMethodJS
+	return mouseResult(result), nil
+}
+
+// handleRightClick right-clicks an element and reports whether the page handled the
+// contextmenu event.
+func (bs *BrowserServer) handleRightClick(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	selector, ok := request.GetArguments()["selector"].(string)
+	if !ok || selector == "" {
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInvalidArgument, "selector must be a non-empty string", "")), nil
+	}
+	runCtx, cancel := bs.mouseContext(ctx)
+	defer cancel()
+
+	result := MouseActionResult{Action: "right_click", Selector: selector, Method: MouseMethodCDP}
+	var probe struct {
+		Fired   bool `json:"fired"`
+		Handled bool `json:"handled"`
+	}
+	var installed bool
+	err := bs.runner.Run(runCtx, chromedp.WaitVisible(selector))
+	var p point
+	if err == nil {
+		p, err = bs.elementCenter(runCtx, selector)
+	}
+	if err == nil {
+		err = bs.runner.Evaluate(runCtx, contextMenuProbeJS, &installed)
+	}
+	if err == nil {
+		err = bs.runner.Run(runCtx, mouseActions(clickEvents(p, input.Right, 1))...)
+	}
+	if err == nil {
+		err = bs.runner.Evaluate(runCtx, contextMenuResultJS, &probe)
+	}
+	if err == nil && !probe.Fired {
+		err = fmt.Errorf("no contextmenu event reached the page")
+	}
+	if err == nil {
+		result.From = &p
+		result.ContextMenuHandled = &probe.Handled
+		return mouseResult(result), nil
+	}
+
+	bs.Logger.Debug().Str("selector", selector).Err(err).Msg("鼠标输入右击失败，尝试通过JavaScript右击")
+	res, err := bs.runFallback(runCtx, rightClickFallbackJS(selector))
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to right-click the element", err)), nil
+	}
+	result.Method = MouseMethodJS
+	result.ContextMenuHandled = &res.Handled
+	return mouseResult(result), nil
+}
+
+// dragArgs are the arguments of browser_drag_drop.
+type dragArgs struct {
+	source, target   string
+	offsetX, offsetY float64
+	steps            int
+}
+
+// parseDragArgs reads the arguments of browser_drag_drop. The target is a selector or an offset
+// from the center of the source, not both.
+func parseDragArgs(args map[string]interface{}) (dragArgs, error) {
+	var da dragArgs
+	var ok bool
+	if da.source, ok = args["source"].(string); !ok || da.source == "" {
+		return da, fmt.Errorf("source must be a non-empty string")
+	}
+	target, hasTarget := args["target"]
+	x, hasX := args["offset_x"]
+	y, hasY := args["offset_y"]
+	switch {
+	case hasTarget && (hasX || hasY):
+		return da, fmt.Errorf("use either target or offset_x and offset_y, not both")
+	case hasTarget:
+		if da.target, ok = target.(string); !ok || da.target == "" {
+			return da, fmt.Errorf("target must be a non-empty string")
+		}
+	case hasX || hasY:
+		if hasX {
+			if da.offsetX, ok = x.(float64); !ok {
+				return da, fmt.Errorf("offset_x must be a number")
+			}
+		}
+		if hasY {
+			if da.offsetY, ok = y.(float64); !ok {
+				return da, fmt.Errorf("offset_y must be a number")
+			}
+		}
+	default:
+		return da, fmt.Errorf("target or offset_x and offset_y is required")
+	}
+	da.steps = defaultDragSteps
+	if raw, has := args["steps"]; has {
+		steps, ok := raw.(float64)
+		if !ok || steps < 1 || steps > maxDragSteps || steps != float64(int(steps)) {
+			return da, fmt.Errorf("steps must be an integer between 1 and %d", maxDragSteps)
+		}
+		da.steps = int(steps)
+	}
+	return da, nil
+}
+
+// handleDragDrop drags an element onto another element or by an offset. Real mouse input is
+// tried first; when it fails, or the source uses HTML5 drag and drop and no drop event followed,
+// the drag is simulated in the page.
+func (bs *BrowserServer) handleDragDrop(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	da, err := parseDragArgs(request.GetArguments())
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ArgumentError(err)), nil
+	}
+	runCtx, cancel := bs.mouseContext(ctx)
+	defer cancel()
+
+	result := MouseActionResult{Action: "drag_drop", Source: da.source, Target: da.target, Method: MouseMethodCDP}
+	waits := []chromedp.Action{chromedp.WaitVisible(da.source)}
+	if da.target != "" {
+		waits = append(waits, chromedp.WaitVisible(da.target))
+	}
+	err = bs.runner.Run(runCtx, waits...)
+	var from, to point
+	if err == nil {
+		from, err = bs.elementCenter(runCtx, da.source)
+	}
+	if err == nil {
+		if da.target != "" {
+			to, err = bs.elementCenter(runCtx, da.target)
+		} else {
+			to = point{X: from.X + da.offsetX, Y: from.Y + da.offsetY}
+		}
+	}
+	var draggable bool
+	if err == nil {
+		err = bs.runner.Evaluate(runCtx, dragProbeJS(da.source), &draggable)
+	}
+	if err == nil {
+		err = bs.runner.Run(runCtx, mouseActions(dragEvents(from, to, da.steps))...)
+	}
+	if err == nil && draggable {
+		// 无头模式下鼠标输入通常不会触发 HTML5 拖放，没有 drop 事件时模拟
+		var probe struct {
+			Drop bool `json:"drop"`
+		}
+		err = bs.runner.Evaluate(runCtx, dragResultJS, &probe)
+		if err == nil && !probe.Drop {
+			err = fmt.Errorf("no drop event reached the page")
+		}
+	}
+	if err == nil {
+		result.From, result.To = &from, &to
+		return mouseResult(result), nil
+	}
+
+	bs.Logger.Debug().Str("source", da.source).Err(err).Msg("鼠标输入拖放失败，尝试在页面中模拟拖放")
+	res, err := bs.runFallback(runCtx, dragFallbackJS(da.source, da.target, da.offsetX, da.offsetY))
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to drag and drop the element", err)), nil
+	}
+	result.Method = res.Method
+	result.From, result.To = res.From, res.To
+	return mouseResult(result), nil
+}
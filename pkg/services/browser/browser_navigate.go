@@ -0,0 +1,197 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
+)
+
+// browser_navigate 的 wait_until 取值
+const (
+	WaitUntilLoad             = "load"             // load 事件，chromedp.Navigate 的默认行为
+	WaitUntilDOMContentLoaded = "domcontentloaded" // 文档解析完成，不等待图片等资源
+	WaitUntilNetworkIdle      = "networkidle"      // load 之后 networkIdleQuiet 内没有新的资源请求
+)
+
+const (
+	navigatePollInterval = 100 * time.Millisecond // 轮询页面状态的间隔
+	networkIdleQuiet     = 500 * time.Millisecond // 资源数保持不变多久算网络空闲
+)
+
+// navigateStateJS returns the ready state and the number of loaded resources of the page.
+const navigateStateJS = `({ready: document.readyState, resources: performance.getEntriesByType("resource").length})`
+
+// navigateInfoJS returns the final URL and the HTTP status of the document, 0 when unknown.
+const navigateInfoJS = `(function() {
+	var entries = performance.getEntriesByType("navigation");
+	var status = entries.length && entries[0].responseStatus ? entries[0].responseStatus : 0;
+	return {url: location.href, status: status};
+})()`
+
+// navigateOptions are the arguments of browser_navigate.
+type navigateOptions struct {
+	URL       string
+	WaitFor   string
+	WaitUntil string
+	Timeout   time.Duration
+}
+
+// navigateState is the result of navigateStateJS.
+type navigateState struct {
+	Ready     string `json:"ready"`
+	Resources int    `json:"resources"`
+}
+
+// navigateInfo is the result of navigateInfoJS.
+type navigateInfo struct {
+	URL    string `json:"url"`
+	Status int    `json:"status"`
+}
+
+// parseNavigateOptions validates the wait arguments of browser_navigate, the timeout defaults to
+// urlTimeout seconds.
+func parseNavigateOptions(args map[string]interface{}, url string, urlTimeout int) (navigateOptions, error) {
+	opts := navigateOptions{URL: url, WaitUntil: WaitUntilLoad, Timeout: time.Duration(urlTimeout) * time.Second}
+	if v, ok := args["wait_for"]; ok {
+		waitFor, ok := v.(string)
+		if !ok {
+			return opts, errors.New("wait_for must be a CSS selector string")
+		}
+		opts.WaitFor = strings.TrimSpace(waitFor)
+	}
+	if v, ok := args["wait_until"]; ok {
+		waitUntil, _ := v.(string)
+		switch waitUntil {
+		case WaitUntilLoad, WaitUntilDOMContentLoaded, WaitUntilNetworkIdle:
+			opts.WaitUntil = waitUntil
+		default:
+			return opts, fmt.Errorf("wait_until must be one of %s, %s or %s", WaitUntilLoad, WaitUntilDOMContentLoaded, WaitUntilNetworkIdle)
+		}
+	}
+	if v, ok := args["timeout_seconds"]; ok {
+		seconds, ok := v.(float64)
+		if !ok || seconds <= 0 {
+			return opts, errors.New("timeout_seconds must be a positive number")
+		}
+		opts.Timeout = time.Duration(seconds * float64(time.Second))
+	}
+	return opts, nil
+}
+
+// navigateAction navigates to url. With domcontentloaded it doesn't wait for the load event,
+// waitUntil polls the ready state instead.
+func navigateAction(opts navigateOptions) chromedp.Action {
+	if opts.WaitUntil != WaitUntilDOMContentLoaded {
+		return chromedp.Navigate(opts.URL)
+	}
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		_, _, errorText, err := page.Navigate(opts.URL).Do(ctx)
+		if err != nil {
+			return err
+		}
+		if errorText != "" {
+			return errors.New(errorText)
+		}
+		return nil
+	})
+}
+
+// waitUntil waits for the page state of wait_until after the navigation. Network idle means the
+// document is complete and no resource finished loading for networkIdleQuiet.
+func (bs *BrowserServer) waitUntil(ctx context.Context, waitUntil string) error {
+	if waitUntil == WaitUntilLoad {
+		return nil
+	}
+	lastResources := -1
+	var since time.Time
+	for {
+		var state navigateState
+		if err := bs.runner.Evaluate(ctx, navigateStateJS, &state); err != nil {
+			return err
+		}
+		switch waitUntil {
+		case WaitUntilDOMContentLoaded:
+			if state.Ready != "loading" {
+				return nil
+			}
+		case WaitUntilNetworkIdle:
+			if state.Ready != "complete" || state.Resources != lastResources {
+				lastResources = state.Resources
+				since = time.Now()
+			} else if time.Since(since) >= networkIdleQuiet {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(navigatePollInterval):
+		}
+	}
+}
+
+// navigate runs the navigation and the wait conditions, and returns the final URL and status.
+func (bs *BrowserServer) navigate(ctx context.Context, opts navigateOptions) (navigateInfo, error) {
+	info := navigateInfo{URL: opts.URL}
+	runCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	// 超时的错误说明在等什么
+	timedOut := func(err error, what string) error {
+		if errors.Is(err, context.DeadlineExceeded) || runCtx.Err() != nil {
+			return comm.NewCodedError(comm.ErrCodeTimeout, fmt.Errorf("timed out after %s waiting for %s", opts.Timeout, what))
+		}
+		return fmt.Errorf("failed to navigate: %w", err)
+	}
+	if err := bs.runner.Run(runCtx, navigateAction(opts)); err != nil {
+		return info, timedOut(err, "the page to load")
+	}
+	if err := bs.waitUntil(runCtx, opts.WaitUntil); err != nil {
+		return info, timedOut(err, opts.WaitUntil)
+	}
+	if opts.WaitFor != "" {
+		if err := bs.runner.Run(runCtx, chromedp.WaitVisible(opts.WaitFor, chromedp.ByQuery)); err != nil {
+			return info, timedOut(err, "selector "+opts.WaitFor)
+		}
+	}
+	// 最终地址和状态码只是补充，获取失败不影响结果
+	var final navigateInfo
+	if err := bs.runner.Evaluate(runCtx, navigateInfoJS, &final); err != nil {
+		bs.Logger.Debug().Err(err).Msg("failed to get the final URL and status")
+	}
+	if final.URL != "" {
+		info.URL = final.URL
+	}
+	info.Status = final.Status
+	return info, nil
+}
+
+// navigateMessage is the result text of a successful navigation.
+func navigateMessage(url string, info navigateInfo) string {
+	status := "unknown"
+	if info.Status > 0 {
+		status = fmt.Sprintf("%d", info.Status)
+	}
+	return fmt.Sprintf("Navigated to %s\nFinal URL: %s, HTTP status: %s", url, info.URL, status)
+}
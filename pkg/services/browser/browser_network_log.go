@@ -0,0 +1,301 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// networkLogMaxPending is the number of requests in flight tracked at most. Requests that never
+// finish, e.g. of closed tabs, are dropped when it is exceeded.
+const networkLogMaxPending = 1024
+
+// NetworkEntry is a request of the network log.
+type NetworkEntry struct {
+	Method     string  `json:"method"`
+	URL        string  `json:"url"`
+	Type       string  `json:"type,omitempty"`
+	Status     int64   `json:"status"` // 未收到响应的失败请求为 0
+	StatusText string  `json:"status_text,omitempty"`
+	MimeType   string  `json:"mime_type,omitempty"`
+	Size       float64 `json:"size"`        // 传输的字节数
+	Duration   float64 `json:"duration_ms"` // 从发送请求到完成或失败
+	Started    string  `json:"started"`
+	Error      string  `json:"error,omitempty"`
+	start      float64 // requestWillBeSent 的单调时间，秒
+}
+
+// networkLog buffers the last requests of the tabs it listens to in a ring buffer. The event
+// callbacks of several tabs call it concurrently.
+type networkLog struct {
+	mu          sync.Mutex
+	entries     []NetworkEntry // 环形缓冲区
+	next        int            // 下一个写入位置
+	full        bool
+	overwritten int // 被新请求覆盖的条目数
+	pending     map[network.RequestID]*NetworkEntry
+	listening   map[context.Context]context.CancelFunc // 已启用的标签页
+}
+
+// reset empties the buffer and sets its capacity.
+func (nl *networkLog) reset(size int) {
+	nl.entries = make([]NetworkEntry, size)
+	nl.next, nl.full, nl.overwritten = 0, false, 0
+	nl.pending = make(map[network.RequestID]*NetworkEntry)
+}
+
+// listen registers a tab, it returns false when the tab is already logged. The buffer is created
+// with size entries on the first call.
+func (nl *networkLog) listen(tab context.Context, size int, cancel context.CancelFunc) bool {
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+	if _, ok := nl.listening[tab]; ok {
+		return false
+	}
+	if nl.listening == nil {
+		nl.listening = make(map[context.Context]context.CancelFunc)
+	}
+	if nl.entries == nil {
+		nl.reset(size)
+	}
+	nl.listening[tab] = cancel
+	return true
+}
+
+// unlisten stops logging a tab.
+func (nl *networkLog) unlisten(tab context.Context) {
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+	if cancel, ok := nl.listening[tab]; ok {
+		cancel()
+		delete(nl.listening, tab)
+	}
+}
+
+// stopAll stops logging all tabs.
+func (nl *networkLog) stopAll() {
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+	for _, cancel := range nl.listening {
+		cancel()
+	}
+	nl.listening = nil
+}
+
+// handleEvent records a network event. It is called by the event listeners of the tabs and must
+// not block.
+func (nl *networkLog) handleEvent(ev interface{}) {
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+	if nl.entries == nil {
+		return
+	}
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		if ev.Request == nil {
+			return
+		}
+		start := monotonicSeconds(ev.Timestamp)
+		// 重定向复用请求 ID，上一跳以重定向响应结束
+		if e, ok := nl.pending[ev.RequestID]; ok && ev.RedirectResponse != nil {
+			e.setResponse(ev.RedirectResponse)
+			e.Size = ev.RedirectResponse.EncodedDataLength
+			nl.finish(ev.RequestID, start)
+		}
+		if len(nl.pending) >= networkLogMaxPending {
+			nl.pending = make(map[network.RequestID]*NetworkEntry)
+		}
+		started := time.Now()
+		if ev.WallTime != nil {
+			started = ev.WallTime.Time()
+		}
+		nl.pending[ev.RequestID] = &NetworkEntry{
+			Method:  ev.Request.Method,
+			URL:     ev.Request.URL,
+			Type:    string(ev.Type),
+			Started: started.Format(harTimeFormat),
+			start:   start,
+		}
+	case *network.EventResponseReceived:
+		if e, ok := nl.pending[ev.RequestID]; ok && ev.Response != nil {
+			e.setResponse(ev.Response)
+		}
+	case *network.EventLoadingFinished:
+		if e, ok := nl.pending[ev.RequestID]; ok {
+			e.Size = ev.EncodedDataLength
+			nl.finish(ev.RequestID, monotonicSeconds(ev.Timestamp))
+		}
+	case *network.EventLoadingFailed:
+		if e, ok := nl.pending[ev.RequestID]; ok {
+			e.Error = ev.ErrorText
+			if ev.BlockedReason != "" {
+				e.Error = fmt.Sprintf("%s (blocked: %s)", ev.ErrorText, ev.BlockedReason)
+			}
+			nl.finish(ev.RequestID, monotonicSeconds(ev.Timestamp))
+		}
+	}
+}
+
+// setResponse copies the response metadata.
+func (e *NetworkEntry) setResponse(r *network.Response) {
+	e.Status = r.Status
+	e.StatusText = r.StatusText
+	e.MimeType = r.MimeType
+}
+
+// finish moves a pending request into the ring buffer, overwriting the oldest entry when full.
+func (nl *networkLog) finish(id network.RequestID, end float64) {
+	e := nl.pending[id]
+	delete(nl.pending, id)
+	if end > 0 && e.start > 0 {
+		e.Duration = millis(end - e.start)
+	}
+	if nl.full {
+		nl.overwritten++
+	}
+	nl.entries[nl.next] = *e
+	nl.next = (nl.next + 1) % len(nl.entries)
+	if nl.next == 0 {
+		nl.full = true
+	}
+}
+
+// enabled reports whether a tab is logged.
+func (nl *networkLog) enabled() bool {
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+	return len(nl.listening) > 0
+}
+
+// filter returns the buffered entries from the oldest to the newest whose URL matches pattern,
+// when set, and whose status is at least statusMin, and the number of overwritten entries.
+func (nl *networkLog) filter(pattern *regexp.Regexp, statusMin int64) ([]NetworkEntry, int) {
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+	entries := make([]NetworkEntry, 0)
+	n := nl.next
+	first := 0
+	if nl.full {
+		n, first = len(nl.entries), nl.next
+	}
+	for i := 0; i < n; i++ {
+		e := nl.entries[(first+i)%len(nl.entries)]
+		if (pattern == nil || pattern.MatchString(e.URL)) && e.Status >= statusMin {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nl.overwritten
+}
+
+// clear empties the buffer and returns the number of removed entries.
+func (nl *networkLog) clear() int {
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+	if nl.entries == nil {
+		return 0
+	}
+	removed := nl.next
+	if nl.full {
+		removed = len(nl.entries)
+	}
+	nl.reset(len(nl.entries))
+	return removed
+}
+
+// addNetworkLogTools registers browser_network_enable, browser_network_log and
+// browser_network_clear.
+func (bs *BrowserServer) addNetworkLogTools() {
+	bs.addTool(mcp.NewTool(
+		"browser_network_enable",
+		mcp.WithDescription("Start logging the requests of the current tab, including XHR and fetch: method, URL, status, MIME type, size and duration. The last network_log_size requests of the config are kept, read them with browser_network_log"),
+	), bs.handleNetworkEnable)
+	bs.addTool(mcp.NewTool(
+		"browser_network_log",
+		mcp.WithDescription("Return the logged requests as JSON, oldest first. Start logging with browser_network_enable"),
+		mcp.WithString("url_pattern",
+			mcp.Description("Regular expression the URL must match, e.g. /api/"),
+		),
+		mcp.WithNumber("status_min",
+			mcp.Description("Minimum HTTP status, e.g. 400 for failed responses. Requests that failed without a response have status 0"),
+		),
+	), bs.handleNetworkLog)
+	bs.addTool(mcp.NewTool(
+		"browser_network_clear",
+		mcp.WithDescription("Remove the logged requests, logging continues"),
+	), bs.handleNetworkClear)
+}
+
+func (bs *BrowserServer) handleNetworkEnable(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tab := bs.pageContext(ctx)
+	listenCtx, cancel := context.WithCancel(tab)
+	if !bs.netlog.listen(tab, bs.cfg().NetworkLogSize, cancel) {
+		cancel()
+		return mcp.NewToolResultText("Network logging is already enabled in this tab"), nil
+	}
+	bs.listenTarget(listenCtx, bs.netlog.handleEvent)
+	if err := bs.emulate(ctx, network.Enable()); err != nil {
+		bs.netlog.unlisten(tab)
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to enable network events", err)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Network logging enabled, the last %d requests are kept", bs.cfg().NetworkLogSize)), nil
+}
+
+func (bs *BrowserServer) handleNetworkLog(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	var pattern *regexp.Regexp
+	if raw, _ := args["url_pattern"].(string); strings.TrimSpace(raw) != "" {
+		var err error
+		if pattern, err = regexp.Compile(raw); err != nil {
+			return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInvalidArgument, "url_pattern is not a valid regular expression", err.Error())), nil
+		}
+	}
+	var statusMin int64
+	if v, ok := args["status_min"]; ok {
+		f, ok := v.(float64)
+		if !ok || f < 0 {
+			return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInvalidArgument, "status_min must be a non-negative number", "")), nil
+		}
+		statusMin = int64(f)
+	}
+
+	if !bs.netlog.enabled() {
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInvalidArgument, "network logging is not enabled, call browser_network_enable first", "")), nil
+	}
+	entries, overwritten := bs.netlog.filter(pattern, statusMin)
+	data, err := json.Marshal(struct {
+		Entries     []NetworkEntry `json:"entries"`
+		Overwritten int            `json:"overwritten"` // 缓冲区满后丢弃的旧条目
+	}{entries, overwritten})
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to marshal the network log", err)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+func (bs *BrowserServer) handleNetworkClear(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return mcp.NewToolResultText(fmt.Sprintf("Cleared %d network log entries", bs.netlog.clear())), nil
+}
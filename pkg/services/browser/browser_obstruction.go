@@ -0,0 +1,375 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	ObstructionConsent = "consent" // 同意 Cookie 的弹窗
+	ObstructionCaptcha = "captcha" // 人机验证
+	ObstructionOverlay = "overlay" // 覆盖整个视口的浮层
+
+	obstructionAttr    = "data-moling-obstruction" // 标记没有 id 的遮挡元素和按钮，生成可用于点击的选择器
+	obstructionTimeout = 3 * time.Second           // 导航后检测的超时时间
+	obstructionSettle  = 2 * time.Second           // 点击接受按钮后等待弹窗消失的时间
+)
+
+// Obstruction is the verdict of the obstruction check after browser_navigate and of
+// browser_detect_obstruction.
+type Obstruction struct {
+	Obstructed      bool   `json:"obstructed"`
+	Type            string `json:"type,omitempty"`             // Type is consent, captcha or overlay.
+	Vendor          string `json:"vendor,omitempty"`           // Vendor is the consent manager or captcha provider, e.g. OneTrust or reCAPTCHA.
+	Selector        string `json:"selector,omitempty"`         // Selector matches the obstructing element.
+	DismissSelector string `json:"dismiss_selector,omitempty"` // DismissSelector matches the candidate "Accept all" button.
+	DismissText     string `json:"dismiss_text,omitempty"`     // DismissText is the text of the candidate button.
+	Dismissed       *bool  `json:"dismissed,omitempty"`        // Dismissed is the outcome of the dismissal click, nil when it was not attempted.
+	Hint            string `json:"hint,omitempty"`
+}
+
+// obstructionHints tells the model what to do about each type of obstruction.
+var obstructionHints = map[string]string{
+	ObstructionConsent: "A cookie consent dialog covers the page. Click dismiss_selector with browser_click to accept it, or ask the user.",
+	ObstructionCaptcha: "A CAPTCHA blocks the page and cannot be solved automatically. Ask the user to solve it in the browser (headless mode off) or use another source.",
+	ObstructionOverlay: "A full-page overlay covers the page, elements behind it cannot be clicked. Close it first, dismiss_selector is a candidate button when set.",
+}
+
+// obstructionDetectJS looks for captchas, consent managers and full-viewport overlays, in this
+// order, and returns the first finding as a JSON string. It is plain ES5 and only marks elements
+// without an id with obstructionAttr so that a selector can be returned.
+const obstructionDetectJS = `(function() {
+	var attr = "` + obstructionAttr + `";
+	var consentVendors = [
+		{vendor: "OneTrust", containers: ["#onetrust-banner-sdk", "#onetrust-consent-sdk"], accept: ["#onetrust-accept-btn-handler"]},
+		{vendor: "Didomi", containers: ["#didomi-popup", "#didomi-notice", "#didomi-host"], accept: ["#didomi-notice-agree-button"]},
+		{vendor: "Cookiebot", containers: ["#CybotCookiebotDialog"], accept: ["#CybotCookiebotDialogBodyLevelButtonLevelOptinAllowAll", "#CybotCookiebotDialogBodyButtonAccept"]},
+		{vendor: "Quantcast", containers: [".qc-cmp2-container"], accept: []},
+		{vendor: "TrustArc", containers: ["#truste-consent-track"], accept: ["#truste-consent-button"]},
+		{vendor: "Usercentrics", containers: ["#usercentrics-root"], accept: []}
+	];
+	var captchaFrames = [
+		{vendor: "reCAPTCHA", pattern: /(google\.com|recaptcha\.net)\/recaptcha\//},
+		{vendor: "hCaptcha", pattern: /hcaptcha\.com\//},
+		{vendor: "Cloudflare", pattern: /challenges\.cloudflare\.com\//}
+	];
+	var captchaMarkers = [
+		{vendor: "reCAPTCHA", selector: ".g-recaptcha"},
+		{vendor: "hCaptcha", selector: ".h-captcha"},
+		{vendor: "Cloudflare", selector: ".cf-turnstile"},
+		{vendor: "Cloudflare", selector: "#challenge-form"},
+		{vendor: "Cloudflare", selector: "#cf-challenge-running"}
+	];
+	// 按优先级排列，"全部接受"类优先于单纯的"接受"
+	var acceptPhrases = [
+		"accept all", "allow all", "accept all cookies", "allow all cookies", "agree to all",
+		"alle akzeptieren", "alle zulassen", "alle cookies akzeptieren", "tout accepter", "accepter tout",
+		"aceptar todo", "aceptar todas", "accetta tutto", "accetta tutti", "aceitar tudo", "aceitar todos",
+		"alles accepteren", "alle accepteren", "zaakceptuj wszystkie", "принять все",
+		"全部接受", "接受全部", "全部同意", "同意全部", "すべて同意する", "すべて同意", "すべて受け入れる", "모두 동의", "모두 허용",
+		"accept", "accept cookies", "i accept", "agree", "i agree", "allow", "allow cookies", "got it", "ok",
+		"akzeptieren", "zustimmen", "einverstanden", "accepter", "j'accepte", "aceptar", "acepto",
+		"accetta", "accetto", "aceitar", "aceito", "accepteren", "akkoord", "akceptuję", "zgadzam się",
+		"принять", "согласен", "接受", "同意", "我同意", "同意する", "동의"
+	];
+	// 含这些词的按钮只接受部分 Cookie 或打开设置
+	var rejectWords = [
+		"only", "necessary", "essential", "reject", "decline", "refuse", "settings", "customi", "manage", "preferences",
+		"nur ", "notwendig", "ablehnen", "einstellungen", "refuser", "uniquement", "paramètres", "rechazar", "solo ",
+		"rifiuta", "recusar", "weigeren", "odrzuć", "отклонить", "仅", "拒绝", "设置", "必要", "拒否", "設定", "거부"
+	];
+
+	function styleOf(el) {
+		return window.getComputedStyle ? window.getComputedStyle(el) : (el.style || {});
+	}
+	function isVisible(el) {
+		var r = el.getBoundingClientRect();
+		var s = styleOf(el);
+		return r.width > 0 && r.height > 0 && s.display !== "none" && s.visibility !== "hidden" && parseFloat(s.opacity || "1") > 0;
+	}
+	function first(selectors, root) {
+		for (var i = 0; i < selectors.length; i++) {
+			var el = (root || document).querySelector(selectors[i]);
+			if (el && isVisible(el)) {
+				return {el: el, selector: selectors[i]};
+			}
+		}
+		return null;
+	}
+	function selectorOf(el, role) {
+		if (el.id && /^[A-Za-z][\w-]*$/.test(el.id)) {
+			return "#" + el.id;
+		}
+		el.setAttribute(attr, role);
+		return "[" + attr + "=\"" + role + "\"]";
+	}
+	function normalize(text) {
+		text = (text || "").toLowerCase().replace(/\s+/g, " ");
+		return text.replace(/^[\s"'“”«»›>!.。]+|[\s"'“”«»›>!.。]+$/g, "");
+	}
+	function buttonText(el) {
+		return normalize(el.innerText || el.textContent || el.value || el.getAttribute("aria-label"));
+	}
+	function isButton(el) {
+		var tag = el.tagName.toLowerCase();
+		var type = (el.getAttribute("type") || "").toLowerCase();
+		return tag === "button" || tag === "a" || (tag === "input" && (type === "button" || type === "submit")) ||
+			el.getAttribute("role") === "button";
+	}
+	function phraseRank(text) {
+		if (!text || text.length > 40) {
+			return -1;
+		}
+		for (var i = 0; i < rejectWords.length; i++) {
+			if (text.indexOf(rejectWords[i]) >= 0) {
+				return -1;
+			}
+		}
+		for (var j = 0; j < acceptPhrases.length; j++) {
+			var p = acceptPhrases[j];
+			if (text === p || text.indexOf(p + " ") === 0 || text.indexOf(p + ",") === 0) {
+				return j;
+			}
+		}
+		return -1;
+	}
+	// findAccept returns the visible button under root with the best ranked accept phrase.
+	function findAccept(root) {
+		var best = null, bestRank = -1;
+		var tags = ["button", "a", "input", "div", "span"];
+		for (var t = 0; t < tags.length; t++) {
+			var nodes = root.querySelectorAll(tags[t]);
+			for (var i = 0; i < nodes.length; i++) {
+				var el = nodes[i];
+				if (!isButton(el) || !isVisible(el)) {
+					continue;
+				}
+				var text = buttonText(el);
+				var rank = phraseRank(text);
+				if (rank >= 0 && (bestRank < 0 || rank < bestRank)) {
+					best = {el: el, text: text};
+					bestRank = rank;
+				}
+			}
+		}
+		return best;
+	}
+	function withDismiss(result, accept) {
+		if (accept) {
+			result.dismiss_selector = selectorOf(accept.el, "dismiss");
+			result.dismiss_text = accept.text;
+		}
+		return result;
+	}
+	function isFixed(el) {
+		var p = styleOf(el).position;
+		return p === "fixed" || p === "sticky";
+	}
+
+	// 清除上次检测的标记
+	var marked = document.querySelectorAll("[" + attr + "]");
+	for (var m = 0; m < marked.length; m++) {
+		marked[m].removeAttribute(attr);
+	}
+
+	// 人机验证
+	var frames = document.querySelectorAll("iframe");
+	for (var f = 0; f < frames.length; f++) {
+		var src = frames[f].getAttribute("src") || "";
+		if (src.indexOf("size=invisible") >= 0 || !isVisible(frames[f])) {
+			continue;
+		}
+		for (var c = 0; c < captchaFrames.length; c++) {
+			if (captchaFrames[c].pattern.test(src)) {
+				return JSON.stringify({obstructed: true, type: "captcha", vendor: captchaFrames[c].vendor, selector: selectorOf(frames[f], "captcha")});
+			}
+		}
+	}
+	for (var k = 0; k < captchaMarkers.length; k++) {
+		var marker = first([captchaMarkers[k].selector]);
+		if (marker) {
+			return JSON.stringify({obstructed: true, type: "captcha", vendor: captchaMarkers[k].vendor, selector: marker.selector});
+		}
+	}
+	if (/^just a moment/i.test(document.title || "")) {
+		return JSON.stringify({obstructed: true, type: "captcha", vendor: "Cloudflare", selector: "body"});
+	}
+
+	// 已知的同意管理平台
+	for (var v = 0; v < consentVendors.length; v++) {
+		var cv = consentVendors[v];
+		var container = first(cv.containers);
+		if (!container) {
+			continue;
+		}
+		var known = first(cv.accept);
+		var accept = known ? {el: known.el, text: buttonText(known.el)} : findAccept(container.el);
+		var result = withDismiss({obstructed: true, type: "consent", vendor: cv.vendor, selector: container.selector}, accept);
+		if (known) {
+			result.dismiss_selector = known.selector;
+		}
+		return JSON.stringify(result);
+	}
+
+	// 通用的 Cookie 横幅：固定定位且 id 或 class 提及 cookie、consent 或 gdpr
+	var blocks = ["div", "section", "aside", "dialog", "form"];
+	var vw = window.innerWidth || document.documentElement.clientWidth || 0;
+	var vh = window.innerHeight || document.documentElement.clientHeight || 0;
+	var overlay = null;
+	for (var b = 0; b < blocks.length; b++) {
+		var nodes = document.querySelectorAll(blocks[b]);
+		for (var n = 0; n < nodes.length; n++) {
+			var el = nodes[n];
+			if (!isFixed(el) || !isVisible(el)) {
+				continue;
+			}
+			var name = ((el.id || "") + " " + (el.getAttribute("class") || "")).toLowerCase();
+			if (/cookie|consent|gdpr/.test(name)) {
+				var found = findAccept(el);
+				if (found) {
+					return JSON.stringify(withDismiss({obstructed: true, type: "consent", selector: selectorOf(el, "consent")}, found));
+				}
+			}
+			// 覆盖视口九成以上、层级较高的固定浮层
+			var r = el.getBoundingClientRect();
+			var z = parseInt(styleOf(el).zIndex, 10) || 0;
+			if (!overlay && styleOf(el).position === "fixed" && z >= 100 && vw > 0 && vh > 0 &&
+				r.width >= vw * 0.9 && r.height >= vh * 0.9) {
+				overlay = el;
+			}
+		}
+	}
+	if (overlay) {
+		var candidate = findAccept(overlay) || findAccept(document);
+		return JSON.stringify(withDismiss({obstructed: true, type: "overlay", selector: selectorOf(overlay, "overlay")}, candidate));
+	}
+	return JSON.stringify({obstructed: false});
+})()`
+
+// obstructionDismissJS clicks the element of the selector %s and reports whether it was found.
+const obstructionDismissJS = `(function(selector) {
+	var el = document.querySelector(selector);
+	if (!el) {
+		return false;
+	}
+	el.click();
+	return true;
+})(%s)`
+
+// scriptPage evaluates scripts on a page: the tab of the call, or a fake in tests.
+type scriptPage interface {
+	Evaluate(script string, res interface{}) error
+}
+
+// detectObstruction runs obstructionDetectJS and adds the hint of the type.
+func detectObstruction(page scriptPage) (*Obstruction, error) {
+	var raw string
+	if err := page.Evaluate(obstructionDetectJS, &raw); err != nil {
+		return nil, err
+	}
+	var ob Obstruction
+	if err := json.Unmarshal([]byte(raw), &ob); err != nil {
+		return nil, fmt.Errorf("unexpected obstruction result %q: %w", raw, err)
+	}
+	ob.Hint = obstructionHints[ob.Type]
+	return &ob, nil
+}
+
+// checkObstruction detects an obstruction and, with dismiss, clicks its candidate button and
+// waits up to settle for the page to be clear. Captchas have no candidate and are never clicked.
+func checkObstruction(page scriptPage, dismiss bool, settle time.Duration) (*Obstruction, error) {
+	ob, err := detectObstruction(page)
+	if err != nil || !dismiss || ob.DismissSelector == "" {
+		return ob, err
+	}
+	selector, err := json.Marshal(ob.DismissSelector)
+	if err != nil {
+		return ob, err
+	}
+	dismissed := false
+	ob.Dismissed = &dismissed
+	var clicked bool
+	if err := page.Evaluate(fmt.Sprintf(obstructionDismissJS, selector), &clicked); err != nil || !clicked {
+		return ob, nil
+	}
+	// 弹窗关闭通常带有动画，轮询直到页面不再被遮挡
+	deadline := time.Now().Add(settle)
+	for {
+		after, err := detectObstruction(page)
+		if err == nil && !after.Obstructed {
+			dismissed = true
+			return ob, nil
+		}
+		if time.Now().After(deadline) {
+			return ob, nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// addObstructionTool registers browser_detect_obstruction.
+func (bs *BrowserServer) addObstructionTool() {
+	bs.addTool(mcp.NewTool(
+		"browser_detect_obstruction",
+		mcp.WithDescription("Check whether the page is covered by a cookie consent dialog, a CAPTCHA or a full-page overlay. Returns JSON with obstructed, type (consent, captcha or overlay), vendor, selector, and dismiss_selector/dismiss_text of a candidate \"Accept all\" button. Use it when elements are unexpectedly not visible or clickable"),
+		mcp.WithBoolean("dismiss",
+			mcp.Description("Click the candidate button and report in dismissed whether the page is clear afterwards (default: false)"),
+		),
+	), bs.handleDetectObstruction)
+}
+
+func (bs *BrowserServer) handleDetectObstruction(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	dismiss, _ := request.GetArguments()["dismiss"].(bool)
+	page := tabPage{ctx: bs.pageContext(ctx), timeout: time.Duration(bs.cfg().SelectorQueryTimeout) * time.Second}
+	ob, err := checkObstruction(page, dismiss, obstructionSettle)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to detect obstruction", err)), nil
+	}
+	data, err := json.Marshal(ob)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to marshal obstruction", err), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// navigationObstruction checks the page after browser_navigate and dismisses consent dialogs when
+// AutoDismissConsent is enabled. It returns an empty string when the page is clear, a failed check
+// does not fail the navigation.
+func (bs *BrowserServer) navigationObstruction(ctx context.Context) string {
+	page := tabPage{ctx: bs.pageContext(ctx), timeout: obstructionTimeout}
+	ob, err := checkObstruction(page, bs.cfg().AutoDismissConsent, obstructionSettle)
+	if err != nil {
+		bs.Logger.Debug().Err(err).Msg("failed to detect obstruction after navigation")
+		return ""
+	}
+	if !ob.Obstructed {
+		return ""
+	}
+	bs.Logger.Info().Str("type", ob.Type).Str("vendor", ob.Vendor).Msg("page obstructed after navigation")
+	data, err := json.Marshal(ob)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
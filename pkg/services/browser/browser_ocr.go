@@ -0,0 +1,57 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gojue/moling/pkg/ocr"
+)
+
+// ocrEngine returns the OCR engine, created from the config on first use.
+func (bs *BrowserServer) ocrEngine() (ocr.Engine, error) {
+	if bs.ocr != nil {
+		return bs.ocr, nil
+	}
+	engine, err := ocr.New(bs.cfg().OCR)
+	if err != nil {
+		return nil, err
+	}
+	bs.ocr = engine
+	return engine, nil
+}
+
+// recognizeScreenshot recognizes the text of a screenshot, as JSON when boxes are requested.
+func (bs *BrowserServer) recognizeScreenshot(ctx context.Context, image []byte, withBoxes bool) (string, error) {
+	engine, err := bs.ocrEngine()
+	if err != nil {
+		return "", err
+	}
+	result, err := engine.Recognize(ctx, image, ocr.Options{WithBoxes: withBoxes})
+	if err != nil {
+		return "", err
+	}
+	if !withBoxes {
+		return result.Text, nil
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// pageInfoJS collects the page info in a single evaluation without touching the page. It is
+// plain ES5 and tolerates missing elements, so it also works on about:blank.
+const pageInfoJS = `(function() {
+	var meta = function(selector) {
+		var el = document.querySelector(selector);
+		return el ? el.getAttribute("content") : null;
+	};
+	var canonical = document.querySelector('link[rel="canonical"]');
+	var root = document.documentElement;
+	return {
+		url: location.href,
+		title: document.title || null,
+		readyState: document.readyState || null,
+		contentType: document.contentType || null,
+		canonical: canonical ? canonical.href : null,
+		description: meta('meta[name="description"]'),
+		ogTitle: meta('meta[property="og:title"]'),
+		language: (root && root.getAttribute("lang")) || null,
+		frames: window.frames ? window.frames.length : 0
+	};
+})()`
+
+// jsPageInfo is the object returned by pageInfoJS.
+type jsPageInfo struct {
+	URL         string  `json:"url"`
+	Title       *string `json:"title"`
+	ReadyState  *string `json:"readyState"`
+	ContentType *string `json:"contentType"`
+	Canonical   *string `json:"canonical"`
+	Description *string `json:"description"`
+	OGTitle     *string `json:"ogTitle"`
+	Language    *string `json:"language"`
+	Frames      int     `json:"frames"`
+}
+
+// PageInfo is the result of the browser_page_info tool. Missing values are null.
+type PageInfo struct {
+	URL             string  `json:"url"`
+	Title           *string `json:"title"`
+	ReadyState      *string `json:"ready_state"` // loading, interactive or complete
+	ContentType     *string `json:"content_type"`
+	CanonicalURL    *string `json:"canonical_url"`
+	MetaDescription *string `json:"meta_description"`
+	OGTitle         *string `json:"og_title"`
+	Language        *string `json:"language"`
+	FrameCount      int     `json:"frame_count"`
+	TargetID        string  `json:"target_id,omitempty"`
+	TargetType      string  `json:"target_type,omitempty"`
+
+	// 浏览器状态
+	Headless  bool            `json:"headless"`            // 浏览器当前是否为无头模式
+	Emulation *EmulationState `json:"emulation,omitempty"` // 地理位置、时区和语言覆盖
+}
+
+// newPageInfo maps the evaluated page info and the target info to a PageInfo.
+func newPageInfo(raw jsPageInfo, info *target.Info) PageInfo {
+	pi := PageInfo{
+		URL:             raw.URL,
+		Title:           raw.Title,
+		ReadyState:      raw.ReadyState,
+		ContentType:     raw.ContentType,
+		CanonicalURL:    raw.Canonical,
+		MetaDescription: raw.Description,
+		OGTitle:         raw.OGTitle,
+		Language:        raw.Language,
+		FrameCount:      raw.Frames,
+	}
+	if info != nil {
+		pi.TargetID = string(info.TargetID)
+		pi.TargetType = info.Type
+		if pi.URL == "" {
+			pi.URL = info.URL
+		}
+	}
+	return pi
+}
+
+// handlePageInfo returns the URL, title, readiness and basic meta tags of the current page.
+func (bs *BrowserServer) handlePageInfo(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	runCtx, cancel := context.WithTimeout(bs.pageContext(ctx), time.Duration(bs.cfg().SelectorQueryTimeout)*time.Second)
+	defer cancel()
+
+	var raw jsPageInfo
+	var info *target.Info
+	err := chromedp.Run(runCtx,
+		chromedp.Evaluate(pageInfoJS, &raw),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			info, err = target.GetTargetInfo().Do(ctx)
+			if err != nil {
+				// 目标信息只是补充，获取失败不影响结果
+				bs.Logger.Debug().Err(err).Msg("failed to get target info")
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to get page info", err)), nil
+	}
+	pi := newPageInfo(raw, info)
+	pi.Headless = bs.headless()
+	pi.Emulation = bs.emulationState()
+	data, err := json.Marshal(pi)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to marshal page info", err)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,261 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ErrInvalidPermission is returned for an unknown permission name or state, or a malformed origin.
+var ErrInvalidPermission = errors.New("invalid permission")
+
+// allOrigins is the origin of an override that applies to every origin.
+const allOrigins = "*"
+
+// permissionNames are the permissions browser_set_permission can override.
+var permissionNames = []string{"geolocation", "notifications", "clipboard-read", "camera", "microphone", "midi"}
+
+// permissionStates are the states a permission can be set to.
+var permissionStates = []string{
+	string(browser.PermissionSettingGranted),
+	string(browser.PermissionSettingDenied),
+	string(browser.PermissionSettingPrompt),
+}
+
+// PermissionOverride is a permission state set for an origin. CDP can't read the permission
+// state back, so the overrides are tracked by MoLing.
+type PermissionOverride struct {
+	Origin     string `json:"origin"` // * 表示所有来源
+	Permission string `json:"permission"`
+	State      string `json:"state"`
+	Default    bool   `json:"default,omitempty"` // 来自 default_denied_permissions 配置
+}
+
+// validatePermissionName checks the permission against permissionNames.
+func validatePermissionName(name string) error {
+	for _, n := range permissionNames {
+		if name == n {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: unknown permission %q, use one of %s", ErrInvalidPermission, name, strings.Join(permissionNames, ", "))
+}
+
+// validatePermissionState checks the state against permissionStates.
+func validatePermissionState(state string) error {
+	for _, s := range permissionStates {
+		if state == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: unknown state %q, use one of %s", ErrInvalidPermission, state, strings.Join(permissionStates, ", "))
+}
+
+// normalizePermissionOrigin returns the scheme://host[:port] of the origin, or allOrigins for an
+// empty origin or *.
+func normalizePermissionOrigin(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || raw == allOrigins {
+		return allOrigins, nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		strings.TrimSuffix(u.Path, "/") != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return "", fmt.Errorf("%w: origin %q must be scheme://host[:port], e.g. https://maps.example.com", ErrInvalidPermission, raw)
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host), nil
+}
+
+// action returns the Browser.setPermission command of the override.
+func (po PermissionOverride) action() chromedp.Action {
+	params := browser.SetPermission(&browser.PermissionDescriptor{Name: po.Permission}, browser.PermissionSetting(po.State))
+	if po.Origin != allOrigins {
+		params = params.WithOrigin(po.Origin)
+	}
+	return browserCommand{params}
+}
+
+// browserCommand runs a command of the Browser domain on the browser target instead of the tab
+// of the call.
+type browserCommand struct {
+	cmd chromedp.Action
+}
+
+// Do executes the command with the browser executor.
+func (bc browserCommand) Do(ctx context.Context) error {
+	c := chromedp.FromContext(ctx)
+	if c == nil || c.Browser == nil {
+		return bc.cmd.Do(ctx)
+	}
+	return bc.cmd.Do(cdp.WithExecutor(ctx, c.Browser))
+}
+
+// defaultPermissionOverrides returns the denials of default_denied_permissions for all origins.
+func (cfg *BrowserConfig) defaultPermissionOverrides() []PermissionOverride {
+	overrides := make([]PermissionOverride, 0, len(cfg.defaultDeniedPermissions))
+	for _, name := range cfg.defaultDeniedPermissions {
+		overrides = append(overrides, PermissionOverride{
+			Origin:     allOrigins,
+			Permission: name,
+			State:      string(browser.PermissionSettingDenied),
+			Default:    true,
+		})
+	}
+	return overrides
+}
+
+// permissionStore tracks the permission overrides of the running browser.
+type permissionStore struct {
+	mu        sync.Mutex
+	overrides map[string]PermissionOverride // 按来源和权限名
+}
+
+// set records an override, replacing the previous state of the permission for the origin.
+func (s *permissionStore) set(po PermissionOverride) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.overrides == nil {
+		s.overrides = make(map[string]PermissionOverride)
+	}
+	s.overrides[po.Origin+" "+po.Permission] = po
+}
+
+// reset forgets all overrides and records the defaults.
+func (s *permissionStore) reset(defaults []PermissionOverride) {
+	s.mu.Lock()
+	s.overrides = nil
+	s.mu.Unlock()
+	for _, po := range defaults {
+		s.set(po)
+	}
+}
+
+// list returns the overrides sorted by origin and permission.
+func (s *permissionStore) list() []PermissionOverride {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	overrides := make([]PermissionOverride, 0, len(s.overrides))
+	for _, po := range s.overrides {
+		overrides = append(overrides, po)
+	}
+	sort.Slice(overrides, func(i, j int) bool {
+		if overrides[i].Origin != overrides[j].Origin {
+			return overrides[i].Origin < overrides[j].Origin
+		}
+		return overrides[i].Permission < overrides[j].Permission
+	})
+	return overrides
+}
+
+// addPermissionTools registers browser_set_permission and browser_list_permission_overrides.
+func (bs *BrowserServer) addPermissionTools() {
+	bs.addTool(mcp.NewTool(
+		"browser_set_permission",
+		mcp.WithDescription("Grant, deny or reset to prompt a permission for an origin, so that pages don't stall on a permission prompt nobody answers. The override lasts until the browser restarts. Call without arguments to remove all overrides except the configured default denials."),
+		mcp.WithString("origin",
+			mcp.Description("Origin, e.g. https://maps.example.com (default: all origins)"),
+		),
+		mcp.WithString("permission",
+			mcp.Description("Permission name"),
+			mcp.Enum(permissionNames...),
+		),
+		mcp.WithString("state",
+			mcp.Description("granted, denied, or prompt to ask the user as without an override"),
+			mcp.Enum(permissionStates...),
+		),
+	), bs.handleSetPermission)
+
+	bs.addTool(mcp.NewTool(
+		"browser_list_permission_overrides",
+		mcp.WithDescription("List the permission overrides set by browser_set_permission and the default denials of the configuration, as JSON. Permissions without an override are not listed."),
+	), bs.handleListPermissionOverrides)
+}
+
+func (bs *BrowserServer) handleSetPermission(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	rawOrigin, _ := args["origin"].(string)
+	permission, _ := args["permission"].(string)
+	state, _ := args["state"].(string)
+
+	if strings.TrimSpace(rawOrigin) == "" && permission == "" && state == "" {
+		defaults := bs.cfg().defaultPermissionOverrides()
+		actions := []chromedp.Action{browserCommand{browser.ResetPermissions()}}
+		for _, po := range defaults {
+			actions = append(actions, po.action())
+		}
+		if err := bs.emulate(ctx, actions...); err != nil {
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to reset permissions", err)), nil
+		}
+		bs.permissions.reset(defaults)
+		return mcp.NewToolResultText(fmt.Sprintf("All permission overrides removed, %d default denials kept", len(defaults))), nil
+	}
+
+	if err := validatePermissionName(permission); err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	if err := validatePermissionState(state); err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	origin, err := normalizePermissionOrigin(rawOrigin)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	po := PermissionOverride{Origin: origin, Permission: permission, State: state}
+	if err := bs.emulate(ctx, po.action()); err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to set permission", err)), nil
+	}
+	bs.permissions.set(po)
+	return mcp.NewToolResultText(fmt.Sprintf("Permission %s set to %s for %s", permission, state, origin)), nil
+}
+
+func (bs *BrowserServer) handleListPermissionOverrides(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	data, err := json.Marshal(bs.permissions.list())
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to encode the result", err), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// resetPermissions forgets the overrides of the previous browser and applies the default denials
+// to the new one, before any page can prompt. It is called whenever the browser is started.
+func (bs *BrowserServer) resetPermissions() {
+	defaults := bs.cfg().defaultPermissionOverrides()
+	bs.permissions.reset(defaults)
+	if len(defaults) == 0 {
+		return
+	}
+	actions := make([]chromedp.Action, 0, len(defaults))
+	for _, po := range defaults {
+		actions = append(actions, po.action())
+	}
+	if err := bs.emulate(context.Background(), actions...); err != nil {
+		bs.Logger.Warn().Err(err).Msg("failed to apply the default permission denials")
+	}
+}
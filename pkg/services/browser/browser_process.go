@@ -0,0 +1,94 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// processPollInterval is the interval for checking whether Chrome has exited on shutdown.
+const processPollInterval = 50 * time.Millisecond
+
+// chromeProcess is the Chrome process tree started by the allocator, replaced in tests.
+type chromeProcess interface {
+	Alive() bool // Alive reports whether any process of the tree is still running.
+	Kill() error // Kill kills the whole process tree.
+}
+
+// shutdownChrome closes Chrome with graceful and waits up to timeout for proc to exit, then kills
+// the process tree if it is still running. forced reports whether the kill happened, proc is nil
+// when Chrome was never launched.
+func shutdownChrome(parent context.Context, proc chromeProcess, graceful func(context.Context) error, timeout time.Duration) (forced bool, err error) {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- graceful(ctx) }()
+
+	// chromedp.Cancel 在浏览器未退出时会一直阻塞，只等待到超时
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("chrome did not close within %s: %w", timeout, ctx.Err())
+	}
+	if proc == nil {
+		return false, err
+	}
+
+	// 浏览器主进程退出后，渲染等子进程可能仍在退出
+	ticker := time.NewTicker(processPollInterval)
+	defer ticker.Stop()
+	for proc.Alive() {
+		select {
+		case <-ctx.Done():
+			if err := proc.Kill(); err != nil {
+				return true, fmt.Errorf("failed to kill chrome: %w", err)
+			}
+			return true, nil
+		case <-ticker.C:
+		}
+	}
+	// 进程已退出，超时错误不再有意义
+	if ctx.Err() != nil {
+		return false, nil
+	}
+	return false, err
+}
+
+// closeBrowser closes Chrome gracefully and kills its process group when it does not exit within
+// CloseTimeout, so the profile is not left locked for the next start.
+func (bs *BrowserServer) closeBrowser() error {
+	browserCtx := bs.browserContext()
+	var proc chromeProcess
+	if c := chromedp.FromContext(browserCtx); c != nil && c.Browser != nil {
+		if p := c.Browser.Process(); p != nil {
+			proc = newChromeProcess(p)
+		}
+	}
+	timeout := time.Duration(bs.cfg().CloseTimeout) * time.Second
+	start := time.Now()
+	forced, err := shutdownChrome(browserCtx, proc, chromedp.Cancel, timeout)
+	if forced {
+		bs.Logger.Warn().Dur("duration", time.Since(start)).Msg("chrome did not exit in time, killed its process group")
+	} else {
+		bs.Logger.Debug().Dur("duration", time.Since(start)).Msg("chrome closed")
+	}
+	return err
+}
@@ -0,0 +1,33 @@
+//go:build linux
+
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"os"
+	"syscall"
+)
+
+// setParentDeathSignal kills Chrome when MoLing dies, as chromedp does by default.
+func setParentDeathSignal(attr *syscall.SysProcAttr) {
+	// AWS Lambda 不支持
+	if _, ok := os.LookupEnv("LAMBDA_TASK_ROOT"); ok {
+		return
+	}
+	attr.Pdeathsig = syscall.SIGKILL
+}
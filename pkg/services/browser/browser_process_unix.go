@@ -0,0 +1,60 @@
+//go:build darwin || linux || freebsd || openbsd || netbsd
+
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// processGroup is the process group of Chrome. chromeCmdOptions starts Chrome as the group leader,
+// so its renderer and GPU processes are killed together with it.
+type processGroup struct {
+	pgid int
+}
+
+func newChromeProcess(p *os.Process) chromeProcess {
+	return processGroup{pgid: p.Pid}
+}
+
+// Alive reports whether any process of the group is still running.
+func (g processGroup) Alive() bool {
+	err := syscall.Kill(-g.pgid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// Kill sends SIGKILL to the whole group.
+func (g processGroup) Kill() error {
+	if err := syscall.Kill(-g.pgid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+	return nil
+}
+
+// chromeCmdOptions starts Chrome in its own process group. It replaces the command options of
+// chromedp, the parent death signal is kept where the platform supports it.
+func chromeCmdOptions(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = new(syscall.SysProcAttr)
+	}
+	cmd.SysProcAttr.Setpgid = true
+	setParentDeathSignal(cmd.SysProcAttr)
+}
@@ -0,0 +1,50 @@
+//go:build windows
+
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// processTree is the Chrome process and its children, killed with taskkill /T.
+type processTree struct {
+	pid int
+}
+
+func newChromeProcess(p *os.Process) chromeProcess {
+	return processTree{pid: p.Pid}
+}
+
+// Alive reports whether the Chrome process is still running.
+func (t processTree) Alive() bool {
+	exists, err := process.PidExists(int32(t.pid))
+	return err == nil && exists
+}
+
+// Kill kills Chrome and all of its child processes.
+func (t processTree) Kill() error {
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(t.pid)).Run()
+}
+
+// chromeCmdOptions keeps the default command options, Windows has no process groups to set up.
+func chromeCmdOptions(*exec.Cmd) {}
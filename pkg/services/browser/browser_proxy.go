@@ -0,0 +1,117 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidProxy is returned by BrowserConfig.Check for a proxy or bypass entry Chrome can't use.
+var ErrInvalidProxy = errors.New("invalid proxy")
+
+// proxySettings is the parsed Proxy of the config. Chrome only takes the server on the command
+// line, the credentials are answered to the proxy's authentication challenges.
+type proxySettings struct {
+	server   string // scheme://host:port，不含凭据
+	username string
+	password string
+}
+
+// parseProxy parses host:port or scheme://[user:password@]host:port. The scheme defaults to
+// http, socks proxies don't support credentials in Chrome.
+func parseProxy(raw string) (*proxySettings, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	const format = "use [scheme://][user:password@]host:port, e.g. http://127.0.0.1:8080"
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		// 错误信息可能包含密码，不返回原始地址
+		return nil, fmt.Errorf("%w: not a URL, %s", ErrInvalidProxy, format)
+	}
+	redacted := u.Redacted()
+	switch u.Scheme {
+	case "http", "https", "socks4", "socks5":
+	default:
+		return nil, fmt.Errorf("%w: %s, the scheme must be http, https, socks4 or socks5", ErrInvalidProxy, redacted)
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("%w: %s has a path, %s", ErrInvalidProxy, redacted, format)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("%w: %s has no host, %s", ErrInvalidProxy, redacted, format)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil || port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("%w: %s needs a port between 1 and 65535, %s", ErrInvalidProxy, redacted, format)
+	}
+	p := &proxySettings{server: u.Scheme + "://" + net.JoinHostPort(u.Hostname(), u.Port())}
+	if u.User != nil {
+		if strings.HasPrefix(u.Scheme, "socks") {
+			return nil, fmt.Errorf("%w: %s, Chrome doesn't support credentials for socks proxies", ErrInvalidProxy, redacted)
+		}
+		p.username = u.User.Username()
+		p.password, _ = u.User.Password()
+	}
+	return p, nil
+}
+
+// parseNoProxyList validates the bypass entries, Chrome separates them by semicolons.
+func parseNoProxyList(entries []string) (string, error) {
+	hosts := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		if strings.ContainsAny(entry, "; \t") {
+			return "", fmt.Errorf("%w: no_proxy_list entry %q must be one host pattern, e.g. *.internal.example.com or <local>", ErrInvalidProxy, entry)
+		}
+		hosts = append(hosts, entry)
+	}
+	return strings.Join(hosts, ";"), nil
+}
+
+// credential returns the credentials answered to proxy challenges, nil without username.
+func (p *proxySettings) credential() *credential {
+	if p == nil || (p.username == "" && p.password == "") {
+		return nil
+	}
+	return &credential{username: p.username, password: p.password}
+}
+
+// applyProxyAuth hands the proxy credentials of the config to the authentication handler when the
+// browser is first started. Fetch then pauses the requests so that the challenges arrive.
+func (bs *BrowserServer) applyProxyAuth() {
+	cred := bs.cfg().proxy.credential()
+	if cred == nil {
+		return
+	}
+	bs.auth.with(func(as *authState) { as.proxy = cred })
+	if err := bs.emulate(context.Background(), bs.authActions()...); err != nil {
+		bs.Logger.Warn().Err(err).Msg("failed to apply the proxy credentials")
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	queryAllDefaultLimit = 50
+	queryAllMaxLimit     = 1000
+	// queryAllMaxAttributes is the number of attributes read per element at most.
+	queryAllMaxAttributes = 50
+	// queryAllMaxValueLength is the length of a string value at most, longer values end with "...".
+	queryAllMaxValueLength = 1000
+)
+
+// queryAllDefaultAttributes are read when no attributes are given.
+var queryAllDefaultAttributes = []string{"tagName", "id", "class", "textContent"}
+
+// queryAllJS reads the attributes of the first elements matching a selector. A name is read as
+// the element property when it is a string, number or boolean (textContent, href, value,
+// checked), as the attribute otherwise (class, data-*), null when neither exists. The arguments
+// are the selector, the names, the limit and the maximum value length.
+const queryAllJS = `(function(selector, attributes, limit, maxLength) {
+	var nodes = document.querySelectorAll(selector);
+	var elements = [];
+	for (var i = 0; i < nodes.length && i < limit; i++) {
+		var el = nodes[i];
+		var item = {};
+		for (var j = 0; j < attributes.length; j++) {
+			var name = attributes[j];
+			var value = null;
+			var prop = el[name];
+			if (typeof prop === "string" || typeof prop === "number" || typeof prop === "boolean") {
+				value = prop;
+			} else if (el.hasAttribute && el.hasAttribute(name)) {
+				value = el.getAttribute(name);
+			}
+			if (typeof value === "string") {
+				if (name === "textContent" || name === "innerText") {
+					value = value.replace(/\s+/g, " ").replace(/^\s+|\s+$/g, "");
+				}
+				if (value.length > maxLength) {
+					value = value.substring(0, maxLength) + "...";
+				}
+			}
+			item[name] = value;
+		}
+		elements.push(item);
+	}
+	return {total: nodes.length, elements: elements};
+})(%s, %s, %d, %d)`
+
+// QueryAllResult is the result of browser_query_all.
+type QueryAllResult struct {
+	Total    int                      `json:"total"`    // 匹配的元素总数，可能大于返回的数量
+	Returned int                      `json:"returned"` // 返回的元素数，最多 limit 个
+	Elements []map[string]interface{} `json:"elements"`
+}
+
+// queryAllScript validates the arguments of browser_query_all and builds the script. The
+// selector and names are embedded as JSON strings, never as code.
+func queryAllScript(args map[string]interface{}) (string, error) {
+	selector, _ := args["selector"].(string)
+	if selector = strings.TrimSpace(selector); selector == "" {
+		return "", errors.New("selector must be a non-empty CSS selector")
+	}
+	attributes, err := stringArray(args["attributes"], "attributes")
+	if err != nil {
+		return "", err
+	}
+	if len(attributes) == 0 {
+		attributes = queryAllDefaultAttributes
+	}
+	if len(attributes) > queryAllMaxAttributes {
+		return "", fmt.Errorf("at most %d attributes can be read", queryAllMaxAttributes)
+	}
+	names := make([]string, 0, len(attributes))
+	for _, name := range attributes {
+		if name = strings.TrimSpace(name); name == "" {
+			return "", errors.New("attributes must not contain empty names")
+		}
+		names = append(names, safeJSONString(name))
+	}
+	limit := queryAllDefaultLimit
+	if v, ok := args["limit"]; ok {
+		f, ok := v.(float64)
+		if !ok || f < 1 || f > queryAllMaxLimit {
+			return "", fmt.Errorf("limit must be a number between 1 and %d", queryAllMaxLimit)
+		}
+		limit = int(f)
+	}
+	return fmt.Sprintf(queryAllJS, safeJSONString(selector), "["+strings.Join(names, ", ")+"]", limit, queryAllMaxValueLength), nil
+}
+
+// addQueryAllTool registers browser_query_all.
+func (bs *BrowserServer) addQueryAllTool() {
+	bs.addTool(mcp.NewTool(
+		"browser_query_all",
+		mcp.WithDescription("List the elements matching a CSS selector as JSON, one object per element with the requested attributes or properties, e.g. href, textContent, class, value or data-id. Returns the total number of matches even when the list is cut at limit, and an empty list when nothing matches. Use it instead of browser_evaluate to list links, rows or options"),
+		mcp.WithString("selector",
+			mcp.Description("CSS selector of the elements"),
+			mcp.Required(),
+		),
+		mcp.WithArray("attributes",
+			mcp.Description(fmt.Sprintf("Attributes or element properties to read, missing ones are null (default: %s)", strings.Join(queryAllDefaultAttributes, ", "))),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description(fmt.Sprintf("Maximum number of elements to return, 1 to %d (default: %d)", queryAllMaxLimit, queryAllDefaultLimit)),
+		),
+	), bs.handleQueryAll)
+}
+
+func (bs *BrowserServer) handleQueryAll(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	script, err := queryAllScript(request.GetArguments())
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ArgumentError(err)), nil
+	}
+
+	runCtx, cancel := context.WithTimeout(bs.pageContext(ctx), time.Duration(bs.cfg().SelectorQueryTimeout)*time.Second)
+	defer cancel()
+	var result QueryAllResult
+	if err := bs.runner.Evaluate(runCtx, script, &result); err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to query the elements", err)), nil
+	}
+	if result.Elements == nil {
+		result.Elements = []map[string]interface{}{}
+	}
+	result.Returned = len(result.Elements)
+	data, err := json.Marshal(result)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to marshal the elements", err)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
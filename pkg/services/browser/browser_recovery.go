@@ -0,0 +1,209 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ErrBrowserCrashing is returned when the browser keeps crashing and the restart limit is reached.
+var ErrBrowserCrashing = errors.New("browser repeatedly crashing")
+
+// browserGoneWait is how long a call failing with a browserGoneErrors fragment waits for the
+// browser context to be done, chromedp cancels it shortly after the connection drops.
+const browserGoneWait = 500 * time.Millisecond
+
+// browserGoneErrors are the error fragments chromedp reports once the Chrome process is gone. A
+// call failing with one of them only counts as a crash once the browser context is done, and
+// "context canceled" is left out because it is also what a cancelled client call reports.
+var browserGoneErrors = []string{
+	"invalid context",
+	"target closed",
+	"websocket: close",
+	"use of closed network connection",
+	"connection reset by peer",
+	"broken pipe",
+}
+
+// addTool registers a browser tool through the dispatch layer of the browser service. The
+// handler is kept for macro replay, calls through MCP are recorded while a macro is recording.
+func (bs *BrowserServer) addTool(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	handler = bs.withDrain(bs.withRecovery(handler))
+	bs.toolHandlers[tool.Name] = handler
+	bs.AddTool(tool, bs.withMacroRecording(handler))
+}
+
+// withDrain runs the call under the read side of opLock, so that switching the browser mode waits
+// for the calls in flight and holds back new calls until the new browser is up.
+func (bs *BrowserServer) withDrain(handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		bs.opLock.RLock()
+		defer bs.opLock.RUnlock()
+		return handler(ctx, request)
+	}
+}
+
+// withRecovery restarts the browser when it is found dead before or after the call, and retries
+// the failed call once on the new browser. The browser counts as dead only when its context is
+// done, a call cancelled by the client is never retried.
+func (bs *BrowserServer) withRecovery(handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if bs.browserDone() {
+			_, gen := bs.browserGeneration()
+			if err := bs.recoverBrowser(gen); err != nil {
+				return comm.ToolErrorFromErr("failed to restart the browser", err), nil
+			}
+		}
+
+		browserCtx, gen := bs.browserGeneration()
+		result, err := handler(ctx, request)
+		if ctx.Err() != nil || !crashedDuring(browserCtx, result, err) {
+			return result, err
+		}
+
+		bs.Logger.Warn().Str("tool", request.Params.Name).Msg("browser is gone, restarting and retrying")
+		if rerr := bs.recoverBrowser(gen); rerr != nil {
+			return comm.ToolErrorFromErr("failed to restart the browser", rerr), nil
+		}
+		return handler(ctx, request)
+	}
+}
+
+// crashedDuring reports whether the browser a call ran on died during the call. The connection
+// errors arrive before chromedp cancels the browser context, so a call failing with one waits a
+// moment for it.
+func crashedDuring(browserCtx context.Context, result *mcp.CallToolResult, err error) bool {
+	if browserCtx == nil {
+		return false
+	}
+	if browserCtx.Err() != nil {
+		return true
+	}
+	if !isBrowserGoneResult(result, err) {
+		return false
+	}
+	timer := time.NewTimer(browserGoneWait)
+	defer timer.Stop()
+	select {
+	case <-browserCtx.Done():
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// browserDone reports whether the browser context, or the allocator context it derives from, has
+// been cancelled.
+func (bs *BrowserServer) browserDone() bool {
+	browserCtx := bs.browserContext()
+	return browserCtx != nil && browserCtx.Err() != nil
+}
+
+// browserContext returns the context of the browser, nil before the browser is started.
+func (bs *BrowserServer) browserContext() context.Context {
+	bs.browserLock.RLock()
+	defer bs.browserLock.RUnlock()
+	return bs.Context
+}
+
+// browserGeneration returns the context of the browser with its restart generation, which changes
+// every time the browser is stopped.
+func (bs *BrowserServer) browserGeneration() (context.Context, uint64) {
+	bs.browserLock.RLock()
+	defer bs.browserLock.RUnlock()
+	return bs.Context, bs.restartGen
+}
+
+// recoverBrowser tears down the old contexts and starts a new browser, unless the browser has
+// been restarted MaxRestarts times within RestartWindow. gen is the restart generation the caller
+// saw die; when another call already restarted the browser since, or the browser is alive, there
+// is nothing to do.
+func (bs *BrowserServer) recoverBrowser(gen uint64) error {
+	bs.restartLock.Lock()
+	defer bs.restartLock.Unlock()
+	if _, current := bs.browserGeneration(); current != gen || !bs.browserDone() {
+		return nil
+	}
+
+	now := time.Now()
+	window := time.Duration(bs.cfg().RestartWindow) * time.Second
+	recent := bs.restartTimes[:0]
+	for _, t := range bs.restartTimes {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	bs.restartTimes = recent
+	if len(bs.restartTimes) >= bs.cfg().MaxRestarts {
+		return fmt.Errorf("%w: restarted %d times within %s, please check the browser and restart MoLing",
+			ErrBrowserCrashing, len(bs.restartTimes), window)
+	}
+
+	bs.restartTimes = append(bs.restartTimes, now)
+	bs.restartCount++
+	bs.Logger.Info().Int("restarts", bs.restartCount).Msg("restarting browser")
+	return bs.restartBrowser()
+}
+
+// restartBrowser stops the browser and starts a new one with the current config. The profile in
+// BrowserDataPath is kept, so cookies and logins survive, and the emulation overrides, credentials
+// and extra headers are applied again. Permission overrides are dropped, only the default denials
+// are applied. The caller holds restartLock.
+func (bs *BrowserServer) restartBrowser() error {
+	bs.stopBrowser()
+	if err := bs.starter(); err != nil {
+		return fmt.Errorf("failed to restart browser: %w", err)
+	}
+	bs.resetPermissions()
+	bs.reapplyEmulation()
+	bs.reapplyAuth()
+	bs.enableDownloads()
+	return nil
+}
+
+// isBrowserGoneResult reports whether a tool result failed because the browser is gone.
+func isBrowserGoneResult(result *mcp.CallToolResult, err error) bool {
+	if err != nil {
+		return isBrowserGoneMessage(err.Error())
+	}
+	if result == nil || !result.IsError {
+		return false
+	}
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok && isBrowserGoneMessage(text.Text) {
+			return true
+		}
+	}
+	return false
+}
+
+func isBrowserGoneMessage(msg string) bool {
+	for _, fragment := range browserGoneErrors {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/chromedp/chromedp"
+)
+
+var (
+	// ErrInvalidRemoteURL is returned when remote_debugging_url is not a ws, wss, http or https URL.
+	ErrInvalidRemoteURL = errors.New("invalid remote debugging URL")
+	// ErrRemoteBrowser is returned by the tools that relaunch Chrome or wipe its profile when the
+	// browser is connected via remote_debugging_url.
+	ErrRemoteBrowser = errors.New("the browser is connected via remote_debugging_url and is not launched by MoLing")
+)
+
+// checkRemoteDebuggingURL validates RemoteDebuggingURL. http(s) URLs point to the DevTools HTTP
+// endpoint, e.g. http://127.0.0.1:9222, ws(s) URLs to the browser's websocket.
+func checkRemoteDebuggingURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRemoteURL, err)
+	}
+	switch u.Scheme {
+	case "ws", "wss", "http", "https":
+	default:
+		return fmt.Errorf("%w: scheme must be ws, wss, http or https, got %q", ErrInvalidRemoteURL, u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%w: missing host", ErrInvalidRemoteURL)
+	}
+	return nil
+}
+
+// remote reports whether the browser is connected via RemoteDebuggingURL instead of launched.
+func (cfg *BrowserConfig) remote() bool {
+	return cfg.RemoteDebuggingURL != ""
+}
+
+// remoteIgnoredSettings returns the launch-only settings that are set in the config but have no
+// effect on a remote browser. browser_data_path is always set and not listed, the remote browser
+// keeps its own profile.
+func (cfg *BrowserConfig) remoteIgnoredSettings() []string {
+	defaults := NewBrowserConfig()
+	ignored := make([]string, 0)
+	if cfg.Headless {
+		ignored = append(ignored, "headless")
+	}
+	if cfg.Proxy != "" {
+		ignored = append(ignored, "proxy")
+	}
+	if len(cfg.NoProxyList) > 0 {
+		ignored = append(ignored, "no_proxy_list")
+	}
+	if cfg.UserAgent != defaults.UserAgent {
+		ignored = append(ignored, "user_agent")
+	}
+	if cfg.DefaultLanguage != defaults.DefaultLanguage {
+		ignored = append(ignored, "default_language")
+	}
+	return ignored
+}
+
+// newAllocator returns the allocator of the config: a remote allocator attached to
+// RemoteDebuggingURL, or an exec allocator that launches Chrome with allocatorOptions.
+func (bs *BrowserServer) newAllocator(parent context.Context) (context.Context, context.CancelFunc) {
+	if bs.cfg().remote() {
+		return chromedp.NewRemoteAllocator(parent, bs.cfg().RemoteDebuggingURL)
+	}
+	return chromedp.NewExecAllocator(parent, bs.allocatorOptions()...)
+}
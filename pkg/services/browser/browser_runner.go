@@ -0,0 +1,43 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Runner runs actions and scripts on a browser tab. The tool handlers go through it instead of
+// calling chromedp directly, so that tests can replace it with a fake that doesn't need Chrome.
+type Runner interface {
+	// Run runs the actions in order on the tab of ctx.
+	Run(ctx context.Context, actions ...chromedp.Action) error
+	// Evaluate runs script on the tab of ctx and decodes the result into out.
+	Evaluate(ctx context.Context, script string, out interface{}) error
+}
+
+// chromedpRunner is the default Runner, it runs everything through chromedp.
+type chromedpRunner struct{}
+
+func (chromedpRunner) Run(ctx context.Context, actions ...chromedp.Action) error {
+	return chromedp.Run(ctx, actions...)
+}
+
+func (chromedpRunner) Evaluate(ctx context.Context, script string, out interface{}) error {
+	return chromedp.Run(ctx, chromedp.Evaluate(script, out))
+}
@@ -0,0 +1,229 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// browser_screenshot 的 return_mode 取值
+const (
+	ScreenshotReturnFile   = "file"   // 保存到 DataPath，返回路径
+	ScreenshotReturnInline = "inline" // 以 ImageContent 返回图片，不保存文件
+	ScreenshotReturnBoth   = "both"   // 保存文件并返回图片
+)
+
+// browser_screenshot 的 format 取值
+const (
+	ScreenshotFormatPNG  = "png"
+	ScreenshotFormatJPEG = "jpeg"
+	ScreenshotFormatWebP = "webp"
+)
+
+// screenshotOptions are the image format and quality of a screenshot.
+type screenshotOptions struct {
+	Format  string
+	Quality int // 1-100，PNG 忽略
+}
+
+// checkScreenshotFormat validates an image format of the config or of a call.
+func checkScreenshotFormat(format string) error {
+	switch format {
+	case ScreenshotFormatPNG, ScreenshotFormatJPEG, ScreenshotFormatWebP:
+		return nil
+	}
+	return fmt.Errorf("format must be one of %s, %s or %s", ScreenshotFormatPNG, ScreenshotFormatJPEG, ScreenshotFormatWebP)
+}
+
+// parseScreenshotOptions reads the format and quality arguments of browser_screenshot, the
+// config gives the defaults.
+func parseScreenshotOptions(args map[string]interface{}, cfg *BrowserConfig) (screenshotOptions, error) {
+	opts := screenshotOptions{Format: cfg.ScreenshotFormat, Quality: cfg.ScreenshotQuality}
+	if v, ok := args["format"]; ok {
+		opts.Format, _ = v.(string)
+		opts.Format = strings.ToLower(opts.Format)
+		if err := checkScreenshotFormat(opts.Format); err != nil {
+			return opts, err
+		}
+	}
+	quality, err := intArg(args, "quality", opts.Quality)
+	if err != nil || quality < 1 || quality > 100 {
+		return opts, fmt.Errorf("quality must be an integer between 1 and 100")
+	}
+	opts.Quality = quality
+	return opts, nil
+}
+
+// captureParams builds Page.captureScreenshot for the options, of the clip or of the full page.
+func captureParams(opts screenshotOptions, clip *page.Viewport) *page.CaptureScreenshotParams {
+	params := page.CaptureScreenshot().
+		WithFormat(page.CaptureScreenshotFormat(opts.Format)).
+		WithCaptureBeyondViewport(true).
+		WithFromSurface(true)
+	if opts.Format != ScreenshotFormatPNG {
+		params = params.WithQuality(int64(opts.Quality))
+	}
+	if clip != nil {
+		params = params.WithClip(clip)
+	}
+	return params
+}
+
+// captureAction takes the screenshot of params into buf.
+func captureAction(params *page.CaptureScreenshotParams, buf *[]byte) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		*buf, err = params.Do(ctx)
+		return err
+	})
+}
+
+// elementClipJS returns the box of the element in page coordinates, for the clip of an element
+// screenshot.
+func elementClipJS(selector string) string {
+	return fmt.Sprintf(`(function() {
+	var el = document.querySelector(%s);
+	if (!el) return { found: false };
+	var r = el.getBoundingClientRect();
+	return { found: true, x: r.left + window.scrollX, y: r.top + window.scrollY, width: r.width, height: r.height };
+})()`, safeJSONString(selector))
+}
+
+// elementClip returns the clip of an element screenshot, rounded to whole pixels.
+func (bs *BrowserServer) elementClip(ctx context.Context, selector string) (*page.Viewport, error) {
+	var res struct {
+		Found  bool    `json:"found"`
+		X      float64 `json:"x"`
+		Y      float64 `json:"y"`
+		Width  float64 `json:"width"`
+		Height float64 `json:"height"`
+	}
+	if err := bs.runner.Evaluate(ctx, elementClipJS(selector), &res); err != nil {
+		return nil, err
+	}
+	if !res.Found {
+		return nil, fmt.Errorf("element %s not found", selector)
+	}
+	x, y := math.Round(res.X), math.Round(res.Y)
+	clip := &page.Viewport{X: x, Y: y, Width: math.Round(res.Width + res.X - x), Height: math.Round(res.Height + res.Y - y), Scale: 1}
+	if clip.Width < 1 || clip.Height < 1 {
+		return nil, fmt.Errorf("element %s has no size", selector)
+	}
+	return clip, nil
+}
+
+// imageExtension is the file extension of an image MIME type.
+func imageExtension(mimeType string) string {
+	switch mimeType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/webp":
+		return ".webp"
+	}
+	return ".png"
+}
+
+// screenshotBaseName strips an image extension from the name of a screenshot.
+func screenshotBaseName(name string) string {
+	switch ext := filepath.Ext(name); strings.ToLower(ext) {
+	case ".png", ".jpg", ".jpeg", ".webp":
+		return strings.TrimSuffix(name, ext)
+	}
+	return name
+}
+
+// parseReturnMode validates the return_mode argument of browser_screenshot.
+func parseReturnMode(args map[string]interface{}) (string, error) {
+	v, ok := args["return_mode"]
+	if !ok {
+		return ScreenshotReturnFile, nil
+	}
+	mode, _ := v.(string)
+	switch mode {
+	case ScreenshotReturnFile, ScreenshotReturnInline, ScreenshotReturnBoth:
+		return mode, nil
+	}
+	return "", fmt.Errorf("return_mode must be one of %s, %s or %s", ScreenshotReturnFile, ScreenshotReturnInline, ScreenshotReturnBoth)
+}
+
+// imageMIMEType detects the format of the screenshot.
+func imageMIMEType(buf []byte) string {
+	if mimeType := http.DetectContentType(buf); strings.HasPrefix(mimeType, "image/") {
+		return mimeType
+	}
+	return "image/png"
+}
+
+// screenshotResult saves and/or inlines the screenshot according to return_mode, and appends the
+// OCR text when requested. Screenshots larger than MaxInlineImageBytes are saved to a file
+// instead of being inlined.
+func (bs *BrowserServer) screenshotResult(ctx context.Context, request mcp.CallToolRequest, name string, buf []byte) *mcp.CallToolResult {
+	args := request.GetArguments()
+	mode, err := parseReturnMode(args)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ArgumentError(err))
+	}
+	var lines []string
+	if mode != ScreenshotReturnFile && len(buf) > bs.cfg().MaxInlineImageBytes {
+		lines = append(lines, fmt.Sprintf("Warning: the screenshot has %d bytes, more than max_inline_image_bytes %d, it is saved to a file instead", len(buf), bs.cfg().MaxInlineImageBytes))
+		bs.Logger.Warn().Int("bytes", len(buf)).Int("max", bs.cfg().MaxInlineImageBytes).Msg("截图过大，改为保存文件")
+		mode = ScreenshotReturnFile
+	}
+
+	mimeType := imageMIMEType(buf)
+	if mode != ScreenshotReturnInline {
+		// 使用随机数确保文件名唯一，扩展名与图片格式一致
+		newName := filepath.Join(bs.cfg().DataPath, fmt.Sprintf("%s_%d%s", screenshotBaseName(name), rand.Int(), imageExtension(mimeType)))
+		if err := os.WriteFile(newName, buf, 0644); err != nil {
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to save the screenshot", err))
+		}
+		bs.Logger.Debug().Str("path", newName).Msg("成功保存截图")
+		lines = append(lines, fmt.Sprintf("截图已保存至: %s", newName))
+	}
+	if mode != ScreenshotReturnFile {
+		lines = append(lines, fmt.Sprintf("Screenshot %s (%s, %d bytes)", name, mimeType, len(buf)))
+	}
+
+	if doOCR, _ := args["ocr"].(bool); doOCR {
+		withBoxes, _ := args["with_boxes"].(bool)
+		text, err := bs.recognizeScreenshot(ctx, buf, withBoxes)
+		if err != nil {
+			// 截图已保存时仍告知路径
+			return bs.toolError(ctx, request, comm.ToolError(comm.ErrorCodeOf(err), "failed to recognize text in the screenshot", err.Error()+"\n"+strings.Join(lines, "\n")))
+		}
+		lines = append(lines, "", text)
+	}
+
+	result := &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(strings.Join(lines, "\n"))}}
+	if mode != ScreenshotReturnFile {
+		result.Content = append(result.Content, mcp.NewImageContent(base64.StdEncoding.EncodeToString(buf), mimeType))
+	}
+	return result
+}
@@ -0,0 +1,210 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// browser_scroll 的 position 取值
+const (
+	ScrollPositionTop    = "top"
+	ScrollPositionBottom = "bottom"
+)
+
+// scrollStateJS returns the vertical scroll position of the window and its maximum.
+const scrollStateJS = `(function() {
+	var el = document.scrollingElement || document.documentElement;
+	var y = window.scrollY || window.pageYOffset || 0;
+	return {scroll_y: Math.round(y), max_scroll_y: Math.max(0, Math.round(el.scrollHeight - window.innerHeight))};
+})()`
+
+// windowScrollJS scrolls the window by a delta or to the top or bottom, the arguments are the
+// position ("" to scroll by the delta), the delta and the behavior.
+const windowScrollJS = `(function(position, delta, behavior) {
+	var el = document.scrollingElement || document.documentElement;
+	if (position === "") {
+		window.scrollBy({top: delta, left: 0, behavior: behavior});
+	} else {
+		window.scrollTo({top: position === "bottom" ? el.scrollHeight : 0, left: window.scrollX || 0, behavior: behavior});
+	}
+	return true;
+})(%s, %d, %q)`
+
+// smoothScrollIntoViewJS scrolls the first element matching the selector smoothly to the center
+// of the window, chromedp.ScrollIntoView jumps.
+const smoothScrollIntoViewJS = `(function(selector) {
+	var el = document.querySelector(selector);
+	if (!el) return false;
+	el.scrollIntoView({behavior: "smooth", block: "center"});
+	return true;
+})(%s)`
+
+// scrollSettleInterval is the interval of checking whether a smooth scroll has ended.
+const scrollSettleInterval = 100 * time.Millisecond
+
+// scrollOptions are the arguments of browser_scroll, exactly one of Selector, DeltaY and
+// Position is set.
+type scrollOptions struct {
+	Selector string
+	DeltaY   int
+	Position string
+	Smooth   bool
+}
+
+// ScrollState is the result of browser_scroll.
+type ScrollState struct {
+	ScrollY    int  `json:"scroll_y"`
+	MaxScrollY int  `json:"max_scroll_y"`
+	AtBottom   bool `json:"at_bottom"`
+}
+
+// parseScrollOptions validates the arguments of browser_scroll.
+func parseScrollOptions(args map[string]interface{}) (scrollOptions, error) {
+	var opts scrollOptions
+	modes := 0
+	if v, ok := args["selector"]; ok {
+		selector, _ := v.(string)
+		if opts.Selector = strings.TrimSpace(selector); opts.Selector == "" {
+			return opts, errors.New("selector must be a non-empty CSS selector")
+		}
+		modes++
+	}
+	if v, ok := args["delta_y"]; ok {
+		delta, ok := v.(float64)
+		if !ok {
+			return opts, errors.New("delta_y must be a number of pixels")
+		}
+		opts.DeltaY = int(delta)
+		modes++
+	}
+	if v, ok := args["position"]; ok {
+		opts.Position, _ = v.(string)
+		if opts.Position != ScrollPositionTop && opts.Position != ScrollPositionBottom {
+			return opts, fmt.Errorf("position must be %s or %s", ScrollPositionTop, ScrollPositionBottom)
+		}
+		modes++
+	}
+	if modes != 1 {
+		return opts, errors.New("pass exactly one of selector, delta_y or position")
+	}
+	opts.Smooth, _ = args["smooth"].(bool)
+	return opts, nil
+}
+
+// scrollState reads the scroll position. After a smooth scroll it waits until the position
+// stops changing, or ctx ends.
+func (bs *BrowserServer) scrollState(ctx context.Context, smooth bool) (ScrollState, error) {
+	var state ScrollState
+	if err := bs.runner.Evaluate(ctx, scrollStateJS, &state); err != nil {
+		return state, err
+	}
+	for settled := !smooth; !settled; {
+		select {
+		case <-ctx.Done():
+			settled = true
+			continue
+		case <-time.After(scrollSettleInterval):
+		}
+		var next ScrollState
+		if err := bs.runner.Evaluate(ctx, scrollStateJS, &next); err != nil {
+			return state, err
+		}
+		settled = next == state
+		state = next
+	}
+	state.AtBottom = state.ScrollY >= state.MaxScrollY
+	return state, nil
+}
+
+// addScrollTool registers browser_scroll.
+func (bs *BrowserServer) addScrollTool() {
+	bs.addTool(mcp.NewTool(
+		"browser_scroll",
+		mcp.WithDescription("Scroll an element into view, scroll the window by a number of pixels, or to the top or bottom of the page. Returns the scroll position as JSON: scroll_y, max_scroll_y and at_bottom, which tells when an infinite scroll page stops growing"),
+		mcp.WithString("selector",
+			mcp.Description("CSS selector of the element to scroll into view"),
+		),
+		mcp.WithNumber("delta_y",
+			mcp.Description("Pixels to scroll the window down, negative to scroll up"),
+		),
+		mcp.WithString("position",
+			mcp.Description("Scroll the window to the top or bottom of the page"),
+			mcp.Enum(ScrollPositionTop, ScrollPositionBottom),
+		),
+		mcp.WithBoolean("smooth",
+			mcp.Description("Scroll smoothly like a user and wait for the scrolling to end, pages that load content on scroll events may need it (default: false)"),
+		),
+	), bs.handleScroll)
+}
+
+func (bs *BrowserServer) handleScroll(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	opts, err := parseScrollOptions(request.GetArguments())
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ArgumentError(err)), nil
+	}
+
+	runCtx, cancel := context.WithTimeout(bs.pageContext(ctx), time.Duration(bs.cfg().SelectorQueryTimeout)*time.Second)
+	defer cancel()
+	if opts.Selector != "" {
+		action := chromedp.ScrollIntoView(opts.Selector, chromedp.ByQuery)
+		if opts.Smooth {
+			action = chromedp.WaitReady(opts.Selector, chromedp.ByQuery)
+		}
+		err = bs.runner.Run(runCtx, action)
+		if err == nil && opts.Smooth {
+			selector, _ := json.Marshal(opts.Selector)
+			var found bool
+			err = bs.runner.Evaluate(runCtx, fmt.Sprintf(smoothScrollIntoViewJS, selector), &found)
+		}
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) || runCtx.Err() != nil {
+				return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeNotFound, fmt.Sprintf("no element matches selector %s within %d seconds", opts.Selector, bs.cfg().SelectorQueryTimeout), "")), nil
+			}
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr(fmt.Sprintf("failed to scroll to %s", opts.Selector), err)), nil
+		}
+	} else {
+		behavior := "auto"
+		if opts.Smooth {
+			behavior = "smooth"
+		}
+		position, _ := json.Marshal(opts.Position)
+		var ok bool
+		if err := bs.runner.Evaluate(runCtx, fmt.Sprintf(windowScrollJS, position, opts.DeltaY, behavior), &ok); err != nil {
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to scroll the window", err)), nil
+		}
+	}
+
+	state, err := bs.scrollState(runCtx, opts.Smooth)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("scrolled, but failed to read the scroll position", err)), nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to marshal the scroll position", err)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,311 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	textSelectorPrefix   = "text="                // 按可见文本定位元素的伪选择器前缀
+	selectorTargetAttr   = "data-moling-target"   // 文本选择器命中的元素上设置的属性，用于生成可复用的选择器
+	selectorPollInterval = 100 * time.Millisecond // 候选选择器的轮询间隔
+)
+
+// 文本选择器匹配的元素类别
+const (
+	targetClickable = "clickable" // 链接、按钮等可点击元素，按其文本匹配
+	targetField     = "field"     // 输入框等表单字段，按标签、aria-label 和 placeholder 匹配
+)
+
+// clickableSelector and fieldSelector are the elements text= looks at for each target kind.
+const (
+	clickableSelector = `a[href], button, input[type=button], input[type=submit], input[type=reset], input[type=image], summary, label, ` +
+		`[role=button], [role=link], [role=menuitem], [role=tab], [role=checkbox], [role=option], [onclick], [tabindex]`
+	fieldSelector = `input:not([type=hidden]), textarea, select, [contenteditable=""], [contenteditable=true], [role=textbox], [role=combobox]`
+)
+
+// textMatcherJSTemplate finds the visible element of a kind whose trimmed text equals the needle,
+// case-insensitively, or otherwise the one with the shortest text containing it unless exact is
+// set. It returns a selector of the element: its id when that is unique, else an attribute set on
+// it. It returns an empty string when nothing matches. It is plain ES5; the %s are the needle,
+// exact, the target kind, the element selector and the attribute as JSON.
+const textMatcherJSTemplate = `(function(needle, exact, kind, elements, attr) {
+	function clean(s) {
+		return String(s === null || s === undefined ? "" : s).replace(/\s+/g, " ").replace(/^ | $/g, "").toLowerCase();
+	}
+	function attrOf(el, name) {
+		return el.getAttribute ? el.getAttribute(name) : null;
+	}
+	function visible(el) {
+		return !!(el.offsetWidth || el.offsetHeight || (el.getClientRects && el.getClientRects().length));
+	}
+	function textsOf(el) {
+		var texts = [typeof el.innerText === "string" ? el.innerText : el.textContent, el.value, attrOf(el, "aria-label")];
+		if (kind === "` + targetField + `") {
+			texts = [attrOf(el, "aria-label"), attrOf(el, "placeholder")];
+			for (var i = 0; el.labels && i < el.labels.length; i++) {
+				texts.push(typeof el.labels[i].innerText === "string" ? el.labels[i].innerText : el.labels[i].textContent);
+			}
+		}
+		return texts;
+	}
+	needle = clean(needle);
+	var nodes = document.querySelectorAll(elements);
+	var best = null, bestLength = 0, equal = false;
+	for (var i = 0; i < nodes.length && !equal; i++) {
+		if (!visible(nodes[i])) {
+			continue;
+		}
+		var texts = textsOf(nodes[i]);
+		for (var j = 0; j < texts.length; j++) {
+			var text = clean(texts[j]);
+			if (text === needle) {
+				best = nodes[i];
+				equal = true;
+				break;
+			}
+			if (!exact && text.indexOf(needle) >= 0 && (best === null || text.length < bestLength)) {
+				best = nodes[i];
+				bestLength = text.length;
+			}
+		}
+	}
+	if (best === null) {
+		return "";
+	}
+	if (best.id && /^[A-Za-z][\w-]*$/.test(best.id) && document.querySelectorAll("#" + best.id).length === 1) {
+		return "#" + best.id;
+	}
+	var key = attrOf(best, attr);
+	if (!key) {
+		window.__molingTargetSeq = (window.__molingTargetSeq || 0) + 1;
+		key = String(window.__molingTargetSeq);
+		best.setAttribute(attr, key);
+	}
+	return "[" + attr + "=\"" + key + "\"]";
+})(%s, %s, %s, %s, %s)`
+
+// cssProbeJS reports whether the element of the selector %s exists and is visible. An invalid
+// selector throws.
+const cssProbeJS = `(function(selector) {
+	var el = document.querySelector(selector);
+	return !!el && !!(el.offsetWidth || el.offsetHeight || (el.getClientRects && el.getClientRects().length));
+})(%s)`
+
+// textMatcherJS returns the script that resolves a text= selector for the target kind.
+func textMatcherJS(text string, exact bool, kind string) string {
+	elements := clickableSelector
+	if kind == targetField {
+		elements = fieldSelector
+	}
+	return fmt.Sprintf(textMatcherJSTemplate, safeJSONString(text), fmt.Sprint(exact), safeJSONString(kind), safeJSONString(elements), safeJSONString(selectorTargetAttr))
+}
+
+// parseTextSelector returns the text of a text= selector. A quoted text, e.g. text="Sign in",
+// must match exactly.
+func parseTextSelector(candidate string) (text string, exact bool, ok bool) {
+	candidate = strings.TrimSpace(candidate)
+	if !strings.HasPrefix(candidate, textSelectorPrefix) {
+		return "", false, false
+	}
+	text = strings.TrimSpace(strings.TrimPrefix(candidate, textSelectorPrefix))
+	if len(text) >= 2 && strings.HasPrefix(text, `"`) && strings.HasSuffix(text, `"`) {
+		return text[1 : len(text)-1], true, true
+	}
+	return text, false, true
+}
+
+// parseSelectorCandidates returns the candidates of selector and selectors in order. Both accept
+// a string or an array of strings; empty and repeated candidates are dropped.
+func parseSelectorCandidates(args map[string]interface{}) ([]string, error) {
+	var candidates []string
+	seen := make(map[string]bool)
+	for _, key := range []string{"selector", "selectors"} {
+		var values []interface{}
+		switch v := args[key].(type) {
+		case nil:
+		case string:
+			values = []interface{}{v}
+		case []interface{}:
+			values = v
+		case []string:
+			for _, s := range v {
+				values = append(values, s)
+			}
+		default:
+			return nil, fmt.Errorf("%s must be a string or an array of strings", key)
+		}
+		for _, value := range values {
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must only contain strings, got %v", key, value)
+			}
+			if s = strings.TrimSpace(s); s == "" || seen[s] {
+				continue
+			}
+			if text, _, ok := parseTextSelector(s); ok && strings.TrimSpace(text) == "" {
+				return nil, fmt.Errorf("%q has no text", s)
+			}
+			seen[s] = true
+			candidates = append(candidates, s)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, errors.New("selector or selectors is required")
+	}
+	return candidates, nil
+}
+
+// SelectorMatch is the candidate that matched an element, with the concrete CSS selector it
+// resolved to.
+type SelectorMatch struct {
+	Selector  string // 实际使用的 CSS 选择器
+	Candidate string
+	Index     int // 从 1 开始
+	Total     int
+}
+
+func (sm *SelectorMatch) String() string {
+	return fmt.Sprintf("Resolved selector: %s (candidate %d of %d: %s). Pass it as selector to target the same element again.",
+		sm.Selector, sm.Index, sm.Total, sm.Candidate)
+}
+
+// selectorResolver tries the candidates in order, each for at most timeout.
+type selectorResolver struct {
+	page    scriptPage
+	timeout time.Duration
+	exact   bool   // text= 只匹配完全相同的文本
+	kind    string // targetClickable 或 targetField
+}
+
+// try returns the concrete selector of the candidate, or an empty string when it doesn't match yet.
+func (sr selectorResolver) try(candidate string) (string, error) {
+	if text, exact, ok := parseTextSelector(candidate); ok {
+		var selector string
+		err := sr.page.Evaluate(textMatcherJS(text, exact || sr.exact, sr.kind), &selector)
+		return selector, err
+	}
+	var found bool
+	if err := sr.page.Evaluate(fmt.Sprintf(cssProbeJS, safeJSONString(candidate)), &found); err != nil || !found {
+		return "", err
+	}
+	return candidate, nil
+}
+
+// resolve returns the first candidate that matches a visible element. A candidate that fails,
+// e.g. with an invalid selector, is skipped at once; one that doesn't match is polled until its
+// timeout runs out. The error lists why every candidate was rejected.
+func (sr selectorResolver) resolve(candidates []string) (*SelectorMatch, error) {
+	failures := make([]string, 0, len(candidates))
+	for i, candidate := range candidates {
+		deadline := time.Now().Add(sr.timeout)
+		for {
+			selector, err := sr.try(candidate)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", candidate, err))
+				break
+			}
+			if selector != "" {
+				return &SelectorMatch{Selector: selector, Candidate: candidate, Index: i + 1, Total: len(candidates)}, nil
+			}
+			if !time.Now().Before(deadline) {
+				failures = append(failures, fmt.Sprintf("%s: no visible element within %s", candidate, sr.timeout))
+				break
+			}
+			time.Sleep(min(selectorPollInterval, time.Until(deadline)))
+		}
+	}
+	return nil, fmt.Errorf("no candidate matched: %s", strings.Join(failures, "; "))
+}
+
+// withSelector returns a copy of the request with selector set and selectors removed.
+func withSelector(request mcp.CallToolRequest, selector string) mcp.CallToolRequest {
+	args := make(map[string]interface{}, len(request.GetArguments()))
+	for k, v := range request.GetArguments() {
+		args[k] = v
+	}
+	delete(args, "selectors")
+	args["selector"] = selector
+	request.Params.Arguments = args
+	return request
+}
+
+// withSelectorFallback lets browser_click, browser_fill, browser_hover and the other tools on one
+// element take several candidate selectors and text= selectors. The candidates are resolved to a
+// concrete selector, each with the short SelectorCandidateTimeout, before the handler runs with
+// it; the result names the selector used. A single CSS selector goes to the handler unchanged,
+// with its usual wait.
+func (bs *BrowserServer) withSelectorFallback(kind string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := request.GetArguments()
+		if _, ok, _ := parseAriaTarget(args["aria"]); ok {
+			return handler(ctx, request)
+		}
+		candidates, err := parseSelectorCandidates(args)
+		if err != nil {
+			return bs.toolError(ctx, request, comm.ArgumentError(err)), nil
+		}
+		if _, _, isText := parseTextSelector(candidates[0]); len(candidates) == 1 && !isText {
+			return handler(ctx, withSelector(request, candidates[0]))
+		}
+
+		exact, _ := args["exact"].(bool)
+		timeout := time.Duration(bs.cfg().SelectorCandidateTimeout) * time.Second
+		resolver := selectorResolver{
+			page:    tabPage{ctx: bs.pageContext(ctx), timeout: timeout},
+			timeout: timeout,
+			exact:   exact,
+			kind:    kind,
+		}
+		match, err := resolver.resolve(candidates)
+		if err != nil {
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to find the element", err)), nil
+		}
+		bs.Logger.Debug().Str("selector", match.Selector).Str("candidate", match.Candidate).Msg("selector resolved")
+		result, err := handler(ctx, withSelector(request, match.Selector))
+		if err == nil && result != nil && !result.IsError {
+			result.Content = append(result.Content, mcp.NewTextContent(match.String()))
+		}
+		return result, err
+	}
+}
+
+// selectorOptions are the arguments of the tools wrapped by withSelectorFallback, what names the
+// element and text what text= matches.
+func selectorOptions(what, text string) []mcp.ToolOption {
+	return []mcp.ToolOption{
+		mcp.WithString("selector",
+			mcp.Description(fmt.Sprintf("CSS selector for the %s, or text=... to find it by %s (case-insensitive, contains; text=\"...\" for an exact match)", what, text)),
+		),
+		mcp.WithArray("selectors",
+			mcp.Description("Several candidate selectors tried in order, each with a short timeout, e.g. [\"#submit\", \"button[type=submit]\", \"text=Sign in\"]. The result names the selector that matched"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithBoolean("exact",
+			mcp.Description("text= selectors only match elements whose whole text equals the text (default: false)"),
+		),
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/services/abstract"
+)
+
+// browserSession is the session-scoped state of the browser: the tab of one MCP client session.
+// Sessions share the Chrome process and its profile, so cookies and logins are shared, but each
+// session navigates in its own tab.
+type browserSession struct {
+	id     string
+	mu     sync.Mutex
+	parent context.Context    // 打开标签页时的浏览器上下文，浏览器重启后随之变化
+	tab    context.Context    // 会话的标签页，首次调用时打开
+	cancel context.CancelFunc // 关闭标签页
+}
+
+// NewSessionState implements abstract.SessionScoped. The tab is opened on the first call that
+// uses it, not here.
+func (bs *BrowserServer) NewSessionState(sessionID string) (abstract.SessionState, error) {
+	return &browserSession{id: sessionID}, nil
+}
+
+// pageContext returns the browser context the call works on: the tab the session of the call
+// switched to with browser_tab_switch, otherwise the tab of the session, or the default tab for
+// calls without a session.
+func (bs *BrowserServer) pageContext(ctx context.Context) context.Context {
+	if tab := bs.tabs.activeTab(abstract.SessionIDFromContext(ctx)); tab != nil {
+		return tab
+	}
+	return bs.mainTabContext(ctx)
+}
+
+// mainTabContext returns the main tab of the call, ignoring the tab it has switched to.
+func (bs *BrowserServer) mainTabContext(ctx context.Context) context.Context {
+	session, ok := abstract.SessionStateFromContext(ctx).(*browserSession)
+	browserCtx := bs.browserContext()
+	if !ok || browserCtx == nil {
+		return browserCtx
+	}
+	return session.tabContext(browserCtx, bs.openTab)
+}
+
+// newTab opens a tab in the browser and applies the current emulation overrides, credentials,
+// extra headers and the download directory to it.
+func (bs *BrowserServer) newTab(parent context.Context) (context.Context, context.CancelFunc) {
+	tab, cancel := chromedp.NewContext(parent)
+	bs.listenAuth(tab)
+	chromedp.ListenTarget(tab, bs.downloads.handleEvent)
+	if actions := append(append(bs.emulationActions(), bs.authActions()...), bs.downloadActions()...); len(actions) > 0 {
+		runCtx, cancelRun := context.WithTimeout(tab, time.Duration(bs.cfg().SelectorQueryTimeout)*time.Second)
+		defer cancelRun()
+		if err := chromedp.Run(runCtx, actions...); err != nil {
+			bs.Logger.Warn().Err(err).Msg("failed to apply the emulation overrides and extra headers to the session tab")
+		}
+	}
+	return tab, cancel
+}
+
+// tabContext returns the tab of the session, opening a new one when there is none yet, or when
+// the browser was restarted or the tab was closed since.
+func (s *browserSession) tabContext(parent context.Context, open func(context.Context) (context.Context, context.CancelFunc)) context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tab != nil && s.parent == parent && s.tab.Err() == nil {
+		return s.tab
+	}
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.parent = parent
+	s.tab, s.cancel = open(parent)
+	return s.tab
+}
+
+// Close closes the tab of the session.
+func (s *browserSession) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.tab, s.cancel, s.parent = nil, nil, nil
+	return nil
+}
+
+// String describes the session state in the status resource.
+func (s *browserSession) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tab == nil || s.tab.Err() != nil {
+		return "no tab open"
+	}
+	return "tab open"
+}
@@ -0,0 +1,551 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	snapshotDir      = "sessions" // 会话快照目录，位于 DataPath 下
+	snapshotExt      = ".json"
+	snapshotVersion  = 1
+	autosaveSnapshot = "autosave"  // 定时和关闭时保存、启动时恢复的快照
+	autosaveInterval = time.Minute // 定时保存的间隔
+	maxRestoredTabs  = 10          // 恢复的标签页上限，避免启动过慢
+)
+
+var (
+	// ErrStaleSnapshot is returned for a snapshot older than restore_session_max_age.
+	ErrStaleSnapshot = errors.New("session snapshot is stale")
+
+	snapshotNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// TabSnapshot is an open tab of a session snapshot.
+type TabSnapshot struct {
+	URL    string `json:"url"`
+	Title  string `json:"title,omitempty"`
+	Active bool   `json:"active,omitempty"` // 工具调用所在的标签页
+}
+
+// OriginStorage is the localStorage of an origin.
+type OriginStorage struct {
+	Origin string            `json:"origin"`
+	Items  map[string]string `json:"items"`
+}
+
+// SessionSnapshot is the browsing state saved by browser_session_save and on shutdown: the open
+// tabs and the emulation and header overrides. browser_session_save also saves the cookies and
+// the localStorage of the current origin, the autosave snapshot leaves them to the profile. HTTP
+// credentials are never saved.
+type SessionSnapshot struct {
+	Version       int                          `json:"version"`
+	SavedAt       time.Time                    `json:"saved_at"`
+	Tabs          []TabSnapshot                `json:"tabs"`
+	Emulation     *EmulationState              `json:"emulation,omitempty"`
+	Headers       map[string]string            `json:"headers,omitempty"`        // 发往所有来源的请求头
+	ScopedHeaders map[string]map[string]string `json:"scoped_headers,omitempty"` // 按来源模式的请求头
+	Cookies       []CookieInfo                 `json:"cookies,omitempty"`
+	LocalStorage  *OriginStorage               `json:"local_storage,omitempty"` // 当前标签页来源的 localStorage
+}
+
+// TabRestoreError is a tab that could not be restored.
+type TabRestoreError struct {
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
+
+// SessionRestoreReport is the result of restoring a snapshot.
+type SessionRestoreReport struct {
+	Snapshot  string            `json:"snapshot"`
+	SavedAt   time.Time         `json:"saved_at"`
+	Restored  []string          `json:"restored"`
+	Failed    []TabRestoreError `json:"failed,omitempty"`
+	Skipped   int               `json:"skipped,omitempty"` // 超过 maxRestoredTabs 未恢复的标签页
+	Emulation bool              `json:"emulation"`
+	Headers   int               `json:"headers"`
+	Cookies   int               `json:"cookies"`
+	Storage   int               `json:"local_storage"` // 恢复的 localStorage 条目数
+	Warnings  []string          `json:"warnings,omitempty"`
+}
+
+// localStorageJS reads the localStorage of the current page. The origin is empty for pages
+// without a storage of their own, e.g. about:blank and file:// pages.
+const localStorageJS = `(function() {
+	var origin = location.origin;
+	if (!/^https?:/.test(location.protocol)) return { origin: "", items: {} };
+	var items = {};
+	try {
+		for (var i = 0; i < localStorage.length; i++) {
+			var key = localStorage.key(i);
+			items[key] = localStorage.getItem(key);
+		}
+	} catch (e) {
+		return { origin: "", items: {} };
+	}
+	return { origin: origin, items: items };
+})()`
+
+// setLocalStorageJS writes items into the localStorage of the current page and returns the
+// number of items written. The argument is the items as a JSON object.
+const setLocalStorageJS = `(function(items) {
+	var n = 0;
+	for (var key in items) {
+		if (Object.prototype.hasOwnProperty.call(items, key)) {
+			localStorage.setItem(key, items[key]);
+			n++;
+		}
+	}
+	return n;
+})(%s)`
+
+// cookieParams converts saved cookies back to the parameters of Network.setCookies. Session
+// cookies stay session cookies.
+func cookieParams(cookies []CookieInfo) []*network.CookieParam {
+	params := make([]*network.CookieParam, 0, len(cookies))
+	for _, c := range cookies {
+		param := &network.CookieParam{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HTTPOnly: c.HTTPOnly,
+			SameSite: network.CookieSameSite(c.SameSite),
+		}
+		if c.Expires > 0 {
+			expires := cdp.TimeSinceEpoch(time.Unix(0, int64(c.Expires*float64(time.Second))))
+			param.Expires = &expires
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+// tabsFromTargets returns the restorable pages among the targets, the active one first. Blank
+// pages and browser internal pages are left out.
+func tabsFromTargets(infos []*target.Info, active target.ID) []TabSnapshot {
+	tabs := make([]TabSnapshot, 0, len(infos))
+	for _, info := range infos {
+		if info.Type != "page" || !restorableURL(info.URL) {
+			continue
+		}
+		tabs = append(tabs, TabSnapshot{URL: info.URL, Title: info.Title, Active: info.TargetID == active})
+	}
+	sort.SliceStable(tabs, func(i, j int) bool { return tabs[i].Active && !tabs[j].Active })
+	return tabs
+}
+
+// restorableURL reports whether a tab URL can be navigated to again.
+func restorableURL(rawURL string) bool {
+	for _, prefix := range []string{"http://", "https://", "file://"} {
+		if strings.HasPrefix(strings.ToLower(rawURL), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeSnapshot writes the snapshot atomically, readable only by the user as the headers may
+// hold API keys.
+func writeSnapshot(path string, snap *SessionSnapshot) error {
+	if err := utils.CreateDirectory(filepath.Dir(path)); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// readSnapshot reads a snapshot, a corrupt file or an unknown version is an error.
+func readSnapshot(path string) (*SessionSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snap SessionSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("invalid session snapshot %s: %w", path, err)
+	}
+	if snap.Version != snapshotVersion || snap.SavedAt.IsZero() {
+		return nil, fmt.Errorf("invalid session snapshot %s: unsupported version %d", path, snap.Version)
+	}
+	return &snap, nil
+}
+
+// readFreshSnapshot reads a snapshot saved within maxAge before now.
+func readFreshSnapshot(path string, maxAge time.Duration, now time.Time) (*SessionSnapshot, error) {
+	snap, err := readSnapshot(path)
+	if err != nil {
+		return nil, err
+	}
+	if age := now.Sub(snap.SavedAt); age > maxAge {
+		return nil, fmt.Errorf("%w: saved %s ago, restore_session_max_age is %s", ErrStaleSnapshot, age.Round(time.Second), maxAge)
+	}
+	return snap, nil
+}
+
+// restoreTabs opens the tabs one after another, each within timeout. A tab that fails or times
+// out doesn't stop the others.
+func restoreTabs(ctx context.Context, tabs []TabSnapshot, timeout time.Duration, open func(ctx context.Context, tab TabSnapshot) error) ([]string, []TabRestoreError) {
+	restored := make([]string, 0, len(tabs))
+	var failed []TabRestoreError
+	for _, tab := range tabs {
+		tabCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := open(tabCtx, tab)
+		cancel()
+		if err != nil {
+			failed = append(failed, TabRestoreError{URL: tab.URL, Error: err.Error()})
+			continue
+		}
+		restored = append(restored, tab.URL)
+	}
+	return restored, failed
+}
+
+// snapshotPath returns the file of a named snapshot.
+func (bs *BrowserServer) snapshotPath(name string) string {
+	return filepath.Join(bs.cfg().DataPath, snapshotDir, name+snapshotExt)
+}
+
+// browserRunning reports whether Chrome has been launched, so that reading its tabs doesn't
+// launch it.
+func (bs *BrowserServer) browserRunning() bool {
+	browserCtx := bs.browserContext()
+	if browserCtx == nil || browserCtx.Err() != nil {
+		return false
+	}
+	c := chromedp.FromContext(browserCtx)
+	return c != nil && c.Browser != nil
+}
+
+// captureSnapshot collects the open tabs of the browser and the overrides in effect, with
+// withStorage also the cookies and the localStorage of the tab of the call. The tab of the call
+// is the active one.
+func (bs *BrowserServer) captureSnapshot(ctx context.Context, withStorage bool) (*SessionSnapshot, error) {
+	snap := &SessionSnapshot{Version: snapshotVersion, SavedAt: time.Now(), Tabs: []TabSnapshot{}, Emulation: bs.emulationState()}
+	bs.auth.with(func(as *authState) {
+		if len(as.global) > 0 {
+			snap.Headers = make(map[string]string, len(as.global))
+			for name, value := range as.global {
+				snap.Headers[name] = value
+			}
+		}
+		for key, scope := range as.scoped {
+			if snap.ScopedHeaders == nil {
+				snap.ScopedHeaders = make(map[string]map[string]string)
+			}
+			snap.ScopedHeaders[key] = scope.headers
+		}
+	})
+	if !bs.browserRunning() {
+		return snap, nil
+	}
+
+	page := bs.pageContext(ctx)
+	var active target.ID
+	if c := chromedp.FromContext(page); c != nil && c.Target != nil {
+		active = c.Target.TargetID
+	}
+	infos, err := chromedp.Targets(page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the tabs: %w", err)
+	}
+	snap.Tabs = tabsFromTargets(infos, active)
+	if withStorage {
+		if err := bs.captureStorage(ctx, snap); err != nil {
+			return nil, err
+		}
+	}
+	return snap, nil
+}
+
+// captureStorage adds the cookies of all sites and the localStorage of the tab of the call to
+// the snapshot.
+func (bs *BrowserServer) captureStorage(ctx context.Context, snap *SessionSnapshot) error {
+	runCtx, cancel := bs.cookieContext(ctx)
+	defer cancel()
+	cookies, err := bs.allCookies(runCtx)
+	if err != nil {
+		return fmt.Errorf("failed to get cookies: %w", err)
+	}
+	snap.Cookies = cookieInfos(cookies)
+	var storage OriginStorage
+	if err := bs.runner.Evaluate(runCtx, localStorageJS, &storage); err != nil {
+		return fmt.Errorf("failed to read localStorage: %w", err)
+	}
+	if storage.Origin != "" {
+		snap.LocalStorage = &storage
+	}
+	return nil
+}
+
+// saveSnapshot captures and writes a named snapshot.
+func (bs *BrowserServer) saveSnapshot(ctx context.Context, name string, withStorage bool) (*SessionSnapshot, string, error) {
+	snap, err := bs.captureSnapshot(ctx, withStorage)
+	if err != nil {
+		return nil, "", err
+	}
+	path := bs.snapshotPath(name)
+	if err := writeSnapshot(path, snap); err != nil {
+		return nil, "", fmt.Errorf("failed to write session snapshot: %w", err)
+	}
+	return snap, path, nil
+}
+
+// restoreSnapshot applies the overrides and the cookies of the snapshot and opens its tabs with
+// open. The active tab is navigated in the tab of the call, the others in new tabs. The
+// localStorage is written after navigating the tab of the call to its origin, before the tabs are
+// opened.
+func (bs *BrowserServer) restoreSnapshot(ctx context.Context, name string, snap *SessionSnapshot, open func(ctx context.Context, tab TabSnapshot) error) *SessionRestoreReport {
+	report := &SessionRestoreReport{Snapshot: name, SavedAt: snap.SavedAt}
+	if snap.Emulation != nil && !snap.Emulation.empty() {
+		bs.updateEmulation(func(es *EmulationState) { *es = *snap.Emulation })
+		report.Emulation = true
+	}
+	scoped := make(map[string]headerScope)
+	for raw, headers := range snap.ScopedHeaders {
+		pattern, err := parseOriginPattern(raw)
+		if err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("skipped headers: %v", err))
+			continue
+		}
+		scoped[pattern.String()] = headerScope{pattern: pattern, headers: headers}
+	}
+	if len(snap.Headers) > 0 || len(scoped) > 0 {
+		bs.auth.update(func(as *authState) { as.global, as.scoped = snap.Headers, scoped })
+		report.Headers = len(snap.Headers) + len(scoped)
+	}
+	if actions := append(bs.emulationActions(), bs.authActions()...); len(actions) > 0 {
+		if err := bs.emulate(ctx, actions...); err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("failed to apply the overrides: %v", err))
+		}
+	}
+
+	bs.restoreStorage(ctx, snap, report, open)
+
+	tabs := snap.Tabs
+	if len(tabs) > maxRestoredTabs {
+		report.Skipped = len(tabs) - maxRestoredTabs
+		tabs = tabs[:maxRestoredTabs]
+	}
+	report.Restored, report.Failed = restoreTabs(ctx, tabs, time.Duration(bs.cfg().URLTimeout)*time.Second, open)
+	return report
+}
+
+// restoreStorage sets the cookies of the snapshot and writes its localStorage. Failures are
+// reported as warnings, the tabs are restored anyway.
+func (bs *BrowserServer) restoreStorage(ctx context.Context, snap *SessionSnapshot, report *SessionRestoreReport, open func(ctx context.Context, tab TabSnapshot) error) {
+	if len(snap.Cookies) > 0 {
+		runCtx, cancel := bs.cookieContext(ctx)
+		err := bs.runner.Run(runCtx, network.SetCookies(cookieParams(snap.Cookies)))
+		cancel()
+		if err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("failed to set the cookies: %v", err))
+		} else {
+			report.Cookies = len(snap.Cookies)
+		}
+	}
+	if snap.LocalStorage == nil || len(snap.LocalStorage.Items) == 0 {
+		return
+	}
+	origin := snap.LocalStorage.Origin
+	if !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("skipped localStorage of invalid origin %q", origin))
+		return
+	}
+	navCtx, cancel := context.WithTimeout(ctx, time.Duration(bs.cfg().URLTimeout)*time.Second)
+	err := open(navCtx, TabSnapshot{URL: origin + "/", Active: true})
+	cancel()
+	if err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("failed to open %s for localStorage: %v", origin, err))
+		return
+	}
+	items, err := json.Marshal(snap.LocalStorage.Items)
+	if err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("failed to encode localStorage: %v", err))
+		return
+	}
+	runCtx, cancel := bs.cookieContext(ctx)
+	defer cancel()
+	if err := bs.runner.Evaluate(runCtx, fmt.Sprintf(setLocalStorageJS, items), &report.Storage); err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("failed to write localStorage of %s: %v", origin, err))
+	}
+}
+
+// openRestoredTab navigates the tab of the call to an active tab of the snapshot, and a new tab to
+// the others. The new tabs stay open until the browser stops.
+func (bs *BrowserServer) openRestoredTab(ctx context.Context, tab TabSnapshot) error {
+	page := bs.pageContext(ctx)
+	if !tab.Active {
+		page, _ = bs.openTab(bs.browserContext())
+	}
+	deadline, _ := ctx.Deadline()
+	runCtx, cancel := context.WithDeadline(page, deadline)
+	defer cancel()
+	return chromedp.Run(runCtx, chromedp.Navigate(tab.URL))
+}
+
+// restoreOnStart restores the autosave snapshot when restore_session is enabled and starts saving
+// it every minute. A missing, corrupt or stale snapshot is skipped with a warning, it never
+// fails the start.
+func (bs *BrowserServer) restoreOnStart() {
+	if !bs.cfg().RestoreSession {
+		return
+	}
+	maxAge := time.Duration(bs.cfg().RestoreSessionMaxAge) * time.Second
+	snap, err := readFreshSnapshot(bs.snapshotPath(autosaveSnapshot), maxAge, time.Now())
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		bs.Logger.Warn().Err(err).Msg("ignoring the saved browser session")
+	default:
+		report := bs.restoreSnapshot(context.Background(), autosaveSnapshot, snap, bs.openRestoredTab)
+		bs.Logger.Info().Strs("restored", report.Restored).Interface("failed", report.Failed).Int("skipped", report.Skipped).
+			Strs("warnings", report.Warnings).Msg("restored the browser session")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	bs.stopAutosave = cancel
+	go bs.autosave(ctx)
+}
+
+// autosave saves the autosave snapshot every autosaveInterval until ctx is done.
+func (bs *BrowserServer) autosave(ctx context.Context) {
+	ticker := time.NewTicker(autosaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			bs.saveAutosave()
+		}
+	}
+}
+
+// saveAutosave saves the autosave snapshot while the browser is running. Calls in flight finish
+// first, the browser is not switched or restarted while the tabs are read.
+func (bs *BrowserServer) saveAutosave() {
+	bs.opLock.RLock()
+	defer bs.opLock.RUnlock()
+	if !bs.browserRunning() {
+		return
+	}
+	if _, _, err := bs.saveSnapshot(context.Background(), autosaveSnapshot, false); err != nil {
+		bs.Logger.Debug().Err(err).Msg("failed to save the browser session")
+	}
+}
+
+// addSnapshotTools registers browser_session_save and browser_session_restore, unless
+// disable_session_tools is set.
+func (bs *BrowserServer) addSnapshotTools() {
+	if bs.cfg().DisableSessionTools {
+		return
+	}
+	bs.addTool(mcp.NewTool(
+		"browser_session_save",
+		mcp.WithDescription("Save the open tabs, the cookies, the localStorage of the current page's origin and the geolocation, timezone, locale and extra header overrides as a named snapshot, to be restored with browser_session_restore after a restart, e.g. to keep a login. HTTP credentials are not saved."),
+		mcp.WithString("name",
+			mcp.Description("Snapshot name, letters, digits, _ and -. "+autosaveSnapshot+" is saved every minute when restore_session is enabled"),
+			mcp.Required(),
+		),
+	), bs.handleSessionSave)
+
+	bs.addTool(mcp.NewTool(
+		"browser_session_restore",
+		mcp.WithDescription("Restore a snapshot saved with browser_session_save: apply its overrides and cookies, write its localStorage after opening its origin, navigate the current tab to its active tab and open its other tabs. Tabs that don't load within url_timeout are reported and skipped."),
+		mcp.WithString("name",
+			mcp.Description("Snapshot name"),
+			mcp.Required(),
+		),
+	), bs.handleSessionRestore)
+}
+
+func (bs *BrowserServer) handleSessionSave(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, _ := request.GetArguments()["name"].(string)
+	if !snapshotNameRegexp.MatchString(name) {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("invalid snapshot name %q, use letters, digits, _ and -", name), ""), nil
+	}
+	snap, path, err := bs.saveSnapshot(ctx, name, true)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to save the session snapshot", err)), nil
+	}
+	msg := fmt.Sprintf("Saved session snapshot %s with %d tabs and %d cookies", name, len(snap.Tabs), len(snap.Cookies))
+	if snap.LocalStorage != nil {
+		msg += fmt.Sprintf(", %d localStorage items of %s", len(snap.LocalStorage.Items), snap.LocalStorage.Origin)
+	}
+	return mcp.NewToolResultText(msg + " to " + path), nil
+}
+
+func (bs *BrowserServer) handleSessionRestore(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, _ := request.GetArguments()["name"].(string)
+	if !snapshotNameRegexp.MatchString(name) {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("invalid snapshot name %q, use letters, digits, _ and -", name), ""), nil
+	}
+	snap, err := readSnapshot(bs.snapshotPath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return comm.ToolError(comm.ErrCodeNotFound, fmt.Sprintf("session snapshot %s not found, available: %s", name, bs.snapshotNames()), ""), nil
+	}
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to read the session snapshot", err), nil
+	}
+	data, err := json.Marshal(bs.restoreSnapshot(ctx, name, snap, bs.openRestoredTab))
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to encode the result", err), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// snapshotNames lists the saved snapshots for error messages.
+func (bs *BrowserServer) snapshotNames() string {
+	entries, _ := os.ReadDir(filepath.Join(bs.cfg().DataPath, snapshotDir))
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == snapshotExt {
+			names = append(names, strings.TrimSuffix(entry.Name(), snapshotExt))
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,325 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/services/abstract"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// MainTabID is the id of the tab calls use when no other tab is selected: the tab of the session,
+// or the first tab of the browser. It can't be closed.
+const MainTabID = "main"
+
+// tabInfoJS returns the title and the URL of a tab.
+const tabInfoJS = `({title: document.title, url: location.href})`
+
+// ErrTabNotFound is returned for a tab id that isn't open.
+var ErrTabNotFound = comm.NewCodedError(comm.ErrCodeNotFound, errors.New("tab not found"))
+
+// managedTab is a tab opened with browser_tab_new.
+type managedTab struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// tabStore holds the tabs opened with browser_tab_new and the tab each session has switched to.
+type tabStore struct {
+	mu     sync.Mutex
+	seq    int
+	tabs   map[string]*managedTab
+	active map[string]string // 会话 ID 到选中的标签页，不使用会话的调用为空字符串
+}
+
+// add registers a tab and returns its id.
+func (s *tabStore) add(ctx context.Context, cancel context.CancelFunc) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tabs == nil {
+		s.tabs = make(map[string]*managedTab)
+	}
+	s.seq++
+	id := "tab-" + strconv.Itoa(s.seq)
+	s.tabs[id] = &managedTab{ctx: ctx, cancel: cancel}
+	return id
+}
+
+// pruneLocked removes the tabs that were closed by the page or by a browser restart.
+func (s *tabStore) pruneLocked() {
+	for id, tab := range s.tabs {
+		if tab.ctx.Err() != nil {
+			tab.cancel()
+			delete(s.tabs, id)
+		}
+	}
+}
+
+// ids returns the open tabs sorted by the order they were opened in.
+func (s *tabStore) ids() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	ids := make([]string, 0, len(s.tabs))
+	for id := range s.tabs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return tabSeq(ids[i]) < tabSeq(ids[j]) })
+	return ids
+}
+
+func tabSeq(id string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(id, "tab-"))
+	return n
+}
+
+// get returns the context of an open tab.
+func (s *tabStore) get(id string) (context.Context, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tab, ok := s.tabs[id]
+	if !ok || tab.ctx.Err() != nil {
+		return nil, fmt.Errorf("%w: %s", ErrTabNotFound, id)
+	}
+	return tab.ctx, nil
+}
+
+// activeTab returns the tab the session has switched to, nil for the main tab. A selected tab
+// that was closed in the meantime falls back to the main tab.
+func (s *tabStore) activeTab(session string) context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.active[session]
+	if !ok {
+		return nil
+	}
+	if tab, ok := s.tabs[id]; ok && tab.ctx.Err() == nil {
+		return tab.ctx
+	}
+	delete(s.active, session)
+	return nil
+}
+
+// activeID returns the id of the tab the session works on.
+func (s *tabStore) activeID(session string) string {
+	if s.activeTab(session) == nil {
+		return MainTabID
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active[session]
+}
+
+// switchTo selects the tab for the session, MainTabID selects the main tab.
+func (s *tabStore) switchTo(session, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id == MainTabID {
+		delete(s.active, session)
+		return nil
+	}
+	if tab, ok := s.tabs[id]; !ok || tab.ctx.Err() != nil {
+		return fmt.Errorf("%w: %s", ErrTabNotFound, id)
+	}
+	if s.active == nil {
+		s.active = make(map[string]string)
+	}
+	s.active[session] = id
+	return nil
+}
+
+// close closes a tab. Sessions that worked on it go back to the main tab.
+func (s *tabStore) close(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id == MainTabID {
+		return comm.NewCodedError(comm.ErrCodeInvalidArgument, errors.New("the main tab can't be closed"))
+	}
+	tab, ok := s.tabs[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrTabNotFound, id)
+	}
+	tab.cancel()
+	delete(s.tabs, id)
+	for session, active := range s.active {
+		if active == id {
+			delete(s.active, session)
+		}
+	}
+	return nil
+}
+
+// closeAll closes all tabs.
+func (s *tabStore) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, tab := range s.tabs {
+		tab.cancel()
+		delete(s.tabs, id)
+	}
+	s.active = nil
+}
+
+// TabInfo is an entry of browser_tab_list.
+type TabInfo struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	Active bool   `json:"active"`
+	Error  string `json:"error,omitempty"`
+}
+
+// tabInfo reads the title and the URL of a tab.
+func (bs *BrowserServer) tabInfo(tab context.Context, id string) TabInfo {
+	info := TabInfo{ID: id}
+	runCtx, cancel := context.WithTimeout(tab, time.Duration(bs.cfg().SelectorQueryTimeout)*time.Second)
+	defer cancel()
+	if err := bs.runner.Evaluate(runCtx, tabInfoJS, &info); err != nil {
+		info.Error = err.Error()
+	}
+	info.ID = id
+	return info
+}
+
+// addTabTools registers the tab management tools.
+func (bs *BrowserServer) addTabTools() {
+	bs.addTool(mcp.NewTool(
+		"browser_tab_new",
+		mcp.WithDescription("Open a new tab, optionally at a URL, and switch to it so that the following browser tools work on it. Returns the tab id"),
+		mcp.WithString("url",
+			mcp.Description("URL to open in the new tab"),
+		),
+		mcp.WithBoolean("switch",
+			mcp.Description("Switch to the new tab (default: true)"),
+		),
+	), bs.handleTabNew)
+	bs.addTool(mcp.NewTool(
+		"browser_tab_list",
+		mcp.WithDescription("List the open tabs with their id, title, URL and which one is active. The main tab is the tab used before any tab was opened"),
+	), bs.handleTabList)
+	bs.addTool(mcp.NewTool(
+		"browser_tab_switch",
+		mcp.WithDescription("Switch to a tab, the following browser tools work on it"),
+		mcp.WithString("id",
+			mcp.Description("Id of the tab from browser_tab_list, \"main\" for the main tab"),
+			mcp.Required(),
+		),
+	), bs.handleTabSwitch)
+	bs.addTool(mcp.NewTool(
+		"browser_tab_close",
+		mcp.WithDescription("Close a tab opened with browser_tab_new. When it was active, the browser tools go back to the main tab"),
+		mcp.WithString("id",
+			mcp.Description("Id of the tab to close"),
+			mcp.Required(),
+		),
+	), bs.handleTabClose)
+}
+
+func (bs *BrowserServer) handleTabNew(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	url, _ := args["url"].(string)
+	switchTo := true
+	if v, ok := args["switch"].(bool); ok {
+		switchTo = v
+	}
+	browserCtx := bs.browserContext()
+	if browserCtx == nil {
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInternal, "the browser is not running", "")), nil
+	}
+
+	tab, cancel := bs.openTab(browserCtx)
+	if url = strings.TrimSpace(url); url != "" {
+		runCtx, cancelRun := context.WithTimeout(tab, time.Duration(bs.cfg().URLTimeout)*time.Second)
+		err := bs.runner.Run(runCtx, chromedp.Navigate(url))
+		cancelRun()
+		if err != nil {
+			cancel()
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr(fmt.Sprintf("failed to open %s in a new tab", url), err)), nil
+		}
+	}
+	id := bs.tabs.add(tab, cancel)
+	if switchTo {
+		if err := bs.tabs.switchTo(abstract.SessionIDFromContext(ctx), id); err != nil {
+			return bs.toolError(ctx, request, comm.ToolErrorFromErr(fmt.Sprintf("failed to switch to tab %s", id), err)), nil
+		}
+	}
+	message := fmt.Sprintf("Opened tab %s", id)
+	if url != "" {
+		message += " at " + url
+	}
+	if switchTo {
+		message += " and switched to it"
+	}
+	return mcp.NewToolResultText(message), nil
+}
+
+func (bs *BrowserServer) handleTabList(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	session := abstract.SessionIDFromContext(ctx)
+	active := bs.tabs.activeID(session)
+	tabs := make([]TabInfo, 0)
+	if main := bs.mainTabContext(ctx); main != nil {
+		tabs = append(tabs, bs.tabInfo(main, MainTabID))
+	}
+	for _, id := range bs.tabs.ids() {
+		tab, err := bs.tabs.get(id)
+		if err != nil {
+			continue
+		}
+		tabs = append(tabs, bs.tabInfo(tab, id))
+	}
+	for i := range tabs {
+		tabs[i].Active = tabs[i].ID == active
+	}
+	data, err := json.Marshal(tabs)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr("failed to marshal tabs", err)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+func (bs *BrowserServer) handleTabSwitch(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, _ := request.GetArguments()["id"].(string)
+	if id = strings.TrimSpace(id); id == "" {
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInvalidArgument, "id must be a non-empty string", "")), nil
+	}
+	if err := bs.tabs.switchTo(abstract.SessionIDFromContext(ctx), id); err != nil {
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrorCodeOf(err), err.Error(), "open tabs: "+strings.Join(append([]string{MainTabID}, bs.tabs.ids()...), ", "))), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Switched to tab %s", id)), nil
+}
+
+func (bs *BrowserServer) handleTabClose(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, _ := request.GetArguments()["id"].(string)
+	if id = strings.TrimSpace(id); id == "" {
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInvalidArgument, "id must be a non-empty string", "")), nil
+	}
+	if err := bs.tabs.close(id); err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr(fmt.Sprintf("failed to close tab %s", id), err)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Closed tab %s, active tab: %s", id, bs.tabs.activeID(abstract.SessionIDFromContext(ctx)))), nil
+}
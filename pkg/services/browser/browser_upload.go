@@ -0,0 +1,180 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+var (
+	// ErrUploadPathNotAllowed is returned when an upload path is outside AllowedUploadDirs.
+	ErrUploadPathNotAllowed = errors.New("upload path not allowed")
+	// ErrNotFileInput is returned when the upload selector does not match an <input type=file>.
+	ErrNotFileInput = errors.New("element is not a file input")
+)
+
+// parseUploadPaths reads the path or paths argument of browser_upload_file.
+func parseUploadPaths(args map[string]interface{}) ([]string, error) {
+	path, hasPath := args["path"].(string)
+	rawPaths, hasPaths := args["paths"]
+	if hasPath && hasPaths {
+		return nil, fmt.Errorf("use either path or paths, not both")
+	}
+	if hasPath {
+		if strings.TrimSpace(path) == "" {
+			return nil, fmt.Errorf("path must not be empty")
+		}
+		return []string{path}, nil
+	}
+	if !hasPaths {
+		return nil, fmt.Errorf("path or paths is required")
+	}
+	items, ok := rawPaths.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("paths must be an array of strings, got %T", rawPaths)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("paths must not be empty")
+	}
+	paths := make([]string, 0, len(items))
+	for i, item := range items {
+		p, ok := item.(string)
+		if !ok || strings.TrimSpace(p) == "" {
+			return nil, fmt.Errorf("paths[%d] must be a non-empty string, got %v", i, item)
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+// validateUploadPath resolves path to an absolute regular file inside AllowedUploadDirs.
+// Symlinks are resolved first, so a link cannot point outside the allowed directories.
+func (bs *BrowserServer) validateUploadPath(path string) (string, error) {
+	if bs.cfg().allowedUploadDirs == nil {
+		if err := bs.cfg().parseUploadDirs(); err != nil {
+			return "", err
+		}
+	}
+	if len(bs.cfg().allowedUploadDirs) == 0 {
+		return "", fmt.Errorf("%w: %s, allowed_upload_dirs is empty", ErrUploadPathNotAllowed, path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path %s: %w", path, err)
+	}
+	realPath, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", fmt.Errorf("failed to access file %s: %w", abs, err)
+	}
+	allowed := false
+	for _, dir := range bs.cfg().allowedUploadDirs {
+		// 允许目录本身可能是软链接，两边都解析后再比较
+		realDir := dir
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			realDir = filepath.Clean(resolved) + string(filepath.Separator)
+		}
+		if strings.HasPrefix(realPath, realDir) || strings.HasPrefix(realPath, dir) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return "", fmt.Errorf("%w: %s is outside the allowed upload directories %s", ErrUploadPathNotAllowed, realPath, strings.Join(bs.cfg().allowedUploadDirs, ", "))
+	}
+	info, err := os.Stat(realPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to access file %s: %w", realPath, err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%s is not a regular file", realPath)
+	}
+	return realPath, nil
+}
+
+// checkFileInput checks that node is an <input type=file> accepting count files.
+func checkFileInput(node *cdp.Node, count int) error {
+	inputType := strings.ToLower(node.AttributeValue("type"))
+	if !strings.EqualFold(node.NodeName, "input") || inputType != "file" {
+		desc := strings.ToLower(node.NodeName)
+		if inputType != "" {
+			desc = fmt.Sprintf("%s type=%s", desc, inputType)
+		}
+		return fmt.Errorf("%w: matched <%s>", ErrNotFileInput, desc)
+	}
+	if _, multiple := node.Attribute("multiple"); count > 1 && !multiple {
+		return fmt.Errorf("the file input does not have the multiple attribute, only 1 file can be uploaded, got %d", count)
+	}
+	return nil
+}
+
+// handleUploadFile sets the files of an <input type=file> with DOM.setFileInputFiles.
+func (bs *BrowserServer) handleUploadFile(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	selector, ok := args["selector"].(string)
+	if !ok || selector == "" {
+		return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("selector must be a string: %v", args["selector"]), "")), nil
+	}
+	paths, err := parseUploadPaths(args)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ArgumentError(err)), nil
+	}
+	files := make([]string, 0, len(paths))
+	for _, p := range paths {
+		realPath, err := bs.validateUploadPath(p)
+		if err != nil {
+			return bs.toolError(ctx, request, comm.ArgumentError(err)), nil
+		}
+		files = append(files, realPath)
+	}
+
+	bs.Logger.Debug().Str("selector", selector).Strs("files", files).Msg("尝试上传文件")
+	runCtx, cancelFunc := context.WithTimeout(bs.pageContext(ctx), time.Duration(bs.cfg().SelectorQueryTimeout)*time.Second)
+	defer cancelFunc()
+
+	// 文件输入框常被样式按钮隐藏，只等待元素存在，不要求可见
+	var nodes []*cdp.Node
+	if err := chromedp.Run(runCtx, chromedp.Nodes(selector, &nodes, chromedp.ByQuery)); err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr(fmt.Sprintf("failed to find file input %s", selector), err)), nil
+	}
+	if err := checkFileInput(nodes[0], len(files)); err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr(fmt.Sprintf("%s", selector), err)), nil
+	}
+
+	selectorJSON, _ := json.Marshal(selector)
+	var names []string
+	err = chromedp.Run(runCtx,
+		dom.SetFileInputFiles(files).WithNodeID(nodes[0].NodeID),
+		chromedp.Evaluate(fmt.Sprintf(`Array.from(document.querySelector(%s).files || []).map(f => f.name)`, selectorJSON), &names),
+	)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr(fmt.Sprintf("failed to upload files to %s", selector), err)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Uploaded %d file(s) to %s, the input now holds: %s", len(files), selector, strings.Join(names, ", "))), nil
+}
@@ -0,0 +1,184 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// browser_wait_for 的 state 取值
+const (
+	WaitStateVisible  = "visible"  // 元素存在且可见
+	WaitStateHidden   = "hidden"   // 元素不存在或不可见
+	WaitStateAttached = "attached" // 元素存在于 DOM 中，不要求可见
+	WaitStateDetached = "detached" // 元素从 DOM 中移除
+)
+
+// waitTextJS reports whether the first element matching the selector contains the text.
+const waitTextJS = `(function() {
+	var el = document.querySelector(%s);
+	return !!el && (el.innerText || el.textContent || "").indexOf(%s) >= 0;
+})()`
+
+// waitForOptions are the arguments of browser_wait_for.
+type waitForOptions struct {
+	Selector string
+	State    string
+	Text     string
+	Timeout  time.Duration
+}
+
+// parseWaitForOptions validates the arguments of browser_wait_for, the timeout defaults to
+// selectorTimeout seconds.
+func parseWaitForOptions(args map[string]interface{}, selectorTimeout int) (waitForOptions, error) {
+	opts := waitForOptions{State: WaitStateVisible, Timeout: time.Duration(selectorTimeout) * time.Second}
+	selector, _ := args["selector"].(string)
+	if opts.Selector = strings.TrimSpace(selector); opts.Selector == "" {
+		return opts, errors.New("selector must be a non-empty string")
+	}
+	if v, ok := args["state"]; ok {
+		state, _ := v.(string)
+		switch state {
+		case WaitStateVisible, WaitStateHidden, WaitStateAttached, WaitStateDetached:
+			opts.State = state
+		default:
+			return opts, fmt.Errorf("state must be one of %s, %s, %s or %s", WaitStateVisible, WaitStateHidden, WaitStateAttached, WaitStateDetached)
+		}
+	}
+	if v, ok := args["text"]; ok {
+		text, ok := v.(string)
+		if !ok {
+			return opts, errors.New("text must be a string")
+		}
+		opts.Text = text
+	}
+	// 隐藏或移除的元素里没有可等待的文本
+	if opts.Text != "" && (opts.State == WaitStateHidden || opts.State == WaitStateDetached) {
+		return opts, fmt.Errorf("text can only be used with the %s or %s state", WaitStateVisible, WaitStateAttached)
+	}
+	if v, ok := args["timeout_seconds"]; ok {
+		seconds, ok := v.(float64)
+		if !ok || seconds <= 0 {
+			return opts, errors.New("timeout_seconds must be a positive number")
+		}
+		opts.Timeout = time.Duration(seconds * float64(time.Second))
+	}
+	return opts, nil
+}
+
+// condition describes what browser_wait_for waits for, used in the result and the errors.
+func (opts waitForOptions) condition() string {
+	condition := fmt.Sprintf("selector %s to be %s", opts.Selector, opts.State)
+	if opts.Text != "" {
+		condition += fmt.Sprintf(" and contain %q", opts.Text)
+	}
+	return condition
+}
+
+// waitStateAction returns the chromedp action that waits for the state of the selector.
+func waitStateAction(opts waitForOptions) chromedp.Action {
+	switch opts.State {
+	case WaitStateHidden:
+		return chromedp.WaitNotVisible(opts.Selector, chromedp.ByQuery)
+	case WaitStateAttached:
+		return chromedp.WaitReady(opts.Selector, chromedp.ByQuery)
+	case WaitStateDetached:
+		return chromedp.WaitNotPresent(opts.Selector, chromedp.ByQuery)
+	default:
+		return chromedp.WaitVisible(opts.Selector, chromedp.ByQuery)
+	}
+}
+
+// waitText polls the page until the element contains the text.
+func (bs *BrowserServer) waitText(ctx context.Context, opts waitForOptions) error {
+	selector, err := json.Marshal(opts.Selector)
+	if err != nil {
+		return err
+	}
+	text, err := json.Marshal(opts.Text)
+	if err != nil {
+		return err
+	}
+	script := fmt.Sprintf(waitTextJS, selector, text)
+	for {
+		var found bool
+		if err := bs.runner.Evaluate(ctx, script, &found); err != nil {
+			return err
+		}
+		if found {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(navigatePollInterval):
+		}
+	}
+}
+
+// addWaitForTool registers browser_wait_for.
+func (bs *BrowserServer) addWaitForTool() {
+	bs.addTool(mcp.NewTool(
+		"browser_wait_for",
+		mcp.WithDescription("Wait until an element is visible, hidden, attached to or detached from the page, optionally until it contains a text. Returns how long it waited"),
+		mcp.WithString("selector",
+			mcp.Description("CSS selector of the element to wait for"),
+			mcp.Required(),
+		),
+		mcp.WithString("state",
+			mcp.Description("State to wait for: visible, hidden (missing or not visible), attached (in the DOM) or detached (removed from the DOM), default visible"),
+			mcp.Enum(WaitStateVisible, WaitStateHidden, WaitStateAttached, WaitStateDetached),
+		),
+		mcp.WithString("text",
+			mcp.Description("Text the element must contain, only with the visible or attached state"),
+		),
+		mcp.WithNumber("timeout_seconds",
+			mcp.Description("Maximum time to wait in seconds (default: selector_query_timeout)"),
+		),
+	), bs.handleWaitFor)
+}
+
+func (bs *BrowserServer) handleWaitFor(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	opts, err := parseWaitForOptions(request.GetArguments(), bs.cfg().SelectorQueryTimeout)
+	if err != nil {
+		return bs.toolError(ctx, request, comm.ArgumentError(err)), nil
+	}
+
+	runCtx, cancel := context.WithTimeout(bs.pageContext(ctx), opts.Timeout)
+	defer cancel()
+	start := time.Now()
+	err = bs.runner.Run(runCtx, waitStateAction(opts))
+	if err == nil && opts.Text != "" {
+		err = bs.waitText(runCtx, opts)
+	}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || runCtx.Err() != nil {
+			return bs.toolError(ctx, request, comm.ToolError(comm.ErrCodeTimeout, fmt.Sprintf("timed out after %s waiting for %s", opts.Timeout, opts.condition()), "")), nil
+		}
+		return bs.toolError(ctx, request, comm.ToolErrorFromErr(fmt.Sprintf("failed to wait for %s", opts.condition()), err)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Waited %s for %s", time.Since(start).Round(time.Millisecond), opts.condition())), nil
+}
@@ -0,0 +1,207 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+// Package clipboard provides tools for reading and writing the system clipboard.
+package clipboard
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/services/abstract"
+	"github.com/gojue/moling/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	ClipboardServerName comm.MoLingServerType = "Clipboard"
+)
+
+// ClipboardServer implements the Service interface and provides the clipboard_* tools.
+type ClipboardServer struct {
+	abstract.MLService
+	config     *ClipboardConfig // 重新加载时整体替换，处理函数通过 cfg 读取
+	configLock sync.RWMutex     // 保护 config
+	backend    Backend          // 系统剪贴板，测试时可替换
+}
+
+// NewClipboardServer creates a new ClipboardServer with the default configuration and the
+// clipboard backend of the current OS.
+func NewClipboardServer(ctx context.Context) (abstract.Service, error) {
+	base, err := abstract.NewServiceBase(ctx, ClipboardServerName)
+	if err != nil {
+		return nil, err
+	}
+
+	cs := &ClipboardServer{
+		MLService: base,
+		config:    NewClipboardConfig(),
+		backend:   newBackend(),
+	}
+	if err := cs.InitResources(); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// RegisterTools registers the prompt and the tools of the clipboard service.
+func (cs *ClipboardServer) RegisterTools() error {
+	cs.AddPrompt(abstract.PromptEntry{
+		PromptVar: mcp.Prompt{
+			Name:        "clipboard_prompt",
+			Description: "Get the relevant functions and prompts of the Clipboard MCP Server.",
+		},
+		HandlerFunc: cs.handlePrompt,
+	})
+
+	cs.AddTool(mcp.NewTool(
+		"clipboard_read",
+		mcp.WithDescription("Read the system clipboard. Returns the text, or the image as base64 PNG when the clipboard holds an image and allow_image is enabled in the configuration. Text longer than max_read_size is truncated."),
+	), cs.handleRead)
+
+	cs.AddTool(mcp.NewTool(
+		"clipboard_write",
+		mcp.WithDescription("Copy text to the system clipboard, replacing its content, so the user can paste it into another application."),
+		mcp.WithString("text",
+			mcp.Description("Text to copy to the clipboard"),
+			mcp.Required(),
+		),
+	), cs.handleWrite)
+	return nil
+}
+
+func (cs *ClipboardServer) handlePrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	return &mcp.GetPromptResult{
+		Description: "",
+		Messages: []mcp.PromptMessage{
+			{
+				Role: mcp.RoleUser,
+				Content: mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf(cs.cfg().prompt, cs.MlConfig().SystemInfo),
+				},
+			},
+		},
+	}, nil
+}
+
+func (cs *ClipboardServer) handleRead(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if !cs.cfg().AllowRead {
+		return comm.ToolError(comm.ErrCodePermissionDenied, "reading the clipboard is disabled, set allow_read to true in the Clipboard configuration to enable it", ""), nil
+	}
+
+	if cs.cfg().AllowImage {
+		img, err := cs.backend.ReadImage(ctx)
+		switch {
+		case err == nil:
+			// 截断的图片无法解码，超出大小限制时直接拒绝
+			if len(img) > cs.cfg().MaxReadSize {
+				return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("the clipboard image is %d bytes, larger than max_read_size (%d bytes)", len(img), cs.cfg().MaxReadSize), ""), nil
+			}
+			cs.Logger.Debug().Int("bytes", len(img)).Msg("clipboard image read")
+			return mcp.NewToolResultImage(fmt.Sprintf("Clipboard image (PNG, %d bytes)", len(img)),
+				base64.StdEncoding.EncodeToString(img), "image/png"), nil
+		case !errors.Is(err, ErrNoImage):
+			return comm.ToolErrorFromErr("failed to read the clipboard image", err), nil
+		}
+	}
+
+	text, err := cs.backend.ReadText(ctx)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to read the clipboard", err), nil
+	}
+	if text == "" {
+		return mcp.NewToolResultText("The clipboard is empty or holds no text."), nil
+	}
+	cs.Logger.Debug().Int("bytes", len(text)).Msg("clipboard text read")
+	if len(text) > cs.cfg().MaxReadSize {
+		return mcp.NewToolResultText(fmt.Sprintf("%s\n\n[clipboard content truncated: showing %d of %d bytes]",
+			truncateUTF8(text, cs.cfg().MaxReadSize), cs.cfg().MaxReadSize, len(text))), nil
+	}
+	return mcp.NewToolResultText(text), nil
+}
+
+func (cs *ClipboardServer) handleWrite(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	text, ok := request.GetArguments()["text"].(string)
+	if !ok {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, "text must be a string", ""), nil
+	}
+	if err := cs.backend.WriteText(ctx, text); err != nil {
+		return comm.ToolErrorFromErr("failed to write the clipboard", err), nil
+	}
+	// 只记录长度，不记录剪贴板内容
+	cs.Logger.Info().Int("bytes", len(text)).Msg("clipboard written")
+	return mcp.NewToolResultText(fmt.Sprintf("Copied %d characters to the clipboard", utf8.RuneCountInString(text))), nil
+}
+
+// truncateUTF8 truncates s to at most n bytes without splitting a UTF-8 character.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func (cs *ClipboardServer) Config() string {
+	cfg, err := json.Marshal(cs.cfg())
+	if err != nil {
+		cs.Logger.Err(err).Msg("failed to marshal config")
+		return "{}"
+	}
+	return string(cfg)
+}
+
+func (cs *ClipboardServer) Name() comm.MoLingServerType {
+	return ClipboardServerName
+}
+
+func (cs *ClipboardServer) Close() error {
+	cs.Logger.Debug().Msg("ClipboardServer closed")
+	return nil
+}
+
+// LoadConfig loads the configuration from a JSON object.
+func (cs *ClipboardServer) LoadConfig(jsonData map[string]interface{}) error {
+	// 在副本上合并和校验，校验通过后才替换正在使用的配置
+	next := *cs.cfg()
+	err := utils.MergeJSONToStruct(&next, jsonData)
+	if err != nil {
+		return err
+	}
+	if err := next.Check(); err != nil {
+		return err
+	}
+	cs.configLock.Lock()
+	cs.config = &next
+	cs.configLock.Unlock()
+	return nil
+}
+
+// cfg returns the running config, LoadConfig replaces it as a whole instead of changing it.
+func (cs *ClipboardServer) cfg() *ClipboardConfig {
+	cs.configLock.RLock()
+	defer cs.configLock.RUnlock()
+	return cs.config
+}
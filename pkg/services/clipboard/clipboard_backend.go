@@ -0,0 +1,71 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package clipboard
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+)
+
+// commandTimeout is the timeout of a clipboard command.
+const commandTimeout = 5 * time.Second
+
+var (
+	// ErrNoImage is returned by ReadImage when the clipboard holds no image.
+	ErrNoImage = errors.New("the clipboard holds no image")
+	// ErrNoClipboardTool is returned when no clipboard tool is installed.
+	ErrNoClipboardTool = comm.NewCodedError(comm.ErrCodeNotFound, errors.New("no clipboard tool found"))
+)
+
+// Backend reads and writes the system clipboard, implemented per OS and replaced in tests.
+type Backend interface {
+	ReadText(ctx context.Context) (string, error)     // ReadText returns the text on the clipboard, empty when it holds no text.
+	ReadImage(ctx context.Context) ([]byte, error)    // ReadImage returns the image on the clipboard as PNG, or ErrNoImage.
+	WriteText(ctx context.Context, text string) error // WriteText replaces the clipboard content with text.
+}
+
+// runCommand runs a clipboard command with stdin and returns its stdout, stderr is added to the error.
+func runCommand(ctx context.Context, stdin []byte, args []string, env ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	if len(env) > 0 {
+		cmd.Env = append(cmd.Environ(), env...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// xclip、wl-copy 写入后在后台持有剪贴板，进程退出后不再等待其继承的输出
+	cmd.WaitDelay = time.Second
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", args[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
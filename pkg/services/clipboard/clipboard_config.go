@@ -0,0 +1,69 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package clipboard
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	// ClipboardPromptDefault is the default prompt for the clipboard service.
+	ClipboardPromptDefault = `
+You are a clipboard assistant running on %s. You can read and write the system clipboard of the user. Your capabilities include:
+
+1. **Read the Clipboard**: Get the text the user copied, for example to summarize, translate or reformat it. When enabled in the configuration, images on the clipboard are returned as PNG.
+
+2. **Write the Clipboard**: Copy text such as a summary, a table or a draft to the clipboard, so the user can paste it into Word, Excel, mail or any other application.
+
+Reading may be disabled by the user for privacy, do not try to work around it. Tell the user after writing, since the previous clipboard content is replaced.
+`
+)
+
+// ClipboardConfig represents the configuration for the clipboard service.
+type ClipboardConfig struct {
+	PromptFile  string `json:"prompt_file"` // PromptFile is the prompt file for the clipboard service.
+	prompt      string
+	AllowRead   bool `json:"allow_read"`    // AllowRead enables clipboard_read, disable it to keep the clipboard private.
+	AllowImage  bool `json:"allow_image"`   // AllowImage lets clipboard_read return an image on the clipboard as base64 PNG.
+	MaxReadSize int  `json:"max_read_size"` // MaxReadSize is the maximum size in bytes returned by clipboard_read, longer text is truncated and larger images are rejected.
+}
+
+// NewClipboardConfig creates a new ClipboardConfig with default values.
+func NewClipboardConfig() *ClipboardConfig {
+	return &ClipboardConfig{
+		prompt:      ClipboardPromptDefault,
+		AllowRead:   true,
+		MaxReadSize: 1024 * 1024, // 1MB
+	}
+}
+
+// Check validates the ClipboardConfig.
+func (cc *ClipboardConfig) Check() error {
+	cc.prompt = ClipboardPromptDefault
+	if cc.MaxReadSize <= 0 {
+		return fmt.Errorf("max read size must be greater than 0")
+	}
+	if cc.PromptFile != "" {
+		read, err := os.ReadFile(cc.PromptFile)
+		if err != nil {
+			return fmt.Errorf("failed to read prompt file:%s, error: %v", cc.PromptFile, err)
+		}
+		cc.prompt = string(read)
+	}
+	return nil
+}
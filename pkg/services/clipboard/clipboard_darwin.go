@@ -0,0 +1,72 @@
+//go:build darwin
+
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package clipboard
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// utf8Env makes pbcopy and pbpaste use UTF-8 regardless of the locale of MoLing.
+const utf8Env = "LANG=en_US.UTF-8"
+
+// pasteboardBackend uses pbcopy and pbpaste for text, and AppleScript for images.
+type pasteboardBackend struct{}
+
+func newBackend() Backend {
+	return pasteboardBackend{}
+}
+
+func (pasteboardBackend) ReadText(ctx context.Context) (string, error) {
+	out, err := runCommand(ctx, nil, []string{"pbpaste"}, utf8Env)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func (pasteboardBackend) ReadImage(ctx context.Context) ([]byte, error) {
+	info, err := runCommand(ctx, nil, []string{"osascript", "-e", "clipboard info"})
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(string(info), "PNGf") {
+		return nil, ErrNoImage
+	}
+	// 输出形如 «data PNGf89504E47...»
+	out, err := runCommand(ctx, nil, []string{"osascript", "-e", "the clipboard as «class PNGf»"})
+	if err != nil {
+		return nil, err
+	}
+	s := strings.TrimSpace(string(out))
+	s = strings.TrimPrefix(s, "«data PNGf")
+	s = strings.TrimSuffix(s, "»")
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the clipboard image: %w", err)
+	}
+	return data, nil
+}
+
+func (pasteboardBackend) WriteText(ctx context.Context, text string) error {
+	_, err := runCommand(ctx, []byte(text), []string{"pbcopy"}, utf8Env)
+	return err
+}
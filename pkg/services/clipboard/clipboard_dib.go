@@ -0,0 +1,101 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package clipboard
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+const (
+	biRGB       = 0 // 未压缩
+	biBitFields = 3 // 按颜色掩码存储，32 位时与未压缩相同
+)
+
+// dibToPNG converts an uncompressed 24 or 32 bit device independent bitmap, the CF_DIB clipboard
+// format of Windows, to PNG.
+func dibToPNG(dib []byte) ([]byte, error) {
+	if len(dib) < 40 {
+		return nil, fmt.Errorf("invalid bitmap: header too short")
+	}
+	headerSize := int(binary.LittleEndian.Uint32(dib[0:4]))
+	width := int(int32(binary.LittleEndian.Uint32(dib[4:8])))
+	height := int(int32(binary.LittleEndian.Uint32(dib[8:12])))
+	bitCount := int(binary.LittleEndian.Uint16(dib[14:16]))
+	compression := binary.LittleEndian.Uint32(dib[16:20])
+	if headerSize < 40 || headerSize > len(dib) {
+		return nil, fmt.Errorf("invalid bitmap: header size %d", headerSize)
+	}
+	if bitCount != 24 && bitCount != 32 {
+		return nil, fmt.Errorf("unsupported bitmap: %d bits per pixel", bitCount)
+	}
+	if compression != biRGB && !(compression == biBitFields && bitCount == 32) {
+		return nil, fmt.Errorf("unsupported bitmap compression %d", compression)
+	}
+
+	// 高度为负时按从上到下存储
+	topDown := height < 0
+	if topDown {
+		height = -height
+	}
+	if width <= 0 || height <= 0 || width > 1<<15 || height > 1<<15 {
+		return nil, fmt.Errorf("invalid bitmap size %dx%d", width, height)
+	}
+	offset := headerSize
+	if compression == biBitFields && headerSize == 40 {
+		offset += 12 // 颜色掩码紧跟在 BITMAPINFOHEADER 之后
+	}
+	stride := (width*bitCount + 31) / 32 * 4
+	if len(dib) < offset+stride*height {
+		return nil, fmt.Errorf("invalid bitmap: pixel data too short")
+	}
+
+	bytesPerPixel := bitCount / 8
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	hasAlpha := false
+	for y := 0; y < height; y++ {
+		row := dib[offset+stride*y:]
+		if !topDown {
+			row = dib[offset+stride*(height-1-y):]
+		}
+		for x := 0; x < width; x++ {
+			p := row[x*bytesPerPixel:]
+			c := color.NRGBA{R: p[2], G: p[1], B: p[0], A: 0xff}
+			if bytesPerPixel == 4 {
+				c.A = p[3]
+				hasAlpha = hasAlpha || p[3] != 0
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	// 多数程序写入的 32 位位图 alpha 全为 0，按不透明处理
+	if bitCount == 32 && !hasAlpha {
+		for i := 3; i < len(img.Pix); i += 4 {
+			img.Pix[i] = 0xff
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
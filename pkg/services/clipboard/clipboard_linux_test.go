@@ -0,0 +1,77 @@
+//go:build linux
+
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package clipboard
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestToolDetection(t *testing.T) {
+	backend := func(env map[string]string, installed ...string) *commandBackend {
+		return &commandBackend{
+			lookPath: func(name string) (string, error) {
+				for _, tool := range installed {
+					if tool == name {
+						return "/usr/bin/" + name, nil
+					}
+				}
+				return "", exec.ErrNotFound
+			},
+			getenv: func(key string) string { return env[key] },
+		}
+	}
+	wayland := map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"}
+	x11 := map[string]string{"DISPLAY": ":0"}
+
+	tests := []struct {
+		name    string
+		backend *commandBackend
+		want    string
+		wantErr string
+	}{
+		{"Wayland", backend(wayland, "wl-paste", "xclip"), "wl-clipboard", ""},
+		{"XWayland", backend(wayland, "xclip"), "xclip", ""},
+		{"X11", backend(x11, "wl-paste", "xclip", "xsel"), "xclip", ""},
+		{"Xsel", backend(x11, "xsel"), "xsel", ""},
+		{"WaylandMissing", backend(wayland), "", "install wl-clipboard"},
+		{"X11Missing", backend(x11, "wl-paste"), "", "install xclip or xsel"},
+		{"NoDisplay", backend(nil, "xclip"), "", "graphical session"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool, err := tt.backend.tool()
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrNoClipboardTool) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected an error naming %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || tool.name != tt.want {
+				t.Errorf("Expected %s, got %s (%v)", tt.want, tool.name, err)
+			}
+		})
+	}
+}
+
+func TestClipboardIntegration(t *testing.T) {
+	testRoundTrip(t, newBackend())
+}
@@ -0,0 +1,283 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package clipboard
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// fakeBackend is an in-memory clipboard.
+type fakeBackend struct {
+	text  string
+	image []byte
+	err   error
+	reads int
+}
+
+func (b *fakeBackend) ReadText(context.Context) (string, error) {
+	b.reads++
+	return b.text, b.err
+}
+
+func (b *fakeBackend) ReadImage(context.Context) ([]byte, error) {
+	b.reads++
+	if b.err != nil {
+		return nil, b.err
+	}
+	if b.image == nil {
+		return nil, ErrNoImage
+	}
+	return b.image, nil
+}
+
+func (b *fakeBackend) WriteText(_ context.Context, text string) error {
+	if b.err != nil {
+		return b.err
+	}
+	b.text, b.image = text, nil
+	return nil
+}
+
+func newTestServer(t *testing.T, config map[string]interface{}) (*ClipboardServer, *fakeBackend) {
+	t.Helper()
+	_, ctx, err := comm.InitTestEnv()
+	if err != nil {
+		t.Fatalf("Failed to initialize test environment: %v", err)
+	}
+	svc, err := NewClipboardServer(ctx)
+	if err != nil {
+		t.Fatalf("Failed to create ClipboardServer: %v", err)
+	}
+	cs := svc.(*ClipboardServer)
+	if config != nil {
+		if err := cs.LoadConfig(config); err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+	}
+	backend := &fakeBackend{}
+	cs.backend = backend
+	return cs, backend
+}
+
+// call calls a tool handler and returns its result.
+func call(t *testing.T, handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), args map[string]interface{}) *mcp.CallToolResult {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = args
+	result, err := handler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return result
+}
+
+func resultText(result *mcp.CallToolResult) string {
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			return text.Text
+		}
+	}
+	return ""
+}
+
+func TestClipboardTools(t *testing.T) {
+	t.Run("WriteAndRead", func(t *testing.T) {
+		cs, backend := newTestServer(t, nil)
+		result := call(t, cs.handleWrite, map[string]interface{}{"text": "季度报告"})
+		if result.IsError || backend.text != "季度报告" {
+			t.Fatalf("Expected the text on the clipboard, got %q: %s", backend.text, resultText(result))
+		}
+		if !strings.Contains(resultText(result), "4 characters") {
+			t.Errorf("Expected the character count, got %s", resultText(result))
+		}
+		if text := resultText(call(t, cs.handleRead, nil)); text != "季度报告" {
+			t.Errorf("Expected the clipboard text, got %q", text)
+		}
+		if result := call(t, cs.handleWrite, nil); !result.IsError {
+			t.Errorf("Expected an error without text")
+		}
+	})
+
+	t.Run("Truncate", func(t *testing.T) {
+		cs, backend := newTestServer(t, map[string]interface{}{"max_read_size": float64(5)})
+		backend.text = "ab中文字"
+		text := resultText(call(t, cs.handleRead, nil))
+		// 不截断在 UTF-8 字符中间
+		if !strings.HasPrefix(text, "ab中\n\n") || !strings.Contains(text, "showing 5 of 11 bytes") {
+			t.Errorf("Expected the truncated text with a note, got %q", text)
+		}
+	})
+
+	t.Run("ReadDisabled", func(t *testing.T) {
+		cs, backend := newTestServer(t, map[string]interface{}{"allow_read": false, "allow_image": true})
+		backend.text = "secret"
+		result := call(t, cs.handleRead, nil)
+		if payload, ok := comm.ParseToolError(result); !ok || payload.Code != comm.ErrCodePermissionDenied || !strings.Contains(payload.Message, "allow_read") {
+			t.Errorf("Expected reading to be disabled, got %s", resultText(result))
+		}
+		if backend.reads != 0 {
+			t.Errorf("Expected the clipboard not to be read, got %d reads", backend.reads)
+		}
+		// 禁止读取不影响写入
+		if result := call(t, cs.handleWrite, map[string]interface{}{"text": "x"}); result.IsError {
+			t.Errorf("Expected writing to stay enabled, got %s", resultText(result))
+		}
+	})
+
+	t.Run("Image", func(t *testing.T) {
+		png := []byte("\x89PNG\r\n\x1a\nfake")
+		cs, backend := newTestServer(t, nil)
+		backend.text, backend.image = "caption", png
+		// 默认不返回图片
+		if text := resultText(call(t, cs.handleRead, nil)); text != "caption" {
+			t.Errorf("Expected the text when images are not allowed, got %q", text)
+		}
+
+		cs, backend = newTestServer(t, map[string]interface{}{"allow_image": true})
+		backend.image = png
+		result := call(t, cs.handleRead, nil)
+		var image *mcp.ImageContent
+		for _, content := range result.Content {
+			if c, ok := content.(mcp.ImageContent); ok {
+				image = &c
+			}
+		}
+		if image == nil || image.MIMEType != "image/png" || image.Data != base64.StdEncoding.EncodeToString(png) {
+			t.Fatalf("Expected the image as base64 PNG, got %#v", result.Content)
+		}
+
+		cs, backend = newTestServer(t, map[string]interface{}{"allow_image": true, "max_read_size": float64(4)})
+		backend.image = png
+		if result := call(t, cs.handleRead, nil); !result.IsError || !strings.Contains(resultText(result), "max_read_size") {
+			t.Errorf("Expected oversized images to be rejected, got %s", resultText(result))
+		}
+	})
+
+	t.Run("BackendError", func(t *testing.T) {
+		cs, backend := newTestServer(t, map[string]interface{}{"allow_image": true})
+		backend.err = ErrNoClipboardTool
+		for _, result := range []*mcp.CallToolResult{
+			call(t, cs.handleRead, nil),
+			call(t, cs.handleWrite, map[string]interface{}{"text": "x"}),
+		} {
+			if !result.IsError || !strings.Contains(resultText(result), ErrNoClipboardTool.Error()) {
+				t.Errorf("Expected the backend error, got %s", resultText(result))
+			}
+		}
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cs, _ := newTestServer(t, nil)
+		if err := cs.LoadConfig(map[string]interface{}{"max_read_size": float64(0)}); err == nil {
+			t.Errorf("Expected max_read_size 0 to be rejected")
+		}
+	})
+}
+
+func TestDIBToPNG(t *testing.T) {
+	// 2x2 的 24 位自下而上位图，每行补齐到 4 字节
+	header := []byte{
+		40, 0, 0, 0, // biSize
+		2, 0, 0, 0, // biWidth
+		2, 0, 0, 0, // biHeight
+		1, 0, 24, 0, // biPlanes, biBitCount
+		0, 0, 0, 0, // biCompression
+	}
+	header = append(header, make([]byte, 20)...)
+	pixels := []byte{
+		0, 0, 255, 0, 255, 0, 0, 0, // 底行：红、绿
+		255, 0, 0, 255, 255, 255, 0, 0, // 顶行：蓝、白
+	}
+	data, err := dibToPNG(append(header, pixels...))
+	if err != nil {
+		t.Fatalf("Failed to convert bitmap: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Expected a PNG, got %v", err)
+	}
+	for _, tt := range []struct {
+		x, y    int
+		r, g, b uint32
+	}{
+		{0, 0, 0, 0, 0xffff}, {1, 0, 0xffff, 0xffff, 0xffff},
+		{0, 1, 0xffff, 0, 0}, {1, 1, 0, 0xffff, 0},
+	} {
+		r, g, b, a := img.At(tt.x, tt.y).RGBA()
+		if r != tt.r || g != tt.g || b != tt.b || a != 0xffff {
+			t.Errorf("Pixel (%d,%d): expected %x/%x/%x, got %x/%x/%x/%x", tt.x, tt.y, tt.r, tt.g, tt.b, r, g, b, a)
+		}
+	}
+
+	if _, err := dibToPNG(header[:20]); err == nil {
+		t.Errorf("Expected a truncated bitmap to be rejected")
+	}
+	paletted := append([]byte(nil), header...)
+	paletted[14] = 8
+	if _, err := dibToPNG(append(paletted, pixels...)); err == nil {
+		t.Errorf("Expected 8 bit bitmaps to be rejected")
+	}
+}
+
+// testRoundTrip writes to the real clipboard and reads it back, the previous text is restored.
+func testRoundTrip(t *testing.T, backend Backend) {
+	t.Helper()
+	ctx := context.Background()
+	previous, err := backend.ReadText(ctx)
+	if errors.Is(err, ErrNoClipboardTool) {
+		t.Skipf("Clipboard not available: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("Failed to read the clipboard: %v", err)
+	}
+	t.Cleanup(func() { _ = backend.WriteText(ctx, previous) })
+
+	want := "MoLing 剪贴板测试\nline 2"
+	if err := backend.WriteText(ctx, want); err != nil {
+		t.Fatalf("Failed to write the clipboard: %v", err)
+	}
+	got, err := backend.ReadText(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read the clipboard: %v", err)
+	}
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if _, err := backend.ReadImage(ctx); !errors.Is(err, ErrNoImage) {
+		t.Errorf("Expected no image after writing text, got %v", err)
+	}
+}
+
+func TestReloadConfigKeepsRunningOnError(t *testing.T) {
+	cs, _ := newTestServer(t, map[string]interface{}{"allow_read": false})
+	running := cs.cfg()
+	if err := cs.LoadConfig(map[string]interface{}{"allow_read": true, "max_read_size": float64(0)}); err == nil {
+		t.Fatal("Expected max_read_size 0 to be rejected")
+	}
+	if cs.cfg() != running || running.AllowRead {
+		t.Errorf("Expected the running config to be kept, got %+v", cs.cfg())
+	}
+}
@@ -0,0 +1,160 @@
+//go:build !darwin && !windows
+
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package clipboard
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// clipboardTool is a command line clipboard tool of Wayland or X11.
+type clipboardTool struct {
+	name      string
+	readText  []string
+	writeText []string
+	listTypes []string // 为空时不支持读取图片
+	readImage []string
+}
+
+var (
+	wlClipboard = clipboardTool{
+		name:      "wl-clipboard",
+		readText:  []string{"wl-paste", "--no-newline"},
+		writeText: []string{"wl-copy"},
+		listTypes: []string{"wl-paste", "--list-types"},
+		readImage: []string{"wl-paste", "--type", "image/png"},
+	}
+	xclip = clipboardTool{
+		name:      "xclip",
+		readText:  []string{"xclip", "-selection", "clipboard", "-out"},
+		writeText: []string{"xclip", "-selection", "clipboard", "-in"},
+		listTypes: []string{"xclip", "-selection", "clipboard", "-target", "TARGETS", "-out"},
+		readImage: []string{"xclip", "-selection", "clipboard", "-target", "image/png", "-out"},
+	}
+	xsel = clipboardTool{
+		name:      "xsel",
+		readText:  []string{"xsel", "--clipboard", "--output"},
+		writeText: []string{"xsel", "--clipboard", "--input"},
+	}
+)
+
+// commandBackend uses wl-clipboard on Wayland, and xclip or xsel on X11.
+type commandBackend struct {
+	lookPath func(string) (string, error)
+	getenv   func(string) string
+}
+
+func newBackend() Backend {
+	return &commandBackend{lookPath: exec.LookPath, getenv: os.Getenv}
+}
+
+// tool detects the clipboard tool of the current session on every call, so that a tool installed
+// while MoLing is running is picked up.
+func (b *commandBackend) tool() (clipboardTool, error) {
+	var candidates []clipboardTool
+	if b.getenv("WAYLAND_DISPLAY") != "" {
+		candidates = append(candidates, wlClipboard)
+	}
+	// XWayland 下 xclip 同样可用
+	if b.getenv("DISPLAY") != "" {
+		candidates = append(candidates, xclip, xsel)
+	}
+	if len(candidates) == 0 {
+		return clipboardTool{}, fmt.Errorf("%w: neither WAYLAND_DISPLAY nor DISPLAY is set, the clipboard needs a graphical session", ErrNoClipboardTool)
+	}
+	for _, tool := range candidates {
+		if _, err := b.lookPath(tool.readText[0]); err == nil {
+			return tool, nil
+		}
+	}
+	if b.getenv("WAYLAND_DISPLAY") != "" {
+		return clipboardTool{}, fmt.Errorf("%w: install wl-clipboard (wl-copy and wl-paste), or xclip for XWayland", ErrNoClipboardTool)
+	}
+	return clipboardTool{}, fmt.Errorf("%w: install xclip or xsel", ErrNoClipboardTool)
+}
+
+func (b *commandBackend) ReadText(ctx context.Context) (string, error) {
+	tool, err := b.tool()
+	if err != nil {
+		return "", err
+	}
+	out, err := runCommand(ctx, nil, tool.readText)
+	if err != nil {
+		// 剪贴板为空或不含文本时，wl-paste 和 xclip 以错误退出
+		if isEmptyClipboard(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return string(out), nil
+}
+
+func (b *commandBackend) ReadImage(ctx context.Context) ([]byte, error) {
+	tool, err := b.tool()
+	if err != nil {
+		return nil, err
+	}
+	if tool.listTypes == nil {
+		return nil, ErrNoImage
+	}
+	types, err := runCommand(ctx, nil, tool.listTypes)
+	if err != nil {
+		if isEmptyClipboard(err) {
+			return nil, ErrNoImage
+		}
+		return nil, err
+	}
+	if !hasLine(string(types), "image/png") {
+		return nil, ErrNoImage
+	}
+	return runCommand(ctx, nil, tool.readImage)
+}
+
+func (b *commandBackend) WriteText(ctx context.Context, text string) error {
+	tool, err := b.tool()
+	if err != nil {
+		return err
+	}
+	_, err = runCommand(ctx, []byte(text), tool.writeText)
+	return err
+}
+
+// isEmptyClipboard reports whether a read failed because the clipboard holds nothing readable.
+func isEmptyClipboard(err error) bool {
+	msg := err.Error()
+	for _, s := range []string{"Nothing is copied", "No selection", "not available", "No suitable type"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasLine reports whether s contains line as a whole line.
+func hasLine(s, line string) bool {
+	for _, l := range strings.Split(s, "\n") {
+		if strings.TrimSpace(l) == line {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+//go:build windows
+
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package clipboard
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	cfDIB         = 8
+	cfUnicodeText = 13
+	gmemMoveable  = 0x0002
+)
+
+var (
+	user32   = syscall.NewLazyDLL("user32.dll")
+	kernel32 = syscall.NewLazyDLL("kernel32.dll")
+
+	procOpenClipboard              = user32.NewProc("OpenClipboard")
+	procCloseClipboard             = user32.NewProc("CloseClipboard")
+	procEmptyClipboard             = user32.NewProc("EmptyClipboard")
+	procGetClipboardData           = user32.NewProc("GetClipboardData")
+	procSetClipboardData           = user32.NewProc("SetClipboardData")
+	procIsClipboardFormatAvailable = user32.NewProc("IsClipboardFormatAvailable")
+	procRegisterClipboardFormatW   = user32.NewProc("RegisterClipboardFormatW")
+	procGlobalAlloc                = kernel32.NewProc("GlobalAlloc")
+	procGlobalFree                 = kernel32.NewProc("GlobalFree")
+	procGlobalLock                 = kernel32.NewProc("GlobalLock")
+	procGlobalUnlock               = kernel32.NewProc("GlobalUnlock")
+	procGlobalSize                 = kernel32.NewProc("GlobalSize")
+)
+
+// winBackend uses the clipboard API of user32.
+type winBackend struct{}
+
+func newBackend() Backend {
+	return winBackend{}
+}
+
+// openClipboard opens the clipboard on a locked OS thread, retrying while another application holds
+// it. The returned function closes it.
+func openClipboard(ctx context.Context) (func(), error) {
+	runtime.LockOSThread()
+	for i := 0; ; i++ {
+		r, _, err := procOpenClipboard.Call(0)
+		if r != 0 {
+			return func() {
+				_, _, _ = procCloseClipboard.Call()
+				runtime.UnlockOSThread()
+			}, nil
+		}
+		if i >= 10 {
+			runtime.UnlockOSThread()
+			return nil, fmt.Errorf("failed to open the clipboard: %w", err)
+		}
+		select {
+		case <-ctx.Done():
+			runtime.UnlockOSThread()
+			return nil, ctx.Err()
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
+
+// globalData copies the content of a global memory handle.
+func globalData(h uintptr) ([]byte, error) {
+	p, _, err := procGlobalLock.Call(h)
+	if p == 0 {
+		return nil, fmt.Errorf("failed to lock clipboard data: %w", err)
+	}
+	defer procGlobalUnlock.Call(h)
+	size, _, _ := procGlobalSize.Call(h)
+	// 经由指针转换避免 go vet 对 uintptr 转换的误报
+	data := unsafe.Slice((*byte)(*(*unsafe.Pointer)(unsafe.Pointer(&p))), size)
+	return append([]byte(nil), data...), nil
+}
+
+func (winBackend) ReadText(ctx context.Context) (string, error) {
+	closeClipboard, err := openClipboard(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer closeClipboard()
+	h, _, _ := procGetClipboardData.Call(cfUnicodeText)
+	if h == 0 {
+		return "", nil
+	}
+	data, err := globalData(h)
+	if err != nil {
+		return "", err
+	}
+	text := make([]uint16, len(data)/2)
+	for i := range text {
+		text[i] = uint16(data[2*i]) | uint16(data[2*i+1])<<8
+	}
+	return syscall.UTF16ToString(text), nil
+}
+
+func (winBackend) ReadImage(ctx context.Context) ([]byte, error) {
+	closeClipboard, err := openClipboard(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeClipboard()
+
+	// 浏览器和 Office 等程序同时写入 PNG 格式，优先读取
+	name, _ := syscall.UTF16PtrFromString("PNG")
+	pngFormat, _, _ := procRegisterClipboardFormatW.Call(uintptr(unsafe.Pointer(name)))
+	if ok, _, _ := procIsClipboardFormatAvailable.Call(pngFormat); pngFormat != 0 && ok != 0 {
+		if h, _, _ := procGetClipboardData.Call(pngFormat); h != 0 {
+			return globalData(h)
+		}
+	}
+	if ok, _, _ := procIsClipboardFormatAvailable.Call(cfDIB); ok == 0 {
+		return nil, ErrNoImage
+	}
+	h, _, err := procGetClipboardData.Call(cfDIB)
+	if h == 0 {
+		return nil, fmt.Errorf("failed to get the clipboard image: %w", err)
+	}
+	dib, err := globalData(h)
+	if err != nil {
+		return nil, err
+	}
+	return dibToPNG(dib)
+}
+
+func (winBackend) WriteText(ctx context.Context, text string) error {
+	utf16, err := syscall.UTF16FromString(text)
+	if err != nil {
+		return fmt.Errorf("text must not contain NUL characters")
+	}
+	closeClipboard, err := openClipboard(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeClipboard()
+
+	if r, _, err := procEmptyClipboard.Call(); r == 0 {
+		return fmt.Errorf("failed to empty the clipboard: %w", err)
+	}
+	size := uintptr(len(utf16) * 2)
+	h, _, err := procGlobalAlloc.Call(gmemMoveable, size)
+	if h == 0 {
+		return fmt.Errorf("failed to allocate clipboard memory: %w", err)
+	}
+	p, _, err := procGlobalLock.Call(h)
+	if p == 0 {
+		_, _, _ = procGlobalFree.Call(h)
+		return fmt.Errorf("failed to lock clipboard memory: %w", err)
+	}
+	dst := unsafe.Slice((*uint16)(*(*unsafe.Pointer)(unsafe.Pointer(&p))), len(utf16))
+	copy(dst, utf16)
+	_, _, _ = procGlobalUnlock.Call(h)
+	// 设置成功后内存归系统所有，失败时需自行释放
+	if r, _, err := procSetClipboardData.Call(cfUnicodeText, h); r == 0 {
+		_, _, _ = procGlobalFree.Call(h)
+		return fmt.Errorf("failed to set clipboard data: %w", err)
+	}
+	return nil
+}
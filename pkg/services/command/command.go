@@ -20,23 +20,26 @@ package command
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gojue/moling/pkg/comm"
 	"github.com/gojue/moling/pkg/config"
 	"github.com/gojue/moling/pkg/services/abstract"
 	"github.com/gojue/moling/pkg/utils"
 	"github.com/mark3labs/mcp-go/mcp"
-	"github.com/rs/zerolog"
 )
 
 var (
 	// ErrCommandNotFound is returned when the command is not found.
-	ErrCommandNotFound = fmt.Errorf("command not found")
+	ErrCommandNotFound = comm.NewCodedError(comm.ErrCodeNotFound, errors.New("command not found"))
 	// ErrCommandNotAllowed is returned when the command is not allowed.
-	ErrCommandNotAllowed = fmt.Errorf("command not allowed")
+	ErrCommandNotAllowed = comm.NewCodedError(comm.ErrCodePermissionDenied, errors.New("command not allowed"))
 )
 
 const (
@@ -46,33 +49,32 @@ const (
 // CommandServer implements the Service interface and provides methods to execute named commands.
 type CommandServer struct {
 	abstract.MLService
-	config    *CommandConfig
-	osName    string
-	osVersion string
+	config     *CommandConfig // 重新加载时整体替换，处理函数通过 cfg 读取
+	configLock sync.RWMutex   // 保护 config
+	osName     string
+	osVersion  string
+	execFunc   func(command string, opts execOptions) (*execResult, error) // 实际启动进程的函数，测试时替换
+	confirms   *confirmStore
+	notify     func(ctx context.Context, method string, params map[string]any) error // 向客户端发送通知，测试时替换
+	history    *commandHistory
 }
 
 // NewCommandServer creates a new CommandServer with the given allowed commands.
 func NewCommandServer(ctx context.Context) (abstract.Service, error) {
-	var err error
-	cc := NewCommandConfig()
-	gConf, ok := ctx.Value(comm.MoLingConfigKey).(*config.MoLingConfig)
-	if !ok {
-		return nil, fmt.Errorf("CommandServer: invalid config type")
-	}
-
-	lger, ok := ctx.Value(comm.MoLingLoggerKey).(zerolog.Logger)
-	if !ok {
-		return nil, fmt.Errorf("CommandServer: invalid logger type")
+	base, err := abstract.NewServiceBase(ctx, CommandServerName)
+	if err != nil {
+		return nil, err
 	}
 
-	loggerNameHook := zerolog.HookFunc(func(e *zerolog.Event, level zerolog.Level, msg string) {
-		e.Str("Service", string(CommandServerName))
-	})
-
 	cs := &CommandServer{
-		MLService: abstract.NewMLService(ctx, lger.Hook(loggerNameHook), gConf),
-		config:    cc,
+		MLService: base,
+		config:    NewCommandConfig(),
+		execFunc:  execCommand,
+		confirms:  newConfirmStore(),
 	}
+	cs.config.StdinReadDirs = []string{filepath.Join(base.MlConfig().BasePath, "data")}
+	cs.notify = cs.SendNotification
+	cs.history = newCommandHistory(filepath.Join(base.MlConfig().BasePath, "data", HistoryFileName), cs.cfg, cs.Logger)
 
 	err = cs.InitResources()
 	if err != nil {
@@ -82,7 +84,7 @@ func NewCommandServer(ctx context.Context) (abstract.Service, error) {
 	return cs, nil
 }
 
-func (cs *CommandServer) Init() error {
+func (cs *CommandServer) RegisterTools() error {
 	var err error
 	pe := abstract.PromptEntry{
 		PromptVar: mcp.Prompt{
@@ -100,7 +102,100 @@ func (cs *CommandServer) Init() error {
 			mcp.Description("The command to execute"),
 			mcp.Required(),
 		),
+		mcp.WithString("working_dir",
+			mcp.Description("Existing directory to run the command in, instead of prefixing the command with cd"),
+		),
+		mcp.WithNumber("timeout_seconds",
+			mcp.Description("Seconds after which the command is killed and its partial output returned, at most and by default exec_timeout_seconds. Use command_execute_stream for longer commands"),
+		),
+		mcp.WithNumber("max_output_bytes",
+			mcp.Description("Bytes of output returned, the rest is discarded, at most and by default max_output_bytes"),
+		),
+		mcp.WithObject("env",
+			mcp.Description("Environment variables set for the command, as an object of strings, e.g. {\"TZ\": \"UTC\"}. Only the names listed in allowed_env are accepted"),
+			mcp.AdditionalProperties(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("use_secrets",
+			mcp.Description("Names of configured secrets to inject as environment variables of the command, e.g. [\"GITHUB_TOKEN\"]. Their values are masked in the output"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithBoolean("explain",
+			mcp.Description("Don't run the command, return what would run as JSON: the allowlist rule matched by each part of the command line, the shell, working directory, injected environment variables and timeout"),
+		),
+		mcp.WithString("confirm_token",
+			mcp.Description("The confirm_token returned by the previous call for the same command and use_secrets, required to run commands when confirmation is enabled"),
+		),
+		mcp.WithString("stdin",
+			mcp.Description("Data written to the stdin of the command, which is closed at the end of the data. Use it instead of echo pipelines for commands like sort, jq or wc -l, or to answer the prompts of interactive commands. It is not checked against the allowed commands"),
+		),
+		mcp.WithArray("stdin_lines",
+			mcp.Description("Lines written to the stdin of the command, each followed by a newline, to answer several prompts in turn, e.g. [\"y\", \"my-name\"]. Use it instead of stdin"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithBoolean("stdin_base64",
+			mcp.Description("Decode stdin as base64 before writing it, for binary input"),
+		),
+		mcp.WithString("stdin_file",
+			mcp.Description("Path of a file streamed into the stdin of the command instead of stdin, for large input. Must be inside the configured stdin_read_dirs"),
+		),
 	), cs.handleExecuteCommand)
+	cs.AddTool(mcp.NewTool(
+		"command_execute_stream",
+		mcp.WithDescription("Execute a long-running command, such as a build or a test suite, and send its output to the client as progress notifications while it runs. Returns the whole output and the exit code once the command exits or is killed. The same rules as execute_command apply"),
+		mcp.WithString("command",
+			mcp.Description("The command to execute"),
+			mcp.Required(),
+		),
+		mcp.WithNumber("timeout_seconds",
+			mcp.Description(fmt.Sprintf("Seconds after which the command and the processes it spawned are killed, default %d, at most max_stream_timeout", defaultStreamTimeout)),
+		),
+		mcp.WithString("working_dir",
+			mcp.Description("Existing directory to run the command in, instead of prefixing the command with cd"),
+		),
+		mcp.WithObject("env",
+			mcp.Description("Environment variables set for the command, as an object of strings, e.g. {\"TZ\": \"UTC\"}. Only the names listed in allowed_env are accepted"),
+			mcp.AdditionalProperties(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("use_secrets",
+			mcp.Description("Names of configured secrets to inject as environment variables of the command. Their values are masked in the output"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithBoolean("explain",
+			mcp.Description("Don't run the command, return what would run as JSON"),
+		),
+		mcp.WithString("confirm_token",
+			mcp.Description("The confirm_token returned by the previous call for the same command and use_secrets, required to run commands when confirmation is enabled"),
+		),
+		mcp.WithString("stdin",
+			mcp.Description("Data written to the stdin of the command, which is closed at the end of the data"),
+		),
+		mcp.WithArray("stdin_lines",
+			mcp.Description("Lines written to the stdin of the command, each followed by a newline, to answer several prompts in turn, e.g. [\"y\", \"my-name\"]. Use it instead of stdin"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithBoolean("stdin_base64",
+			mcp.Description("Decode stdin as base64 before writing it, for binary input"),
+		),
+		mcp.WithString("stdin_file",
+			mcp.Description("Path of a file streamed into the stdin of the command instead of stdin. Must be inside the configured stdin_read_dirs"),
+		),
+	), cs.handleExecuteStream)
+	if !cs.cfg().DisableHistory {
+		cs.AddTool(mcp.NewTool(
+			"command_history",
+			mcp.WithDescription("Return the last commands executed by execute_command and command_execute_stream as JSON, oldest first, with their time, exit code, duration and truncated output"),
+			mcp.WithNumber("limit",
+				mcp.Description(fmt.Sprintf("Number of entries to return, default %d, at most %d", defaultHistoryLimit, maxHistoryLimit)),
+			),
+			mcp.WithString("filter",
+				mcp.Description("Only return the commands containing this text"),
+			),
+		), cs.handleHistory)
+	}
+	cs.AddTool(mcp.NewTool(
+		"command_secrets_list",
+		mcp.WithDescription("List the names and sources of the configured secrets that can be passed to execute_command with use_secrets. Values are never returned."),
+	), cs.handleSecretsList)
 	return err
 }
 
@@ -112,7 +207,7 @@ func (cs *CommandServer) handlePrompt(ctx context.Context, request mcp.GetPrompt
 				Role: mcp.RoleUser,
 				Content: mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf(cs.config.prompt, cs.MlConfig().SystemInfo),
+					Text: fmt.Sprintf(cs.cfg().prompt, cs.MlConfig().SystemInfo),
 				},
 			},
 		},
@@ -122,71 +217,221 @@ func (cs *CommandServer) handlePrompt(ctx context.Context, request mcp.GetPrompt
 // handleExecuteCommand handles the execution of a named command.
 func (cs *CommandServer) handleExecuteCommand(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args := request.GetArguments()
+	timeout, err := boundedArg(args, "timeout_seconds", int64(cs.cfg().ExecTimeoutSeconds), int64(cs.cfg().ExecTimeoutSeconds))
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	maxOutput, err := boundedArg(args, "max_output_bytes", cs.cfg().MaxOutputBytes, cs.cfg().MaxOutputBytes)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	opts := execOptions{Timeout: time.Duration(timeout) * time.Second, MaxOutput: maxOutput}
+	run, result := cs.prepareCommand("execute_command", args, opts.Timeout)
+	if result != nil {
+		return result, nil
+	}
+	opts.Dir, opts.Env = run.dir, run.env
+	if run.input != nil {
+		defer run.input.Close()
+		opts.Stdin = run.input
+	}
+
+	// Execute the command
+	start := time.Now()
+	res, err := cs.execFunc(run.command, opts)
+	if res == nil {
+		res = &execResult{ExitCode: -1}
+	}
+	entry := HistoryEntry{
+		Time:       start,
+		Tool:       "execute_command",
+		Command:    run.command,
+		WorkingDir: run.dir,
+		ExitCode:   res.ExitCode,
+		TimedOut:   res.TimedOut,
+		DurationMs: time.Since(start).Milliseconds(),
+		Output:     cs.scrubSecrets(res.Output),
+	}
+	if err != nil {
+		entry.Error = cs.scrubSecrets(err.Error())
+	}
+	cs.history.record(entry)
+	if err != nil {
+		return comm.ToolError(comm.ErrorCodeOf(err), "failed to execute the command", cs.scrubSecrets(err.Error())), nil
+	}
+
+	output := res.Output
+	var notes []string
+	if res.TimedOut {
+		notes = append(notes, fmt.Sprintf("timed out after %s, the command was killed and the output is partial", opts.Timeout))
+	}
+	if run.input != nil {
+		notes = append(notes, fmt.Sprintf("%d bytes fed to stdin", run.input.Fed()))
+	}
+	if len(notes) > 0 {
+		output = fmt.Sprintf("%s\n(%s)", strings.TrimRight(output, "\n"), strings.Join(notes, ", "))
+	}
+	return mcp.NewToolResultText(cs.scrubSecrets(output)), nil
+}
+
+// commandRun is a command line that passed the checks and is ready to be executed.
+type commandRun struct {
+	command string
+	dir     string        // 为空表示 MoLing 的工作目录
+	env     []string      // 注入的密钥和 env 参数的环境变量
+	input   *commandInput // 为 nil 表示没有 stdin
+}
+
+// prepareCommand checks the arguments shared by the tools that execute a command line: the
+// allowlist, use_secrets, explain, confirm_token and stdin. The timeout is shown in explanations. It returns a result instead of the run
+// when the call ends without executing. The caller must close the input of the run.
+func (cs *CommandServer) prepareCommand(tool string, args map[string]interface{}, timeout time.Duration) (*commandRun, *mcp.CallToolResult) {
 	command, ok := args["command"].(string)
 	if !ok {
-		return mcp.NewToolResultError(fmt.Errorf("command must be a string").Error()), nil
+		return nil, comm.ToolError(comm.ErrCodeInvalidArgument, "command must be a string", "")
+	}
+
+	secretNames, err := parseSecretNames(args["use_secrets"])
+	if err != nil {
+		return nil, comm.ArgumentError(err)
+	}
+
+	dir, err := parseWorkingDir(args)
+	if err != nil {
+		return nil, comm.ArgumentError(err)
+	}
+	extraEnv, err := parseCommandEnv(args, secretNames, cs.cfg().allowedEnv)
+	if err != nil {
+		return nil, comm.ArgumentError(err)
+	}
+
+	// explain 模式只做校验并返回将要执行的内容，不启动进程
+	explanation := cs.explain(command, secretNames)
+	explanation.Timeout = int(timeout / time.Second)
+	if dir != "" {
+		explanation.Cwd = dir
+	}
+	explanation.Env = append(explanation.Env, envNames(extraEnv)...)
+	sort.Strings(explanation.Env)
+	if explain, _ := args["explain"].(bool); explain {
+		if _, err := cs.secretEnv(secretNames); err != nil {
+			return nil, comm.ArgumentError(err)
+		}
+		return nil, cs.explanationResult(explanation, "")
 	}
 
 	// Check if the command is allowed
-	if !cs.isAllowedCommand(command) {
+	if !explanation.Allowed {
 		cs.Logger.Err(ErrCommandNotAllowed).Str("command", command).Msgf("If you want to allow this command, add it to %s", filepath.Join(cs.MlConfig().BasePath, "config", cs.MlConfig().ConfigFile))
-		return mcp.NewToolResultError(fmt.Sprintf("Error: Command '%s' is not allowed", command)), nil
+		return nil, comm.ToolError(comm.ErrCodePermissionDenied, fmt.Sprintf("command '%s' is not allowed, denied: %s. Add them to allowed_command or allow_rules to allow them", command, deniedSegments(explanation.Segments)), "")
 	}
 
-	// Execute the command
-	output, err := ExecCommand(command)
+	env, err := cs.secretEnv(secretNames)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error executing command: %v", err)), nil
+		return nil, comm.ArgumentError(err)
 	}
 
-	return mcp.NewToolResultText(output), nil
-}
-
-// isAllowedCommand checks if the command is allowed based on the configuration.
-func (cs *CommandServer) isAllowedCommand(command string) bool {
-	// 检查命令是否在允许的列表中
-	for _, allowed := range cs.config.allowedCommands {
-		if strings.HasPrefix(command, allowed) {
-			return true
-		}
+	input, err := cs.parseStdin(args)
+	if err != nil {
+		return nil, comm.ArgumentError(err)
 	}
 
-	// 如果命令包含管道符，进一步检查每个子命令
-	if strings.Contains(command, "|") {
-		parts := strings.Split(command, "|")
-		for _, part := range parts {
-			part = strings.TrimSpace(part)
-			if !cs.isAllowedCommand(part) {
-				return false
+	// 需要确认时，首次调用返回说明和确认令牌，带回令牌后才执行
+	if cs.cfg().AlwaysExplainFirst {
+		key := confirmKey(command, secretNames, dir, extraEnv)
+		token, _ := args["confirm_token"].(string)
+		if token == "" {
+			closeInput(input)
+			ttl := time.Duration(cs.cfg().ConfirmTimeout) * time.Second
+			token, expires, err := cs.confirms.issue(key, ttl)
+			if err != nil {
+				return nil, comm.ToolErrorFromErr("failed to create confirm_token", err)
 			}
+			explanation.ConfirmToken = token
+			explanation.ConfirmExpires = expires.Format(time.RFC3339)
+			return nil, cs.explanationResult(explanation, fmt.Sprintf("The command has not been executed. Show this to the user, and call %s again with the same arguments and confirm_token within %d seconds to execute it.\n", tool, cs.cfg().ConfirmTimeout))
+		}
+		if err := cs.confirms.consume(token, key); err != nil {
+			closeInput(input)
+			return nil, comm.ArgumentError(err)
 		}
-		return true
 	}
 
-	if strings.Contains(command, "&") {
-		parts := strings.Split(command, "&")
-		for _, part := range parts {
-			part = strings.TrimSpace(part)
-			if !cs.isAllowedCommand(part) {
-				return false
-			}
-		}
-		return true
+	if len(secretNames) > 0 {
+		// 只记录密钥名称，不记录值
+		cs.Logger.Debug().Strs("secrets", secretNames).Msg("injecting secrets into command environment")
+	}
+	return &commandRun{command: command, dir: dir, env: append(env, extraEnv...), input: input}, nil
+}
+
+// closeInput closes the stdin of a command that is not executed.
+func closeInput(input *commandInput) {
+	if input != nil {
+		_ = input.Close()
+	}
+}
+
+// explanationResult returns the explanation as JSON after the note.
+func (cs *CommandServer) explanationResult(explanation *Explanation, note string) *mcp.CallToolResult {
+	// 不转义命令行中的 & < >
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(explanation); err != nil {
+		return comm.ToolErrorFromErr("failed to encode the explanation", err)
+	}
+	return mcp.NewToolResultText(note + strings.TrimSuffix(buf.String(), "\n"))
+}
+
+// handleSecretsList lists the names and sources of the configured secrets.
+func (cs *CommandServer) handleSecretsList(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	secrets := make([]Secret, 0, len(cs.cfg().secrets))
+	for _, name := range cs.secretNames() {
+		secrets = append(secrets, cs.cfg().secrets[name])
+	}
+	data, err := json.Marshal(secrets)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to encode the secrets", err), nil
 	}
+	return mcp.NewToolResultText(string(data)), nil
+}
 
-	return false
+// isAllowedCommand checks if the command is allowed based on the configuration.
+func (cs *CommandServer) isAllowedCommand(command string) bool {
+	_, allowed := cs.matchCommand(command)
+	return allowed
+}
+
+// ConfigSchema returns the JSON Schema of the Command configuration with its default values.
+func (cs *CommandServer) ConfigSchema() string {
+	schema, err := config.GenerateSchema("Command", NewCommandConfig())
+	if err != nil {
+		cs.Logger.Err(err).Msg("failed to generate config schema")
+		return ""
+	}
+	return schema
 }
 
 // Config returns the configuration of the service as a string.
 func (cs *CommandServer) Config() string {
-	cs.config.AllowedCommand = strings.Join(cs.config.allowedCommands, ",")
-	cfg, err := json.Marshal(cs.config)
+	cfg := cs.cfg()
+	// 字面量密钥不输出，env: 和 file: 引用本身不是密钥
+	masked := *cfg
+	masked.AllowedCommand = strings.Join(cfg.allowedCommands, ",")
+	masked.Secrets = make(map[string]string, len(cfg.Secrets))
+	for name, spec := range cfg.Secrets {
+		if !strings.HasPrefix(spec, secretEnvPrefix) && !strings.HasPrefix(spec, secretFilePrefix) {
+			spec = secretMask
+		}
+		masked.Secrets[name] = spec
+	}
+	data, err := json.Marshal(masked)
 	if err != nil {
 		cs.Logger.Err(err).Msg("failed to marshal config")
 		return "{}"
 	}
-	cs.Logger.Debug().Str("config", string(cfg)).Msg("CommandServer config")
-	return string(cfg)
+	cs.Logger.Debug().Str("config", string(data)).Msg("CommandServer config")
+	return string(data)
 }
 
 func (cs *CommandServer) Name() comm.MoLingServerType {
@@ -194,18 +439,38 @@ func (cs *CommandServer) Name() comm.MoLingServerType {
 }
 
 func (cs *CommandServer) Close() error {
-	// Cancel the context to stop the browser
 	cs.Logger.Debug().Msg("CommandServer closed")
-	return nil
+	return cs.history.close()
 }
 
 // LoadConfig loads the configuration from a JSON object.
 func (cs *CommandServer) LoadConfig(jsonData map[string]interface{}) error {
-	err := utils.MergeJSONToStruct(cs.config, jsonData)
+	// 在副本上合并和校验，校验通过后才替换正在使用的配置
+	next := *cs.cfg()
+	err := utils.MergeJSONToStruct(&next, jsonData)
 	if err != nil {
 		return err
 	}
 	// split the AllowedCommand string into a slice
-	cs.config.allowedCommands = strings.Split(cs.config.AllowedCommand, ",")
-	return cs.config.Check()
+	next.allowedCommands = strings.Split(next.AllowedCommand, ",")
+	if err := next.Check(); err != nil {
+		return err
+	}
+	cs.configLock.Lock()
+	cs.config = &next
+	cs.configLock.Unlock()
+	return nil
+}
+
+// cfg returns the running config. LoadConfig replaces it as a whole instead of changing it, a
+// handler reading it sees either the old or the new config.
+func (cs *CommandServer) cfg() *CommandConfig {
+	cs.configLock.RLock()
+	defer cs.configLock.RUnlock()
+	return cs.config
+}
+
+// RestartOnlyFields returns the fields read when the tools are registered.
+func (cs *CommandServer) RestartOnlyFields() []string {
+	return []string{"disable_history"}
 }
@@ -0,0 +1,307 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnterminatedQuote is returned when a command line has a quote without its closing quote.
+var ErrUnterminatedQuote = errors.New("unterminated quote")
+
+// shellKeywords are the reserved words of the shell that may start a segment, such as the do of a
+// loop. They are skipped, so that the command after them is checked.
+var shellKeywords = map[string]bool{
+	"!": true, "{": true, "}": true, "if": true, "then": true, "elif": true, "else": true, "fi": true,
+	"while": true, "until": true, "do": true, "done": true,
+}
+
+// AllowRule allows a command, optionally only with some leading arguments, such as
+// {"cmd": "git", "args": ["log", "status", "diff"]}. Each of args may have several words, e.g.
+// "remote -v". A rule without args allows the command with any arguments.
+type AllowRule struct {
+	Cmd  string   `json:"cmd"`
+	Args []string `json:"args,omitempty"`
+}
+
+// String describes the rule as shown in explanations and errors.
+func (r AllowRule) String() string {
+	if len(r.Args) == 0 {
+		return r.Cmd
+	}
+	return fmt.Sprintf("%s [%s]", r.Cmd, strings.Join(r.Args, ", "))
+}
+
+// parseAllowRules parses allow_rules, whose entries are command names or AllowRule objects.
+func parseAllowRules(raw []interface{}) ([]AllowRule, error) {
+	rules := make([]AllowRule, 0, len(raw))
+	for i, entry := range raw {
+		var rule AllowRule
+		switch v := entry.(type) {
+		case string:
+			rule.Cmd = v
+		case map[string]interface{}:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid allow_rules entry %d: %w", i, err)
+			}
+			if err := json.Unmarshal(data, &rule); err != nil {
+				return nil, fmt.Errorf("invalid allow_rules entry %d: %w", i, err)
+			}
+		default:
+			return nil, fmt.Errorf("allow_rules entry %d must be a command or an object with cmd and args", i)
+		}
+		rule.Cmd = strings.TrimSpace(rule.Cmd)
+		if rule.Cmd == "" {
+			return nil, fmt.Errorf("allow_rules entry %d has no cmd", i)
+		}
+		for _, arg := range rule.Args {
+			if strings.TrimSpace(arg) == "" {
+				return nil, fmt.Errorf("allow_rules entry %d (%s) has an empty argument", i, rule.Cmd)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// rules returns the plain commands of allowed_command followed by allow_rules.
+func (cc *CommandConfig) rules() []AllowRule {
+	rules := make([]AllowRule, 0, len(cc.allowedCommands)+len(cc.allowRules))
+	for _, cmd := range cc.allowedCommands {
+		if cmd = strings.TrimSpace(cmd); cmd != "" {
+			rules = append(rules, AllowRule{Cmd: cmd})
+		}
+	}
+	return append(rules, cc.allowRules...)
+}
+
+// shellSegment is a simple command of a command line, split by | & ; && || and newlines.
+type shellSegment struct {
+	text  string   // 原始文本
+	words []string // 去除引号后的单词
+	subst bool     // 包含 $(...) 或 `...` 命令替换
+}
+
+// splitCommandLine splits a command line into its simple commands with the quoting rules of the
+// POSIX shell: separators and spaces inside quotes or escaped with a backslash don't split.
+func splitCommandLine(command string) ([]shellSegment, error) {
+	var segments []shellSegment
+	var seg shellSegment
+	var text, word strings.Builder
+	inWord := false
+	endWord := func() {
+		if inWord {
+			seg.words = append(seg.words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endSegment := func() {
+		endWord()
+		seg.text = strings.TrimSpace(text.String())
+		if seg.text != "" {
+			segments = append(segments, seg)
+		}
+		seg = shellSegment{}
+		text.Reset()
+	}
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == '\\':
+			if i+1 < len(runes) {
+				i++
+				text.WriteRune(c)
+				text.WriteRune(runes[i])
+				if runes[i] != '\n' {
+					word.WriteRune(runes[i])
+					inWord = true
+				}
+				continue
+			}
+		case c == '\'':
+			end := indexRunes(runes, i+1, "'")
+			if end < 0 {
+				return nil, ErrUnterminatedQuote
+			}
+			text.WriteString(string(runes[i : end+1]))
+			word.WriteString(string(runes[i+1 : end]))
+			inWord = true
+			i = end
+			continue
+		case c == '"':
+			j := i + 1
+			for ; j < len(runes) && runes[j] != '"'; j++ {
+				switch {
+				case runes[j] == '\\' && j+1 < len(runes) && strings.ContainsRune("$`\"\\\n", runes[j+1]):
+					j++
+					word.WriteRune(runes[j])
+					continue
+				case runes[j] == '`', runes[j] == '$' && j+1 < len(runes) && runes[j+1] == '(' && (j+2 >= len(runes) || runes[j+2] != '('):
+					seg.subst = true
+				}
+				word.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, ErrUnterminatedQuote
+			}
+			text.WriteString(string(runes[i : j+1]))
+			inWord = true
+			i = j
+			continue
+		case c == '$' && i+2 < len(runes) && runes[i+1] == '(' && runes[i+2] == '(':
+			// $((...)) 算术展开不执行命令，整体作为单词的一部分
+			if end := indexRunes(runes, i+3, "))"); end >= 0 {
+				text.WriteString(string(runes[i : end+2]))
+				word.WriteString(string(runes[i : end+2]))
+				inWord = true
+				i = end + 1
+				continue
+			}
+		case c == '$' && i+1 < len(runes) && runes[i+1] == '(', c == '`':
+			// 命令替换整体留在当前单词中，所在的段不被允许
+			seg.subst = true
+			end := len(runes) - 1
+			if c == '`' {
+				if j := indexRunes(runes, i+1, "`"); j >= 0 {
+					end = j
+				}
+			} else if j := closingParen(runes, i+2); j >= 0 {
+				end = j
+			}
+			text.WriteString(string(runes[i : end+1]))
+			word.WriteString(string(runes[i : end+1]))
+			inWord = true
+			i = end
+			continue
+		case c == '&' && (i > 0 && strings.ContainsRune("<>", runes[i-1]) || i+1 < len(runes) && runes[i+1] == '>'):
+			// >&、<& 和 &> 是重定向，不是后台运行
+		case c == ';' || c == '&' || c == '|' || c == '\n' || c == '(' || c == ')':
+			endSegment()
+			continue
+		case c == ' ' || c == '\t':
+			endWord()
+			text.WriteRune(c)
+			continue
+		}
+		text.WriteRune(c)
+		word.WriteRune(c)
+		inWord = true
+	}
+	endSegment()
+	return segments, nil
+}
+
+// indexRunes returns the index of the first sub in runes from start, or -1.
+func indexRunes(runes []rune, start int, sub string) int {
+	if start > len(runes) {
+		return -1
+	}
+	if i := strings.Index(string(runes[start:]), sub); i >= 0 {
+		return start + len([]rune(string(runes[start:])[:i]))
+	}
+	return -1
+}
+
+// closingParen returns the index of the ) that closes the ( before start, or -1.
+func closingParen(runes []rune, start int) int {
+	depth := 1
+	for i := start; i < len(runes); i++ {
+		switch runes[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// hasWordPrefix reports whether words starts with the words of prefix.
+func hasWordPrefix(words []string, prefix string) bool {
+	want := strings.Fields(prefix)
+	if len(want) == 0 || len(want) > len(words) {
+		return false
+	}
+	for i, w := range want {
+		if words[i] != w {
+			return false
+		}
+	}
+	return true
+}
+
+// matchSegment checks a simple command against the allow rules.
+func matchSegment(seg shellSegment, rules []AllowRule) CommandSegment {
+	result := CommandSegment{Command: seg.text}
+	if seg.subst {
+		result.Reason = "command substitution is not allowed"
+		return result
+	}
+	words := seg.words
+	for len(words) > 0 && shellKeywords[words[0]] {
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		result.Allowed = true
+		return result
+	}
+
+	var restricted []string
+	for _, rule := range rules {
+		if !hasWordPrefix(words, rule.Cmd) {
+			continue
+		}
+		if len(rule.Args) == 0 {
+			result.Rule, result.Allowed = rule.String(), true
+			return result
+		}
+		args := words[len(strings.Fields(rule.Cmd)):]
+		for _, arg := range rule.Args {
+			if hasWordPrefix(args, arg) {
+				result.Rule, result.Allowed = rule.String(), true
+				return result
+			}
+		}
+		restricted = append(restricted, rule.Args...)
+	}
+	if len(restricted) > 0 {
+		result.Reason = fmt.Sprintf("%s only allows %s", words[0], strings.Join(restricted, ", "))
+	} else {
+		result.Reason = fmt.Sprintf("no rule allows %s", words[0])
+	}
+	return result
+}
+
+// deniedSegments describes the segments that are not allowed, for the tool error.
+func deniedSegments(segments []CommandSegment) string {
+	var denied []string
+	for _, s := range segments {
+		if !s.Allowed {
+			denied = append(denied, fmt.Sprintf("%q (%s)", s.Command, s.Reason))
+		}
+	}
+	return strings.Join(denied, "; ")
+}
@@ -0,0 +1,212 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package command
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	for _, c := range []struct {
+		command string
+		words   [][]string
+		subst   []bool
+	}{
+		{`git log --since="today" --pretty=format:"%h - %an, %ar : %s"`, [][]string{{"git", "log", "--since=today", "--pretty=format:%h - %an, %ar : %s"}}, nil},
+		{`cd /var/logs/notfound && git log --since=\"today\"`, [][]string{{"cd", "/var/logs/notfound"}, {"git", "log", `--since="today"`}}, nil},
+		{`echo 'a;b' | grep "x|y" || echo "it's"`, [][]string{{"echo", "a;b"}, {"grep", "x|y"}, {"echo", "it's"}}, nil},
+		{`echo a\;b; ls -l` + "\n" + `pwd`, [][]string{{"echo", "a;b"}, {"ls", "-l"}, {"pwd"}}, nil},
+		{`ls 2>&1 | grep x &> out & echo "a\"b"`, [][]string{{"ls", "2>&1"}, {"grep", "x", "&>", "out"}, {"echo", `a"b`}}, nil},
+		{`(cd x; rm y)`, [][]string{{"cd", "x"}, {"rm", "y"}}, nil},
+		{`echo $((1+2)); echo $(id); echo "$(id)"; echo '$(id)'; echo ` + "`id`", [][]string{{"echo", "$((1+2))"}, {"echo", "$(id)"}, {"echo", "$(id)"}, {"echo", "$(id)"}, {"echo", "`id`"}}, []bool{false, true, true, false, true}},
+	} {
+		segments, err := splitCommandLine(c.command)
+		if err != nil {
+			t.Errorf("splitCommandLine(%q) failed: %v", c.command, err)
+			continue
+		}
+		var words [][]string
+		var subst []bool
+		for _, s := range segments {
+			words = append(words, s.words)
+			subst = append(subst, s.subst)
+		}
+		if !reflect.DeepEqual(words, c.words) {
+			t.Errorf("splitCommandLine(%q) = %q, expected %q", c.command, words, c.words)
+		}
+		if c.subst != nil && !reflect.DeepEqual(subst, c.subst) {
+			t.Errorf("splitCommandLine(%q) substitutions = %v, expected %v", c.command, subst, c.subst)
+		}
+	}
+
+	for _, command := range []string{`echo "abc`, `echo 'abc`, `git log --since="today`} {
+		if _, err := splitCommandLine(command); !errors.Is(err, ErrUnterminatedQuote) {
+			t.Errorf("Expected an unterminated quote error for %q, got %v", command, err)
+		}
+	}
+}
+
+func TestReloadAllowedCommands(t *testing.T) {
+	cs, _ := newExplainTestServer(t, false)
+	if err := cs.LoadConfig(map[string]interface{}{"allowed_command": "echo,ls"}); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	running := cs.config
+
+	// 校验失败时不会留下部分合并的允许列表
+	if err := cs.LoadConfig(map[string]interface{}{"allowed_command": "echo,rm", "confirm_timeout": 0}); err == nil {
+		t.Fatal("Expected the invalid config to be rejected")
+	}
+	if cs.config != running {
+		t.Error("Expected the running config to be kept")
+	}
+	if _, allowed := cs.matchCommand("rm -rf /tmp/x"); allowed {
+		t.Error("Expected rm to stay denied after the rejected reload")
+	}
+
+	// 重新加载与处理函数并发执行
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			commands := "echo,ls"
+			if i%2 == 0 {
+				commands = "echo,ls,cat"
+			}
+			if err := cs.LoadConfig(map[string]interface{}{"allowed_command": commands}); err != nil {
+				t.Errorf("LoadConfig failed: %v", err)
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+			if _, allowed := cs.matchCommand("ls -l"); !allowed {
+				t.Error("Expected ls to stay allowed during reloads")
+				<-done
+				return
+			}
+		}
+	}
+}
+
+func TestDefaultAllowRules(t *testing.T) {
+	cs, _ := newExplainTestServer(t, false)
+	for command, allow := range map[string]bool{
+		"git status":                    true,
+		"git log -n 5 | head":           true,
+		"git diff HEAD~1":               true,
+		"git push origin":               false,
+		"git config core.pager x":       false,
+		"cd /tmp && ls":                 true,
+		"cd /tmp && rm -rf x":           false,
+		"networksetup -listallhardware": true,
+	} {
+		if _, allowed := cs.matchCommand(command); allowed != allow {
+			t.Errorf("Expected %q allowed=%v by default", command, allow)
+		}
+	}
+}
+
+func TestAllowRules(t *testing.T) {
+	cs, spawned := newExplainTestServer(t, false)
+	err := cs.LoadConfig(map[string]interface{}{
+		"allowed_command": "echo,ls,cd",
+		"allow_rules": []interface{}{
+			map[string]interface{}{"cmd": "git", "args": []interface{}{"log", "status", "diff", "remote -v"}},
+			"make test",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	for command, rules := range map[string][]string{
+		`git log --since="today" --pretty=format:"%h - %an, %ar : %s"`: {"git [log, status, diff, remote -v]"},
+		"cd /var/logs/notfound && git status -s":                       {"cd", "git [log, status, diff, remote -v]"},
+		"git remote -v":                                                {"git [log, status, diff, remote -v]"},
+		"make test | ls":                                               {"make test", "ls"},
+		"if ls x; then echo yes; fi":                                   {"ls", "echo", ""},
+		"echo 'git push; rm -rf /'":                                    {"echo"},
+		`echo "$((1+2))" && echo done &`:                               {"echo", "echo"},
+	} {
+		segments, allowed := cs.matchCommand(command)
+		var got []string
+		for _, s := range segments {
+			got = append(got, s.Rule)
+		}
+		if !allowed || !reflect.DeepEqual(got, rules) {
+			t.Errorf("Expected %q to match %q, got %+v", command, rules, segments)
+		}
+	}
+
+	for command, reasons := range map[string][]string{
+		"git push --force origin":     {"git only allows log, status, diff, remote -v"},
+		"git remote add x y":          {"git only allows log, status, diff, remote -v"},
+		`git "push"`:                  {"git only allows log, status, diff, remote -v"},
+		"make install":                {"no rule allows make"},
+		"lsof -i":                     {"no rule allows lsof"},
+		"echo $(rm -rf /)":            {"command substitution is not allowed"},
+		"echo `rm -rf /`":             {"command substitution is not allowed"},
+		"then rm -rf /":               {"no rule allows rm"},
+		`git log --since="today`:      {ErrUnterminatedQuote.Error()},
+		"ls; rm -rf x && git push":    {"", "no rule allows rm", "git only allows log, status, diff, remote -v"},
+		"cd /tmp && rm -rf important": {"", "no rule allows rm"},
+	} {
+		segments, allowed := cs.matchCommand(command)
+		var got []string
+		for _, s := range segments {
+			got = append(got, s.Reason)
+		}
+		if allowed || !reflect.DeepEqual(got, reasons) {
+			t.Errorf("Expected %q to be denied with %q, got %+v", command, reasons, segments)
+		}
+	}
+
+	// 工具错误逐段列出被拒绝的部分
+	text, isErr := callExecute(t, cs, map[string]interface{}{"command": "ls; rm -rf x && git push --force"})
+	if !isErr || !strings.Contains(text, `denied: "rm -rf x" (no rule allows rm); "git push --force" (git only allows log, status, diff, remote -v)`) || strings.Contains(text, `"ls"`) {
+		t.Errorf("Expected the denied segments in the error, got %s", text)
+	}
+	if text, isErr := callExecute(t, cs, map[string]interface{}{"command": "git diff HEAD~1 | ls"}); isErr || text != "ran git diff HEAD~1 | ls" {
+		t.Errorf("Expected the command to run, got %s", text)
+	}
+	if *spawned != 1 {
+		t.Errorf("Expected one process, got %d", *spawned)
+	}
+
+	if !strings.Contains(cs.Config(), `"allow_rules":[{"args":["log","status","diff","remote -v"],"cmd":"git"},"make test"]`) {
+		t.Errorf("Expected the allow rules in the config, got %s", cs.Config())
+	}
+
+	for _, rules := range [][]interface{}{
+		{float64(1)},
+		{map[string]interface{}{"args": []interface{}{"log"}}},
+		{map[string]interface{}{"cmd": "git", "args": []interface{}{" "}}},
+		{map[string]interface{}{"cmd": "git", "args": "log"}},
+	} {
+		if _, err := parseAllowRules(rules); err == nil {
+			t.Errorf("Expected an error for %v", rules)
+		}
+	}
+}
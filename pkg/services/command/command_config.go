@@ -19,6 +19,9 @@ package command
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 )
 
@@ -60,16 +63,45 @@ Before executing any actions, please provide clear instructions, including:
 - Any optional parameters (e.g., modification options, output formats, etc.)
 - Relevant expected results or output
 
+Secrets such as API keys are never passed on the command line. Use command_secrets_list to see the configured secret names, and pass the names with use_secrets to make them available as environment variables of the command (e.g. $GITHUB_TOKEN). Secret values are masked as *** in the output.
+
+Commands that read stdin, such as sort, jq or wc -l, can get their input from the stdin argument of execute_command instead of a long echo pipeline: pass the text as stdin, binary data base64 encoded with stdin_base64 set to true, or a large file with stdin_file, which is streamed into the command. Commands that prompt for input, such as confirmations, hang until they time out without stdin: answer the prompts with stdin, or with stdin_lines, one line per prompt. The stdin is closed after the input, so a command that asks for more gets end of file instead of hanging.
+
+For long-running commands such as builds or test suites, use command_execute_stream instead of execute_command: it sends the output as it is produced and returns the whole output and the exit code at the end. Set timeout_seconds to how long the command may run; it is killed with the processes it spawned when the timeout expires.
+
+To run a command in a project directory, pass the directory as working_dir instead of prefixing the command with cd, and set environment variables with env, e.g. {"TZ": "UTC"}. env only accepts the variable names listed in allowed_env of the Command configuration.
+
+To check what a command would do without running it, call execute_command with explain set to true. When execute_command returns a confirm_token instead of running the command, show the explanation to the user and call it again with the same arguments and the confirm_token once the user agrees.
+
 When dealing with sensitive operations or destructive commands, please confirm before execution. Report back with clear status updates, success/failure indicators, and any relevant output or results.
 `
 )
 
 // CommandConfig represents the configuration for allowed commands.
 type CommandConfig struct {
-	PromptFile      string `json:"prompt_file"` // PromptFile is the prompt file for the command.
-	prompt          string
-	AllowedCommand  string `json:"allowed_command"` // AllowedCommand is a list of allowed command. split by comma. e.g. ls,cat,echo
-	allowedCommands []string
+	PromptFile         string `json:"prompt_file" desc:"File whose content replaces the default prompt of the command service"` // PromptFile is the prompt file for the command.
+	prompt             string
+	AllowedCommand     string `json:"allowed_command" desc:"Comma separated commands that may be executed, e.g. ls,cat,echo"` // AllowedCommand is a list of allowed command. split by comma. e.g. ls,cat,echo
+	allowedCommands    []string
+	AllowRules         []interface{} `json:"allow_rules" desc:"Allow rules in addition to allowed_command: a command, or an object like {\"cmd\": \"git\", \"args\": [\"log\", \"status\"]} that only allows the listed leading arguments"` // AllowRules are commands or AllowRule objects.
+	allowRules         []AllowRule
+	AllowedEnv         []string `json:"allowed_env" desc:"Names of the environment variables the env argument of a command may set. Variables such as PATH, LD_PRELOAD or GIT_* change what an allowed command runs, don't add them"` // AllowedEnv lists the variable names accepted in the env argument.
+	allowedEnv         []string
+	Secrets            map[string]string `json:"secrets" desc:"Secrets injected into commands with use_secrets: a literal value, env:VARNAME or file:/path"` // Secrets maps names to a literal value, env:VARNAME or file:/path, injected into commands with use_secrets.
+	secrets            map[string]Secret
+	AlwaysExplainFirst bool     `json:"always_explain_first" desc:"Return an explanation and a confirm_token on the first call of a command line, and run it only when the token is passed back"` // AlwaysExplainFirst requires a confirmation round trip before every command.
+	ConfirmTimeout     int      `json:"confirm_timeout" desc:"Seconds a confirm_token stays valid"`                                                                                               // ConfirmTimeout is the validity of a confirmation token in seconds.
+	MaxStdinSize       int64    `json:"max_stdin_size" desc:"Maximum size in bytes of the inline stdin of a command, larger input must be passed with stdin_file"`                                // MaxStdinSize limits the inline stdin, after base64 decoding.
+	StdinReadDirs      []string `json:"stdin_read_dirs" desc:"Directories stdin_file may read from, usually the allowed_read_dirs of the file system service, default: the data directory"`       // StdinReadDirs are the directories stdin_file may read from.
+	stdinReadDirs      []string
+	ExecTimeoutSeconds int   `json:"exec_timeout_seconds" desc:"Seconds after which execute_command kills a command and returns its partial output, the maximum of its timeout_seconds"` // ExecTimeoutSeconds is the timeout of execute_command.
+	MaxOutputBytes     int64 `json:"max_output_bytes" desc:"Maximum bytes of output returned by execute_command, the rest is discarded, the maximum of its max_output_bytes"`            // MaxOutputBytes caps the output of execute_command.
+	MaxStreamOutput    int64 `json:"max_stream_output" desc:"Maximum bytes of output kept by command_execute_stream, the middle of longer output is truncated"`                          // MaxStreamOutput caps the output buffered by command_execute_stream.
+	MaxStreamTimeout   int   `json:"max_stream_timeout" desc:"Maximum timeout_seconds of command_execute_stream"`                                                                        // MaxStreamTimeout caps the timeout of a streamed command in seconds.
+	DisableHistory     bool  `json:"disable_history" desc:"Don't record the executed commands in data/command_history.jsonl and don't register the command_history tool"`                // DisableHistory turns the command history off.
+	HistoryMaxSize     int64 `json:"history_max_size" desc:"Size in bytes of a command history file before it is rotated"`                                                               // HistoryMaxSize is the rotation threshold of the command history.
+	HistoryMaxFiles    int   `json:"history_max_files" desc:"Number of rotated command history files kept"`                                                                              // HistoryMaxFiles is the number of rotated command history files.
+	HistoryOutputSize  int   `json:"history_output_size" desc:"Maximum bytes of the output of a command kept in the command history"`                                                    // HistoryOutputSize caps the output of a history entry.
 }
 
 var (
@@ -80,34 +112,86 @@ var (
 		"iostat", "mpstat", "sar", "uptime", "cut", "sort", "uniq", "wc", "awk", "sed",
 		"diff", "cmp", "comm", "file", "basename", "dirname", "chmod", "chown", "curl",
 		"nslookup", "dig", "host", "ssh", "scp", "sftp", "ftp", "wget", "tar", "gzip",
-		"scutil", "networksetup, git", "cd",
+		"scutil", "networksetup", "cd",
+	}
+	// Windows 上 cmd 的只读命令，逐段检查时 cd 之后的命令也要在允许列表中
+	allowedCmdWindows = []string{"dir", "type"}
+	// env 参数默认只允许不会改变命令行为的变量
+	allowedEnvDefault = []string{"LANG", "LC_ALL", "TZ", "NO_COLOR", "COLUMNS", "CI", "NODE_ENV", "RUST_BACKTRACE"}
+	// git 不在默认命令中，默认只允许只读的子命令
+	allowRulesDefault = []interface{}{
+		map[string]interface{}{"cmd": "git", "args": []interface{}{"status", "log", "diff"}},
 	}
 )
 
 // NewCommandConfig creates a new CommandConfig with the given allowed commands.
 func NewCommandConfig() *CommandConfig {
+	allowed := allowedCmdDefault
+	if runtime.GOOS == "windows" {
+		allowed = append(slices.Clone(allowedCmdDefault), allowedCmdWindows...)
+	}
 	return &CommandConfig{
-		allowedCommands: allowedCmdDefault,
-		AllowedCommand:  strings.Join(allowedCmdDefault, ","),
+		allowedCommands:    allowed,
+		AllowedCommand:     strings.Join(allowed, ","),
+		AllowRules:         allowRulesDefault,
+		AllowedEnv:         allowedEnvDefault,
+		Secrets:            map[string]string{},
+		ConfirmTimeout:     60,
+		MaxStdinSize:       1024 * 1024,
+		StdinReadDirs:      []string{},
+		ExecTimeoutSeconds: 60,
+		MaxOutputBytes:     1024 * 1024,
+		MaxStreamOutput:    1024 * 1024,
+		MaxStreamTimeout:   1800,
+		HistoryMaxSize:     16 * 1024 * 1024,
+		HistoryMaxFiles:    2,
+		HistoryOutputSize:  4096,
 	}
 }
 
 // Check validates the allowed commands in the CommandConfig.
 func (cc *CommandConfig) Check() error {
 	cc.prompt = CommandPromptDefault
-	var cnt int
-	cnt = len(cc.allowedCommands)
-
-	// Check if any command is empty
-	for _, cmd := range cc.allowedCommands {
-		if cmd == "" {
-			cnt -= 1
-		}
+	rules, err := parseAllowRules(cc.AllowRules)
+	if err != nil {
+		return err
 	}
-
-	if cnt <= 0 {
+	cc.allowRules = rules
+	if len(cc.rules()) == 0 {
 		return fmt.Errorf("no allowed commands specified")
 	}
+	if cc.ConfirmTimeout <= 0 {
+		return fmt.Errorf("confirm_timeout must be greater than 0")
+	}
+	if cc.MaxStdinSize <= 0 {
+		return fmt.Errorf("max_stdin_size must be greater than 0")
+	}
+	if cc.ExecTimeoutSeconds <= 0 {
+		return fmt.Errorf("exec_timeout_seconds must be greater than 0")
+	}
+	if cc.MaxOutputBytes <= 0 {
+		return fmt.Errorf("max_output_bytes must be greater than 0")
+	}
+	if cc.MaxStreamOutput <= 0 {
+		return fmt.Errorf("max_stream_output must be greater than 0")
+	}
+	if cc.MaxStreamTimeout <= 0 {
+		return fmt.Errorf("max_stream_timeout must be greater than 0")
+	}
+	if cc.HistoryMaxSize <= 0 || cc.HistoryMaxFiles <= 0 || cc.HistoryOutputSize < 0 {
+		return fmt.Errorf("history_max_size and history_max_files must be greater than 0, history_output_size must not be negative")
+	}
+	if err := cc.parseStdinReadDirs(); err != nil {
+		return err
+	}
+	if err := cc.parseAllowedEnv(); err != nil {
+		return err
+	}
+	secrets, err := resolveSecrets(cc.Secrets)
+	if err != nil {
+		return err
+	}
+	cc.secrets = secrets
 	if cc.PromptFile != "" {
 		read, err := os.ReadFile(cc.PromptFile)
 		if err != nil {
@@ -117,3 +201,36 @@ func (cc *CommandConfig) Check() error {
 	}
 	return nil
 }
+
+// parseAllowedEnv validates the names of AllowedEnv.
+func (cc *CommandConfig) parseAllowedEnv() error {
+	names := make([]string, 0, len(cc.AllowedEnv))
+	for _, name := range cc.AllowedEnv {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if strings.ContainsAny(name, "=\x00") {
+			return fmt.Errorf("invalid allowed_env name %q", name)
+		}
+		names = append(names, name)
+	}
+	cc.allowedEnv = names
+	return nil
+}
+
+// parseStdinReadDirs resolves StdinReadDirs to absolute directories ending with a separator.
+func (cc *CommandConfig) parseStdinReadDirs() error {
+	dirs := make([]string, 0, len(cc.StdinReadDirs))
+	for _, dir := range cc.StdinReadDirs {
+		if dir = strings.TrimSpace(dir); dir == "" {
+			continue
+		}
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve stdin read directory %s: %w", dir, err)
+		}
+		dirs = append(dirs, filepath.Clean(abs)+string(filepath.Separator))
+	}
+	cc.stdinReadDirs = dirs
+	return nil
+}
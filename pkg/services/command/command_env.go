@@ -0,0 +1,100 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gojue/moling/pkg/comm"
+)
+
+// parseWorkingDir reads the working_dir argument, an existing directory the command runs in. It
+// returns an empty string when the command runs in the working directory of MoLing.
+func parseWorkingDir(args map[string]interface{}) (string, error) {
+	raw, ok := args["working_dir"]
+	if !ok {
+		return "", nil
+	}
+	dir, ok := raw.(string)
+	if !ok || strings.TrimSpace(dir) == "" {
+		return "", fmt.Errorf("working_dir must be a non-empty string")
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve working_dir %s: %w", dir, err)
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", comm.NewCodedError(comm.ErrCodeNotFound, fmt.Errorf("working_dir %s does not exist", abs))
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("working_dir %s is not a directory", abs)
+	}
+	return abs, nil
+}
+
+// parseCommandEnv reads the env argument, a map of environment variables set for the command, and
+// returns them as sorted KEY=value pairs. The names must be listed in allowed, variables such as
+// PATH or GIT_EXTERNAL_DIFF would otherwise run other commands through an allowed one, and must
+// not collide with the secrets of use_secrets.
+func parseCommandEnv(args map[string]interface{}, secretNames, allowed []string) ([]string, error) {
+	raw, ok := args["env"]
+	if !ok {
+		return nil, nil
+	}
+	vars, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("env must be an object of strings")
+	}
+	env := make([]string, 0, len(vars))
+	for key, rawValue := range vars {
+		value, ok := rawValue.(string)
+		if !ok {
+			return nil, fmt.Errorf("env %s must be a string", key)
+		}
+		if key == "" || strings.ContainsAny(key, "=\x00") {
+			return nil, fmt.Errorf("invalid env name %q: it must be non-empty without = or NUL", key)
+		}
+		if strings.ContainsRune(value, 0) {
+			return nil, fmt.Errorf("env %s must not contain NUL", key)
+		}
+		if slices.Contains(secretNames, key) {
+			return nil, fmt.Errorf("env %s is also passed with use_secrets", key)
+		}
+		if !slices.Contains(allowed, key) {
+			return nil, comm.NewCodedError(comm.ErrCodePermissionDenied, fmt.Errorf("env %s is not allowed, add it to allowed_env in the Command configuration", key))
+		}
+		env = append(env, key+"="+value)
+	}
+	sort.Strings(env)
+	return env, nil
+}
+
+// envNames returns the names of KEY=value pairs.
+func envNames(env []string) []string {
+	names := make([]string, 0, len(env))
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		names = append(names, name)
+	}
+	return names
+}
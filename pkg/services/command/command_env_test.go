@@ -0,0 +1,212 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestCommandWorkingDirAndEnv(t *testing.T) {
+	t.Run("Run", func(t *testing.T) {
+		cs, _ := newSecretsTestServer(t)
+		cs.config.allowedCommands = append(cs.config.allowedCommands, "type")
+		cs.config.allowedEnv = append(cs.config.allowedEnv, "GREETING")
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "marker.txt"), []byte("in-temp-dir\n"), 0600); err != nil {
+			t.Fatalf("Failed to write marker: %v", err)
+		}
+		command := "cat marker.txt; echo greeting=$GREETING"
+		if runtime.GOOS == "windows" {
+			command = "type marker.txt & echo greeting=%GREETING%"
+		}
+		args := map[string]interface{}{
+			"command":     command,
+			"working_dir": dir,
+			"env":         map[string]interface{}{"GREETING": "hello world"},
+		}
+		text, isErr := callExecute(t, cs, args)
+		if isErr || !strings.Contains(text, "in-temp-dir") || !strings.Contains(text, "greeting=hello world") {
+			t.Errorf("Expected the marker and the env var, got %q", text)
+		}
+		if runtime.GOOS != "windows" {
+			text, isErr = callStream(t, cs, args, nil)
+			if isErr || !strings.HasPrefix(text, "in-temp-dir\ngreeting=hello world\n(exit code 0") {
+				t.Errorf("Expected the streamed command to use the directory and the env var, got %q", text)
+			}
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		cs, spawned := newExplainTestServer(t, false)
+		cs.config.allowedEnv = append(cs.config.allowedEnv, "A")
+		file := filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(file, nil, 0600); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		for _, c := range []struct {
+			args map[string]interface{}
+			want string
+		}{
+			{map[string]interface{}{"working_dir": filepath.Join(t.TempDir(), "missing")}, "does not exist"},
+			{map[string]interface{}{"working_dir": file}, "is not a directory"},
+			{map[string]interface{}{"working_dir": " "}, "non-empty string"},
+			{map[string]interface{}{"env": "A=1"}, "object of strings"},
+			{map[string]interface{}{"env": map[string]interface{}{"A=B": "1"}}, "invalid env name"},
+			{map[string]interface{}{"env": map[string]interface{}{"A\x00": "1"}}, "invalid env name"},
+			{map[string]interface{}{"env": map[string]interface{}{"": "1"}}, "invalid env name"},
+			{map[string]interface{}{"env": map[string]interface{}{"A": "1\x002"}}, "must not contain NUL"},
+			{map[string]interface{}{"env": map[string]interface{}{"A": float64(1)}}, "must be a string"},
+			{map[string]interface{}{"env": map[string]interface{}{"ENV_TOKEN": "x"}, "use_secrets": []interface{}{"ENV_TOKEN"}}, "also passed with use_secrets"},
+		} {
+			c.args["command"] = "echo hi"
+			if text, isErr := callExecute(t, cs, c.args); !isErr || !strings.Contains(text, c.want) {
+				t.Errorf("Expected an error containing %q for %v, got %q", c.want, c.args, text)
+			}
+		}
+
+		// 允许检查针对命令本身，working_dir 不会放行其他命令
+		if text, isErr := callExecute(t, cs, map[string]interface{}{"command": "rm -rf marker", "working_dir": t.TempDir()}); !isErr || !strings.Contains(text, "is not allowed") {
+			t.Errorf("Expected the command to be checked against the allowlist, got %q", text)
+		}
+		if *spawned != 0 {
+			t.Errorf("Expected no process to be spawned, got %d", *spawned)
+		}
+	})
+
+	t.Run("Plumbing", func(t *testing.T) {
+		cs, _ := newExplainTestServer(t, false)
+		cs.config.allowedEnv = append(cs.config.allowedEnv, "A", "B")
+		var gotDir string
+		var gotEnv []string
+		cs.execFunc = func(command string, opts execOptions) (*execResult, error) {
+			gotDir, gotEnv = opts.Dir, opts.Env
+			return &execResult{}, nil
+		}
+		dir := t.TempDir()
+		callExecute(t, cs, map[string]interface{}{
+			"command":     "echo hi",
+			"working_dir": dir,
+			"env":         map[string]interface{}{"B": "2", "A": "1"},
+			"use_secrets": []interface{}{"LITERAL_TOKEN"},
+		})
+		if gotDir != dir || !reflect.DeepEqual(gotEnv, []string{"LITERAL_TOKEN=" + testLiteralSecret, "A=1", "B=2"}) {
+			t.Errorf("Expected the directory and the env vars, got %q, %q", gotDir, gotEnv)
+		}
+		callExecute(t, cs, map[string]interface{}{"command": "echo hi"})
+		if gotDir != "" || len(gotEnv) != 0 {
+			t.Errorf("Expected the working directory of MoLing without extra env, got %q, %q", gotDir, gotEnv)
+		}
+
+		text, _ := callExecute(t, cs, map[string]interface{}{
+			"command":     "echo hi",
+			"working_dir": dir,
+			"env":         map[string]interface{}{"B": "2"},
+			"use_secrets": []interface{}{"LITERAL_TOKEN"},
+			"explain":     true,
+		})
+		explanation := parseExplanation(t, text)
+		if explanation.Cwd != dir || !reflect.DeepEqual(explanation.Env, []string{"B", "LITERAL_TOKEN"}) {
+			t.Errorf("Expected the working directory and the env names in the explanation, got %+v", explanation)
+		}
+	})
+
+	t.Run("AllowedEnv", func(t *testing.T) {
+		cs, spawned := newExplainTestServer(t, false)
+		// 这些变量能让允许的命令执行其他程序
+		for _, name := range []string{"GIT_EXTERNAL_DIFF", "GIT_SSH_COMMAND", "PATH", "LD_PRELOAD", "DYLD_INSERT_LIBRARIES", "BASH_ENV", "ENV", "IFS"} {
+			text, isErr := callExecute(t, cs, map[string]interface{}{"command": "git diff", "env": map[string]interface{}{name: "touch marker #"}})
+			if !isErr || !strings.Contains(text, "allowed_env") {
+				t.Errorf("Expected %s to be denied, got %s", name, text)
+			}
+		}
+		if *spawned != 0 {
+			t.Errorf("Expected no process to be spawned, got %d", *spawned)
+		}
+		if text, isErr := callExecute(t, cs, map[string]interface{}{"command": "echo hi", "env": map[string]interface{}{"TZ": "UTC"}}); isErr {
+			t.Errorf("Expected a default allowed_env name to be accepted, got %s", text)
+		}
+
+		if err := cs.LoadConfig(map[string]interface{}{"allowed_env": []interface{}{"APP_MODE", "A=B"}}); err == nil {
+			t.Error("Expected an invalid allowed_env name to be rejected")
+		}
+		if err := cs.LoadConfig(map[string]interface{}{"allowed_env": []interface{}{"APP_MODE"}}); err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if text, isErr := callExecute(t, cs, map[string]interface{}{"command": "echo hi", "env": map[string]interface{}{"TZ": "UTC"}}); !isErr {
+			t.Errorf("Expected TZ to be denied once allowed_env replaces the defaults, got %s", text)
+		}
+	})
+
+	t.Run("ConfirmToken", func(t *testing.T) {
+		cs, spawned := newExplainTestServer(t, true)
+		text, _ := callExecute(t, cs, map[string]interface{}{"command": "echo hi", "working_dir": t.TempDir()})
+		token := parseExplanation(t, text).ConfirmToken
+		// 令牌绑定工作目录和环境变量
+		for _, args := range []map[string]interface{}{
+			{"command": "echo hi", "working_dir": t.TempDir(), "confirm_token": token},
+			{"command": "echo hi", "confirm_token": token},
+		} {
+			if text, isErr := callExecute(t, cs, args); !isErr || text != ErrConfirmTokenMismatch.Error() {
+				t.Errorf("Expected a mismatch for %v, got %s", args, text)
+			}
+		}
+		if *spawned != 0 {
+			t.Errorf("Expected no process to be spawned, got %d", *spawned)
+		}
+	})
+}
+
+func TestExecuteCommandErrorCodes(t *testing.T) {
+	cs, spawned := newExplainTestServer(t, false)
+	for _, c := range []struct {
+		args map[string]interface{}
+		code comm.ErrorCode
+	}{
+		{map[string]interface{}{}, comm.ErrCodeInvalidArgument},
+		{map[string]interface{}{"command": "echo hi", "timeout_seconds": "soon"}, comm.ErrCodeInvalidArgument},
+		{map[string]interface{}{"command": "rm -rf marker"}, comm.ErrCodePermissionDenied},
+		{map[string]interface{}{"command": "git diff", "env": map[string]interface{}{"GIT_EXTERNAL_DIFF": "touch marker #"}}, comm.ErrCodePermissionDenied},
+		{map[string]interface{}{"command": "echo hi", "working_dir": filepath.Join(t.TempDir(), "missing")}, comm.ErrCodeNotFound},
+	} {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = c.args
+		result, err := cs.handleExecuteCommand(context.Background(), request)
+		if err != nil {
+			t.Fatalf("handleExecuteCommand failed: %v", err)
+		}
+		var payload map[string]interface{}
+		if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &payload); err != nil || !result.IsError {
+			t.Fatalf("Expected a JSON error result for %v, got %v", c.args, result.Content[0])
+		}
+		if payload["code"] != string(c.code) || payload["message"] == "" {
+			t.Errorf("Expected code %s and a message for %v, got %v", c.code, c.args, payload)
+		}
+	}
+	if *spawned != 0 {
+		t.Errorf("Expected no process, got %d", *spawned)
+	}
+}
@@ -21,29 +21,91 @@ package command
 import (
 	"context"
 	"errors"
+	"io"
+	"os"
 	"os/exec"
+	"syscall"
 	"time"
 )
 
+var (
+	// commandShell is the shell that runs the command line.
+	commandShell = []string{"sh", "-c"}
+	// commandTimeout is the time after which the command is killed.
+	commandTimeout = time.Second * 10
+)
+
 // ExecCommand executes a command and returns its output.
 func ExecCommand(command string) (string, error) {
-	var cmd *exec.Cmd
-	ctx, cfunc := context.WithTimeout(context.Background(), time.Second*10)
+	return ExecCommandWithEnv(command, nil)
+}
+
+// ExecCommandWithEnv executes a command with extra environment variables (KEY=value) and returns its output.
+func ExecCommandWithEnv(command string, env []string) (string, error) {
+	return ExecCommandWithInput(command, env, nil)
+}
+
+// ExecCommandWithInput executes a command with extra environment variables (KEY=value), streams
+// stdin into it when not nil and returns its output. The stdin of the command is closed at EOF.
+func ExecCommandWithInput(command string, env []string, stdin io.Reader) (string, error) {
+	return ExecCommandInDir(command, "", env, stdin)
+}
+
+// ExecCommandInDir executes a command in dir, the working directory of MoLing when empty, with
+// extra environment variables (KEY=value), streams stdin into it when not nil and returns its output.
+func ExecCommandInDir(command, dir string, env []string, stdin io.Reader) (string, error) {
+	result, err := execCommand(command, execOptions{Dir: dir, Env: env, Stdin: stdin, Timeout: commandTimeout})
+	return result.Output, err
+}
+
+// execCommand runs a command with the options. The command and the processes it spawned are
+// killed when the timeout expires, and the output is cut at MaxOutput bytes.
+func execCommand(command string, opts execOptions) (*execResult, error) {
+	ctx, cfunc := context.WithCancel(context.Background())
+	if opts.Timeout > 0 {
+		ctx, cfunc = context.WithTimeout(context.Background(), opts.Timeout)
+	}
 	defer cfunc()
-	cmd = exec.CommandContext(ctx, "sh", "-c", command)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		switch {
-		case errors.Is(err, exec.ErrNotFound):
-			// 命令未找到
-			return "", errors.New("command not found")
-		case errors.Is(ctx.Err(), context.DeadlineExceeded):
-			// 超时时仅返回输出，不返回错误
-			return string(output), nil
-		default:
-			return string(output), nil
-		}
+	cmd := exec.CommandContext(ctx, commandShell[0], append(commandShell[1:], command)...)
+	cmd.Dir = opts.Dir
+	if len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
+	cmd.Stdin = opts.Stdin
+	out := &limitedOutput{limit: opts.MaxOutput}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+	// 子进程继承输出管道时，杀死后不再无限等待管道关闭
+	cmd.WaitDelay = streamKillDelay
+	err := cmd.Run()
+	result := &execResult{
+		Output:    out.String(),
+		ExitCode:  -1,
+		TimedOut:  errors.Is(ctx.Err(), context.DeadlineExceeded),
+		Truncated: out.Truncated(),
+	}
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
 	}
+	if errors.Is(err, exec.ErrNotFound) {
+		// 命令未找到
+		return result, ErrCommandNotFound
+	}
+	// 非零退出码和超时仅返回输出，不返回错误
+	return result, nil
+}
+
+// setProcessGroup starts the command in a new process group, so that killProcessGroup also kills
+// the processes it spawns.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
 
-	return string(output), nil
+// killProcessGroup kills the process group of a command started with setProcessGroup.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
 }
@@ -20,13 +20,92 @@
 package command
 
 import (
+	"context"
+	"errors"
+	"io"
+	"os"
 	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+var (
+	// commandShell is the shell that runs the command line.
+	commandShell = []string{"cmd", "/C"}
+	// commandTimeout is the time after which the command is killed, 0 means no timeout.
+	commandTimeout time.Duration
 )
 
 // ExecCommand executes a command and returns its output.
 func ExecCommand(command string) (string, error) {
-	var cmd *exec.Cmd
-	cmd = exec.Command("cmd", "/C", command)
-	output, err := cmd.CombinedOutput()
-	return string(output), err
+	return ExecCommandWithEnv(command, nil)
+}
+
+// ExecCommandWithEnv executes a command with extra environment variables (KEY=value) and returns its output.
+func ExecCommandWithEnv(command string, env []string) (string, error) {
+	return ExecCommandWithInput(command, env, nil)
+}
+
+// ExecCommandWithInput executes a command with extra environment variables (KEY=value), streams
+// stdin into it when not nil and returns its output. The stdin of the command is closed at EOF.
+func ExecCommandWithInput(command string, env []string, stdin io.Reader) (string, error) {
+	return ExecCommandInDir(command, "", env, stdin)
+}
+
+// ExecCommandInDir executes a command in dir, the working directory of MoLing when empty, with
+// extra environment variables (KEY=value), streams stdin into it when not nil and returns its output.
+func ExecCommandInDir(command, dir string, env []string, stdin io.Reader) (string, error) {
+	result, err := execCommand(command, execOptions{Dir: dir, Env: env, Stdin: stdin, Timeout: commandTimeout})
+	return result.Output, err
+}
+
+// execCommand runs a command with the options. The command and the processes it spawned are
+// killed when the timeout expires, and the output is cut at MaxOutput bytes.
+func execCommand(command string, opts execOptions) (*execResult, error) {
+	ctx, cfunc := context.WithCancel(context.Background())
+	if opts.Timeout > 0 {
+		ctx, cfunc = context.WithTimeout(context.Background(), opts.Timeout)
+	}
+	defer cfunc()
+	cmd := exec.CommandContext(ctx, commandShell[0], append(commandShell[1:], command)...)
+	cmd.Dir = opts.Dir
+	if len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
+	cmd.Stdin = opts.Stdin
+	out := &limitedOutput{limit: opts.MaxOutput}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+	// 子进程继承输出管道时，杀死后不再无限等待管道关闭
+	cmd.WaitDelay = streamKillDelay
+	err := cmd.Run()
+	result := &execResult{
+		Output:    out.String(),
+		ExitCode:  -1,
+		TimedOut:  errors.Is(ctx.Err(), context.DeadlineExceeded),
+		Truncated: out.Truncated(),
+	}
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if err != nil && !result.TimedOut {
+		// Windows 上非零退出码同样作为错误返回
+		return result, err
+	}
+	return result, nil
+}
+
+// setProcessGroup starts the command in a new process group.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// killProcessGroup kills the command and the processes it spawned.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
 }
@@ -0,0 +1,163 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package command
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrConfirmTokenInvalid is returned when the confirmation token is unknown or has been used.
+	ErrConfirmTokenInvalid = errors.New("invalid or already used confirm_token, call execute_command without it to get a new one")
+	// ErrConfirmTokenExpired is returned when the confirmation token has expired.
+	ErrConfirmTokenExpired = errors.New("confirm_token has expired, call execute_command without it to get a new one")
+	// ErrConfirmTokenMismatch is returned when the confirmation token was issued for another command line.
+	ErrConfirmTokenMismatch = errors.New("confirm_token was issued for a different command or secrets")
+)
+
+// CommandSegment is a simple command of the command line, split by | & ; && || and newlines, with the allow rule it matches.
+type CommandSegment struct {
+	Command string `json:"command"`
+	Rule    string `json:"rule,omitempty"` // 匹配的允许规则，为空表示未匹配
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"` // 未允许的原因
+}
+
+// Explanation describes what execute_command would run, without running it.
+type Explanation struct {
+	Command        string           `json:"command"`
+	Allowed        bool             `json:"allowed"`
+	Segments       []CommandSegment `json:"segments"`
+	Shell          []string         `json:"shell"`   // 解释器及其参数，命令行作为最后一个参数
+	Cwd            string           `json:"cwd"`     // 子进程继承 MoLing 的工作目录
+	Env            []string         `json:"env"`     // 额外注入的环境变量名，不含值
+	Timeout        int              `json:"timeout"` // 超时秒数，0 表示不限制
+	ConfirmToken   string           `json:"confirm_token,omitempty"`
+	ConfirmExpires string           `json:"confirm_expires,omitempty"`
+}
+
+// matchCommand splits the command line into simple commands and matches each against the allow rules.
+func (cs *CommandServer) matchCommand(command string) ([]CommandSegment, bool) {
+	split, err := splitCommandLine(command)
+	if err != nil {
+		return []CommandSegment{{Command: command, Reason: err.Error()}}, false
+	}
+	if len(split) == 0 {
+		return []CommandSegment{{Command: command, Reason: "empty command"}}, false
+	}
+	rules := cs.cfg().rules()
+	segments := make([]CommandSegment, 0, len(split))
+	allowed := true
+	for _, seg := range split {
+		s := matchSegment(seg, rules)
+		segments = append(segments, s)
+		allowed = allowed && s.Allowed
+	}
+	return segments, allowed
+}
+
+// explain performs the checks of execute_command and describes the process it would start.
+func (cs *CommandServer) explain(command string, secretNames []string) *Explanation {
+	segments, allowed := cs.matchCommand(command)
+	cwd, err := os.Getwd()
+	if err != nil {
+		cwd = ""
+	}
+	env := append([]string{}, secretNames...)
+	sort.Strings(env)
+	return &Explanation{
+		Command:  command,
+		Allowed:  allowed,
+		Segments: segments,
+		Shell:    append(append([]string{}, commandShell...), command),
+		Cwd:      cwd,
+		Env:      env,
+	}
+}
+
+// pendingCommand is a command line waiting for its confirmation.
+type pendingCommand struct {
+	key     string
+	expires time.Time
+}
+
+// confirmStore keeps the confirmation tokens of AlwaysExplainFirst. A token is bound to one
+// command line with its secrets, can be used once and expires after the confirm timeout.
+type confirmStore struct {
+	now     func() time.Time
+	mu      sync.Mutex
+	pending map[string]pendingCommand
+}
+
+func newConfirmStore() *confirmStore {
+	return &confirmStore{now: time.Now, pending: make(map[string]pendingCommand)}
+}
+
+// confirmKey identifies a command line with its secrets, working directory and environment variables.
+func confirmKey(command string, secretNames []string, dir string, env []string) string {
+	names := append([]string{}, secretNames...)
+	sort.Strings(names)
+	return strings.Join([]string{command, strings.Join(names, ","), dir, strings.Join(env, "\x00")}, "\x00")
+}
+
+// issue creates a token for the command line that is valid for ttl.
+func (s *confirmStore) issue(key string, ttl time.Duration) (string, time.Time, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(buf)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	// 顺带清理过期的令牌
+	for t, p := range s.pending {
+		if now.After(p.expires) {
+			delete(s.pending, t)
+		}
+	}
+	expires := now.Add(ttl)
+	s.pending[token] = pendingCommand{key: key, expires: expires}
+	return token, expires, nil
+}
+
+// consume checks the token against the command line and invalidates it.
+func (s *confirmStore) consume(token, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.pending[token]
+	if !ok {
+		return ErrConfirmTokenInvalid
+	}
+	if s.now().After(p.expires) {
+		delete(s.pending, token)
+		return ErrConfirmTokenExpired
+	}
+	// 命令不一致时保留令牌，原命令仍可确认执行
+	if p.key != key {
+		return ErrConfirmTokenMismatch
+	}
+	delete(s.pending, token)
+	return nil
+}
@@ -0,0 +1,181 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newExplainTestServer creates a CommandServer whose exec hook counts the started processes instead of starting them.
+func newExplainTestServer(t *testing.T, alwaysExplainFirst bool) (*CommandServer, *int) {
+	t.Helper()
+	cs, _ := newSecretsTestServer(t)
+	cs.config.AlwaysExplainFirst = alwaysExplainFirst
+	spawned := new(int)
+	cs.execFunc = func(command string, opts execOptions) (*execResult, error) {
+		*spawned++
+		return &execResult{Output: "ran " + command}, nil
+	}
+	return cs, spawned
+}
+
+// parseExplanation parses the explanation JSON at the end of the tool result.
+func parseExplanation(t *testing.T, text string) Explanation {
+	t.Helper()
+	var explanation Explanation
+	if err := json.Unmarshal([]byte(text[strings.Index(text, "{"):]), &explanation); err != nil {
+		t.Fatalf("Expected an explanation, got %s", text)
+	}
+	return explanation
+}
+
+func TestExplain(t *testing.T) {
+	cs, spawned := newExplainTestServer(t, false)
+
+	text, isErr := callExecute(t, cs, map[string]interface{}{
+		"command":     "ls -l | grep go & echo done",
+		"use_secrets": []interface{}{"LITERAL_TOKEN", "ENV_TOKEN"},
+		"explain":     true,
+	})
+	if isErr {
+		t.Fatalf("Unexpected error: %s", text)
+	}
+	explanation := parseExplanation(t, text)
+	// 每一段分别匹配规则
+	if !explanation.Allowed || len(explanation.Segments) != 3 {
+		t.Fatalf("Expected three allowed segments, got %+v", explanation)
+	}
+	for i, rule := range []string{"ls", "grep", "echo"} {
+		if explanation.Segments[i].Rule != rule {
+			t.Errorf("Expected segment %d to match %q, got %+v", i, rule, explanation.Segments[i])
+		}
+	}
+	if explanation.Shell[len(explanation.Shell)-1] != "ls -l | grep go & echo done" || explanation.Shell[0] != commandShell[0] {
+		t.Errorf("Unexpected shell %v", explanation.Shell)
+	}
+	if strings.Join(explanation.Env, ",") != "ENV_TOKEN,LITERAL_TOKEN" || explanation.Cwd == "" {
+		t.Errorf("Unexpected env %v or cwd %q", explanation.Env, explanation.Cwd)
+	}
+	if strings.Contains(text, testLiteralSecret) || strings.Contains(text, testEnvSecret) {
+		t.Errorf("Secret value leaked in explanation: %s", text)
+	}
+
+	// 未允许的命令同样只返回说明，并指出每一段匹配的规则
+	text, isErr = callExecute(t, cs, map[string]interface{}{"command": "rm -rf /tmp/x & grep go | echo done", "explain": true})
+	explanation = parseExplanation(t, text)
+	if isErr || explanation.Allowed || len(explanation.Segments) != 3 {
+		t.Fatalf("Expected three segments of a rejected command, got %s", text)
+	}
+	for i, rule := range []string{"", "grep", "echo"} {
+		if explanation.Segments[i].Rule != rule || explanation.Segments[i].Allowed != (rule != "") {
+			t.Errorf("Expected segment %d to match %q, got %+v", i, rule, explanation.Segments[i])
+		}
+	}
+	text, isErr = callExecute(t, cs, map[string]interface{}{"command": "echo hi", "use_secrets": []interface{}{"MISSING"}, "explain": true})
+	if !isErr || !strings.Contains(text, "unknown secret") {
+		t.Errorf("Expected unknown secret error, got %s", text)
+	}
+
+	// explain 模式下从不启动进程，即使开启了确认
+	cs.config.AlwaysExplainFirst = true
+	text, _ = callExecute(t, cs, map[string]interface{}{"command": "echo hi", "explain": true})
+	if parseExplanation(t, text).ConfirmToken != "" {
+		t.Errorf("Expected no confirm_token in explain mode, got %s", text)
+	}
+	if *spawned != 0 {
+		t.Errorf("Expected explain mode not to spawn a process, got %d", *spawned)
+	}
+}
+
+func TestAlwaysExplainFirst(t *testing.T) {
+	cs, spawned := newExplainTestServer(t, true)
+	now := time.Now()
+	cs.confirms.now = func() time.Time { return now }
+	args := func(command, token string) map[string]interface{} {
+		return map[string]interface{}{"command": command, "use_secrets": []interface{}{"ENV_TOKEN"}, "confirm_token": token}
+	}
+	issue := func(command string) string {
+		t.Helper()
+		text, isErr := callExecute(t, cs, args(command, ""))
+		explanation := parseExplanation(t, text)
+		if isErr || explanation.ConfirmToken == "" || explanation.ConfirmExpires == "" {
+			t.Fatalf("Expected an explanation with a confirm_token, got %s", text)
+		}
+		return explanation.ConfirmToken
+	}
+
+	t.Run("SingleUse", func(t *testing.T) {
+		token := issue("echo hi")
+		if *spawned != 0 {
+			t.Fatalf("Expected the first call not to spawn a process, got %d", *spawned)
+		}
+		if text, isErr := callExecute(t, cs, args("echo hi", token)); isErr || text != "ran echo hi" {
+			t.Fatalf("Expected the confirmed command to run, got %s", text)
+		}
+		if text, isErr := callExecute(t, cs, args("echo hi", token)); !isErr || text != ErrConfirmTokenInvalid.Error() {
+			t.Errorf("Expected a used token to be rejected, got %s", text)
+		}
+		if *spawned != 1 {
+			t.Errorf("Expected one process, got %d", *spawned)
+		}
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		token := issue("echo hi")
+		if text, isErr := callExecute(t, cs, args("echo bye", token)); !isErr || text != ErrConfirmTokenMismatch.Error() {
+			t.Errorf("Expected a token of another command to be rejected, got %s", text)
+		}
+		if text, isErr := callExecute(t, cs, map[string]interface{}{"command": "echo hi", "confirm_token": token}); !isErr || text != ErrConfirmTokenMismatch.Error() {
+			t.Errorf("Expected a token issued with other secrets to be rejected, got %s", text)
+		}
+		if text, isErr := callExecute(t, cs, args("echo hi", token)); isErr {
+			t.Errorf("Expected the token to stay valid for its command, got %s", text)
+		}
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		token := issue("echo hi")
+		now = now.Add(time.Duration(cs.config.ConfirmTimeout)*time.Second + time.Second)
+		if text, isErr := callExecute(t, cs, args("echo hi", token)); !isErr || text != ErrConfirmTokenExpired.Error() {
+			t.Errorf("Expected an expired token to be rejected, got %s", text)
+		}
+		if text, _ := callExecute(t, cs, args("echo hi", token)); text != ErrConfirmTokenInvalid.Error() {
+			t.Errorf("Expected an expired token to be removed, got %s", text)
+		}
+	})
+
+	t.Run("NotAllowed", func(t *testing.T) {
+		// 未允许的命令不发放令牌
+		if text, isErr := callExecute(t, cs, args("rm -rf /tmp/x", "")); !isErr || strings.Contains(text, "confirm_token") {
+			t.Errorf("Expected a disallowed command to be rejected, got %s", text)
+		}
+		if _, isErr := callExecute(t, cs, args("echo hi", "bogus")); !isErr {
+			t.Error("Expected an unknown token to be rejected")
+		}
+	})
+
+	if *spawned != 2 {
+		t.Errorf("Expected only the confirmed commands to spawn processes, got %d", *spawned)
+	}
+	if !errors.Is(cs.confirms.consume("bogus", ""), ErrConfirmTokenInvalid) {
+		t.Error("Expected consume to reject an unknown token")
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package command
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// HistoryFileName is the name of the command history in the data directory, rotated to
+	// command_history.jsonl.1 .. N.
+	HistoryFileName = "command_history.jsonl"
+	// defaultHistoryLimit is the number of entries returned by command_history when limit is not given.
+	defaultHistoryLimit = 20
+	// maxHistoryLimit is the largest limit of command_history.
+	maxHistoryLimit = 1000
+)
+
+// HistoryEntry is one executed command in the command history.
+type HistoryEntry struct {
+	Time       time.Time `json:"time"`
+	Tool       string    `json:"tool"`
+	Command    string    `json:"command"`
+	WorkingDir string    `json:"working_dir,omitempty"`
+	ExitCode   int       `json:"exit_code"` // 被杀死或未启动时为 -1
+	TimedOut   bool      `json:"timed_out,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	Output     string    `json:"output"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// commandHistory appends the executed commands to a rotated JSONL file, opened on the first
+// command. Failures are logged and never fail the command.
+type commandHistory struct {
+	path   string
+	config func() *CommandConfig // 返回正在使用的配置，重新加载后读取新配置
+	logger zerolog.Logger
+	mu     sync.Mutex
+	w      *utils.RotateWriter
+}
+
+func newCommandHistory(path string, config func() *CommandConfig, logger zerolog.Logger) *commandHistory {
+	return &commandHistory{path: path, config: config, logger: logger}
+}
+
+// record appends an entry, with the output truncated to history_output_size bytes.
+func (ch *commandHistory) record(entry HistoryEntry) {
+	if ch.config().DisableHistory {
+		return
+	}
+	entry.Time = entry.Time.UTC()
+	entry.Output = truncateOutput(entry.Output, ch.config().HistoryOutputSize)
+	data, err := json.Marshal(entry)
+	if err != nil {
+		ch.logger.Warn().Err(err).Str("command", entry.Command).Msg("failed to encode command history entry")
+		return
+	}
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.w == nil {
+		if err := os.MkdirAll(filepath.Dir(ch.path), 0o755); err != nil {
+			ch.logger.Warn().Err(err).Str("path", ch.path).Msg("failed to create command history directory")
+			return
+		}
+		w, err := utils.NewRotateWriterN(ch.path, ch.config().HistoryMaxSize, ch.config().HistoryMaxFiles)
+		if err != nil {
+			ch.logger.Warn().Err(err).Str("path", ch.path).Msg("failed to open command history")
+			return
+		}
+		ch.w = w
+	}
+	if _, err := ch.w.Write(append(data, '\n')); err != nil {
+		ch.logger.Warn().Err(err).Str("command", entry.Command).Msg("failed to write command history entry")
+	}
+}
+
+// read returns the last limit entries whose command contains filter, oldest first.
+func (ch *commandHistory) read(filter string, limit int) ([]HistoryEntry, error) {
+	files, err := filepath.Glob(ch.path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	// 按修改时间排序，旧文件中的记录在前
+	modTimes := make(map[string]time.Time, len(files))
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			modTimes[file] = info.ModTime()
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool { return modTimes[files[i]].Before(modTimes[files[j]]) })
+	var entries []HistoryEntry
+	for _, file := range files {
+		fileEntries, err := readHistoryFile(file, filter)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries, nil
+}
+
+func readHistoryFile(file, filter string) ([]HistoryEntry, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// 轮转时截断的行等无法解析的行直接跳过
+			continue
+		}
+		if filter != "" && !strings.Contains(entry.Command, filter) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return entries, nil
+}
+
+// close closes the history file.
+func (ch *commandHistory) close() error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.w == nil {
+		return nil
+	}
+	err := ch.w.Close()
+	ch.w = nil
+	return err
+}
+
+// truncateOutput keeps the first size bytes of the output, without splitting a UTF-8 character.
+func truncateOutput(output string, size int) string {
+	if len(output) <= size {
+		return output
+	}
+	cut := size
+	for cut > 0 && !utf8.RuneStart(output[cut]) {
+		cut--
+	}
+	return output[:cut] + truncationMarker(int64(len(output)-cut))
+}
+
+// handleHistory returns the last entries of the command history.
+func (cs *CommandServer) handleHistory(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	limit := defaultHistoryLimit
+	if raw, ok := args["limit"]; ok {
+		n, ok := raw.(float64)
+		if !ok || n != math.Trunc(n) || n < 1 || n > maxHistoryLimit {
+			return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("limit must be an integer between 1 and %d", maxHistoryLimit), ""), nil
+		}
+		limit = int(n)
+	}
+	filter, _ := args["filter"].(string)
+	entries, err := cs.history.read(filter, limit)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to read the command history", err), nil
+	}
+	if entries == nil {
+		entries = []HistoryEntry{}
+	}
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(entries); err != nil {
+		return comm.ToolErrorFromErr("failed to encode the command history", err), nil
+	}
+	return mcp.NewToolResultText(strings.TrimSuffix(buf.String(), "\n")), nil
+}
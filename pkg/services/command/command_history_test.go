@@ -0,0 +1,169 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// readHistory calls command_history and parses its entries.
+func readHistory(t *testing.T, cs *CommandServer, args map[string]interface{}) []HistoryEntry {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = args
+	result, err := cs.handleHistory(context.Background(), request)
+	if err != nil {
+		t.Fatalf("handleHistory failed: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if result.IsError {
+		t.Fatalf("Unexpected error: %s", text)
+	}
+	var entries []HistoryEntry
+	if err := json.Unmarshal([]byte(text), &entries); err != nil {
+		t.Fatalf("Expected history entries, got %s", text)
+	}
+	return entries
+}
+
+func TestCommandHistory(t *testing.T) {
+	t.Run("ReadBack", func(t *testing.T) {
+		cs, _ := newSecretsTestServer(t)
+		if entries := readHistory(t, cs, nil); len(entries) != 0 {
+			t.Fatalf("Expected an empty history, got %v", entries)
+		}
+		dir := t.TempDir()
+		callExecute(t, cs, map[string]interface{}{"command": "echo first $LITERAL_TOKEN", "use_secrets": []interface{}{"LITERAL_TOKEN"}, "working_dir": dir})
+		exitCommand := "ls /nonexistent-moling-dir"
+		callExecute(t, cs, map[string]interface{}{"command": exitCommand})
+		// 未执行的命令不记录
+		callExecute(t, cs, map[string]interface{}{"command": "echo explained", "explain": true})
+		callExecute(t, cs, map[string]interface{}{"command": "rm -rf /tmp/x"})
+
+		entries := readHistory(t, cs, nil)
+		if len(entries) != 2 {
+			t.Fatalf("Expected two entries, got %+v", entries)
+		}
+		first, second := entries[0], entries[1]
+		if first.Tool != "execute_command" || first.Command != "echo first $LITERAL_TOKEN" || first.WorkingDir != dir || first.ExitCode != 0 || first.DurationMs < 0 || first.Time.IsZero() {
+			t.Errorf("Unexpected first entry %+v", first)
+		}
+		if !strings.Contains(first.Output, "first ***") || strings.Contains(first.Output, testLiteralSecret) {
+			t.Errorf("Expected the masked output, got %q", first.Output)
+		}
+		if second.Command != exitCommand || second.ExitCode == 0 || second.Time.Before(first.Time) {
+			t.Errorf("Expected the failed command after the first one, got %+v", second)
+		}
+
+		if entries := readHistory(t, cs, map[string]interface{}{"filter": "nonexistent"}); len(entries) != 1 || entries[0].Command != exitCommand {
+			t.Errorf("Expected the filtered command, got %+v", entries)
+		}
+		if entries := readHistory(t, cs, map[string]interface{}{"limit": float64(1)}); len(entries) != 1 || entries[0].Command != exitCommand {
+			t.Errorf("Expected the last command, got %+v", entries)
+		}
+		for _, limit := range []interface{}{float64(0), 1.5, float64(maxHistoryLimit + 1), "2"} {
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = map[string]interface{}{"limit": limit}
+			if result, _ := cs.handleHistory(context.Background(), request); !result.IsError {
+				t.Errorf("Expected an error for limit %v", limit)
+			}
+		}
+
+		if runtime.GOOS != "windows" {
+			cs.config.allowedCommands = append(cs.config.allowedCommands, "exit")
+			callStream(t, cs, map[string]interface{}{"command": "echo streamed; exit 4"}, nil)
+			entries := readHistory(t, cs, map[string]interface{}{"filter": "streamed"})
+			if len(entries) != 1 || entries[0].Tool != "command_execute_stream" || entries[0].ExitCode != 4 || entries[0].Output != "streamed\n" {
+				t.Errorf("Expected the streamed command, got %+v", entries)
+			}
+		}
+	})
+
+	t.Run("Truncation", func(t *testing.T) {
+		if got := truncateOutput("héllo world", 2); got != "h"+truncationMarker(11) {
+			t.Errorf("Expected the output cut before the multi-byte character, got %q", got)
+		}
+		if got := truncateOutput("short", 10); got != "short" {
+			t.Errorf("Expected the output unchanged, got %q", got)
+		}
+		cs, _ := newSecretsTestServer(t)
+		cs.config.HistoryOutputSize = 4
+		callExecute(t, cs, map[string]interface{}{"command": "echo 0123456789"})
+		if entries := readHistory(t, cs, nil); len(entries) != 1 || !strings.HasPrefix(entries[0].Output, "0123\n[... ") {
+			t.Errorf("Expected the truncated output, got %+v", entries)
+		}
+	})
+
+	t.Run("Rotation", func(t *testing.T) {
+		cs, _ := newSecretsTestServer(t)
+		cs.config.HistoryMaxSize = 256
+		for i := 0; i < 25; i++ {
+			cs.history.record(HistoryEntry{Time: time.Now(), Command: "echo " + strings.Repeat("x", i), Output: "x"})
+		}
+		files, _ := filepath.Glob(cs.history.path + ".*")
+		if len(files) != cs.config.HistoryMaxFiles {
+			t.Errorf("Expected %d history files, got %v", cs.config.HistoryMaxFiles, files)
+		}
+		entries := readHistory(t, cs, map[string]interface{}{"limit": float64(3)})
+		if len(entries) != 3 || entries[2].Command != "echo "+strings.Repeat("x", 24) {
+			t.Errorf("Expected the latest entries after rotation, got %+v", entries)
+		}
+	})
+
+	t.Run("WriteFailure", func(t *testing.T) {
+		cs, logs := newSecretsTestServer(t)
+		// 历史文件所在目录无法创建时只记录日志，命令照常执行
+		blocker := filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(blocker, nil, 0600); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		cs.history = newCommandHistory(filepath.Join(blocker, "data", HistoryFileName), cs.cfg, cs.Logger)
+		text, isErr := callExecute(t, cs, map[string]interface{}{"command": "echo still runs"})
+		if isErr || !strings.Contains(text, "still runs") {
+			t.Errorf("Expected the command to run, got %q", text)
+		}
+		if !strings.Contains(logs.String(), "failed to create command history directory") {
+			t.Errorf("Expected the failure to be logged, got %s", logs.String())
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		cs, _ := newSecretsTestServer(t)
+		cs.config.DisableHistory = true
+		callExecute(t, cs, map[string]interface{}{"command": "echo hi"})
+		if files, _ := filepath.Glob(cs.history.path + ".*"); len(files) != 0 {
+			t.Errorf("Expected no history file, got %v", files)
+		}
+		if err := cs.RegisterTools(); err != nil {
+			t.Fatalf("RegisterTools failed: %v", err)
+		}
+		for _, tool := range cs.Tools() {
+			if tool.Tool.Name == "command_history" {
+				t.Error("Expected command_history not to be registered")
+			}
+		}
+	})
+}
@@ -0,0 +1,99 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// execOptions controls how execCommand runs a command.
+type execOptions struct {
+	Dir       string    // 为空表示 MoLing 的工作目录
+	Env       []string  // 额外的环境变量 KEY=value
+	Stdin     io.Reader // 为 nil 表示没有 stdin
+	Timeout   time.Duration
+	MaxOutput int64 // 0 表示不限制
+}
+
+// execResult is the outcome of execCommand.
+type execResult struct {
+	Output    string
+	ExitCode  int // 被杀死时为 -1
+	TimedOut  bool
+	Truncated bool
+}
+
+// limitedOutput keeps the first limit bytes of the combined stdout and stderr of a command and
+// discards the rest. Writes never fail, so the command is not killed by a broken pipe.
+type limitedOutput struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	limit     int64 // 0 表示不限制
+	truncated bool
+}
+
+func (lo *limitedOutput) Write(p []byte) (int, error) {
+	lo.mu.Lock()
+	defer lo.mu.Unlock()
+	n := len(p)
+	if lo.limit > 0 {
+		if room := lo.limit - int64(lo.buf.Len()); int64(len(p)) > room {
+			p = p[:max(room, 0)]
+			lo.truncated = true
+		}
+	}
+	lo.buf.Write(p)
+	return n, nil
+}
+
+// String returns the kept output, followed by a marker when the rest was discarded.
+func (lo *limitedOutput) String() string {
+	lo.mu.Lock()
+	defer lo.mu.Unlock()
+	if lo.truncated {
+		return lo.buf.String() + fmt.Sprintf("\n[output truncated at %d bytes]", lo.limit)
+	}
+	return lo.buf.String()
+}
+
+// Truncated reports whether output was discarded.
+func (lo *limitedOutput) Truncated() bool {
+	lo.mu.Lock()
+	defer lo.mu.Unlock()
+	return lo.truncated
+}
+
+// boundedArg reads an optional integer argument between 1 and limit, def when it is missing.
+func boundedArg(args map[string]interface{}, name string, def, limit int64) (int64, error) {
+	raw, ok := args[name]
+	if !ok {
+		return min(def, limit), nil
+	}
+	n, ok := raw.(float64)
+	if !ok || n != math.Trunc(n) {
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+	if n < 1 || n > float64(limit) {
+		return 0, fmt.Errorf("%s must be between 1 and %d", name, limit)
+	}
+	return int64(n), nil
+}
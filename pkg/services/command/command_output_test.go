@@ -0,0 +1,118 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package command
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimitedOutput(t *testing.T) {
+	out := &limitedOutput{limit: 5}
+	for _, chunk := range []string{"abc", "def", "ghi"} {
+		if n, err := out.Write([]byte(chunk)); n != len(chunk) || err != nil {
+			t.Errorf("Expected writes to succeed, got %d, %v", n, err)
+		}
+	}
+	if !out.Truncated() || out.String() != "abcde\n[output truncated at 5 bytes]" {
+		t.Errorf("Expected the first 5 bytes and a marker, got %q", out.String())
+	}
+	unlimited := &limitedOutput{}
+	unlimited.Write([]byte("abcdef"))
+	if unlimited.Truncated() || unlimited.String() != "abcdef" {
+		t.Errorf("Expected the whole output, got %q", unlimited.String())
+	}
+}
+
+func TestExecLimits(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test commands need a POSIX shell")
+	}
+
+	t.Run("Timeout", func(t *testing.T) {
+		cs, _ := newSecretsTestServer(t)
+		cs.config.allowedCommands = append(cs.config.allowedCommands, "sleep")
+		cs.config.ExecTimeoutSeconds = 1
+		start := time.Now()
+		text, isErr := callExecute(t, cs, map[string]interface{}{"command": "echo partial; sleep 30 & sleep 30"})
+		if elapsed := time.Since(start); elapsed > time.Second*4 {
+			t.Errorf("Expected the command to be killed after 1s, took %s", elapsed)
+		}
+		if isErr || !strings.HasPrefix(text, "partial\n") || !strings.HasSuffix(text, "(timed out after 1s, the command was killed and the output is partial)") {
+			t.Errorf("Expected the partial output and a timeout message, got %q", text)
+		}
+		if entries := readHistory(t, cs, nil); len(entries) != 1 || !entries[0].TimedOut || entries[0].ExitCode != -1 {
+			t.Errorf("Expected the timeout in the history, got %+v", entries)
+		}
+
+		// 单次调用可以缩短超时，但不能超过配置
+		cs.config.ExecTimeoutSeconds = 60
+		start = time.Now()
+		text, _ = callExecute(t, cs, map[string]interface{}{"command": "sleep 30", "timeout_seconds": float64(1)})
+		if time.Since(start) > time.Second*4 || !strings.Contains(text, "timed out after 1s") {
+			t.Errorf("Expected the timeout_seconds override, got %q", text)
+		}
+		for _, timeout := range []interface{}{float64(61), float64(0), 0.5, "1"} {
+			if text, isErr := callExecute(t, cs, map[string]interface{}{"command": "echo hi", "timeout_seconds": timeout}); !isErr || !strings.Contains(text, "timeout_seconds") {
+				t.Errorf("Expected timeout_seconds %v to be rejected, got %q", timeout, text)
+			}
+		}
+		text, _ = callExecute(t, cs, map[string]interface{}{"command": "echo hi", "timeout_seconds": float64(5), "explain": true})
+		if parseExplanation(t, text).Timeout != 5 {
+			t.Errorf("Expected the timeout in the explanation, got %s", text)
+		}
+	})
+
+	t.Run("OutputCap", func(t *testing.T) {
+		cs, _ := newSecretsTestServer(t)
+		cs.config.allowedCommands = append(cs.config.allowedCommands, "seq")
+		cs.config.MaxOutputBytes = 10
+		text, isErr := callExecute(t, cs, map[string]interface{}{"command": "seq 100000"})
+		if isErr || text != "1\n2\n3\n4\n5\n\n[output truncated at 10 bytes]" {
+			t.Errorf("Expected the output cut at 10 bytes, got %q", text)
+		}
+
+		text, _ = callExecute(t, cs, map[string]interface{}{"command": "seq 100000", "max_output_bytes": float64(4)})
+		if text != "1\n2\n\n[output truncated at 4 bytes]" {
+			t.Errorf("Expected the max_output_bytes override, got %q", text)
+		}
+		if text, isErr := callExecute(t, cs, map[string]interface{}{"command": "seq 3", "max_output_bytes": float64(11)}); !isErr || !strings.Contains(text, "between 1 and 10") {
+			t.Errorf("Expected max_output_bytes above the config to be rejected, got %q", text)
+		}
+		if text, _ := callExecute(t, cs, map[string]interface{}{"command": "seq 3"}); text != "1\n2\n3\n" {
+			t.Errorf("Expected short output unchanged, got %q", text)
+		}
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		if cc := NewCommandConfig(); cc.ExecTimeoutSeconds != 60 || cc.MaxOutputBytes != 1<<20 {
+			t.Errorf("Unexpected defaults %d, %d", cc.ExecTimeoutSeconds, cc.MaxOutputBytes)
+		}
+		for _, modify := range []func(cc *CommandConfig){
+			func(cc *CommandConfig) { cc.ExecTimeoutSeconds = 0 },
+			func(cc *CommandConfig) { cc.MaxOutputBytes = -1 },
+		} {
+			cc := NewCommandConfig()
+			modify(cc)
+			if err := cc.Check(); err == nil {
+				t.Errorf("Expected an invalid config to be rejected: %+v", cc)
+			}
+		}
+	})
+}
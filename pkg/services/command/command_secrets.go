@@ -0,0 +1,133 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	secretEnvPrefix  = "env:"  // 从环境变量读取
+	secretFilePrefix = "file:" // 从文件读取
+	secretMask       = "***"
+)
+
+var secretNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Secret is a resolved secret. Only the name and the source are ever shown to the model or logged.
+type Secret struct {
+	Name   string `json:"name"`
+	Source string `json:"source"` // literal, env:VARNAME or file:/path
+	value  string
+}
+
+// resolveSecrets resolves the secrets section of the config. A spec is a literal value, an
+// env:VARNAME reference or a file:/path reference; surrounding whitespace of file contents is trimmed.
+func resolveSecrets(specs map[string]string) (map[string]Secret, error) {
+	secrets := make(map[string]Secret, len(specs))
+	for name, spec := range specs {
+		if !secretNameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("invalid secret name %q, must be a valid environment variable name", name)
+		}
+		secret := Secret{Name: name, Source: "literal", value: spec}
+		switch {
+		case strings.HasPrefix(spec, secretEnvPrefix):
+			secret.Source = spec
+			varName := strings.TrimPrefix(spec, secretEnvPrefix)
+			value, ok := os.LookupEnv(varName)
+			if !ok {
+				return nil, fmt.Errorf("secret %s: environment variable %s is not set", name, varName)
+			}
+			secret.value = value
+		case strings.HasPrefix(spec, secretFilePrefix):
+			secret.Source = spec
+			path := strings.TrimPrefix(spec, secretFilePrefix)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				// 不返回原始错误之外的文件内容
+				return nil, fmt.Errorf("secret %s: failed to read %s: %v", name, path, err)
+			}
+			secret.value = strings.TrimSpace(string(data))
+		}
+		if secret.value == "" {
+			return nil, fmt.Errorf("secret %s resolves to an empty value", name)
+		}
+		secrets[name] = secret
+	}
+	return secrets, nil
+}
+
+// secretEnv returns the KEY=value environment entries of the named secrets.
+func (cs *CommandServer) secretEnv(names []string) ([]string, error) {
+	env := make([]string, 0, len(names))
+	for _, name := range names {
+		secret, ok := cs.cfg().secrets[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown secret %q, available secrets: %s", name, strings.Join(cs.secretNames(), ", "))
+		}
+		env = append(env, name+"="+secret.value)
+	}
+	return env, nil
+}
+
+// secretNames returns the sorted names of the configured secrets.
+func (cs *CommandServer) secretNames() []string {
+	names := make([]string, 0, len(cs.cfg().secrets))
+	for name := range cs.cfg().secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// scrubSecrets replaces every known secret value in output with ***, longest values first so
+// that a secret containing another one is fully masked.
+func (cs *CommandServer) scrubSecrets(output string) string {
+	values := make([]string, 0, len(cs.cfg().secrets))
+	for _, secret := range cs.cfg().secrets {
+		values = append(values, secret.value)
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for _, value := range values {
+		output = strings.ReplaceAll(output, value, secretMask)
+	}
+	return output
+}
+
+// parseSecretNames reads the use_secrets argument.
+func parseSecretNames(raw interface{}) ([]string, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("use_secrets must be an array of secret names")
+	}
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		name, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("use_secrets must be an array of secret names, got %v", item)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog"
+)
+
+const (
+	testLiteralSecret = "lit-5ecret-value-123"
+	testEnvSecret     = "env-5ecret-value-456"
+	testFileSecret    = "file-5ecret-value-789"
+)
+
+// newSecretsTestServer creates a CommandServer with three secrets whose debug logs go to the returned buffer.
+func newSecretsTestServer(t *testing.T) (*CommandServer, *bytes.Buffer) {
+	t.Helper()
+	_, ctx, err := comm.InitTestEnv()
+	if err != nil {
+		t.Fatalf("Failed to initialize test environment: %v", err)
+	}
+	logs := &bytes.Buffer{}
+	ctx = context.WithValue(ctx, comm.MoLingLoggerKey, zerolog.New(logs).Level(zerolog.DebugLevel))
+	svc, err := NewCommandServer(ctx)
+	if err != nil {
+		t.Fatalf("Failed to create CommandServer: %v", err)
+	}
+
+	t.Setenv("MOLING_TEST_SECRET", testEnvSecret)
+	secretFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(secretFile, []byte(testFileSecret+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+	cc := StructToMap(NewCommandConfig())
+	cc["secrets"] = map[string]interface{}{
+		"LITERAL_TOKEN": testLiteralSecret,
+		"ENV_TOKEN":     "env:MOLING_TEST_SECRET",
+		"FILE_TOKEN":    "file:" + secretFile,
+	}
+	if err := svc.LoadConfig(cc); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	cs := svc.(*CommandServer)
+	cs.history = newCommandHistory(filepath.Join(t.TempDir(), HistoryFileName), cs.cfg, cs.Logger)
+	t.Cleanup(func() { cs.history.close() })
+	return cs, logs
+}
+
+func callExecute(t *testing.T, cs *CommandServer, args map[string]interface{}) (string, bool) {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "execute_command"
+	request.Params.Arguments = args
+	result, err := cs.handleExecuteCommand(context.Background(), request)
+	if err != nil {
+		t.Fatalf("handleExecuteCommand failed: %v", err)
+	}
+	// 错误结果只比较 message 和 details
+	if payload, ok := comm.ParseToolError(result); ok {
+		if payload.Details != "" {
+			return payload.Message + ": " + payload.Details, true
+		}
+		return payload.Message, true
+	}
+	return result.Content[0].(mcp.TextContent).Text, result.IsError
+}
+
+func TestCommandSecrets(t *testing.T) {
+	cs, logs := newSecretsTestServer(t)
+	printEnv := "echo $LITERAL_TOKEN $ENV_TOKEN $FILE_TOKEN"
+	if runtime.GOOS == "windows" {
+		printEnv = "echo %LITERAL_TOKEN% %ENV_TOKEN% %FILE_TOKEN%"
+	}
+
+	t.Run("InjectAndScrub", func(t *testing.T) {
+		text, isErr := callExecute(t, cs, map[string]interface{}{
+			"command":     printEnv,
+			"use_secrets": []interface{}{"LITERAL_TOKEN", "ENV_TOKEN", "FILE_TOKEN"},
+		})
+		if isErr {
+			t.Fatalf("Unexpected error: %s", text)
+		}
+		// 三个密钥都已注入，输出中被替换为 ***
+		if strings.TrimSpace(text) != "*** *** ***" {
+			t.Errorf("Expected all secrets to be injected and scrubbed, got %q", text)
+		}
+	})
+
+	t.Run("ChildEnv", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("sh is required")
+		}
+		// 只打印去掉前缀后的值，不会被替换，用来验证子进程拿到了正确的值
+		text, _ := callExecute(t, cs, map[string]interface{}{
+			"command":     "echo ${ENV_TOKEN#env-}",
+			"use_secrets": []interface{}{"ENV_TOKEN"},
+		})
+		if strings.TrimSpace(text) != strings.TrimPrefix(testEnvSecret, "env-") {
+			t.Errorf("Expected the child to see ENV_TOKEN, got %q", text)
+		}
+		text, _ = callExecute(t, cs, map[string]interface{}{"command": "echo ${#LITERAL_TOKEN}"})
+		if strings.TrimSpace(text) != "0" {
+			t.Errorf("Expected secrets not to be injected without use_secrets, got %q", text)
+		}
+	})
+
+	t.Run("UnknownSecret", func(t *testing.T) {
+		text, isErr := callExecute(t, cs, map[string]interface{}{"command": "echo hi", "use_secrets": []interface{}{"MISSING"}})
+		if !isErr || !strings.Contains(text, "unknown secret") || !strings.Contains(text, "ENV_TOKEN") {
+			t.Errorf("Expected unknown secret error listing the names, got %s", text)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		result, _ := cs.handleSecretsList(context.Background(), mcp.CallToolRequest{})
+		text := result.Content[0].(mcp.TextContent).Text
+		var secrets []Secret
+		if err := json.Unmarshal([]byte(text), &secrets); err != nil {
+			t.Fatalf("Expected JSON, got %s", text)
+		}
+		if len(secrets) != 3 || secrets[0].Name != "ENV_TOKEN" || secrets[0].Source != "env:MOLING_TEST_SECRET" || secrets[2].Source != "literal" {
+			t.Errorf("Unexpected secrets %+v", secrets)
+		}
+		for _, value := range []string{testLiteralSecret, testEnvSecret, testFileSecret} {
+			if strings.Contains(text, value) {
+				t.Errorf("Secret value leaked in list: %s", text)
+			}
+		}
+	})
+
+	t.Run("NoLeakInLogsOrConfig", func(t *testing.T) {
+		config := cs.Config()
+		for _, value := range []string{testLiteralSecret, testEnvSecret, testFileSecret} {
+			if strings.Contains(logs.String(), value) {
+				t.Errorf("Secret value leaked in logs: %s", logs.String())
+			}
+			if strings.Contains(config, value) {
+				t.Errorf("Secret value leaked in config: %s", config)
+			}
+		}
+		if !strings.Contains(logs.String(), "LITERAL_TOKEN") {
+			t.Errorf("Expected secret names in debug logs, got %s", logs.String())
+		}
+	})
+}
+
+func TestResolveSecrets(t *testing.T) {
+	for name, specs := range map[string]map[string]string{
+		"InvalidName": {"BAD-NAME": "value"},
+		"MissingEnv":  {"TOKEN": "env:MOLING_TEST_SECRET_NOT_SET"},
+		"MissingFile": {"TOKEN": "file:/nonexistent/moling/token"},
+		"Empty":       {"TOKEN": ""},
+	} {
+		if _, err := resolveSecrets(specs); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
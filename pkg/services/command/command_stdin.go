@@ -0,0 +1,194 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package command
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gojue/moling/pkg/comm"
+)
+
+var (
+	// ErrStdinTooLarge is returned when the inline stdin is larger than max_stdin_size.
+	ErrStdinTooLarge = errors.New("stdin too large")
+	// ErrStdinPathNotAllowed is returned when stdin_file is outside stdin_read_dirs.
+	ErrStdinPathNotAllowed = comm.NewCodedError(comm.ErrCodePermissionDenied, errors.New("stdin_file not allowed"))
+)
+
+// commandInput is the data fed to the stdin of a command, either inline or streamed from a file.
+type commandInput struct {
+	reader io.Reader
+	file   *os.File // stdin_file 打开的文件，执行后关闭
+	fed    atomic.Int64
+}
+
+func (in *commandInput) Read(p []byte) (int, error) {
+	n, err := in.reader.Read(p)
+	in.fed.Add(int64(n))
+	return n, err
+}
+
+// Close closes the file of stdin_file.
+func (in *commandInput) Close() error {
+	if in.file == nil {
+		return nil
+	}
+	return in.file.Close()
+}
+
+// Fed returns how many bytes were read into the stdin of the command.
+func (in *commandInput) Fed() int64 {
+	return in.fed.Load()
+}
+
+// parseStdin reads the stdin, stdin_lines, stdin_file and stdin_base64 arguments. It returns nil
+// when the command gets no stdin. The file of stdin_file is opened, not read, so that it is streamed.
+func (cs *CommandServer) parseStdin(args map[string]interface{}) (*commandInput, error) {
+	inline, hasInline := args["stdin"]
+	lines, hasLines := args["stdin_lines"]
+	path, hasFile := args["stdin_file"]
+	useBase64, _ := args["stdin_base64"].(bool)
+	if (hasInline && hasFile) || (hasLines && (hasInline || hasFile)) {
+		return nil, fmt.Errorf("stdin, stdin_lines and stdin_file are mutually exclusive")
+	}
+
+	if hasFile {
+		p, ok := path.(string)
+		if !ok || strings.TrimSpace(p) == "" {
+			return nil, fmt.Errorf("stdin_file must be a non-empty string")
+		}
+		if useBase64 {
+			return nil, fmt.Errorf("stdin_base64 only applies to stdin, stdin_file is streamed as is")
+		}
+		realPath, err := cs.validateStdinPath(p)
+		if err != nil {
+			return nil, err
+		}
+		file, err := os.Open(realPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open stdin_file %s: %w", realPath, err)
+		}
+		return &commandInput{reader: file, file: file}, nil
+	}
+
+	if hasLines {
+		if useBase64 {
+			return nil, fmt.Errorf("stdin_base64 only applies to stdin")
+		}
+		text, err := joinStdinLines(lines)
+		if err != nil {
+			return nil, err
+		}
+		inline = text
+	} else if !hasInline {
+		return nil, nil
+	}
+	text, ok := inline.(string)
+	if !ok {
+		return nil, fmt.Errorf("stdin must be a string")
+	}
+	data := []byte(text)
+	if useBase64 {
+		// 先按编码长度估算，避免解码超大的输入
+		if int64(base64.StdEncoding.DecodedLen(len(text))) > cs.cfg().MaxStdinSize+2 {
+			return nil, fmt.Errorf("%w: more than max_stdin_size %d bytes, pass large input with stdin_file", ErrStdinTooLarge, cs.cfg().MaxStdinSize)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+		if err != nil {
+			return nil, fmt.Errorf("stdin is not valid base64: %v", err)
+		}
+		data = decoded
+	}
+	if int64(len(data)) > cs.cfg().MaxStdinSize {
+		return nil, fmt.Errorf("%w: %d bytes, more than max_stdin_size %d bytes, pass large input with stdin_file", ErrStdinTooLarge, len(data), cs.cfg().MaxStdinSize)
+	}
+	return &commandInput{reader: bytes.NewReader(data)}, nil
+}
+
+// joinStdinLines joins the answers of stdin_lines, each ended by a newline as if typed at a prompt.
+func joinStdinLines(raw interface{}) (string, error) {
+	lines, ok := raw.([]interface{})
+	if !ok {
+		return "", fmt.Errorf("stdin_lines must be an array of strings")
+	}
+	var b strings.Builder
+	for i, line := range lines {
+		s, ok := line.(string)
+		if !ok {
+			return "", fmt.Errorf("stdin_lines[%d] must be a string", i)
+		}
+		b.WriteString(s)
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
+// validateStdinPath resolves path to an absolute regular file inside stdin_read_dirs. Symlinks
+// are resolved first, so a link cannot point outside the allowed directories.
+func (cs *CommandServer) validateStdinPath(path string) (string, error) {
+	dirs := cs.cfg().stdinReadDirs
+	if dirs == nil {
+		// 配置未经 Check，解析到副本中，不修改正在使用的配置
+		parsed := *cs.cfg()
+		if err := parsed.parseStdinReadDirs(); err != nil {
+			return "", err
+		}
+		dirs = parsed.stdinReadDirs
+	}
+	if len(dirs) == 0 {
+		return "", fmt.Errorf("%w: %s, stdin_read_dirs is empty", ErrStdinPathNotAllowed, path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path %s: %w", path, err)
+	}
+	realPath, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", fmt.Errorf("failed to access stdin_file %s: %w", abs, err)
+	}
+	allowed := false
+	for _, dir := range dirs {
+		// 允许目录本身可能是软链接，两边都解析后再比较
+		realDir := dir
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			realDir = filepath.Clean(resolved) + string(filepath.Separator)
+		}
+		if strings.HasPrefix(realPath, realDir) || strings.HasPrefix(realPath, dir) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return "", fmt.Errorf("%w: %s is outside the stdin read directories %s", ErrStdinPathNotAllowed, realPath, strings.Join(dirs, ", "))
+	}
+	info, err := os.Stat(realPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to access stdin_file %s: %w", realPath, err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("stdin_file %s is not a regular file", realPath)
+	}
+	return realPath, nil
+}
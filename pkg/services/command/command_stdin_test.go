@@ -0,0 +1,222 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package command
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newStdinTestServer creates a CommandServer that may read stdin_file from the returned directory.
+func newStdinTestServer(t *testing.T) (*CommandServer, string) {
+	t.Helper()
+	cs, _ := newSecretsTestServer(t)
+	dir := t.TempDir()
+	cs.config.allowedCommands = append(cs.config.allowedCommands, "findstr")
+	cs.config.StdinReadDirs = []string{dir}
+	if err := cs.config.parseStdinReadDirs(); err != nil {
+		t.Fatalf("Failed to parse stdin_read_dirs: %v", err)
+	}
+	return cs, dir
+}
+
+// catCommand prints its stdin.
+func catCommand() string {
+	if runtime.GOOS == "windows" {
+		return `findstr "^"`
+	}
+	return "cat"
+}
+
+func TestCommandStdin(t *testing.T) {
+	t.Run("Inline", func(t *testing.T) {
+		cs, _ := newStdinTestServer(t)
+		text, isErr := callExecute(t, cs, map[string]interface{}{"command": catCommand(), "stdin": "banana\napple\n"})
+		if isErr {
+			t.Fatalf("Unexpected error: %s", text)
+		}
+		if !strings.Contains(text, "banana") || !strings.Contains(text, "apple") {
+			t.Errorf("Expected the stdin in the output, got %q", text)
+		}
+		if !strings.HasSuffix(text, "(13 bytes fed to stdin)") {
+			t.Errorf("Expected the fed byte count, got %q", text)
+		}
+
+		// 没有 stdin 参数时不附加说明
+		text, _ = callExecute(t, cs, map[string]interface{}{"command": "echo hi"})
+		if strings.Contains(text, "fed to stdin") {
+			t.Errorf("Unexpected stdin note without stdin: %q", text)
+		}
+	})
+
+	t.Run("Base64", func(t *testing.T) {
+		cs, _ := newStdinTestServer(t)
+		encoded := base64.StdEncoding.EncodeToString([]byte("héllo\n"))
+		text, isErr := callExecute(t, cs, map[string]interface{}{"command": catCommand(), "stdin": encoded, "stdin_base64": true})
+		if isErr || !strings.Contains(text, "héllo") || !strings.HasSuffix(text, "(7 bytes fed to stdin)") {
+			t.Errorf("Expected the decoded stdin, got %q", text)
+		}
+
+		text, isErr = callExecute(t, cs, map[string]interface{}{"command": catCommand(), "stdin": "not base64!", "stdin_base64": true})
+		if !isErr || !strings.Contains(text, "not valid base64") {
+			t.Errorf("Expected a base64 error, got %q", text)
+		}
+	})
+
+	t.Run("Interactive", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("the test script needs a POSIX shell")
+		}
+		cs, dir := newStdinTestServer(t)
+		cs.config.allowedCommands = append(cs.config.allowedCommands, "sh")
+		script := filepath.Join(dir, "confirm.sh")
+		body := "printf 'Continue? '\nread answer\necho \"got $answer\"\nprintf 'Name? '\nread name || echo 'no name'\necho \"name $name\"\n"
+		if err := os.WriteFile(script, []byte(body), 0600); err != nil {
+			t.Fatalf("Failed to write script: %v", err)
+		}
+
+		// stdin 用完后关闭，第二个提示读到 EOF 而不是一直等待
+		start := time.Now()
+		text, isErr := callExecute(t, cs, map[string]interface{}{"command": "sh " + script, "stdin": "yes\n"})
+		if isErr || !strings.Contains(text, "Continue? got yes") || !strings.Contains(text, "no name") || !strings.HasSuffix(text, "(4 bytes fed to stdin)") {
+			t.Errorf("Expected the script to read yes, got %q", text)
+		}
+		if elapsed := time.Since(start); elapsed > commandTimeout/2 {
+			t.Errorf("Expected the script not to wait for more input, took %s", elapsed)
+		}
+
+		text, isErr = callExecute(t, cs, map[string]interface{}{"command": "sh " + script, "stdin_lines": []interface{}{"yes", "moling"}})
+		if isErr || !strings.Contains(text, "got yes") || !strings.Contains(text, "name moling") || strings.Contains(text, "no name") {
+			t.Errorf("Expected the script to read both lines, got %q", text)
+		}
+
+		for _, args := range []map[string]interface{}{
+			{"stdin_lines": "yes"},
+			{"stdin_lines": []interface{}{"yes", float64(1)}},
+			{"stdin_lines": []interface{}{"yes"}, "stdin": "yes\n"},
+			{"stdin_lines": []interface{}{"yes"}, "stdin_base64": true},
+		} {
+			args["command"] = "sh " + script
+			if text, isErr := callExecute(t, cs, args); !isErr {
+				t.Errorf("Expected an error for %v, got %q", args, text)
+			}
+		}
+	})
+
+	t.Run("SizeCap", func(t *testing.T) {
+		cs, _ := newStdinTestServer(t)
+		spawned := 0
+		cs.execFunc = func(command string, opts execOptions) (*execResult, error) {
+			spawned++
+			return &execResult{}, nil
+		}
+		cs.config.MaxStdinSize = 16
+		text, isErr := callExecute(t, cs, map[string]interface{}{"command": "cat", "stdin": strings.Repeat("x", 17)})
+		if !isErr || !strings.Contains(text, ErrStdinTooLarge.Error()) || !strings.Contains(text, "stdin_file") {
+			t.Errorf("Expected a size error, got %q", text)
+		}
+		// 上限按解码后的大小计算
+		encoded := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", 16)))
+		if text, isErr = callExecute(t, cs, map[string]interface{}{"command": "cat", "stdin": encoded, "stdin_base64": true}); isErr {
+			t.Errorf("Expected 16 decoded bytes to be accepted, got %q", text)
+		}
+		encoded = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", 64)))
+		if text, isErr = callExecute(t, cs, map[string]interface{}{"command": "cat", "stdin": encoded, "stdin_base64": true}); !isErr {
+			t.Errorf("Expected 64 decoded bytes to be rejected, got %q", text)
+		}
+		if spawned != 1 {
+			t.Errorf("Expected only the accepted command to run, ran %d", spawned)
+		}
+	})
+
+	t.Run("File", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("wc is not available on windows")
+		}
+		cs, dir := newStdinTestServer(t)
+		const size = 8 << 20
+		path := filepath.Join(dir, "big.txt")
+		line := strings.Repeat("x", 1023) + "\n"
+		if err := os.WriteFile(path, []byte(strings.Repeat(line, size/len(line))), 0600); err != nil {
+			t.Fatalf("Failed to write stdin file: %v", err)
+		}
+
+		// 文件按块写入，不会整体读入内存
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		text, isErr := callExecute(t, cs, map[string]interface{}{"command": "wc -c", "stdin_file": path})
+		runtime.ReadMemStats(&after)
+		if isErr || !strings.Contains(text, "8388608") || !strings.HasSuffix(text, "(8388608 bytes fed to stdin)") {
+			t.Fatalf("Expected wc to count the whole file, got %q", text)
+		}
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > size/4 {
+			t.Errorf("Expected the file to be streamed, %d bytes were allocated", allocated)
+		}
+	})
+
+	t.Run("FileValidation", func(t *testing.T) {
+		cs, dir := newStdinTestServer(t)
+		outside := filepath.Join(t.TempDir(), "secret.txt")
+		if err := os.WriteFile(outside, []byte("secret"), 0600); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		text, isErr := callExecute(t, cs, map[string]interface{}{"command": catCommand(), "stdin_file": outside})
+		if !isErr || !strings.Contains(text, ErrStdinPathNotAllowed.Error()) {
+			t.Errorf("Expected a path error, got %q", text)
+		}
+		if runtime.GOOS != "windows" {
+			link := filepath.Join(dir, "link.txt")
+			if err := os.Symlink(outside, link); err != nil {
+				t.Fatalf("Failed to create symlink: %v", err)
+			}
+			if text, isErr = callExecute(t, cs, map[string]interface{}{"command": catCommand(), "stdin_file": link}); !isErr {
+				t.Errorf("Expected a symlink out of the directory to be rejected, got %q", text)
+			}
+		}
+		if text, isErr = callExecute(t, cs, map[string]interface{}{"command": catCommand(), "stdin_file": dir}); !isErr {
+			t.Errorf("Expected a directory to be rejected, got %q", text)
+		}
+		text, isErr = callExecute(t, cs, map[string]interface{}{"command": catCommand(), "stdin": "x", "stdin_file": outside})
+		if !isErr || !strings.Contains(text, "mutually exclusive") {
+			t.Errorf("Expected stdin and stdin_file to be exclusive, got %q", text)
+		}
+
+		// 命令允许列表不因 stdin 改变
+		text, isErr = callExecute(t, cs, map[string]interface{}{"command": "rm -rf /tmp/x", "stdin": "y\n"})
+		if !isErr || !strings.Contains(text, "is not allowed") {
+			t.Errorf("Expected the allowlist to apply, got %q", text)
+		}
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cc := NewCommandConfig()
+		cc.allowedCommands = []string{"cat"}
+		cc.MaxStdinSize = 0
+		if err := cc.Check(); err == nil {
+			t.Errorf("Expected max_stdin_size 0 to be rejected")
+		}
+		if NewCommandConfig().MaxStdinSize != 1<<20 {
+			t.Errorf("Unexpected default max_stdin_size %d", NewCommandConfig().MaxStdinSize)
+		}
+	})
+}
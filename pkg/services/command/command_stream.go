@@ -0,0 +1,316 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// defaultStreamTimeout is the timeout_seconds of command_execute_stream when it is not given.
+	defaultStreamTimeout = 300
+	// streamKillDelay is how long to wait for the output pipes to close after the command is killed.
+	streamKillDelay = time.Second * 2
+)
+
+// streamInterval is how often the new output of a streamed command is sent to the client.
+var streamInterval = time.Second
+
+// streamOptions controls how streamCommand runs a command.
+type streamOptions struct {
+	Dir       string
+	Env       []string
+	Stdin     io.Reader
+	Timeout   time.Duration
+	MaxOutput int64              // 保留的输出上限，超出时丢弃中间部分
+	OnOutput  func(chunk string) // 每隔 streamInterval 收到新输出，可为 nil
+}
+
+// streamResult is the outcome of a streamed command.
+type streamResult struct {
+	Output    string
+	ExitCode  int // 被杀死时为 -1
+	TimedOut  bool
+	Canceled  bool
+	Truncated int64 // 丢弃的输出字节数
+	Duration  time.Duration
+}
+
+// streamOutput keeps the combined stdout and stderr of a command, at most limit bytes: the start
+// and the end of the output are kept and the middle is dropped. It also keeps the output that
+// has not been sent to the client yet.
+type streamOutput struct {
+	mu      sync.Mutex
+	limit   int
+	head    []byte
+	tail    []byte
+	dropped int64
+	pending []byte // 尚未推送的输出
+	skipped int64  // 推送前丢弃的输出字节数
+}
+
+func newStreamOutput(limit int64) *streamOutput {
+	if limit > math.MaxInt32 {
+		limit = math.MaxInt32
+	}
+	return &streamOutput{limit: int(limit)}
+}
+
+func (so *streamOutput) Write(p []byte) (int, error) {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+	n := len(p)
+
+	so.pending = append(so.pending, p...)
+	if over := len(so.pending) - so.limit; over > 0 {
+		so.skipped += int64(over)
+		so.pending = so.pending[over:]
+	}
+
+	if room := so.limit/2 - len(so.head); room > 0 {
+		room = min(room, len(p))
+		so.head = append(so.head, p[:room]...)
+		p = p[room:]
+	}
+	so.tail = append(so.tail, p...)
+	if over := len(so.tail) - (so.limit - so.limit/2); over > 0 {
+		so.dropped += int64(over)
+		so.tail = so.tail[over:]
+	}
+	return n, nil
+}
+
+// String returns the kept output with a truncation marker in place of the dropped bytes.
+func (so *streamOutput) String() string {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+	if so.dropped == 0 {
+		return string(so.head) + string(so.tail)
+	}
+	return string(so.head) + truncationMarker(so.dropped) + string(so.tail)
+}
+
+// Dropped returns how many bytes of the output were dropped.
+func (so *streamOutput) Dropped() int64 {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+	return so.dropped
+}
+
+// take returns the output that has not been sent yet. Unless final, it stops at the last newline,
+// so that secrets are masked in whole lines.
+func (so *streamOutput) take(final bool) string {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+	end := len(so.pending)
+	if !final {
+		end = bytes.LastIndexByte(so.pending, '\n') + 1
+	}
+	if end == 0 && so.skipped == 0 {
+		return ""
+	}
+	chunk := string(so.pending[:end])
+	if so.skipped > 0 {
+		chunk = truncationMarker(so.skipped) + chunk
+		so.skipped = 0
+	}
+	so.pending = append(so.pending[:0], so.pending[end:]...)
+	return chunk
+}
+
+// truncationMarker replaces n dropped bytes of output.
+func truncationMarker(n int64) string {
+	return fmt.Sprintf("\n[... %d bytes truncated ...]\n", n)
+}
+
+// streamCommand runs a command line, sends its output to opts.OnOutput while it runs and returns
+// the whole output once it exits. The command and the processes it spawns are killed when the
+// timeout expires or ctx is canceled.
+func streamCommand(ctx context.Context, command string, opts streamOptions) (*streamResult, error) {
+	runCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(runCtx, commandShell[0], append(commandShell[1:], command)...)
+	cmd.Dir = opts.Dir
+	if len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
+	cmd.Stdin = opts.Stdin
+	out := newStreamOutput(opts.MaxOutput)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+	// 子进程继承输出管道时，杀死后不再无限等待管道关闭
+	cmd.WaitDelay = streamKillDelay
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, ErrCommandNotFound
+		}
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	if opts.OnOutput != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(streamInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if chunk := out.take(false); chunk != "" {
+						opts.OnOutput(chunk)
+					}
+				case <-done:
+					if chunk := out.take(true); chunk != "" {
+						opts.OnOutput(chunk)
+					}
+					return
+				}
+			}
+		}()
+	}
+
+	err := cmd.Wait()
+	close(done)
+	wg.Wait()
+
+	result := &streamResult{
+		Output:    out.String(),
+		ExitCode:  cmd.ProcessState.ExitCode(),
+		Canceled:  ctx.Err() != nil,
+		Truncated: out.Dropped(),
+		Duration:  time.Since(start),
+	}
+	result.TimedOut = !result.Canceled && errors.Is(runCtx.Err(), context.DeadlineExceeded)
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) && !errors.Is(err, exec.ErrWaitDelay) && runCtx.Err() == nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// handleExecuteStream executes a command like execute_command, and sends its output to the client
+// as notifications while it runs: progress notifications when the request has a progress token,
+// logging notifications otherwise.
+func (cs *CommandServer) handleExecuteStream(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	seconds, err := boundedArg(args, "timeout_seconds", defaultStreamTimeout, int64(cs.cfg().MaxStreamTimeout))
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	timeout := time.Duration(seconds) * time.Second
+	run, result := cs.prepareCommand("command_execute_stream", args, timeout)
+	if result != nil {
+		return result, nil
+	}
+	opts := streamOptions{
+		Dir:       run.dir,
+		Env:       run.env,
+		Timeout:   timeout,
+		MaxOutput: cs.cfg().MaxStreamOutput,
+	}
+	if run.input != nil {
+		defer run.input.Close()
+		opts.Stdin = run.input
+	}
+
+	var token mcp.ProgressToken
+	if request.Params.Meta != nil {
+		token = request.Params.Meta.ProgressToken
+	}
+	progress := 0
+	opts.OnOutput = func(chunk string) {
+		chunk = cs.scrubSecrets(chunk)
+		progress++
+		method, params := "notifications/message", map[string]any{
+			"level":  mcp.LoggingLevelInfo,
+			"logger": "command_execute_stream",
+			"data":   map[string]any{"command": run.command, "output": chunk},
+		}
+		if token != nil {
+			method, params = "notifications/progress", map[string]any{
+				"progressToken": token,
+				"progress":      progress,
+				"message":       chunk,
+			}
+		}
+		if err := cs.notify(ctx, method, params); err != nil {
+			cs.Logger.Debug().Err(err).Msg("failed to send the command output to the client")
+		}
+	}
+
+	res, err := streamCommand(ctx, run.command, opts)
+	entry := HistoryEntry{
+		Time:       time.Now(),
+		Tool:       "command_execute_stream",
+		Command:    run.command,
+		WorkingDir: run.dir,
+		ExitCode:   -1,
+	}
+	if res != nil {
+		entry.Time = entry.Time.Add(-res.Duration)
+		entry.ExitCode = res.ExitCode
+		entry.TimedOut = res.TimedOut
+		entry.DurationMs = res.Duration.Milliseconds()
+		entry.Output = cs.scrubSecrets(res.Output)
+	}
+	if err != nil {
+		entry.Error = cs.scrubSecrets(err.Error())
+	}
+	cs.history.record(entry)
+	if err != nil {
+		return comm.ToolError(comm.ErrorCodeOf(err), "failed to execute the command", cs.scrubSecrets(err.Error())), nil
+	}
+
+	var status string
+	switch {
+	case res.TimedOut:
+		status = fmt.Sprintf("killed after the timeout of %s", timeout)
+	case res.Canceled:
+		status = "killed after the request was canceled"
+	default:
+		status = fmt.Sprintf("exit code %d after %s", res.ExitCode, res.Duration.Round(time.Millisecond))
+	}
+	if res.Truncated > 0 {
+		status += fmt.Sprintf(", %d bytes of output truncated", res.Truncated)
+	}
+	if run.input != nil {
+		status += fmt.Sprintf(", %d bytes fed to stdin", run.input.Fed())
+	}
+	output := fmt.Sprintf("%s\n(%s)", strings.TrimRight(res.Output, "\n"), status)
+	return mcp.NewToolResultText(cs.scrubSecrets(output)), nil
+}
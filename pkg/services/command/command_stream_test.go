@@ -0,0 +1,226 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// notification is a notification sent by a CommandServer under test.
+type notification struct {
+	method string
+	params map[string]any
+}
+
+// newStreamTestServer creates a CommandServer whose notifications are returned by the function.
+func newStreamTestServer(t *testing.T) (*CommandServer, func() []notification) {
+	t.Helper()
+	cs, _ := newSecretsTestServer(t)
+	cs.config.allowedCommands = append(cs.config.allowedCommands, "for", "seq", "true", "sleep", "exit")
+	interval := streamInterval
+	streamInterval = time.Millisecond * 20
+	t.Cleanup(func() { streamInterval = interval })
+
+	var mu sync.Mutex
+	var sent []notification
+	cs.notify = func(ctx context.Context, method string, params map[string]any) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, notification{method: method, params: params})
+		return nil
+	}
+	return cs, func() []notification {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]notification(nil), sent...)
+	}
+}
+
+func callStream(t *testing.T, cs *CommandServer, args map[string]interface{}, token mcp.ProgressToken) (string, bool) {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "command_execute_stream"
+	request.Params.Arguments = args
+	if token != nil {
+		request.Params.Meta = &mcp.Meta{ProgressToken: token}
+	}
+	result, err := cs.handleExecuteStream(context.Background(), request)
+	if err != nil {
+		t.Fatalf("handleExecuteStream failed: %v", err)
+	}
+	return result.Content[0].(mcp.TextContent).Text, result.IsError
+}
+
+func TestStreamOutput(t *testing.T) {
+	out := newStreamOutput(10)
+	out.Write([]byte("abc\nde"))
+	if chunk := out.take(false); chunk != "abc\n" {
+		t.Errorf("Expected the complete lines, got %q", chunk)
+	}
+	if chunk := out.take(false); chunk != "" {
+		t.Errorf("Expected no complete line, got %q", chunk)
+	}
+	out.Write([]byte("fghijklmnop\n"))
+	if out.String() != "abc\nd"+truncationMarker(8)+"mnop\n" || out.Dropped() != 8 {
+		t.Errorf("Expected the start and the end of the output, got %q", out.String())
+	}
+	if chunk := out.take(true); chunk != truncationMarker(4)+"hijklmnop\n" {
+		t.Errorf("Expected the pending output after a marker, got %q", chunk)
+	}
+	if chunk := out.take(true); chunk != "" {
+		t.Errorf("Expected nothing pending, got %q", chunk)
+	}
+}
+
+func TestExecuteStream(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test commands need a POSIX shell")
+	}
+
+	t.Run("Notifications", func(t *testing.T) {
+		cs, sent := newStreamTestServer(t)
+		text, isErr := callStream(t, cs, map[string]interface{}{
+			"command":     "for i in 0 1 2; do echo tick-$i $LITERAL_TOKEN; sleep 0.05; done",
+			"use_secrets": []interface{}{"LITERAL_TOKEN"},
+		}, "build-1")
+		if isErr {
+			t.Fatalf("Unexpected error: %s", text)
+		}
+		if !strings.Contains(text, "tick-0 ***") || !strings.Contains(text, "tick-2 ***") || !strings.Contains(text, "(exit code 0 after ") {
+			t.Errorf("Expected the whole output and the exit code, got %q", text)
+		}
+
+		notifications := sent()
+		if len(notifications) < 2 {
+			t.Fatalf("Expected the output to be sent while the command runs, got %v", notifications)
+		}
+		var streamed strings.Builder
+		for i, n := range notifications {
+			if n.method != "notifications/progress" || n.params["progressToken"] != "build-1" || n.params["progress"] != i+1 {
+				t.Errorf("Unexpected notification %d: %v", i, n)
+			}
+			streamed.WriteString(n.params["message"].(string))
+		}
+		if streamed.String() != "tick-0 ***\ntick-1 ***\ntick-2 ***\n" || strings.Contains(streamed.String(), testLiteralSecret) {
+			t.Errorf("Expected the masked output in the notifications, got %q", streamed.String())
+		}
+
+		// 没有 progressToken 时发送日志通知
+		cs, sent = newStreamTestServer(t)
+		callStream(t, cs, map[string]interface{}{"command": "echo hi; exit 3"}, nil)
+		notifications = sent()
+		if len(notifications) != 1 || notifications[0].method != "notifications/message" {
+			t.Fatalf("Expected a logging notification, got %v", notifications)
+		}
+		if data := notifications[0].params["data"].(map[string]any); data["output"] != "hi\n" || data["command"] != "echo hi; exit 3" {
+			t.Errorf("Unexpected logging notification %v", data)
+		}
+	})
+
+	t.Run("ExitCode", func(t *testing.T) {
+		cs, _ := newStreamTestServer(t)
+		text, isErr := callStream(t, cs, map[string]interface{}{"command": "echo failed >&2; exit 3", "stdin": "x"}, nil)
+		if isErr || !strings.HasPrefix(text, "failed\n(exit code 3 after ") || !strings.HasSuffix(text, ", 1 bytes fed to stdin)") {
+			t.Errorf("Expected stderr and the exit code, got %q", text)
+		}
+	})
+
+	t.Run("TimeoutKill", func(t *testing.T) {
+		cs, _ := newStreamTestServer(t)
+		leaked := filepath.Join(t.TempDir(), "leaked")
+		start := time.Now()
+		text, isErr := callStream(t, cs, map[string]interface{}{
+			"command":         "sleep 1 && echo leaked > " + leaked + " & while true; do echo tick; sleep 0.1; done",
+			"timeout_seconds": float64(1),
+		}, nil)
+		if elapsed := time.Since(start); elapsed > time.Second*3 {
+			t.Errorf("Expected the command to be killed after 1s, took %s", elapsed)
+		}
+		if isErr || !strings.HasPrefix(text, "tick\n") || !strings.HasSuffix(text, "(killed after the timeout of 1s)") {
+			t.Errorf("Expected the output and the timeout, got %q", text)
+		}
+		// 同一进程组的子进程也被杀死
+		time.Sleep(time.Millisecond * 500)
+		if _, err := os.Stat(leaked); err == nil {
+			t.Error("Expected the background process to be killed")
+		}
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*200)
+		defer cancel()
+		res, err := streamCommand(ctx, "sleep 30", streamOptions{Timeout: time.Minute, MaxOutput: 100})
+		if err != nil || !res.Canceled || res.TimedOut || res.ExitCode != -1 || res.Duration > time.Second*3 {
+			t.Errorf("Expected the command to be killed on cancel, got %+v, %v", res, err)
+		}
+	})
+
+	t.Run("OutputCap", func(t *testing.T) {
+		cs, sent := newStreamTestServer(t)
+		cs.config.MaxStreamOutput = 200
+		text, isErr := callStream(t, cs, map[string]interface{}{
+			"command": "seq 1000 | sed 's/^/line-/'",
+		}, "cap")
+		if isErr || !strings.HasPrefix(text, "line-1\nline-2\n") || !strings.Contains(text, "line-1000\n(exit code 0") {
+			t.Fatalf("Expected the start and the end of the output, got %q", text)
+		}
+		if !strings.Contains(text, " bytes truncated ...]\n") || !strings.Contains(text, " bytes of output truncated)") || strings.Contains(text, "line-500\n") {
+			t.Errorf("Expected the middle of the output to be truncated, got %q", text)
+		}
+		if output, _, _ := strings.Cut(text, "\n("); len(output) > 200+len(truncationMarker(10000)) {
+			t.Errorf("Expected at most 200 bytes of output, got %d", len(output))
+		}
+		for _, n := range sent() {
+			if message := n.params["message"].(string); len(message) > 200+len(truncationMarker(10000)) {
+				t.Errorf("Expected capped notifications, got %d bytes", len(message))
+			}
+		}
+	})
+
+	t.Run("Arguments", func(t *testing.T) {
+		cs, sent := newStreamTestServer(t)
+		for _, args := range []map[string]interface{}{
+			{"command": "echo hi", "timeout_seconds": float64(0)},
+			{"command": "echo hi", "timeout_seconds": 1.5},
+			{"command": "echo hi", "timeout_seconds": float64(cs.config.MaxStreamTimeout + 1)},
+			{"command": "echo hi", "timeout_seconds": "10"},
+			{"command": "rm -rf /tmp/x"},
+			{"command": "echo hi", "use_secrets": []interface{}{"MISSING"}},
+		} {
+			if text, isErr := callStream(t, cs, args, nil); !isErr {
+				t.Errorf("Expected an error for %v, got %q", args, text)
+			}
+		}
+		if len(sent()) != 0 {
+			t.Errorf("Expected no command to run, got %v", sent())
+		}
+
+		text, isErr := callStream(t, cs, map[string]interface{}{"command": "echo hi", "explain": true}, nil)
+		if isErr || !strings.Contains(text, `"allowed":true`) {
+			t.Errorf("Expected an explanation, got %q", text)
+		}
+	})
+}
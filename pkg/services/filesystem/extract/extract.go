@@ -0,0 +1,218 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+// Package extract extracts plain text from documents such as PDF, DOCX, XLSX and PPTX files.
+// Every format is an Extractor, new formats are added with Register.
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrEncrypted is returned for password protected documents.
+	ErrEncrypted = errors.New("document is encrypted")
+	// ErrUnsupported is returned when no extractor handles the document.
+	ErrUnsupported = errors.New("unsupported document format")
+)
+
+// HeadSize is the number of leading bytes passed to Extractor.Match.
+const HeadSize = 512
+
+// Section kinds.
+const (
+	KindDocument = "document"
+	KindPage     = "page"
+	KindSheet    = "sheet"
+	KindSlide    = "slide"
+)
+
+// Options limits the extracted parts of a document.
+type Options struct {
+	Pages  string // Pages selects PDF pages, e.g. 1-3,5. Empty means all.
+	Sheets string // Sheets selects XLSX sheets by name or index ranges, e.g. Summary or 1-2. Empty means all.
+	Slides string // Slides selects PPTX slides, e.g. 2-4. Empty means all.
+}
+
+// Section is the text of a page, sheet, slide or a whole document.
+type Section struct {
+	Kind  string
+	Index int    // 1-based index of the page, sheet or slide
+	Name  string // sheet name
+	Text  string
+}
+
+// Title returns the separator title of the section, e.g. "Page 2" or "Sheet 1: Summary".
+func (s Section) Title() string {
+	switch s.Kind {
+	case KindPage:
+		return fmt.Sprintf("Page %d", s.Index)
+	case KindSlide:
+		return fmt.Sprintf("Slide %d", s.Index)
+	case KindSheet:
+		return fmt.Sprintf("Sheet %d: %s", s.Index, s.Name)
+	default:
+		return ""
+	}
+}
+
+// Document is the extracted text of a document.
+type Document struct {
+	Format   string    // pdf, docx, xlsx, pptx or text
+	Total    int       // number of pages, sheets or slides in the document, before the range selection
+	Sections []Section // selected sections in document order
+}
+
+// String joins the sections with separators.
+func (d *Document) String() string {
+	var sb strings.Builder
+	for i, s := range d.Sections {
+		if title := s.Title(); title != "" {
+			if i > 0 {
+				sb.WriteString("\n")
+			}
+			sb.WriteString("===== " + title + " =====\n")
+		}
+		sb.WriteString(strings.Trim(s.Text, "\n"))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Extractor extracts the text of one document format.
+type Extractor interface {
+	// Name returns the name of the format.
+	Name() string
+	// Match reports whether the extractor handles a document, given its first HeadSize bytes and
+	// its lower case file extension, e.g. ".pdf".
+	Match(head []byte, ext string) bool
+	// Extract extracts the text of the document.
+	Extract(r io.ReaderAt, size int64, opts Options) (*Document, error)
+}
+
+var (
+	lock       sync.RWMutex
+	extractors []Extractor
+)
+
+// Register adds an extractor. Extractors are matched in the order of registration, the plain
+// text fallback is always matched last.
+func Register(e Extractor) {
+	lock.Lock()
+	defer lock.Unlock()
+	extractors = append(extractors, e)
+}
+
+func init() {
+	Register(&pdfExtractor{})
+	Register(&ooxmlExtractor{})
+}
+
+// Detect returns the extractor for a document.
+func Detect(head []byte, ext string) (Extractor, error) {
+	lock.RLock()
+	defer lock.RUnlock()
+	for _, e := range extractors {
+		if e.Match(head, ext) {
+			return e, nil
+		}
+	}
+	if isOLE(head) {
+		return nil, fmt.Errorf("%w, or a legacy binary Office file (doc, xls, ppt) which is not supported", ErrEncrypted)
+	}
+	if (textExtractor{}).Match(head, ext) {
+		return textExtractor{}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupported, ext)
+}
+
+// ExtractFile extracts the text of the file at path.
+func ExtractFile(path string, opts Options) (*Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	head := make([]byte, HeadSize)
+	n, err := f.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	e, err := Detect(head[:n], strings.ToLower(filepath.Ext(path)))
+	if err != nil {
+		return nil, err
+	}
+	return e.Extract(f, info.Size(), opts)
+}
+
+// isOLE reports whether head is an OLE compound file, used by encrypted OOXML and legacy Office files.
+func isOLE(head []byte) bool {
+	return len(head) >= 8 && string(head[:8]) == "\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1"
+}
+
+// Ranges is a set of 1-based index ranges, e.g. 1-3,5,8-.
+type Ranges [][2]int
+
+// ParseRanges parses a comma separated list of indexes and ranges. An empty spec selects
+// everything, an open range such as 3- selects up to the end.
+func ParseRanges(spec string) (Ranges, error) {
+	var ranges Ranges
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil || start < 1 {
+			return nil, fmt.Errorf("invalid range %q, expected e.g. 1-3,5", part)
+		}
+		end := start
+		if isRange {
+			if to = strings.TrimSpace(to); to == "" {
+				end = -1
+			} else if end, err = strconv.Atoi(to); err != nil || end < start {
+				return nil, fmt.Errorf("invalid range %q, expected e.g. 1-3,5", part)
+			}
+		}
+		ranges = append(ranges, [2]int{start, end})
+	}
+	return ranges, nil
+}
+
+// Contains reports whether i is selected. Empty ranges select everything.
+func (r Ranges) Contains(i int) bool {
+	if len(r) == 0 {
+		return true
+	}
+	for _, rg := range r {
+		if i >= rg[0] && (rg[1] == -1 || i <= rg[1]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package extract
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtractFormats(t *testing.T) {
+	tests := []struct {
+		file     string
+		opts     Options
+		format   string
+		total    int
+		sections int
+		contains []string
+		excludes []string
+	}{
+		{file: "sample.pdf", format: "pdf", total: 3, sections: 3,
+			contains: []string{"===== Page 1 =====", "Quarterly report", "Revenue grew", "===== Page 3 =====", "Third page closing"}},
+		{file: "sample.pdf", opts: Options{Pages: "2-3"}, format: "pdf", total: 3, sections: 2,
+			contains: []string{"===== Page 2 =====", "Second page text", "Third page closing"}, excludes: []string{"Quarterly report"}},
+		{file: "sample.pdf", opts: Options{Pages: "1,3"}, format: "pdf", total: 3, sections: 2,
+			contains: []string{"Quarterly report", "Third page closing"}, excludes: []string{"Second page text"}},
+		{file: "sample.docx", format: "docx", total: 1, sections: 1,
+			contains: []string{"Meeting notes\nAction: ship v2\tOwner"}},
+		{file: "sample.xlsx", format: "xlsx", total: 2, sections: 2,
+			contains: []string{"===== Sheet 1: Summary =====\nRegion\tTotal\nNorth\t\t42", "===== Sheet 2: Data =====\nraw data\tTRUE"}},
+		{file: "sample.xlsx", opts: Options{Sheets: "data"}, format: "xlsx", total: 2, sections: 1,
+			contains: []string{"raw data"}, excludes: []string{"Region"}},
+		{file: "sample.xlsx", opts: Options{Sheets: "1"}, format: "xlsx", total: 2, sections: 1,
+			contains: []string{"Region"}, excludes: []string{"raw data"}},
+		{file: "sample.pptx", format: "pptx", total: 3, sections: 3,
+			contains: []string{"===== Slide 1 =====\nWelcome\nAgenda", "===== Slide 3 =====\nThanks"}},
+		{file: "sample.pptx", opts: Options{Slides: "2-"}, format: "pptx", total: 3, sections: 2,
+			contains: []string{"Roadmap", "Thanks"}, excludes: []string{"Welcome"}},
+	}
+	for _, tt := range tests {
+		doc, err := ExtractFile(filepath.Join("testdata", tt.file), tt.opts)
+		if err != nil {
+			t.Errorf("%s %+v: %v", tt.file, tt.opts, err)
+			continue
+		}
+		if doc.Format != tt.format || doc.Total != tt.total || len(doc.Sections) != tt.sections {
+			t.Errorf("%s %+v: expected %s with %d/%d sections, got %s with %d/%d", tt.file, tt.opts,
+				tt.format, tt.sections, tt.total, doc.Format, len(doc.Sections), doc.Total)
+		}
+		text := doc.String()
+		for _, s := range tt.contains {
+			if !strings.Contains(text, s) {
+				t.Errorf("%s %+v: expected %q in %q", tt.file, tt.opts, s, text)
+			}
+		}
+		for _, s := range tt.excludes {
+			if strings.Contains(text, s) {
+				t.Errorf("%s %+v: unexpected %q in %q", tt.file, tt.opts, s, text)
+			}
+		}
+	}
+}
+
+func TestExtractErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := ExtractFile(filepath.Join("testdata", "encrypted.pdf"), Options{}); !errors.Is(err, ErrEncrypted) {
+		t.Errorf("Expected ErrEncrypted for an encrypted PDF, got %v", err)
+	}
+
+	ole := filepath.Join(dir, "protected.docx")
+	if err := os.WriteFile(ole, append([]byte("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1"), make([]byte, 504)...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ExtractFile(ole, Options{}); !errors.Is(err, ErrEncrypted) {
+		t.Errorf("Expected ErrEncrypted for an OLE container, got %v", err)
+	}
+
+	binary := filepath.Join(dir, "data.bin")
+	if err := os.WriteFile(binary, []byte{0x00, 0x01, 0x02, 0xff}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ExtractFile(binary, Options{}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported for binary data, got %v", err)
+	}
+
+	if _, err := ExtractFile(filepath.Join("testdata", "sample.xlsx"), Options{Sheets: "Missing"}); err == nil || !strings.Contains(err.Error(), "Summary, Data") {
+		t.Errorf("Expected sheet not found error listing the sheets, got %v", err)
+	}
+	if _, err := ExtractFile(filepath.Join("testdata", "sample.pdf"), Options{Pages: "3-1"}); err == nil {
+		t.Error("Expected error for an invalid page range")
+	}
+
+	text := filepath.Join(dir, "notes.md")
+	if err := os.WriteFile(text, []byte("# Notes\nplain text"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ExtractFile(text, Options{})
+	if err != nil || doc.Format != "text" || doc.String() != "# Notes\nplain text\n" {
+		t.Errorf("Unexpected plain text result %+v, %v", doc, err)
+	}
+}
+
+func TestParseRanges(t *testing.T) {
+	ranges, err := ParseRanges("1-3, 5, 8-")
+	if err != nil {
+		t.Fatalf("ParseRanges failed: %v", err)
+	}
+	for i, want := range map[int]bool{1: true, 3: true, 4: false, 5: true, 7: false, 8: true, 100: true} {
+		if ranges.Contains(i) != want {
+			t.Errorf("Contains(%d) = %v, want %v", i, !want, want)
+		}
+	}
+	for _, spec := range []string{"0", "a", "3-2", "-2"} {
+		if _, err := ParseRanges(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
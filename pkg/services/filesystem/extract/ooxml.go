@@ -0,0 +1,437 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// maxPartSize is the maximum uncompressed size of an XML part, it protects against zip bombs.
+const maxPartSize = 1024 * 1024 * 100
+
+// ooxmlExtractor extracts the text of Office Open XML documents: DOCX, XLSX and PPTX. The
+// format is detected from the parts of the zip archive, not from the file extension.
+type ooxmlExtractor struct{}
+
+func (ooxmlExtractor) Name() string {
+	return "ooxml"
+}
+
+func (ooxmlExtractor) Match(head []byte, ext string) bool {
+	return bytes.HasPrefix(head, []byte("PK\x03\x04"))
+}
+
+func (ooxmlExtractor) Extract(r io.ReaderAt, size int64, opts Options) (*Document, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip archive: %w", err)
+	}
+	parts := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		parts[f.Name] = f
+	}
+	switch {
+	case parts["word/document.xml"] != nil:
+		return extractDOCX(parts)
+	case parts["xl/workbook.xml"] != nil:
+		return extractXLSX(parts, opts)
+	case parts["ppt/presentation.xml"] != nil:
+		return extractPPTX(parts, opts)
+	default:
+		return nil, fmt.Errorf("%w: zip archive is not a DOCX, XLSX or PPTX document", ErrUnsupported)
+	}
+}
+
+func extractDOCX(parts map[string]*zip.File) (*Document, error) {
+	data, err := readPart(parts, "word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+	text, err := paragraphText(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{Format: "docx", Total: 1, Sections: []Section{{Kind: KindDocument, Index: 1, Text: text}}}, nil
+}
+
+func extractPPTX(parts map[string]*zip.File, opts Options) (*Document, error) {
+	slides, err := ParseRanges(opts.Slides)
+	if err != nil {
+		return nil, fmt.Errorf("slide: %w", err)
+	}
+	ids, err := relIDs(parts, "ppt/presentation.xml", "sldId")
+	if err != nil {
+		return nil, err
+	}
+	targets, err := relTargets(parts, "ppt/_rels/presentation.xml.rels", "ppt")
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{Format: "pptx", Total: len(ids)}
+	for i, id := range ids {
+		if !slides.Contains(i + 1) {
+			continue
+		}
+		data, err := readPart(parts, targets[id.rel])
+		if err != nil {
+			return nil, err
+		}
+		text, err := paragraphText(data)
+		if err != nil {
+			return nil, err
+		}
+		doc.Sections = append(doc.Sections, Section{Kind: KindSlide, Index: i + 1, Text: text})
+	}
+	return doc, nil
+}
+
+func extractXLSX(parts map[string]*zip.File, opts Options) (*Document, error) {
+	sheets, err := relIDs(parts, "xl/workbook.xml", "sheet")
+	if err != nil {
+		return nil, err
+	}
+	selected, err := selectSheets(sheets, opts.Sheets)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := relTargets(parts, "xl/_rels/workbook.xml.rels", "xl")
+	if err != nil {
+		return nil, err
+	}
+	var shared []string
+	if parts["xl/sharedStrings.xml"] != nil {
+		data, err := readPart(parts, "xl/sharedStrings.xml")
+		if err != nil {
+			return nil, err
+		}
+		if shared, err = sharedStrings(data); err != nil {
+			return nil, err
+		}
+	}
+
+	doc := &Document{Format: "xlsx", Total: len(sheets)}
+	for i, sheet := range sheets {
+		if !selected[i] {
+			continue
+		}
+		data, err := readPart(parts, targets[sheet.rel])
+		if err != nil {
+			return nil, err
+		}
+		text, err := sheetText(data, shared)
+		if err != nil {
+			return nil, fmt.Errorf("sheet %s: %w", sheet.name, err)
+		}
+		doc.Sections = append(doc.Sections, Section{Kind: KindSheet, Index: i + 1, Name: sheet.name, Text: text})
+	}
+	return doc, nil
+}
+
+// selectSheets selects sheets by index ranges (1-2) or by comma separated names (Summary,Data).
+func selectSheets(sheets []relID, spec string) ([]bool, error) {
+	selected := make([]bool, len(sheets))
+	if ranges, err := ParseRanges(spec); err == nil {
+		for i := range sheets {
+			selected[i] = ranges.Contains(i + 1)
+		}
+		return selected, nil
+	}
+	found := false
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		for i, sheet := range sheets {
+			if strings.EqualFold(sheet.name, name) {
+				selected[i], found = true, true
+			}
+		}
+	}
+	if !found {
+		names := make([]string, 0, len(sheets))
+		for _, sheet := range sheets {
+			names = append(names, sheet.name)
+		}
+		return nil, fmt.Errorf("sheet %q not found, available sheets: %s", spec, strings.Join(names, ", "))
+	}
+	return selected, nil
+}
+
+// readPart reads a part of the archive, limited to maxPartSize.
+func readPart(parts map[string]*zip.File, name string) ([]byte, error) {
+	f, ok := parts[name]
+	if !ok {
+		return nil, fmt.Errorf("missing part %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open part %s: %w", name, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxPartSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read part %s: %w", name, err)
+	}
+	if len(data) > maxPartSize {
+		return nil, fmt.Errorf("part %s is larger than %d bytes", name, maxPartSize)
+	}
+	return data, nil
+}
+
+// relID is an element referencing a part by relationship id, e.g. a sheet of a workbook.
+type relID struct {
+	name string
+	rel  string
+}
+
+// relIDs returns the name and r:id attributes of the elements named local in document order.
+func relIDs(parts map[string]*zip.File, partName, local string) ([]relID, error) {
+	data, err := readPart(parts, partName)
+	if err != nil {
+		return nil, err
+	}
+	var ids []relID
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return ids, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", partName, err)
+		}
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local == local {
+			var id relID
+			for _, attr := range se.Attr {
+				switch {
+				case attr.Name.Local == "name" && attr.Name.Space == "":
+					id.name = attr.Value
+				case attr.Name.Local == "id" && attr.Name.Space != "":
+					id.rel = attr.Value
+				}
+			}
+			ids = append(ids, id)
+		}
+	}
+}
+
+// relTargets maps relationship ids to part names, relative targets are resolved against dir.
+func relTargets(parts map[string]*zip.File, relsName, dir string) (map[string]string, error) {
+	data, err := readPart(parts, relsName)
+	if err != nil {
+		return nil, err
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := xml.Unmarshal(data, &rels); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", relsName, err)
+	}
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		if strings.HasPrefix(rel.Target, "/") {
+			targets[rel.ID] = strings.TrimPrefix(rel.Target, "/")
+		} else {
+			targets[rel.ID] = path.Join(dir, rel.Target)
+		}
+	}
+	return targets, nil
+}
+
+// paragraphText returns the text runs (w:t, a:t) of a DOCX or PPTX part, one paragraph per line.
+func paragraphText(data []byte) (string, error) {
+	var sb strings.Builder
+	inText := false
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return sb.String(), nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid document XML: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				sb.WriteString("\t")
+			case "br", "cr":
+				sb.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				sb.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				sb.Write(t)
+			}
+		}
+	}
+}
+
+// sharedStrings returns the shared string table of a workbook.
+func sharedStrings(data []byte) ([]string, error) {
+	var table []string
+	var sb strings.Builder
+	inText, inPhonetic := false, false
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return table, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid shared strings: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				sb.Reset()
+			case "t":
+				inText = true
+			case "rPh":
+				inPhonetic = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				table = append(table, sb.String())
+			case "t":
+				inText = false
+			case "rPh":
+				inPhonetic = false
+			}
+		case xml.CharData:
+			if inText && !inPhonetic {
+				sb.Write(t)
+			}
+		}
+	}
+}
+
+// sheetText returns the cell values of a worksheet, one row per line and cells separated by tabs.
+// Formulas are represented by their cached values.
+func sheetText(data []byte, shared []string) (string, error) {
+	var sb strings.Builder
+	var row []string
+	var cellType, value string
+	col, inValue := 0, false
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return sb.String(), nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid worksheet XML: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				row = row[:0]
+			case "c":
+				cellType, value = "", ""
+				col = len(row)
+				for _, attr := range t.Attr {
+					switch attr.Name.Local {
+					case "t":
+						cellType = attr.Value
+					case "r":
+						if c := columnIndex(attr.Value); c >= 0 {
+							col = c
+						}
+					}
+				}
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				for len(row) < col {
+					row = append(row, "")
+				}
+				row = append(row, cellValue(cellType, value, shared))
+			case "row":
+				line := strings.TrimRight(strings.Join(row, "\t"), "\t")
+				if line != "" {
+					sb.WriteString(line)
+					sb.WriteString("\n")
+				}
+			}
+		case xml.CharData:
+			if inValue {
+				value += string(t)
+			}
+		}
+	}
+}
+
+// cellValue converts the raw value of a cell according to its type.
+func cellValue(cellType, value string, shared []string) string {
+	switch cellType {
+	case "s":
+		i, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || i < 0 || i >= len(shared) {
+			return value
+		}
+		return shared[i]
+	case "b":
+		if strings.TrimSpace(value) == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	default:
+		return value
+	}
+}
+
+// columnIndex returns the 0-based column of a cell reference such as B3, or -1.
+func columnIndex(ref string) int {
+	col := 0
+	n := 0
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		col = col*26 + int(c-'A'+1)
+		n++
+	}
+	if n == 0 {
+		return -1
+	}
+	return col - 1
+}
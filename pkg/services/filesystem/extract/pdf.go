@@ -0,0 +1,75 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// pdfExtractor extracts the text of PDF files page by page.
+type pdfExtractor struct{}
+
+func (pdfExtractor) Name() string {
+	return "pdf"
+}
+
+func (pdfExtractor) Match(head []byte, ext string) bool {
+	return bytes.HasPrefix(head, []byte("%PDF-"))
+}
+
+func (pdfExtractor) Extract(r io.ReaderAt, size int64, opts Options) (doc *Document, err error) {
+	pages, err := ParseRanges(opts.Pages)
+	if err != nil {
+		return nil, fmt.Errorf("pages: %w", err)
+	}
+	// 解析器遇到损坏的文件可能 panic
+	defer func() {
+		if r := recover(); r != nil {
+			doc, err = nil, fmt.Errorf("failed to parse PDF: %v", r)
+		}
+	}()
+	reader, err := pdf.NewReader(r, size)
+	if err != nil {
+		if errors.Is(err, pdf.ErrInvalidPassword) {
+			return nil, fmt.Errorf("%w: the PDF requires a password", ErrEncrypted)
+		}
+		return nil, fmt.Errorf("failed to parse PDF: %w", err)
+	}
+
+	doc = &Document{Format: "pdf", Total: reader.NumPage()}
+	fonts := make(map[string]*pdf.Font)
+	for i := 1; i <= doc.Total; i++ {
+		if !pages.Contains(i) {
+			continue
+		}
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		text, err := page.GetPlainText(fonts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract page %d: %w", i, err)
+		}
+		doc.Sections = append(doc.Sections, Section{Kind: KindPage, Index: i, Text: text})
+	}
+	return doc, nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// textExtractor returns plain text files as they are.
+type textExtractor struct{}
+
+func (textExtractor) Name() string {
+	return "text"
+}
+
+func (textExtractor) Match(head []byte, ext string) bool {
+	return strings.HasPrefix(http.DetectContentType(head), "text/") && !bytes.ContainsRune(head, 0)
+}
+
+func (textExtractor) Extract(r io.ReaderAt, size int64, opts Options) (*Document, error) {
+	data, err := io.ReadAll(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read text: %w", err)
+	}
+	return &Document{Format: "text", Total: 1, Sections: []Section{{Kind: KindDocument, Index: 1, Text: string(data)}}}, nil
+}
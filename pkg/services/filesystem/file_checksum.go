@@ -0,0 +1,301 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// file_checksum 支持的摘要算法
+const (
+	ChecksumMD5    = "md5"
+	ChecksumSHA1   = "sha1"
+	ChecksumSHA256 = "sha256"
+)
+
+// checksumMaxFiles bounds the files of one file_checksum call.
+const checksumMaxFiles = 10000
+
+// newChecksumHash returns the hash of the algorithm.
+func newChecksumHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case ChecksumMD5:
+		return md5.New(), nil
+	case ChecksumSHA1:
+		return sha1.New(), nil
+	case ChecksumSHA256:
+		return sha256.New(), nil
+	}
+	return nil, fmt.Errorf("invalid algorithm %q, must be one of %s, %s, %s", algorithm, ChecksumMD5, ChecksumSHA1, ChecksumSHA256)
+}
+
+// digestFile streams the file into h with a fixed-size buffer, checking ctx between chunks, and
+// returns the digest and the number of bytes read.
+func digestFile(ctx context.Context, name string, h hash.Hash) ([]byte, int64, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	buf := make([]byte, copyBufferSize)
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, total, err
+		}
+		n, err := io.CopyBuffer(h, io.LimitReader(f, copyBufferSize), buf)
+		total += n
+		if err != nil {
+			return nil, total, err
+		}
+		if n < copyBufferSize {
+			break
+		}
+	}
+	return h.Sum(nil), total, nil
+}
+
+// ChecksumEntry is the digest of one file. Path is relative to the directory and slash
+// separated, or the full path when a single file was hashed.
+type ChecksumEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Digest string `json:"digest"`
+}
+
+// DuplicateGroup is a set of files with the same digest. Wasted counts the bytes of all copies
+// but one.
+type DuplicateGroup struct {
+	Digest string   `json:"digest"`
+	Size   int64    `json:"size"`
+	Paths  []string `json:"paths"`
+	Wasted int64    `json:"wasted_bytes"`
+}
+
+// ChecksumReport is the structured result of the file_checksum tool. Skipped lists the files
+// that were not hashed because max_hash_bytes or the file limit was reached, or they could not
+// be read.
+type ChecksumReport struct {
+	Path        string           `json:"path"`
+	Algorithm   string           `json:"algorithm"`
+	Files       []ChecksumEntry  `json:"files,omitempty"`
+	Duplicates  []DuplicateGroup `json:"duplicates,omitempty"`
+	HashedFiles int              `json:"hashed_files"`
+	HashedBytes int64            `json:"hashed_bytes"`
+	Skipped     []string         `json:"skipped,omitempty"`
+	Truncated   bool             `json:"truncated"`
+}
+
+// checksumOptions are the arguments of file_checksum.
+type checksumOptions struct {
+	Algorithm      string
+	Recursive      bool
+	FindDuplicates bool
+	MaxBytes       int64
+}
+
+// collectChecksumFiles returns the regular files under root by relative path, the direct ones
+// only unless recursive. Symlinks are not followed.
+func collectChecksumFiles(ctx context.Context, root string, recursive bool) ([]ChecksumEntry, error) {
+	var files []ChecksumEntry
+	err := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			if p == root {
+				return err
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if p != root && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, ChecksumEntry{Path: filepath.ToSlash(rel), Size: info.Size()})
+		return nil
+	})
+	return files, err
+}
+
+// checksumTree hashes the files under root. With FindDuplicates only the files that share
+// their size with another file are hashed, and the groups of equal digests are reported instead
+// of the listing. Hashing stops before a file that would exceed MaxBytes.
+func checksumTree(ctx context.Context, root string, opts checksumOptions) (*ChecksumReport, error) {
+	report := &ChecksumReport{Path: root, Algorithm: opts.Algorithm}
+	files, err := collectChecksumFiles(ctx, root, opts.Recursive)
+	if err != nil {
+		return nil, err
+	}
+	if opts.FindDuplicates {
+		// 大小唯一的文件不可能重复，无需计算摘要
+		bySize := make(map[int64]int)
+		for _, f := range files {
+			bySize[f.Size]++
+		}
+		candidates := files[:0]
+		for _, f := range files {
+			if bySize[f.Size] > 1 {
+				candidates = append(candidates, f)
+			}
+		}
+		files = candidates
+	}
+
+	hashed := make([]ChecksumEntry, 0, len(files))
+	for _, f := range files {
+		if report.HashedFiles >= checksumMaxFiles || report.HashedBytes+f.Size > opts.MaxBytes {
+			report.Truncated = true
+			report.Skipped = append(report.Skipped, f.Path)
+			continue
+		}
+		h, _ := newChecksumHash(opts.Algorithm)
+		sum, n, err := digestFile(ctx, filepath.Join(root, filepath.FromSlash(f.Path)), h)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			report.Skipped = append(report.Skipped, f.Path)
+			continue
+		}
+		f.Digest = hex.EncodeToString(sum)
+		f.Size = n
+		report.HashedFiles++
+		report.HashedBytes += n
+		hashed = append(hashed, f)
+	}
+
+	if !opts.FindDuplicates {
+		report.Files = hashed
+		return report, nil
+	}
+	groups := make(map[string]*DuplicateGroup)
+	for _, f := range hashed {
+		g, ok := groups[f.Digest]
+		if !ok {
+			g = &DuplicateGroup{Digest: f.Digest, Size: f.Size}
+			groups[f.Digest] = g
+		}
+		g.Paths = append(g.Paths, f.Path)
+	}
+	report.Duplicates = []DuplicateGroup{}
+	for _, g := range groups {
+		if len(g.Paths) < 2 {
+			continue
+		}
+		sort.Strings(g.Paths)
+		g.Wasted = g.Size * int64(len(g.Paths)-1)
+		report.Duplicates = append(report.Duplicates, *g)
+	}
+	// 浪费空间最多的组排在前面
+	sort.Slice(report.Duplicates, func(i, j int) bool {
+		a, b := report.Duplicates[i], report.Duplicates[j]
+		if a.Wasted != b.Wasted {
+			return a.Wasted > b.Wasted
+		}
+		return a.Paths[0] < b.Paths[0]
+	})
+	return report, nil
+}
+
+func (fs *FilesystemServer) handleFileChecksum(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	path, ok := args["path"].(string)
+	if !ok {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("path %v must be a string", args["path"]), ""), nil
+	}
+	opts := checksumOptions{Algorithm: ChecksumSHA256, MaxBytes: fs.cfg().MaxHashBytes}
+	if v, ok := args["algorithm"].(string); ok && v != "" {
+		opts.Algorithm = v
+	}
+	if _, err := newChecksumHash(opts.Algorithm); err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	opts.Recursive, _ = args["recursive"].(bool)
+	opts.FindDuplicates, _ = args["find_duplicates"].(bool)
+
+	validPath, err := fs.validatePath(path)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	info, err := os.Stat(validPath)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+
+	var report *ChecksumReport
+	if info.IsDir() {
+		report, err = checksumTree(ctx, validPath, opts)
+		if err != nil {
+			return comm.ToolErrorFromErr("failed to hash files", err), nil
+		}
+	} else {
+		if opts.FindDuplicates {
+			return comm.ToolError(comm.ErrCodeInvalidArgument, "find_duplicates needs a directory", ""), nil
+		}
+		if info.Size() > opts.MaxBytes {
+			return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("%s is larger than max_hash_bytes %d", path, opts.MaxBytes), ""), nil
+		}
+		h, _ := newChecksumHash(opts.Algorithm)
+		sum, n, err := digestFile(ctx, validPath, h)
+		if err != nil {
+			return comm.ToolErrorFromErr("failed to hash file", err), nil
+		}
+		report = &ChecksumReport{
+			Path:        validPath,
+			Algorithm:   opts.Algorithm,
+			Files:       []ChecksumEntry{{Path: validPath, Size: n, Digest: hex.EncodeToString(sum)}},
+			HashedFiles: 1,
+			HashedBytes: n,
+		}
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to encode checksums", err), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
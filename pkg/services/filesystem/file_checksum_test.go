@@ -0,0 +1,138 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func callFileChecksum(t *testing.T, fs *FilesystemServer, args map[string]interface{}) (*ChecksumReport, string) {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "file_checksum"
+	request.Params.Arguments = args
+	result, err := fs.handleFileChecksum(context.Background(), request)
+	if err != nil {
+		t.Fatalf("handleFileChecksum failed: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if result.IsError {
+		return nil, text
+	}
+	var report ChecksumReport
+	if err := json.Unmarshal([]byte(text), &report); err != nil {
+		t.Fatalf("Invalid report %s: %v", text, err)
+	}
+	return &report, ""
+}
+
+func TestFileChecksum(t *testing.T) {
+	fs, dir := newTestFilesystemServer(t)
+	root := filepath.Join(dir, "downloads")
+	writeTree(t, root, map[string]string{
+		"setup.exe":       "hello world",
+		"setup (1).exe":   "hello world",
+		"notes.txt":       "hello there",
+		"sub/nested.txt":  "unique",
+		"sub/another.exe": "hello world",
+	}, time.Now())
+	const (
+		helloMD5    = "5eb63bbbe01eeed093cb22bb8f5acdc3"
+		helloSHA256 = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	)
+
+	t.Run("File", func(t *testing.T) {
+		for algorithm, want := range map[string]string{ChecksumMD5: helloMD5, ChecksumSHA256: helloSHA256, "": helloSHA256} {
+			report, errText := callFileChecksum(t, fs, map[string]interface{}{"path": filepath.Join(root, "setup.exe"), "algorithm": algorithm})
+			if report == nil {
+				t.Fatalf("Unexpected error: %s", errText)
+			}
+			if len(report.Files) != 1 || report.Files[0].Digest != want || report.HashedBytes != 11 {
+				t.Errorf("Expected %s digest %s, got %+v", algorithm, want, report)
+			}
+		}
+	})
+
+	t.Run("Directory", func(t *testing.T) {
+		report, _ := callFileChecksum(t, fs, map[string]interface{}{"path": root})
+		if len(report.Files) != 3 {
+			t.Errorf("Expected the direct files only, got %+v", report.Files)
+		}
+		report, _ = callFileChecksum(t, fs, map[string]interface{}{"path": root, "recursive": true, "algorithm": ChecksumSHA1})
+		if len(report.Files) != 5 || report.Algorithm != ChecksumSHA1 {
+			t.Fatalf("Expected all files, got %+v", report)
+		}
+		for _, f := range report.Files {
+			if f.Path == "setup.exe" && f.Digest != "2aae6c35c94fcfb415dbe95f408b9ce91ee846ed" {
+				t.Errorf("Unexpected SHA-1 %s", f.Digest)
+			}
+		}
+	})
+
+	t.Run("FindDuplicates", func(t *testing.T) {
+		report, errText := callFileChecksum(t, fs, map[string]interface{}{"path": root, "recursive": true, "find_duplicates": true})
+		if report == nil {
+			t.Fatalf("Unexpected error: %s", errText)
+		}
+		if len(report.Duplicates) != 1 {
+			t.Fatalf("Expected one group of duplicates, got %+v", report.Duplicates)
+		}
+		g := report.Duplicates[0]
+		want := []string{"setup (1).exe", "setup.exe", "sub/another.exe"}
+		if g.Digest != helloSHA256 || strings.Join(g.Paths, ",") != strings.Join(want, ",") || g.Wasted != 22 {
+			t.Errorf("Expected %v with 22 wasted bytes, got %+v", want, g)
+		}
+		// 大小唯一的 nested.txt 不计算摘要，大小相同但内容不同的 notes.txt 不在结果中
+		if report.HashedFiles != 4 || len(report.Files) != 0 {
+			t.Errorf("Expected only the 4 files sharing a size to be hashed, got %+v", report)
+		}
+	})
+
+	t.Run("MaxHashBytes", func(t *testing.T) {
+		fs.config.MaxHashBytes = 25
+		defer func() { fs.config.MaxHashBytes = 4 * 1024 * 1024 * 1024 }()
+		report, _ := callFileChecksum(t, fs, map[string]interface{}{"path": root})
+		if !report.Truncated || report.HashedFiles != 2 || len(report.Skipped) != 1 || report.HashedBytes > 25 {
+			t.Errorf("Expected hashing to stop at max_hash_bytes, got %+v", report)
+		}
+		if _, errText := callFileChecksum(t, fs, map[string]interface{}{"path": filepath.Join(root, "sub")}); errText != "" {
+			t.Errorf("Unexpected error: %s", errText)
+		}
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		outside, _ := filepath.EvalSymlinks(t.TempDir())
+		for _, args := range []map[string]interface{}{
+			{"path": outside},
+			{"path": root, "algorithm": "crc32"},
+			{"path": filepath.Join(root, "notes.txt"), "find_duplicates": true},
+		} {
+			if report, _ := callFileChecksum(t, fs, args); report != nil {
+				t.Errorf("Expected %v to be rejected, got %+v", args, report)
+			}
+		}
+	})
+}
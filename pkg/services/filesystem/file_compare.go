@@ -0,0 +1,485 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// dir_compare 的内容比较模式和变化原因
+const (
+	CompareContentOff  = "off"  // 只比较大小和修改时间
+	CompareContentHash = "hash" // 大小相同时比较 SHA-256
+
+	CompareReasonType    = "type"
+	CompareReasonSize    = "size"
+	CompareReasonMtime   = "mtime"
+	CompareReasonContent = "content"
+	CompareReasonTarget  = "target"
+)
+
+const (
+	compareDefaultMaxEntries = 1000       // 每类变化默认返回的条目数
+	compareMaxEntries        = 100000     // max_entries 上限
+	compareMaxResultSize     = 256 * 1024 // 超过时只返回摘要，完整报告写入 output_file
+)
+
+// compareEntry is what the walk of one root records about a path.
+type compareEntry struct {
+	Type   string
+	Size   int64
+	Mtime  time.Time
+	Target string // 符号链接的目标，不跟随
+}
+
+// CompareEntry is a path that was added, removed or modified, relative to both roots and slash
+// separated. Old* describe the entry under the old root, New* under the new one.
+type CompareEntry struct {
+	Path      string     `json:"path"`
+	Type      string     `json:"type"`
+	Reason    string     `json:"reason,omitempty"` // type, size, mtime, content or target
+	OldSize   *int64     `json:"old_size,omitempty"`
+	NewSize   *int64     `json:"new_size,omitempty"`
+	OldMtime  *time.Time `json:"old_mtime,omitempty"`
+	NewMtime  *time.Time `json:"new_mtime,omitempty"`
+	OldTarget string     `json:"old_target,omitempty"`
+	NewTarget string     `json:"new_target,omitempty"`
+}
+
+// CompareSummary counts the changes. ByteDelta is the size of the files under the new root minus
+// the size of the files under the old root, over the compared paths.
+type CompareSummary struct {
+	Added     int   `json:"added"`
+	Removed   int   `json:"removed"`
+	Modified  int   `json:"modified"`
+	Unchanged int   `json:"unchanged"`
+	Hashed    int   `json:"hashed"` // 计算了哈希的文件对
+	ByteDelta int64 `json:"byte_delta"`
+	Truncated bool  `json:"truncated"` // 有变化类别超过 max_entries
+}
+
+// DirComparison is the structured result of the dir_compare tool.
+type DirComparison struct {
+	Old            string         `json:"old"`
+	New            string         `json:"new"`
+	CompareContent string         `json:"compare_content"`
+	Summary        CompareSummary `json:"summary"`
+	Added          []CompareEntry `json:"added,omitempty"`
+	Removed        []CompareEntry `json:"removed,omitempty"`
+	Modified       []CompareEntry `json:"modified,omitempty"`
+	ReportFile     string         `json:"report_file,omitempty"`
+	Note           string         `json:"note,omitempty"`
+}
+
+// compareOptions are the arguments of dir_compare.
+type compareOptions struct {
+	Include    []string
+	Exclude    []string
+	Content    string
+	MaxEntries int
+	OutputFile string
+}
+
+// parseCompareOptions validates the arguments of dir_compare.
+func parseCompareOptions(args map[string]interface{}) (compareOptions, error) {
+	opts := compareOptions{Content: CompareContentOff, MaxEntries: compareDefaultMaxEntries}
+	var err error
+	if opts.Include, err = parseGlobs(args, "include"); err != nil {
+		return opts, err
+	}
+	if opts.Exclude, err = parseGlobs(args, "exclude"); err != nil {
+		return opts, err
+	}
+	if v, ok := args["compare_content"].(string); ok && v != "" {
+		switch v {
+		case CompareContentOff, CompareContentHash:
+			opts.Content = v
+		default:
+			return opts, fmt.Errorf("invalid compare_content %q, must be %s or %s", v, CompareContentOff, CompareContentHash)
+		}
+	}
+	if v, ok := args["max_entries"].(float64); ok {
+		if v < 1 || v > compareMaxEntries {
+			return opts, fmt.Errorf("max_entries must be between 1 and %d", compareMaxEntries)
+		}
+		opts.MaxEntries = int(v)
+	}
+	if v, ok := args["output_file"].(string); ok && v != "" {
+		if v != filepath.Base(v) || v == "." || v == ".." {
+			return opts, fmt.Errorf("output_file must be a file name without directories, got %q", v)
+		}
+		opts.OutputFile = v
+	}
+	return opts, nil
+}
+
+// parseGlobs reads an array of glob patterns, a single string is accepted too.
+func parseGlobs(args map[string]interface{}, key string) ([]string, error) {
+	var globs []string
+	switch v := args[key].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		globs = []string{v}
+	case []interface{}:
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be an array of glob patterns, got %v", key, item)
+			}
+			globs = append(globs, s)
+		}
+	default:
+		return nil, fmt.Errorf("%s must be an array of glob patterns, got %T", key, v)
+	}
+	for _, g := range globs {
+		if _, err := path.Match(strings.ReplaceAll(g, "**", "*"), ""); err != nil {
+			return nil, fmt.Errorf("invalid %s pattern %q: %v", key, g, err)
+		}
+	}
+	return globs, nil
+}
+
+// matchGlob matches the slash separated relative path against pattern. A pattern without a slash
+// matches the base name in any directory, like *.log; ** matches any number of directories.
+func matchGlob(pattern, rel string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+func matchAny(globs []string, rel string) bool {
+	for _, g := range globs {
+		if matchGlob(g, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkCompareRoot records the entries under root by their relative path. Symlinks are recorded
+// with their target and never followed. Excluded directories are not walked; include only
+// applies to the entries that are not directories.
+func walkCompareRoot(ctx context.Context, root string, opts compareOptions) (map[string]compareEntry, error) {
+	entries := make(map[string]compareEntry)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			if p == root {
+				return err
+			}
+			// 遍历时被删除或无权限的条目跳过
+			return nil
+		}
+		if p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if matchAny(opts.Exclude, rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && len(opts.Include) > 0 && !matchAny(opts.Include, rel) {
+			return nil
+		}
+		if d.IsDir() && len(opts.Include) > 0 {
+			// 指定 include 时只比较匹配的文件，目录本身不报告
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		entry := newListEntry(filepath.Dir(p), info)
+		ce := compareEntry{Type: entry.Type, Mtime: info.ModTime()}
+		switch entry.Type {
+		case ListTypeFile:
+			ce.Size = info.Size()
+		case ListTypeSymlink:
+			ce.Target = entry.Target
+		}
+		entries[rel] = ce
+		return nil
+	})
+	return entries, err
+}
+
+// hashFile returns the SHA-256 of the file, checking ctx between chunks.
+func hashFile(ctx context.Context, name string) ([]byte, error) {
+	sum, _, err := digestFile(ctx, name, sha256.New())
+	return sum, err
+}
+
+// sameContent compares the files with the same relative path under both roots by hash.
+func sameContent(ctx context.Context, oldRoot, newRoot, rel string) (bool, error) {
+	oldSum, err := hashFile(ctx, filepath.Join(oldRoot, filepath.FromSlash(rel)))
+	if err != nil {
+		return false, err
+	}
+	newSum, err := hashFile(ctx, filepath.Join(newRoot, filepath.FromSlash(rel)))
+	if err != nil {
+		return false, err
+	}
+	return string(oldSum) == string(newSum), nil
+}
+
+// changeReason returns why the entry changed, or an empty string when it didn't.
+func changeReason(ctx context.Context, oldRoot, newRoot, rel string, o, n compareEntry, content string, summary *CompareSummary) (string, error) {
+	switch {
+	case o.Type != n.Type:
+		return CompareReasonType, nil
+	case o.Type == ListTypeSymlink:
+		if o.Target != n.Target {
+			return CompareReasonTarget, nil
+		}
+		return "", nil
+	case o.Type != ListTypeFile:
+		// 目录的修改时间随子项变化，不单独比较
+		return "", nil
+	case o.Size != n.Size:
+		return CompareReasonSize, nil
+	case content == CompareContentHash:
+		summary.Hashed++
+		same, err := sameContent(ctx, oldRoot, newRoot, rel)
+		if err != nil {
+			return "", err
+		}
+		if !same {
+			return CompareReasonContent, nil
+		}
+		return "", nil
+	case !o.Mtime.Equal(n.Mtime):
+		return CompareReasonMtime, nil
+	}
+	return "", nil
+}
+
+func describeOld(e *CompareEntry, o compareEntry) {
+	if o.Type == ListTypeFile {
+		size := o.Size
+		e.OldSize = &size
+	}
+	mtime := o.Mtime
+	e.OldMtime = &mtime
+	e.OldTarget = o.Target
+}
+
+func describeNew(e *CompareEntry, n compareEntry) {
+	if n.Type == ListTypeFile {
+		size := n.Size
+		e.NewSize = &size
+	}
+	mtime := n.Mtime
+	e.NewMtime = &mtime
+	e.NewTarget = n.Target
+}
+
+// compareDirs compares the trees under oldRoot and newRoot. The entries of each class are sorted
+// by path and not truncated, see truncate.
+func compareDirs(ctx context.Context, oldRoot, newRoot string, opts compareOptions) (*DirComparison, error) {
+	oldEntries, err := walkCompareRoot(ctx, oldRoot, opts)
+	if err != nil {
+		return nil, err
+	}
+	newEntries, err := walkCompareRoot(ctx, newRoot, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	cmp := &DirComparison{
+		Old:            oldRoot,
+		New:            newRoot,
+		CompareContent: opts.Content,
+		Added:          []CompareEntry{},
+		Removed:        []CompareEntry{},
+		Modified:       []CompareEntry{},
+	}
+	for rel, o := range oldEntries {
+		n, ok := newEntries[rel]
+		if !ok {
+			e := CompareEntry{Path: rel, Type: o.Type}
+			describeOld(&e, o)
+			cmp.Removed = append(cmp.Removed, e)
+			cmp.Summary.ByteDelta -= o.Size
+			continue
+		}
+		reason, err := changeReason(ctx, oldRoot, newRoot, rel, o, n, opts.Content, &cmp.Summary)
+		if err != nil {
+			return nil, err
+		}
+		cmp.Summary.ByteDelta += n.Size - o.Size
+		if reason == "" {
+			cmp.Summary.Unchanged++
+			continue
+		}
+		e := CompareEntry{Path: rel, Type: n.Type, Reason: reason}
+		describeOld(&e, o)
+		describeNew(&e, n)
+		cmp.Modified = append(cmp.Modified, e)
+	}
+	for rel, n := range newEntries {
+		if _, ok := oldEntries[rel]; ok {
+			continue
+		}
+		e := CompareEntry{Path: rel, Type: n.Type}
+		describeNew(&e, n)
+		cmp.Added = append(cmp.Added, e)
+		cmp.Summary.ByteDelta += n.Size
+	}
+	for _, entries := range [][]CompareEntry{cmp.Added, cmp.Removed, cmp.Modified} {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	}
+	cmp.Summary.Added = len(cmp.Added)
+	cmp.Summary.Removed = len(cmp.Removed)
+	cmp.Summary.Modified = len(cmp.Modified)
+	return cmp, nil
+}
+
+// truncate returns a copy that keeps at most limit entries of each class.
+func (c *DirComparison) truncate(limit int) *DirComparison {
+	t := *c
+	cut := func(entries []CompareEntry) []CompareEntry {
+		if len(entries) > limit {
+			t.Summary.Truncated = true
+			return entries[:limit]
+		}
+		return entries
+	}
+	t.Added, t.Removed, t.Modified = cut(c.Added), cut(c.Removed), cut(c.Modified)
+	return &t
+}
+
+// summaryOnly returns a copy without the entries.
+func (c *DirComparison) summaryOnly() *DirComparison {
+	t := *c
+	t.Summary.Truncated = len(c.Added)+len(c.Removed)+len(c.Modified) > 0
+	t.Added, t.Removed, t.Modified = nil, nil, nil
+	return &t
+}
+
+// handleDirCompare handles the dir_compare tool.
+func (fs *FilesystemServer) handleDirCompare(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	opts, err := parseCompareOptions(args)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	var roots [2]string
+	for i, key := range []string{"old_path", "new_path"} {
+		p, ok := args[key].(string)
+		if !ok {
+			return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("%s %v must be a string", key, args[key]), ""), nil
+		}
+		validPath, err := fs.validatePath(p)
+		if err != nil {
+			return comm.ArgumentError(err), nil
+		}
+		info, err := os.Stat(validPath)
+		if err != nil {
+			return comm.ArgumentError(err), nil
+		}
+		if !info.IsDir() {
+			return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("%s is not a directory", p), ""), nil
+		}
+		roots[i] = validPath
+	}
+
+	cmp, err := compareDirs(ctx, roots[0], roots[1], opts)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to compare directories", err), nil
+	}
+
+	if opts.OutputFile != "" {
+		// 报告文件包含全部条目，不受 max_entries 限制
+		dataDir := filepath.Join(fs.MlConfig().BasePath, "data")
+		if err := os.MkdirAll(dataDir, 0755); err != nil {
+			return comm.ToolErrorFromErr("failed to create data directory", err), nil
+		}
+		report, err := json.MarshalIndent(cmp, "", "  ")
+		if err != nil {
+			return comm.ToolErrorFromErr("failed to encode comparison", err), nil
+		}
+		cmp.ReportFile = filepath.Join(dataDir, opts.OutputFile)
+		if err := os.WriteFile(cmp.ReportFile, report, 0644); err != nil {
+			return comm.ToolErrorFromErr("failed to write report", err), nil
+		}
+	}
+
+	result := cmp.truncate(opts.MaxEntries)
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to encode comparison", err), nil
+	}
+	if len(data) > compareMaxResultSize {
+		result = cmp.summaryOnly()
+		if cmp.ReportFile != "" {
+			result.Note = "the report is too large to return, read the full report from report_file"
+		} else {
+			result.Note = "the report is too large to return, pass output_file to write it to a file, or lower max_entries"
+		}
+		if data, err = json.MarshalIndent(result, "", "  "); err != nil {
+			return comm.ToolErrorFromErr("failed to encode comparison", err), nil
+		}
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
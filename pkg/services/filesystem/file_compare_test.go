@@ -0,0 +1,276 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// writeTree writes the files, relative slash separated paths to contents, under root with the
+// same mtime, so that only the changes made by a test show up.
+func writeTree(t *testing.T, root string, files map[string]string, mtime time.Time) {
+	t.Helper()
+	for rel, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatalf("Failed to set mtime: %v", err)
+		}
+	}
+}
+
+// newCompareTestTrees generates an old and a new tree under the allowed directory with one
+// change of each class.
+func newCompareTestTrees(t *testing.T) (*FilesystemServer, string, string) {
+	t.Helper()
+	fs, dir := newTestFilesystemServer(t)
+	oldRoot, newRoot := filepath.Join(dir, "old"), filepath.Join(dir, "new")
+	mtime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	common := map[string]string{
+		"README.md":         "readme",
+		"src/main.go":       "package main",
+		"src/util/util.go":  "package util",
+		"build/out.bin":     "0123456789",
+		"node_modules/x.js": "x",
+	}
+	writeTree(t, oldRoot, common, mtime)
+	writeTree(t, newRoot, common, mtime)
+	writeTree(t, oldRoot, map[string]string{"removed.txt": "gone", "src/grow.go": "a"}, mtime)
+	writeTree(t, newRoot, map[string]string{"src/added.go": "new file", "src/grow.go": "abc"}, mtime)
+	// 大小相同、修改时间相同，只有内容不同
+	writeTree(t, oldRoot, map[string]string{"same.txt": "aaaa"}, mtime)
+	writeTree(t, newRoot, map[string]string{"same.txt": "bbbb"}, mtime)
+	// 内容相同，只修改了时间
+	writeTree(t, oldRoot, map[string]string{"touched.txt": "same"}, mtime)
+	writeTree(t, newRoot, map[string]string{"touched.txt": "same"}, mtime.Add(time.Hour))
+	return fs, oldRoot, newRoot
+}
+
+func callDirCompare(t *testing.T, fs *FilesystemServer, args map[string]interface{}) (*DirComparison, string) {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "dir_compare"
+	request.Params.Arguments = args
+	result, err := fs.handleDirCompare(context.Background(), request)
+	if err != nil {
+		t.Fatalf("handleDirCompare failed: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if result.IsError {
+		return nil, text
+	}
+	var cmp DirComparison
+	if err := json.Unmarshal([]byte(text), &cmp); err != nil {
+		t.Fatalf("Invalid comparison %s: %v", text, err)
+	}
+	return &cmp, ""
+}
+
+func comparePaths(entries []CompareEntry) []string {
+	paths := []string{}
+	for _, e := range entries {
+		paths = append(paths, e.Path)
+	}
+	return paths
+}
+
+func TestDirCompare(t *testing.T) {
+	t.Run("ChangeClasses", func(t *testing.T) {
+		fs, oldRoot, newRoot := newCompareTestTrees(t)
+		cmp, errText := callDirCompare(t, fs, map[string]interface{}{"old_path": oldRoot, "new_path": newRoot})
+		if cmp == nil {
+			t.Fatalf("Unexpected error: %s", errText)
+		}
+		if got := strings.Join(comparePaths(cmp.Added), ","); got != "src/added.go" {
+			t.Errorf("Unexpected added paths %s", got)
+		}
+		if got := strings.Join(comparePaths(cmp.Removed), ","); got != "removed.txt" {
+			t.Errorf("Unexpected removed paths %s", got)
+		}
+		// 只比较大小和修改时间时，相同大小的修改不会被发现
+		if got := strings.Join(comparePaths(cmp.Modified), ","); got != "src/grow.go,touched.txt" {
+			t.Errorf("Unexpected modified paths %s", got)
+		}
+		grow := cmp.Modified[0]
+		if grow.Reason != CompareReasonSize || *grow.OldSize != 1 || *grow.NewSize != 3 || grow.OldMtime == nil || grow.NewMtime == nil {
+			t.Errorf("Unexpected modified entry %+v", grow)
+		}
+		if cmp.Modified[1].Reason != CompareReasonMtime {
+			t.Errorf("Expected the touched file to be modified by mtime, got %+v", cmp.Modified[1])
+		}
+		added := cmp.Added[0]
+		if added.OldSize != nil || added.NewSize == nil || *added.NewSize != 8 || added.Type != ListTypeFile {
+			t.Errorf("Unexpected added entry %+v", added)
+		}
+		// 8 字节新增，4 字节删除，2 字节增长
+		s := cmp.Summary
+		if s.Added != 1 || s.Removed != 1 || s.Modified != 2 || s.ByteDelta != 6 || s.Hashed != 0 || s.Truncated {
+			t.Errorf("Unexpected summary %+v", s)
+		}
+	})
+
+	t.Run("HashMode", func(t *testing.T) {
+		fs, oldRoot, newRoot := newCompareTestTrees(t)
+		cmp, errText := callDirCompare(t, fs, map[string]interface{}{"old_path": oldRoot, "new_path": newRoot, "compare_content": "hash"})
+		if cmp == nil {
+			t.Fatalf("Unexpected error: %s", errText)
+		}
+		// 哈希模式发现相同大小的修改，忽略只改了时间的文件
+		if got := strings.Join(comparePaths(cmp.Modified), ","); got != "same.txt,src/grow.go" {
+			t.Errorf("Unexpected modified paths %s", got)
+		}
+		if cmp.Modified[0].Reason != CompareReasonContent {
+			t.Errorf("Expected a content change, got %+v", cmp.Modified[0])
+		}
+		if cmp.Summary.Hashed == 0 || cmp.CompareContent != CompareContentHash {
+			t.Errorf("Expected files to be hashed, got %+v", cmp.Summary)
+		}
+	})
+
+	t.Run("Globs", func(t *testing.T) {
+		fs, oldRoot, newRoot := newCompareTestTrees(t)
+		cmp, _ := callDirCompare(t, fs, map[string]interface{}{
+			"old_path": oldRoot, "new_path": newRoot, "include": []interface{}{"*.go"},
+		})
+		if got := strings.Join(comparePaths(cmp.Added), ","); got != "src/added.go" || len(cmp.Removed) != 0 {
+			t.Errorf("Expected only Go files, got added %s removed %v", got, comparePaths(cmp.Removed))
+		}
+		if cmp.Summary.Unchanged != 2 {
+			t.Errorf("Expected main.go and util.go to be unchanged, got %+v", cmp.Summary)
+		}
+
+		cmp, _ = callDirCompare(t, fs, map[string]interface{}{
+			"old_path": oldRoot, "new_path": newRoot, "include": []interface{}{"src/**/*.go"}, "exclude": []interface{}{"src/util", "grow.go"},
+		})
+		if got := strings.Join(comparePaths(cmp.Added), ","); got != "src/added.go" || len(cmp.Modified) != 0 || cmp.Summary.Unchanged != 1 {
+			t.Errorf("Unexpected comparison with include and exclude %+v", cmp)
+		}
+
+		// 新树中新增被排除目录下的文件不会出现
+		writeTree(t, newRoot, map[string]string{"node_modules/y.js": "y"}, time.Now())
+		cmp, _ = callDirCompare(t, fs, map[string]interface{}{
+			"old_path": oldRoot, "new_path": newRoot, "exclude": "node_modules",
+		})
+		for _, e := range append(cmp.Added, cmp.Removed...) {
+			if strings.HasPrefix(e.Path, "node_modules") {
+				t.Errorf("Expected node_modules to be excluded, got %s", e.Path)
+			}
+		}
+
+		for pattern, want := range map[string]bool{"a/b/c.go": true, "c.go": true, "a/c.txt": false} {
+			if got := matchGlob("**/*.go", pattern); got != want {
+				t.Errorf("matchGlob(**/*.go, %s) = %v, want %v", pattern, got, want)
+			}
+		}
+	})
+
+	t.Run("Symlinks", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("symlinks need privileges on windows")
+		}
+		fs, oldRoot, newRoot := newCompareTestTrees(t)
+		outside := t.TempDir()
+		if err := os.Symlink(outside, filepath.Join(oldRoot, "link")); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+		if err := os.Symlink("README.md", filepath.Join(newRoot, "link")); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+		cmp, _ := callDirCompare(t, fs, map[string]interface{}{"old_path": oldRoot, "new_path": newRoot})
+		var link *CompareEntry
+		for i := range cmp.Modified {
+			if cmp.Modified[i].Path == "link" {
+				link = &cmp.Modified[i]
+			}
+		}
+		if link == nil || link.Reason != CompareReasonTarget || link.OldTarget != outside || link.NewTarget != "README.md" {
+			t.Errorf("Expected the symlink target change, got %+v", cmp.Modified)
+		}
+	})
+
+	t.Run("MaxEntriesAndOutputFile", func(t *testing.T) {
+		fs, oldRoot, newRoot := newCompareTestTrees(t)
+		files := map[string]string{}
+		for i := 0; i < 3000; i++ {
+			files[filepath.ToSlash(filepath.Join("gen", strings.Repeat("d", i%5+1), "file"+strings.Repeat("x", 40)+string(rune('a'+i%26))+"-"+time.Duration(i).String()))] = "x"
+		}
+		writeTree(t, newRoot, files, time.Now())
+
+		cmp, _ := callDirCompare(t, fs, map[string]interface{}{"old_path": oldRoot, "new_path": newRoot, "max_entries": float64(5)})
+		if len(cmp.Added) != 5 || !cmp.Summary.Truncated || cmp.Summary.Added < 3000 {
+			t.Errorf("Expected 5 of the added entries, got %d of %+v", len(cmp.Added), cmp.Summary)
+		}
+
+		// 报告超过上限时只返回摘要，完整报告写入数据目录
+		cmp, _ = callDirCompare(t, fs, map[string]interface{}{
+			"old_path": oldRoot, "new_path": newRoot, "max_entries": float64(100000), "output_file": "compare.json",
+		})
+		if len(cmp.Added) != 0 || cmp.Summary.Added < 3000 || cmp.ReportFile == "" || cmp.Note == "" {
+			t.Fatalf("Expected only the summary and the report file, got %+v", cmp.Summary)
+		}
+		if filepath.Dir(cmp.ReportFile) != filepath.Join(fs.MlConfig().BasePath, "data") {
+			t.Errorf("Expected the report in the data directory, got %s", cmp.ReportFile)
+		}
+		data, err := os.ReadFile(cmp.ReportFile)
+		if err != nil {
+			t.Fatalf("Failed to read report: %v", err)
+		}
+		var full DirComparison
+		if err := json.Unmarshal(data, &full); err != nil || len(full.Added) != cmp.Summary.Added {
+			t.Errorf("Expected every added entry in the report, got %d: %v", len(full.Added), err)
+		}
+	})
+
+	t.Run("Arguments", func(t *testing.T) {
+		fs, oldRoot, newRoot := newCompareTestTrees(t)
+		for _, args := range []map[string]interface{}{
+			{"old_path": oldRoot},
+			{"old_path": oldRoot, "new_path": t.TempDir()},
+			{"old_path": oldRoot, "new_path": filepath.Join(newRoot, "README.md")},
+			{"old_path": oldRoot, "new_path": newRoot, "compare_content": "mtime"},
+			{"old_path": oldRoot, "new_path": newRoot, "max_entries": float64(0)},
+			{"old_path": oldRoot, "new_path": newRoot, "include": []interface{}{"[a-"}},
+			{"old_path": oldRoot, "new_path": newRoot, "output_file": "../escape.json"},
+		} {
+			if cmp, _ := callDirCompare(t, fs, args); cmp != nil {
+				t.Errorf("Expected an error for %v", args)
+			}
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := compareDirs(ctx, oldRoot, newRoot, compareOptions{Content: CompareContentOff}); err == nil {
+			t.Errorf("Expected the walk to stop when the context is canceled")
+		}
+	})
+}
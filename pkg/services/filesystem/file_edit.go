@@ -0,0 +1,281 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// EditInsertAfter inserts the content after start_line, 0 inserts at the beginning.
+	EditInsertAfter = "insert_after"
+	// EditInsertBefore inserts the content before start_line, the line count + 1 appends.
+	EditInsertBefore = "insert_before"
+	// EditReplaceRange replaces the lines start_line to end_line with the content.
+	EditReplaceRange = "replace_range"
+	// EditDeleteRange deletes the lines start_line to end_line.
+	EditDeleteRange = "delete_range"
+
+	// diffContext is the number of unchanged lines around the edit in the returned diff.
+	diffContext = 3
+)
+
+// pathLocks serializes the edits of each file.
+type pathLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// lock locks the path and returns the unlock function.
+func (pl *pathLocks) lock(path string) func() {
+	pl.mu.Lock()
+	if pl.locks == nil {
+		pl.locks = make(map[string]*sync.Mutex)
+	}
+	l, ok := pl.locks[path]
+	if !ok {
+		l = &sync.Mutex{}
+		pl.locks[path] = l
+	}
+	pl.mu.Unlock()
+	l.Lock()
+	return l.Unlock
+}
+
+// textFile is a text file split into lines, with the line ending and the trailing newline it uses.
+type textFile struct {
+	lines           []string
+	eol             string
+	trailingNewline bool
+}
+
+// parseTextFile splits the content into lines. The line ending is the first one found, files
+// without line breaks use \n. An empty file is treated as ending with a newline.
+func parseTextFile(content string) textFile {
+	tf := textFile{eol: "\n", trailingNewline: true}
+	if i := strings.Index(content, "\n"); i > 0 && content[i-1] == '\r' {
+		tf.eol = "\r\n"
+	}
+	if content == "" {
+		return tf
+	}
+	tf.trailingNewline = strings.HasSuffix(content, "\n")
+	tf.lines = splitLines(content)
+	return tf
+}
+
+// splitLines splits the text into lines without their line endings, a final line break doesn't start a new line.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
+// String joins the lines with the line ending of the file.
+func (tf textFile) String() string {
+	if len(tf.lines) == 0 {
+		return ""
+	}
+	s := strings.Join(tf.lines, tf.eol)
+	if tf.trailingNewline {
+		s += tf.eol
+	}
+	return s
+}
+
+// lineEdit replaces the lines [start, end) (0-based) with insert.
+type lineEdit struct {
+	start, end int
+	insert     []string
+}
+
+// planLineEdit validates the line numbers of the operation against the line count.
+func planLineEdit(operation string, startLine, endLine, count int, content string) (lineEdit, error) {
+	insert := splitLines(content)
+	switch operation {
+	case EditInsertAfter, EditInsertBefore:
+		if len(insert) == 0 {
+			return lineEdit{}, fmt.Errorf("content is required for %s, use \\n to insert an empty line", operation)
+		}
+		at := startLine
+		if operation == EditInsertBefore {
+			at = startLine - 1
+		}
+		if at < 0 || at > count {
+			low, high := 0, count
+			if operation == EditInsertBefore {
+				low, high = 1, count+1
+			}
+			return lineEdit{}, fmt.Errorf("start_line %d is out of range, the file has %d lines, %s accepts %d to %d", startLine, count, operation, low, high)
+		}
+		return lineEdit{start: at, end: at, insert: insert}, nil
+	case EditReplaceRange, EditDeleteRange:
+		if endLine == 0 {
+			endLine = startLine
+		}
+		if startLine < 1 || endLine < startLine || endLine > count {
+			return lineEdit{}, fmt.Errorf("line range %d-%d is out of range, the file has %d lines", startLine, endLine, count)
+		}
+		if operation == EditDeleteRange {
+			insert = nil
+		}
+		return lineEdit{start: startLine - 1, end: endLine, insert: insert}, nil
+	default:
+		return lineEdit{}, fmt.Errorf("invalid operation %q, use %s, %s, %s or %s", operation, EditInsertAfter, EditInsertBefore, EditReplaceRange, EditDeleteRange)
+	}
+}
+
+// apply returns the lines after the edit.
+func (le lineEdit) apply(lines []string) []string {
+	edited := make([]string, 0, len(lines)-(le.end-le.start)+len(le.insert))
+	edited = append(edited, lines[:le.start]...)
+	edited = append(edited, le.insert...)
+	return append(edited, lines[le.end:]...)
+}
+
+// unifiedDiff formats the edit as a unified diff with one hunk.
+func (le lineEdit) unifiedDiff(path string, lines []string) string {
+	before := max(0, le.start-diffContext)
+	after := min(len(lines), le.end+diffContext)
+	oldCount := after - before
+	newCount := oldCount - (le.end - le.start) + len(le.insert)
+	// 行数为 0 时起始行号指向前一行
+	oldStart, newStart := before+1, before+1
+	if oldCount == 0 {
+		oldStart = before
+	}
+	if newCount == 0 {
+		newStart = before
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", path, path))
+	sb.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount))
+	for _, line := range lines[before:le.start] {
+		sb.WriteString(" " + line + "\n")
+	}
+	for _, line := range lines[le.start:le.end] {
+		sb.WriteString("-" + line + "\n")
+	}
+	for _, line := range le.insert {
+		sb.WriteString("+" + line + "\n")
+	}
+	for _, line := range lines[le.end:after] {
+		sb.WriteString(" " + line + "\n")
+	}
+	return sb.String()
+}
+
+// writeFileAtomic writes the data to a temporary file in the same directory and renames it over path.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	return writeAtomic(path, perm, os.Rename, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// writeAtomic fills a temporary file in the same directory as path, sets its permissions and
+// moves it into place with commit, e.g. os.Rename. The temporary file is removed on failure.
+func writeAtomic(path string, perm os.FileMode, commit func(tmp, path string) error, fill func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := fill(tmp); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return commit(tmp.Name(), path)
+}
+
+// handleEditLines inserts, replaces or deletes lines of a text file by line number.
+func (fs *FilesystemServer) handleEditLines(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	path, ok := args["path"].(string)
+	if !ok {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, "path must be a string", ""), nil
+	}
+	operation, _ := args["operation"].(string)
+	startLine, ok := args["start_line"].(float64)
+	if !ok {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, "start_line must be a number", ""), nil
+	}
+	endLine, _ := args["end_line"].(float64)
+	content, _ := args["content"].(string)
+
+	validPath, err := fs.validateWritePath(path)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	unlock := fs.editLocks.lock(validPath)
+	defer unlock()
+
+	info, err := os.Stat(validPath)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	if info.IsDir() {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("%s is a directory", path), ""), nil
+	}
+	if info.Size() > MaxInlineSize {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("%s is larger than %d bytes", path, MaxInlineSize), ""), nil
+	}
+	data, err := os.ReadFile(validPath)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to read file", err), nil
+	}
+
+	tf := parseTextFile(string(data))
+	edit, err := planLineEdit(operation, int(startLine), int(endLine), len(tf.lines), content)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	diff := edit.unifiedDiff(path, tf.lines)
+	tf.lines = edit.apply(tf.lines)
+	if err := writeFileAtomic(validPath, []byte(tf.String()), info.Mode().Perm()); err != nil {
+		return comm.ToolErrorFromErr("failed to write file", err), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Edited %s, the file now has %d lines\n%s", path, len(tf.lines), diff)), nil
+}
@@ -0,0 +1,183 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func callEditLines(t *testing.T, fs *FilesystemServer, args map[string]interface{}) (string, bool) {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = args
+	result, err := fs.handleEditLines(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return result.Content[0].(mcp.TextContent).Text, result.IsError
+}
+
+func TestFileEditLines(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		file      string
+		args      map[string]interface{}
+		want      string
+		wantLines int
+	}{
+		{"InsertAfter", "a\nb\nc\n", map[string]interface{}{"operation": "insert_after", "start_line": 1, "content": "x\ny"}, "a\nx\ny\nb\nc\n", 5},
+		{"InsertAtBeginning", "a\nb\n", map[string]interface{}{"operation": "insert_after", "start_line": 0, "content": "x"}, "x\na\nb\n", 3},
+		{"InsertBefore", "a\nb\n", map[string]interface{}{"operation": "insert_before", "start_line": 2, "content": "x\n"}, "a\nx\nb\n", 3},
+		{"InsertAtEOF", "a\nb\n", map[string]interface{}{"operation": "insert_after", "start_line": 2, "content": "c"}, "a\nb\nc\n", 3},
+		{"InsertBeforeEOF", "a\nb\n", map[string]interface{}{"operation": "insert_before", "start_line": 3, "content": "c"}, "a\nb\nc\n", 3},
+		{"InsertAtEOFNoTrailingNewline", "a\nb", map[string]interface{}{"operation": "insert_after", "start_line": 2, "content": "c"}, "a\nb\nc", 3},
+		{"InsertEmptyLine", "a\nb\n", map[string]interface{}{"operation": "insert_after", "start_line": 1, "content": "\n"}, "a\n\nb\n", 3},
+		{"InsertIntoEmptyFile", "", map[string]interface{}{"operation": "insert_after", "start_line": 0, "content": "a"}, "a\n", 1},
+		{"ReplaceRange", "a\nb\nc\nd\n", map[string]interface{}{"operation": "replace_range", "start_line": 2, "end_line": 3, "content": "x"}, "a\nx\nd\n", 3},
+		{"ReplaceLine", "a\nb\nc\n", map[string]interface{}{"operation": "replace_range", "start_line": 3, "content": "x\ny\n"}, "a\nb\nx\ny\n", 4},
+		{"DeleteRange", "a\nb\nc\nd\n", map[string]interface{}{"operation": "delete_range", "start_line": 1, "end_line": 2}, "c\nd\n", 2},
+		{"DeleteAll", "a\nb\n", map[string]interface{}{"operation": "delete_range", "start_line": 1, "end_line": 2}, "", 0},
+		{"CRLF", "a\r\nb\r\nc\r\n", map[string]interface{}{"operation": "replace_range", "start_line": 2, "content": "x\ny"}, "a\r\nx\r\ny\r\nc\r\n", 4},
+		{"CRLFContent", "a\r\nb\r\n", map[string]interface{}{"operation": "insert_after", "start_line": 2, "content": "x\r\ny\r\n"}, "a\r\nb\r\nx\r\ny\r\n", 4},
+		{"CRLFNoTrailingNewline", "a\r\nb", map[string]interface{}{"operation": "delete_range", "start_line": 2}, "a", 1},
+		{"LFContentInCRLF", "a\r\nb", map[string]interface{}{"operation": "insert_before", "start_line": 1, "content": "x\n"}, "x\r\na\r\nb", 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fs, dir := newTestFilesystemServer(t)
+			writeTestFile(t, filepath.Join(dir, "notes.txt"), tc.file)
+			tc.args["path"] = "notes.txt"
+			for _, key := range []string{"start_line", "end_line"} {
+				if n, ok := tc.args[key].(int); ok {
+					tc.args[key] = float64(n)
+				}
+			}
+			text, isErr := callEditLines(t, fs, tc.args)
+			if isErr {
+				t.Fatalf("Unexpected error: %s", text)
+			}
+			if got := readTestFile(t, filepath.Join(dir, "notes.txt")); got != tc.want {
+				t.Errorf("Expected %q, got %q", tc.want, got)
+			}
+			if !strings.Contains(text, fmt.Sprintf("the file now has %d lines", tc.wantLines)) {
+				t.Errorf("Expected the new line count %d, got %s", tc.wantLines, text)
+			}
+		})
+	}
+}
+
+func TestFileEditLinesDiff(t *testing.T) {
+	fs, dir := newTestFilesystemServer(t)
+	writeTestFile(t, filepath.Join(dir, "notes.txt"), "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n")
+	text, isErr := callEditLines(t, fs, map[string]interface{}{
+		"path": "notes.txt", "operation": "replace_range", "start_line": float64(5), "end_line": float64(6), "content": "five\nsix\nsix and a half",
+	})
+	if isErr {
+		t.Fatalf("Unexpected error: %s", text)
+	}
+	want := `Edited notes.txt, the file now has 11 lines
+--- notes.txt
++++ notes.txt
+@@ -2,8 +2,9 @@
+ 2
+ 3
+ 4
+-5
+-6
++five
++six
++six and a half
+ 7
+ 8
+ 9
+`
+	if text != want {
+		t.Errorf("Unexpected diff:\n%s\nexpected:\n%s", text, want)
+	}
+
+	// 文件开头和结尾的上下文不足 3 行
+	writeTestFile(t, filepath.Join(dir, "short.txt"), "a\r\nb\r\n")
+	text, _ = callEditLines(t, fs, map[string]interface{}{"path": "short.txt", "operation": "insert_after", "start_line": float64(2), "content": "c"})
+	if want := "--- short.txt\n+++ short.txt\n@@ -1,2 +1,3 @@\n a\n b\n+c\n"; !strings.HasSuffix(text, want) {
+		t.Errorf("Unexpected diff at EOF:\n%s", text)
+	}
+	text, _ = callEditLines(t, fs, map[string]interface{}{"path": "short.txt", "operation": "delete_range", "start_line": float64(1), "end_line": float64(3)})
+	if want := "@@ -1,3 +0,0 @@\n-a\n-b\n-c\n"; !strings.HasSuffix(text, want) {
+		t.Errorf("Unexpected diff of deleting all lines:\n%s", text)
+	}
+}
+
+func TestFileEditLinesErrors(t *testing.T) {
+	fs, dir := newTestFilesystemServer(t)
+	writeTestFile(t, filepath.Join(dir, "notes.txt"), "a\nb\nc\n")
+	for _, tc := range []struct {
+		args map[string]interface{}
+		want string
+	}{
+		{map[string]interface{}{"operation": "replace_range", "start_line": 2.0, "end_line": 4.0, "content": "x"}, "line range 2-4 is out of range, the file has 3 lines"},
+		{map[string]interface{}{"operation": "delete_range", "start_line": 0.0}, "line range 0-0 is out of range, the file has 3 lines"},
+		{map[string]interface{}{"operation": "delete_range", "start_line": 3.0, "end_line": 2.0}, "line range 3-2 is out of range"},
+		{map[string]interface{}{"operation": "insert_after", "start_line": 4.0, "content": "x"}, "start_line 4 is out of range, the file has 3 lines, insert_after accepts 0 to 3"},
+		{map[string]interface{}{"operation": "insert_before", "start_line": 0.0, "content": "x"}, "start_line 0 is out of range, the file has 3 lines, insert_before accepts 1 to 4"},
+		{map[string]interface{}{"operation": "insert_after", "start_line": 1.0}, "content is required"},
+		{map[string]interface{}{"operation": "append", "start_line": 1.0, "content": "x"}, "invalid operation"},
+		{map[string]interface{}{"operation": "insert_after", "content": "x"}, "start_line must be a number"},
+	} {
+		tc.args["path"] = "notes.txt"
+		text, isErr := callEditLines(t, fs, tc.args)
+		if !isErr || !strings.Contains(text, tc.want) {
+			t.Errorf("Expected error %q, got %s", tc.want, text)
+		}
+	}
+	if text, isErr := callEditLines(t, fs, map[string]interface{}{"path": "missing.txt", "operation": "insert_after", "start_line": 0.0, "content": "x"}); !isErr {
+		t.Errorf("Expected an error for a missing file, got %s", text)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "notes.txt")); got != "a\nb\nc\n" {
+		t.Errorf("Expected failed edits to keep the file, got %q", got)
+	}
+}
+
+func TestFileEditLinesConcurrent(t *testing.T) {
+	fs, dir := newTestFilesystemServer(t)
+	writeTestFile(t, filepath.Join(dir, "notes.txt"), "")
+	const edits = 50
+	var wg sync.WaitGroup
+	for i := 0; i < edits; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = map[string]interface{}{"path": "notes.txt", "operation": "insert_after", "start_line": 0.0, "content": fmt.Sprint(i)}
+			if result, err := fs.handleEditLines(context.Background(), request); err != nil || result.IsError {
+				t.Errorf("Edit %d failed: %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	// 每次编辑都基于上一次的结果，没有丢失的行
+	if lines := strings.Count(readTestFile(t, filepath.Join(dir, "notes.txt")), "\n"); lines != edits {
+		t.Errorf("Expected %d lines, got %d", edits, lines)
+	}
+}
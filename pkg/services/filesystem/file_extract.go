@@ -0,0 +1,91 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"unicode/utf8"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/services/filesystem/extract"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// DefaultExtractMaxBytes is the default maximum size of the text returned by file_extract_text (256KB).
+	DefaultExtractMaxBytes = 1024 * 256
+	// MaxExtractFileSize is the maximum size of a document read by file_extract_text (100MB).
+	MaxExtractFileSize = 1024 * 1024 * 100
+)
+
+// handleExtractText handles the file_extract_text tool.
+func (fs *FilesystemServer) handleExtractText(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	path, ok := args["path"].(string)
+	if !ok {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("path %v must be a string", args["path"]), ""), nil
+	}
+	opts := extract.Options{}
+	opts.Pages, _ = args["pages"].(string)
+	opts.Sheets, _ = args["sheet"].(string)
+	opts.Slides, _ = args["slide"].(string)
+	maxBytes := DefaultExtractMaxBytes
+	if v, ok := args["max_bytes"].(float64); ok && v > 0 {
+		maxBytes = min(int(v), MaxInlineSize)
+	}
+
+	validPath, err := fs.validatePath(path)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	info, err := os.Stat(validPath)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	if info.IsDir() {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("%s is a directory", path), ""), nil
+	}
+	if info.Size() > MaxExtractFileSize {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("document %s is too large (%d bytes, max %d)", path, info.Size(), MaxExtractFileSize), ""), nil
+	}
+
+	doc, err := extract.ExtractFile(validPath, opts)
+	if err != nil {
+		return comm.ToolErrorFromErr(fmt.Sprintf("failed to extract text from %s", path), err), nil
+	}
+	text := doc.String()
+	if len(text) > maxBytes {
+		text = fmt.Sprintf("%s\n[truncated: the text has %d bytes, showing the first %d. Use the pages, sheet or slide arguments to read the rest]",
+			truncateUTF8(text, maxBytes), len(text), maxBytes)
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Format: %s, %d part(s) in total, %d extracted\n\n%s", doc.Format, doc.Total, len(doc.Sections), text)), nil
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a UTF-8 character.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestFileExtractText(t *testing.T) {
+	fs, dir := newTestFilesystemServer(t)
+	data, err := os.ReadFile(filepath.Join("extract", "testdata", "sample.pptx"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "deck.pptx"), data, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	call := func(args map[string]interface{}) (string, bool) {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = args
+		result, _ := fs.handleExtractText(context.Background(), request)
+		return result.Content[0].(mcp.TextContent).Text, result.IsError
+	}
+
+	text, isErr := call(map[string]interface{}{"path": "deck.pptx", "slide": "2"})
+	if isErr || !strings.Contains(text, "Format: pptx, 3 part(s) in total, 1 extracted") || !strings.Contains(text, "Roadmap") || strings.Contains(text, "Welcome") {
+		t.Errorf("Unexpected result %s", text)
+	}
+
+	text, isErr = call(map[string]interface{}{"path": "deck.pptx", "max_bytes": float64(30)})
+	if isErr || !strings.Contains(text, "[truncated:") || strings.Contains(text, "Thanks") {
+		t.Errorf("Expected truncated result, got %s", text)
+	}
+
+	if _, isErr = call(map[string]interface{}{"path": filepath.Join(os.TempDir(), "..", "etc", "passwd")}); !isErr {
+		t.Error("Expected error for path outside allowed directories")
+	}
+}
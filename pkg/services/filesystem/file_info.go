@@ -0,0 +1,188 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// MaxLineCountSize is the maximum size of a text file whose lines are counted by file_info.
+const MaxLineCountSize = 1024 * 1024 * 1
+
+// FileMetadata is the structured result of the file_info tool.
+type FileMetadata struct {
+	Path          string     `json:"path"`
+	Name          string     `json:"name"`
+	Size          int64      `json:"size"`
+	Mode          string     `json:"mode"`        // e.g. -rw-r--r--
+	Permissions   string     `json:"permissions"` // e.g. 0644
+	IsDirectory   bool       `json:"is_directory"`
+	IsSymlink     bool       `json:"is_symlink"`
+	SymlinkTarget string     `json:"symlink_target,omitempty"`
+	Modified      time.Time  `json:"modified"`
+	Accessed      *time.Time `json:"accessed,omitempty"`
+	Created       *time.Time `json:"created,omitempty"`
+	Changed       *time.Time `json:"changed,omitempty"`    // inode change time, Unix only
+	Owner         string     `json:"owner,omitempty"`      // Unix only
+	Group         string     `json:"group,omitempty"`      // Unix only
+	Attributes    []string   `json:"attributes,omitempty"` // Windows file attributes, e.g. readonly, hidden
+	MimeType      string     `json:"mime_type,omitempty"`
+	LineCount     *int       `json:"line_count,omitempty"`
+	EntryCount    *int       `json:"entry_count,omitempty"`
+	IsEmpty       *bool      `json:"is_empty,omitempty"`
+}
+
+// handleFileInfo handles the file_info tool.
+func (fs *FilesystemServer) handleFileInfo(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	path, ok := args["path"].(string)
+	if !ok {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("path %v must be a string", args["path"]), ""), nil
+	}
+	followSymlink, _ := args["follow_symlink"].(bool)
+
+	// validatePath resolves symlinks and checks that the target is allowed
+	validPath, err := fs.validatePath(path)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	linkPath, err := fs.absPath(path, accessRead)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+
+	meta, err := fileMetadata(linkPath, validPath, followSymlink)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to get file info", err), nil
+	}
+
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to encode file info", err), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// fileMetadata collects the metadata of linkPath. realPath is linkPath with symlinks resolved,
+// it is described instead of the link itself when followSymlink is true.
+func fileMetadata(linkPath, realPath string, followSymlink bool) (*FileMetadata, error) {
+	linfo, err := os.Lstat(linkPath)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &FileMetadata{Path: linkPath}
+	info := linfo
+	if linfo.Mode()&os.ModeSymlink != 0 {
+		meta.IsSymlink = true
+		if target, err := os.Readlink(linkPath); err == nil {
+			meta.SymlinkTarget = target
+		}
+		if followSymlink {
+			if info, err = os.Stat(realPath); err != nil {
+				return nil, err
+			}
+			meta.Path = realPath
+		}
+	}
+
+	meta.Name = info.Name()
+	meta.Size = info.Size()
+	meta.Mode = info.Mode().String()
+	meta.Permissions = formatPermissions(info.Mode())
+	meta.IsDirectory = info.IsDir()
+	meta.Modified = info.ModTime()
+	fillPlatformInfo(meta, info)
+
+	switch {
+	case info.IsDir():
+		entries, err := os.ReadDir(meta.Path)
+		if err != nil {
+			return nil, err
+		}
+		count := len(entries)
+		empty := count == 0
+		meta.EntryCount = &count
+		meta.IsEmpty = &empty
+	case info.Mode().IsRegular():
+		mimeType, lines, err := sniffFile(meta.Path, info.Size())
+		if err != nil {
+			return nil, err
+		}
+		meta.MimeType = mimeType
+		meta.LineCount = lines
+	}
+	return meta, nil
+}
+
+// sniffFile detects the MIME type from the first 512 bytes and counts the lines of small text files.
+func sniffFile(path string, size int64) (string, *int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", nil, err
+	}
+	mimeType := http.DetectContentType(head[:n])
+	if !strings.HasPrefix(mimeType, "text/") || size > MaxLineCountSize {
+		return mimeType, nil, nil
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return mimeType, nil, err
+	}
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return mimeType, nil, err
+	}
+	lines := bytes.Count(content, []byte{'\n'})
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		lines++
+	}
+	return mimeType, &lines, nil
+}
+
+// formatPermissions formats the permission bits of mode as an octal string, e.g. 0644.
+func formatPermissions(mode os.FileMode) string {
+	return fmt.Sprintf("%04o", mode.Perm())
+}
+
+// timePtr returns a pointer to t, or nil when t is zero.
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
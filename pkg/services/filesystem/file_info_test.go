@@ -0,0 +1,161 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// newTestFilesystemServer creates a FilesystemServer whose only allowed directory is a temp dir.
+func newTestFilesystemServer(t *testing.T) (*FilesystemServer, string) {
+	t.Helper()
+	_, ctx, err := comm.InitTestEnv()
+	if err != nil {
+		t.Fatalf("Failed to initialize test environment: %v", err)
+	}
+	svc, err := NewFilesystemServer(ctx)
+	if err != nil {
+		t.Fatalf("Failed to create FilesystemServer: %v", err)
+	}
+	// macOS 的临时目录是软链接，先解析成真实路径
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to resolve temp dir: %v", err)
+	}
+	fs := svc.(*FilesystemServer)
+	if err := fs.LoadConfig(map[string]interface{}{"allowed_dir": dir}); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	return fs, dir
+}
+
+func callFileInfo(t *testing.T, fs *FilesystemServer, args map[string]interface{}) *FileMetadata {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "file_info"
+	request.Params.Arguments = args
+	result, err := fs.handleFileInfo(context.Background(), request)
+	if err != nil {
+		t.Fatalf("handleFileInfo failed: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if result.IsError {
+		t.Fatalf("Unexpected error: %s", text)
+	}
+	var meta FileMetadata
+	if err := json.Unmarshal([]byte(text), &meta); err != nil {
+		t.Fatalf("Expected structured JSON, got %s", text)
+	}
+	return &meta
+}
+
+func TestFileInfo(t *testing.T) {
+	fs, dir := newTestFilesystemServer(t)
+	textFile := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(textFile, []byte("line1\nline2\nline3"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	t.Run("TextFile", func(t *testing.T) {
+		meta := callFileInfo(t, fs, map[string]interface{}{"path": "notes.txt"})
+		if meta.Size != 17 || meta.IsDirectory || meta.IsSymlink {
+			t.Errorf("Unexpected metadata: %+v", meta)
+		}
+		if !strings.HasPrefix(meta.MimeType, "text/plain") {
+			t.Errorf("Expected text/plain, got %s", meta.MimeType)
+		}
+		if meta.LineCount == nil || *meta.LineCount != 3 {
+			t.Errorf("Expected 3 lines, got %v", meta.LineCount)
+		}
+	})
+
+	t.Run("BinaryMime", func(t *testing.T) {
+		png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")
+		if err := os.WriteFile(filepath.Join(dir, "image.bin"), png, 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		meta := callFileInfo(t, fs, map[string]interface{}{"path": "image.bin"})
+		if meta.MimeType != "image/png" {
+			t.Errorf("Expected image/png, got %s", meta.MimeType)
+		}
+		if meta.LineCount != nil {
+			t.Errorf("Expected no line count for binary file, got %d", *meta.LineCount)
+		}
+	})
+
+	t.Run("Directory", func(t *testing.T) {
+		sub := filepath.Join(dir, "sub")
+		if err := os.Mkdir(sub, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		meta := callFileInfo(t, fs, map[string]interface{}{"path": "sub"})
+		if !meta.IsDirectory || meta.EntryCount == nil || *meta.EntryCount != 0 || meta.IsEmpty == nil || !*meta.IsEmpty {
+			t.Errorf("Expected empty directory, got %+v", meta)
+		}
+
+		if err := os.WriteFile(filepath.Join(sub, "a.txt"), []byte("a"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		meta = callFileInfo(t, fs, map[string]interface{}{"path": "sub"})
+		if *meta.EntryCount != 1 || *meta.IsEmpty {
+			t.Errorf("Expected one entry, got %+v", meta)
+		}
+	})
+
+	t.Run("Symlink", func(t *testing.T) {
+		link := filepath.Join(dir, "link.txt")
+		if err := os.Symlink(textFile, link); err != nil {
+			// Windows 下创建软链接需要特权
+			t.Skipf("symlink not supported: %v", err)
+		}
+
+		meta := callFileInfo(t, fs, map[string]interface{}{"path": "link.txt"})
+		if !meta.IsSymlink || meta.SymlinkTarget != textFile {
+			t.Errorf("Expected symlink to %s, got %+v", textFile, meta)
+		}
+		if meta.Name != "link.txt" || meta.Path != link {
+			t.Errorf("Expected the link itself without follow_symlink, got %+v", meta)
+		}
+
+		meta = callFileInfo(t, fs, map[string]interface{}{"path": "link.txt", "follow_symlink": true})
+		if !meta.IsSymlink || meta.Name != "notes.txt" || meta.Size != 17 {
+			t.Errorf("Expected the symlink target with follow_symlink, got %+v", meta)
+		}
+	})
+
+	t.Run("OutsideAllowedDirs", func(t *testing.T) {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]interface{}{"path": filepath.Join(filepath.Dir(dir), "other")}
+		result, err := fs.handleFileInfo(context.Background(), request)
+		if err != nil {
+			t.Fatalf("handleFileInfo failed: %v", err)
+		}
+		if !result.IsError {
+			t.Errorf("Expected access denied, got %v", result.Content)
+		}
+	})
+}
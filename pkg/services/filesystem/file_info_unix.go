@@ -0,0 +1,47 @@
+//go:build !windows
+
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// fillPlatformInfo fills owner, group and the access/change/creation times on Unix.
+func fillPlatformInfo(meta *FileMetadata, info os.FileInfo) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	uid := strconv.FormatUint(uint64(st.Uid), 10)
+	meta.Owner = uid
+	if u, err := user.LookupId(uid); err == nil {
+		meta.Owner = u.Username
+	}
+	gid := strconv.FormatUint(uint64(st.Gid), 10)
+	meta.Group = gid
+	if g, err := user.LookupGroupId(gid); err == nil {
+		meta.Group = g.Name
+	}
+	fillStatTimes(meta, st)
+}
@@ -0,0 +1,53 @@
+//go:build !windows
+
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileInfoPermissionsUnix(t *testing.T) {
+	fs, dir := newTestFilesystemServer(t)
+	path := filepath.Join(dir, "secret.txt")
+	if err := os.WriteFile(path, []byte("secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	// 显式 chmod，避免受 umask 影响
+	if err := os.Chmod(path, 0640); err != nil {
+		t.Fatalf("Failed to chmod file: %v", err)
+	}
+
+	meta := callFileInfo(t, fs, map[string]interface{}{"path": "secret.txt"})
+	if meta.Permissions != "0640" {
+		t.Errorf("Expected permissions 0640, got %s", meta.Permissions)
+	}
+	if meta.Mode != "-rw-r-----" {
+		t.Errorf("Expected mode -rw-r-----, got %s", meta.Mode)
+	}
+	if meta.Owner == "" || meta.Group == "" {
+		t.Errorf("Expected owner and group, got %q %q", meta.Owner, meta.Group)
+	}
+	if meta.Accessed == nil {
+		t.Errorf("Expected access time")
+	}
+}
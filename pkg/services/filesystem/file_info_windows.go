@@ -0,0 +1,55 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fillPlatformInfo fills the access/creation times and the file attributes on Windows.
+func fillPlatformInfo(meta *FileMetadata, info os.FileInfo) {
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return
+	}
+	meta.Accessed = timePtr(time.Unix(0, data.LastAccessTime.Nanoseconds()))
+	meta.Created = timePtr(time.Unix(0, data.CreationTime.Nanoseconds()))
+	meta.Attributes = windowsAttributes(data.FileAttributes)
+}
+
+// windowsAttributes converts FILE_ATTRIBUTE_* flags to readable names.
+func windowsAttributes(attrs uint32) []string {
+	var names []string
+	for _, a := range []struct {
+		flag uint32
+		name string
+	}{
+		{syscall.FILE_ATTRIBUTE_READONLY, "readonly"},
+		{syscall.FILE_ATTRIBUTE_HIDDEN, "hidden"},
+		{syscall.FILE_ATTRIBUTE_SYSTEM, "system"},
+		{syscall.FILE_ATTRIBUTE_ARCHIVE, "archive"},
+	} {
+		if attrs&a.flag != 0 {
+			names = append(names, a.name)
+		}
+	}
+	return names
+}
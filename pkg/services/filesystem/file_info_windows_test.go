@@ -0,0 +1,53 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestFileInfoPermissionsWindows(t *testing.T) {
+	fs, dir := newTestFilesystemServer(t)
+	path := filepath.Join(dir, "readonly.txt")
+	if err := os.WriteFile(path, []byte("readonly\r\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	// Windows 只支持只读位，0444 会设置 FILE_ATTRIBUTE_READONLY
+	if err := os.Chmod(path, 0444); err != nil {
+		t.Fatalf("Failed to chmod file: %v", err)
+	}
+	defer func() { _ = os.Chmod(path, 0644) }()
+
+	meta := callFileInfo(t, fs, map[string]interface{}{"path": "readonly.txt"})
+	if meta.Permissions != "0444" {
+		t.Errorf("Expected permissions 0444, got %s", meta.Permissions)
+	}
+	if meta.Mode != "-r--r--r--" {
+		t.Errorf("Expected mode -r--r--r--, got %s", meta.Mode)
+	}
+	if !slices.Contains(meta.Attributes, "readonly") {
+		t.Errorf("Expected readonly attribute, got %v", meta.Attributes)
+	}
+	if meta.Created == nil {
+		t.Errorf("Expected creation time")
+	}
+}
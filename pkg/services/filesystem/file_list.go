@@ -0,0 +1,345 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// file_list 的排序字段、顺序和类型
+const (
+	ListSortName      = "name"
+	ListSortSize      = "size"
+	ListSortMtime     = "mtime"
+	ListSortExtension = "extension"
+
+	ListOrderAsc  = "asc"
+	ListOrderDesc = "desc"
+
+	ListTypeFile    = "file"
+	ListTypeDir     = "dir"
+	ListTypeSymlink = "symlink"
+	ListTypeOther   = "other"
+)
+
+const (
+	listDefaultLimit = 100 // file_list 默认返回的条目数
+	listMaxLimit     = 1000
+	listReadBatch    = 256 // 每次从目录读取的条目数
+)
+
+// ListEntry is an entry of the file_list result.
+type ListEntry struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	Type      string    `json:"type"`
+	Size      int64     `json:"size"`
+	Mtime     time.Time `json:"mtime"`
+	Mode      string    `json:"mode"`                // e.g. -rw-r--r--
+	Extension string    `json:"extension,omitempty"` // 小写，不带点
+	Target    string    `json:"target,omitempty"`    // 符号链接的目标
+}
+
+// DirListing is the structured result of the file_list tool. Total counts the entries that pass
+// the filters, so the model can page with offset until HasMore is false.
+type DirListing struct {
+	Path    string      `json:"path"`
+	Total   int         `json:"total"`
+	Offset  int         `json:"offset"`
+	Limit   int         `json:"limit"`
+	HasMore bool        `json:"has_more"`
+	Entries []ListEntry `json:"entries"`
+}
+
+// listOptions are the arguments of file_list.
+type listOptions struct {
+	SortBy        string
+	Desc          bool
+	Limit         int
+	Offset        int
+	Filter        string // 匹配条目名的 glob
+	Type          string
+	IncludeHidden bool
+}
+
+// listLess returns the order of the entries for sortBy. Entries with equal keys are ordered by
+// name, so the order is total and pages stay consistent across calls.
+func listLess(sortBy string, desc bool) func(a, b *ListEntry) bool {
+	byName := func(a, b *ListEntry) bool {
+		la, lb := strings.ToLower(a.Name), strings.ToLower(b.Name)
+		if la != lb {
+			return la < lb
+		}
+		return a.Name < b.Name
+	}
+	var less func(a, b *ListEntry) bool
+	switch sortBy {
+	case ListSortSize:
+		less = func(a, b *ListEntry) bool {
+			if a.Size != b.Size {
+				return a.Size < b.Size
+			}
+			return byName(a, b)
+		}
+	case ListSortMtime:
+		less = func(a, b *ListEntry) bool {
+			if !a.Mtime.Equal(b.Mtime) {
+				return a.Mtime.Before(b.Mtime)
+			}
+			return byName(a, b)
+		}
+	case ListSortExtension:
+		less = func(a, b *ListEntry) bool {
+			if a.Extension != b.Extension {
+				return a.Extension < b.Extension
+			}
+			return byName(a, b)
+		}
+	default:
+		less = byName
+	}
+	if desc {
+		return func(a, b *ListEntry) bool { return less(b, a) }
+	}
+	return less
+}
+
+// entryHeap is a max-heap of the entries by less: the root is the last of the kept entries.
+type entryHeap struct {
+	entries []ListEntry
+	less    func(a, b *ListEntry) bool
+}
+
+func (h *entryHeap) Len() int           { return len(h.entries) }
+func (h *entryHeap) Less(i, j int) bool { return h.less(&h.entries[j], &h.entries[i]) }
+func (h *entryHeap) Swap(i, j int)      { h.entries[i], h.entries[j] = h.entries[j], h.entries[i] }
+func (h *entryHeap) Push(x interface{}) { h.entries = append(h.entries, x.(ListEntry)) }
+func (h *entryHeap) Pop() interface{} {
+	last := h.entries[len(h.entries)-1]
+	h.entries = h.entries[:len(h.entries)-1]
+	return last
+}
+
+// topN keeps the first n entries by less, in O(n) memory however many entries are offered.
+type topN struct {
+	n int
+	h entryHeap
+}
+
+func newTopN(n int, less func(a, b *ListEntry) bool) *topN {
+	return &topN{n: n, h: entryHeap{less: less}}
+}
+
+func (t *topN) offer(e ListEntry) {
+	if t.n <= 0 {
+		return
+	}
+	if t.h.Len() < t.n {
+		heap.Push(&t.h, e)
+		return
+	}
+	if t.h.less(&e, &t.h.entries[0]) {
+		t.h.entries[0] = e
+		heap.Fix(&t.h, 0)
+	}
+}
+
+// sorted returns the kept entries in order.
+func (t *topN) sorted() []ListEntry {
+	entries := t.h.entries
+	sort.Slice(entries, func(i, j int) bool { return t.h.less(&entries[i], &entries[j]) })
+	return entries
+}
+
+// newListEntry describes the entry name of dir, info comes from lstat.
+func newListEntry(dir string, info os.FileInfo) ListEntry {
+	entry := ListEntry{
+		Name:  info.Name(),
+		Path:  filepath.Join(dir, info.Name()),
+		Size:  info.Size(),
+		Mtime: info.ModTime(),
+		Mode:  info.Mode().String(),
+	}
+	switch mode := info.Mode(); {
+	case mode&os.ModeSymlink != 0:
+		entry.Type = ListTypeSymlink
+		entry.Target, _ = os.Readlink(entry.Path)
+	case mode.IsDir():
+		entry.Type = ListTypeDir
+	case mode.IsRegular():
+		entry.Type = ListTypeFile
+	default:
+		entry.Type = ListTypeOther
+	}
+	if entry.Type != ListTypeDir {
+		entry.Extension = strings.ToLower(strings.TrimPrefix(filepath.Ext(entry.Name), "."))
+	}
+	return entry
+}
+
+// listDirectory streams the entries of dir in batches, filters them and keeps only the first
+// offset+limit entries of the order, so large directories are never held in memory as a whole.
+func listDirectory(ctx context.Context, dir string, opts listOptions) (*DirListing, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	listing := &DirListing{Path: dir, Offset: opts.Offset, Limit: opts.Limit}
+	top := newTopN(opts.Offset+opts.Limit, listLess(opts.SortBy, opts.Desc))
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		batch, err := f.ReadDir(listReadBatch)
+		for _, de := range batch {
+			name := de.Name()
+			if !opts.IncludeHidden && strings.HasPrefix(name, ".") {
+				continue
+			}
+			if opts.Filter != "" {
+				if ok, _ := filepath.Match(opts.Filter, name); !ok {
+					continue
+				}
+			}
+			info, err := de.Info()
+			if err != nil {
+				// 读取目录后被删除的条目直接跳过
+				continue
+			}
+			entry := newListEntry(dir, info)
+			if opts.Type != "" && entry.Type != opts.Type {
+				continue
+			}
+			listing.Total++
+			top.offer(entry)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	listing.Entries = []ListEntry{}
+	if entries := top.sorted(); opts.Offset < len(entries) {
+		listing.Entries = entries[opts.Offset:]
+	}
+	listing.HasMore = opts.Offset+len(listing.Entries) < listing.Total
+	return listing, nil
+}
+
+// parseListOptions validates the arguments of file_list.
+func parseListOptions(args map[string]interface{}) (listOptions, error) {
+	opts := listOptions{SortBy: ListSortName, Limit: listDefaultLimit}
+	if v, ok := args["sort_by"].(string); ok && v != "" {
+		switch v {
+		case ListSortName, ListSortSize, ListSortMtime, ListSortExtension:
+			opts.SortBy = v
+		default:
+			return opts, fmt.Errorf("invalid sort_by %q, must be one of %s, %s, %s, %s", v, ListSortName, ListSortSize, ListSortMtime, ListSortExtension)
+		}
+	}
+	if v, ok := args["order"].(string); ok && v != "" {
+		switch v {
+		case ListOrderAsc:
+		case ListOrderDesc:
+			opts.Desc = true
+		default:
+			return opts, fmt.Errorf("invalid order %q, must be %s or %s", v, ListOrderAsc, ListOrderDesc)
+		}
+	}
+	if v, ok := args["limit"].(float64); ok {
+		if v < 0 || v > listMaxLimit {
+			return opts, fmt.Errorf("limit must be between 0 and %d", listMaxLimit)
+		}
+		opts.Limit = int(v)
+	}
+	if v, ok := args["offset"].(float64); ok {
+		if v < 0 || v > math.MaxInt32 {
+			return opts, fmt.Errorf("offset must be between 0 and %d", math.MaxInt32)
+		}
+		opts.Offset = int(v)
+	}
+	if v, ok := args["filter"].(string); ok && v != "" {
+		if _, err := filepath.Match(v, ""); err != nil {
+			return opts, fmt.Errorf("invalid filter %q: %v", v, err)
+		}
+		opts.Filter = v
+	}
+	if v, ok := args["type"].(string); ok && v != "" {
+		switch v {
+		case ListTypeFile, ListTypeDir, ListTypeSymlink:
+			opts.Type = v
+		default:
+			return opts, fmt.Errorf("invalid type %q, must be one of %s, %s, %s", v, ListTypeFile, ListTypeDir, ListTypeSymlink)
+		}
+	}
+	opts.IncludeHidden, _ = args["include_hidden"].(bool)
+	return opts, nil
+}
+
+func (fs *FilesystemServer) handleFileList(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	path, ok := args["path"].(string)
+	if !ok {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("path %v must be a string", args["path"]), ""), nil
+	}
+	opts, err := parseListOptions(args)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+
+	validPath, err := fs.validatePath(path)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	info, err := os.Stat(validPath)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	if !info.IsDir() {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("%s is not a directory", path), ""), nil
+	}
+	listing, err := listDirectory(ctx, validPath, opts)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to read directory", err), nil
+	}
+
+	data, err := json.MarshalIndent(listing, "", "  ")
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to encode directory listing", err), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
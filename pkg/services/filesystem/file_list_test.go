@@ -0,0 +1,298 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	listTestFiles = 3000
+	listTestDirs  = 20
+)
+
+// listTestExts are the extensions of the generated files, every fifth file has none.
+var listTestExts = []string{".txt", ".log", ".PDF", ".go", ""}
+
+// newListTestDir generates listTestFiles files with repeating sizes and mtimes, listTestDirs
+// directories, a hidden file and, where supported, a symlink.
+func newListTestDir(t *testing.T) (*FilesystemServer, string, bool) {
+	t.Helper()
+	fs, dir := newTestFilesystemServer(t)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < listTestFiles; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("f%04d%s", i, listTestExts[i%len(listTestExts)]))
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", i%97)), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		// 修改时间按分钟重复，检验相同排序键下的顺序
+		mtime := base.Add(time.Duration(i*7%500) * time.Minute)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("Failed to set mtime: %v", err)
+		}
+	}
+	for i := 0; i < listTestDirs; i++ {
+		if err := os.Mkdir(filepath.Join(dir, fmt.Sprintf("d%02d", i)), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".hidden"), []byte("h"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	symlink := runtime.GOOS != "windows" && os.Symlink("f0001.log", filepath.Join(dir, "link")) == nil
+	return fs, dir, symlink
+}
+
+func callFileList(t *testing.T, fs *FilesystemServer, args map[string]interface{}) (*DirListing, string) {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "file_list"
+	request.Params.Arguments = args
+	result, err := fs.handleFileList(context.Background(), request)
+	if err != nil {
+		t.Fatalf("handleFileList failed: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if result.IsError {
+		return nil, text
+	}
+	var listing DirListing
+	if err := json.Unmarshal([]byte(text), &listing); err != nil {
+		t.Fatalf("Expected structured JSON, got %s", text)
+	}
+	return &listing, ""
+}
+
+func TestFileListPagination(t *testing.T) {
+	fs, dir, symlink := newListTestDir(t)
+	visible := listTestFiles + listTestDirs
+	if symlink {
+		visible++
+	}
+
+	for _, sortBy := range []string{ListSortName, ListSortSize, ListSortMtime, ListSortExtension} {
+		for _, order := range []string{ListOrderAsc, ListOrderDesc} {
+			t.Run(sortBy+"_"+order, func(t *testing.T) {
+				var all []ListEntry
+				for offset := 0; ; offset += 250 {
+					listing, errText := callFileList(t, fs, map[string]interface{}{
+						"path": dir, "sort_by": sortBy, "order": order, "limit": float64(250), "offset": float64(offset),
+					})
+					if listing == nil {
+						t.Fatalf("Unexpected error: %s", errText)
+					}
+					if listing.Total != visible {
+						t.Fatalf("Expected a total of %d, got %d", visible, listing.Total)
+					}
+					all = append(all, listing.Entries...)
+					if !listing.HasMore {
+						break
+					}
+				}
+				if len(all) != visible {
+					t.Fatalf("Expected %d entries over all pages, got %d", visible, len(all))
+				}
+				seen := make(map[string]bool)
+				less := listLess(sortBy, order == ListOrderDesc)
+				for i := range all {
+					if seen[all[i].Name] {
+						t.Fatalf("Entry %s is on two pages", all[i].Name)
+					}
+					seen[all[i].Name] = true
+					if i > 0 && !less(&all[i-1], &all[i]) {
+						t.Fatalf("Entries %s and %s are out of order", all[i-1].Name, all[i].Name)
+					}
+				}
+			})
+		}
+	}
+
+	t.Run("SortKeys", func(t *testing.T) {
+		listing, _ := callFileList(t, fs, map[string]interface{}{"path": dir, "sort_by": ListSortSize, "order": ListOrderDesc, "limit": float64(5), "type": ListTypeFile})
+		for _, entry := range listing.Entries {
+			if entry.Size != 96 {
+				t.Errorf("Expected the largest files first, got %s with %d bytes", entry.Name, entry.Size)
+			}
+		}
+		listing, _ = callFileList(t, fs, map[string]interface{}{"path": dir, "sort_by": ListSortMtime, "limit": float64(2), "type": ListTypeFile})
+		// i*7%500 为 0 的文件：f0000 和 f0500
+		if names := listing.Entries[0].Name + "," + listing.Entries[1].Name; names != "f0000.txt,f0500.txt" {
+			t.Errorf("Expected the oldest files ordered by name, got %s", names)
+		}
+		listing, _ = callFileList(t, fs, map[string]interface{}{"path": dir, "sort_by": ListSortExtension, "order": ListOrderDesc, "limit": float64(1)})
+		if entry := listing.Entries[0]; entry.Extension != "txt" || entry.Name != "f2995.txt" {
+			t.Errorf("Expected the last .txt file first, got %+v", entry)
+		}
+		if listing.Entries[0].Mode != "-rw-r--r--" && runtime.GOOS != "windows" {
+			t.Errorf("Unexpected mode %s", listing.Entries[0].Mode)
+		}
+	})
+
+	t.Run("OffsetPastEnd", func(t *testing.T) {
+		listing, _ := callFileList(t, fs, map[string]interface{}{"path": dir, "offset": float64(visible + 10)})
+		if listing.Total != visible || len(listing.Entries) != 0 || listing.HasMore {
+			t.Errorf("Unexpected listing past the end: total %d, %d entries", listing.Total, len(listing.Entries))
+		}
+		listing, _ = callFileList(t, fs, map[string]interface{}{"path": dir, "limit": float64(0)})
+		if listing.Total != visible || len(listing.Entries) != 0 || !listing.HasMore {
+			t.Errorf("Expected only the total with limit 0, got %d entries", len(listing.Entries))
+		}
+	})
+}
+
+func TestFileListFilters(t *testing.T) {
+	fs, dir, symlink := newListTestDir(t)
+	perExt := listTestFiles / len(listTestExts)
+	tests := []struct {
+		name  string
+		args  map[string]interface{}
+		total int
+	}{
+		{"glob", map[string]interface{}{"filter": "*.log"}, perExt},
+		{"glob case sensitive", map[string]interface{}{"filter": "*.pdf"}, 0},
+		{"glob prefix", map[string]interface{}{"filter": "f00[0-4]?.*"}, 40},
+		{"dirs", map[string]interface{}{"type": ListTypeDir}, listTestDirs},
+		{"files", map[string]interface{}{"type": ListTypeFile}, listTestFiles},
+		{"hidden", map[string]interface{}{"filter": ".*", "include_hidden": true}, 1},
+		{"hidden excluded", map[string]interface{}{"filter": ".*"}, 0},
+		{"glob and type", map[string]interface{}{"filter": "d0*", "type": ListTypeFile}, 0},
+	}
+	if symlink {
+		tests = append(tests, struct {
+			name  string
+			args  map[string]interface{}
+			total int
+		}{"symlinks", map[string]interface{}{"type": ListTypeSymlink}, 1})
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.args["path"] = dir
+			tt.args["limit"] = float64(listMaxLimit)
+			listing, errText := callFileList(t, fs, tt.args)
+			if listing == nil {
+				t.Fatalf("Unexpected error: %s", errText)
+			}
+			if listing.Total != tt.total || len(listing.Entries) != min(tt.total, listMaxLimit) {
+				t.Errorf("Expected %d entries, got a total of %d with %d entries", tt.total, listing.Total, len(listing.Entries))
+			}
+			for _, entry := range listing.Entries {
+				if filter, _ := tt.args["filter"].(string); filter != "" {
+					if ok, _ := filepath.Match(filter, entry.Name); !ok {
+						t.Errorf("Entry %s doesn't match %s", entry.Name, filter)
+					}
+				}
+				if typ, _ := tt.args["type"].(string); typ != "" && entry.Type != typ {
+					t.Errorf("Entry %s has type %s, want %s", entry.Name, entry.Type, typ)
+				}
+				if entry.Type == ListTypeSymlink && entry.Target != "f0001.log" {
+					t.Errorf("Expected the symlink target, got %q", entry.Target)
+				}
+			}
+		})
+	}
+}
+
+func TestFileListTopN(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// 同一目录下的条目名唯一，其余排序键大量重复
+	names := rnd.Perm(2000)
+	entries := make([]ListEntry, len(names))
+	for i := range entries {
+		entries[i] = ListEntry{
+			Name:      fmt.Sprintf("e%05d", names[i]),
+			Size:      int64(rnd.Intn(50)),
+			Mtime:     base.Add(time.Duration(rnd.Intn(100)) * time.Second),
+			Extension: listTestExts[rnd.Intn(len(listTestExts))],
+		}
+	}
+	for _, sortBy := range []string{ListSortName, ListSortSize, ListSortMtime, ListSortExtension} {
+		for _, desc := range []bool{false, true} {
+			less := listLess(sortBy, desc)
+			want := append([]ListEntry(nil), entries...)
+			sort.SliceStable(want, func(i, j int) bool { return less(&want[i], &want[j]) })
+			for _, n := range []int{0, 1, 7, 100, len(entries), len(entries) + 5} {
+				top := newTopN(n, less)
+				for _, e := range entries {
+					top.offer(e)
+				}
+				got := top.sorted()
+				if len(got) != min(n, len(entries)) {
+					t.Fatalf("%s desc=%v n=%d: expected %d entries, got %d", sortBy, desc, n, min(n, len(entries)), len(got))
+				}
+				for i := range got {
+					if got[i] != want[i] {
+						t.Fatalf("%s desc=%v n=%d: entry %d is %+v, want %+v", sortBy, desc, n, i, got[i], want[i])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestFileListErrors(t *testing.T) {
+	fs, dir := newTestFilesystemServer(t)
+	file := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(file, []byte("a\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want string
+		code comm.ErrorCode
+	}{
+		{"file", map[string]interface{}{"path": file}, "not a directory", comm.ErrCodeInvalidArgument},
+		{"outside", map[string]interface{}{"path": "../other"}, "access denied", comm.ErrCodePermissionDenied},
+		{"missing", map[string]interface{}{"path": "missing"}, "missing", comm.ErrCodeNotFound},
+		{"filter", map[string]interface{}{"path": dir, "filter": "["}, "invalid filter", comm.ErrCodeInvalidArgument},
+		{"sort_by", map[string]interface{}{"path": dir, "sort_by": "owner"}, "invalid sort_by", comm.ErrCodeInvalidArgument},
+		{"order", map[string]interface{}{"path": dir, "order": "up"}, "invalid order", comm.ErrCodeInvalidArgument},
+		{"type", map[string]interface{}{"path": dir, "type": "socket"}, "invalid type", comm.ErrCodeInvalidArgument},
+		{"limit", map[string]interface{}{"path": dir, "limit": float64(listMaxLimit + 1)}, "limit must be between", comm.ErrCodeInvalidArgument},
+		{"offset", map[string]interface{}{"path": dir, "offset": float64(-1)}, "offset must be between", comm.ErrCodeInvalidArgument},
+		{"missing path", map[string]interface{}{}, "must be a string", comm.ErrCodeInvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listing, errText := callFileList(t, fs, tt.args)
+			// 错误结果为 {"code","message","details"} 结构
+			var payload comm.ToolErrorPayload
+			if err := json.Unmarshal([]byte(errText), &payload); err != nil {
+				t.Fatalf("Expected a JSON error, got %q", errText)
+			}
+			if listing != nil || payload.Code != tt.code || !strings.Contains(strings.ToLower(payload.Message), tt.want) {
+				t.Errorf("Expected a %s error containing %q, got %q", tt.code, tt.want, errText)
+			}
+		})
+	}
+}
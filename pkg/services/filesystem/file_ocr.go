@@ -0,0 +1,101 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/ocr"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// MaxOCRImageSize is the maximum size of an image file recognized by file_ocr (20MB).
+const MaxOCRImageSize = 1024 * 1024 * 20
+
+// ocrEngine returns the OCR engine, created from the config on first use.
+func (fs *FilesystemServer) ocrEngine() (ocr.Engine, error) {
+	if fs.ocr != nil {
+		return fs.ocr, nil
+	}
+	engine, err := ocr.New(fs.cfg().OCR)
+	if err != nil {
+		return nil, err
+	}
+	fs.ocr = engine
+	return engine, nil
+}
+
+// handleFileOCR handles the file_ocr tool.
+func (fs *FilesystemServer) handleFileOCR(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	path, ok := args["path"].(string)
+	if !ok {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("path %v must be a string", args["path"]), ""), nil
+	}
+	withBoxes, _ := args["with_boxes"].(bool)
+	var langs []string
+	if l, ok := args["languages"].(string); ok {
+		for _, lang := range strings.Split(l, ",") {
+			if lang = strings.TrimSpace(lang); lang != "" {
+				langs = append(langs, lang)
+			}
+		}
+	}
+
+	validPath, err := fs.validatePath(path)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	info, err := os.Stat(validPath)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	if info.IsDir() {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("%s is a directory", path), ""), nil
+	}
+	if info.Size() > MaxOCRImageSize {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("image %s is too large (%d bytes, max %d)", path, info.Size(), MaxOCRImageSize), ""), nil
+	}
+
+	engine, err := fs.ocrEngine()
+	if err != nil {
+		return comm.ToolErrorFromErr("no OCR engine available", err), nil
+	}
+	image, err := os.ReadFile(validPath)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to read file", err), nil
+	}
+	result, err := engine.Recognize(ctx, image, ocr.Options{Languages: langs, WithBoxes: withBoxes})
+	if err != nil {
+		return comm.ToolErrorFromErr(fmt.Sprintf("failed to recognize text with %s", engine.Name()), err), nil
+	}
+	if !withBoxes {
+		return mcp.NewToolResultText(result.Text), nil
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to encode the OCR result", err), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gojue/moling/pkg/ocr"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// fakeOCR is an ocr.Engine that returns a fixed result and records the request.
+type fakeOCR struct {
+	image []byte
+	opts  ocr.Options
+}
+
+func (f *fakeOCR) Name() string {
+	return "fake"
+}
+
+func (f *fakeOCR) Recognize(ctx context.Context, image []byte, opts ocr.Options) (*ocr.Result, error) {
+	f.image, f.opts = image, opts
+	result := &ocr.Result{Text: "Hello MoLing"}
+	if opts.WithBoxes {
+		result.Boxes = []ocr.Box{{Text: "Hello", X: 1, Y: 2, Width: 30, Height: 10, Confidence: 90}, {Text: "MoLing", X: 40, Y: 2, Width: 40, Height: 10, Confidence: 88}}
+	}
+	return result, nil
+}
+
+func callFileOCR(fs *FilesystemServer, args map[string]interface{}) (string, bool) {
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "file_ocr"
+	request.Params.Arguments = args
+	result, _ := fs.handleFileOCR(context.Background(), request)
+	return result.Content[0].(mcp.TextContent).Text, result.IsError
+}
+
+func TestFileOCR(t *testing.T) {
+	fs, dir := newTestFilesystemServer(t)
+	image := []byte("\x89PNG\r\n\x1a\n fixture")
+	if err := os.WriteFile(filepath.Join(dir, "text.png"), image, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	t.Run("NotConfigured", func(t *testing.T) {
+		text, isErr := callFileOCR(fs, map[string]interface{}{"path": "text.png"})
+		if !isErr || !strings.Contains(text, "OCR not configured") || !strings.Contains(text, ocr.BackendTesseract) {
+			t.Errorf("Expected OCR not configured error, got %s", text)
+		}
+	})
+
+	engine := &fakeOCR{}
+	fs.ocr = engine
+
+	t.Run("Text", func(t *testing.T) {
+		text, isErr := callFileOCR(fs, map[string]interface{}{"path": "text.png", "languages": "eng, chi_sim"})
+		if isErr || text != "Hello MoLing" {
+			t.Fatalf("Unexpected result %s", text)
+		}
+		if string(engine.image) != string(image) || strings.Join(engine.opts.Languages, ",") != "eng,chi_sim" {
+			t.Errorf("Unexpected request %q %+v", engine.image, engine.opts)
+		}
+	})
+
+	t.Run("WithBoxes", func(t *testing.T) {
+		text, isErr := callFileOCR(fs, map[string]interface{}{"path": "text.png", "with_boxes": true})
+		if isErr {
+			t.Fatalf("Unexpected error %s", text)
+		}
+		var result ocr.Result
+		if err := json.Unmarshal([]byte(text), &result); err != nil {
+			t.Fatalf("Expected JSON, got %s", text)
+		}
+		if len(result.Boxes) != 2 || result.Boxes[1].Text != "MoLing" {
+			t.Errorf("Unexpected boxes %+v", result.Boxes)
+		}
+	})
+
+	t.Run("OutsideAllowedDirs", func(t *testing.T) {
+		if _, isErr := callFileOCR(fs, map[string]interface{}{"path": filepath.Join(os.TempDir(), "..", "etc", "passwd")}); !isErr {
+			t.Error("Expected error for path outside allowed directories")
+		}
+	})
+}
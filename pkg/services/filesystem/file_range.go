@@ -0,0 +1,226 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	rangeDefaultLength = 64 * 1024   // read_file 按范围读取时默认返回的字节数
+	rangeMaxLength     = 1024 * 1024 // length 上限
+	rangeMaxTailLines  = 100000
+)
+
+// tailChunkSize is the size of the blocks read backwards from the end of the file by tail_lines,
+// tests lower it.
+var tailChunkSize int64 = 64 * 1024
+
+// FileRange is the result of read_file with offset, length or tail_lines. Offset and Length are
+// the byte range actually returned, after partial UTF-8 characters at the edges were dropped, so
+// the next range starts at Offset+Length. Truncated is set when the range ends before the end of
+// the file, or when tail_lines returned fewer lines than requested because of length.
+type FileRange struct {
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	Offset    int64  `json:"offset"`
+	Length    int64  `json:"length"`
+	TailLines int    `json:"tail_lines,omitempty"`
+	Truncated bool   `json:"truncated"`
+	Content   string `json:"content"`
+}
+
+// rangeOptions are the range arguments of read_file.
+type rangeOptions struct {
+	Offset    int64
+	Length    int64
+	TailLines int
+}
+
+// parseRangeOptions returns the range arguments of read_file, ok is false when none is given
+// and the whole file is read.
+func parseRangeOptions(args map[string]interface{}) (opts rangeOptions, ok bool, err error) {
+	opts.Length = rangeDefaultLength
+	offset, hasOffset := args["offset"].(float64)
+	length, hasLength := args["length"].(float64)
+	tail, hasTail := args["tail_lines"].(float64)
+	if !hasOffset && !hasLength && !hasTail {
+		return opts, false, nil
+	}
+	if hasOffset {
+		if offset < 0 || offset > math.MaxInt64/2 || offset != math.Trunc(offset) {
+			return opts, true, fmt.Errorf("offset must be a non-negative integer")
+		}
+		opts.Offset = int64(offset)
+	}
+	if hasLength {
+		if length < 1 || length > rangeMaxLength || length != math.Trunc(length) {
+			return opts, true, fmt.Errorf("length must be an integer between 1 and %d", rangeMaxLength)
+		}
+		opts.Length = int64(length)
+	}
+	if hasTail {
+		if hasOffset {
+			return opts, true, fmt.Errorf("tail_lines cannot be combined with offset")
+		}
+		if tail < 1 || tail > rangeMaxTailLines || tail != math.Trunc(tail) {
+			return opts, true, fmt.Errorf("tail_lines must be an integer between 1 and %d", rangeMaxTailLines)
+		}
+		opts.TailLines = int(tail)
+		if !hasLength {
+			opts.Length = rangeMaxLength
+		}
+	}
+	return opts, true, nil
+}
+
+// trimRunes drops the partial UTF-8 character at the start of data when it does not start the
+// file, and at the end when it does not end the file. It returns the bytes dropped at the start
+// and the remaining data.
+func trimRunes(data []byte, atStart, atEnd bool) (int, []byte) {
+	skip := 0
+	if !atStart {
+		for skip < len(data) && skip < utf8.UTFMax-1 && !utf8.RuneStart(data[skip]) {
+			skip++
+		}
+	}
+	data = data[skip:]
+	if !atEnd {
+		// 从末尾找到最后一个字符的起始字节，不完整时去掉
+		for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(data[i]) {
+				if !utf8.FullRune(data[i:]) {
+					data = data[:i]
+				}
+				break
+			}
+		}
+	}
+	return skip, data
+}
+
+// readRange reads length bytes at offset. An offset past the end returns no content.
+func readRange(f *os.File, size int64, opts rangeOptions) (*FileRange, error) {
+	r := &FileRange{Size: size, Offset: opts.Offset}
+	if opts.Offset >= size {
+		return r, nil
+	}
+	end := min(size, opts.Offset+opts.Length)
+	data := make([]byte, end-opts.Offset)
+	if _, err := f.ReadAt(data, opts.Offset); err != nil && err != io.EOF {
+		return nil, err
+	}
+	skip, data := trimRunes(data, opts.Offset == 0, end == size)
+	r.Offset += int64(skip)
+	r.Length = int64(len(data))
+	r.Truncated = r.Offset+r.Length < size
+	r.Content = strings.ToValidUTF8(string(data), "�")
+	return r, nil
+}
+
+// readTail returns the last lines of the file, reading blocks backwards from the end until
+// enough line breaks were seen or opts.Length bytes were scanned. A line break at the very end
+// of the file does not start another line.
+func readTail(f *os.File, size int64, opts rangeOptions) (*FileRange, error) {
+	r := &FileRange{Size: size, TailLines: opts.TailLines}
+	limit := max(size-opts.Length, 0)
+	start := limit
+	found := false
+	breaks := 0
+	buf := make([]byte, tailChunkSize)
+	for pos := size; pos > limit && !found; {
+		n := min(tailChunkSize, pos-limit)
+		pos -= n
+		chunk := buf[:n]
+		if _, err := f.ReadAt(chunk, pos); err != nil && err != io.EOF {
+			return nil, err
+		}
+		for i := len(chunk) - 1; i >= 0; i-- {
+			if chunk[i] != '\n' || pos+int64(i) == size-1 {
+				continue
+			}
+			breaks++
+			if breaks == opts.TailLines {
+				start = pos + int64(i) + 1
+				found = true
+				break
+			}
+		}
+	}
+	if !found && limit > 0 {
+		// 行数不足时受 length 限制，从第一个完整的行开始
+		r.Truncated = true
+		head := make([]byte, min(size-limit, tailChunkSize))
+		if _, err := f.ReadAt(head, limit); err != nil && err != io.EOF {
+			return nil, err
+		}
+		if i := bytes.IndexByte(head, '\n'); i >= 0 && limit+int64(i)+1 < size {
+			start = limit + int64(i) + 1
+		}
+	}
+	data := make([]byte, size-start)
+	if _, err := f.ReadAt(data, start); err != nil && err != io.EOF {
+		return nil, err
+	}
+	skip, data := trimRunes(data, start == 0, true)
+	r.Offset = start + int64(skip)
+	r.Length = int64(len(data))
+	r.Content = strings.ToValidUTF8(string(data), "�")
+	return r, nil
+}
+
+// handleReadRange serves read_file with offset, length or tail_lines, validPath is a file.
+func (fs *FilesystemServer) handleReadRange(validPath string, opts rangeOptions) (*mcp.CallToolResult, error) {
+	f, err := os.Open(validPath)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to read file", err), nil
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to read file", err), nil
+	}
+
+	var r *FileRange
+	if opts.TailLines > 0 {
+		r, err = readTail(f, info.Size(), opts)
+	} else {
+		r, err = readRange(f, info.Size(), opts)
+	}
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to read file", err), nil
+	}
+	r.Path = validPath
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to encode file range", err), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func callReadRange(t *testing.T, fs *FilesystemServer, args map[string]interface{}) (*FileRange, string) {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "read_file"
+	request.Params.Arguments = args
+	result, err := fs.handleReadFile(context.Background(), request)
+	if err != nil {
+		t.Fatalf("handleReadFile failed: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if result.IsError {
+		return nil, text
+	}
+	var r FileRange
+	if err := json.Unmarshal([]byte(text), &r); err != nil {
+		t.Fatalf("Invalid range %s: %v", text, err)
+	}
+	return &r, ""
+}
+
+func TestReadFileRange(t *testing.T) {
+	old := tailChunkSize
+	tailChunkSize = 1024
+	defer func() { tailChunkSize = old }()
+
+	fs, dir := newTestFilesystemServer(t)
+	log := filepath.Join(dir, "app.log")
+	var b strings.Builder
+	for i := 1; i <= 5000; i++ {
+		fmt.Fprintf(&b, "line %05d\n", i) // 每行 11 字节
+	}
+	if err := os.WriteFile(log, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	size := int64(b.Len())
+
+	t.Run("Tail", func(t *testing.T) {
+		// 文件远大于每次向前读取的块
+		r, errText := callReadRange(t, fs, map[string]interface{}{"path": log, "tail_lines": float64(300)})
+		if r == nil {
+			t.Fatalf("Unexpected error: %s", errText)
+		}
+		lines := strings.Split(strings.TrimSuffix(r.Content, "\n"), "\n")
+		if len(lines) != 300 || lines[0] != "line 04701" || lines[299] != "line 05000" {
+			t.Errorf("Expected the last 300 lines, got %d from %q", len(lines), lines[0])
+		}
+		if r.Size != size || r.Offset != size-300*11 || r.Length != 300*11 || r.Truncated {
+			t.Errorf("Unexpected metadata %+v", r)
+		}
+
+		// 不以换行结尾的文件，最后一行也计数
+		short := filepath.Join(dir, "short.txt")
+		if err := os.WriteFile(short, []byte("a\nb\nc"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		r, _ = callReadRange(t, fs, map[string]interface{}{"path": short, "tail_lines": float64(2)})
+		if r.Content != "b\nc" {
+			t.Errorf("Expected the last 2 lines, got %q", r.Content)
+		}
+		r, _ = callReadRange(t, fs, map[string]interface{}{"path": short, "tail_lines": float64(10)})
+		if r.Content != "a\nb\nc" || r.Offset != 0 || r.Truncated {
+			t.Errorf("Expected the whole file, got %+v", r)
+		}
+	})
+
+	t.Run("TailLimitedByLength", func(t *testing.T) {
+		r, _ := callReadRange(t, fs, map[string]interface{}{"path": log, "tail_lines": float64(1000), "length": float64(100)})
+		// 100 字节内只有完整的 9 行
+		if !r.Truncated || r.Content != strings.Join(strings.Split(b.String(), "\n")[4991:], "\n") {
+			t.Errorf("Expected the complete lines within 100 bytes, got %+v", r)
+		}
+	})
+
+	t.Run("Range", func(t *testing.T) {
+		r, _ := callReadRange(t, fs, map[string]interface{}{"path": log, "offset": float64(11), "length": float64(22)})
+		if r.Content != "line 00002\nline 00003\n" || r.Offset != 11 || r.Length != 22 || !r.Truncated {
+			t.Errorf("Unexpected range %+v", r)
+		}
+		r, _ = callReadRange(t, fs, map[string]interface{}{"path": log, "offset": float64(size - 11)})
+		if r.Content != "line 05000\n" || r.Truncated {
+			t.Errorf("Expected the end of the file, got %+v", r)
+		}
+	})
+
+	t.Run("OffsetPastEOF", func(t *testing.T) {
+		r, errText := callReadRange(t, fs, map[string]interface{}{"path": log, "offset": float64(size + 100)})
+		if r == nil {
+			t.Fatalf("Unexpected error: %s", errText)
+		}
+		if r.Content != "" || r.Length != 0 || r.Size != size || r.Truncated {
+			t.Errorf("Expected no content past the end, got %+v", r)
+		}
+	})
+
+	t.Run("UTF8Edges", func(t *testing.T) {
+		file := filepath.Join(dir, "utf8.txt")
+		if err := os.WriteFile(file, []byte("ab中文cd"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		// 从“中”的第二个字节开始，到“文”的第二个字节结束
+		r, _ := callReadRange(t, fs, map[string]interface{}{"path": file, "offset": float64(3), "length": float64(4)})
+		if r.Content != "" || r.Offset != 5 || r.Length != 0 {
+			t.Errorf("Expected the partial characters to be dropped, got %+v", r)
+		}
+		r, _ = callReadRange(t, fs, map[string]interface{}{"path": file, "offset": float64(3), "length": float64(6)})
+		if r.Content != "文c" || r.Offset != 5 || r.Length != 4 {
+			t.Errorf("Expected 文c, got %+v", r)
+		}
+		r, _ = callReadRange(t, fs, map[string]interface{}{"path": file, "length": float64(4)})
+		if r.Content != "ab" || r.Length != 2 || !r.Truncated {
+			t.Errorf("Expected ab, got %+v", r)
+		}
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		for _, args := range []map[string]interface{}{
+			{"path": log, "offset": float64(-1)},
+			{"path": log, "length": float64(0)},
+			{"path": log, "tail_lines": float64(5), "offset": float64(1)},
+			{"path": filepath.Join(t.TempDir(), "outside.log"), "tail_lines": float64(5)},
+		} {
+			if r, _ := callReadRange(t, fs, args); r != nil {
+				t.Errorf("Expected %v to be rejected, got %+v", args, r)
+			}
+		}
+	})
+}
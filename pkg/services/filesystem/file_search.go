@@ -0,0 +1,317 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	searchDefaultMaxResults = 100
+	searchMaxResults        = 10000
+	searchMaxWorkers        = 8
+	searchLineSize          = 64 * 1024 // 超长行只匹配前 64 KiB，其余部分丢弃
+	searchSniffSize         = 8000      // 与 git 相同，前 8000 字节含 NUL 视为二进制文件
+	searchSnippetSize       = 200
+	searchCheckInterval     = 1024 // 每读取多少行检查一次是否已取消
+)
+
+// ContentMatch is one line matching the pattern of search_content, Line is 1-based.
+type ContentMatch struct {
+	Path    string
+	Line    int
+	Snippet string
+}
+
+// contentSearch is the result of searchContent.
+type contentSearch struct {
+	Matches   []ContentMatch
+	Files     int  // 搜索过的文本文件
+	Binary    int  // 跳过的二进制文件
+	Truncated bool // 达到 max_results 后提前停止
+}
+
+// searchOptions are the arguments of search_content.
+type searchOptions struct {
+	Pattern    *regexp.Regexp
+	Glob       string
+	MaxResults int
+}
+
+// parseSearchOptions validates the arguments of search_content.
+func parseSearchOptions(args map[string]interface{}) (searchOptions, error) {
+	opts := searchOptions{MaxResults: searchDefaultMaxResults}
+	pattern, ok := args["pattern"].(string)
+	if !ok || pattern == "" {
+		return opts, fmt.Errorf("pattern must be a non-empty string")
+	}
+	if ci, _ := args["case_insensitive"].(bool); ci {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return opts, fmt.Errorf("invalid pattern: %v", err)
+	}
+	opts.Pattern = re
+	if v, ok := args["glob"].(string); ok && v != "" {
+		if _, err := path.Match(strings.ReplaceAll(v, "**", "*"), ""); err != nil {
+			return opts, fmt.Errorf("invalid glob pattern %q: %v", v, err)
+		}
+		opts.Glob = v
+	}
+	if v, ok := args["max_results"].(float64); ok {
+		if v < 1 || v > searchMaxResults {
+			return opts, fmt.Errorf("max_results must be between 1 and %d", searchMaxResults)
+		}
+		opts.MaxResults = int(v)
+	}
+	return opts, nil
+}
+
+// searchContent searches the text files under root, or root itself when it is a file, for lines
+// matching the pattern. Every file is checked against the read directories after resolving
+// symlinks, files outside them are skipped. The files are read line by line by a bounded pool of
+// workers, and the search stops once more than MaxResults lines matched.
+func (fs *FilesystemServer) searchContent(ctx context.Context, root string, opts searchOptions) (*contentSearch, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	result := &contentSearch{}
+	var mu sync.Mutex
+	paths := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < min(runtime.NumCPU(), searchMaxWorkers); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range paths {
+				if ctx.Err() != nil {
+					continue
+				}
+				// 单个文件最多取 MaxResults+1 行，多出的一行用于判断是否截断
+				matches, binary, err := scanFile(ctx, p, opts.Pattern, opts.MaxResults+1)
+				if err != nil {
+					// 读取时被删除或无权限的文件跳过
+					continue
+				}
+				mu.Lock()
+				if binary {
+					result.Binary++
+				} else {
+					result.Files++
+				}
+				result.Matches = append(result.Matches, matches...)
+				if len(result.Matches) > opts.MaxResults {
+					result.Truncated = true
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	walkErr := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return filepath.SkipAll
+		}
+		if err != nil {
+			if p == root {
+				return err
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if opts.Glob != "" {
+			rel, err := filepath.Rel(root, p)
+			if err != nil || rel == "." {
+				rel = filepath.Base(p)
+			}
+			if !matchGlob(opts.Glob, filepath.ToSlash(rel)) {
+				return nil
+			}
+		}
+		// 每个文件都检查读权限，符号链接指向允许目录之外时跳过
+		realPath, err := fs.validatePath(p)
+		if err != nil {
+			return nil
+		}
+		if info, err := os.Stat(realPath); err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		select {
+		case paths <- p:
+		case <-ctx.Done():
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	close(paths)
+	wg.Wait()
+	if walkErr != nil {
+		return nil, walkErr
+	}
+	if err := ctx.Err(); err != nil && !result.Truncated {
+		return nil, err
+	}
+
+	sort.Slice(result.Matches, func(i, j int) bool {
+		a, b := result.Matches[i], result.Matches[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Line < b.Line
+	})
+	if len(result.Matches) > opts.MaxResults {
+		result.Matches = result.Matches[:opts.MaxResults]
+	}
+	return result, nil
+}
+
+// scanFile returns up to limit lines of the file matching re, reading one line at a time. Files
+// with a NUL byte in the first searchSniffSize bytes are reported as binary and not searched.
+func scanFile(ctx context.Context, name string, re *regexp.Regexp, limit int) ([]ContentMatch, bool, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReaderSize(f, searchLineSize)
+	head, err := r.Peek(searchSniffSize)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, false, err
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil, true, nil
+	}
+
+	var matches []ContentMatch
+	for lineNo := 1; ; lineNo++ {
+		if lineNo%searchCheckInterval == 0 && ctx.Err() != nil {
+			return matches, false, nil
+		}
+		line, err := r.ReadSlice('\n')
+		if len(line) == 0 && err != nil {
+			if errors.Is(err, io.EOF) {
+				return matches, false, nil
+			}
+			return nil, false, err
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		if loc := re.FindIndex(line); loc != nil {
+			matches = append(matches, ContentMatch{Path: name, Line: lineNo, Snippet: snippet(line, loc)})
+			if len(matches) >= limit {
+				return matches, false, nil
+			}
+		}
+		// ReadSlice 返回的内容在下次读取时失效，匹配完再丢弃超长行的剩余部分
+		for errors.Is(err, bufio.ErrBufferFull) {
+			_, err = r.ReadSlice('\n')
+		}
+		if errors.Is(err, io.EOF) {
+			return matches, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+}
+
+// snippet returns the line, or searchSnippetSize bytes of it around the match at loc when it is
+// longer, cut at rune boundaries.
+func snippet(line []byte, loc []int) string {
+	if len(line) <= searchSnippetSize {
+		return strings.ToValidUTF8(string(line), "�")
+	}
+	start := max(0, min(loc[0]-searchSnippetSize/4, len(line)-searchSnippetSize))
+	end := start + searchSnippetSize
+	for start > 0 && !utf8.RuneStart(line[start]) {
+		start--
+	}
+	for end < len(line) && !utf8.RuneStart(line[end]) {
+		end--
+	}
+	s := strings.ToValidUTF8(string(line[start:end]), "�")
+	if start > 0 {
+		s = "..." + s
+	}
+	if end < len(line) {
+		s += "..."
+	}
+	return s
+}
+
+func (fs *FilesystemServer) handleSearchContent(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	p, ok := args["path"].(string)
+	if !ok {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, "path must be a string", ""), nil
+	}
+	opts, err := parseSearchOptions(args)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	validPath, err := fs.validatePath(p)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+
+	found, err := fs.searchContent(ctx, validPath, opts)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to search content", err), nil
+	}
+	if len(found.Matches) == 0 {
+		return mcp.NewToolResultText(fmt.Sprintf("No lines matching pattern '%s' in %s (%d files searched, %d binary files skipped)",
+			args["pattern"], p, found.Files, found.Binary)), nil
+	}
+
+	files := make(map[string]bool)
+	for _, m := range found.Matches {
+		files[m.Path] = true
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Found %d matching lines in %d files (%d files searched, %d binary files skipped):\n\n",
+		len(found.Matches), len(files), found.Files, found.Binary)
+	for _, m := range found.Matches {
+		fmt.Fprintf(&b, "%s:%d: %s\n", m.Path, m.Line, m.Snippet)
+	}
+	if found.Truncated {
+		fmt.Fprintf(&b, "\nStopped after %d matches, narrow the pattern or the glob, or raise max_results.\n", opts.MaxResults)
+	}
+	return mcp.NewToolResultText(b.String()), nil
+}
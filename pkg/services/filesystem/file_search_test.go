@@ -0,0 +1,159 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func callSearchContent(t *testing.T, fs *FilesystemServer, args map[string]interface{}) (string, bool) {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "search_content"
+	request.Params.Arguments = args
+	result, err := fs.handleSearchContent(context.Background(), request)
+	if err != nil {
+		t.Fatalf("handleSearchContent failed: %v", err)
+	}
+	return result.Content[0].(mcp.TextContent).Text, result.IsError
+}
+
+func TestSearchContent(t *testing.T) {
+	fs, dir := newTestFilesystemServer(t)
+	root := filepath.Join(dir, "tree")
+	mtime := time.Now()
+	writeTree(t, root, map[string]string{
+		"main.go":          "package main\n\nfunc main() {\n\tTODO()\n}\n",
+		"src/util/util.go": "package util\r\n// todo: remove\r\n",
+		"src/app.js":       "// TODO later\n",
+		"notes.txt":        "nothing here\n",
+	}, mtime)
+	if err := os.WriteFile(filepath.Join(root, "blob.bin"), []byte("TODO\x00binary"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Matches", func(t *testing.T) {
+		text, isErr := callSearchContent(t, fs, map[string]interface{}{"path": root, "pattern": `TODO\(`})
+		if isErr {
+			t.Fatalf("Unexpected error: %s", text)
+		}
+		want := filepath.Join(root, "main.go") + ":4: \tTODO()"
+		if !strings.Contains(text, want) || !strings.Contains(text, "Found 1 matching lines in 1 files") {
+			t.Errorf("Expected %q, got %s", want, text)
+		}
+	})
+
+	t.Run("GlobAndCase", func(t *testing.T) {
+		text, _ := callSearchContent(t, fs, map[string]interface{}{"path": root, "pattern": "todo", "glob": "src/**/*.go", "case_insensitive": true})
+		want := filepath.Join(root, "src", "util", "util.go") + ":2: // todo: remove\n"
+		if !strings.Contains(text, want) || strings.Contains(text, "app.js") || strings.Contains(text, "main.go") {
+			t.Errorf("Expected only %q, got %s", want, text)
+		}
+		text, _ = callSearchContent(t, fs, map[string]interface{}{"path": root, "pattern": "todo"})
+		if !strings.Contains(text, "util.go") || strings.Contains(text, "app.js") {
+			t.Errorf("Expected a case-sensitive match, got %s", text)
+		}
+	})
+
+	t.Run("BinarySkipped", func(t *testing.T) {
+		text, _ := callSearchContent(t, fs, map[string]interface{}{"path": root, "pattern": "TODO"})
+		if strings.Contains(text, "blob.bin") || !strings.Contains(text, "1 binary files skipped") {
+			t.Errorf("Expected the binary file to be skipped, got %s", text)
+		}
+		if !strings.Contains(text, "app.js:1: // TODO later") {
+			t.Errorf("Expected the text files to be searched, got %s", text)
+		}
+	})
+
+	t.Run("MaxResults", func(t *testing.T) {
+		var b strings.Builder
+		for i := 1; i <= 500; i++ {
+			fmt.Fprintf(&b, "match %d\n", i)
+		}
+		many := filepath.Join(dir, "many")
+		writeTree(t, many, map[string]string{"a.txt": b.String(), "b.txt": b.String()}, mtime)
+		text, isErr := callSearchContent(t, fs, map[string]interface{}{"path": many, "pattern": "match", "max_results": float64(10)})
+		if isErr {
+			t.Fatalf("Unexpected error: %s", text)
+		}
+		if strings.Count(text, ": match ") != 10 || !strings.Contains(text, "Stopped after 10 matches") {
+			t.Errorf("Expected 10 matches and a note, got %s", text)
+		}
+		// 单个文件的结果按行号排序
+		if !strings.Contains(text, ":1: match 1\n") {
+			t.Errorf("Expected the results sorted by line, got %s", text)
+		}
+	})
+
+	t.Run("LongLine", func(t *testing.T) {
+		long := filepath.Join(dir, "long")
+		line := strings.Repeat("x", searchLineSize*2) + "\nneedle " + strings.Repeat("y", 500) + "\n"
+		writeTree(t, long, map[string]string{"long.txt": line}, mtime)
+		text, _ := callSearchContent(t, fs, map[string]interface{}{"path": long, "pattern": "needle"})
+		if !strings.Contains(text, "long.txt:2: needle yyy") || !strings.HasSuffix(strings.TrimSpace(text), "...") {
+			t.Errorf("Expected the second line as a cut snippet, got %s", text)
+		}
+	})
+
+	t.Run("AccessDenied", func(t *testing.T) {
+		outside, _ := filepath.EvalSymlinks(t.TempDir())
+		if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("TODO secret\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		text, isErr := callSearchContent(t, fs, map[string]interface{}{"path": outside, "pattern": "TODO"})
+		if !isErr {
+			t.Errorf("Expected a path outside the allowed directories to be rejected, got %s", text)
+		}
+
+		if runtime.GOOS == "windows" {
+			return
+		}
+		// 允许目录中指向外部的符号链接不被读取
+		if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(root, "link.txt")); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(filepath.Join(root, "link.txt"))
+		text, _ = callSearchContent(t, fs, map[string]interface{}{"path": root, "pattern": "secret"})
+		if strings.Contains(text, "link.txt") || !strings.Contains(text, "No lines matching") {
+			t.Errorf("Expected the symlink to be skipped, got %s", text)
+		}
+	})
+
+	t.Run("InvalidArguments", func(t *testing.T) {
+		for _, args := range []map[string]interface{}{
+			{"path": root, "pattern": "("},
+			{"path": root, "pattern": ""},
+			{"path": root, "pattern": "x", "glob": "["},
+			{"path": root, "pattern": "x", "max_results": float64(0)},
+		} {
+			if text, isErr := callSearchContent(t, fs, args); !isErr {
+				t.Errorf("Expected an error for %v, got %s", args, text)
+			}
+		}
+	})
+}
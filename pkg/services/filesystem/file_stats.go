@@ -0,0 +1,474 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// EncodingUTF8 is reported for UTF-8 files, with or without BOM. Plain ASCII is UTF-8 too.
+	EncodingUTF8 = "UTF-8"
+	// EncodingUTF16LE is reported for files starting with the UTF-16 little endian BOM.
+	EncodingUTF16LE = "UTF-16LE"
+	// EncodingUTF16BE is reported for files starting with the UTF-16 big endian BOM.
+	EncodingUTF16BE = "UTF-16BE"
+	// EncodingLatin1 is reported for text files that are not valid UTF-8.
+	EncodingLatin1 = "ISO-8859-1"
+	// EncodingBinary is reported for binary files, which are not counted.
+	EncodingBinary = "binary"
+
+	// statsSniffSize is the number of bytes the encoding is detected from.
+	statsSniffSize = 8192
+	// maxTopWords is the maximum of top_words.
+	maxTopWords = 100
+	// maxWordLength is the maximum length in runes of the words counted for top_words, longer ones are skipped.
+	maxWordLength = 64
+	// csvSniffLines is the number of lines the CSV delimiter is detected from.
+	csvSniffLines = 5
+	// csvSniffSize is the maximum size of the lines the CSV delimiter is detected from.
+	csvSniffSize = 64 * 1024
+)
+
+// maxTrackedWords caps the word frequency map of top_words, words first seen after it is full are not counted.
+var maxTrackedWords = 50000
+
+// csvDelimiters are the delimiter candidates, in order of preference.
+var csvDelimiters = []rune{',', ';', '\t', '|'}
+
+// stopwords are the common English words left out of top_words by default.
+var stopwords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`a about above after again against all am an and any are as at be because been
+		before being below between both but by can could did do does doing down during each few for from further had
+		has have having he her here hers herself him himself his how i if in into is it its itself just me more most
+		my myself no nor not now of off on once only or other our ours ourselves out over own same she should so some
+		such than that the their theirs them themselves then there these they this those through to too under until
+		up very was we were what when where which while who whom why will with would you your yours yourself
+		yourselves`) {
+		stopwords[w] = true
+	}
+}
+
+// FileStats is the result of the file_stats tool.
+type FileStats struct {
+	Path        string      `json:"path"`
+	Size        int64       `json:"size"`
+	Encoding    string      `json:"encoding"`
+	BOM         bool        `json:"bom,omitempty"`
+	Lines       int         `json:"lines"`
+	Words       int         `json:"words"`
+	Chars       int         `json:"chars"`
+	LongestLine int         `json:"longest_line"` // in characters, without the line ending
+	CSV         *CSVStats   `json:"csv,omitempty"`
+	TopWords    []WordCount `json:"top_words,omitempty"`
+	// TopWordsCapped is set when the word frequency map was full and some words were not counted.
+	TopWordsCapped bool `json:"top_words_capped,omitempty"`
+}
+
+// CSVStats describes the first row of a CSV or TSV file.
+type CSVStats struct {
+	Delimiter string `json:"delimiter"`
+	Columns   int    `json:"columns"`
+}
+
+// WordCount is a word and its number of occurrences.
+type WordCount struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+// handleFileStats handles the file_stats tool.
+func (fs *FilesystemServer) handleFileStats(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	path, ok := args["path"].(string)
+	if !ok {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("path %v must be a string", args["path"]), ""), nil
+	}
+	topWords := 0
+	if v, ok := args["top_words"].(float64); ok {
+		if v < 0 || v > maxTopWords {
+			return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("top_words must be between 0 and %d", maxTopWords), ""), nil
+		}
+		topWords = int(v)
+	}
+	excludeStopwords := true
+	if v, ok := args["exclude_stopwords"].(bool); ok {
+		excludeStopwords = v
+	}
+
+	validPath, err := fs.validatePath(path)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	info, err := os.Stat(validPath)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	if !info.Mode().IsRegular() {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("%s is not a regular file", path), ""), nil
+	}
+	f, err := os.Open(validPath)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to open file", err), nil
+	}
+	defer f.Close()
+
+	ext := strings.ToLower(filepath.Ext(validPath))
+	sc := statsCounter{
+		topWords:         topWords,
+		excludeStopwords: excludeStopwords,
+		csv:              ext == ".csv" || ext == ".tsv",
+	}
+	stats, err := sc.count(ctx, f)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to read file", err), nil
+	}
+	stats.Path = validPath
+	stats.Size = info.Size()
+
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to encode file stats", err), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// detectEncoding detects the encoding from the first bytes of a file. It returns the encoding and
+// the length of the BOM. Files with a NUL byte or mostly control characters are binary.
+func detectEncoding(head []byte, eof bool) (string, int) {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}):
+		return EncodingUTF8, 3
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		return EncodingUTF16LE, 2
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		return EncodingUTF16BE, 2
+	}
+	control := 0
+	for _, b := range head {
+		if b == 0 {
+			return EncodingBinary, 0
+		}
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f' && b != 0x1B {
+			control++
+		}
+	}
+	if control*10 > len(head) {
+		return EncodingBinary, 0
+	}
+	valid := head
+	if !eof {
+		// 头部可能在一个多字节字符中间截断
+		if cut := lastRuneStart(valid); cut >= 0 && !utf8.FullRune(valid[cut:]) {
+			valid = valid[:cut]
+		}
+	}
+	if utf8.Valid(valid) {
+		return EncodingUTF8, 0
+	}
+	return EncodingLatin1, 0
+}
+
+// lastRuneStart returns the index of the start of the last rune of b, or -1 when b is empty.
+func lastRuneStart(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			return i
+		}
+	}
+	return len(b) - 1
+}
+
+// runeReader decodes the runes of one encoding.
+type runeReader func() (rune, error)
+
+// newRuneReader returns a runeReader for the encoding. Invalid sequences are decoded as one
+// utf8.RuneError per byte (UTF-8) or code unit (UTF-16).
+func newRuneReader(r *bufio.Reader, encoding string) runeReader {
+	switch encoding {
+	case EncodingLatin1:
+		return func() (rune, error) {
+			b, err := r.ReadByte()
+			return rune(b), err
+		}
+	case EncodingUTF16LE, EncodingUTF16BE:
+		readUnit := func() (uint16, error) {
+			var u [2]byte
+			if _, err := io.ReadFull(r, u[:]); err != nil {
+				if err == io.ErrUnexpectedEOF {
+					// 奇数字节，最后一个字节无法解码
+					return unicode.ReplacementChar, nil
+				}
+				return 0, err
+			}
+			if encoding == EncodingUTF16LE {
+				return uint16(u[0]) | uint16(u[1])<<8, nil
+			}
+			return uint16(u[1]) | uint16(u[0])<<8, nil
+		}
+		var pending *uint16
+		return func() (rune, error) {
+			var u1 uint16
+			if pending != nil {
+				u1, pending = *pending, nil
+			} else {
+				var err error
+				if u1, err = readUnit(); err != nil {
+					return 0, err
+				}
+			}
+			if !utf16.IsSurrogate(rune(u1)) {
+				return rune(u1), nil
+			}
+			u2, err := readUnit()
+			if err == io.EOF {
+				return unicode.ReplacementChar, nil
+			} else if err != nil {
+				return 0, err
+			}
+			if r := utf16.DecodeRune(rune(u1), rune(u2)); r != unicode.ReplacementChar {
+				return r, nil
+			}
+			// 无效的代理对，第二个码元单独解码
+			pending = &u2
+			return unicode.ReplacementChar, nil
+		}
+	default:
+		return func() (rune, error) {
+			r, _, err := r.ReadRune()
+			return r, err
+		}
+	}
+}
+
+// statsCounter counts the statistics of a file in one pass. Only the word frequency map of
+// top_words and the first lines of CSV files are kept in memory.
+type statsCounter struct {
+	topWords         int
+	excludeStopwords bool
+	csv              bool
+}
+
+// count reads f and returns its statistics. Binary files are not counted.
+func (sc *statsCounter) count(ctx context.Context, f io.Reader) (*FileStats, error) {
+	r := bufio.NewReaderSize(f, statsSniffSize)
+	head, err := r.Peek(statsSniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	encoding, bomLen := detectEncoding(head, err == io.EOF)
+	stats := &FileStats{Encoding: encoding, BOM: bomLen > 0}
+	if encoding == EncodingBinary {
+		return stats, nil
+	}
+	if _, err := r.Discard(bomLen); err != nil {
+		return nil, err
+	}
+
+	var (
+		read       = newRuneReader(r, encoding)
+		inWord     bool
+		lineLen    int
+		lastRune   rune
+		word       strings.Builder
+		wordLen    int
+		freq       map[string]int
+		csvHead    strings.Builder
+		csvLines   int
+		csvCapture = sc.csv
+	)
+	if sc.topWords > 0 {
+		freq = make(map[string]int)
+	}
+	flushWord := func() {
+		if wordLen > 0 && wordLen <= maxWordLength {
+			w := word.String()
+			if !sc.excludeStopwords || !stopwords[w] {
+				if _, ok := freq[w]; ok || len(freq) < maxTrackedWords {
+					freq[w]++
+				} else {
+					stats.TopWordsCapped = true
+				}
+			}
+		}
+		word.Reset()
+		wordLen = 0
+	}
+
+	for i := 0; ; i++ {
+		if i%65536 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		c, err := read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		stats.Chars++
+		lastRune = c
+
+		if unicode.IsSpace(c) {
+			inWord = false
+		} else if !inWord {
+			inWord = true
+			stats.Words++
+		}
+
+		if c == '\n' {
+			stats.Lines++
+			lineLen = 0
+		} else if c != '\r' {
+			lineLen++
+			if lineLen > stats.LongestLine {
+				stats.LongestLine = lineLen
+			}
+		}
+
+		if freq != nil {
+			if unicode.IsLetter(c) || unicode.IsDigit(c) {
+				if wordLen <= maxWordLength {
+					word.WriteRune(unicode.ToLower(c))
+				}
+				wordLen++
+			} else {
+				flushWord()
+			}
+		}
+
+		if csvCapture {
+			csvHead.WriteRune(c)
+			if c == '\n' {
+				csvLines++
+			}
+			csvCapture = csvLines < csvSniffLines && csvHead.Len() < csvSniffSize
+		}
+	}
+	if stats.Chars > 0 && lastRune != '\n' {
+		stats.Lines++
+	}
+	if freq != nil {
+		flushWord()
+		stats.TopWords = topWordCounts(freq, sc.topWords)
+	}
+	if sc.csv && stats.Chars > 0 {
+		stats.CSV = sniffCSV(csvHead.String())
+	}
+	return stats, nil
+}
+
+// topWordCounts returns the n most frequent words, ties are ordered alphabetically.
+func topWordCounts(freq map[string]int, n int) []WordCount {
+	counts := make([]WordCount, 0, len(freq))
+	for w, c := range freq {
+		counts = append(counts, WordCount{Word: w, Count: c})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Word < counts[j].Word
+	})
+	if len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+// sniffCSV detects the delimiter from the first lines of a CSV file and counts the columns of the
+// first row. The delimiter that occurs the same number of times on every line wins, then the one
+// occurring most often on the first line. Delimiters inside quotes are not counted.
+func sniffCSV(head string) *CSVStats {
+	lines := strings.Split(strings.ReplaceAll(head, "\r\n", "\n"), "\n")
+	if len(lines) > 1 && !strings.HasSuffix(head, "\n") {
+		// 最后一行可能不完整
+		lines = lines[:len(lines)-1]
+	}
+	var nonEmpty []string
+	for _, line := range lines {
+		if line != "" {
+			nonEmpty = append(nonEmpty, line)
+		}
+	}
+	if len(nonEmpty) == 0 {
+		return &CSVStats{Delimiter: ",", Columns: 0}
+	}
+
+	best, bestCount, bestConsistent := csvDelimiters[0], 0, false
+	for _, d := range csvDelimiters {
+		first := countDelimiter(nonEmpty[0], d)
+		if first == 0 {
+			continue
+		}
+		consistent := true
+		for _, line := range nonEmpty[1:] {
+			if countDelimiter(line, d) != first {
+				consistent = false
+				break
+			}
+		}
+		if (consistent && !bestConsistent) || (consistent == bestConsistent && first > bestCount) {
+			best, bestCount, bestConsistent = d, first, consistent
+		}
+	}
+
+	cr := csv.NewReader(strings.NewReader(head))
+	cr.Comma = best
+	cr.LazyQuotes = true
+	cr.FieldsPerRecord = -1
+	columns := bestCount + 1
+	if record, err := cr.Read(); err == nil {
+		columns = len(record)
+	}
+	return &CSVStats{Delimiter: string(best), Columns: columns}
+}
+
+// countDelimiter counts the delimiters of a line outside double quotes.
+func countDelimiter(line string, d rune) int {
+	n, quoted := 0, false
+	for _, c := range line {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == d && !quoted:
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,275 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func callFileStats(t *testing.T, fs *FilesystemServer, args map[string]interface{}) (*FileStats, string) {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "file_stats"
+	request.Params.Arguments = args
+	result, err := fs.handleFileStats(context.Background(), request)
+	if err != nil {
+		t.Fatalf("handleFileStats failed: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if result.IsError {
+		return nil, text
+	}
+	var stats FileStats
+	if err := json.Unmarshal([]byte(text), &stats); err != nil {
+		t.Fatalf("Expected structured JSON, got %s", text)
+	}
+	return &stats, ""
+}
+
+// encodeUTF16 encodes s as UTF-16 with a BOM.
+func encodeUTF16(s string, order binary.ByteOrder) []byte {
+	units := append([]uint16{0xFEFF}, utf16.Encode([]rune(s))...)
+	data := make([]byte, 2*len(units))
+	for i, u := range units {
+		order.PutUint16(data[2*i:], u)
+	}
+	return data
+}
+
+func TestFileStats(t *testing.T) {
+	fs, dir := newTestFilesystemServer(t)
+	tests := []struct {
+		name     string
+		file     string
+		content  []byte
+		expected FileStats
+	}{
+		{
+			name:     "multi-byte UTF-8",
+			file:     "multibyte.txt",
+			content:  []byte("héllo 世界\n日本語 text\n"),
+			expected: FileStats{Size: 29, Encoding: EncodingUTF8, Lines: 2, Words: 4, Chars: 18, LongestLine: 8},
+		},
+		{
+			name:     "no trailing newline",
+			file:     "notrailing.txt",
+			content:  []byte("one two\r\nthree"),
+			expected: FileStats{Size: 14, Encoding: EncodingUTF8, Lines: 2, Words: 3, Chars: 14, LongestLine: 7},
+		},
+		{
+			name:     "empty",
+			file:     "empty.txt",
+			content:  []byte{},
+			expected: FileStats{Encoding: EncodingUTF8},
+		},
+		{
+			name:     "UTF-8 BOM",
+			file:     "bom.txt",
+			content:  []byte("\xEF\xBB\xBFabc déf\n"),
+			expected: FileStats{Size: 12, Encoding: EncodingUTF8, BOM: true, Lines: 1, Words: 2, Chars: 8, LongestLine: 7},
+		},
+		{
+			name:     "UTF-16LE BOM",
+			file:     "utf16le.txt",
+			content:  encodeUTF16("hi 世界 😀\nok\n", binary.LittleEndian),
+			expected: FileStats{Size: 26, Encoding: EncodingUTF16LE, BOM: true, Lines: 2, Words: 4, Chars: 11, LongestLine: 7},
+		},
+		{
+			name:     "UTF-16BE BOM",
+			file:     "utf16be.txt",
+			content:  encodeUTF16("hi 世界 😀\nok\n", binary.BigEndian),
+			expected: FileStats{Size: 26, Encoding: EncodingUTF16BE, BOM: true, Lines: 2, Words: 4, Chars: 11, LongestLine: 7},
+		},
+		{
+			name:     "ISO-8859-1",
+			file:     "latin1.txt",
+			content:  []byte("caf\xE9 na\xEFve\n"),
+			expected: FileStats{Size: 11, Encoding: EncodingLatin1, Lines: 1, Words: 2, Chars: 11, LongestLine: 10},
+		},
+		{
+			name:     "binary",
+			file:     "image.bin",
+			content:  append([]byte("\x89PNG\r\n\x1A\n\x00\x00\x00\rIHDR"), make([]byte, 100)...),
+			expected: FileStats{Size: 116, Encoding: EncodingBinary},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(path, tt.content, 0644); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+			stats, errText := callFileStats(t, fs, map[string]interface{}{"path": path})
+			if stats == nil {
+				t.Fatalf("Unexpected error: %s", errText)
+			}
+			tt.expected.Path = path
+			if !reflect.DeepEqual(*stats, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, *stats)
+			}
+		})
+	}
+}
+
+func TestFileStatsBinaryShortCircuit(t *testing.T) {
+	sc := statsCounter{topWords: 10, csv: true}
+	// 一个 NUL 字节就足以判定为二进制，不再读取后面的内容
+	r := &countingReader{data: append([]byte("text\x00"), make([]byte, 4*statsSniffSize)...)}
+	stats, err := sc.count(context.Background(), r)
+	if err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if stats.Encoding != EncodingBinary || stats.Chars != 0 || stats.CSV != nil || stats.TopWords != nil {
+		t.Errorf("Expected an uncounted binary file, got %+v", *stats)
+	}
+	if r.read > statsSniffSize {
+		t.Errorf("Expected at most %d bytes to be read, read %d", statsSniffSize, r.read)
+	}
+
+	// 控制字符过多也是二进制
+	encoding, _ := detectEncoding([]byte("\x01\x02\x03\x04abc\x05\x06"), true)
+	if encoding != EncodingBinary {
+		t.Errorf("Expected binary for control characters, got %s", encoding)
+	}
+	// 头部在多字节字符中间截断时仍是 UTF-8
+	head := []byte(strings.Repeat("世", 10))
+	if encoding, _ := detectEncoding(head[:len(head)-1], false); encoding != EncodingUTF8 {
+		t.Errorf("Expected UTF-8 for a truncated head, got %s", encoding)
+	}
+}
+
+type countingReader struct {
+	data []byte
+	read int
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	if len(cr.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, cr.data)
+	cr.data = cr.data[n:]
+	cr.read += n
+	return n, nil
+}
+
+func TestFileStatsCSV(t *testing.T) {
+	fs, dir := newTestFilesystemServer(t)
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		expected *CSVStats
+	}{
+		{"comma", "data.csv", "name,age\n\"Doe, John\",42\n", &CSVStats{Delimiter: ",", Columns: 2}},
+		{"semicolon", "data_semicolon.csv", "a,b;c;d\n1;2;3\n\"x;y\";5;6\n", &CSVStats{Delimiter: ";", Columns: 3}},
+		{"tab", "data.tsv", "a\tb\tc\td\r\n1\t2\t3\t4\r\n", &CSVStats{Delimiter: "\t", Columns: 4}},
+		{"pipe", "data_pipe.CSV", "id|note\n1|a,b\n2|c,d,e\n", &CSVStats{Delimiter: "|", Columns: 2}},
+		{"single column", "single.csv", "name\nalice\n", &CSVStats{Delimiter: ",", Columns: 1}},
+		{"not csv", "data.txt", "a,b,c\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+			stats, errText := callFileStats(t, fs, map[string]interface{}{"path": path})
+			if stats == nil {
+				t.Fatalf("Unexpected error: %s", errText)
+			}
+			if !reflect.DeepEqual(stats.CSV, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, stats.CSV)
+			}
+		})
+	}
+}
+
+func TestFileStatsTopWords(t *testing.T) {
+	fs, dir := newTestFilesystemServer(t)
+	path := filepath.Join(dir, "words.txt")
+	if err := os.WriteFile(path, []byte("The cat and the hat.\nThe CAT sat! Café café\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	stats, errText := callFileStats(t, fs, map[string]interface{}{"path": path, "top_words": float64(3)})
+	if stats == nil {
+		t.Fatalf("Unexpected error: %s", errText)
+	}
+	expected := []WordCount{{"café", 2}, {"cat", 2}, {"hat", 1}}
+	if !reflect.DeepEqual(stats.TopWords, expected) {
+		t.Errorf("Expected %v, got %v", expected, stats.TopWords)
+	}
+
+	stats, _ = callFileStats(t, fs, map[string]interface{}{"path": path, "top_words": float64(1), "exclude_stopwords": false})
+	if expected := []WordCount{{"the", 3}}; !reflect.DeepEqual(stats.TopWords, expected) {
+		t.Errorf("Expected %v, got %v", expected, stats.TopWords)
+	}
+
+	t.Run("Capped", func(t *testing.T) {
+		defer func(old int) { maxTrackedWords = old }(maxTrackedWords)
+		maxTrackedWords = 2
+		if err := os.WriteFile(path, []byte("alpha beta gamma alpha gamma\n"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		stats, _ := callFileStats(t, fs, map[string]interface{}{"path": path, "top_words": float64(5)})
+		expected := []WordCount{{"alpha", 2}, {"beta", 1}}
+		if !reflect.DeepEqual(stats.TopWords, expected) || !stats.TopWordsCapped {
+			t.Errorf("Expected capped %v, got %v (capped %v)", expected, stats.TopWords, stats.TopWordsCapped)
+		}
+	})
+}
+
+func TestFileStatsErrors(t *testing.T) {
+	fs, dir := newTestFilesystemServer(t)
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("a\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want string
+	}{
+		{"directory", map[string]interface{}{"path": dir}, "not a regular file"},
+		{"outside", map[string]interface{}{"path": "../other.txt"}, "access denied"},
+		{"missing", map[string]interface{}{"path": "missing.txt"}, "missing.txt"},
+		{"top_words", map[string]interface{}{"path": path, "top_words": float64(maxTopWords + 1)}, "top_words must be between"},
+		{"missing path", map[string]interface{}{}, "must be a string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats, errText := callFileStats(t, fs, tt.args)
+			if stats != nil || !strings.Contains(strings.ToLower(errText), tt.want) {
+				t.Errorf("Expected an error containing %q, got %q", tt.want, errText)
+			}
+		})
+	}
+}
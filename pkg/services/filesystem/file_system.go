@@ -22,18 +22,20 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gojue/moling/pkg/comm"
 	"github.com/gojue/moling/pkg/config"
+	"github.com/gojue/moling/pkg/ocr"
 	"github.com/gojue/moling/pkg/services/abstract"
 	"github.com/gojue/moling/pkg/utils"
 	"github.com/mark3labs/mcp-go/mcp"
-	"github.com/rs/zerolog"
 )
 
 const (
@@ -58,31 +60,29 @@ type FileInfo struct {
 
 type FilesystemServer struct {
 	abstract.MLService
-	config *FileSystemConfig
+	config     *FileSystemConfig // 重新加载时整体替换，处理函数通过 cfg 读取
+	configLock sync.RWMutex      // 保护 config
+	ocr        ocr.Engine        // OCR 引擎，为空时按配置创建
+	// editLocks 串行化同一文件的按行编辑
+	editLocks pathLocks
+	watches   watchSet                                                              // watch_path 注册的监听
+	notify    func(ctx context.Context, method string, params map[string]any) error // 向客户端发送通知，测试时替换
 }
 
 func NewFilesystemServer(ctx context.Context) (abstract.Service, error) {
-	// Validate the config
-	var err error
-	globalConf := ctx.Value(comm.MoLingConfigKey).(*config.MoLingConfig)
-	userDataDir := filepath.Join(globalConf.BasePath, "data")
-
-	fc := NewFileSystemConfig(userDataDir)
-
-	lger, ok := ctx.Value(comm.MoLingLoggerKey).(zerolog.Logger)
-	if !ok {
-		return nil, fmt.Errorf("FilesystemServer: invalid logger type")
+	base, err := abstract.NewServiceBase(ctx, FilesystemServerName)
+	if err != nil {
+		return nil, err
 	}
-
-	loggerNameHook := zerolog.HookFunc(func(e *zerolog.Event, level zerolog.Level, msg string) {
-		e.Str("Service", string(FilesystemServerName))
-	})
+	userDataDir := filepath.Join(base.MlConfig().BasePath, "data")
 
 	fs := &FilesystemServer{
-		MLService: abstract.NewMLService(ctx, lger.Hook(loggerNameHook), globalConf),
-		config:    fc,
+		MLService: base,
+		config:    NewFileSystemConfig(userDataDir),
 	}
 
+	fs.notify = fs.SendNotification
+
 	err = fs.InitResources()
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize filesystem server: %v", err)
@@ -91,7 +91,7 @@ func NewFilesystemServer(ctx context.Context) (abstract.Service, error) {
 	return fs, nil
 }
 
-func (fs *FilesystemServer) Init() error {
+func (fs *FilesystemServer) RegisterTools() error {
 	// Register resource handlers
 	fs.AddResource(mcp.NewResource("file://", "File System",
 		mcp.WithResourceDescription("Access to files and directories on the local file system"),
@@ -108,16 +108,25 @@ func (fs *FilesystemServer) Init() error {
 
 	// Register tool handlers
 	fs.AddTool(mcp.NewTool("read_file",
-		mcp.WithDescription("Read the complete contents of a file from the file system."),
+		mcp.WithDescription("Read the complete contents of a file from the file system. For large files such as logs, read a byte range with offset and length, or the last lines with tail_lines; the range is returned as JSON with the file size, the exact byte range and whether more content follows."),
 		mcp.WithString("path",
 			mcp.Description("Relative path to the file to read"),
 			mcp.Required(),
 		),
+		mcp.WithNumber("offset",
+			mcp.Description("Byte offset to start reading at (default: 0). An offset past the end of the file returns no content"),
+		),
+		mcp.WithNumber("length",
+			mcp.Description(fmt.Sprintf("Maximum number of bytes to return (default: %d, max: %d)", rangeDefaultLength, rangeMaxLength)),
+		),
+		mcp.WithNumber("tail_lines",
+			mcp.Description(fmt.Sprintf("Return the last lines of the file, at most length bytes (default length: %d). Cannot be combined with offset", rangeMaxLength)),
+		),
 	), fs.handleReadFile)
 
 	fs.AddTool(mcp.NewTool(
 		"write_file",
-		mcp.WithDescription("Create a new file or overwrite an existing file with new content."),
+		mcp.WithDescription("Create a new file or overwrite an existing file with new content, or append to it. Returns the number of bytes written."),
 		mcp.WithString("path",
 			mcp.Description("Relative Path where to write the file"),
 			mcp.Required(),
@@ -126,6 +135,16 @@ func (fs *FilesystemServer) Init() error {
 			mcp.Description("Content to write to the file"),
 			mcp.Required(),
 		),
+		mcp.WithString("mode",
+			mcp.Description(fmt.Sprintf("%s replaces the content, %s adds it to the end, %s fails when the file exists (default: %s)", WriteModeOverwrite, WriteModeAppend, WriteModeCreateNew, WriteModeOverwrite)),
+			mcp.Enum(WriteModeOverwrite, WriteModeAppend, WriteModeCreateNew),
+		),
+		mcp.WithBoolean("atomic",
+			mcp.Description("Write to a temporary file in the same directory and rename it into place, so a failed write never leaves a partial file. The permissions of an existing file are kept (default: false)"),
+		),
+		mcp.WithBoolean("backup",
+			mcp.Description("Copy an existing file to <name>.bak before changing it (default: false)"),
+		),
 	), fs.handleWriteFile)
 
 	fs.AddTool(mcp.NewTool(
@@ -137,6 +156,57 @@ func (fs *FilesystemServer) Init() error {
 		),
 	), fs.handleListDirectory)
 
+	fs.AddTool(mcp.NewTool(
+		"file_list",
+		mcp.WithDescription("List a directory as JSON with the size, modification time, mode and extension of every entry, sorted and paginated. Returns total, the number of entries that pass the filters, and has_more; page with offset. Use it instead of list_directory for large directories such as Downloads."),
+		mcp.WithString("path",
+			mcp.Description("Relative Path of the directory to list"),
+			mcp.Required(),
+		),
+		mcp.WithString("sort_by",
+			mcp.Description("Sort key (default: name)"),
+			mcp.Enum(ListSortName, ListSortSize, ListSortMtime, ListSortExtension),
+		),
+		mcp.WithString("order",
+			mcp.Description("Sort order (default: asc)"),
+			mcp.Enum(ListOrderAsc, ListOrderDesc),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description(fmt.Sprintf("Maximum entries to return, at most %d; 0 returns only the total (default: %d)", listMaxLimit, listDefaultLimit)),
+		),
+		mcp.WithNumber("offset",
+			mcp.Description("Entries to skip in the sorted order (default: 0)"),
+		),
+		mcp.WithString("filter",
+			mcp.Description("Glob the entry names must match, e.g. *.pdf"),
+		),
+		mcp.WithString("type",
+			mcp.Description("Only list entries of this type"),
+			mcp.Enum(ListTypeFile, ListTypeDir, ListTypeSymlink),
+		),
+		mcp.WithBoolean("include_hidden",
+			mcp.Description("Include entries whose names start with a dot (default: false)"),
+		),
+	), fs.handleFileList)
+
+	fs.AddTool(mcp.NewTool(
+		"directory_tree",
+		mcp.WithDescription("Return the tree below a directory as JSON in one call, with the type, size and modification time of every entry and the total size of the files below every directory. Directories are listed breadth first up to max_depth levels; when max_entries is reached the partial tree is returned with truncated set. Symlinks are reported with their target and never followed."),
+		mcp.WithString("path",
+			mcp.Description("Path of the root directory"),
+			mcp.Required(),
+		),
+		mcp.WithNumber("max_depth",
+			mcp.Description(fmt.Sprintf("Number of levels to list below the root, deeper directories have children_omitted set (default: %d, max: %d)", treeDefaultMaxDepth, treeMaxDepth)),
+		),
+		mcp.WithNumber("max_entries",
+			mcp.Description(fmt.Sprintf("Maximum number of entries in the tree (default: %d, max: %d)", treeDefaultMaxEntries, treeMaxEntries)),
+		),
+		mcp.WithBoolean("include_hidden",
+			mcp.Description("Include entries whose names start with a dot, such as .git (default: false)"),
+		),
+	), fs.handleDirectoryTree)
+
 	fs.AddTool(mcp.NewTool(
 		"create_directory",
 		mcp.WithDescription("Create a new directory or ensure a directory exists."),
@@ -159,6 +229,68 @@ func (fs *FilesystemServer) Init() error {
 		),
 	), fs.handleMoveFile)
 
+	conflictDesc := mcp.Description("What to do when the destination exists: error (default), overwrite, or rename (append a numeric suffix and report the final name)")
+	fs.AddTool(mcp.NewTool(
+		"file_edit_lines",
+		mcp.WithDescription("Insert, replace or delete lines of a text file by line number (1-based). The line endings and the trailing newline of the file are kept. Returns a unified diff of the change and the new line count."),
+		mcp.WithString("path",
+			mcp.Description("Relative Path to the file"),
+			mcp.Required(),
+		),
+		mcp.WithString("operation",
+			mcp.Description("insert_after and insert_before insert the content next to start_line, replace_range replaces and delete_range deletes the lines start_line to end_line"),
+			mcp.Enum(EditInsertAfter, EditInsertBefore, EditReplaceRange, EditDeleteRange),
+			mcp.Required(),
+		),
+		mcp.WithNumber("start_line",
+			mcp.Description("First line of the range, or the line to insert next to. insert_after 0 inserts at the beginning, insert_after the line count appends"),
+			mcp.Required(),
+		),
+		mcp.WithNumber("end_line",
+			mcp.Description("Last line of the range, inclusive (default: start_line)"),
+		),
+		mcp.WithString("content",
+			mcp.Description("The lines to insert or the replacement lines, may span multiple lines. Use \\n to insert an empty line"),
+		),
+	), fs.handleEditLines)
+
+	fs.AddTool(mcp.NewTool(
+		"file_move",
+		mcp.WithDescription("Move or rename a file or directory. Falls back to copy and delete when the destination is on another device."),
+		mcp.WithString("source",
+			mcp.Description("Source path of the file or directory"),
+			mcp.Required(),
+		),
+		mcp.WithString("destination",
+			mcp.Description("Destination path, the full path of the moved file or directory"),
+			mcp.Required(),
+		),
+		mcp.WithString("on_conflict", conflictDesc, mcp.Enum(ConflictError, ConflictOverwrite, ConflictRename)),
+		mcp.WithBoolean("create_parents",
+			mcp.Description("Create the missing parent directories of the destination"),
+		),
+	), fs.handleFileMove)
+
+	fs.AddTool(mcp.NewTool(
+		"file_copy",
+		mcp.WithDescription("Copy a file, or a directory with recursive, keeping permissions and modification times. Reports the bytes copied and the final path."),
+		mcp.WithString("source",
+			mcp.Description("Source path of the file or directory"),
+			mcp.Required(),
+		),
+		mcp.WithString("destination",
+			mcp.Description("Destination path, the full path of the copy"),
+			mcp.Required(),
+		),
+		mcp.WithString("on_conflict", conflictDesc, mcp.Enum(ConflictError, ConflictOverwrite, ConflictRename)),
+		mcp.WithBoolean("recursive",
+			mcp.Description("Copy directories recursively"),
+		),
+		mcp.WithBoolean("create_parents",
+			mcp.Description("Create the missing parent directories of the destination"),
+		),
+	), fs.handleFileCopy)
+
 	fs.AddTool(mcp.NewTool(
 		"search_files",
 		mcp.WithDescription("Recursively search for files and directories matching a pattern."),
@@ -172,6 +304,28 @@ func (fs *FilesystemServer) Init() error {
 		),
 	), fs.handleSearchFiles)
 
+	fs.AddTool(mcp.NewTool(
+		"search_content",
+		mcp.WithDescription("Search the content of the text files under a directory, or of one file, for lines matching a regular expression and return them as path:line: snippet. Binary files and files outside the allowed directories are skipped, and the search stops after max_results matching lines."),
+		mcp.WithString("path",
+			mcp.Description("Directory or file to search"),
+			mcp.Required(),
+		),
+		mcp.WithString("pattern",
+			mcp.Description("Regular expression (Go RE2 syntax) matched against every line"),
+			mcp.Required(),
+		),
+		mcp.WithString("glob",
+			mcp.Description("Only search files matching this glob, e.g. \"*.go\" or \"src/**/*.js\". A glob without / matches the file name in any directory, ** matches any number of directories"),
+		),
+		mcp.WithNumber("max_results",
+			mcp.Description(fmt.Sprintf("Maximum number of returned lines (default: %d, max: %d)", searchDefaultMaxResults, searchMaxResults)),
+		),
+		mcp.WithBoolean("case_insensitive",
+			mcp.Description("Match the pattern case-insensitively"),
+		),
+	), fs.handleSearchContent)
+
 	fs.AddTool(mcp.NewTool(
 		"get_file_info",
 		mcp.WithDescription("Retrieve detailed metadata about a file or directory."),
@@ -181,6 +335,140 @@ func (fs *FilesystemServer) Init() error {
 		),
 	), fs.handleGetFileInfo)
 
+	fs.AddTool(mcp.NewTool(
+		"file_info",
+		mcp.WithDescription("Retrieve structured metadata about a file or directory as JSON: size, permissions, owner, timestamps, symlink target, sniffed MIME type, line count of small text files and entry count of directories."),
+		mcp.WithString("path",
+			mcp.Description("Relative Path to the file or directory"),
+			mcp.Required(),
+		),
+		mcp.WithBoolean("follow_symlink",
+			mcp.Description("Report the symlink target instead of the link itself (default: false)"),
+		),
+	), fs.handleFileInfo)
+
+	fs.AddTool(mcp.NewTool(
+		"file_stats",
+		mcp.WithDescription("Count the lines, words and characters of a text file without reading it into the conversation. Also returns the size, the longest line, the detected encoding (UTF-8, UTF-16LE/BE, ISO-8859-1 or binary, binary files are not counted), the delimiter and column count of CSV and TSV files, and optionally the most frequent words."),
+		mcp.WithString("path",
+			mcp.Description("Relative Path to the file"),
+			mcp.Required(),
+		),
+		mcp.WithNumber("top_words",
+			mcp.Description(fmt.Sprintf("Return the N most frequent words, lowercased, at most %d (default: 0)", maxTopWords)),
+		),
+		mcp.WithBoolean("exclude_stopwords",
+			mcp.Description("Leave common English words such as the, and, of out of top_words (default: true)"),
+		),
+	), fs.handleFileStats)
+
+	fs.AddTool(mcp.NewTool(
+		"file_ocr",
+		mcp.WithDescription("Recognize the text in an image file (PNG, JPEG, ...) with the configured OCR backend."),
+		mcp.WithString("path",
+			mcp.Description("Relative Path to the image file"),
+			mcp.Required(),
+		),
+		mcp.WithString("languages",
+			mcp.Description("Language hints separated by comma, e.g. eng,chi_sim (default: the configured languages)"),
+		),
+		mcp.WithBoolean("with_boxes",
+			mcp.Description("Return the bounding box and confidence of every recognized word as JSON (default: false)"),
+		),
+	), fs.handleFileOCR)
+
+	fs.AddTool(mcp.NewTool(
+		"file_extract_text",
+		mcp.WithDescription("Extract the plain text of a document: PDF, DOCX, XLSX, PPTX or plain text files. The format is detected from the content. PDF pages, XLSX sheets and PPTX slides are returned as separate sections."),
+		mcp.WithString("path",
+			mcp.Description("Relative Path to the document"),
+			mcp.Required(),
+		),
+		mcp.WithString("pages",
+			mcp.Description("PDF pages to extract, e.g. 1-3,5 (default: all)"),
+		),
+		mcp.WithString("sheet",
+			mcp.Description("XLSX sheets to extract, by name (e.g. Summary) or index range (e.g. 1-2) (default: all)"),
+		),
+		mcp.WithString("slide",
+			mcp.Description("PPTX slides to extract, e.g. 2-4 (default: all)"),
+		),
+		mcp.WithNumber("max_bytes",
+			mcp.Description("Maximum size of the returned text in bytes, longer text is truncated (default: 262144)"),
+		),
+	), fs.handleExtractText)
+
+	fs.AddTool(mcp.NewTool(
+		"dir_compare",
+		mcp.WithDescription("Compare two directories, e.g. before and after a build or a sync, and return the added, removed and modified paths relative to the roots as JSON, with the old and new size and modification time of each entry and summary counts including the total byte delta. Symlinks are compared by their target and never followed."),
+		mcp.WithString("old_path",
+			mcp.Description("Path of the directory before the change"),
+			mcp.Required(),
+		),
+		mcp.WithString("new_path",
+			mcp.Description("Path of the directory after the change"),
+			mcp.Required(),
+		),
+		mcp.WithArray("include",
+			mcp.Description("Only compare files matching one of these globs, e.g. [\"*.go\", \"src/**/*.js\"]. A glob without / matches the file name in any directory, ** matches any number of directories"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithArray("exclude",
+			mcp.Description("Skip files and directories matching one of these globs, e.g. [\"node_modules\", \"*.tmp\"]"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithString("compare_content",
+			mcp.Description(fmt.Sprintf("%s compares size and modification time only, %s compares the SHA-256 of files with the same size, which catches same-size edits and ignores touched files (default: %s)", CompareContentOff, CompareContentHash, CompareContentOff)),
+			mcp.Enum(CompareContentOff, CompareContentHash),
+		),
+		mcp.WithNumber("max_entries",
+			mcp.Description(fmt.Sprintf("Maximum number of returned entries per change class, the summary counts all of them (default: %d, max: %d)", compareDefaultMaxEntries, compareMaxEntries)),
+		),
+		mcp.WithString("output_file",
+			mcp.Description("File name in the data directory to write the full report to. When the report is too large to return, only the summary is returned"),
+		),
+	), fs.handleDirCompare)
+
+	fs.AddTool(mcp.NewTool(
+		"file_checksum",
+		mcp.WithDescription("Compute the checksum of a file, or of the files in a directory, and return them as JSON. With find_duplicates the files of the directory that have the same content are grouped instead, e.g. to find duplicate downloads; only files sharing their size with another file are hashed. Symlinks are not followed."),
+		mcp.WithString("path",
+			mcp.Description("Path of the file or directory"),
+			mcp.Required(),
+		),
+		mcp.WithString("algorithm",
+			mcp.Description(fmt.Sprintf("Digest algorithm (default: %s)", ChecksumSHA256)),
+			mcp.Enum(ChecksumMD5, ChecksumSHA1, ChecksumSHA256),
+		),
+		mcp.WithBoolean("recursive",
+			mcp.Description("Include the files of subdirectories, otherwise only the direct files of the directory are hashed (default: false)"),
+		),
+		mcp.WithBoolean("find_duplicates",
+			mcp.Description("Return the groups of files with identical content, largest waste first, instead of one digest per file (default: false)"),
+		),
+	), fs.handleFileChecksum)
+
+	fs.AddTool(mcp.NewTool(
+		"watch_path",
+		mcp.WithDescription(fmt.Sprintf("Watch a file, or a directory and its direct entries, for changes, e.g. the artifacts of a build. Every change is sent to the client as a notifications/message with logger watch_path and the watch ID, the path and the event types (%s, %s, %s, %s, %s); the events of one path are merged into at most one notification every %s. Returns the watch ID for unwatch_path.",
+			WatchEventCreate, WatchEventWrite, WatchEventRemove, WatchEventRename, WatchEventChmod, watchDebounce)),
+		mcp.WithString("path",
+			mcp.Description("Path of the file or directory to watch"),
+			mcp.Required(),
+		),
+	), fs.handleWatchPath)
+
+	fs.AddTool(mcp.NewTool(
+		"unwatch_path",
+		mcp.WithDescription("Stop a watch started by watch_path, by its ID or by the watched path."),
+		mcp.WithString("watch_id",
+			mcp.Description("ID returned by watch_path"),
+		),
+		mcp.WithString("path",
+			mcp.Description("Watched path, all of its watches are stopped"),
+		),
+	), fs.handleUnwatchPath)
+
 	fs.AddTool(mcp.NewTool(
 		"list_allowed_directories",
 		mcp.WithDescription("Returns the list of directories that this server is allowed to access."),
@@ -197,15 +485,15 @@ func (fs *FilesystemServer) handlePrompt(ctx context.Context, request mcp.GetPro
 				Role: mcp.RoleUser,
 				Content: mcp.TextContent{
 					Type: "text",
-					Text: fs.config.prompt,
+					Text: fs.cfg().prompt,
 				},
 			},
 		},
 	}, nil
 }
 
-// isPathInAllowedDirs checks if a path is within any of the allowed directories
-func (fs *FilesystemServer) isPathInAllowedDirs(path string) bool {
+// isPathInAllowedDirs checks if a path is within any of the directories allowed for the access
+func (fs *FilesystemServer) isPathInAllowedDirs(path string, a access) bool {
 	// Ensure path is absolute and clean
 	absPath, err := filepath.Abs(path)
 	if err != nil {
@@ -224,38 +512,52 @@ func (fs *FilesystemServer) isPathInAllowedDirs(path string) bool {
 	}
 
 	// Check if the path is within any of the allowed directories
-	for _, dir := range fs.config.allowedDirs {
-		if strings.HasPrefix(absPath, dir) {
-			return true
-		}
-	}
-	return false
+	return inDirs(absPath, fs.cfg().dirs(a))
 }
 
-func (fs *FilesystemServer) validatePath(requestedPath string) (string, error) {
-	// Always convert to absolute path first
-	var hasPrefix bool
+// absPath converts a requested path into an absolute path. Paths that are not within a read or
+// write directory are resolved against the first directory allowed for the access, so that the
+// permission check reports the access that is missing. Symlinks are not resolved.
+func (fs *FilesystemServer) absPath(requestedPath string, a access) (string, error) {
+	cfg := fs.cfg()
 	var firstDir string
-	for _, dir := range fs.config.allowedDirs {
-		if firstDir == "" {
-			firstDir = dir
-		}
-		if strings.HasPrefix(requestedPath, dir) {
-			hasPrefix = true
-			break
-		}
+	if dirs := cfg.dirs(a); len(dirs) > 0 {
+		firstDir = dirs[0]
 	}
-	if !hasPrefix {
+	withSep := strings.TrimSuffix(requestedPath, string(filepath.Separator)) + string(filepath.Separator)
+	if !inDirs(withSep, cfg.readDirs) && !inDirs(withSep, cfg.writeDirs) {
 		requestedPath = filepath.Join(firstDir, requestedPath)
 	}
 	abs, err := filepath.Abs(requestedPath)
 	if err != nil {
-		return "", fmt.Errorf("invalid path: %w", err)
+		return "", comm.NewCodedError(comm.ErrCodeInvalidArgument, fmt.Errorf("invalid path: %w", err))
+	}
+	return abs, nil
+}
+
+// ErrAccessDenied is returned when a path is outside the directories allowed for the access.
+var ErrAccessDenied = comm.NewCodedError(comm.ErrCodePermissionDenied, errors.New("access denied"))
+
+// validatePath checks that the path, after resolving symlinks, is within the read directories.
+func (fs *FilesystemServer) validatePath(requestedPath string) (string, error) {
+	return fs.validatePathFor(requestedPath, accessRead)
+}
+
+// validateWritePath checks that the path, after resolving symlinks, is within the write directories.
+func (fs *FilesystemServer) validateWritePath(requestedPath string) (string, error) {
+	return fs.validatePathFor(requestedPath, accessWrite)
+}
+
+func (fs *FilesystemServer) validatePathFor(requestedPath string, a access) (string, error) {
+	// Always convert to absolute path first
+	abs, err := fs.absPath(requestedPath, a)
+	if err != nil {
+		return "", err
 	}
 
 	// Check if path is within allowed directories
-	if !fs.isPathInAllowedDirs(abs) {
-		return "", fmt.Errorf("access denied - path outside allowed directories: %s", abs)
+	if !fs.isPathInAllowedDirs(abs, a) {
+		return "", fmt.Errorf("%w - %s permission missing, path outside allowed %s directories: %s", ErrAccessDenied, a, a, abs)
 	}
 
 	// Handle symlinks
@@ -268,21 +570,21 @@ func (fs *FilesystemServer) validatePath(requestedPath string) (string, error) {
 		parent := filepath.Dir(abs)
 		realParent, err := filepath.EvalSymlinks(parent)
 		if err != nil {
-			return "", fmt.Errorf("parent directory does not exist: %s", parent)
+			return "", comm.NewCodedError(comm.ErrCodeNotFound, fmt.Errorf("parent directory does not exist: %s", parent))
 		}
 
-		if !fs.isPathInAllowedDirs(realParent) {
+		if !fs.isPathInAllowedDirs(realParent, a) {
 			return "", fmt.Errorf(
-				"access denied - parent directory outside allowed directories",
+				"%w - %s permission missing, parent directory outside allowed %s directories", ErrAccessDenied, a, a,
 			)
 		}
 		return abs, nil
 	}
 
 	// Check if the real path (after resolving symlinks) is still within allowed directories
-	if !fs.isPathInAllowedDirs(realPath) {
+	if !fs.isPathInAllowedDirs(realPath, a) {
 		return "", fmt.Errorf(
-			"access denied - symlink target outside allowed directories",
+			"%w - %s permission missing, symlink target outside allowed %s directories", ErrAccessDenied, a, a,
 		)
 	}
 
@@ -456,20 +758,20 @@ func (fs *FilesystemServer) handleReadFile(ctx context.Context, request mcp.Call
 	args := request.GetArguments()
 	path, ok := args["path"].(string)
 	if !ok {
-		return mcp.NewToolResultError("Path must be a string"), nil
+		return comm.ToolError(comm.ErrCodeInvalidArgument, "path must be a string", ""), nil
 	}
 
 	// 判断 前缀是不是已经包含了
 	//path = filepath.Join(fss.config.CachePath, path)
 	validPath, err := fs.validatePath(path)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("validate Path Error: %v", err)), nil
+		return comm.ArgumentError(err), nil
 	}
 
 	// Check if it'fss a directory
 	info, err := os.Stat(validPath)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("check directory error: %v", err)), nil
+		return comm.ToolErrorFromErr("failed to get file info", err), nil
 	}
 
 	if info.IsDir() {
@@ -493,6 +795,12 @@ func (fs *FilesystemServer) handleReadFile(ctx context.Context, request mcp.Call
 		}, nil
 	}
 
+	if opts, ok, err := parseRangeOptions(args); err != nil {
+		return comm.ArgumentError(err), nil
+	} else if ok {
+		return fs.handleReadRange(validPath, opts)
+	}
+
 	// Determine MIME type
 	mimeType := utils.DetectMimeType(validPath)
 
@@ -504,7 +812,7 @@ func (fs *FilesystemServer) handleReadFile(ctx context.Context, request mcp.Call
 			Content: []mcp.Content{
 				mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("File is too large to display inline (%d bytes). Access it via resource URI: %s", info.Size(), resourceURI),
+					Text: fmt.Sprintf("File is too large to display inline (%d bytes). Read parts of it with offset and length or tail_lines, or access it via resource URI: %s", info.Size(), resourceURI),
 				},
 				mcp.EmbeddedResource{
 					Type: "resource",
@@ -521,7 +829,7 @@ func (fs *FilesystemServer) handleReadFile(ctx context.Context, request mcp.Call
 	// Read file content
 	content, err := os.ReadFile(validPath)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error reading file: %v", err)), nil
+		return comm.ToolErrorFromErr("failed to read file", err), nil
 	}
 
 	// Handle based on content type
@@ -612,16 +920,20 @@ func (fs *FilesystemServer) handleWriteFile(ctx context.Context, request mcp.Cal
 	args := request.GetArguments()
 	path, ok := args["path"].(string)
 	if !ok {
-		return mcp.NewToolResultError("Path must be a string"), nil
+		return comm.ToolError(comm.ErrCodeInvalidArgument, "path must be a string", ""), nil
 	}
 	content, ok := args["content"].(string)
 	if !ok {
-		return mcp.NewToolResultError("Content must be a string"), nil
+		return comm.ToolError(comm.ErrCodeInvalidArgument, "content must be a string", ""), nil
+	}
+	opts, err := parseWriteOptions(args)
+	if err != nil {
+		return comm.ArgumentError(err), nil
 	}
 
 	//path = filepath.Join(fss.config.CachePath, path)
 
-	validPath, err := fs.validatePath(path)
+	validPath, err := fs.validateWritePath(path)
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
@@ -636,24 +948,29 @@ func (fs *FilesystemServer) handleWriteFile(ctx context.Context, request mcp.Cal
 
 	// Check if it'fss a directory
 	if info, err := os.Stat(validPath); err == nil && info.IsDir() {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: Cannot write to a directory:%s", validPath)), nil
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("cannot write to a directory: %s", validPath), ""), nil
 	}
 
 	// Create parent directories if they don't exist
 	parentDir := filepath.Dir(validPath)
 	if err := os.MkdirAll(parentDir, 0755); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error creating parent directories: %v", err)), nil
+		return comm.ToolErrorFromErr("failed to create parent directories", err), nil
 	}
 
-	if err := os.WriteFile(validPath, []byte(content), 0644); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error writing file: %v", err)), nil
+	// 与按行编辑共用文件锁，避免同时写入
+	unlock := fs.editLocks.lock(validPath)
+	res, err := writeContent(validPath, content, opts)
+	unlock()
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to write file", err), nil
 	}
 
-	// Get file info for the response
-	info, err := os.Stat(validPath)
-	if err != nil {
-		// File was written but we couldn't get info
-		return mcp.NewToolResultText(fmt.Sprintf("Successfully wrote to %s", path)), nil
+	text := fmt.Sprintf("Successfully wrote %d bytes to %s", res.Written, path)
+	if opts.Mode == WriteModeAppend && !res.Created {
+		text = fmt.Sprintf("Successfully appended %d bytes to %s", res.Written, path)
+	}
+	if res.Backup != "" {
+		text += fmt.Sprintf(", the previous content was saved to %s", res.Backup)
 	}
 
 	resourceURI := utils.PathToResourceURI(validPath)
@@ -661,14 +978,14 @@ func (fs *FilesystemServer) handleWriteFile(ctx context.Context, request mcp.Cal
 		Content: []mcp.Content{
 			mcp.TextContent{
 				Type: "text",
-				Text: fmt.Sprintf("Successfully wrote %d bytes to %s", info.Size(), path),
+				Text: text,
 			},
 			mcp.EmbeddedResource{
 				Type: "resource",
 				Resource: mcp.TextResourceContents{
 					URI:      resourceURI,
 					MIMEType: "text/plain",
-					Text:     fmt.Sprintf("File: %s (%d bytes)", validPath, info.Size()),
+					Text:     fmt.Sprintf("File: %s (%d bytes)", validPath, res.Size),
 				},
 			},
 		},
@@ -679,27 +996,27 @@ func (fs *FilesystemServer) handleListDirectory(ctx context.Context, request mcp
 	args := request.GetArguments()
 	path, ok := args["path"].(string)
 	if !ok {
-		return mcp.NewToolResultError("Path must be a string"), nil
+		return comm.ToolError(comm.ErrCodeInvalidArgument, "path must be a string", ""), nil
 	}
 
 	validPath, err := fs.validatePath(path)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("validate path error: %v, path:%s", err, validPath)), nil
+		return comm.ArgumentError(err), nil
 	}
 
 	// Check if it'fss a directory
 	info, err := os.Stat(validPath)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Check directory %s Error: %v", validPath, err)), nil
+		return comm.ToolErrorFromErr(fmt.Sprintf("failed to get info of %s", validPath), err), nil
 	}
 
 	if !info.IsDir() {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: Path is not a directory:%s", validPath)), nil
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("%s is not a directory", validPath), ""), nil
 	}
 
 	entries, err := os.ReadDir(validPath)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error reading directory: %v", err)), nil
+		return comm.ToolErrorFromErr("failed to read directory", err), nil
 	}
 
 	var result strings.Builder
@@ -746,12 +1063,12 @@ func (fs *FilesystemServer) handleCreateDirectory(ctx context.Context, request m
 	args := request.GetArguments()
 	path, ok := args["path"].(string)
 	if !ok {
-		return mcp.NewToolResultError("path must be a string"), nil
+		return comm.ToolError(comm.ErrCodeInvalidArgument, "path must be a string", ""), nil
 	}
 
-	validPath, err := fs.validatePath(path)
+	validPath, err := fs.validateWritePath(path)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		return comm.ArgumentError(err), nil
 	}
 
 	// Check if path already exists
@@ -775,11 +1092,11 @@ func (fs *FilesystemServer) handleCreateDirectory(ctx context.Context, request m
 				},
 			}, nil
 		}
-		return mcp.NewToolResultError(fmt.Sprintf("Error: Path exists but is not a directory: %s", path)), nil
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("%s exists but is not a directory", path), ""), nil
 	}
 
 	if err := os.MkdirAll(validPath, 0755); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error creating directory: %v", err)), nil
+		return comm.ToolErrorFromErr("failed to create directory", err), nil
 	}
 
 	resourceURI := utils.PathToResourceURI(validPath)
@@ -805,36 +1122,36 @@ func (fs *FilesystemServer) handleMoveFile(ctx context.Context, request mcp.Call
 	args := request.GetArguments()
 	source, ok := args["source"].(string)
 	if !ok {
-		return mcp.NewToolResultError("source must be a string"), nil
+		return comm.ToolError(comm.ErrCodeInvalidArgument, "source must be a string", ""), nil
 	}
 	destination, ok := args["destination"].(string)
 	if !ok {
-		return mcp.NewToolResultError("destination must be a string"), nil
+		return comm.ToolError(comm.ErrCodeInvalidArgument, "destination must be a string", ""), nil
 	}
 
-	validSource, err := fs.validatePath(source)
+	validSource, err := fs.validateWritePath(source)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error with source path: %v", err)), nil
+		return comm.ToolErrorFromErr("invalid source path", err), nil
 	}
 
 	// Check if source exists
 	if _, err := os.Stat(validSource); os.IsNotExist(err) {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: Source does not exist: %s", source)), nil
+		return comm.ToolError(comm.ErrCodeNotFound, fmt.Sprintf("source does not exist: %s", source), ""), nil
 	}
 
-	validDest, err := fs.validatePath(destination)
+	validDest, err := fs.validateWritePath(destination)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error with destination path: %v", err)), nil
+		return comm.ToolErrorFromErr("invalid destination path", err), nil
 	}
 
 	// Create parent directory for destination if it doesn't exist
 	destDir := filepath.Dir(validDest)
 	if err := os.MkdirAll(destDir, 0755); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error creating destination directory: %v", err)), nil
+		return comm.ToolErrorFromErr("failed to create destination directory", err), nil
 	}
 
 	if err := os.Rename(validSource, validDest); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error moving file: %v", err)), nil
+		return comm.ToolErrorFromErr("failed to move file", err), nil
 	}
 
 	resourceURI := utils.PathToResourceURI(validDest)
@@ -864,31 +1181,31 @@ func (fs *FilesystemServer) handleSearchFiles(ctx context.Context, request mcp.C
 	args := request.GetArguments()
 	path, ok := args["path"].(string)
 	if !ok {
-		return mcp.NewToolResultError("path must be a string"), nil
+		return comm.ToolError(comm.ErrCodeInvalidArgument, "path must be a string", ""), nil
 	}
 	pattern, ok := args["pattern"].(string)
 	if !ok {
-		return mcp.NewToolResultError("pattern must be a string"), nil
+		return comm.ToolError(comm.ErrCodeInvalidArgument, "pattern must be a string", ""), nil
 	}
 
 	validPath, err := fs.validatePath(path)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		return comm.ArgumentError(err), nil
 	}
 
 	// Check if it'fss a directory
 	info, err := os.Stat(validPath)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error: %v", err)), nil
+		return comm.ArgumentError(err), nil
 	}
 
 	if !info.IsDir() {
-		return mcp.NewToolResultError("Error: Search path must be a directory"), nil
+		return comm.ToolError(comm.ErrCodeInvalidArgument, "search path must be a directory", ""), nil
 	}
 
 	results, err := fs.searchFiles(validPath, pattern)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error searching files: %v", err)), nil
+		return comm.ToolErrorFromErr("failed to search files", err), nil
 	}
 
 	if len(results) == 0 {
@@ -921,7 +1238,7 @@ func (fs *FilesystemServer) handleGetFileInfo(ctx context.Context, request mcp.C
 	args := request.GetArguments()
 	path, ok := args["path"].(string)
 	if !ok {
-		return mcp.NewToolResultError(fmt.Errorf("path %v must be a string", args["path"]).Error()), nil
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("path %v must be a string", args["path"]), ""), nil
 	}
 
 	validPath, err := fs.validatePath(path)
@@ -939,7 +1256,7 @@ func (fs *FilesystemServer) handleGetFileInfo(ctx context.Context, request mcp.C
 
 	info, err := fs.getFileStats(validPath)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error getting file info: %v", err)), nil
+		return comm.ToolErrorFromErr("failed to get file info", err), nil
 	}
 
 	// Get MIME type for files
@@ -993,27 +1310,37 @@ func (fs *FilesystemServer) handleGetFileInfo(ctx context.Context, request mcp.C
 }
 
 func (fs *FilesystemServer) handleListAllowedDirectories(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	// Remove the trailing separator for display purposes
-	displayDirs := make([]string, len(fs.config.allowedDirs))
-	for i, dir := range fs.config.allowedDirs {
-		displayDirs[i] = strings.TrimSuffix(dir, string(filepath.Separator))
-	}
-
 	var result strings.Builder
-	result.WriteString("Allowed directories:")
-
-	for _, dir := range displayDirs {
-		resourceURI := utils.PathToResourceURI(dir)
-		result.WriteString(fmt.Sprintf("%s (%s)\n", dir, resourceURI))
+	for _, a := range []access{accessRead, accessWrite} {
+		result.WriteString(fmt.Sprintf("Allowed %s directories:\n", a))
+		// Remove the trailing separator for display purposes
+		for _, dir := range displayDirs(fs.cfg().dirs(a)) {
+			resourceURI := utils.PathToResourceURI(dir)
+			result.WriteString(fmt.Sprintf("%s (%s)\n", dir, resourceURI))
+		}
 	}
 
 	return mcp.NewToolResultText(result.String()), nil
 }
 
+// ConfigSchema returns the JSON Schema of the FileSystem configuration with its default values.
+func (fs *FilesystemServer) ConfigSchema() string {
+	schema, err := config.GenerateSchema("FileSystem", NewFileSystemConfig(""))
+	if err != nil {
+		fs.Logger.Err(err).Msg("failed to generate config schema")
+		return ""
+	}
+	return schema
+}
+
 // Config returns the configuration of the service as a string.
 func (fs *FilesystemServer) Config() string {
-	fs.config.AllowedDir = strings.Join(fs.config.allowedDirs, ",")
-	cfg, err := json.Marshal(fs.config)
+	// 输出合并后的读写目录，旧的 allowed_dir 已合并到两者中
+	config := *fs.cfg()
+	config.AllowedDir = ""
+	config.AllowedReadDirs = displayDirs(config.readDirs)
+	config.AllowedWriteDirs = displayDirs(config.writeDirs)
+	cfg, err := json.Marshal(&config)
 	if err != nil {
 		fs.Logger.Err(err).Msg("failed to marshal config")
 		return "{}"
@@ -1028,15 +1355,41 @@ func (fs *FilesystemServer) Name() comm.MoLingServerType {
 func (fs *FilesystemServer) Close() error {
 	// Cancel the context to stop the browser
 	fs.Logger.Debug().Msg("closing FilesystemServer")
+	fs.watches.closeAll()
 	return nil
 }
 
 // LoadConfig loads the configuration from a JSON object.
 func (fs *FilesystemServer) LoadConfig(jsonData map[string]interface{}) error {
-	err := utils.MergeJSONToStruct(fs.config, jsonData)
+	// 在副本上合并和校验，校验通过后才替换正在使用的配置
+	next := *fs.cfg()
+	err := utils.MergeJSONToStruct(&next, jsonData)
 	if err != nil {
 		return err
 	}
-	fs.config.allowedDirs = strings.Split(fs.config.AllowedDir, ",")
-	return fs.config.Check()
+	// 只配置了读写目录时不再使用默认的 allowed_dir
+	_, hasLegacy := jsonData["allowed_dir"]
+	_, hasRead := jsonData["allowed_read_dirs"]
+	_, hasWrite := jsonData["allowed_write_dirs"]
+	if !hasLegacy && (hasRead || hasWrite) {
+		next.AllowedDir = ""
+	}
+	if err := next.Check(); err != nil {
+		return err
+	}
+	for _, warning := range next.Warnings() {
+		fs.Logger.Warn().Msg(warning)
+	}
+	fs.configLock.Lock()
+	fs.config = &next
+	fs.configLock.Unlock()
+	return nil
+}
+
+// cfg returns the running config. LoadConfig replaces it as a whole instead of changing it, a
+// handler reading it sees either the old or the new config.
+func (fs *FilesystemServer) cfg() *FileSystemConfig {
+	fs.configLock.RLock()
+	defer fs.configLock.RUnlock()
+	return fs.config
 }
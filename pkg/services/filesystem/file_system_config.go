@@ -20,7 +20,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+
+	"github.com/gojue/moling/pkg/ocr"
 )
 
 const (
@@ -28,7 +31,7 @@ const (
 	FileSystemPromptDefault = `
 You are a powerful local filesystem management assistant capable of performing various file operations and management tasks. Your capabilities include:
 
-1. **File Browsing**: Navigate to specified directories to load lists of files and folders.
+1. **File Browsing**: Navigate to specified directories to load lists of files and folders. For large directories, list them sorted by name, size, modification time or extension, one page at a time, filtered by a glob or entry type. Get the tree of a nested project in one call, with the size of every file and the total size of every directory.
 
 2. **File Operations**:
    - Create new files or folders
@@ -38,15 +41,24 @@ You are a powerful local filesystem management assistant capable of performing v
 
 3. **File Content Operations**:
    - Read the contents of text files and return them
+   - Read a byte range or the last lines of large files such as logs
+   - Extract the text of documents such as PDF, DOCX, XLSX and PPTX, optionally limited to pages, sheets or slides
    - Write text to specified files
    - Append content to existing files
 
 4. **File Information Retrieval**:
    - Retrieve properties of files or folders (e.g., size, creation date, modification date)
+   - Retrieve structured metadata such as permissions, owner, symlink target, MIME type and line count
+   - Count the lines, words and characters of a file, detect its encoding and list its most frequent words without reading the whole content
    - Check if files or folders exist
+   - Recognize text in image files (OCR), optionally with word bounding boxes
+   - Compare two directories to see exactly which files were added, removed or modified, e.g. before and after a build or a sync
+   - Compute the MD5, SHA-1 or SHA-256 checksum of files and find duplicate files in a directory
+   - Watch a file or directory and get notified when it is created, written, removed or renamed, e.g. while a build writes its artifacts
 
 5. **Search Functionality**:
    - Search for files in specified directories, supporting wildcard matching
+   - Search the content of text files for lines matching a regular expression, optionally limited to files matching a glob
    - Filter search results by file type or modification date
 
 For all actions, please provide clear instructions, including:
@@ -63,13 +75,35 @@ var (
 	allowedDirsDefault = os.TempDir()
 )
 
+// access is the permission a tool needs on a path.
+type access int
+
+const (
+	accessRead  access = iota // read, list, search and inspect
+	accessWrite               // create, write, move and the destination of a copy
+)
+
+func (a access) String() string {
+	if a == accessWrite {
+		return "write"
+	}
+	return "read"
+}
+
 // FileSystemConfig represents the configuration for the file system.
 type FileSystemConfig struct {
-	PromptFile  string `json:"prompt_file"` // PromptFile is the prompt file for the file system.
-	prompt      string
-	AllowedDir  string `json:"allowed_dir"` // AllowedDirs is a list of allowed directories. split by comma. e.g. /tmp,/var/tmp
-	allowedDirs []string
-	CachePath   string `json:"cache_path"` // CachePath is the root path for the file system.
+	PromptFile       string `json:"prompt_file" desc:"File whose content replaces the default prompt of the file system service"` // PromptFile is the prompt file for the file system.
+	prompt           string
+	AllowedDir       string   `json:"allowed_dir,omitempty" desc:"Legacy comma separated directories allowed for reading and writing"` // AllowedDir is the legacy list of directories allowed for reading and writing. split by comma. e.g. /tmp,/var/tmp
+	AllowedReadDirs  []string `json:"allowed_read_dirs" desc:"Directories the tools may read, list and search"`                        // AllowedReadDirs are the directories the tools may read, list and search.
+	AllowedWriteDirs []string `json:"allowed_write_dirs" desc:"Directories the tools may create, write and move files in"`             // AllowedWriteDirs are the directories the tools may create, write and move files in.
+	readDirs         []string
+	writeDirs        []string
+	warnings         []string
+	CachePath        string     `json:"cache_path" desc:"Directory for cached files of the file system tools"`          // CachePath is the root path for the file system.
+	OCR              ocr.Config `json:"ocr" desc:"OCR backend used by file_ocr"`                                        // OCR configures the backend of the file_ocr tool.
+	MaxWatches       int        `json:"max_watches" desc:"Maximum number of concurrent watches of watch_path"`          // MaxWatches limits the watches started by watch_path.
+	MaxHashBytes     int64      `json:"max_hash_bytes" desc:"Maximum number of bytes file_checksum hashes in one call"` // MaxHashBytes caps the bytes hashed by one file_checksum call.
 }
 
 // NewFileSystemConfig creates a new FileSystemConfig with the given allowed directories.
@@ -82,32 +116,54 @@ func NewFileSystemConfig(path string) *FileSystemConfig {
 	}
 
 	return &FileSystemConfig{
-		AllowedDir:  path,
-		CachePath:   path,
-		allowedDirs: paths,
+		AllowedDir:   path,
+		CachePath:    path,
+		readDirs:     paths,
+		writeDirs:    paths,
+		OCR:          ocr.NewConfig(),
+		MaxWatches:   16,
+		MaxHashBytes: 4 * 1024 * 1024 * 1024,
 	}
 }
 
-// Check validates the allowed directories in the FileSystemConfig.
+// Check validates the allowed directories in the FileSystemConfig. The legacy allowed_dir is
+// added to both the read and the write directories.
 func (fc *FileSystemConfig) Check() error {
 	fc.prompt = FileSystemPromptDefault
-	normalized := make([]string, 0, len(fc.allowedDirs))
-	for _, dir := range fc.allowedDirs {
-		abs, err := filepath.Abs(strings.TrimSpace(dir))
-		if err != nil {
-			return fmt.Errorf("failed to resolve path %s: %w", dir, err)
-		}
-		info, err := os.Stat(abs)
-		if err != nil {
-			return fmt.Errorf("failed to access directory %s: %w", abs, err)
-		}
-		if !info.IsDir() {
-			return fmt.Errorf("path is not a directory: %s", abs)
+	var legacy []string
+	if strings.TrimSpace(fc.AllowedDir) != "" {
+		legacy = strings.Split(fc.AllowedDir, ",")
+	}
+	var err error
+	if fc.readDirs, err = normalizeDirs(append(append([]string{}, legacy...), fc.AllowedReadDirs...)); err != nil {
+		return err
+	}
+	if fc.writeDirs, err = normalizeDirs(append(append([]string{}, legacy...), fc.AllowedWriteDirs...)); err != nil {
+		return err
+	}
+	if len(fc.readDirs) == 0 && len(fc.writeDirs) == 0 {
+		return fmt.Errorf("no allowed directories, set allowed_read_dirs or allowed_write_dirs")
+	}
+
+	// 可写但不可读的目录通常是配置错误：文件能写入却无法读取或列出
+	fc.warnings = nil
+	for _, dir := range fc.writeDirs {
+		if !inDirs(dir, fc.readDirs) {
+			fc.warnings = append(fc.warnings, fmt.Sprintf("write directory %s is not readable, add it to allowed_read_dirs to read or list the written files", dir))
 		}
+	}
+
+	if fc.MaxWatches <= 0 {
+		return fmt.Errorf("max_watches must be greater than 0")
+	}
+
+	if fc.MaxHashBytes <= 0 {
+		return fmt.Errorf("max_hash_bytes must be greater than 0")
+	}
 
-		normalized = append(normalized, filepath.Clean(abs)+string(filepath.Separator))
+	if err := fc.OCR.Check(); err != nil {
+		return err
 	}
-	fc.allowedDirs = normalized
 
 	if fc.PromptFile != "" {
 		read, err := os.ReadFile(fc.PromptFile)
@@ -119,3 +175,63 @@ func (fc *FileSystemConfig) Check() error {
 
 	return nil
 }
+
+// Warnings returns the problems found by Check that do not make the config invalid.
+func (fc *FileSystemConfig) Warnings() []string {
+	return fc.warnings
+}
+
+// dirs returns the normalized directories allowed for the access.
+func (fc *FileSystemConfig) dirs(a access) []string {
+	if a == accessWrite {
+		return fc.writeDirs
+	}
+	return fc.readDirs
+}
+
+// normalizeDirs resolves the directories to absolute paths with a trailing separator, checks that
+// they exist and removes duplicates.
+func normalizeDirs(dirs []string) ([]string, error) {
+	normalized := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		dir = strings.TrimSpace(dir)
+		if dir == "" {
+			continue
+		}
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve path %s: %w", dir, err)
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to access directory %s: %w", abs, err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("path is not a directory: %s", abs)
+		}
+		abs = filepath.Clean(abs) + string(filepath.Separator)
+		if !slices.Contains(normalized, abs) {
+			normalized = append(normalized, abs)
+		}
+	}
+	return normalized, nil
+}
+
+// inDirs reports whether the directory path, with a trailing separator, is within one of dirs.
+func inDirs(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// displayDirs returns the directories without the trailing separator.
+func displayDirs(dirs []string) []string {
+	display := make([]string, len(dirs))
+	for i, dir := range dirs {
+		display[i] = strings.TrimSuffix(dir, string(filepath.Separator))
+	}
+	return display
+}
@@ -0,0 +1,226 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// newAccessTestServer creates a FilesystemServer with the given config and returns the resolved
+// temp dirs "read" and "write" referenced by the config as {read} and {write}.
+func newAccessTestServer(t *testing.T, cfg map[string]interface{}) (*FilesystemServer, string, string) {
+	t.Helper()
+	_, ctx, err := comm.InitTestEnv()
+	if err != nil {
+		t.Fatalf("Failed to initialize test environment: %v", err)
+	}
+	svc, err := NewFilesystemServer(ctx)
+	if err != nil {
+		t.Fatalf("Failed to create FilesystemServer: %v", err)
+	}
+	// macOS 的临时目录是软链接，先解析成真实路径
+	readDir, _ := filepath.EvalSymlinks(t.TempDir())
+	writeDir, _ := filepath.EvalSymlinks(t.TempDir())
+	replace := strings.NewReplacer("{read}", readDir, "{write}", writeDir)
+	data, _ := json.Marshal(cfg)
+	var resolved map[string]interface{}
+	if err := json.Unmarshal([]byte(replace.Replace(string(data))), &resolved); err != nil {
+		t.Fatal(err)
+	}
+	fs := svc.(*FilesystemServer)
+	if err := fs.LoadConfig(resolved); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	return fs, readDir, writeDir
+}
+
+func TestAllowedDirsConfig(t *testing.T) {
+	t.Run("LegacyKey", func(t *testing.T) {
+		fs, readDir, _ := newAccessTestServer(t, map[string]interface{}{"allowed_dir": "{read}"})
+		want := readDir + string(filepath.Separator)
+		if len(fs.config.readDirs) != 1 || fs.config.readDirs[0] != want || len(fs.config.writeDirs) != 1 || fs.config.writeDirs[0] != want {
+			t.Errorf("Expected allowed_dir to apply to reading and writing, got %v %v", fs.config.readDirs, fs.config.writeDirs)
+		}
+		if len(fs.config.Warnings()) != 0 {
+			t.Errorf("Unexpected warnings %v", fs.config.Warnings())
+		}
+
+		// 配置输出拆分为读写目录，重新加载后保持不变
+		var out map[string]interface{}
+		if err := json.Unmarshal([]byte(fs.Config()), &out); err != nil {
+			t.Fatalf("Invalid config output: %v", err)
+		}
+		if _, ok := out["allowed_dir"]; ok {
+			t.Errorf("Expected allowed_dir to be migrated, got %v", out)
+		}
+		if read := out["allowed_read_dirs"].([]interface{}); len(read) != 1 || read[0] != readDir {
+			t.Errorf("Unexpected allowed_read_dirs %v", out["allowed_read_dirs"])
+		}
+		if err := fs.LoadConfig(out); err != nil || fs.config.writeDirs[0] != want {
+			t.Errorf("Failed to reload the config output: %v %v", err, fs.config.writeDirs)
+		}
+	})
+
+	t.Run("LegacyKeyWithWriteDirs", func(t *testing.T) {
+		fs, readDir, writeDir := newAccessTestServer(t, map[string]interface{}{
+			"allowed_dir":        "{read}",
+			"allowed_write_dirs": []string{"{write}"},
+		})
+		if len(fs.config.readDirs) != 1 || len(fs.config.writeDirs) != 2 {
+			t.Errorf("Expected allowed_dir in both lists, got %v %v", fs.config.readDirs, fs.config.writeDirs)
+		}
+		if warnings := fs.config.Warnings(); len(warnings) != 1 || !strings.Contains(warnings[0], writeDir) || strings.Contains(warnings[0], readDir) {
+			t.Errorf("Expected a warning for the unreadable write dir, got %v", warnings)
+		}
+	})
+
+	t.Run("SplitKeysReplaceDefault", func(t *testing.T) {
+		fs, readDir, writeDir := newAccessTestServer(t, map[string]interface{}{
+			"allowed_read_dirs":  []string{"{read}", "{write}"},
+			"allowed_write_dirs": []string{"{write}"},
+		})
+		if strings.Join(displayDirs(fs.config.readDirs), ",") != readDir+","+writeDir || strings.Join(displayDirs(fs.config.writeDirs), ",") != writeDir {
+			t.Errorf("Expected only the configured dirs, got %v %v", fs.config.readDirs, fs.config.writeDirs)
+		}
+	})
+}
+
+func TestReloadConfig(t *testing.T) {
+	fs, readDir, writeDir := newAccessTestServer(t, map[string]interface{}{
+		"allowed_read_dirs":  []string{"{read}"},
+		"allowed_write_dirs": []string{"{write}"},
+	})
+	running := fs.config
+
+	// 校验失败时正在使用的配置不变，不会留下部分合并的目录
+	err := fs.LoadConfig(map[string]interface{}{
+		"allowed_read_dirs": []string{readDir, filepath.Join(readDir, "missing")},
+		"max_watches":       0,
+	})
+	if err == nil {
+		t.Fatal("Expected the invalid config to be rejected")
+	}
+	if fs.config != running || len(running.AllowedReadDirs) != 1 || running.MaxWatches != 16 {
+		t.Errorf("Expected the running config to be kept, got %+v", fs.config)
+	}
+
+	// 重新加载与处理函数并发执行
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			dirs := []string{readDir}
+			if i%2 == 0 {
+				dirs = append(dirs, writeDir)
+			}
+			if err := fs.LoadConfig(map[string]interface{}{"allowed_read_dirs": dirs}); err != nil {
+				t.Errorf("LoadConfig failed: %v", err)
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			if !fs.isPathInAllowedDirs(readDir, accessRead) {
+				t.Errorf("Expected %s to stay readable", readDir)
+			}
+			return
+		default:
+			fs.isPathInAllowedDirs(writeDir, accessRead)
+		}
+	}
+}
+
+func TestAllowedDirsEnforcement(t *testing.T) {
+	fs, readDir, writeDir := newAccessTestServer(t, map[string]interface{}{
+		"allowed_read_dirs":  []string{"{read}"},
+		"allowed_write_dirs": []string{"{write}"},
+	})
+	writeTestFile(t, filepath.Join(readDir, "r.txt"), "read")
+	writeTestFile(t, filepath.Join(writeDir, "w.txt"), "write")
+	r, w := func(name string) string { return filepath.Join(readDir, name) }, func(name string) string { return filepath.Join(writeDir, name) }
+
+	handlers := map[string]server.ToolHandlerFunc{
+		"read_file":         fs.handleReadFile,
+		"list_directory":    fs.handleListDirectory,
+		"search_files":      fs.handleSearchFiles,
+		"get_file_info":     fs.handleGetFileInfo,
+		"file_info":         fs.handleFileInfo,
+		"file_extract_text": fs.handleExtractText,
+		"write_file":        fs.handleWriteFile,
+		"create_directory":  fs.handleCreateDirectory,
+		"move_file":         fs.handleMoveFile,
+		"file_copy":         fs.handleFileCopy,
+		"file_move":         fs.handleFileMove,
+	}
+	for _, tc := range []struct {
+		tool    string
+		args    map[string]interface{}
+		missing string // 缺少的权限，为空表示允许
+	}{
+		{"read_file", map[string]interface{}{"path": r("r.txt")}, ""},
+		{"read_file", map[string]interface{}{"path": w("w.txt")}, "read"},
+		{"list_directory", map[string]interface{}{"path": readDir}, ""},
+		{"list_directory", map[string]interface{}{"path": writeDir}, "read"},
+		{"search_files", map[string]interface{}{"path": readDir, "pattern": "r"}, ""},
+		{"search_files", map[string]interface{}{"path": writeDir, "pattern": "w"}, "read"},
+		{"get_file_info", map[string]interface{}{"path": r("r.txt")}, ""},
+		{"get_file_info", map[string]interface{}{"path": w("w.txt")}, "read"},
+		{"file_info", map[string]interface{}{"path": r("r.txt")}, ""},
+		{"file_info", map[string]interface{}{"path": w("w.txt")}, "read"},
+		{"file_extract_text", map[string]interface{}{"path": r("r.txt")}, ""},
+		{"file_extract_text", map[string]interface{}{"path": w("w.txt")}, "read"},
+		{"write_file", map[string]interface{}{"path": w("new.txt"), "content": "x"}, ""},
+		{"write_file", map[string]interface{}{"path": r("new.txt"), "content": "x"}, "write"},
+		{"create_directory", map[string]interface{}{"path": w("dir")}, ""},
+		{"create_directory", map[string]interface{}{"path": r("dir")}, "write"},
+		{"file_copy", map[string]interface{}{"source": r("r.txt"), "destination": w("copy.txt")}, ""},
+		{"file_copy", map[string]interface{}{"source": r("r.txt"), "destination": r("copy.txt")}, "write"},
+		{"file_copy", map[string]interface{}{"source": w("w.txt"), "destination": w("copy2.txt")}, "read"},
+		{"file_move", map[string]interface{}{"source": r("r.txt"), "destination": w("moved.txt")}, "write"},
+		{"file_move", map[string]interface{}{"source": w("copy.txt"), "destination": r("moved.txt")}, "write"},
+		{"file_move", map[string]interface{}{"source": w("copy.txt"), "destination": w("moved.txt")}, ""},
+		{"move_file", map[string]interface{}{"source": r("r.txt"), "destination": w("moved2.txt")}, "write"},
+		{"move_file", map[string]interface{}{"source": w("moved.txt"), "destination": w("moved2.txt")}, ""},
+	} {
+		request := mcp.CallToolRequest{}
+		request.Params.Name = tc.tool
+		request.Params.Arguments = tc.args
+		result, err := handlers[tc.tool](context.Background(), request)
+		if err != nil {
+			t.Fatalf("%s %v: unexpected error %v", tc.tool, tc.args, err)
+		}
+		text := result.Content[0].(mcp.TextContent).Text
+		switch {
+		case tc.missing == "" && result.IsError:
+			t.Errorf("%s %v: expected success, got %s", tc.tool, tc.args, text)
+		case tc.missing != "" && (!result.IsError || !strings.Contains(text, tc.missing+" permission missing")):
+			t.Errorf("%s %v: expected missing %s permission, got %s", tc.tool, tc.args, tc.missing, text)
+		}
+	}
+}
@@ -0,0 +1,440 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// ConflictError fails when the destination exists.
+	ConflictError = "error"
+	// ConflictOverwrite replaces the destination.
+	ConflictOverwrite = "overwrite"
+	// ConflictRename appends a numeric suffix to the destination name.
+	ConflictRename = "rename"
+
+	// copyBufferSize is the buffer used to stream file contents.
+	copyBufferSize = 1024 * 1024
+	// maxRenameAttempts bounds the numeric suffixes tried by ConflictRename.
+	maxRenameAttempts = 10000
+)
+
+var (
+	// ErrDestinationExists is returned by ConflictError when the destination exists.
+	ErrDestinationExists = comm.NewCodedError(comm.ErrCodeInvalidArgument, errors.New("destination already exists"))
+	// ErrIsDirectory is returned when a directory is copied without recursive.
+	ErrIsDirectory = comm.NewCodedError(comm.ErrCodeInvalidArgument, errors.New("source is a directory, set recursive to copy it"))
+)
+
+// renameFile renames a file, tests replace it to simulate a cross-device move.
+var renameFile = os.Rename
+
+// transferResult is the outcome of a copy or a move.
+type transferResult struct {
+	Path    string // 最终的目标路径
+	Bytes   int64  // 复制的字节数，同一设备上的移动为 0
+	Files   int    // 复制的文件数
+	Renamed bool   // 因冲突而改名
+	Copied  bool   // 跨设备移动时通过复制后删除完成
+}
+
+// String formats the result for the tool output.
+func (tr transferResult) String(action, source string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s %s to %s\n", action, source, tr.Path))
+	sb.WriteString(fmt.Sprintf("Final path: %s\n", tr.Path))
+	sb.WriteString(fmt.Sprintf("Bytes copied: %d\n", tr.Bytes))
+	sb.WriteString(fmt.Sprintf("Files copied: %d\n", tr.Files))
+	if tr.Renamed {
+		sb.WriteString("The destination existed, the numeric suffix avoids the conflict\n")
+	}
+	if tr.Copied {
+		sb.WriteString("The destination is on another device, the source was copied and then deleted\n")
+	}
+	return sb.String()
+}
+
+// parseConflictPolicy validates on_conflict, the default is ConflictError.
+func parseConflictPolicy(args map[string]interface{}) (string, error) {
+	policy, _ := args["on_conflict"].(string)
+	switch policy {
+	case "":
+		return ConflictError, nil
+	case ConflictError, ConflictOverwrite, ConflictRename:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid on_conflict %q, use %s, %s or %s", policy, ConflictError, ConflictOverwrite, ConflictRename)
+	}
+}
+
+// uniqueName returns path with the first free numeric suffix, report.txt becomes report_1.txt.
+func uniqueName(path string) (string, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 1; i <= maxRenameAttempts; i++ {
+		candidate := fmt.Sprintf("%s_%d%s", base, i, ext)
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no free name for %s after %d attempts", path, maxRenameAttempts)
+}
+
+// validateDestination checks that the destination and its nearest existing ancestor are within
+// the write directories, and creates the missing parent directories if createParents is set.
+func (fs *FilesystemServer) validateDestination(destination string, createParents bool) (string, error) {
+	abs, err := fs.absPath(destination, accessWrite)
+	if err != nil {
+		return "", err
+	}
+	if !fs.isPathInAllowedDirs(abs, accessWrite) {
+		return "", fmt.Errorf("%w - write permission missing, path outside allowed write directories: %s", ErrAccessDenied, abs)
+	}
+	// 逐级向上找到已存在的祖先目录，解析软链接后仍需在允许的目录内
+	ancestor := filepath.Dir(abs)
+	for {
+		if _, err := os.Lstat(ancestor); err == nil {
+			break
+		}
+		parent := filepath.Dir(ancestor)
+		if parent == ancestor {
+			break
+		}
+		ancestor = parent
+	}
+	realAncestor, err := filepath.EvalSymlinks(ancestor)
+	if err != nil {
+		return "", err
+	}
+	if !fs.isPathInAllowedDirs(realAncestor, accessWrite) {
+		return "", fmt.Errorf("%w - write permission missing, parent directory outside allowed write directories", ErrAccessDenied)
+	}
+	if ancestor != filepath.Dir(abs) {
+		if !createParents {
+			return "", comm.NewCodedError(comm.ErrCodeNotFound, fmt.Errorf("parent directory does not exist: %s, set create_parents to create it", filepath.Dir(abs)))
+		}
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			return "", fmt.Errorf("failed to create parent directory: %w", err)
+		}
+	}
+	return fs.validateWritePath(abs)
+}
+
+// prepareDestination applies the conflict policy and returns the path to write to, whether it
+// was renamed and whether it replaces an existing destination. The destination is not touched,
+// an overwrite is staged next to it and swapped in by replaceDestination.
+func prepareDestination(source, dest, policy string) (string, bool, bool, error) {
+	if source == dest {
+		return "", false, false, fmt.Errorf("source and destination are the same: %s", dest)
+	}
+	if strings.HasPrefix(dest, source+string(filepath.Separator)) {
+		return "", false, false, fmt.Errorf("cannot copy or move %s into itself", source)
+	}
+	_, err := os.Lstat(dest)
+	if os.IsNotExist(err) {
+		return dest, false, false, nil
+	}
+	if err != nil {
+		return "", false, false, err
+	}
+	switch policy {
+	case ConflictOverwrite:
+		return dest, false, true, nil
+	case ConflictRename:
+		renamed, err := uniqueName(dest)
+		return renamed, true, false, err
+	default:
+		return "", false, false, fmt.Errorf("%w: %s", ErrDestinationExists, dest)
+	}
+}
+
+// replaceDestination swaps staged in place of dest. The old destination is moved into stage
+// first and restored if the swap fails, it is deleted with stage afterwards.
+func replaceDestination(staged, dest, stage string) error {
+	backup := filepath.Join(stage, "old")
+	if err := os.Rename(dest, backup); err != nil {
+		return fmt.Errorf("failed to replace the destination: %v", err)
+	}
+	if err := os.Rename(staged, dest); err != nil {
+		if rerr := os.Rename(backup, dest); rerr != nil {
+			return fmt.Errorf("failed to replace the destination: %v, the old destination is kept at %s", err, backup)
+		}
+		return fmt.Errorf("failed to replace the destination: %v", err)
+	}
+	return nil
+}
+
+// copyFile streams a regular file and keeps its permissions and modification time.
+func copyFile(source, dest string, info fs.FileInfo) (int64, error) {
+	in, err := os.Open(source)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.CopyBuffer(out, in, make([]byte, copyBufferSize))
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return n, err
+	}
+	return n, preserveAttrs(dest, info)
+}
+
+// preserveAttrs sets the permissions and the modification time of dest from info.
+func preserveAttrs(dest string, info fs.FileInfo) error {
+	if err := os.Chmod(dest, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(dest, info.ModTime(), info.ModTime())
+}
+
+// checkSymlink rejects a symlink of the source tree whose target is outside the read
+// directories. Relative targets that leave the tree point elsewhere once copied, so their
+// target at the destination is checked as well.
+func (fs *FilesystemServer) checkSymlink(source, path, target, link string) error {
+	resolved := link
+	if !filepath.IsAbs(link) {
+		resolved = filepath.Join(filepath.Dir(path), link)
+	}
+	targets := []string{resolved}
+	if rel, err := filepath.Rel(source, resolved); !filepath.IsAbs(link) && (err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))) {
+		targets = append(targets, filepath.Join(filepath.Dir(target), link))
+	}
+	for _, candidate := range targets {
+		// 目标不存在时按其父目录解析
+		real, err := filepath.EvalSymlinks(candidate)
+		if err != nil {
+			parent, perr := filepath.EvalSymlinks(filepath.Dir(candidate))
+			if perr != nil {
+				parent = filepath.Dir(candidate)
+			}
+			real = filepath.Join(parent, filepath.Base(candidate))
+		}
+		if !fs.isPathInAllowedDirs(real, accessRead) {
+			return fmt.Errorf("%w - symlink %s points outside allowed read directories: %s", ErrAccessDenied, path, link)
+		}
+	}
+	return nil
+}
+
+// copyTree copies source to dest. Directories are copied recursively, symlinks inside a
+// directory are recreated as symlinks rather than followed, as long as checkLink accepts them.
+func copyTree(source, dest string, recursive bool, checkLink func(path, target, link string) error) (int64, int, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !info.IsDir() {
+		n, err := copyFile(source, dest, info)
+		return n, 1, err
+	}
+	if !recursive {
+		return 0, 0, ErrIsDirectory
+	}
+
+	var total int64
+	var files int
+	type dirAttrs struct {
+		path string
+		info fs.FileInfo
+	}
+	var dirs []dirAttrs
+	err = filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			dirs = append(dirs, dirAttrs{target, info})
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := checkLink(path, target, link); err != nil {
+				return err
+			}
+			if err := os.Symlink(link, target); err != nil {
+				return err
+			}
+		case d.Type().IsRegular():
+			n, err := copyFile(path, target, info)
+			total += n
+			if err != nil {
+				return err
+			}
+			files++
+		}
+		return nil
+	})
+	if err != nil {
+		return total, files, err
+	}
+	// 目录的修改时间在写入子项后才能恢复，从最深的目录开始
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := preserveAttrs(dirs[i].path, dirs[i].info); err != nil {
+			return total, files, err
+		}
+	}
+	return total, files, nil
+}
+
+// transfer copies or moves source to destination with the conflict policy.
+func (fs *FilesystemServer) transfer(args map[string]interface{}, move bool) (string, *transferResult, error) {
+	source, ok := args["source"].(string)
+	if !ok {
+		return "", nil, comm.NewCodedError(comm.ErrCodeInvalidArgument, errors.New("source must be a string"))
+	}
+	destination, ok := args["destination"].(string)
+	if !ok {
+		return "", nil, comm.NewCodedError(comm.ErrCodeInvalidArgument, errors.New("destination must be a string"))
+	}
+	policy, err := parseConflictPolicy(args)
+	if err != nil {
+		return "", nil, comm.NewCodedError(comm.ErrCodeInvalidArgument, err)
+	}
+	recursive, _ := args["recursive"].(bool)
+	createParents, _ := args["create_parents"].(bool)
+
+	// 复制只需读取源，移动会删除源，需要写权限
+	validate := fs.validatePath
+	if move {
+		validate = fs.validateWritePath
+	}
+	validSource, err := validate(source)
+	if err != nil {
+		return "", nil, fmt.Errorf("error with source path: %w", err)
+	}
+	srcInfo, err := os.Stat(validSource)
+	if err != nil {
+		return "", nil, comm.NewCodedError(comm.ErrCodeNotFound, fmt.Errorf("source does not exist: %s", source))
+	}
+	// 在处理冲突前拒绝未设置 recursive 的目录复制，避免覆盖时先动了目标
+	if srcInfo.IsDir() && !recursive && !move {
+		return "", nil, ErrIsDirectory
+	}
+	validDest, err := fs.validateDestination(destination, createParents)
+	if err != nil {
+		return "", nil, fmt.Errorf("error with destination path: %w", err)
+	}
+	dest, renamed, overwrite, err := prepareDestination(validSource, validDest, policy)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// 覆盖时先写入目标旁的临时目录，成功后再替换目标
+	target, stage, keepStage := dest, "", false
+	if overwrite {
+		if stage, err = os.MkdirTemp(filepath.Dir(dest), ".moling-transfer-"); err != nil {
+			return "", nil, fmt.Errorf("failed to create a staging directory: %v", err)
+		}
+		defer func() {
+			if !keepStage {
+				os.RemoveAll(stage)
+			}
+		}()
+		target = filepath.Join(stage, "new")
+	}
+
+	result := &transferResult{Path: dest, Renamed: renamed}
+	if move {
+		err = renameFile(validSource, target)
+		if err == nil {
+			if overwrite {
+				if err = replaceDestination(target, dest, stage); err != nil {
+					// 替换失败时把源移回原处，避免随临时目录一起删除
+					if rerr := renameFile(target, validSource); rerr != nil {
+						keepStage = true
+						return source, result, fmt.Errorf("%v, the source is kept at %s", err, target)
+					}
+				}
+			}
+			return source, result, err
+		}
+		if !errors.Is(err, errCrossDevice) {
+			return source, result, err
+		}
+		// 跨设备无法重命名，复制后删除源
+		fs.Logger.Debug().Str("source", validSource).Str("destination", dest).Msg("cross-device move, copying")
+		result.Copied = true
+		recursive = true
+	}
+	result.Bytes, result.Files, err = copyTree(validSource, target, recursive, func(path, linkTarget, link string) error {
+		return fs.checkSymlink(validSource, path, linkTarget, link)
+	})
+	if err != nil {
+		return source, result, err
+	}
+	if overwrite {
+		if err := replaceDestination(target, dest, stage); err != nil {
+			return source, result, err
+		}
+	}
+	if move {
+		if err := os.RemoveAll(validSource); err != nil {
+			return source, result, fmt.Errorf("copied to %s but failed to remove the source: %v", dest, err)
+		}
+	}
+	return source, result, nil
+}
+
+func (fs *FilesystemServer) handleFileCopy(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	source, result, err := fs.transfer(request.GetArguments(), false)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to copy", err), nil
+	}
+	return mcp.NewToolResultText(result.String("Copied", source)), nil
+}
+
+func (fs *FilesystemServer) handleFileMove(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	source, result, err := fs.transfer(request.GetArguments(), true)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to move", err), nil
+	}
+	return mcp.NewToolResultText(result.String("Moved", source)), nil
+}
@@ -0,0 +1,302 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func callTransfer(t *testing.T, fs *FilesystemServer, move bool, args map[string]interface{}) (string, bool) {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = args
+	handler := fs.handleFileCopy
+	if move {
+		handler = fs.handleFileMove
+	}
+	result, err := handler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return result.Content[0].(mcp.TextContent).Text, result.IsError
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0640); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestFileTransfer(t *testing.T) {
+	t.Run("ConflictError", func(t *testing.T) {
+		fs, dir := newTestFilesystemServer(t)
+		writeTestFile(t, filepath.Join(dir, "a.txt"), "new")
+		writeTestFile(t, filepath.Join(dir, "b.txt"), "old")
+		for _, move := range []bool{false, true} {
+			text, isErr := callTransfer(t, fs, move, map[string]interface{}{"source": "a.txt", "destination": "b.txt"})
+			if !isErr || !strings.Contains(text, ErrDestinationExists.Error()) {
+				t.Errorf("Expected %v, got %s", ErrDestinationExists, text)
+			}
+		}
+		if readTestFile(t, filepath.Join(dir, "b.txt")) != "old" {
+			t.Error("Expected the destination to be kept")
+		}
+	})
+
+	t.Run("ConflictOverwrite", func(t *testing.T) {
+		fs, dir := newTestFilesystemServer(t)
+		writeTestFile(t, filepath.Join(dir, "a.txt"), "new")
+		writeTestFile(t, filepath.Join(dir, "b.txt"), "old")
+		text, isErr := callTransfer(t, fs, false, map[string]interface{}{"source": "a.txt", "destination": "b.txt", "on_conflict": "overwrite"})
+		if isErr || !strings.Contains(text, "Bytes copied: 3") {
+			t.Fatalf("Unexpected result %s", text)
+		}
+		if readTestFile(t, filepath.Join(dir, "b.txt")) != "new" {
+			t.Error("Expected the destination to be overwritten")
+		}
+		// 目录覆盖文件
+		writeTestFile(t, filepath.Join(dir, "src", "c.txt"), "c")
+		if text, isErr := callTransfer(t, fs, true, map[string]interface{}{"source": "src", "destination": "b.txt", "on_conflict": "overwrite"}); isErr {
+			t.Fatalf("Unexpected error %s", text)
+		}
+		if readTestFile(t, filepath.Join(dir, "b.txt", "c.txt")) != "c" {
+			t.Error("Expected the directory to replace the file")
+		}
+	})
+
+	t.Run("OverwriteKeepsDestinationOnFailure", func(t *testing.T) {
+		fs, dir := newTestFilesystemServer(t)
+		outside := t.TempDir()
+		writeTestFile(t, filepath.Join(dir, "dst", "keep.txt"), "keep")
+		writeTestFile(t, filepath.Join(dir, "src", "a.txt"), "a")
+		text, isErr := callTransfer(t, fs, false, map[string]interface{}{"source": "src", "destination": "dst", "on_conflict": "overwrite"})
+		if !isErr || !strings.Contains(text, ErrIsDirectory.Error()) {
+			t.Errorf("Expected %v without recursive, got %s", ErrIsDirectory, text)
+		}
+		if readTestFile(t, filepath.Join(dir, "dst", "keep.txt")) != "keep" {
+			t.Error("Expected the destination to be kept when recursive is missing")
+		}
+		// 复制中途失败时目标保持不变，且不留下临时目录
+		if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(dir, "src", "z.lnk")); err != nil {
+			t.Skipf("symlinks are not supported: %v", err)
+		}
+		text, isErr = callTransfer(t, fs, false, map[string]interface{}{"source": "src", "destination": "dst", "on_conflict": "overwrite", "recursive": true})
+		if !isErr {
+			t.Fatalf("Expected the copy to fail, got %s", text)
+		}
+		if readTestFile(t, filepath.Join(dir, "dst", "keep.txt")) != "keep" {
+			t.Error("Expected the destination to be kept when the copy fails")
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range entries {
+			if strings.HasPrefix(e.Name(), ".moling-transfer-") {
+				t.Errorf("Expected the staging directory to be removed, found %s", e.Name())
+			}
+		}
+	})
+
+	t.Run("SymlinkEscape", func(t *testing.T) {
+		fs, dir := newTestFilesystemServer(t)
+		outside := t.TempDir()
+		writeTestFile(t, filepath.Join(outside, "secret.txt"), "s")
+		writeTestFile(t, filepath.Join(dir, "src", "a.txt"), "a")
+		if err := os.Symlink("a.txt", filepath.Join(dir, "src", "inside.lnk")); err != nil {
+			t.Skipf("symlinks are not supported: %v", err)
+		}
+		text, isErr := callTransfer(t, fs, false, map[string]interface{}{"source": "src", "destination": "ok", "recursive": true})
+		if isErr {
+			t.Fatalf("Unexpected error %s", text)
+		}
+		if readTestFile(t, filepath.Join(dir, "ok", "inside.lnk")) != "a" {
+			t.Error("Expected the symlink inside the tree to be recreated")
+		}
+
+		escape, err := filepath.Rel(filepath.Join(dir, "src"), filepath.Join(outside, "secret.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, link := range map[string]string{"abs.lnk": filepath.Join(outside, "secret.txt"), "rel.lnk": escape} {
+			src := filepath.Join(dir, "src-"+name)
+			writeTestFile(t, filepath.Join(src, "a.txt"), "a")
+			if err := os.Symlink(link, filepath.Join(src, name)); err != nil {
+				t.Fatal(err)
+			}
+			text, isErr := callTransfer(t, fs, false, map[string]interface{}{"source": "src-" + name, "destination": "out-" + name, "recursive": true})
+			if !isErr || !strings.Contains(text, "access denied") {
+				t.Errorf("%s: expected access denied, got %s", name, text)
+			}
+			if _, err := os.Lstat(filepath.Join(dir, "out-"+name, name)); !os.IsNotExist(err) {
+				t.Errorf("%s: expected the symlink not to be recreated", name)
+			}
+		}
+	})
+
+	t.Run("ConflictRename", func(t *testing.T) {
+		fs, dir := newTestFilesystemServer(t)
+		writeTestFile(t, filepath.Join(dir, "a.txt"), "new")
+		writeTestFile(t, filepath.Join(dir, "b.txt"), "old")
+		writeTestFile(t, filepath.Join(dir, "b_1.txt"), "older")
+		text, isErr := callTransfer(t, fs, true, map[string]interface{}{"source": "a.txt", "destination": "b.txt", "on_conflict": "rename"})
+		final := filepath.Join(dir, "b_2.txt")
+		if isErr || !strings.Contains(text, "Final path: "+final) {
+			t.Fatalf("Expected the final path %s, got %s", final, text)
+		}
+		if readTestFile(t, final) != "new" || readTestFile(t, filepath.Join(dir, "b.txt")) != "old" {
+			t.Error("Expected the renamed destination next to the existing files")
+		}
+		if _, err := os.Stat(filepath.Join(dir, "a.txt")); !os.IsNotExist(err) {
+			t.Error("Expected the source to be moved")
+		}
+	})
+
+	t.Run("InvalidPolicy", func(t *testing.T) {
+		fs, dir := newTestFilesystemServer(t)
+		writeTestFile(t, filepath.Join(dir, "a.txt"), "a")
+		if text, isErr := callTransfer(t, fs, false, map[string]interface{}{"source": "a.txt", "destination": "b.txt", "on_conflict": "merge"}); !isErr {
+			t.Errorf("Expected an invalid policy to be rejected, got %s", text)
+		}
+	})
+
+	t.Run("DirectoryRecursion", func(t *testing.T) {
+		fs, dir := newTestFilesystemServer(t)
+		mtime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		writeTestFile(t, filepath.Join(dir, "src", "a.txt"), "aaa")
+		writeTestFile(t, filepath.Join(dir, "src", "sub", "b.txt"), "bb")
+		if err := os.Chmod(filepath.Join(dir, "src", "sub", "b.txt"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range []string{filepath.Join(dir, "src", "a.txt"), filepath.Join(dir, "src", "sub")} {
+			if err := os.Chtimes(p, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if text, isErr := callTransfer(t, fs, false, map[string]interface{}{"source": "src", "destination": "dst"}); !isErr || !strings.Contains(text, ErrIsDirectory.Error()) {
+			t.Errorf("Expected %v without recursive, got %s", ErrIsDirectory, text)
+		}
+		text, isErr := callTransfer(t, fs, false, map[string]interface{}{"source": "src", "destination": "out/dst", "recursive": true, "create_parents": true})
+		if isErr || !strings.Contains(text, "Bytes copied: 5") || !strings.Contains(text, "Files copied: 2") {
+			t.Fatalf("Unexpected result %s", text)
+		}
+		dst := filepath.Join(dir, "out", "dst")
+		if readTestFile(t, filepath.Join(dst, "sub", "b.txt")) != "bb" {
+			t.Error("Expected the nested file to be copied")
+		}
+		for _, p := range []string{filepath.Join(dst, "a.txt"), filepath.Join(dst, "sub")} {
+			if info, err := os.Stat(p); err != nil || !info.ModTime().Equal(mtime) {
+				t.Errorf("%s: expected mtime %v, got %v", p, mtime, info.ModTime())
+			}
+		}
+		if info, _ := os.Stat(filepath.Join(dst, "sub", "b.txt")); info.Mode().Perm() != 0600 {
+			t.Errorf("Expected permissions 0600, got %o", info.Mode().Perm())
+		}
+		if text, isErr := callTransfer(t, fs, false, map[string]interface{}{"source": "src", "destination": "src/inner", "recursive": true}); !isErr {
+			t.Errorf("Expected copying a directory into itself to fail, got %s", text)
+		}
+	})
+
+	t.Run("CreateParents", func(t *testing.T) {
+		fs, dir := newTestFilesystemServer(t)
+		writeTestFile(t, filepath.Join(dir, "a.txt"), "a")
+		if text, isErr := callTransfer(t, fs, false, map[string]interface{}{"source": "a.txt", "destination": "x/y/a.txt"}); !isErr || !strings.Contains(text, "create_parents") {
+			t.Errorf("Expected a missing parent error, got %s", text)
+		}
+		if text, isErr := callTransfer(t, fs, false, map[string]interface{}{"source": "a.txt", "destination": "x/y/a.txt", "create_parents": true}); isErr {
+			t.Errorf("Unexpected error %s", text)
+		}
+	})
+
+	t.Run("CrossDevice", func(t *testing.T) {
+		fs, dir := newTestFilesystemServer(t)
+		defer func(rename func(string, string) error) { renameFile = rename }(renameFile)
+		renameFile = func(oldpath, newpath string) error {
+			return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: errCrossDevice}
+		}
+		mtime := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+		writeTestFile(t, filepath.Join(dir, "src", "a.txt"), "hello")
+		if err := os.Chtimes(filepath.Join(dir, "src", "a.txt"), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		text, isErr := callTransfer(t, fs, true, map[string]interface{}{"source": "src", "destination": "moved"})
+		if isErr || !strings.Contains(text, "another device") || !strings.Contains(text, "Bytes copied: 5") {
+			t.Fatalf("Unexpected result %s", text)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "src")); !os.IsNotExist(err) {
+			t.Error("Expected the source to be deleted after the copy")
+		}
+		if info, err := os.Stat(filepath.Join(dir, "moved", "a.txt")); err != nil || !info.ModTime().Equal(mtime) {
+			t.Errorf("Expected the moved file with its mtime, got %v", err)
+		}
+	})
+
+	t.Run("AllowedRoots", func(t *testing.T) {
+		fs, dir := newTestFilesystemServer(t)
+		outside := t.TempDir()
+		writeTestFile(t, filepath.Join(dir, "a.txt"), "a")
+		writeTestFile(t, filepath.Join(outside, "secret.txt"), "s")
+		if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+			t.Skipf("symlinks are not supported: %v", err)
+		}
+		// 绝对路径会被拼接到允许的目录下，用相对路径逃逸
+		escape, err := filepath.Rel(dir, outside)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, move := range []bool{false, true} {
+			for _, args := range []map[string]interface{}{
+				{"source": filepath.Join(escape, "secret.txt"), "destination": "secret.txt"},
+				{"source": "a.txt", "destination": filepath.Join(escape, "a.txt")},
+				{"source": "a.txt", "destination": filepath.Join(escape, "new", "a.txt"), "create_parents": true},
+				{"source": "a.txt", "destination": "link/a.txt"},
+				{"source": "a.txt", "destination": "link/new/a.txt", "create_parents": true},
+				{"source": "link/secret.txt", "destination": "secret.txt"},
+			} {
+				if text, isErr := callTransfer(t, fs, move, args); !isErr || !strings.Contains(text, "access denied") {
+					t.Errorf("%v: expected access denied, got %s", args, text)
+				}
+			}
+		}
+		if _, err := os.Stat(filepath.Join(outside, "new")); !os.IsNotExist(err) {
+			t.Error("Expected no directory to be created outside the allowed directories")
+		}
+	})
+}
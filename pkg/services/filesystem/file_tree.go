@@ -0,0 +1,229 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	treeDefaultMaxDepth   = 3
+	treeMaxDepth          = 64
+	treeDefaultMaxEntries = 1000
+	treeMaxEntries        = 100000
+)
+
+// TreeNode is an entry of the directory_tree result. Files and symlinks carry their size,
+// directories the total size of the regular files below them, however deep.
+type TreeNode struct {
+	Name            string      `json:"name"`
+	Type            string      `json:"type"`
+	Size            int64       `json:"size,omitempty"`
+	SubtreeSize     *int64      `json:"subtree_size,omitempty"`
+	Mtime           time.Time   `json:"mtime"`
+	Target          string      `json:"target,omitempty"`           // 符号链接的目标，不跟随
+	Children        []*TreeNode `json:"children,omitempty"`         // 按名称排序
+	ChildrenOmitted bool        `json:"children_omitted,omitempty"` // 超过 max_depth 或 max_entries，未列出子项
+}
+
+// DirTree is the structured result of the directory_tree tool. Entries counts the nodes below
+// the root; Truncated is set when max_entries was reached and the tree is partial.
+type DirTree struct {
+	Path      string    `json:"path"`
+	MaxDepth  int       `json:"max_depth"`
+	Entries   int       `json:"entries"`
+	Truncated bool      `json:"truncated"`
+	Root      *TreeNode `json:"root"`
+}
+
+// treeOptions are the arguments of directory_tree.
+type treeOptions struct {
+	MaxDepth      int
+	MaxEntries    int
+	IncludeHidden bool
+}
+
+// parseTreeOptions validates the arguments of directory_tree.
+func parseTreeOptions(args map[string]interface{}) (treeOptions, error) {
+	opts := treeOptions{MaxDepth: treeDefaultMaxDepth, MaxEntries: treeDefaultMaxEntries}
+	if v, ok := args["max_depth"].(float64); ok {
+		if v < 1 || v > treeMaxDepth {
+			return opts, fmt.Errorf("max_depth must be between 1 and %d", treeMaxDepth)
+		}
+		opts.MaxDepth = int(v)
+	}
+	if v, ok := args["max_entries"].(float64); ok {
+		if v < 1 || v > treeMaxEntries {
+			return opts, fmt.Errorf("max_entries must be between 1 and %d", treeMaxEntries)
+		}
+		opts.MaxEntries = int(v)
+	}
+	opts.IncludeHidden, _ = args["include_hidden"].(bool)
+	return opts, nil
+}
+
+func newTreeNode(entry ListEntry) *TreeNode {
+	node := &TreeNode{Name: entry.Name, Type: entry.Type, Mtime: entry.Mtime, Target: entry.Target}
+	if entry.Type == ListTypeDir {
+		node.SubtreeSize = new(int64)
+	} else {
+		node.Size = entry.Size
+	}
+	return node
+}
+
+// buildTree lists root breadth first, so a truncated tree still shows the upper levels
+// completely, and then sums the sizes of the regular files into the listed directories.
+// Symlinks are reported and never followed; hidden entries are skipped, and not counted in the
+// sizes, unless opts.IncludeHidden is set.
+func buildTree(ctx context.Context, root string, opts treeOptions) (*DirTree, error) {
+	info, err := os.Lstat(root)
+	if err != nil {
+		return nil, err
+	}
+	tree := &DirTree{Path: root, MaxDepth: opts.MaxDepth, Root: newTreeNode(newListEntry(filepath.Dir(root), info))}
+	dirs := map[string]*TreeNode{root: tree.Root}
+
+	type pending struct {
+		node  *TreeNode
+		path  string
+		depth int
+	}
+	queue := []pending{{tree.Root, root, 0}}
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		cur := queue[0]
+		queue = queue[1:]
+		if cur.depth >= opts.MaxDepth || tree.Truncated {
+			cur.node.ChildrenOmitted = true
+			continue
+		}
+		entries, err := os.ReadDir(cur.path)
+		if err != nil && len(entries) == 0 {
+			// 无权限的目录不展开
+			cur.node.ChildrenOmitted = true
+			continue
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+		for _, de := range entries {
+			if !opts.IncludeHidden && strings.HasPrefix(de.Name(), ".") {
+				continue
+			}
+			if tree.Entries >= opts.MaxEntries {
+				tree.Truncated = true
+				cur.node.ChildrenOmitted = true
+				break
+			}
+			info, err := de.Info()
+			if err != nil {
+				continue
+			}
+			child := newTreeNode(newListEntry(cur.path, info))
+			cur.node.Children = append(cur.node.Children, child)
+			tree.Entries++
+			if child.Type == ListTypeDir {
+				p := filepath.Join(cur.path, de.Name())
+				dirs[p] = child
+				queue = append(queue, pending{child, p, cur.depth + 1})
+			}
+		}
+	}
+
+	// 目录大小包含 max_depth 以下未列出的文件，需要遍历整个子树
+	err = filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil || p == root {
+			return nil
+		}
+		if !opts.IncludeHidden && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		for dir := filepath.Dir(p); ; dir = filepath.Dir(dir) {
+			if node, ok := dirs[dir]; ok {
+				*node.SubtreeSize += info.Size()
+			}
+			if dir == root || dir == filepath.Dir(dir) {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+func (fs *FilesystemServer) handleDirectoryTree(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	path, ok := args["path"].(string)
+	if !ok {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("path %v must be a string", args["path"]), ""), nil
+	}
+	opts, err := parseTreeOptions(args)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+
+	validPath, err := fs.validatePath(path)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	info, err := os.Stat(validPath)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	if !info.IsDir() {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("%s is not a directory", path), ""), nil
+	}
+	tree, err := buildTree(ctx, validPath, opts)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to read directory tree", err), nil
+	}
+
+	data, err := json.MarshalIndent(tree, "", "  ")
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to encode directory tree", err), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func callDirectoryTree(t *testing.T, fs *FilesystemServer, args map[string]interface{}) (*DirTree, string) {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "directory_tree"
+	request.Params.Arguments = args
+	result, err := fs.handleDirectoryTree(context.Background(), request)
+	if err != nil {
+		t.Fatalf("handleDirectoryTree failed: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if result.IsError {
+		return nil, text
+	}
+	var tree DirTree
+	if err := json.Unmarshal([]byte(text), &tree); err != nil {
+		t.Fatalf("Invalid tree %s: %v", text, err)
+	}
+	return &tree, ""
+}
+
+// child returns the child of node with the name, or nil.
+func child(node *TreeNode, name string) *TreeNode {
+	for _, c := range node.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestDirectoryTree(t *testing.T) {
+	fs, dir := newTestFilesystemServer(t)
+	root := filepath.Join(dir, "project")
+	writeTree(t, root, map[string]string{
+		"README.md":          "12345",
+		"src/main.go":        "1234567890",
+		"src/a/b/c/deep.go":  "123",
+		".git/config":        "1234567",
+		"src/.cache/tmp.bin": "12",
+	}, time.Now())
+
+	t.Run("DepthAndSizes", func(t *testing.T) {
+		tree, errText := callDirectoryTree(t, fs, map[string]interface{}{"path": root, "max_depth": float64(2)})
+		if tree == nil {
+			t.Fatalf("Unexpected error: %s", errText)
+		}
+		if tree.Truncated || tree.Root.Type != ListTypeDir || *tree.Root.SubtreeSize != 18 {
+			t.Errorf("Expected the root to total the visible files, got %+v", tree.Root)
+		}
+		if child(tree.Root, ".git") != nil {
+			t.Errorf("Expected hidden entries to be excluded, got %+v", tree.Root.Children)
+		}
+		src := child(tree.Root, "src")
+		if src == nil || *src.SubtreeSize != 13 || src.ChildrenOmitted {
+			t.Fatalf("Expected src with a subtree size of 13, got %+v", src)
+		}
+		if f := child(src, "main.go"); f == nil || f.Type != ListTypeFile || f.Size != 10 {
+			t.Errorf("Expected main.go with size 10, got %+v", f)
+		}
+		// 第三层不再展开，但大小包含更深的文件
+		a := child(src, "a")
+		if a == nil || !a.ChildrenOmitted || len(a.Children) != 0 || *a.SubtreeSize != 3 {
+			t.Errorf("Expected a to be cut at max_depth with size 3, got %+v", a)
+		}
+		if tree.Entries != 4 {
+			t.Errorf("Expected 4 entries, got %d", tree.Entries)
+		}
+	})
+
+	t.Run("IncludeHidden", func(t *testing.T) {
+		tree, _ := callDirectoryTree(t, fs, map[string]interface{}{"path": root, "include_hidden": true})
+		if git := child(tree.Root, ".git"); git == nil || *git.SubtreeSize != 7 {
+			t.Errorf("Expected .git to be listed, got %+v", git)
+		}
+		if *tree.Root.SubtreeSize != 27 {
+			t.Errorf("Expected hidden files in the total, got %d", *tree.Root.SubtreeSize)
+		}
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		tree, errText := callDirectoryTree(t, fs, map[string]interface{}{"path": root, "max_entries": float64(2)})
+		if tree == nil {
+			t.Fatalf("Expected a partial tree, got %s", errText)
+		}
+		// 广度优先，先列出第一层
+		if !tree.Truncated || tree.Entries != 2 || child(tree.Root, "README.md") == nil || child(tree.Root, "src") == nil {
+			t.Errorf("Expected the first level of a truncated tree, got %+v", tree)
+		}
+		if src := child(tree.Root, "src"); !src.ChildrenOmitted || *src.SubtreeSize != 13 {
+			t.Errorf("Expected src to be omitted with its full size, got %+v", src)
+		}
+	})
+
+	t.Run("Symlinks", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("symlinks need privileges on windows")
+		}
+		outside := t.TempDir()
+		writeTree(t, outside, map[string]string{"big.bin": "0123456789"}, time.Now())
+		linkRoot := filepath.Join(dir, "links")
+		writeTree(t, linkRoot, map[string]string{"f.txt": "1"}, time.Now())
+		if err := os.Symlink(outside, filepath.Join(linkRoot, "out")); err != nil {
+			t.Fatal(err)
+		}
+		tree, _ := callDirectoryTree(t, fs, map[string]interface{}{"path": linkRoot})
+		link := child(tree.Root, "out")
+		if link == nil || link.Type != ListTypeSymlink || link.Target != outside || len(link.Children) != 0 {
+			t.Errorf("Expected the symlink to be reported and not followed, got %+v", link)
+		}
+		if *tree.Root.SubtreeSize != 1 {
+			t.Errorf("Expected the symlink target not to be counted, got %d", *tree.Root.SubtreeSize)
+		}
+	})
+
+	t.Run("Refused", func(t *testing.T) {
+		outside, _ := filepath.EvalSymlinks(t.TempDir())
+		if tree, _ := callDirectoryTree(t, fs, map[string]interface{}{"path": outside}); tree != nil {
+			t.Errorf("Expected a path outside the allowed directories to be refused, got %+v", tree)
+		}
+		if _, errText := callDirectoryTree(t, fs, map[string]interface{}{"path": root, "max_depth": float64(0)}); errText == "" {
+			t.Error("Expected max_depth 0 to be rejected")
+		}
+		if _, errText := callDirectoryTree(t, fs, map[string]interface{}{"path": filepath.Join(root, "README.md")}); errText == "" {
+			t.Error("Expected a file to be rejected")
+		}
+	})
+}
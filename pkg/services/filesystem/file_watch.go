@@ -0,0 +1,276 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog"
+)
+
+// watch_path 通知中的事件类型
+const (
+	WatchEventCreate = "create"
+	WatchEventWrite  = "write"
+	WatchEventRemove = "remove"
+	WatchEventRename = "rename"
+	WatchEventChmod  = "chmod"
+)
+
+// watchDebounce is the window in which the events of one path are merged into one notification.
+var watchDebounce = 500 * time.Millisecond
+
+// watchOps returns the event types of the fsnotify operation.
+func watchOps(op fsnotify.Op) []string {
+	var ops []string
+	for _, o := range []struct {
+		op   fsnotify.Op
+		name string
+	}{
+		{fsnotify.Create, WatchEventCreate},
+		{fsnotify.Write, WatchEventWrite},
+		{fsnotify.Remove, WatchEventRemove},
+		{fsnotify.Rename, WatchEventRename},
+		{fsnotify.Chmod, WatchEventChmod},
+	} {
+		if op.Has(o.op) {
+			ops = append(ops, o.name)
+		}
+	}
+	return ops
+}
+
+// pathWatch is a watch registered by watch_path. The events of each path are collected for
+// watchDebounce and then sent as one notification.
+type pathWatch struct {
+	ID      string
+	Path    string
+	watcher *fsnotify.Watcher
+	notify  func(path string, events []string)
+	logger  zerolog.Logger
+
+	mu      sync.Mutex
+	pending map[string]map[string]bool // 路径 -> 窗口内的事件类型
+	timers  map[string]*time.Timer
+	closed  bool
+}
+
+func (w *pathWatch) run() {
+	for {
+		select {
+		case ev, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.add(ev)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.logger.Warn().Err(err).Str("path", w.Path).Msg("file watch error")
+		}
+	}
+}
+
+func (w *pathWatch) add(ev fsnotify.Event) {
+	ops := watchOps(ev.Op)
+	if len(ops) == 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	events, ok := w.pending[ev.Name]
+	if !ok {
+		events = make(map[string]bool)
+		w.pending[ev.Name] = events
+		w.timers[ev.Name] = time.AfterFunc(watchDebounce, func() { w.flush(ev.Name) })
+	}
+	for _, op := range ops {
+		events[op] = true
+	}
+}
+
+// flush sends the events collected for the path since the first one of the window.
+func (w *pathWatch) flush(path string) {
+	w.mu.Lock()
+	events := w.pending[path]
+	delete(w.pending, path)
+	delete(w.timers, path)
+	closed := w.closed
+	w.mu.Unlock()
+	if closed || len(events) == 0 {
+		return
+	}
+	names := make([]string, 0, len(events))
+	for name := range events {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w.notify(path, names)
+}
+
+// close stops the watch, pending events are dropped.
+func (w *pathWatch) close() error {
+	w.mu.Lock()
+	w.closed = true
+	for _, t := range w.timers {
+		t.Stop()
+	}
+	w.mu.Unlock()
+	return w.watcher.Close()
+}
+
+// watchSet holds the active watches of the server, keyed by their ID.
+type watchSet struct {
+	mu      sync.Mutex
+	next    int
+	watches map[string]*pathWatch
+}
+
+// add starts watching path unless max watches are active.
+func (ws *watchSet) add(path string, max int, logger zerolog.Logger, notify func(w *pathWatch, path string, events []string)) (*pathWatch, error) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if len(ws.watches) >= max {
+		return nil, fmt.Errorf("too many watches, %d of max_watches %d are active, remove one with unwatch_path", len(ws.watches), max)
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(path); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	ws.next++
+	w := &pathWatch{
+		ID:      "watch-" + strconv.Itoa(ws.next),
+		Path:    path,
+		watcher: watcher,
+		logger:  logger,
+		pending: make(map[string]map[string]bool),
+		timers:  make(map[string]*time.Timer),
+	}
+	w.notify = func(p string, events []string) { notify(w, p, events) }
+	if ws.watches == nil {
+		ws.watches = make(map[string]*pathWatch)
+	}
+	ws.watches[w.ID] = w
+	go w.run()
+	return w, nil
+}
+
+// remove stops the watch with the ID, or all the watches of the path, and returns the removed IDs.
+func (ws *watchSet) remove(id, path string) []string {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	var removed []string
+	for wid, w := range ws.watches {
+		if (id != "" && wid == id) || (path != "" && w.Path == path) {
+			_ = w.close()
+			delete(ws.watches, wid)
+			removed = append(removed, wid)
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+// closeAll stops all the watches.
+func (ws *watchSet) closeAll() {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	for id, w := range ws.watches {
+		_ = w.close()
+		delete(ws.watches, id)
+	}
+}
+
+func (fs *FilesystemServer) handleWatchPath(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	path, ok := args["path"].(string)
+	if !ok {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("path %v must be a string", args["path"]), ""), nil
+	}
+	validPath, err := fs.validatePath(path)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+	info, err := os.Stat(validPath)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+
+	// 通知在工具调用返回后发送，只保留 ctx 中的客户端会话，不随请求取消
+	session := context.WithoutCancel(ctx)
+	w, err := fs.watches.add(validPath, fs.cfg().MaxWatches, fs.Logger, func(w *pathWatch, p string, events []string) {
+		err := fs.notify(session, "notifications/message", map[string]any{
+			"level":  mcp.LoggingLevelInfo,
+			"logger": "watch_path",
+			"data":   map[string]any{"watch_id": w.ID, "path": p, "events": events},
+		})
+		if err != nil {
+			fs.Logger.Debug().Err(err).Str("path", p).Msg("failed to send the file event to the client")
+		}
+	})
+	if err != nil {
+		return comm.ToolErrorFromErr(fmt.Sprintf("failed to watch %s", path), err), nil
+	}
+
+	what := "file"
+	if info.IsDir() {
+		what = "directory and its direct entries"
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Watching %s %s as %s. Changes are sent as notifications/message with logger watch_path, at most one per path every %s; stop with unwatch_path.",
+		what, validPath, w.ID, watchDebounce)), nil
+}
+
+func (fs *FilesystemServer) handleUnwatchPath(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	id, _ := args["watch_id"].(string)
+	path, _ := args["path"].(string)
+	if id == "" && path == "" {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, "watch_id or path is required", ""), nil
+	}
+	if path != "" {
+		validPath, err := fs.validatePath(path)
+		if err != nil {
+			return comm.ArgumentError(err), nil
+		}
+		path = validPath
+	}
+	removed := fs.watches.remove(id, path)
+	if len(removed) == 0 {
+		return comm.ToolError(comm.ErrCodeNotFound, "no matching watch", ""), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Removed %s", strings.Join(removed, ", "))), nil
+}
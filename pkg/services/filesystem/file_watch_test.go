@@ -0,0 +1,187 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// watchRecorder collects the watch_path notifications of a test server.
+type watchRecorder struct {
+	mu     sync.Mutex
+	events []map[string]any
+}
+
+func (r *watchRecorder) notify(ctx context.Context, method string, params map[string]any) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if method == "notifications/message" && params["logger"] == "watch_path" {
+		r.events = append(r.events, params["data"].(map[string]any))
+	}
+	return nil
+}
+
+// waitFor waits until an event of the path with the event type arrives and returns the events of
+// the path received so far.
+func (r *watchRecorder) waitFor(t *testing.T, path, event string) []map[string]any {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		var got []map[string]any
+		found := false
+		for _, e := range r.events {
+			if e["path"] != path {
+				continue
+			}
+			got = append(got, e)
+			for _, ev := range e["events"].([]string) {
+				found = found || ev == event
+			}
+		}
+		r.mu.Unlock()
+		if found {
+			return got
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("No %s event for %s", event, path)
+	return nil
+}
+
+func callWatch(t *testing.T, fs *FilesystemServer, tool string, args map[string]interface{}) (string, bool) {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = tool
+	request.Params.Arguments = args
+	handler := fs.handleWatchPath
+	if tool == "unwatch_path" {
+		handler = fs.handleUnwatchPath
+	}
+	result, err := handler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("%s failed: %v", tool, err)
+	}
+	return result.Content[0].(mcp.TextContent).Text, result.IsError
+}
+
+func TestWatchPath(t *testing.T) {
+	old := watchDebounce
+	watchDebounce = 200 * time.Millisecond
+	defer func() { watchDebounce = old }()
+
+	fs, dir := newTestFilesystemServer(t)
+	rec := &watchRecorder{}
+	fs.notify = rec.notify
+	defer fs.Close()
+
+	t.Run("Events", func(t *testing.T) {
+		text, isErr := callWatch(t, fs, "watch_path", map[string]interface{}{"path": dir})
+		if isErr || !strings.Contains(text, "watch-") {
+			t.Fatalf("Unexpected result: %s", text)
+		}
+		file := filepath.Join(dir, "artifact.txt")
+		if err := os.WriteFile(file, []byte("v1"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		rec.waitFor(t, file, WatchEventCreate)
+
+		// 同一窗口内的多次写入合并为一次通知
+		time.Sleep(watchDebounce)
+		before := len(rec.waitFor(t, file, WatchEventCreate))
+		for i := 0; i < 5; i++ {
+			if err := os.WriteFile(file, []byte(strings.Repeat("v", i+2)), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		got := rec.waitFor(t, file, WatchEventWrite)
+		time.Sleep(2 * watchDebounce)
+		if after := len(rec.waitFor(t, file, WatchEventWrite)); after-before != 1 {
+			t.Errorf("Expected the writes to be debounced into one notification, got %d: %v", after-before, got)
+		}
+
+		if err := os.Remove(file); err != nil {
+			t.Fatal(err)
+		}
+		rec.waitFor(t, file, WatchEventRemove)
+		if id := got[0]["watch_id"]; id == "" {
+			t.Errorf("Expected a watch ID in the notification, got %v", got[0])
+		}
+	})
+
+	t.Run("Unwatch", func(t *testing.T) {
+		sub := filepath.Join(dir, "sub")
+		if err := os.Mkdir(sub, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if _, isErr := callWatch(t, fs, "watch_path", map[string]interface{}{"path": sub}); isErr {
+			t.Fatal("Expected the watch to start")
+		}
+		text, isErr := callWatch(t, fs, "unwatch_path", map[string]interface{}{"path": sub})
+		if isErr || !strings.Contains(text, "Removed watch-") {
+			t.Fatalf("Unexpected result: %s", text)
+		}
+		if err := os.WriteFile(filepath.Join(sub, "late.txt"), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(2 * watchDebounce)
+		rec.mu.Lock()
+		for _, e := range rec.events {
+			if strings.HasPrefix(e["path"].(string), sub+string(filepath.Separator)) {
+				t.Errorf("Unexpected event after unwatch_path: %v", e)
+			}
+		}
+		rec.mu.Unlock()
+		if _, isErr := callWatch(t, fs, "unwatch_path", map[string]interface{}{"watch_id": "watch-999"}); !isErr {
+			t.Error("Expected an unknown watch ID to fail")
+		}
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		outside, _ := filepath.EvalSymlinks(t.TempDir())
+		if _, isErr := callWatch(t, fs, "watch_path", map[string]interface{}{"path": outside}); !isErr {
+			t.Error("Expected a path outside the allowed directories to be refused")
+		}
+
+		fs.config.MaxWatches = len(fs.watches.watches) + 1
+		if _, isErr := callWatch(t, fs, "watch_path", map[string]interface{}{"path": dir}); isErr {
+			t.Fatal("Expected the watch to start")
+		}
+		text, isErr := callWatch(t, fs, "watch_path", map[string]interface{}{"path": dir})
+		if !isErr || !strings.Contains(text, "too many watches") {
+			t.Errorf("Expected max_watches to be enforced, got %s", text)
+		}
+
+		// Close 停止所有监听
+		if err := fs.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if n := len(fs.watches.watches); n != 0 {
+			t.Errorf("Expected Close to stop all watches, %d left", n)
+		}
+	})
+}
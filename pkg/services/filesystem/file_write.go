@@ -0,0 +1,148 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// write_file 的写入模式
+const (
+	WriteModeOverwrite = "overwrite"  // 替换已有内容，文件不存在时创建
+	WriteModeAppend    = "append"     // 追加到末尾，文件不存在时创建
+	WriteModeCreateNew = "create_new" // 文件已存在时失败
+)
+
+// backupSuffix is appended to the name of the copy made by write_file with backup.
+const backupSuffix = ".bak"
+
+// writeOptions are the arguments of write_file besides the path and the content.
+type writeOptions struct {
+	Mode   string
+	Atomic bool
+	Backup bool
+}
+
+// parseWriteOptions validates the mode, atomic and backup arguments of write_file.
+func parseWriteOptions(args map[string]interface{}) (writeOptions, error) {
+	opts := writeOptions{Mode: WriteModeOverwrite}
+	if v, ok := args["mode"].(string); ok && v != "" {
+		switch v {
+		case WriteModeOverwrite, WriteModeAppend, WriteModeCreateNew:
+			opts.Mode = v
+		default:
+			return opts, fmt.Errorf("invalid mode %q, must be one of %s, %s, %s", v, WriteModeOverwrite, WriteModeAppend, WriteModeCreateNew)
+		}
+	}
+	opts.Atomic, _ = args["atomic"].(bool)
+	opts.Backup, _ = args["backup"].(bool)
+	return opts, nil
+}
+
+// writeResult is the outcome of writeContent.
+type writeResult struct {
+	Written int64  // 本次写入的字节数
+	Size    int64  // 写入后的文件大小
+	Backup  string // 备份文件的路径，未备份时为空
+	Created bool   // 文件原本不存在
+}
+
+// writeContent writes content to path with the mode. With atomic the new content is written to a
+// temporary file in the same directory and moved into place, so readers see the old or the new
+// file but never a partial one; the permissions of an existing file are kept. With backup an
+// existing file is copied to path.bak first.
+func writeContent(path, content string, opts writeOptions) (*writeResult, error) {
+	res := &writeResult{Written: int64(len(content))}
+	perm := os.FileMode(0644)
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		if info.IsDir() {
+			return nil, fmt.Errorf("cannot write to a directory: %s", path)
+		}
+		if opts.Mode == WriteModeCreateNew {
+			return nil, fmt.Errorf("%w: %s", ErrDestinationExists, path)
+		}
+		perm = info.Mode().Perm()
+	case os.IsNotExist(err):
+		res.Created = true
+	default:
+		return nil, err
+	}
+
+	if opts.Backup && !res.Created {
+		res.Backup = path + backupSuffix
+		if _, err := copyFile(path, res.Backup, info); err != nil {
+			return nil, fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+	}
+
+	if opts.Atomic {
+		commit := os.Rename
+		if opts.Mode == WriteModeCreateNew {
+			// 硬链接在目标已存在时失败，避免 rename 覆盖检查之后才创建的文件
+			commit = os.Link
+		}
+		err = writeAtomic(path, perm, commit, func(w io.Writer) error {
+			if opts.Mode == WriteModeAppend && !res.Created {
+				in, err := os.Open(path)
+				if err != nil {
+					return err
+				}
+				defer in.Close()
+				if _, err := io.CopyBuffer(w, in, make([]byte, copyBufferSize)); err != nil {
+					return err
+				}
+			}
+			_, err := io.Copy(w, strings.NewReader(content))
+			return err
+		})
+	} else {
+		flags := os.O_WRONLY | os.O_CREATE
+		switch opts.Mode {
+		case WriteModeAppend:
+			flags |= os.O_APPEND
+		case WriteModeCreateNew:
+			flags |= os.O_EXCL
+		default:
+			flags |= os.O_TRUNC
+		}
+		var f *os.File
+		if f, err = os.OpenFile(path, flags, perm); err == nil {
+			_, err = f.WriteString(content)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+	if err != nil {
+		if os.IsExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrDestinationExists, path)
+		}
+		return nil, err
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		res.Size = info.Size()
+	}
+	return res, nil
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package filesystem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func callWriteFile(t *testing.T, fs *FilesystemServer, args map[string]interface{}) (string, bool) {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "write_file"
+	request.Params.Arguments = args
+	result, err := fs.handleWriteFile(context.Background(), request)
+	if err != nil {
+		t.Fatalf("handleWriteFile failed: %v", err)
+	}
+	return result.Content[0].(mcp.TextContent).Text, result.IsError
+}
+
+func readString(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestWriteFileModes(t *testing.T) {
+	fs, dir := newTestFilesystemServer(t)
+
+	for _, atomic := range []bool{false, true} {
+		name := "Direct"
+		if atomic {
+			name = "Atomic"
+		}
+		t.Run(name, func(t *testing.T) {
+			file := filepath.Join(dir, strings.ToLower(name)+".txt")
+			text, isErr := callWriteFile(t, fs, map[string]interface{}{"path": file, "content": "one\n", "mode": WriteModeCreateNew, "atomic": atomic})
+			if isErr || !strings.Contains(text, "wrote 4 bytes") {
+				t.Fatalf("Unexpected result: %s", text)
+			}
+			text, isErr = callWriteFile(t, fs, map[string]interface{}{"path": file, "content": "other", "mode": WriteModeCreateNew, "atomic": atomic})
+			if !isErr || !strings.Contains(text, "already exists") || readString(t, file) != "one\n" {
+				t.Errorf("Expected create_new to fail on an existing file, got %s", text)
+			}
+
+			text, isErr = callWriteFile(t, fs, map[string]interface{}{"path": file, "content": "two\n", "mode": WriteModeAppend, "atomic": atomic})
+			if isErr || !strings.Contains(text, "appended 4 bytes") || readString(t, file) != "one\ntwo\n" {
+				t.Errorf("Expected the content to be appended, got %s: %q", text, readString(t, file))
+			}
+
+			text, isErr = callWriteFile(t, fs, map[string]interface{}{"path": file, "content": "three", "atomic": atomic})
+			if isErr || !strings.Contains(text, "wrote 5 bytes") || readString(t, file) != "three" {
+				t.Errorf("Expected the content to be replaced, got %s: %q", text, readString(t, file))
+			}
+
+			// 原子写入不留下临时文件
+			matches, _ := filepath.Glob(filepath.Join(dir, ".*.tmp"))
+			if len(matches) != 0 {
+				t.Errorf("Unexpected temporary files %v", matches)
+			}
+		})
+	}
+
+	t.Run("Backup", func(t *testing.T) {
+		file := filepath.Join(dir, "config.ini")
+		if err := os.WriteFile(file, []byte("old"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		text, isErr := callWriteFile(t, fs, map[string]interface{}{"path": file, "content": "new", "atomic": true, "backup": true})
+		if isErr || !strings.Contains(text, file+backupSuffix) {
+			t.Fatalf("Unexpected result: %s", text)
+		}
+		if readString(t, file) != "new" || readString(t, file+backupSuffix) != "old" {
+			t.Errorf("Expected the old content in the backup, got %q and %q", readString(t, file), readString(t, file+backupSuffix))
+		}
+
+		// 新文件没有可备份的内容
+		fresh := filepath.Join(dir, "fresh.txt")
+		text, _ = callWriteFile(t, fs, map[string]interface{}{"path": fresh, "content": "x", "backup": true})
+		if _, err := os.Stat(fresh + backupSuffix); err == nil || strings.Contains(text, "saved to") {
+			t.Errorf("Expected no backup of a new file, got %s", text)
+		}
+	})
+
+	t.Run("Permissions", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("permission bits are not supported on windows")
+		}
+		file := filepath.Join(dir, "script.sh")
+		if err := os.WriteFile(file, []byte("#!/bin/sh\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(file, 0750); err != nil {
+			t.Fatal(err)
+		}
+		for _, mode := range []string{WriteModeOverwrite, WriteModeAppend} {
+			if text, isErr := callWriteFile(t, fs, map[string]interface{}{"path": file, "content": "echo hi\n", "mode": mode, "atomic": true}); isErr {
+				t.Fatalf("Unexpected error: %s", text)
+			}
+			info, err := os.Stat(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0750 {
+				t.Errorf("Expected the %s atomic write to keep 0750, got %o", mode, info.Mode().Perm())
+			}
+		}
+		if got := readString(t, file); got != "echo hi\necho hi\n" {
+			t.Errorf("Unexpected content %q", got)
+		}
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		if text, isErr := callWriteFile(t, fs, map[string]interface{}{"path": filepath.Join(dir, "x.txt"), "content": "x", "mode": "truncate"}); !isErr {
+			t.Errorf("Expected an invalid mode to fail, got %s", text)
+		}
+		outside, _ := filepath.EvalSymlinks(t.TempDir())
+		target := filepath.Join(outside, "x.txt")
+		if text, isErr := callWriteFile(t, fs, map[string]interface{}{"path": target, "content": "x", "atomic": true}); !isErr {
+			t.Errorf("Expected a path outside the allowed directories to fail, got %s", text)
+		}
+		if _, err := os.Stat(target); err == nil {
+			t.Error("Expected nothing to be written outside the allowed directories")
+		}
+	})
+}
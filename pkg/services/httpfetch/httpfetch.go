@@ -0,0 +1,474 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+// Package httpfetch provides an HTTP client service for calling APIs without launching a browser.
+package httpfetch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/services/abstract"
+	"github.com/gojue/moling/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	HttpFetchServerName comm.MoLingServerType = "HttpFetch"
+)
+
+var errTooManyRedirects = errors.New("too many redirects")
+
+// HttpFetchServer implements the Service interface and provides the http_request tool.
+type HttpFetchServer struct {
+	abstract.MLService
+	config     *HttpFetchConfig // 重新加载时整体替换，处理函数通过 cfg 读取
+	configLock sync.RWMutex     // 保护 config
+
+	transport     *http.Transport // 按 transportKey 缓存的 Transport，请求之间复用连接
+	transportKey  transportKey    // 构建 transport 时的策略和代理
+	transportLock sync.Mutex      // 保护 transport 和 transportKey
+}
+
+// transportKey holds the config fields a transport is built from, the cached transport is
+// rebuilt when any of them changes.
+type transportKey struct {
+	allowedHosts        string
+	deniedHosts         string
+	blockPrivateNetwork bool
+	proxy               string
+}
+
+// NewHttpFetchServer creates a new HttpFetchServer with the default configuration.
+func NewHttpFetchServer(ctx context.Context) (abstract.Service, error) {
+	base, err := abstract.NewServiceBase(ctx, HttpFetchServerName)
+	if err != nil {
+		return nil, err
+	}
+
+	hs := &HttpFetchServer{
+		MLService: base,
+		config:    NewHttpFetchConfig(),
+	}
+	if err := hs.InitResources(); err != nil {
+		return nil, err
+	}
+	return hs, nil
+}
+
+// RegisterTools registers the prompt and the tools of the HTTP fetch service.
+func (hs *HttpFetchServer) RegisterTools() error {
+	hs.AddPrompt(abstract.PromptEntry{
+		PromptVar: mcp.Prompt{
+			Name:        "httpfetch_prompt",
+			Description: "Get the relevant functions and prompts of the HttpFetch MCP Server.",
+		},
+		HandlerFunc: hs.handlePrompt,
+	})
+
+	hs.AddTool(mcp.NewTool(
+		"http_request",
+		mcp.WithDescription("Send an HTTP request and return the status code, response headers and body. JSON bodies are pretty-printed, large bodies are truncated."),
+		mcp.WithString("url",
+			mcp.Description("URL to request, http or https"),
+			mcp.Required(),
+		),
+		mcp.WithString("method",
+			mcp.Description("HTTP method (default: GET)"),
+			mcp.Enum(http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead, http.MethodOptions),
+		),
+		mcp.WithObject("headers",
+			mcp.Description("Request headers as a map of header name to value"),
+		),
+		withAnyValue("body", "Request body, a string is sent as-is, an object or array is sent as JSON"),
+		mcp.WithNumber("timeout",
+			mcp.Description(fmt.Sprintf("Timeout in seconds (default: %d)", hs.cfg().Timeout)),
+		),
+		mcp.WithBoolean("follow_redirects",
+			mcp.Description("Follow redirects (default: true)"),
+		),
+		mcp.WithNumber("max_redirects",
+			mcp.Description(fmt.Sprintf("Maximum number of redirects to follow (default: %d)", hs.cfg().MaxRedirects)),
+		),
+		mcp.WithBoolean("cookies",
+			mcp.Description("Keep cookies set by responses for the redirects of this call (default: false)"),
+		),
+	), hs.handleHttpRequest)
+	return nil
+}
+
+// withAnyValue adds a property that accepts any JSON value.
+func withAnyValue(name, description string) mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		t.InputSchema.Properties[name] = map[string]any{
+			"description": description,
+		}
+	}
+}
+
+func (hs *HttpFetchServer) handlePrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	return &mcp.GetPromptResult{
+		Description: "",
+		Messages: []mcp.PromptMessage{
+			{
+				Role: mcp.RoleUser,
+				Content: mcp.TextContent{
+					Type: "text",
+					Text: hs.cfg().prompt,
+				},
+			},
+		},
+	}, nil
+}
+
+// handleHttpRequest handles the http_request tool.
+func (hs *HttpFetchServer) handleHttpRequest(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	rawURL, ok := args["url"].(string)
+	if !ok || rawURL == "" {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, "url must be a non-empty string", ""), nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("invalid http(s) url: %s", rawURL), ""), nil
+	}
+
+	method := http.MethodGet
+	if m, ok := args["method"].(string); ok && m != "" {
+		method = strings.ToUpper(m)
+	}
+
+	headers := make(map[string]string)
+	if rawHeaders, ok := args["headers"]; ok && rawHeaders != nil {
+		hm, ok := rawHeaders.(map[string]interface{})
+		if !ok {
+			return comm.ToolError(comm.ErrCodeInvalidArgument, "headers must be an object", ""), nil
+		}
+		for k, v := range hm {
+			headers[k] = fmt.Sprint(v)
+		}
+	}
+
+	body, isJSON, err := requestBody(args["body"])
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+
+	timeout := time.Duration(hs.cfg().Timeout) * time.Second
+	if t, ok := args["timeout"].(float64); ok && t > 0 {
+		timeout = time.Duration(t * float64(time.Second))
+	}
+	followRedirects := true
+	if f, ok := args["follow_redirects"].(bool); ok {
+		followRedirects = f
+	}
+	maxRedirects := hs.cfg().MaxRedirects
+	if m, ok := args["max_redirects"].(float64); ok && m >= 0 {
+		maxRedirects = int(m)
+	}
+	useCookies, _ := args["cookies"].(bool)
+
+	policy := hs.cfg().hostPolicy()
+	if err := policy.Check(ctx, u.Host); err != nil {
+		return comm.ToolError(comm.ErrCodePermissionDenied, err.Error(), ""), nil
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, method, u.String(), body)
+	if err != nil {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, "failed to create the request", err.Error()), nil
+	}
+	req.Header.Set("User-Agent", hs.cfg().UserAgent)
+	if isJSON {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range hs.cfg().headersForHost(u.Hostname()) {
+		req.Header.Set(k, v)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	client := &http.Client{
+		CheckRedirect: hs.checkRedirect(policy, followRedirects, maxRedirects),
+	}
+	// 配置了代理或禁止访问内网时使用独立的 Transport，否则沿用默认 Transport（读取 HTTP_PROXY 等环境变量）
+	transport, err := hs.transportFor(hs.cfg())
+	if err != nil {
+		return comm.ToolErrorFromErr("invalid proxy", err), nil
+	}
+	if transport != nil {
+		client.Transport = transport
+	}
+	if useCookies {
+		jar, err := cookiejar.New(nil)
+		if err == nil {
+			client.Jar = jar
+		}
+	}
+
+	hs.Logger.Debug().Str("method", method).Str("url", u.String()).Msg("sending http request")
+	resp, err := client.Do(req)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrHostDenied), errors.Is(err, utils.ErrHostNotAllowed), errors.Is(err, utils.ErrPrivateNetwork):
+			return comm.ToolError(comm.ErrCodePermissionDenied, err.Error(), ""), nil
+		case errors.Is(err, errTooManyRedirects):
+			return comm.ToolError(comm.ErrCodeInternal, errTooManyRedirects.Error(), err.Error()), nil
+		case errors.Is(err, context.DeadlineExceeded):
+			return comm.ToolError(comm.ErrCodeTimeout, fmt.Sprintf("request timed out after %s", timeout), ""), nil
+		default:
+			return comm.ToolErrorFromErr("request failed", err), nil
+		}
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(hs.cfg().MaxResponseSize)+1))
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to read the response body", err), nil
+	}
+	truncated := len(data) > hs.cfg().MaxResponseSize
+	if truncated {
+		data = data[:hs.cfg().MaxResponseSize]
+	}
+
+	return mcp.NewToolResultText(formatResponse(resp, data, truncated, hs.cfg().MaxResponseSize)), nil
+}
+
+// transportFor returns the transport for the policy and the proxy of cfg, nil means the default
+// transport. The transport is cached, when cfg asks for another one the idle connections of the
+// previous one are closed.
+func (hs *HttpFetchServer) transportFor(cfg *HttpFetchConfig) (*http.Transport, error) {
+	key := transportKey{
+		allowedHosts:        cfg.AllowedHosts,
+		deniedHosts:         cfg.DeniedHosts,
+		blockPrivateNetwork: cfg.BlockPrivateNetwork,
+		proxy:               cfg.Proxy,
+	}
+	hs.transportLock.Lock()
+	defer hs.transportLock.Unlock()
+	if hs.transport != nil && hs.transportKey == key {
+		return hs.transport, nil
+	}
+	proxy, err := cfg.proxyURL()
+	if err != nil {
+		return nil, err
+	}
+	if hs.transport != nil {
+		hs.transport.CloseIdleConnections()
+		hs.transport = nil
+	}
+	if proxy == nil && !cfg.BlockPrivateNetwork {
+		return nil, nil
+	}
+	hs.transport = newTransport(cfg.hostPolicy(), proxy)
+	hs.transportKey = key
+	return hs.transport, nil
+}
+
+// newTransport clones the default transport with proxy, nil keeps the proxy of the environment.
+// The host policy is enforced on every connection, including those of redirects. Through a proxy
+// the proxy resolves the target, only its name is checked, and the proxy itself may be private.
+func newTransport(policy utils.HostPolicy, proxy *url.URL) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxy != nil {
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	var proxies sync.Map
+	proxyFunc := transport.Proxy
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		u, err := proxyFunc(req)
+		if u != nil {
+			proxies.Store(proxyAddr(u), true)
+		}
+		return u, err
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport.DialContext = policy.DialContext(dialer, func(addr string) bool {
+		_, ok := proxies.Load(addr)
+		return ok
+	})
+	return transport
+}
+
+// proxyAddr returns the host:port the transport dials for the proxy u.
+func proxyAddr(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return net.JoinHostPort(u.Hostname(), port)
+	}
+	port := "80"
+	switch u.Scheme {
+	case "https":
+		port = "443"
+	case "socks5", "socks5h":
+		port = "1080"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// checkRedirect builds the redirect policy of a single call. Every redirect target is checked
+// against the host policy, and headers injected for the original host are not forwarded to other hosts.
+func (hs *HttpFetchServer) checkRedirect(policy utils.HostPolicy, follow bool, maxRedirects int) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if !follow {
+			return http.ErrUseLastResponse
+		}
+		if len(via) > maxRedirects {
+			return fmt.Errorf("%w: stopped after %d redirects", errTooManyRedirects, maxRedirects)
+		}
+		if err := policy.Check(req.Context(), req.URL.Host); err != nil {
+			return err
+		}
+		prevHost := via[len(via)-1].URL.Hostname()
+		if !strings.EqualFold(prevHost, req.URL.Hostname()) {
+			for k := range hs.cfg().headersForHost(prevHost) {
+				req.Header.Del(k)
+			}
+			for k, v := range hs.cfg().headersForHost(req.URL.Hostname()) {
+				req.Header.Set(k, v)
+			}
+		}
+		return nil
+	}
+}
+
+// requestBody converts the body argument into a reader, objects and arrays are encoded as JSON.
+func requestBody(raw interface{}) (io.Reader, bool, error) {
+	switch b := raw.(type) {
+	case nil:
+		return nil, false, nil
+	case string:
+		if b == "" {
+			return nil, false, nil
+		}
+		return strings.NewReader(b), false, nil
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to encode body as JSON: %v", err)
+		}
+		return bytes.NewReader(data), true, nil
+	default:
+		return nil, false, fmt.Errorf("body must be a string, an object or an array, got %T", raw)
+	}
+}
+
+// formatResponse renders the status line, headers and body of a response.
+func formatResponse(resp *http.Response, body []byte, truncated bool, limit int) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Status: %s\n", resp.Status))
+	sb.WriteString(fmt.Sprintf("Status Code: %d\n", resp.StatusCode))
+	if resp.Request != nil && resp.Request.URL != nil {
+		sb.WriteString(fmt.Sprintf("URL: %s\n", resp.Request.URL.String()))
+	}
+
+	sb.WriteString("Headers:\n")
+	keys := make([]string, 0, len(resp.Header))
+	for k := range resp.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		sb.WriteString(fmt.Sprintf("  %s: %s\n", k, strings.Join(resp.Header[k], ", ")))
+	}
+
+	sb.WriteString("\nBody:\n")
+	if !truncated && isJSONContentType(resp.Header.Get("Content-Type")) {
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, body, "", "  "); err == nil {
+			body = pretty.Bytes()
+		}
+	}
+	sb.Write(body)
+	if truncated {
+		sb.WriteString(fmt.Sprintf("\n\n[body truncated: only the first %d bytes are shown]", limit))
+	}
+	return sb.String()
+}
+
+// isJSONContentType reports whether the content type describes a JSON document.
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// Config returns the configuration of the service as a string.
+func (hs *HttpFetchServer) Config() string {
+	cfg, err := json.Marshal(hs.cfg())
+	if err != nil {
+		hs.Logger.Err(err).Msg("failed to marshal config")
+		return "{}"
+	}
+	return string(cfg)
+}
+
+func (hs *HttpFetchServer) Name() comm.MoLingServerType {
+	return HttpFetchServerName
+}
+
+func (hs *HttpFetchServer) Close() error {
+	hs.transportLock.Lock()
+	if hs.transport != nil {
+		hs.transport.CloseIdleConnections()
+		hs.transport = nil
+	}
+	hs.transportLock.Unlock()
+	hs.Logger.Debug().Msg("HttpFetchServer closed")
+	return nil
+}
+
+// LoadConfig loads the configuration from a JSON object.
+func (hs *HttpFetchServer) LoadConfig(jsonData map[string]interface{}) error {
+	// 在副本上合并和校验，校验通过后才替换正在使用的配置
+	next := *hs.cfg()
+	err := utils.MergeJSONToStruct(&next, jsonData)
+	if err != nil {
+		return err
+	}
+	if err := next.Check(); err != nil {
+		return err
+	}
+	hs.configLock.Lock()
+	hs.config = &next
+	hs.configLock.Unlock()
+	return nil
+}
+
+// cfg returns the running config, LoadConfig replaces it as a whole instead of changing it.
+func (hs *HttpFetchServer) cfg() *HttpFetchConfig {
+	hs.configLock.RLock()
+	defer hs.configLock.RUnlock()
+	return hs.config
+}
@@ -0,0 +1,150 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package httpfetch
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/gojue/moling/pkg/utils"
+)
+
+const (
+	// HttpFetchPromptDefault is the default prompt for the HTTP fetch service.
+	HttpFetchPromptDefault = `
+You are an HTTP API assistant capable of calling web APIs directly without launching a browser. Your capabilities include:
+
+1. **HTTP Requests**: Send GET, POST, PUT, PATCH, DELETE, HEAD and OPTIONS requests to a URL.
+
+2. **Request Customization**:
+   - Set custom request headers
+   - Send a string body, or a JSON object/array that is encoded automatically
+   - Control the timeout, redirect following and the maximum number of redirects
+   - Keep cookies across redirects within a single call
+
+3. **Response Inspection**:
+   - Read the status code and response headers
+   - Read the response body, JSON bodies are pretty-printed
+   - Large bodies are truncated, the result tells you when this happens
+
+Requests may be routed through a proxy configured by the server. Some hosts may be blocked by the server configuration, private network addresses are blocked by default. Prefer this tool over the browser for JSON APIs and plain HTTP resources. Report back with the status code and the relevant parts of the response.
+`
+)
+
+// HttpFetchConfig represents the configuration for the HTTP fetch service.
+type HttpFetchConfig struct {
+	PromptFile          string `json:"prompt_file"` // PromptFile is the prompt file for the HTTP fetch service.
+	prompt              string
+	UserAgent           string                       `json:"user_agent"`            // UserAgent is the default User-Agent header.
+	Timeout             int                          `json:"timeout"`               // Timeout is the default request timeout. time.Second
+	MaxRedirects        int                          `json:"max_redirects"`         // MaxRedirects is the default maximum number of redirects to follow.
+	MaxResponseSize     int                          `json:"max_response_size"`     // MaxResponseSize is the maximum number of body bytes returned, larger bodies are truncated.
+	AllowedHosts        string                       `json:"allowed_hosts"`         // AllowedHosts is a list of allowed hosts. split by comma. e.g. api.github.com,*.example.com
+	DeniedHosts         string                       `json:"denied_hosts"`          // DeniedHosts is a list of denied hosts. split by comma.
+	BlockPrivateNetwork bool                         `json:"block_private_network"` // BlockPrivateNetwork denies loopback, private and link-local addresses unless explicitly allowed.
+	HostHeaders         map[string]map[string]string `json:"host_headers"`          // HostHeaders injects headers per host, values may reference env vars, e.g. {"api.github.com": {"Authorization": "Bearer ${GITHUB_TOKEN}"}}
+	Proxy               string                       `json:"proxy"`                 // Proxy is the proxy URL for all requests, http, https or socks5. e.g. http://127.0.0.1:7890, empty uses HTTP_PROXY/HTTPS_PROXY.
+}
+
+// NewHttpFetchConfig creates a new HttpFetchConfig with default values.
+func NewHttpFetchConfig() *HttpFetchConfig {
+	return &HttpFetchConfig{
+		prompt:              HttpFetchPromptDefault,
+		UserAgent:           "MoLing-HttpFetch/1.0",
+		Timeout:             30,
+		MaxRedirects:        10,
+		MaxResponseSize:     1024 * 1024, // 1MB
+		BlockPrivateNetwork: true,
+		HostHeaders:         map[string]map[string]string{},
+	}
+}
+
+// Check validates the HttpFetchConfig.
+func (hc *HttpFetchConfig) Check() error {
+	hc.prompt = HttpFetchPromptDefault
+	if hc.Timeout <= 0 {
+		return fmt.Errorf("timeout must be greater than 0")
+	}
+	if hc.MaxRedirects < 0 {
+		return fmt.Errorf("max redirects must not be negative")
+	}
+	if hc.MaxResponseSize <= 0 {
+		return fmt.Errorf("max response size must be greater than 0")
+	}
+	if _, err := hc.proxyURL(); err != nil {
+		return err
+	}
+	if hc.PromptFile != "" {
+		read, err := os.ReadFile(hc.PromptFile)
+		if err != nil {
+			return fmt.Errorf("failed to read prompt file:%s, error: %v", hc.PromptFile, err)
+		}
+		hc.prompt = string(read)
+	}
+	return nil
+}
+
+// hostPolicy returns the host policy built from the allow and deny lists.
+func (hc *HttpFetchConfig) hostPolicy() utils.HostPolicy {
+	return utils.NewHostPolicy(hc.AllowedHosts, hc.DeniedHosts, hc.BlockPrivateNetwork)
+}
+
+// proxyURL parses the configured proxy, it returns nil when no proxy is set.
+func (hc *HttpFetchConfig) proxyURL() (*url.URL, error) {
+	if hc.Proxy == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(hc.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %q: %v", hc.Proxy, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy %q: scheme must be http, https or socks5", hc.Proxy)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q: missing host", hc.Proxy)
+	}
+	return u, nil
+}
+
+// headersForHost returns the injected headers for host with environment variables expanded.
+func (hc *HttpFetchConfig) headersForHost(host string) map[string]string {
+	headers := make(map[string]string)
+	for pattern, hs := range hc.HostHeaders {
+		if !hostMatches(pattern, host) {
+			continue
+		}
+		for k, v := range hs {
+			headers[k] = os.ExpandEnv(v)
+		}
+	}
+	return headers
+}
+
+// hostMatches reports whether host matches pattern, "*.example.com" matches any subdomain.
+func hostMatches(pattern, host string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	host = strings.ToLower(host)
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return pattern == host
+}
@@ -17,19 +17,34 @@
 package services
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/config"
 	"github.com/gojue/moling/pkg/services/abstract"
 	"github.com/gojue/moling/pkg/services/browser"
+	"github.com/gojue/moling/pkg/services/clipboard"
 	"github.com/gojue/moling/pkg/services/command"
 	"github.com/gojue/moling/pkg/services/filesystem"
+	"github.com/gojue/moling/pkg/services/httpfetch"
+	"github.com/gojue/moling/pkg/services/screen"
+	"github.com/gojue/moling/pkg/services/system"
 )
 
 var serviceLists = make(map[comm.MoLingServerType]abstract.ServiceFactory)
 
-// RegisterServ register service
+// RegisterServ register service. It panics when a service with the same name, compared
+// case-insensitively like --module, is already registered, so that one service can't silently
+// replace another.
 func RegisterServ(n comm.MoLingServerType, f abstract.ServiceFactory) {
-	//serviceLists = append(, f)
+	for name := range serviceLists {
+		if strings.EqualFold(string(name), string(n)) {
+			panic(fmt.Sprintf("services: RegisterServ called twice for service %s", n))
+		}
+	}
 	serviceLists[n] = f
+	config.RegisterModule(string(n))
 }
 
 // ServiceList  get service lists
@@ -44,4 +59,12 @@ func init() {
 	RegisterServ(command.CommandServerName, command.NewCommandServer)
 	// 文件系统操作工具
 	RegisterServ(filesystem.FilesystemServerName, filesystem.NewFilesystemServer)
+	// HTTP 请求工具
+	RegisterServ(httpfetch.HttpFetchServerName, httpfetch.NewHttpFetchServer)
+	// 系统信息工具
+	RegisterServ(system.SystemServerName, system.NewSystemServer)
+	// 剪贴板读写工具
+	RegisterServ(clipboard.ClipboardServerName, clipboard.NewClipboardServer)
+	// 桌面截图工具
+	RegisterServ(screen.ScreenServerName, screen.NewScreenServer)
 }
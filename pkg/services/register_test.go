@@ -0,0 +1,63 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/services/abstract"
+	"github.com/gojue/moling/pkg/services/browser"
+)
+
+func TestServiceList(t *testing.T) {
+	var browsers []abstract.ServiceFactory
+	for name, factory := range ServiceList() {
+		if strings.EqualFold(string(name), string(browser.BrowserServerName)) {
+			browsers = append(browsers, factory)
+		}
+	}
+	if len(browsers) != 1 {
+		t.Fatalf("Expected exactly one Browser factory, got %d", len(browsers))
+	}
+	if reflect.ValueOf(browsers[0]).Pointer() != reflect.ValueOf(abstract.ServiceFactory(browser.NewBrowserServer)).Pointer() {
+		t.Error("Expected the Browser factory to be browser.NewBrowserServer")
+	}
+}
+
+func TestRegisterServDuplicate(t *testing.T) {
+	count := len(ServiceList())
+	for _, name := range []comm.MoLingServerType{browser.BrowserServerName, "BROWSER"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected RegisterServ(%s) to panic for a registered name", name)
+				}
+			}()
+			RegisterServ(name, browser.NewBrowserServer)
+		}()
+	}
+	if len(ServiceList()) != count {
+		t.Errorf("Expected the service list unchanged, got %d services", len(ServiceList()))
+	}
+}
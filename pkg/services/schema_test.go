@@ -0,0 +1,108 @@
+/*
+ *
+ *  Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ *  Repository: https://github.com/gojue/moling
+ *
+ */
+
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// TestConfigSchema validates the config schema of each service and the default config of the service against it.
+func TestConfigSchema(t *testing.T) {
+	_, ctx, err := comm.InitTestEnv()
+	if err != nil {
+		t.Fatalf("Failed to initialize test environment: %v", err)
+	}
+	schemas := make(map[comm.MoLingServerType]map[string]interface{})
+	for name, factory := range ServiceList() {
+		srv, err := factory(ctx)
+		if err != nil {
+			t.Fatalf("Failed to create service %s: %v", name, err)
+		}
+		schema := srv.ConfigSchema()
+		if schema == "" {
+			continue
+		}
+		doc, err := jsonschema.UnmarshalJSON(strings.NewReader(schema))
+		if err != nil {
+			t.Fatalf("Invalid schema JSON of %s: %v", name, err)
+		}
+		c := jsonschema.NewCompiler()
+		if err := c.AddResource("schema.json", doc); err != nil {
+			t.Fatalf("Failed to add schema of %s: %v", name, err)
+		}
+		sch, err := c.Compile("schema.json")
+		if err != nil {
+			t.Fatalf("Failed to compile schema of %s: %v", name, err)
+		}
+		inst, err := jsonschema.UnmarshalJSON(strings.NewReader(srv.Config()))
+		if err != nil {
+			t.Fatalf("Invalid config JSON of %s: %v", name, err)
+		}
+		if err := sch.Validate(inst); err != nil {
+			t.Errorf("Config of %s does not match its schema: %v", name, err)
+		}
+		var parsed struct {
+			Properties map[string]interface{} `json:"properties"`
+		}
+		if err := json.Unmarshal([]byte(schema), &parsed); err != nil {
+			t.Fatalf("Invalid schema JSON of %s: %v", name, err)
+		}
+		schemas[name] = parsed.Properties
+	}
+
+	propType := func(service comm.MoLingServerType, path ...string) interface{} {
+		props := schemas[service]
+		for i, name := range path {
+			prop, _ := props[name].(map[string]interface{})
+			if i == len(path)-1 {
+				return prop["type"]
+			}
+			props, _ = prop["properties"].(map[string]interface{})
+		}
+		return nil
+	}
+	for _, tc := range []struct {
+		service comm.MoLingServerType
+		path    []string
+		want    string
+	}{
+		{"Browser", []string{"headless"}, "boolean"},
+		{"Browser", []string{"timeout"}, "integer"},
+		{"Browser", []string{"user_agent"}, "string"},
+		{"Command", []string{"allowed_command"}, "string"},
+		{"Command", []string{"secrets"}, "object"},
+		{"FileSystem", []string{"allowed_read_dirs"}, "array"},
+		{"FileSystem", []string{"ocr", "backend"}, "string"},
+	} {
+		if got := propType(tc.service, tc.path...); got != tc.want {
+			t.Errorf("Expected %s %s to be %s, got %v", tc.service, strings.Join(tc.path, "."), tc.want, got)
+		}
+	}
+	backend := schemas["FileSystem"]["ocr"].(map[string]interface{})["properties"].(map[string]interface{})["backend"].(map[string]interface{})
+	if _, ok := backend["enum"]; !ok {
+		t.Error("Expected an enum for the OCR backend")
+	}
+}
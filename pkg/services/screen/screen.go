@@ -0,0 +1,231 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+// Package screen provides the screen_capture tool to take screenshots of the desktop.
+package screen
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/config"
+	"github.com/gojue/moling/pkg/services/abstract"
+	"github.com/gojue/moling/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	ScreenServerName comm.MoLingServerType = "Screen"
+)
+
+// ScreenServer implements the Service interface and provides the screen_capture tool.
+type ScreenServer struct {
+	abstract.MLService
+	config     *ScreenConfig           // 重新加载时整体替换，处理函数通过 cfg 读取
+	configLock sync.RWMutex            // 保护 config
+	capturer   Capturer                // 系统截屏，测试时可替换
+	getenv     func(key string) string // 检查图形会话的环境变量，测试时可替换
+	goos       string
+}
+
+// NewScreenServer creates a new ScreenServer with the default configuration, screenshots are
+// saved to BasePath/data.
+func NewScreenServer(ctx context.Context) (abstract.Service, error) {
+	base, err := abstract.NewServiceBase(ctx, ScreenServerName)
+	if err != nil {
+		return nil, err
+	}
+
+	ss := &ScreenServer{
+		MLService: base,
+		config:    NewScreenConfig(filepath.Join(base.MlConfig().BasePath, "data")),
+		capturer:  systemCapturer{},
+		getenv:    os.Getenv,
+		goos:      runtime.GOOS,
+	}
+	if err := ss.InitResources(); err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+// RegisterTools registers the prompt and the tools of the screen service, nothing when the
+// service is disabled.
+func (ss *ScreenServer) RegisterTools() error {
+	if ss.cfg().Disabled {
+		ss.Logger.Info().Msg("screen service is disabled, no tools registered")
+		return nil
+	}
+	ss.AddPrompt(abstract.PromptEntry{
+		PromptVar: mcp.Prompt{
+			Name:        "screen_prompt",
+			Description: "Get the relevant functions and prompts of the Screen MCP Server.",
+		},
+		HandlerFunc: ss.handlePrompt,
+	})
+
+	ss.AddTool(mcp.NewTool(
+		"screen_capture",
+		mcp.WithDescription("Take a screenshot of the desktop, spanning all displays, or of one display, and save it to the data directory. Returns the path of the file, and the image itself with inline."),
+		mcp.WithNumber("display",
+			mcp.Description("Index of the display to capture, 0 is the primary display (default: all displays)"),
+		),
+		mcp.WithString("name",
+			mcp.Description("Base name of the saved file, a random number and the extension are appended (default: screen)"),
+		),
+		mcp.WithBoolean("inline",
+			mcp.Description(fmt.Sprintf("Also return the image, unless it is larger than max_inline_image_bytes (default: false, format: %s)", ss.cfg().Format)),
+		),
+	), ss.handleCapture)
+	return nil
+}
+
+func (ss *ScreenServer) handlePrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	return &mcp.GetPromptResult{
+		Description: "",
+		Messages: []mcp.PromptMessage{
+			{
+				Role: mcp.RoleUser,
+				Content: mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf(ss.cfg().prompt, ss.MlConfig().SystemInfo),
+				},
+			},
+		},
+	}, nil
+}
+
+func (ss *ScreenServer) handleCapture(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	index := -1
+	if v, ok := args["display"].(float64); ok {
+		if v < 0 || v != float64(int(v)) {
+			return comm.ToolError(comm.ErrCodeInvalidArgument, "display must be a non-negative integer", ""), nil
+		}
+		index = int(v)
+	}
+	name, _ := args["name"].(string)
+	inline, _ := args["inline"].(bool)
+	file, err := captureFileName(ss.cfg().DataPath, name, ss.cfg().Format)
+	if err != nil {
+		return comm.ArgumentError(err), nil
+	}
+
+	if err := checkDisplay(ss.goos, ss.getenv); err != nil {
+		return comm.ToolErrorFromErr("failed to capture the screen", err), nil
+	}
+	bounds, err := captureBounds(ss.capturer, index)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to capture the screen", err), nil
+	}
+	img, err := ss.capturer.Capture(bounds)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to capture the screen", err), nil
+	}
+	data, mimeType, err := encodeImage(img, ss.cfg().Format, ss.cfg().Quality)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to encode the screenshot", err), nil
+	}
+
+	if err := utils.CreateDirectory(ss.cfg().DataPath); err != nil {
+		return comm.ToolErrorFromErr("failed to create the data directory", err), nil
+	}
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		return comm.ToolErrorFromErr("failed to save the screenshot", err), nil
+	}
+	ss.Logger.Debug().Str("path", file).Int("bytes", len(data)).Msg("screenshot saved")
+
+	what := "the desktop"
+	if index >= 0 {
+		what = fmt.Sprintf("display %d", index)
+	}
+	lines := []string{fmt.Sprintf("Captured %s (%dx%d at %d,%d) to %s (%s, %d bytes)",
+		what, bounds.Dx(), bounds.Dy(), bounds.Min.X, bounds.Min.Y, file, mimeType, len(data))}
+	if inline && len(data) > ss.cfg().MaxInlineImageBytes {
+		lines = append(lines, fmt.Sprintf("Warning: the screenshot has %d bytes, more than max_inline_image_bytes %d, it is only saved to the file", len(data), ss.cfg().MaxInlineImageBytes))
+		inline = false
+	}
+	result := &mcp.CallToolResult{Content: []mcp.Content{mcp.NewTextContent(strings.Join(lines, "\n"))}}
+	if inline {
+		result.Content = append(result.Content, mcp.NewImageContent(base64.StdEncoding.EncodeToString(data), mimeType))
+	}
+	return result, nil
+}
+
+// ConfigSchema returns the JSON Schema of the Screen configuration with its default values.
+func (ss *ScreenServer) ConfigSchema() string {
+	schema, err := config.GenerateSchema("Screen", NewScreenConfig(filepath.Join(ss.MlConfig().BasePath, "data")))
+	if err != nil {
+		ss.Logger.Err(err).Msg("failed to generate config schema")
+		return ""
+	}
+	return schema
+}
+
+func (ss *ScreenServer) Config() string {
+	cfg, err := json.Marshal(ss.cfg())
+	if err != nil {
+		ss.Logger.Err(err).Msg("failed to marshal config")
+		return "{}"
+	}
+	return string(cfg)
+}
+
+func (ss *ScreenServer) Name() comm.MoLingServerType {
+	return ScreenServerName
+}
+
+func (ss *ScreenServer) Close() error {
+	ss.Logger.Debug().Msg("ScreenServer closed")
+	return nil
+}
+
+// LoadConfig loads the configuration from a JSON object.
+func (ss *ScreenServer) LoadConfig(jsonData map[string]interface{}) error {
+	// 在副本上合并和校验，校验通过后才替换正在使用的配置
+	next := *ss.cfg()
+	err := utils.MergeJSONToStruct(&next, jsonData)
+	if err != nil {
+		return err
+	}
+	if err := next.Check(); err != nil {
+		return err
+	}
+	ss.configLock.Lock()
+	ss.config = &next
+	ss.configLock.Unlock()
+	return nil
+}
+
+// cfg returns the running config, LoadConfig replaces it as a whole instead of changing it.
+func (ss *ScreenServer) cfg() *ScreenConfig {
+	ss.configLock.RLock()
+	defer ss.configLock.RUnlock()
+	return ss.config
+}
+
+// RestartOnlyFields returns the fields read when the tools are registered.
+func (ss *ScreenServer) RestartOnlyFields() []string {
+	return []string{"disabled"}
+}
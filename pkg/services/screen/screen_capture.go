@@ -0,0 +1,131 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package screen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"path/filepath"
+	"strings"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/kbinani/screenshot"
+)
+
+var (
+	// ErrNoDisplay is returned on Linux and the BSDs without an X11 or Wayland session, e.g. on a
+	// headless server or over SSH.
+	ErrNoDisplay = comm.NewCodedError(comm.ErrCodeNotFound, errors.New("no graphical display, DISPLAY and WAYLAND_DISPLAY are not set; screen_capture needs a desktop session"))
+	// ErrNoActiveDisplay is returned when the desktop reports no display.
+	ErrNoActiveDisplay = comm.NewCodedError(comm.ErrCodeNotFound, errors.New("no active display found"))
+)
+
+// Capturer grabs the screen, tests replace it.
+type Capturer interface {
+	// NumDisplays returns the number of active displays.
+	NumDisplays() int
+	// DisplayBounds returns the bounds of the display in desktop coordinates.
+	DisplayBounds(index int) image.Rectangle
+	// Capture grabs the region of the desktop.
+	Capture(rect image.Rectangle) (*image.RGBA, error)
+}
+
+// systemCapturer captures the desktop of the current session.
+type systemCapturer struct{}
+
+func (systemCapturer) NumDisplays() int { return screenshot.NumActiveDisplays() }
+func (systemCapturer) DisplayBounds(index int) image.Rectangle {
+	return screenshot.GetDisplayBounds(index)
+}
+func (systemCapturer) Capture(rect image.Rectangle) (*image.RGBA, error) {
+	return screenshot.CaptureRect(rect)
+}
+
+// checkDisplay returns ErrNoDisplay when goos needs an X11 or Wayland display and none is set.
+// macOS and Windows always have one.
+func checkDisplay(goos string, getenv func(string) string) error {
+	switch goos {
+	case "darwin", "windows":
+		return nil
+	}
+	if getenv("DISPLAY") != "" || getenv("WAYLAND_DISPLAY") != "" {
+		return nil
+	}
+	return ErrNoDisplay
+}
+
+// captureBounds returns the region to capture: the display with the index, or with index -1
+// the union of all displays.
+func captureBounds(c Capturer, index int) (image.Rectangle, error) {
+	n := c.NumDisplays()
+	if n <= 0 {
+		return image.Rectangle{}, ErrNoActiveDisplay
+	}
+	if index >= n {
+		return image.Rectangle{}, comm.NewCodedError(comm.ErrCodeInvalidArgument, fmt.Errorf("display %d does not exist, %d displays are active (0 to %d)", index, n, n-1))
+	}
+	if index >= 0 {
+		return c.DisplayBounds(index), nil
+	}
+	var all image.Rectangle
+	for i := 0; i < n; i++ {
+		all = all.Union(c.DisplayBounds(i))
+	}
+	return all, nil
+}
+
+// encodeImage encodes the screenshot in the format and returns the data and the MIME type.
+func encodeImage(img image.Image, format string, quality int) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if format == FormatJPEG {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/png", nil
+}
+
+// captureFileName returns a unique path under dir for a screenshot, named like the screenshots
+// of the browser: the name without an image extension, a random number and the extension of
+// the format. The name must not contain directories.
+func captureFileName(dir, name, format string) (string, error) {
+	if name == "" {
+		name = "screen"
+	}
+	if name != filepath.Base(name) || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("name must be a file name without directories, got %q", name)
+	}
+	switch ext := filepath.Ext(name); strings.ToLower(ext) {
+	case ".png", ".jpg", ".jpeg":
+		name = strings.TrimSuffix(name, ext)
+	}
+	ext := ".png"
+	if format == FormatJPEG {
+		ext = ".jpg"
+	}
+	// 使用随机数确保文件名唯一，扩展名与图片格式一致
+	return filepath.Join(dir, fmt.Sprintf("%s_%d%s", name, rand.Int(), ext)), nil
+}
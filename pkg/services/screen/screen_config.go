@@ -0,0 +1,91 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package screen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// ScreenPromptDefault is the default prompt for the screen service.
+	ScreenPromptDefault = `
+You are a desktop assistant running on %s. You can take screenshots of the user's screen to see what is displayed. Your capabilities include:
+
+1. **Capture the Screen**: Take a screenshot of the whole desktop, spanning all displays, or of one display by its index (0 is the primary display). The image is saved to a file, and can be returned inline to look at it.
+
+Screenshots may contain private information, only capture the screen when the task needs it and tell the user where the file was saved.
+`
+)
+
+// 截图保存的格式
+const (
+	FormatPNG  = "png"
+	FormatJPEG = "jpeg"
+)
+
+// ScreenConfig represents the configuration for the screen service.
+type ScreenConfig struct {
+	PromptFile          string `json:"prompt_file" desc:"File whose content replaces the default prompt of the screen service"` // PromptFile is the prompt file for the screen service.
+	prompt              string
+	Disabled            bool   `json:"disabled" desc:"Disable the screen service, no tools are registered"`                                                  // Disabled turns the service off, e.g. on shared machines.
+	Format              string `json:"format" desc:"Image format of the screenshots" enum:"png,jpeg"`                                                        // Format is png or jpeg.
+	Quality             int    `json:"quality" desc:"JPEG quality of the screenshots, 1 to 100"`                                                             // Quality is the JPEG quality, ignored for png.
+	DataPath            string `json:"data_path" desc:"Directory the screenshots are saved to"`                                                              // DataPath is the directory of the saved screenshots.
+	MaxInlineImageBytes int    `json:"max_inline_image_bytes" desc:"Largest screenshot screen_capture returns inline, larger ones are only saved to a file"` // MaxInlineImageBytes is the largest screenshot returned as image content, before base64 encoding.
+}
+
+// NewScreenConfig creates a new ScreenConfig with default values, screenshots are saved to
+// dataPath.
+func NewScreenConfig(dataPath string) *ScreenConfig {
+	return &ScreenConfig{
+		prompt:              ScreenPromptDefault,
+		Format:              FormatPNG,
+		Quality:             90,
+		DataPath:            dataPath,
+		MaxInlineImageBytes: 2 * 1024 * 1024,
+	}
+}
+
+// Check validates the ScreenConfig.
+func (sc *ScreenConfig) Check() error {
+	sc.prompt = ScreenPromptDefault
+	switch sc.Format {
+	case FormatPNG, FormatJPEG:
+	default:
+		return fmt.Errorf("invalid format %q, must be %s or %s", sc.Format, FormatPNG, FormatJPEG)
+	}
+	if sc.Quality < 1 || sc.Quality > 100 {
+		return fmt.Errorf("quality must be between 1 and 100")
+	}
+	if sc.DataPath == "" {
+		return fmt.Errorf("data_path must not be empty")
+	}
+	sc.DataPath = filepath.Clean(sc.DataPath)
+	if sc.MaxInlineImageBytes <= 0 {
+		return fmt.Errorf("max_inline_image_bytes must be greater than 0")
+	}
+	if sc.PromptFile != "" {
+		read, err := os.ReadFile(sc.PromptFile)
+		if err != nil {
+			return fmt.Errorf("failed to read prompt file:%s, error: %v", sc.PromptFile, err)
+		}
+		sc.prompt = string(read)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package screen
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// fakeCapturer is a desktop of two side-by-side displays filled with one color.
+type fakeCapturer struct {
+	displays []image.Rectangle
+	err      error
+	captured []image.Rectangle
+}
+
+func (c *fakeCapturer) NumDisplays() int                        { return len(c.displays) }
+func (c *fakeCapturer) DisplayBounds(index int) image.Rectangle { return c.displays[index] }
+func (c *fakeCapturer) Capture(rect image.Rectangle) (*image.RGBA, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	c.captured = append(c.captured, rect)
+	img := image.NewRGBA(rect)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			img.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	return img, nil
+}
+
+func newTestScreenServer(t *testing.T, cfg map[string]interface{}) (*ScreenServer, *fakeCapturer) {
+	t.Helper()
+	_, ctx, err := comm.InitTestEnv()
+	if err != nil {
+		t.Fatalf("Failed to initialize test environment: %v", err)
+	}
+	svc, err := NewScreenServer(ctx)
+	if err != nil {
+		t.Fatalf("Failed to create ScreenServer: %v", err)
+	}
+	ss := svc.(*ScreenServer)
+	if cfg == nil {
+		cfg = map[string]interface{}{}
+	}
+	cfg["data_path"] = t.TempDir()
+	if err := ss.LoadConfig(cfg); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	fc := &fakeCapturer{displays: []image.Rectangle{image.Rect(0, 0, 40, 30), image.Rect(40, 0, 60, 20)}}
+	ss.capturer = fc
+	ss.goos = "linux"
+	ss.getenv = func(key string) string {
+		if key == "DISPLAY" {
+			return ":0"
+		}
+		return ""
+	}
+	return ss, fc
+}
+
+func callCapture(t *testing.T, ss *ScreenServer, args map[string]interface{}) *mcp.CallToolResult {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "screen_capture"
+	request.Params.Arguments = args
+	result, err := ss.handleCapture(context.Background(), request)
+	if err != nil {
+		t.Fatalf("handleCapture failed: %v", err)
+	}
+	return result
+}
+
+func TestScreenConfigCheck(t *testing.T) {
+	if err := NewScreenConfig(t.TempDir()).Check(); err != nil {
+		t.Errorf("Expected the default config to be valid, got %v", err)
+	}
+	for name, mutate := range map[string]func(c *ScreenConfig){
+		"format":     func(c *ScreenConfig) { c.Format = "gif" },
+		"quality":    func(c *ScreenConfig) { c.Quality = 0 },
+		"quality100": func(c *ScreenConfig) { c.Quality = 101 },
+		"data_path":  func(c *ScreenConfig) { c.DataPath = "" },
+		"max_inline": func(c *ScreenConfig) { c.MaxInlineImageBytes = 0 },
+		"prompt":     func(c *ScreenConfig) { c.PromptFile = filepath.Join(t.TempDir(), "missing.txt") },
+	} {
+		c := NewScreenConfig(t.TempDir())
+		mutate(c)
+		if err := c.Check(); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+	c := NewScreenConfig(t.TempDir())
+	c.Format, c.Quality = FormatJPEG, 50
+	if err := c.Check(); err != nil {
+		t.Errorf("Expected jpeg to be valid, got %v", err)
+	}
+}
+
+func TestCaptureFileName(t *testing.T) {
+	dir := t.TempDir()
+	a, err := captureFileName(dir, "", FormatPNG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := captureFileName(dir, "", FormatPNG)
+	if a == b || filepath.Dir(a) != dir || !strings.HasPrefix(filepath.Base(a), "screen_") || filepath.Ext(a) != ".png" {
+		t.Errorf("Expected unique screen_<n>.png names, got %s and %s", a, b)
+	}
+	// 已有的图片扩展名被替换为格式的扩展名
+	c, _ := captureFileName(dir, "report.png", FormatJPEG)
+	if base := filepath.Base(c); !strings.HasPrefix(base, "report_") || filepath.Ext(base) != ".jpg" || strings.Contains(base, ".png") {
+		t.Errorf("Expected report_<n>.jpg, got %s", base)
+	}
+	for _, name := range []string{"../escape", "a/b", `a\b`, ".."} {
+		if _, err := captureFileName(dir, name, FormatPNG); err == nil {
+			t.Errorf("Expected %q to be rejected", name)
+		}
+	}
+}
+
+func TestScreenCapture(t *testing.T) {
+	t.Run("Desktop", func(t *testing.T) {
+		ss, fc := newTestScreenServer(t, nil)
+		result := callCapture(t, ss, map[string]interface{}{"inline": true})
+		text := result.Content[0].(mcp.TextContent).Text
+		if result.IsError || len(result.Content) != 2 {
+			t.Fatalf("Unexpected result: %s", text)
+		}
+		if len(fc.captured) != 1 || fc.captured[0] != image.Rect(0, 0, 60, 30) {
+			t.Errorf("Expected the union of the displays, got %v", fc.captured)
+		}
+		img := result.Content[1].(mcp.ImageContent)
+		data, _ := base64.StdEncoding.DecodeString(img.Data)
+		decoded, err := png.Decode(bytes.NewReader(data))
+		if err != nil || img.MIMEType != "image/png" || decoded.Bounds().Dx() != 60 {
+			t.Errorf("Expected an inline 60px PNG, got %s: %v", img.MIMEType, err)
+		}
+		files, _ := filepath.Glob(filepath.Join(ss.config.DataPath, "screen_*.png"))
+		if len(files) != 1 || !strings.Contains(text, files[0]) {
+			t.Errorf("Expected the screenshot to be saved, got %v: %s", files, text)
+		}
+	})
+
+	t.Run("DisplayJPEG", func(t *testing.T) {
+		ss, fc := newTestScreenServer(t, map[string]interface{}{"format": FormatJPEG, "quality": 60})
+		result := callCapture(t, ss, map[string]interface{}{"display": float64(1), "name": "second"})
+		if result.IsError || len(result.Content) != 1 {
+			t.Fatalf("Unexpected result: %v", result.Content)
+		}
+		if fc.captured[0] != image.Rect(40, 0, 60, 20) {
+			t.Errorf("Expected display 1, got %v", fc.captured)
+		}
+		files, _ := filepath.Glob(filepath.Join(ss.config.DataPath, "second_*.jpg"))
+		if len(files) != 1 {
+			t.Fatalf("Expected a jpg file, got %v", files)
+		}
+		data, _ := os.ReadFile(files[0])
+		if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+			t.Error("Expected JPEG data")
+		}
+	})
+
+	t.Run("InlineTooLarge", func(t *testing.T) {
+		ss, _ := newTestScreenServer(t, map[string]interface{}{"max_inline_image_bytes": 10})
+		result := callCapture(t, ss, map[string]interface{}{"inline": true})
+		if len(result.Content) != 1 || !strings.Contains(result.Content[0].(mcp.TextContent).Text, "max_inline_image_bytes") {
+			t.Errorf("Expected only the file with a warning, got %v", result.Content)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		ss, fc := newTestScreenServer(t, nil)
+		ss.getenv = func(string) string { return "" }
+		result := callCapture(t, ss, nil)
+		if !result.IsError || !strings.Contains(result.Content[0].(mcp.TextContent).Text, "DISPLAY") {
+			t.Errorf("Expected a headless Linux error, got %v", result.Content)
+		}
+		ss.goos = "windows"
+		if payload, ok := comm.ParseToolError(callCapture(t, ss, map[string]interface{}{"display": float64(5)})); !ok || payload.Code != comm.ErrCodeInvalidArgument {
+			t.Errorf("Expected an unknown display to fail with %s, got %+v", comm.ErrCodeInvalidArgument, payload)
+		}
+		fc.err = errors.New("permission denied")
+		if result := callCapture(t, ss, nil); !result.IsError || !strings.Contains(result.Content[0].(mcp.TextContent).Text, "permission denied") {
+			t.Errorf("Expected the capture error, got %v", result.Content)
+		}
+		fc.displays = nil
+		if payload, ok := comm.ParseToolError(callCapture(t, ss, nil)); !ok || payload.Code != comm.ErrCodeNotFound {
+			t.Errorf("Expected no display to fail with %s, got %+v", comm.ErrCodeNotFound, payload)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		ss, _ := newTestScreenServer(t, map[string]interface{}{"disabled": true})
+		if err := ss.RegisterTools(); err != nil {
+			t.Fatal(err)
+		}
+		if len(ss.Tools()) != 0 {
+			t.Errorf("Expected no tools when disabled, got %d", len(ss.Tools()))
+		}
+		ss, _ = newTestScreenServer(t, nil)
+		_ = ss.RegisterTools()
+		if len(ss.Tools()) != 1 {
+			t.Errorf("Expected screen_capture, got %d tools", len(ss.Tools()))
+		}
+	})
+}
+
+func TestReloadConfigKeepsRunningOnError(t *testing.T) {
+	ss, _ := newTestScreenServer(t, nil)
+	running := ss.cfg()
+	if err := ss.LoadConfig(map[string]interface{}{"format": FormatJPEG, "quality": float64(0)}); err == nil {
+		t.Fatal("Expected quality 0 to be rejected")
+	}
+	if ss.cfg() != running || running.Format == FormatJPEG {
+		t.Errorf("Expected the running config to be kept, got %+v", ss.cfg())
+	}
+}
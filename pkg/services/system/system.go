@@ -0,0 +1,243 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+// Package system provides native, cross-platform tools for inspecting the local machine.
+package system
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/gojue/moling/pkg/services/abstract"
+	"github.com/gojue/moling/pkg/utils"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	SystemServerName comm.MoLingServerType = "System"
+)
+
+// SystemServer implements the Service interface and provides the system_* tools.
+type SystemServer struct {
+	abstract.MLService
+	config     *SystemConfig // 重新加载时整体替换，处理函数通过 cfg 读取
+	configLock sync.RWMutex  // 保护 config
+}
+
+// NewSystemServer creates a new SystemServer with the default configuration.
+func NewSystemServer(ctx context.Context) (abstract.Service, error) {
+	base, err := abstract.NewServiceBase(ctx, SystemServerName)
+	if err != nil {
+		return nil, err
+	}
+
+	ss := &SystemServer{
+		MLService: base,
+		config:    NewSystemConfig(),
+	}
+	if err := ss.InitResources(); err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+// RegisterTools registers the prompt and the tools of the system service.
+func (ss *SystemServer) RegisterTools() error {
+	ss.AddPrompt(abstract.PromptEntry{
+		PromptVar: mcp.Prompt{
+			Name:        "system_prompt",
+			Description: "Get the relevant functions and prompts of the System MCP Server.",
+		},
+		HandlerFunc: ss.handlePrompt,
+	})
+
+	ss.AddTool(mcp.NewTool(
+		"system_info",
+		mcp.WithDescription("Get the OS, platform version, kernel version, architecture, hostname, CPU count and uptime of the machine as JSON."),
+	), ss.handleSystemInfo)
+
+	ss.AddTool(mcp.NewTool(
+		"system_processes",
+		mcp.WithDescription("List running processes with pid, ppid, name, cpu_percent and rss (bytes) as JSON."),
+		mcp.WithString("name",
+			mcp.Description("Only return processes whose name contains this value, case-insensitive"),
+		),
+		mcp.WithNumber("pid",
+			mcp.Description("Only return the process with this pid"),
+		),
+		mcp.WithString("sort_by",
+			mcp.Description("Sort order (default: cpu)"),
+			mcp.Enum("cpu", "rss", "pid", "name"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of processes to return (default: max_processes of the configuration)"),
+		),
+	), ss.handleProcesses)
+
+	ss.AddTool(mcp.NewTool(
+		"system_process_kill",
+		mcp.WithDescription("Terminate a process by pid. Only available when allow_kill is enabled in the configuration."),
+		mcp.WithNumber("pid",
+			mcp.Description("Process ID to terminate"),
+			mcp.Required(),
+		),
+	), ss.handleProcessKill)
+
+	ss.AddTool(mcp.NewTool(
+		"system_disk_usage",
+		mcp.WithDescription("Get the total, used and free bytes of every mounted partition as JSON."),
+	), ss.handleDiskUsage)
+
+	ss.AddTool(mcp.NewTool(
+		"system_network_interfaces",
+		mcp.WithDescription("List network interfaces with their addresses, MAC address, MTU and up/down state as JSON."),
+	), ss.handleNetworkInterfaces)
+	return nil
+}
+
+func (ss *SystemServer) handlePrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	return &mcp.GetPromptResult{
+		Description: "",
+		Messages: []mcp.PromptMessage{
+			{
+				Role: mcp.RoleUser,
+				Content: mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf(ss.cfg().prompt, ss.MlConfig().SystemInfo),
+				},
+			},
+		},
+	}, nil
+}
+
+func (ss *SystemServer) handleSystemInfo(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	info, err := CollectHostInfo(ctx)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to get system info", err), nil
+	}
+	return jsonResult(info)
+}
+
+func (ss *SystemServer) handleProcesses(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	filter := ProcessFilter{Limit: ss.cfg().MaxProcesses}
+	filter.Name, _ = args["name"].(string)
+	filter.SortBy, _ = args["sort_by"].(string)
+	if pid, ok := args["pid"].(float64); ok {
+		filter.PID = int32(pid)
+	}
+	if limit, ok := args["limit"].(float64); ok && limit > 0 {
+		filter.Limit = int(limit)
+	}
+
+	procs, err := listProcesses(ctx)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to list processes", err), nil
+	}
+	return jsonResult(filterProcesses(procs, filter))
+}
+
+func (ss *SystemServer) handleProcessKill(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if !ss.cfg().AllowKill {
+		return comm.ToolError(comm.ErrCodePermissionDenied, "killing processes is disabled, set allow_kill to true in the System configuration to enable it", ""), nil
+	}
+	arg, ok := request.GetArguments()["pid"].(float64)
+	// 先校验范围再转换，避免 1.5 或超出 int32 的值截断后落到其他进程上
+	if !ok || arg != math.Trunc(arg) || arg < 1 || arg > math.MaxInt32 {
+		return comm.ToolError(comm.ErrCodeInvalidArgument, fmt.Sprintf("pid must be an integer between 1 and %d", math.MaxInt32), ""), nil
+	}
+	pid := int32(arg)
+	if int(pid) == os.Getpid() {
+		return comm.ToolError(comm.ErrCodePermissionDenied, "refusing to kill the MoLing server itself", ""), nil
+	}
+	if err := killProcess(ctx, pid); err != nil {
+		return comm.ToolErrorFromErr(fmt.Sprintf("failed to kill process %d", pid), err), nil
+	}
+	ss.Logger.Info().Int32("pid", pid).Msg("process killed")
+	return mcp.NewToolResultText(fmt.Sprintf("Process %d killed", pid)), nil
+}
+
+func (ss *SystemServer) handleDiskUsage(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	usage, err := collectDiskUsage(ctx)
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to get disk usage", err), nil
+	}
+	return jsonResult(usage)
+}
+
+func (ss *SystemServer) handleNetworkInterfaces(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ifaces, err := collectNetworkInterfaces()
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to list network interfaces", err), nil
+	}
+	return jsonResult(ifaces)
+}
+
+// jsonResult encodes v as indented JSON.
+func jsonResult(v interface{}) (*mcp.CallToolResult, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return comm.ToolErrorFromErr("failed to encode the result", err), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+func (ss *SystemServer) Config() string {
+	cfg, err := json.Marshal(ss.cfg())
+	if err != nil {
+		ss.Logger.Err(err).Msg("failed to marshal config")
+		return "{}"
+	}
+	return string(cfg)
+}
+
+func (ss *SystemServer) Name() comm.MoLingServerType {
+	return SystemServerName
+}
+
+func (ss *SystemServer) Close() error {
+	ss.Logger.Debug().Msg("SystemServer closed")
+	return nil
+}
+
+// LoadConfig loads the configuration from a JSON object.
+func (ss *SystemServer) LoadConfig(jsonData map[string]interface{}) error {
+	// 在副本上合并和校验，校验通过后才替换正在使用的配置
+	next := *ss.cfg()
+	err := utils.MergeJSONToStruct(&next, jsonData)
+	if err != nil {
+		return err
+	}
+	if err := next.Check(); err != nil {
+		return err
+	}
+	ss.configLock.Lock()
+	ss.config = &next
+	ss.configLock.Unlock()
+	return nil
+}
+
+// cfg returns the running config, LoadConfig replaces it as a whole instead of changing it.
+func (ss *SystemServer) cfg() *SystemConfig {
+	ss.configLock.RLock()
+	defer ss.configLock.RUnlock()
+	return ss.config
+}
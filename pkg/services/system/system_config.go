@@ -0,0 +1,74 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package system
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	// SystemPromptDefault is the default prompt for the system service.
+	SystemPromptDefault = `
+You are a system information assistant running on %s. You can inspect the local machine without executing shell commands, the results are structured JSON and identical on macOS, Linux and Windows. Your capabilities include:
+
+1. **System Information**: OS, platform version, kernel version, architecture, hostname, CPU count and uptime.
+
+2. **Processes**:
+   - List processes with pid, name, CPU usage and resident memory
+   - Filter processes by name or pid, sort them by cpu, memory, pid or name
+   - Terminate a process, only when enabled in the configuration
+
+3. **Disk Usage**: Total, used and free space of every mounted partition.
+
+4. **Network Interfaces**: Addresses, MAC address, MTU and up/down state of every network interface.
+
+Prefer these tools over commands such as ps, df, uname or ifconfig. Confirm with the user before terminating a process.
+`
+)
+
+// SystemConfig represents the configuration for the system service.
+type SystemConfig struct {
+	PromptFile   string `json:"prompt_file"` // PromptFile is the prompt file for the system service.
+	prompt       string
+	AllowKill    bool `json:"allow_kill"`    // AllowKill enables the system_process_kill tool.
+	MaxProcesses int  `json:"max_processes"` // MaxProcesses is the default maximum number of processes returned by system_processes.
+}
+
+// NewSystemConfig creates a new SystemConfig with default values.
+func NewSystemConfig() *SystemConfig {
+	return &SystemConfig{
+		prompt:       SystemPromptDefault,
+		MaxProcesses: 100,
+	}
+}
+
+// Check validates the SystemConfig.
+func (sc *SystemConfig) Check() error {
+	sc.prompt = SystemPromptDefault
+	if sc.MaxProcesses <= 0 {
+		return fmt.Errorf("max processes must be greater than 0")
+	}
+	if sc.PromptFile != "" {
+		read, err := os.ReadFile(sc.PromptFile)
+		if err != nil {
+			return fmt.Errorf("failed to read prompt file:%s, error: %v", sc.PromptFile, err)
+		}
+		sc.prompt = string(read)
+	}
+	return nil
+}
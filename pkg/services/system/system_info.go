@@ -0,0 +1,231 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package system
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/host"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// HostInfo is the result of the system_info tool.
+type HostInfo struct {
+	OS              string `json:"os"`
+	Platform        string `json:"platform"`
+	PlatformVersion string `json:"platform_version"`
+	KernelVersion   string `json:"kernel_version"`
+	Arch            string `json:"arch"`
+	Hostname        string `json:"hostname"`
+	NumCPU          int    `json:"num_cpu"`
+	Uptime          uint64 `json:"uptime"`    // seconds
+	BootTime        uint64 `json:"boot_time"` // unix timestamp
+}
+
+// ProcessInfo is a single process of the system_processes tool.
+type ProcessInfo struct {
+	PID        int32   `json:"pid"`
+	PPID       int32   `json:"ppid"`
+	Name       string  `json:"name"`
+	CPUPercent float64 `json:"cpu_percent"`
+	RSS        uint64  `json:"rss"` // bytes
+}
+
+// ProcessFilter selects and orders processes.
+type ProcessFilter struct {
+	Name   string // 进程名包含的子串，不区分大小写
+	PID    int32  // 指定进程ID，0 表示不限制
+	SortBy string // cpu, rss, pid or name
+	Limit  int    // 返回的最大数量，0 表示不限制
+}
+
+// DiskUsage is the usage of a mounted partition.
+type DiskUsage struct {
+	Mountpoint  string  `json:"mountpoint"`
+	Device      string  `json:"device"`
+	Fstype      string  `json:"fstype"`
+	Total       uint64  `json:"total"`
+	Used        uint64  `json:"used"`
+	Free        uint64  `json:"free"`
+	UsedPercent float64 `json:"used_percent"`
+}
+
+// NetworkInterface is a network interface with its addresses.
+type NetworkInterface struct {
+	Name         string   `json:"name"`
+	HardwareAddr string   `json:"hardware_addr,omitempty"`
+	MTU          int      `json:"mtu"`
+	Up           bool     `json:"up"`
+	Loopback     bool     `json:"loopback"`
+	Addresses    []string `json:"addresses"`
+}
+
+// CollectHostInfo returns the OS, kernel, architecture, hostname and uptime of the machine.
+func CollectHostInfo(ctx context.Context) (*HostInfo, error) {
+	info, err := host.InfoWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &HostInfo{
+		OS:              info.OS,
+		Platform:        info.Platform,
+		PlatformVersion: info.PlatformVersion,
+		KernelVersion:   info.KernelVersion,
+		Arch:            runtime.GOARCH,
+		Hostname:        info.Hostname,
+		NumCPU:          runtime.NumCPU(),
+		Uptime:          info.Uptime,
+		BootTime:        info.BootTime,
+	}, nil
+}
+
+// Describe returns a short description of the system, e.g. "darwin 15.3.3" or "ubuntu 20.04",
+// used to fill MoLingConfig.SystemInfo.
+func Describe(ctx context.Context) string {
+	info, err := CollectHostInfo(ctx)
+	if err != nil {
+		return runtime.GOOS
+	}
+	if info.Platform != "" {
+		return strings.TrimSpace(fmt.Sprintf("%s %s (%s %s)", info.Platform, info.PlatformVersion, info.OS, info.Arch))
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s %s %s", info.OS, info.KernelVersion, info.Arch))
+}
+
+// listProcesses returns all processes readable by the current user. Processes that exit while
+// being inspected are skipped.
+func listProcesses(ctx context.Context) ([]ProcessInfo, error) {
+	procs, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]ProcessInfo, 0, len(procs))
+	for _, p := range procs {
+		name, err := p.NameWithContext(ctx)
+		if err != nil {
+			continue
+		}
+		info := ProcessInfo{PID: p.Pid, Name: name}
+		info.PPID, _ = p.PpidWithContext(ctx)
+		info.CPUPercent, _ = p.CPUPercentWithContext(ctx)
+		if mem, err := p.MemoryInfoWithContext(ctx); err == nil && mem != nil {
+			info.RSS = mem.RSS
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+// filterProcesses applies the filter to procs, the result is sorted descending by cpu and rss
+// and ascending by pid and name.
+func filterProcesses(procs []ProcessInfo, filter ProcessFilter) []ProcessInfo {
+	name := strings.ToLower(filter.Name)
+	result := make([]ProcessInfo, 0, len(procs))
+	for _, p := range procs {
+		if filter.PID != 0 && p.PID != filter.PID {
+			continue
+		}
+		if name != "" && !strings.Contains(strings.ToLower(p.Name), name) {
+			continue
+		}
+		result = append(result, p)
+	}
+
+	var less func(a, b ProcessInfo) bool
+	switch filter.SortBy {
+	case "rss":
+		less = func(a, b ProcessInfo) bool { return a.RSS > b.RSS }
+	case "pid":
+		less = func(a, b ProcessInfo) bool { return a.PID < b.PID }
+	case "name":
+		less = func(a, b ProcessInfo) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) }
+	default:
+		less = func(a, b ProcessInfo) bool { return a.CPUPercent > b.CPUPercent }
+	}
+	sort.SliceStable(result, func(i, j int) bool { return less(result[i], result[j]) })
+
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+	return result
+}
+
+// killProcess terminates the process with the given pid.
+func killProcess(ctx context.Context, pid int32) error {
+	p, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		return err
+	}
+	return p.KillWithContext(ctx)
+}
+
+// collectDiskUsage returns the usage of every physical partition.
+func collectDiskUsage(ctx context.Context) ([]DiskUsage, error) {
+	partitions, err := disk.PartitionsWithContext(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]DiskUsage, 0, len(partitions))
+	for _, part := range partitions {
+		usage, err := disk.UsageWithContext(ctx, part.Mountpoint)
+		if err != nil {
+			continue
+		}
+		result = append(result, DiskUsage{
+			Mountpoint:  part.Mountpoint,
+			Device:      part.Device,
+			Fstype:      part.Fstype,
+			Total:       usage.Total,
+			Used:        usage.Used,
+			Free:        usage.Free,
+			UsedPercent: usage.UsedPercent,
+		})
+	}
+	return result, nil
+}
+
+// collectNetworkInterfaces returns the network interfaces with their addresses.
+func collectNetworkInterfaces() ([]NetworkInterface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]NetworkInterface, 0, len(ifaces))
+	for _, iface := range ifaces {
+		ni := NetworkInterface{
+			Name:         iface.Name,
+			HardwareAddr: iface.HardwareAddr.String(),
+			MTU:          iface.MTU,
+			Up:           iface.Flags&net.FlagUp != 0,
+			Loopback:     iface.Flags&net.FlagLoopback != 0,
+			Addresses:    []string{},
+		}
+		if addrs, err := iface.Addrs(); err == nil {
+			for _, addr := range addrs {
+				ni.Addresses = append(ni.Addresses, addr.String())
+			}
+		}
+		result = append(result, ni)
+	}
+	return result, nil
+}
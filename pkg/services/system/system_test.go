@@ -0,0 +1,171 @@
+// Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// Repository: https://github.com/gojue/moling
+
+package system
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/gojue/moling/pkg/comm"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func newTestServer(t *testing.T) *SystemServer {
+	t.Helper()
+	_, ctx, err := comm.InitTestEnv()
+	if err != nil {
+		t.Fatalf("Failed to initialize test environment: %v", err)
+	}
+	svc, err := NewSystemServer(ctx)
+	if err != nil {
+		t.Fatalf("Failed to create SystemServer: %v", err)
+	}
+	return svc.(*SystemServer)
+}
+
+// callJSON calls a tool handler and decodes its JSON result into v.
+func callJSON(t *testing.T, handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), args map[string]interface{}, v interface{}) {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = args
+	result, err := handler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if result.IsError {
+		t.Fatalf("Unexpected tool error: %s", text)
+	}
+	if err := json.Unmarshal([]byte(text), v); err != nil {
+		t.Fatalf("Expected JSON result, got %s", text)
+	}
+}
+
+func TestSystemTools(t *testing.T) {
+	ss := newTestServer(t)
+
+	t.Run("SystemInfo", func(t *testing.T) {
+		var info map[string]interface{}
+		callJSON(t, ss.handleSystemInfo, nil, &info)
+		for _, key := range []string{"os", "platform", "kernel_version", "arch", "hostname", "num_cpu", "uptime", "boot_time"} {
+			if _, ok := info[key]; !ok {
+				t.Errorf("Expected key %s in %v", key, info)
+			}
+		}
+		if info["os"] != runtime.GOOS || info["arch"] != runtime.GOARCH {
+			t.Errorf("Expected %s/%s, got %v/%v", runtime.GOOS, runtime.GOARCH, info["os"], info["arch"])
+		}
+	})
+
+	t.Run("CurrentProcess", func(t *testing.T) {
+		var procs []map[string]interface{}
+		callJSON(t, ss.handleProcesses, map[string]interface{}{"pid": float64(os.Getpid())}, &procs)
+		if len(procs) != 1 {
+			t.Fatalf("Expected the current process, got %v", procs)
+		}
+		for _, key := range []string{"pid", "ppid", "name", "cpu_percent", "rss"} {
+			if _, ok := procs[0][key]; !ok {
+				t.Errorf("Expected key %s in %v", key, procs[0])
+			}
+		}
+		if procs[0]["pid"] != float64(os.Getpid()) {
+			t.Errorf("Expected pid %d, got %v", os.Getpid(), procs[0]["pid"])
+		}
+	})
+
+	t.Run("DiskAndNetwork", func(t *testing.T) {
+		var disks []DiskUsage
+		callJSON(t, ss.handleDiskUsage, nil, &disks)
+		var ifaces []NetworkInterface
+		callJSON(t, ss.handleNetworkInterfaces, nil, &ifaces)
+		if len(ifaces) == 0 {
+			t.Errorf("Expected at least one network interface")
+		}
+	})
+
+	t.Run("KillDisabledByDefault", func(t *testing.T) {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]interface{}{"pid": float64(1)}
+		result, _ := ss.handleProcessKill(context.Background(), request)
+		if payload, ok := comm.ParseToolError(result); !ok || payload.Code != comm.ErrCodePermissionDenied {
+			t.Errorf("Expected kill to be disabled, got %v", result.Content)
+		}
+	})
+}
+
+func TestProcessKillInvalidPID(t *testing.T) {
+	ss := newTestServer(t)
+	if err := ss.LoadConfig(map[string]interface{}{"allow_kill": true}); err != nil {
+		t.Fatalf("Failed to enable kill: %v", err)
+	}
+	// 4294967297 截断为 int32 后是 1，1.5 截断后也是 1
+	for _, pid := range []interface{}{float64(0), float64(-1), 1.5, float64(4294967297), float64(os.Getpid()) + 0.5, "1"} {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]interface{}{"pid": pid}
+		result, _ := ss.handleProcessKill(context.Background(), request)
+		if payload, ok := comm.ParseToolError(result); !ok || payload.Code != comm.ErrCodeInvalidArgument {
+			t.Errorf("Expected INVALID_ARGUMENT for pid %v, got %v", pid, result.Content)
+		}
+	}
+}
+
+func TestFilterProcesses(t *testing.T) {
+	procs := []ProcessInfo{
+		{PID: 3, Name: "chrome", CPUPercent: 10, RSS: 300},
+		{PID: 1, Name: "init", CPUPercent: 0.1, RSS: 100},
+		{PID: 2, Name: "Chrome Helper", CPUPercent: 30, RSS: 200},
+	}
+
+	// 名称过滤不区分大小写，默认按 CPU 降序
+	got := filterProcesses(procs, ProcessFilter{Name: "CHROME"})
+	if len(got) != 2 || got[0].PID != 2 || got[1].PID != 3 {
+		t.Errorf("Expected chrome processes sorted by cpu, got %v", got)
+	}
+
+	got = filterProcesses(procs, ProcessFilter{SortBy: "rss", Limit: 2})
+	if len(got) != 2 || got[0].PID != 3 || got[1].PID != 2 {
+		t.Errorf("Expected top 2 processes by rss, got %v", got)
+	}
+
+	got = filterProcesses(procs, ProcessFilter{SortBy: "pid"})
+	if got[0].PID != 1 || got[2].PID != 3 {
+		t.Errorf("Expected processes sorted by pid, got %v", got)
+	}
+
+	got = filterProcesses(procs, ProcessFilter{SortBy: "name"})
+	if got[0].Name != "chrome" || got[2].Name != "init" {
+		t.Errorf("Expected processes sorted by name, got %v", got)
+	}
+
+	if got = filterProcesses(procs, ProcessFilter{PID: 1}); len(got) != 1 || got[0].Name != "init" {
+		t.Errorf("Expected process 1, got %v", got)
+	}
+}
+
+func TestReloadConfigKeepsRunningOnError(t *testing.T) {
+	ss := newTestServer(t)
+	running := ss.cfg()
+	if err := ss.LoadConfig(map[string]interface{}{"allow_kill": true, "max_processes": float64(0)}); err == nil {
+		t.Fatal("Expected max_processes 0 to be rejected")
+	}
+	if ss.cfg() != running || running.AllowKill {
+		t.Errorf("Expected the running config to be kept, got %+v", ss.cfg())
+	}
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2025 CFC4N <cfc4n.cs@gmail.com>. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Repository: https://github.com/gojue/moling
+ */
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+)
+
+var (
+	// ErrHostDenied is returned when the host matches the deny list.
+	ErrHostDenied = errors.New("host is in the deny list")
+	// ErrHostNotAllowed is returned when an allow list is set and the host does not match it.
+	ErrHostNotAllowed = errors.New("host is not in the allow list")
+	// ErrPrivateNetwork is returned when the host resolves to a private, loopback or link-local address.
+	ErrPrivateNetwork = errors.New("host resolves to a private network address")
+)
+
+// HostPolicy decides which hosts outbound requests may reach.
+// Patterns are either exact host names / IPs or "*.example.com" for any subdomain of example.com.
+type HostPolicy struct {
+	AllowedHosts        []string // 允许访问的主机，为空时不限制
+	DeniedHosts         []string // 禁止访问的主机，优先级最高
+	BlockPrivateNetwork bool     // 是否禁止访问内网地址，显式加入允许列表的主机除外
+}
+
+// NewHostPolicy creates a HostPolicy from comma separated allow and deny lists.
+func NewHostPolicy(allowed, denied string, blockPrivate bool) HostPolicy {
+	return HostPolicy{
+		AllowedHosts:        SplitAndTrim(allowed, ","),
+		DeniedHosts:         SplitAndTrim(denied, ","),
+		BlockPrivateNetwork: blockPrivate,
+	}
+}
+
+// Check reports whether host may be reached under the policy. host may carry a port. The private
+// network check only gives an early error, connections must be dialed with DialContext.
+func (p HostPolicy) Check(ctx context.Context, host string) error {
+	host = normalizeHost(host)
+	if host == "" {
+		return fmt.Errorf("empty host")
+	}
+	if matchHostList(p.DeniedHosts, host) {
+		return fmt.Errorf("%w: %s", ErrHostDenied, host)
+	}
+	allowed := matchHostList(p.AllowedHosts, host)
+	if len(p.AllowedHosts) > 0 && !allowed {
+		return fmt.Errorf("%w: %s", ErrHostNotAllowed, host)
+	}
+	if !p.BlockPrivateNetwork || allowed {
+		return nil
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if IsPrivateIP(ip) {
+			return fmt.Errorf("%w: %s", ErrPrivateNetwork, host)
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve host %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if IsPrivateIP(addr.IP) {
+			return fmt.Errorf("%w: %s (%s)", ErrPrivateNetwork, host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// DialContext returns a dial function for http.Transport that enforces the policy on the address
+// actually connected to. Check resolves the host on its own, so a DNS answer that changes between
+// Check and the dial (DNS rebinding) could otherwise still reach a private address. Addresses for
+// which exempt returns true, such as the proxy, are dialed without the private network check.
+func (p HostPolicy) DialContext(dialer *net.Dialer, exempt func(addr string) bool) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host := normalizeHost(addr)
+		if !p.BlockPrivateNetwork || matchHostList(p.AllowedHosts, host) || (exempt != nil && exempt(addr)) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		guarded := *dialer
+		guarded.Control = func(network, address string, _ syscall.RawConn) error {
+			// address 是解析后实际连接的 IP
+			ip := net.ParseIP(normalizeHost(address))
+			if ip == nil || IsPrivateIP(ip) {
+				return fmt.Errorf("%w: %s (%s)", ErrPrivateNetwork, host, address)
+			}
+			return nil
+		}
+		return guarded.DialContext(ctx, network, addr)
+	}
+}
+
+// IsPrivateIP reports whether ip is a loopback, private, link-local or unspecified address.
+func IsPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsUnspecified()
+}
+
+// SplitAndTrim splits s by sep, trims spaces and drops empty items.
+func SplitAndTrim(s, sep string) []string {
+	var items []string
+	for _, item := range strings.Split(s, sep) {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// normalizeHost strips the port and brackets and lower-cases the host.
+func normalizeHost(host string) string {
+	host = strings.TrimSpace(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	return strings.ToLower(host)
+}
+
+// matchHostList reports whether host matches any of the patterns.
+func matchHostList(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if strings.HasPrefix(pattern, "*.") {
+			if strings.HasSuffix(host, pattern[1:]) {
+				return true
+			}
+			continue
+		}
+		if normalizeHost(pattern) == host {
+			return true
+		}
+	}
+	return false
+}
@@ -19,12 +19,19 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 var pidFile *os.File
 
+// ErrInstanceRunning is returned by CreatePIDFile when another instance holds the lock of the PID file.
+var ErrInstanceRunning = errors.New("another instance is already running")
+
 // CreatePIDFile creates and locks a PID file to prevent multiple instances.
 func CreatePIDFile(pidFilePath string) error {
 	// Open or create the PID file
@@ -41,7 +48,7 @@ func CreatePIDFile(pidFilePath string) error {
 	}
 	if !locked {
 		_ = file.Close()
-		return fmt.Errorf("another instance is already running: %s", pidFilePath)
+		return fmt.Errorf("%w: %s", ErrInstanceRunning, pidFilePath)
 	}
 
 	// Write the current PID to the file
@@ -79,3 +86,51 @@ func RemovePIDFile(pidFilePath string) error {
 	}
 	return nil
 }
+
+// ReadPIDFile returns the PID recorded in the PID file and whether the instance that wrote it is
+// still running, i.e. still holds the lock. A missing PID file returns an os.ErrNotExist error.
+func ReadPIDFile(pidFilePath string) (int, bool, error) {
+	file, err := os.OpenFile(pidFilePath, os.O_RDWR, 0644)
+	if err != nil {
+		return 0, false, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var pid int
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read PID file: %w", err)
+	}
+	if _, err := fmt.Sscanf(strings.TrimSpace(string(data)), "%d", &pid); err != nil {
+		return 0, false, fmt.Errorf("invalid PID file %s: %w", pidFilePath, err)
+	}
+
+	// 能加锁说明写入 PID 的实例已退出，PID 文件已过期
+	locked, err := lockFile(file)
+	if err != nil {
+		return pid, false, fmt.Errorf("failed to lock PID file: %w", err)
+	}
+	if locked {
+		_ = unlockFile(file)
+	}
+	return pid, !locked, nil
+}
+
+// IsSameExecutable reports whether the process with the given PID runs an executable with the same
+// name as the current process, i.e. whether it is another MoLing instance.
+func IsSameExecutable(pid int) (bool, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return false, err
+	}
+	other, err := processExecutable(pid)
+	if err != nil {
+		return false, fmt.Errorf("failed to get the executable of process %d: %w", pid, err)
+	}
+	return executableName(self) == executableName(other), nil
+}
+
+// executableName 返回不含目录和 .exe 后缀的可执行文件名，Windows 文件名不区分大小写
+func executableName(path string) string {
+	return strings.TrimSuffix(strings.ToLower(filepath.Base(path)), ".exe")
+}
@@ -22,7 +22,11 @@ package utils
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"os/exec"
+	"strconv"
+	"strings"
 	"syscall"
 )
 
@@ -40,3 +44,29 @@ func lockFile(file *os.File) (bool, error) {
 func unlockFile(file *os.File) error {
 	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
 }
+
+// ProcessAlive reports whether a process with the given PID exists, using signal 0.
+func ProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	// EPERM 说明进程存在，只是属于其他用户
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// processExecutable returns the executable of a process, from /proc on Linux and from ps elsewhere.
+func processExecutable(pid int) (string, error) {
+	if path, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", pid)); err == nil {
+		// 可执行文件被替换(如升级)后链接带有 (deleted) 后缀
+		return strings.TrimSuffix(path, " (deleted)"), nil
+	}
+	out, err := exec.Command("ps", "-o", "comm=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return "", err
+	}
+	if name := strings.TrimSpace(string(out)); name != "" {
+		return name, nil
+	}
+	return "", fmt.Errorf("no process %d", pid)
+}
@@ -28,9 +28,10 @@ import (
 )
 
 var (
-	kernel32     = syscall.NewLazyDLL("kernel32.dll")
-	lockFileEx   = kernel32.NewProc("LockFileEx")
-	unlockFileEx = kernel32.NewProc("UnlockFileEx")
+	kernel32                  = syscall.NewLazyDLL("kernel32.dll")
+	lockFileEx                = kernel32.NewProc("LockFileEx")
+	unlockFileEx              = kernel32.NewProc("UnlockFileEx")
+	queryFullProcessImageName = kernel32.NewProc("QueryFullProcessImageNameW")
 )
 
 const (
@@ -38,6 +39,7 @@ const (
 	LockfileFailImmediately = 1
 )
 const ErrorLockViolation = syscall.Errno(33) // 0x21
+const processQueryLimitedInformation = 0x1000
 
 // lockFile locks the given file using Windows API.
 func lockFile(file *os.File) (bool, error) {
@@ -83,3 +85,39 @@ func unlockFile(file *os.File) error {
 
 	return nil
 }
+
+// ProcessAlive reports whether a process with the given PID exists. Windows has no signal 0,
+// os.FindProcess opens the process and fails when it doesn't exist.
+func ProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = process.Release()
+	return true
+}
+
+// processExecutable returns the executable of a process using Windows API.
+func processExecutable(pid int) (string, error) {
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = syscall.CloseHandle(handle) }()
+
+	buf := make([]uint16, syscall.MAX_PATH)
+	size := uint32(len(buf))
+	r, _, err := queryFullProcessImageName.Call(
+		uintptr(handle),
+		0,
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(unsafe.Pointer(&size)),
+	)
+	if r == 0 {
+		return "", err
+	}
+	return syscall.UTF16ToString(buf[:size]), nil
+}
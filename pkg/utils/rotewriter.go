@@ -19,35 +19,51 @@
 package utils
 
 import (
+	"fmt"
 	"os"
 	"sync"
 )
 
 // RotateWriter 是一个简单的日志轮转写入器
 type RotateWriter struct {
-	filePaths    [2]string // 两个日志文件的路径
-	currentIndex int       // 当前写入的文件索引（0或1）
-	maxSize      int64     // 文件大小阈值（字节）
-	count        uint16    // 当前文件的写入次数
+	filePaths    []string // 轮转的日志文件路径
+	currentIndex int      // 当前写入的文件索引
+	maxSize      int64    // 文件大小阈值（字节）
+	count        uint16   // 当前文件的写入次数
 	mu           sync.Mutex
 	file         *os.File // 当前打开的文件句柄
 }
 
-// NewRotateWriter 创建一个新的 RotateWriter 实例
+// NewRotateWriter 创建一个新的 RotateWriter 实例，在 filePath.1 和 filePath.2 之间轮转
 func NewRotateWriter(filePath string, maxSize int64) (*RotateWriter, error) {
+	return NewRotateWriterN(filePath, maxSize, 2)
+}
+
+// NewRotateWriterN 创建一个在 filePath.1 到 filePath.N 之间轮转的 RotateWriter，从最近写入的文件继续写入
+func NewRotateWriterN(filePath string, maxSize int64, files int) (*RotateWriter, error) {
+	if files < 1 {
+		return nil, fmt.Errorf("rotate writer needs at least one file, got %d", files)
+	}
 	rw := &RotateWriter{
-		filePaths:    [2]string{filePath + ".1", filePath + ".2"},
+		filePaths:    make([]string, files),
 		currentIndex: 0,
 		maxSize:      maxSize,
 		count:        0,
 	}
 
-	// 初始化文件，确保两个文件都存在
-	for _, path := range rw.filePaths {
+	// 初始化文件，确保所有文件都存在，并找到最近写入的文件
+	var newest int64
+	for i := range rw.filePaths {
+		path := fmt.Sprintf("%s.%d", filePath, i+1)
+		rw.filePaths[i] = path
 		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
 		if err != nil {
 			return nil, err
 		}
+		if info, err := file.Stat(); err == nil && info.Size() > 0 && info.ModTime().UnixNano() > newest {
+			newest = info.ModTime().UnixNano()
+			rw.currentIndex = i
+		}
 		file.Close()
 	}
 
@@ -88,7 +104,7 @@ func (rw *RotateWriter) CheckFileSize() error {
 			rw.file.Close()
 
 			// 切换到另一个文件
-			rw.currentIndex = (rw.currentIndex + 1) % 2
+			rw.currentIndex = (rw.currentIndex + 1) % len(rw.filePaths)
 
 			// 清空目标文件
 			file, err := os.OpenFile(rw.filePaths[rw.currentIndex], os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0644)
@@ -19,6 +19,7 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
 	"mime"
 	"net/http"
@@ -114,19 +115,31 @@ func MergeJSONToStruct(target interface{}, jsonMap map[string]interface{}) error
 		// 遍历结构体的每个字段
 		for i := 0; i < typ.NumField(); i++ {
 			field := typ.Field(i)
-			// 检查JSON字段名是否与结构体的JSON tag匹配
-			if field.Tag.Get("json") == jsonKey {
+			// 检查JSON字段名是否与结构体的JSON tag匹配，忽略 omitempty 等选项
+			if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name == jsonKey {
 				// 获取结构体字段的反射值
 				fieldVal := val.Field(i)
 				// 检查字段是否可设置
 				if fieldVal.CanSet() {
+					if jsonValue == nil {
+						continue
+					}
 					// 将JSON值转换为结构体字段的类型
 					jsonVal := reflect.ValueOf(jsonValue)
 					if jsonVal.Type().ConvertibleTo(fieldVal.Type()) {
 						fieldVal.Set(jsonVal.Convert(fieldVal.Type()))
-					} else {
+						continue
+					}
+					// 嵌套的对象或数组(如 map、slice)无法直接转换，通过JSON编解码赋值
+					raw, err := json.Marshal(jsonValue)
+					if err != nil {
+						return fmt.Errorf("type mismatch for field %s, value:%v", jsonKey, jsonValue)
+					}
+					newVal := reflect.New(fieldVal.Type())
+					if err := json.Unmarshal(raw, newVal.Interface()); err != nil {
 						return fmt.Errorf("type mismatch for field %s, value:%v", jsonKey, jsonValue)
 					}
+					fieldVal.Set(newVal.Elem())
 				}
 			}
 		}